@@ -0,0 +1,333 @@
+// Package analytics computes fleet rollups over a time window from the
+// agents, tasks, commands and the agent status history: tasks completed per
+// agent per day, time spent waiting for feedback, command acknowledgement
+// latency and failure rates by task type.
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/database"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+const (
+	MetricTasksCompleted  = "tasks_completed"
+	MetricWaitingFeedback = "waiting_feedback"
+	MetricCommandLatency  = "command_latency"
+	MetricTaskOutcomes    = "task_outcomes"
+
+	// DefaultDays is the window when none is given
+	DefaultDays = 30
+
+	dayFormat = "2006-01-02"
+	untyped   = "untyped"
+)
+
+// Metrics are the names the export accepts
+var Metrics = []string{MetricTasksCompleted, MetricWaitingFeedback, MetricCommandLatency, MetricTaskOutcomes}
+
+// Data is what the rollups are computed from
+type Data struct {
+	Agents        []models.Agent
+	Tasks         []models.Task
+	Commands      []models.Command
+	StatusChanges []models.AgentStatusChange
+}
+
+// Load reads the data from the database
+func Load(db *database.DB) (*Data, error) {
+	agents, err := db.ListAllAgents()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := db.ListAllTasks()
+	if err != nil {
+		return nil, err
+	}
+	commands, err := db.ListAllCommands()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := db.ListAgentStatusChanges()
+	if err != nil {
+		return nil, err
+	}
+	return &Data{Agents: agents, Tasks: tasks, Commands: commands, StatusChanges: changes}, nil
+}
+
+// Window returns the time window for the given bounds. Without a start the
+// window covers the last days UTC days up to until, which defaults to now.
+func Window(since, until *time.Time, days int, now time.Time) (time.Time, time.Time) {
+	end := now.UTC()
+	if until != nil {
+		end = until.UTC()
+	}
+	if since != nil {
+		return since.UTC(), end
+	}
+	if days <= 0 {
+		days = DefaultDays
+	}
+	start := end.Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	return start, end
+}
+
+// Compute builds the report for [since, until). Intervals still running at
+// now, like an agent still waiting for feedback, are counted as open.
+func Compute(data *Data, since, until, now time.Time) *models.AnalyticsReport {
+	names := map[string]string{}
+	for _, agent := range data.Agents {
+		names[agent.ID] = agent.Name
+	}
+	agentName := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		if id == "" {
+			return "unassigned"
+		}
+		return id
+	}
+	inWindow := func(t time.Time) bool {
+		return !t.Before(since) && t.Before(until)
+	}
+
+	report := &models.AnalyticsReport{
+		Since:           since,
+		Until:           until,
+		Days:            days(since, until),
+		TasksCompleted:  tasksCompleted(data.Tasks, inWindow, agentName),
+		WaitingFeedback: waitingFeedback(data.StatusChanges, inWindow, agentName),
+		CommandLatency:  commandLatency(data.Commands, inWindow, agentName),
+		TaskOutcomes:    taskOutcomes(data.Tasks, inWindow),
+	}
+	return report
+}
+
+func days(since, until time.Time) []string {
+	result := []string{}
+	for day := since.UTC().Truncate(24 * time.Hour); day.Before(until); day = day.AddDate(0, 0, 1) {
+		result = append(result, day.Format(dayFormat))
+	}
+	return result
+}
+
+func tasksCompleted(tasks []models.Task, inWindow func(time.Time) bool, agentName func(string) string) []models.DailyAgentCount {
+	type key struct{ day, agentID string }
+	counts := map[key]int{}
+	for _, task := range tasks {
+		if task.Status != string(models.TaskStatusCompleted) || task.CompletedAt == nil || !inWindow(*task.CompletedAt) {
+			continue
+		}
+		agentID := ""
+		if task.AssignedAgentID != nil {
+			agentID = *task.AssignedAgentID
+		}
+		counts[key{task.CompletedAt.UTC().Format(dayFormat), agentID}]++
+	}
+
+	result := []models.DailyAgentCount{}
+	for k, count := range counts {
+		result = append(result, models.DailyAgentCount{
+			Day:       k.day,
+			AgentID:   k.agentID,
+			AgentName: agentName(k.agentID),
+			Count:     count,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Day != result[j].Day {
+			return result[i].Day < result[j].Day
+		}
+		return result[i].AgentName < result[j].AgentName
+	})
+	return result
+}
+
+// waitingFeedback measures the periods agents entered waiting_feedback in
+// the window, until their next status change
+func waitingFeedback(changes []models.AgentStatusChange, inWindow func(time.Time) bool, agentName func(string) string) []models.AgentDurationStats {
+	byAgent := map[string][]models.AgentStatusChange{}
+	for _, change := range changes {
+		byAgent[change.AgentID] = append(byAgent[change.AgentID], change)
+	}
+
+	durations := map[string][]float64{}
+	open := map[string]int{}
+	for agentID, history := range byAgent {
+		for i, change := range history {
+			if change.NewStatus != string(models.AgentStatusWaitingFeedback) || !inWindow(change.ChangedAt) {
+				continue
+			}
+			if i+1 == len(history) {
+				open[agentID]++
+				continue
+			}
+			durations[agentID] = append(durations[agentID], history[i+1].ChangedAt.Sub(change.ChangedAt).Seconds())
+		}
+	}
+	return durationStats(durations, open, agentName)
+}
+
+// commandLatency measures the time from sending a command to the agent
+// acknowledging or completing it
+func commandLatency(commands []models.Command, inWindow func(time.Time) bool, agentName func(string) string) []models.AgentDurationStats {
+	durations := map[string][]float64{}
+	open := map[string]int{}
+	for _, command := range commands {
+		if !inWindow(command.SentAt) {
+			continue
+		}
+		if command.RespondedAt == nil {
+			open[command.AgentID]++
+			continue
+		}
+		durations[command.AgentID] = append(durations[command.AgentID], command.RespondedAt.Sub(command.SentAt).Seconds())
+	}
+	return durationStats(durations, open, agentName)
+}
+
+func durationStats(durations map[string][]float64, open map[string]int, agentName func(string) string) []models.AgentDurationStats {
+	agentIDs := map[string]bool{}
+	for agentID := range durations {
+		agentIDs[agentID] = true
+	}
+	for agentID := range open {
+		agentIDs[agentID] = true
+	}
+
+	result := []models.AgentDurationStats{}
+	for agentID := range agentIDs {
+		values := durations[agentID]
+		stats := models.AgentDurationStats{
+			AgentID:   agentID,
+			AgentName: agentName(agentID),
+			Count:     len(values),
+			Open:      open[agentID],
+		}
+		if len(values) > 0 {
+			sort.Float64s(values)
+			for _, v := range values {
+				stats.TotalSeconds += v
+			}
+			stats.MeanSeconds = round(stats.TotalSeconds / float64(len(values)))
+			stats.MedianSeconds = round(median(values))
+			stats.MaxSeconds = round(values[len(values)-1])
+			stats.TotalSeconds = round(stats.TotalSeconds)
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AgentName < result[j].AgentName
+	})
+	return result
+}
+
+// taskOutcomes counts the tasks created in the window by type and status
+func taskOutcomes(tasks []models.Task, inWindow func(time.Time) bool) []models.TaskTypeOutcome {
+	byType := map[string]*models.TaskTypeOutcome{}
+	for _, task := range tasks {
+		if !inWindow(task.CreatedAt) {
+			continue
+		}
+		taskType := task.Type
+		if taskType == "" {
+			taskType = untyped
+		}
+		outcome, ok := byType[taskType]
+		if !ok {
+			outcome = &models.TaskTypeOutcome{Type: taskType}
+			byType[taskType] = outcome
+		}
+
+		outcome.Total++
+		switch task.Status {
+		case string(models.TaskStatusCompleted):
+			outcome.Completed++
+		case string(models.TaskStatusFailed):
+			outcome.Failed++
+		default:
+			outcome.Open++
+		}
+	}
+
+	result := []models.TaskTypeOutcome{}
+	for _, outcome := range byType {
+		if finished := outcome.Completed + outcome.Failed; finished > 0 {
+			outcome.FailureRate = math.Round(float64(outcome.Failed)/float64(finished)*1000) / 1000
+		}
+		result = append(result, *outcome)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// Table returns one metric of a report as a header and rows, for CSV
+func Table(report *models.AnalyticsReport, metric string) ([]string, [][]string, error) {
+	var rows [][]string
+	switch metric {
+	case MetricTasksCompleted:
+		for _, c := range report.TasksCompleted {
+			rows = append(rows, []string{c.Day, c.AgentID, c.AgentName, strconv.Itoa(c.Count)})
+		}
+		return []string{"day", "agent_id", "agent_name", "count"}, rows, nil
+	case MetricWaitingFeedback, MetricCommandLatency:
+		stats := report.WaitingFeedback
+		if metric == MetricCommandLatency {
+			stats = report.CommandLatency
+		}
+		for _, s := range stats {
+			rows = append(rows, []string{
+				s.AgentID, s.AgentName, strconv.Itoa(s.Count), strconv.Itoa(s.Open),
+				formatFloat(s.MeanSeconds), formatFloat(s.MedianSeconds), formatFloat(s.MaxSeconds), formatFloat(s.TotalSeconds),
+			})
+		}
+		return []string{"agent_id", "agent_name", "count", "open", "mean_seconds", "median_seconds", "max_seconds", "total_seconds"}, rows, nil
+	case MetricTaskOutcomes:
+		for _, o := range report.TaskOutcomes {
+			rows = append(rows, []string{
+				o.Type, strconv.Itoa(o.Total), strconv.Itoa(o.Completed), strconv.Itoa(o.Failed), strconv.Itoa(o.Open), formatFloat(o.FailureRate),
+			})
+		}
+		return []string{"type", "total", "completed", "failed", "open", "failure_rate"}, rows, nil
+	}
+	return nil, nil, fmt.Errorf("unknown metric %q", metric)
+}
+
+// Rows returns one metric of a report, for JSON
+func Rows(report *models.AnalyticsReport, metric string) (interface{}, error) {
+	switch metric {
+	case MetricTasksCompleted:
+		return report.TasksCompleted, nil
+	case MetricWaitingFeedback:
+		return report.WaitingFeedback, nil
+	case MetricCommandLatency:
+		return report.CommandLatency, nil
+	case MetricTaskOutcomes:
+		return report.TaskOutcomes, nil
+	}
+	return nil, fmt.Errorf("unknown metric %q", metric)
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func round(seconds float64) float64 {
+	return math.Round(seconds*10) / 10
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
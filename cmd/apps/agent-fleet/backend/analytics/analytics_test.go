@@ -0,0 +1,218 @@
+package analytics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+var since = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// at returns the time hours after the start of the window
+func at(hours float64) time.Time {
+	return since.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func task(taskType, status string, created time.Time, completed *time.Time, agentID *string) models.Task {
+	return models.Task{Type: taskType, Status: status, CreatedAt: created, CompletedAt: completed, AssignedAgentID: agentID}
+}
+
+func waiting(agentID string, hours float64) models.AgentStatusChange {
+	return models.AgentStatusChange{AgentID: agentID, NewStatus: string(models.AgentStatusWaitingFeedback), ChangedAt: at(hours)}
+}
+
+func active(agentID string, hours float64) models.AgentStatusChange {
+	return models.AgentStatusChange{AgentID: agentID, NewStatus: string(models.AgentStatusActive), ChangedAt: at(hours)}
+}
+
+func command(agentID string, sent float64, respondedAfter time.Duration) models.Command {
+	c := models.Command{AgentID: agentID, SentAt: at(sent)}
+	if respondedAfter > 0 {
+		c.RespondedAt = ptr(c.SentAt.Add(respondedAfter))
+	}
+	return c
+}
+
+func TestWindow(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name      string
+		since     *time.Time
+		until     *time.Time
+		days      int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "last days up to now",
+			days:      7,
+			wantStart: time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 5, 10, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "default days",
+			wantStart: time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 5, 10, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "days before until",
+			until:     ptr(since),
+			days:      1,
+			wantStart: since,
+			wantEnd:   since,
+		},
+		{
+			name:      "since wins over days",
+			since:     ptr(at(5)),
+			days:      7,
+			wantStart: at(5),
+			wantEnd:   time.Date(2024, 5, 10, 13, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Window(tt.since, tt.until, tt.days, now)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("Window() = %s to %s, want %s to %s", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+// testData covers the two UTC days from since, with records on both edges
+// of the window
+func testData() *Data {
+	alpha, beta := ptr("a1"), ptr("a2")
+	completed, failed, pending := string(models.TaskStatusCompleted), string(models.TaskStatusFailed), string(models.TaskStatusPending)
+	return &Data{
+		Agents: []models.Agent{{ID: "a1", Name: "alpha"}, {ID: "a2", Name: "beta"}},
+		Tasks: []models.Task{
+			task("bugfix", completed, at(9), ptr(at(10)), alpha),
+			// Created before the window, completed in it
+			task("bugfix", completed, at(-24), ptr(at(48).Add(-time.Second)), alpha),
+			// Completed at the end of the window, which is excluded
+			task("feature", completed, at(30), ptr(at(48)), beta),
+			// Completed at the start of the window, which is included
+			task("", completed, at(0), ptr(at(0)), nil),
+			task("bugfix", failed, at(2), nil, alpha),
+			task("bugfix", pending, at(30), nil, nil),
+		},
+		StatusChanges: []models.AgentStatusChange{
+			// Started waiting before the window
+			waiting("a1", -1), active("a1", 1),
+			waiting("a1", 10), active("a1", 10.5),
+			waiting("a2", 12), active("a2", 12+1.0/60),
+			waiting("a2", 13), active("a2", 13+5.0/60),
+			waiting("a2", 14), active("a2", 14+2.0/60),
+			waiting("a1", 32), active("a1", 32+10.0/60),
+			// Still waiting
+			waiting("a1", 44),
+		},
+		Commands: []models.Command{
+			command("a1", 34, 30*time.Second),
+			command("a1", 35, 0),
+			command("a1", 48, time.Second),
+			command("ghost", 1, 1250*time.Millisecond),
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	report := Compute(testData(), since, at(48), at(60))
+
+	if want := []string{"2024-05-01", "2024-05-02"}; !reflect.DeepEqual(report.Days, want) {
+		t.Errorf("days = %v, want %v", report.Days, want)
+	}
+
+	wantCompleted := []models.DailyAgentCount{
+		{Day: "2024-05-01", AgentID: "a1", AgentName: "alpha", Count: 1},
+		{Day: "2024-05-01", AgentID: "", AgentName: "unassigned", Count: 1},
+		{Day: "2024-05-02", AgentID: "a1", AgentName: "alpha", Count: 1},
+	}
+	if !reflect.DeepEqual(report.TasksCompleted, wantCompleted) {
+		t.Errorf("tasks completed:\n got %+v\nwant %+v", report.TasksCompleted, wantCompleted)
+	}
+
+	wantWaiting := []models.AgentDurationStats{
+		// Even count, the median is the mean of the middle values
+		{AgentID: "a1", AgentName: "alpha", Count: 2, Open: 1, MeanSeconds: 1200, MedianSeconds: 1200, MaxSeconds: 1800, TotalSeconds: 2400},
+		{AgentID: "a2", AgentName: "beta", Count: 3, MeanSeconds: 160, MedianSeconds: 120, MaxSeconds: 300, TotalSeconds: 480},
+	}
+	if !reflect.DeepEqual(report.WaitingFeedback, wantWaiting) {
+		t.Errorf("waiting feedback:\n got %+v\nwant %+v", report.WaitingFeedback, wantWaiting)
+	}
+
+	wantLatency := []models.AgentDurationStats{
+		{AgentID: "a1", AgentName: "alpha", Count: 1, Open: 1, MeanSeconds: 30, MedianSeconds: 30, MaxSeconds: 30, TotalSeconds: 30},
+		// Agents that are gone show up with their ID, durations are rounded
+		{AgentID: "ghost", AgentName: "ghost", Count: 1, MeanSeconds: 1.3, MedianSeconds: 1.3, MaxSeconds: 1.3, TotalSeconds: 1.3},
+	}
+	if !reflect.DeepEqual(report.CommandLatency, wantLatency) {
+		t.Errorf("command latency:\n got %+v\nwant %+v", report.CommandLatency, wantLatency)
+	}
+
+	wantOutcomes := []models.TaskTypeOutcome{
+		{Type: "bugfix", Total: 3, Completed: 1, Failed: 1, Open: 1, FailureRate: 0.5},
+		{Type: "feature", Total: 1, Completed: 1},
+		{Type: "untyped", Total: 1, Completed: 1},
+	}
+	if !reflect.DeepEqual(report.TaskOutcomes, wantOutcomes) {
+		t.Errorf("task outcomes:\n got %+v\nwant %+v", report.TaskOutcomes, wantOutcomes)
+	}
+}
+
+func TestComputeEmpty(t *testing.T) {
+	report := Compute(&Data{}, since, at(24), at(24))
+	// Empty rollups are encoded as [] rather than null
+	if report.TasksCompleted == nil || report.WaitingFeedback == nil || report.CommandLatency == nil || report.TaskOutcomes == nil {
+		t.Errorf("expected empty rollups, got %+v", report)
+	}
+	if len(report.Days) != 1 {
+		t.Errorf("days = %v", report.Days)
+	}
+}
+
+func TestTable(t *testing.T) {
+	report := Compute(testData(), since, at(48), at(60))
+
+	header, rows, err := Table(report, MetricTaskOutcomes)
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if strings.Join(header, ",") != "type,total,completed,failed,open,failure_rate" {
+		t.Errorf("header = %v", header)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "bugfix,3,1,1,1,0.5" {
+		t.Errorf("rows = %v", rows)
+	}
+
+	_, rows, err = Table(report, MetricCommandLatency)
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if strings.Join(rows[1], ",") != "ghost,ghost,1,0,1.3,1.3,1.3,1.3" {
+		t.Errorf("command latency rows = %v", rows)
+	}
+
+	for _, metric := range Metrics {
+		if _, _, err := Table(report, metric); err != nil {
+			t.Errorf("Table(%s) failed: %v", metric, err)
+		}
+		if _, err := Rows(report, metric); err != nil {
+			t.Errorf("Rows(%s) failed: %v", metric, err)
+		}
+	}
+	if _, _, err := Table(report, "uptime"); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+	if _, err := Rows(report, "uptime"); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
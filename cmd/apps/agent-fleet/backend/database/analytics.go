@@ -0,0 +1,39 @@
+package database
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+// Analytics operations. The fleets this is made for are small enough that
+// the rollups are computed from the full tables.
+
+// ListAgentStatusChanges returns the status history of all agents, in the
+// order the changes happened
+func (db *DB) ListAgentStatusChanges() ([]models.AgentStatusChange, error) {
+	changes := []models.AgentStatusChange{}
+	err := db.Select(&changes, "SELECT * FROM agent_status_changes ORDER BY id ASC")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list agent status changes")
+	}
+	return changes, nil
+}
+
+func (db *DB) ListAllTasks() ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := db.Select(&tasks, "SELECT * FROM tasks ORDER BY created_at ASC")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	return tasks, nil
+}
+
+func (db *DB) ListAllCommands() ([]models.Command, error) {
+	commands := []models.Command{}
+	err := db.Select(&commands, "SELECT * FROM commands ORDER BY sent_at ASC")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list commands")
+	}
+	return commands, nil
+}
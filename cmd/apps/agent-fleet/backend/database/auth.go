@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+// Token operations
+
+// CreateAPIToken stores a new token. Only the hash and the prefix of the
+// token are kept, the caller shows the token to the user once.
+func (db *DB) CreateAPIToken(req models.CreateTokenRequest, prefix, hash string) (*models.APIToken, error) {
+	token := &models.APIToken{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Role:        string(models.RoleOperator),
+		AgentID:     req.AgentID,
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+		CreatedAt:   time.Now(),
+	}
+	if token.AgentID != nil {
+		token.Role = string(models.RoleAgent)
+	}
+
+	query := `
+		INSERT INTO api_tokens (id, name, role, agent_id, token_prefix, token_hash, created_at)
+		VALUES (:id, :name, :role, :agent_id, :token_prefix, :token_hash, :created_at)
+	`
+	_, err := db.NamedExec(query, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create token")
+	}
+
+	return token, nil
+}
+
+// GetAPITokenByHash returns the token with the given hash, revoked or not
+func (db *DB) GetAPITokenByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := db.Get(&token, "SELECT * FROM api_tokens WHERE token_hash = ?", hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get token")
+	}
+	return &token, nil
+}
+
+func (db *DB) ListAPITokens(agentID string) ([]models.APIToken, error) {
+	tokens := []models.APIToken{}
+	query := "SELECT * FROM api_tokens"
+	var args []interface{}
+	if agentID != "" {
+		query += " WHERE agent_id = ?"
+		args = append(args, agentID)
+	}
+	query += " ORDER BY created_at DESC"
+
+	err := db.Select(&tokens, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tokens")
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes a token. Revoking it again keeps the first
+// revocation time.
+func (db *DB) RevokeAPIToken(id string) (*models.APIToken, error) {
+	result, err := db.Exec("UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to revoke token")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	var token models.APIToken
+	if err := db.Get(&token, "SELECT * FROM api_tokens WHERE id = ?", id); err != nil {
+		return nil, errors.Wrap(err, "failed to get token")
+	}
+	return &token, nil
+}
+
+func (db *DB) TouchAPIToken(id string) error {
+	_, err := db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update token last use")
+	}
+	return nil
+}
+
+// Audit log operations
+
+func (db *DB) CreateAuditEntry(entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (timestamp, token_id, principal, role, method, path, route, status, duration_ms, remote_addr)
+		VALUES (:timestamp, :token_id, :principal, :role, :method, :path, :route, :status, :duration_ms, :remote_addr)
+	`
+	result, err := db.NamedExec(query, entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to create audit entry")
+	}
+
+	entry.ID, err = result.LastInsertId()
+	if err != nil {
+		return errors.Wrap(err, "failed to get audit entry id")
+	}
+	return nil
+}
+
+func (db *DB) ListAuditEntries(principal string, since *time.Time, limit, offset int) ([]models.AuditEntry, int, error) {
+	entries := []models.AuditEntry{}
+	var total int
+
+	whereClauses := []string{}
+	args := []interface{}{}
+
+	if principal != "" {
+		whereClauses = append(whereClauses, "principal = ?")
+		args = append(args, principal)
+	}
+	if since != nil {
+		whereClauses = append(whereClauses, "timestamp >= ?")
+		args = append(args, since)
+	}
+
+	whereClause := ""
+	if len(whereClauses) > 0 {
+		whereClause = " WHERE " + joinStrings(whereClauses, " AND ")
+	}
+
+	err := db.Get(&total, "SELECT COUNT(*) FROM audit_log"+whereClause, args...)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to count audit entries")
+	}
+
+	query := "SELECT * FROM audit_log" + whereClause + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	listArgs := append(args, limit, offset)
+
+	err = db.Select(&entries, query, listArgs...)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list audit entries")
+	}
+
+	return entries, total, nil
+}
@@ -100,6 +100,42 @@ func (db *DB) migrate() error {
 		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
 	);
 
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		role TEXT NOT NULL,
+		agent_id TEXT,
+		token_prefix TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		revoked_at DATETIME,
+		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		token_id TEXT,
+		principal TEXT NOT NULL,
+		role TEXT NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		route TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		remote_addr TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS agent_status_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		agent_id TEXT NOT NULL,
+		old_status TEXT,
+		new_status TEXT NOT NULL,
+		changed_at DATETIME NOT NULL,
+		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
+	);
+
 	-- Indexes
 	CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
 	CREATE INDEX IF NOT EXISTS idx_events_agent_id ON events(agent_id);
@@ -111,6 +147,26 @@ func (db *DB) migrate() error {
 	CREATE INDEX IF NOT EXISTS idx_tasks_assigned_agent_id ON tasks(assigned_agent_id);
 	CREATE INDEX IF NOT EXISTS idx_commands_agent_id ON commands(agent_id);
 	CREATE INDEX IF NOT EXISTS idx_commands_status ON commands(status);
+	CREATE INDEX IF NOT EXISTS idx_api_tokens_agent_id ON api_tokens(agent_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_principal ON audit_log(principal);
+	CREATE INDEX IF NOT EXISTS idx_agent_status_changes_agent_id ON agent_status_changes(agent_id, changed_at);
+
+	-- Status history for analytics, whoever changes the status
+	CREATE TRIGGER IF NOT EXISTS record_agent_created
+		AFTER INSERT ON agents
+		BEGIN
+			INSERT INTO agent_status_changes (agent_id, old_status, new_status, changed_at)
+			VALUES (NEW.id, NULL, NEW.status, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END;
+
+	CREATE TRIGGER IF NOT EXISTS record_agent_status_change
+		AFTER UPDATE OF status ON agents
+		WHEN OLD.status <> NEW.status
+		BEGIN
+			INSERT INTO agent_status_changes (agent_id, old_status, new_status, changed_at)
+			VALUES (NEW.id, OLD.status, NEW.status, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END;
 
 	-- Trigger to update updated_at on agents
 	CREATE TRIGGER IF NOT EXISTS update_agents_updated_at 
@@ -120,29 +176,76 @@ func (db *DB) migrate() error {
 		END;
 	`
 
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the first release, for databases created before
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"agents", "capabilities", "TEXT NOT NULL DEFAULT '[]'"},
+		{"agents", "max_concurrency", "INTEGER NOT NULL DEFAULT 1"},
+		{"agents", "last_heartbeat", "DATETIME"},
+		{"tasks", "required_capabilities", "TEXT NOT NULL DEFAULT '[]'"},
+		{"tasks", "type", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := db.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
+	var count int
+	err := db.Get(&count, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column)
+	if err != nil {
+		return errors.Wrapf(err, "failed to inspect table %s", table)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return errors.Wrapf(err, "failed to add column %s.%s", table, column)
+	}
+	return nil
 }
 
 // Agent operations
 
 func (db *DB) CreateAgent(req models.CreateAgentRequest) (*models.Agent, error) {
+	now := time.Now()
 	agent := &models.Agent{
-		ID:           uuid.New().String(),
-		Name:         req.Name,
-		Status:       string(models.AgentStatusIdle),
-		Worktree:     req.Worktree,
-		FilesChanged: 0,
-		LinesAdded:   0,
-		LinesRemoved: 0,
-		Progress:     0,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:             uuid.New().String(),
+		Name:           req.Name,
+		Status:         string(models.AgentStatusIdle),
+		Worktree:       req.Worktree,
+		FilesChanged:   0,
+		LinesAdded:     0,
+		LinesRemoved:   0,
+		Progress:       0,
+		Capabilities:   models.StringList(req.Capabilities),
+		MaxConcurrency: req.MaxConcurrency,
+		LastHeartbeat:  &now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if agent.MaxConcurrency <= 0 {
+		agent.MaxConcurrency = 1
+	}
+	if agent.Capabilities == nil {
+		agent.Capabilities = models.StringList{}
 	}
 
 	query := `
-		INSERT INTO agents (id, name, status, worktree, files_changed, lines_added, lines_removed, progress, created_at, updated_at)
-		VALUES (:id, :name, :status, :worktree, :files_changed, :lines_added, :lines_removed, :progress, :created_at, :updated_at)
+		INSERT INTO agents (id, name, status, worktree, files_changed, lines_added, lines_removed, progress, capabilities, max_concurrency, last_heartbeat, created_at, updated_at)
+		VALUES (:id, :name, :status, :worktree, :files_changed, :lines_added, :lines_removed, :progress, :capabilities, :max_concurrency, :last_heartbeat, :created_at, :updated_at)
 	`
 	_, err := db.NamedExec(query, agent)
 	if err != nil {
@@ -241,6 +344,14 @@ func (db *DB) UpdateAgent(id string, req models.UpdateAgentRequest) (*models.Age
 		setParts = append(setParts, "pending_question = :pending_question")
 		args["pending_question"] = *req.PendingQuestion
 	}
+	if req.Capabilities != nil {
+		setParts = append(setParts, "capabilities = :capabilities")
+		args["capabilities"] = models.StringList(*req.Capabilities)
+	}
+	if req.MaxConcurrency != nil {
+		setParts = append(setParts, "max_concurrency = :max_concurrency")
+		args["max_concurrency"] = *req.MaxConcurrency
+	}
 
 	if len(setParts) == 0 {
 		return db.GetAgent(id)
@@ -255,6 +366,25 @@ func (db *DB) UpdateAgent(id string, req models.UpdateAgentRequest) (*models.Age
 	return db.GetAgent(id)
 }
 
+// TouchAgentHeartbeat records that an agent is alive
+func (db *DB) TouchAgentHeartbeat(id string) error {
+	result, err := db.Exec("UPDATE agents SET last_heartbeat = ? WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update agent heartbeat")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 func (db *DB) DeleteAgent(id string) error {
 	query := "DELETE FROM agents WHERE id = ?"
 	result, err := db.Exec(query, id)
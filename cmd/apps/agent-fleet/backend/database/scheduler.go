@@ -0,0 +1,73 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+// Scheduler operations
+
+// ListAllAgents returns every agent, oldest first
+func (db *DB) ListAllAgents() ([]models.Agent, error) {
+	var agents []models.Agent
+	err := db.Select(&agents, "SELECT * FROM agents ORDER BY created_at ASC")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list agents")
+	}
+	return agents, nil
+}
+
+// ListOpenTasks returns the pending, assigned and in progress tasks, oldest
+// first
+func (db *DB) ListOpenTasks() ([]models.Task, error) {
+	var tasks []models.Task
+	query := "SELECT * FROM tasks WHERE status IN (?, ?, ?) ORDER BY created_at ASC"
+	err := db.Select(&tasks, query,
+		models.TaskStatusPending, models.TaskStatusAssigned, models.TaskStatusInProgress)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list open tasks")
+	}
+	return tasks, nil
+}
+
+// AssignPendingTask assigns a task to an agent if it is still pending. It
+// returns false if the task was assigned or deleted in the meantime.
+func (db *DB) AssignPendingTask(taskID, agentID string) (bool, error) {
+	query := `
+		UPDATE tasks SET assigned_agent_id = ?, status = ?, assigned_at = ?
+		WHERE id = ? AND status = ?
+	`
+	result, err := db.Exec(query, agentID, models.TaskStatusAssigned, time.Now(), taskID, models.TaskStatusPending)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to assign task")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get rows affected")
+	}
+	return rowsAffected > 0, nil
+}
+
+// ReleaseTask puts a task assigned to an agent back into the pending queue.
+// It returns false if the task is no longer open on that agent.
+func (db *DB) ReleaseTask(taskID, agentID string) (bool, error) {
+	query := `
+		UPDATE tasks SET assigned_agent_id = NULL, status = ?, assigned_at = NULL
+		WHERE id = ? AND assigned_agent_id = ? AND status IN (?, ?)
+	`
+	result, err := db.Exec(query, models.TaskStatusPending, taskID, agentID,
+		models.TaskStatusAssigned, models.TaskStatusInProgress)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to release task")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get rows affected")
+	}
+	return rowsAffected > 0, nil
+}
@@ -21,7 +21,13 @@ func (db *DB) CreateTask(req models.CreateTaskRequest) (*models.Task, error) {
 		AssignedAgentID: req.AssignedAgentID,
 		Status:          string(models.TaskStatusPending),
 		Priority:        req.Priority,
+		Type:            req.Type,
 		CreatedAt:       time.Now(),
+
+		RequiredCapabilities: models.StringList(req.RequiredCapabilities),
+	}
+	if task.RequiredCapabilities == nil {
+		task.RequiredCapabilities = models.StringList{}
 	}
 
 	// If assigned to an agent, set assigned_at and status
@@ -32,10 +38,10 @@ func (db *DB) CreateTask(req models.CreateTaskRequest) (*models.Task, error) {
 	}
 
 	query := `
-		INSERT INTO tasks (id, title, description, assigned_agent_id, status, priority, created_at, assigned_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO tasks (id, title, description, assigned_agent_id, status, priority, type, required_capabilities, created_at, assigned_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := db.Exec(query, task.ID, task.Title, task.Description, task.AssignedAgentID, task.Status, task.Priority, task.CreatedAt, task.AssignedAt)
+	_, err := db.Exec(query, task.ID, task.Title, task.Description, task.AssignedAgentID, task.Status, task.Priority, task.Type, task.RequiredCapabilities, task.CreatedAt, task.AssignedAt)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create task")
 	}
@@ -128,6 +134,14 @@ func (db *DB) UpdateTask(id string, req models.UpdateTaskRequest) (*models.Task,
 		setParts = append(setParts, "priority = :priority")
 		args["priority"] = *req.Priority
 	}
+	if req.Type != nil {
+		setParts = append(setParts, "type = :type")
+		args["type"] = *req.Type
+	}
+	if req.RequiredCapabilities != nil {
+		setParts = append(setParts, "required_capabilities = :required_capabilities")
+		args["required_capabilities"] = models.StringList(*req.RequiredCapabilities)
+	}
 	if req.AssignedAgentID != nil {
 		setParts = append(setParts, "assigned_agent_id = :assigned_agent_id")
 		args["assigned_agent_id"] = *req.AssignedAgentID
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/analytics"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+// Analytics handlers
+
+// analyticsReport computes the report for the since/until/days query
+// parameters
+func (h *Handlers) analyticsReport(r *http.Request) (*models.AnalyticsReport, error) {
+	data, err := analytics.Load(h.db)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	since, until := analytics.Window(
+		parseQueryTime(r, "since"),
+		parseQueryTime(r, "until"),
+		parseQueryInt(r, "days", analytics.DefaultDays),
+		now,
+	)
+	return analytics.Compute(data, since, until, now), nil
+}
+
+func (h *Handlers) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	report, err := h.analyticsReport(r)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to compute analytics")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to compute analytics")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, report)
+}
+
+func (h *Handlers) ExportAnalytics(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = analytics.MetricTasksCompleted
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_FORMAT", "Format must be csv or json")
+		return
+	}
+
+	report, err := h.analyticsReport(r)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to compute analytics")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to compute analytics")
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s.%s", metric, report.Since.Format("20060102"), report.Until.Format("20060102"), format)
+
+	if format == "json" {
+		rows, err := analytics.Rows(report, metric)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_METRIC", err.Error())
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		writeJSONResponse(w, http.StatusOK, rows)
+		return
+	}
+
+	header, rows, err := analytics.Table(report, metric)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_METRIC", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		log.Error().Err(err).Msg("Failed to write analytics export")
+		return
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		log.Error().Err(err).Msg("Failed to write analytics export")
+	}
+}
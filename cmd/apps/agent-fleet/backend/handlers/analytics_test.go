@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+func TestExportAnalytics(t *testing.T) {
+	s := newTestServer(t, auth.Config{OperatorToken: testOperatorToken})
+	for _, status := range []string{"completed", "failed", "failed"} {
+		task, err := s.db.CreateTask(models.CreateTaskRequest{Title: "fix", Description: "fix it", Priority: "low", Type: "bugfix"})
+		if err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+		if _, err := s.db.UpdateTask(task.ID, models.UpdateTaskRequest{Status: &status}); err != nil {
+			t.Fatalf("UpdateTask failed: %v", err)
+		}
+	}
+
+	// The API takes the bearer token, the web interface the session cookie
+	api := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/analytics/export"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testOperatorToken)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+	web := func(query string) *httptest.ResponseRecorder {
+		return s.get("/analytics/export"+query, testOperatorToken)
+	}
+
+	for name, export := range map[string]func(string) *httptest.ResponseRecorder{"api": api, "web": web} {
+		t.Run(name, func(t *testing.T) {
+			rec := export("?metric=task_outcomes&format=csv&days=7")
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+				t.Fatalf("csv: status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if d := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(d, `attachment; filename="task_outcomes_`) || !strings.HasSuffix(d, `.csv"`) {
+				t.Errorf("content disposition = %q", d)
+			}
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("failed to read csv: %v", err)
+			}
+			want := [][]string{
+				{"type", "total", "completed", "failed", "open", "failure_rate"},
+				{"bugfix", "3", "1", "2", "0", "0.667"},
+			}
+			if !reflect.DeepEqual(records, want) {
+				t.Errorf("csv = %v, want %v", records, want)
+			}
+
+			rec = export("?metric=task_outcomes&format=json")
+			var outcomes []models.TaskTypeOutcome
+			if rec.Code != http.StatusOK {
+				t.Fatalf("json: status %d", rec.Code)
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &outcomes); err != nil {
+				t.Fatalf("failed to decode json: %v", err)
+			}
+			if len(outcomes) != 1 || outcomes[0].Failed != 2 {
+				t.Errorf("outcomes = %+v", outcomes)
+			}
+
+			// Tasks completed is the default metric
+			if rec := export(""); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "day,agent_id,agent_name,count\n") {
+				t.Errorf("default export: status %d, body %q", rec.Code, rec.Body.String())
+			}
+			if rec := export("?format=xml"); rec.Code != http.StatusBadRequest {
+				t.Errorf("xml export: status %d", rec.Code)
+			}
+			if rec := export("?metric=uptime"); rec.Code != http.StatusBadRequest {
+				t.Errorf("unknown metric: status %d", rec.Code)
+			}
+		})
+	}
+
+	// Same auth as the page it is linked from
+	if rec := s.get("/analytics/export?format=csv", ""); rec.Code != http.StatusSeeOther {
+		t.Errorf("export without session: status %d", rec.Code)
+	}
+	if rec := s.get("/analytics", testOperatorToken); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/analytics/export?format=csv&amp;metric=task_outcomes"`) {
+		t.Errorf("analytics page: status %d, export links missing", rec.Code)
+	}
+}
@@ -11,19 +11,26 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/database"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/scheduler"
 )
 
 type Handlers struct {
-	db  *database.DB
-	sse *SSEManager
+	db        *database.DB
+	sse       *SSEManager
+	scheduler *scheduler.Scheduler
+	auth      *auth.Authenticator
 }
 
-func New(db *database.DB) *Handlers {
-	return &Handlers{
-		db:  db,
-		sse: NewSSEManager(),
+func New(db *database.DB, sched *scheduler.Scheduler) *Handlers {
+	h := &Handlers{
+		db:        db,
+		sse:       NewSSEManager(),
+		scheduler: sched,
 	}
+	sched.SetNotifier(h.sse)
+	return h
 }
 
 // Helper functions
@@ -142,6 +149,10 @@ func (h *Handlers) CreateAgent(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusBadRequest, "MISSING_WORKTREE", "Agent worktree is required")
 		return
 	}
+	if req.MaxConcurrency < 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_MAX_CONCURRENCY", "Max concurrency must be at least 1")
+		return
+	}
 
 	agent, err := h.db.CreateAgent(req)
 	if err != nil {
@@ -160,6 +171,9 @@ func (h *Handlers) CreateAgent(w http.ResponseWriter, r *http.Request) {
 	// Broadcast agent creation
 	h.sse.BroadcastAgentStatusChanged(agent.ID, "", agent.Status, agent)
 
+	// A new idle agent may take pending tasks
+	h.scheduler.Trigger()
+
 	writeJSONResponse(w, http.StatusCreated, agent)
 }
 
@@ -187,6 +201,18 @@ func (h *Handlers) UpdateAgent(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON in request body")
 		return
 	}
+	if req.MaxConcurrency != nil && *req.MaxConcurrency < 1 {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_MAX_CONCURRENCY", "Max concurrency must be at least 1")
+		return
+	}
+	// Agents report their state, what the scheduler gives them is up to the
+	// operators
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil && !principal.IsOperator() {
+		if req.Name != nil || req.Worktree != nil || req.Capabilities != nil || req.MaxConcurrency != nil {
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "Agents may only update their status and progress")
+			return
+		}
+	}
 
 	agent, err := h.db.UpdateAgent(agentID, req)
 	if err != nil {
@@ -195,6 +221,11 @@ func (h *Handlers) UpdateAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An agent reporting its state is alive
+	if err := h.db.TouchAgentHeartbeat(agentID); err != nil {
+		log.Warn().Err(err).Str("agentID", agentID).Msg("Failed to update agent heartbeat")
+	}
+
 	// Log significant changes
 	changes := []string{}
 	if req.Status != nil && *req.Status != currentAgent.Status {
@@ -236,6 +267,11 @@ func (h *Handlers) UpdateAgent(w http.ResponseWriter, r *http.Request) {
 		h.sse.BroadcastAgentQuestionPosted(agent.ID, *req.PendingQuestion, agent)
 	}
 
+	// Reschedule if the agent can take other tasks now
+	if (req.Status != nil && *req.Status != currentAgent.Status) || req.Capabilities != nil || req.MaxConcurrency != nil {
+		h.scheduler.Trigger()
+	}
+
 	writeJSONResponse(w, http.StatusOK, agent)
 }
 
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
+)
+
+// RegisterRoutes adds the API and the web interface to a router. API calls
+// authenticate with a bearer token, the web interface with the session cookie
+// set by the login page.
+func (h *Handlers) RegisterRoutes(r chi.Router, authenticator *auth.Authenticator) {
+	h.auth = authenticator
+
+	r.Route("/v1", func(r chi.Router) {
+		// Authentication and audit log for API routes. With auth disabled
+		// every caller is an operator.
+		r.Use(authenticator.Middleware)
+
+		// Agents
+		r.Route("/agents", func(r chi.Router) {
+			// Fleet management is for operators
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireOperator)
+				r.Get("/", h.ListAgents)
+				r.Post("/", h.CreateAgent)
+				r.Delete("/{agentID}", h.DeleteAgent)
+				r.Post("/{agentID}/commands", h.CreateAgentCommand)
+			})
+
+			// An agent may work on its own resources
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireAgentAccess)
+				r.Get("/{agentID}", h.GetAgent)
+				r.Patch("/{agentID}", h.UpdateAgent)
+				r.Post("/{agentID}/heartbeat", h.AgentHeartbeat)
+
+				// Agent events
+				r.Get("/{agentID}/events", h.ListAgentEvents)
+				r.Post("/{agentID}/events", h.CreateAgentEvent)
+
+				// Agent todos
+				r.Get("/{agentID}/todos", h.ListAgentTodos)
+				r.Post("/{agentID}/todos", h.CreateAgentTodo)
+				r.Patch("/{agentID}/todos/{todoID}", h.UpdateAgentTodo)
+				r.Delete("/{agentID}/todos/{todoID}", h.DeleteAgentTodo)
+
+				// Agent commands
+				r.Get("/{agentID}/commands", h.ListAgentCommands)
+				r.Patch("/{agentID}/commands/{commandID}", h.UpdateAgentCommand)
+			})
+		})
+
+		// Tasks, agents only see and update the tasks assigned to them
+		r.Route("/tasks", func(r chi.Router) {
+			r.Get("/", h.ListTasks)
+			r.Get("/{taskID}", h.GetTask)
+			r.Patch("/{taskID}", h.UpdateTask)
+			r.With(auth.RequireOperator).Post("/", h.CreateTask)
+			r.With(auth.RequireOperator).Delete("/{taskID}", h.DeleteTask)
+		})
+
+		// Everything else is for operators
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireOperator)
+
+			// Scheduler
+			r.Route("/scheduler", func(r chi.Router) {
+				r.Get("/status", h.GetSchedulerStatus)
+				r.Post("/run", h.RunScheduler)
+			})
+
+			// Fleet operations
+			r.Route("/fleet", func(r chi.Router) {
+				r.Get("/status", h.GetFleetStatus)
+				r.Get("/recent-updates", h.GetRecentUpdates)
+			})
+
+			// Tokens
+			r.Route("/tokens", func(r chi.Router) {
+				r.Get("/", h.ListTokens)
+				r.Post("/", h.CreateToken)
+				r.Delete("/{tokenID}", h.RevokeToken)
+			})
+
+			// Audit log
+			r.Get("/audit", h.ListAuditLog)
+
+			// Analytics
+			r.Route("/analytics", func(r chi.Router) {
+				r.Get("/", h.GetAnalytics)
+				r.Get("/export", h.ExportAnalytics)
+			})
+
+			// Server-Sent Events
+			r.Get("/stream", h.SSEHandler)
+		})
+	})
+
+	// Web interface, the pages show operator data
+	r.Get("/login", h.LoginPage)
+	r.Post("/login", h.Login)
+	r.Post("/logout", h.Logout)
+	r.Group(func(r chi.Router) {
+		r.Use(authenticator.WebMiddleware)
+		r.Get("/", h.IndexPage)
+		r.Get("/agents", h.AgentsPage)
+		r.Get("/analytics", h.AnalyticsPage)
+		r.Get("/analytics/export", h.ExportAnalytics)
+
+		// Live updates for the pages, which can't send a bearer token
+		r.Get("/stream", h.SSEHandler)
+	})
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/database"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/scheduler"
+)
+
+const testOperatorToken = "test-operator-token"
+
+type testServer struct {
+	t      *testing.T
+	db     *database.DB
+	router chi.Router
+}
+
+func newTestServer(t *testing.T, config auth.Config) *testServer {
+	t.Helper()
+	db, err := database.Initialize(filepath.Join(t.TempDir(), "fleet.db"))
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := New(db, scheduler.New(db, scheduler.Config{}))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r, auth.New(db, config))
+	return &testServer{t: t, db: db, router: r}
+}
+
+// do sends a request with a bearer token and decodes the JSON response into
+// out, if given
+func (s *testServer) do(method, path, token string, body interface{}, out interface{}) int {
+	s.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func (s *testServer) createAgent(name string) string {
+	s.t.Helper()
+	agent, err := s.db.CreateAgent(models.CreateAgentRequest{Name: name, Worktree: "/tmp/" + name})
+	if err != nil {
+		s.t.Fatalf("CreateAgent failed: %v", err)
+	}
+	return agent.ID
+}
+
+// issueToken issues a token through the API, for an agent if agentID is set
+func (s *testServer) issueToken(name, agentID string) models.CreateTokenResponse {
+	s.t.Helper()
+	req := models.CreateTokenRequest{Name: name}
+	if agentID != "" {
+		req.AgentID = &agentID
+	}
+	var token models.CreateTokenResponse
+	if code := s.do("POST", "/v1/tokens", testOperatorToken, req, &token); code != http.StatusCreated {
+		s.t.Fatalf("issuing token %s: status %d", name, code)
+	}
+	return token
+}
+
+func TestTokenScopes(t *testing.T) {
+	s := newTestServer(t, auth.Config{OperatorToken: testOperatorToken})
+	agentA := s.createAgent("agent-a")
+	agentB := s.createAgent("agent-b")
+
+	operator := s.issueToken("ops", "").Token
+	tokenA := s.issueToken("agent-a", agentA).Token
+	revoked := s.issueToken("old-agent-a", agentA)
+	if code := s.do("DELETE", "/v1/tokens/"+revoked.ID, operator, nil, nil); code != http.StatusOK {
+		t.Fatalf("revoking token: status %d", code)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		want   int
+	}{
+		{"no token", "GET", "/v1/agents", "", nil, http.StatusUnauthorized},
+		{"unknown token", "GET", "/v1/agents", "afl_unknown", nil, http.StatusUnauthorized},
+		{"revoked token", "GET", "/v1/agents/" + agentA, revoked.Token, nil, http.StatusUnauthorized},
+
+		{"bootstrap operator lists agents", "GET", "/v1/agents", testOperatorToken, nil, http.StatusOK},
+		{"issued operator lists agents", "GET", "/v1/agents", operator, nil, http.StatusOK},
+		{"operator reads any agent", "GET", "/v1/agents/" + agentB, operator, nil, http.StatusOK},
+		{"operator sets capabilities", "PATCH", "/v1/agents/" + agentB, operator, map[string]interface{}{"capabilities": []string{"go"}}, http.StatusOK},
+
+		{"agent lists agents", "GET", "/v1/agents", tokenA, nil, http.StatusForbidden},
+		{"agent issues tokens", "POST", "/v1/tokens", tokenA, models.CreateTokenRequest{Name: "more"}, http.StatusForbidden},
+		{"agent reads the audit log", "GET", "/v1/audit", tokenA, nil, http.StatusForbidden},
+		{"agent reads itself", "GET", "/v1/agents/" + agentA, tokenA, nil, http.StatusOK},
+		{"agent sends a heartbeat", "POST", "/v1/agents/" + agentA + "/heartbeat", tokenA, nil, http.StatusNoContent},
+		{"agent reports status", "PATCH", "/v1/agents/" + agentA, tokenA, map[string]interface{}{"status": "active", "progress": 40, "files_changed": 3}, http.StatusOK},
+		{"agent widens capabilities", "PATCH", "/v1/agents/" + agentA, tokenA, map[string]interface{}{"capabilities": []string{"go", "deploy"}}, http.StatusForbidden},
+		{"agent raises max concurrency", "PATCH", "/v1/agents/" + agentA, tokenA, map[string]interface{}{"status": "idle", "max_concurrency": 10}, http.StatusForbidden},
+		{"agent renames itself", "PATCH", "/v1/agents/" + agentA, tokenA, map[string]interface{}{"name": "ops"}, http.StatusForbidden},
+
+		{"wrong agent reads", "GET", "/v1/agents/" + agentB, tokenA, nil, http.StatusForbidden},
+		{"wrong agent updates", "PATCH", "/v1/agents/" + agentB, tokenA, map[string]interface{}{"status": "error"}, http.StatusForbidden},
+		{"wrong agent reads commands", "GET", "/v1/agents/" + agentB + "/commands", tokenA, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.do(tt.method, tt.path, tt.token, tt.body, nil); got != tt.want {
+				t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Rejected updates leave the agent alone
+	var agent models.Agent
+	s.do("GET", "/v1/agents/"+agentA, operator, nil, &agent)
+	if len(agent.Capabilities) != 0 || agent.MaxConcurrency != 1 || agent.Name != "agent-a" {
+		t.Errorf("agent changed by rejected updates: %+v", agent)
+	}
+	if agent.Status != "active" || agent.Progress != 40 {
+		t.Errorf("status update not applied: status %s, progress %d", agent.Status, agent.Progress)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	s := newTestServer(t, auth.Config{OperatorToken: testOperatorToken})
+	agentA := s.createAgent("agent-a")
+	agentB := s.createAgent("agent-b")
+	tokenA := s.issueToken("agent-a", agentA)
+
+	s.do("GET", "/v1/agents/"+agentA, tokenA.Token, nil, nil)
+	s.do("GET", "/v1/agents/"+agentB, tokenA.Token, nil, nil)
+	s.do("GET", "/v1/agents", "", nil, nil)
+
+	var agentLog models.AuditListResponse
+	if code := s.do("GET", "/v1/audit?principal="+url.QueryEscape("agent:"+agentA), testOperatorToken, nil, &agentLog); code != http.StatusOK {
+		t.Fatalf("listing audit log: status %d", code)
+	}
+	if len(agentLog.Entries) != 2 {
+		t.Fatalf("expected 2 entries for the agent, got %d", len(agentLog.Entries))
+	}
+	statuses := map[string]int{}
+	for _, e := range agentLog.Entries {
+		statuses[e.Path] = e.Status
+		if e.Role != string(models.RoleAgent) || e.TokenID == nil || *e.TokenID != tokenA.ID {
+			t.Errorf("entry %s: role %s, token %v", e.Path, e.Role, e.TokenID)
+		}
+		if e.Route != "/v1/agents/{agentID}" {
+			t.Errorf("entry %s: route %q", e.Path, e.Route)
+		}
+	}
+	if statuses["/v1/agents/"+agentA] != http.StatusOK || statuses["/v1/agents/"+agentB] != http.StatusForbidden {
+		t.Errorf("statuses = %v", statuses)
+	}
+
+	var anonymous models.AuditListResponse
+	s.do("GET", "/v1/audit?principal=anonymous", testOperatorToken, nil, &anonymous)
+	if len(anonymous.Entries) != 1 || anonymous.Entries[0].Status != http.StatusUnauthorized || anonymous.Entries[0].Route != "" {
+		t.Errorf("anonymous entries = %+v", anonymous.Entries)
+	}
+}
+
+// get requests a web page with the session cookie, if given
+func (s *testServer) get(path, cookie string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: cookie})
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *testServer) login(token, next string) *httptest.ResponseRecorder {
+	form := url.Values{"token": {token}, "next": {next}}
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebInterfaceAuth(t *testing.T) {
+	s := newTestServer(t, auth.Config{OperatorToken: testOperatorToken})
+	agentA := s.createAgent("agent-a")
+	tokenA := s.issueToken("agent-a", agentA).Token
+
+	for _, path := range []string{"/", "/agents", "/analytics", "/stream"} {
+		for _, cookie := range []string{"", tokenA, "afl_unknown"} {
+			rec := s.get(path, cookie)
+			if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login?next="+url.QueryEscape(path) {
+				t.Errorf("%s with cookie %q: status %d, location %q", path, cookie, rec.Code, rec.Header().Get("Location"))
+			}
+		}
+	}
+	if rec := s.get("/agents", testOperatorToken); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "agent-a") {
+		t.Errorf("operator agents page: status %d", rec.Code)
+	}
+
+	if rec := s.login(tokenA, "/agents"); rec.Code != http.StatusForbidden || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("agent login: status %d, cookies %v", rec.Code, rec.Result().Cookies())
+	}
+	if rec := s.login("afl_unknown", "/agents"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token login: status %d", rec.Code)
+	}
+
+	rec := s.login(testOperatorToken, "//evil.example/agents")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Errorf("operator login: status %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.SessionCookie || cookies[0].Value != testOperatorToken || !cookies[0].HttpOnly {
+		t.Fatalf("operator login cookies = %v", cookies)
+	}
+	if rec := s.get("/", cookies[0].Value); rec.Code != http.StatusOK {
+		t.Errorf("dashboard after login: status %d", rec.Code)
+	}
+}
+
+func TestWebInterfaceAuthDisabled(t *testing.T) {
+	s := newTestServer(t, auth.Config{Disabled: true})
+	if rec := s.get("/agents", ""); rec.Code != http.StatusOK {
+		t.Errorf("agents page: status %d", rec.Code)
+	}
+	if rec := s.get("/login?next=/agents", ""); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/agents" {
+		t.Errorf("login page: status %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Scheduler handlers
+
+func (h *Handlers) AgentHeartbeat(w http.ResponseWriter, r *http.Request) {
+	agentID := chi.URLParam(r, "agentID")
+	if agentID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "MISSING_AGENT_ID", "Agent ID is required")
+		return
+	}
+
+	err := h.db.TouchAgentHeartbeat(agentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeErrorResponse(w, http.StatusNotFound, "AGENT_NOT_FOUND", "Agent not found")
+			return
+		}
+		log.Error().Err(err).Str("agentID", agentID).Msg("Failed to update agent heartbeat")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update agent heartbeat")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) RunScheduler(w http.ResponseWriter, r *http.Request) {
+	run, err := h.scheduler.Run()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to run scheduler")
+		writeErrorResponse(w, http.StatusInternalServerError, "SCHEDULER_ERROR", "Failed to run scheduler")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, run)
+}
+
+func (h *Handlers) GetSchedulerStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, h.scheduler.Status())
+}
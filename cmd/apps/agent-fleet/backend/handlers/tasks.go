@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
 )
 
@@ -25,6 +26,11 @@ func (h *Handlers) ListTasks(w http.ResponseWriter, r *http.Request) {
 		limit = 100
 	}
 
+	// Agents only see their own tasks
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil && !principal.IsOperator() {
+		assignedAgentID = principal.AgentID
+	}
+
 	tasks, total, err := h.db.ListTasks(status, assignedAgentID, priority, limit, offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to list tasks")
@@ -58,7 +64,7 @@ func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if task == nil {
+	if task == nil || !canAccessTask(r, task) {
 		writeErrorResponse(w, http.StatusNotFound, "TASK_NOT_FOUND", "Task not found")
 		return
 	}
@@ -66,6 +72,16 @@ func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusOK, task)
 }
 
+// canAccessTask reports whether the caller is an operator or the agent the
+// task is assigned to
+func canAccessTask(r *http.Request, task *models.Task) bool {
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal == nil || principal.IsOperator() {
+		return true
+	}
+	return task.AssignedAgentID != nil && *task.AssignedAgentID == principal.AgentID
+}
+
 func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -102,9 +118,12 @@ func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Broadcast task assignment if assigned to an agent
+	// Broadcast task assignment if assigned to an agent, otherwise let the
+	// scheduler find one
 	if task.AssignedAgentID != nil {
 		h.sse.BroadcastTaskAssigned(task, *task.AssignedAgentID)
+	} else {
+		h.scheduler.Trigger()
 	}
 
 	writeJSONResponse(w, http.StatusCreated, task)
@@ -123,6 +142,25 @@ func (h *Handlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Agents may only update the status of their own tasks, or hand them back
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil && !principal.IsOperator() {
+		current, err := h.db.GetTask(taskID)
+		if err != nil {
+			log.Error().Err(err).Str("taskID", taskID).Msg("Failed to get task")
+			writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to get task")
+			return
+		}
+		if current == nil || !canAccessTask(r, current) {
+			writeErrorResponse(w, http.StatusNotFound, "TASK_NOT_FOUND", "Task not found")
+			return
+		}
+		if req.Title != nil || req.Description != nil || req.Priority != nil || req.Type != nil || req.RequiredCapabilities != nil ||
+			(req.AssignedAgentID != nil && *req.AssignedAgentID != "" && *req.AssignedAgentID != principal.AgentID) {
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "Agents may only update the status of their own tasks")
+			return
+		}
+	}
+
 	// Validate status if provided
 	if req.Status != nil {
 		switch *req.Status {
@@ -157,6 +195,11 @@ func (h *Handlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		h.sse.BroadcastTaskAssigned(task, *req.AssignedAgentID)
 	}
 
+	// Finished, requeued or reprioritized tasks change what can be scheduled
+	if req.Status != nil || req.Priority != nil || req.RequiredCapabilities != nil {
+		h.scheduler.Trigger()
+	}
+
 	writeJSONResponse(w, http.StatusOK, task)
 }
 
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+// Token handlers
+
+func (h *Handlers) ListTokens(w http.ResponseWriter, r *http.Request) {
+	agentID := r.URL.Query().Get("agent_id")
+
+	tokens, err := h.db.ListAPITokens(agentID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list tokens")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to list tokens")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, models.TokensListResponse{Tokens: tokens})
+}
+
+func (h *Handlers) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON in request body")
+		return
+	}
+
+	if req.Name == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "MISSING_NAME", "Token name is required")
+		return
+	}
+	if req.AgentID != nil {
+		agent, err := h.db.GetAgent(*req.AgentID)
+		if err != nil {
+			log.Error().Err(err).Str("agentID", *req.AgentID).Msg("Failed to get agent")
+			writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to get agent")
+			return
+		}
+		if agent == nil {
+			writeErrorResponse(w, http.StatusBadRequest, "AGENT_NOT_FOUND", "Agent not found")
+			return
+		}
+	}
+
+	secret, prefix, err := auth.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate token")
+		writeErrorResponse(w, http.StatusInternalServerError, "TOKEN_ERROR", "Failed to generate token")
+		return
+	}
+
+	token, err := h.db.CreateAPIToken(req, prefix, auth.HashToken(secret))
+	if err != nil {
+		log.Error().Err(err).Str("name", req.Name).Msg("Failed to create token")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to create token")
+		return
+	}
+
+	log.Info().
+		Str("token_id", token.ID).
+		Str("name", token.Name).
+		Str("role", token.Role).
+		Str("issued_by", auth.PrincipalFromContext(r.Context()).String()).
+		Msg("Token issued")
+
+	writeJSONResponse(w, http.StatusCreated, models.CreateTokenResponse{
+		Token:    secret,
+		APIToken: *token,
+	})
+}
+
+func (h *Handlers) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	tokenID := chi.URLParam(r, "tokenID")
+	if tokenID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "MISSING_TOKEN_ID", "Token ID is required")
+		return
+	}
+
+	token, err := h.db.RevokeAPIToken(tokenID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeErrorResponse(w, http.StatusNotFound, "TOKEN_NOT_FOUND", "Token not found")
+			return
+		}
+		log.Error().Err(err).Str("tokenID", tokenID).Msg("Failed to revoke token")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to revoke token")
+		return
+	}
+
+	log.Info().
+		Str("token_id", token.ID).
+		Str("name", token.Name).
+		Str("revoked_by", auth.PrincipalFromContext(r.Context()).String()).
+		Msg("Token revoked")
+
+	writeJSONResponse(w, http.StatusOK, token)
+}
+
+// Audit log handlers
+
+func (h *Handlers) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	principal := r.URL.Query().Get("principal")
+	since := parseQueryTime(r, "since")
+	limit := parseQueryInt(r, "limit", 100)
+	offset := parseQueryInt(r, "offset", 0)
+
+	// Enforce maximum limit
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	entries, total, err := h.db.ListAuditEntries(principal, since, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list audit log")
+		writeErrorResponse(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to list audit log")
+		return
+	}
+
+	response := models.AuditListResponse{
+		Entries: entries,
+		ListResponse: models.ListResponse{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}
+
+	writeJSONResponse(w, http.StatusOK, response)
+}
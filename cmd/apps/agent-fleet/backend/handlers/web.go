@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/templates"
 )
 
@@ -55,3 +57,71 @@ func (h *Handlers) AgentsPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *Handlers) AnalyticsPage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.analyticsReport(r)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to compute analytics for analytics page")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Render template
+	component := templates.Analytics(report, r.URL.Query().Get("days"))
+	err = component.Render(r.Context(), w)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render analytics template")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handlers) LoginPage(w http.ResponseWriter, r *http.Request) {
+	next := localPath(r.URL.Query().Get("next"))
+	if h.auth.Disabled() {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	h.renderLogin(w, r, http.StatusOK, next, "")
+}
+
+// Login checks an operator token and keeps it in the session cookie
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	next := localPath(r.PostFormValue("next"))
+	token := strings.TrimSpace(r.PostFormValue("token"))
+
+	principal, _, message := h.auth.Resolve(token)
+	if principal == nil {
+		h.renderLogin(w, r, http.StatusUnauthorized, next, message)
+		return
+	}
+	if !principal.IsOperator() {
+		h.renderLogin(w, r, http.StatusForbidden, next, "Operator token required")
+		return
+	}
+
+	log.Info().Str("principal", principal.String()).Msg("Signed in to the web interface")
+	auth.SetSessionCookie(w, r, token)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	auth.ClearSessionCookie(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (h *Handlers) renderLogin(w http.ResponseWriter, r *http.Request, statusCode int, next, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if err := templates.Login(next, message).Render(r.Context(), w); err != nil {
+		log.Error().Err(err).Msg("Failed to render login template")
+	}
+}
+
+// localPath only lets the login page redirect to pages of this server
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
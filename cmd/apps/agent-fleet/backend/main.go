@@ -20,6 +20,7 @@ import (
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/database"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/handlers"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/middleware/auth"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/scheduler"
 )
 
 var (
@@ -42,6 +43,9 @@ func init() {
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (trace, debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolP("dev", "", false, "Development mode")
 	rootCmd.PersistentFlags().BoolP("disable-auth", "", false, "Disable authentication for testing")
+	rootCmd.PersistentFlags().String("operator-token", "", "Bootstrap operator token, in addition to the tokens issued through the API (random if not set)")
+	rootCmd.PersistentFlags().Duration("scheduler-interval", 10*time.Second, "Interval between automatic task scheduling passes (0 disables them)")
+	rootCmd.PersistentFlags().Duration("heartbeat-timeout", 2*time.Minute, "Reassign the tasks of agents silent for longer than this (0 disables it)")
 
 	viper.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("host", rootCmd.PersistentFlags().Lookup("host"))
@@ -49,6 +53,9 @@ func init() {
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("dev", rootCmd.PersistentFlags().Lookup("dev"))
 	viper.BindPFlag("disable-auth", rootCmd.PersistentFlags().Lookup("disable-auth"))
+	viper.BindPFlag("operator-token", rootCmd.PersistentFlags().Lookup("operator-token"))
+	viper.BindPFlag("scheduler-interval", rootCmd.PersistentFlags().Lookup("scheduler-interval"))
+	viper.BindPFlag("heartbeat-timeout", rootCmd.PersistentFlags().Lookup("heartbeat-timeout"))
 }
 
 func initConfig() {
@@ -108,8 +115,18 @@ func runServer(cmd *cobra.Command, args []string) {
 
 	log.Info().Str("database", dbPath).Msg("Database initialized")
 
+	// Create the task scheduler
+	sched := scheduler.New(db, scheduler.Config{
+		Interval:         viper.GetDuration("scheduler-interval"),
+		HeartbeatTimeout: viper.GetDuration("heartbeat-timeout"),
+	})
+
 	// Create handlers
-	h := handlers.New(db)
+	h := handlers.New(db, sched)
+
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	sched.Start(schedCtx)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -132,59 +149,11 @@ func runServer(cmd *cobra.Command, args []string) {
 	})
 	r.Use(c.Handler)
 
-	// API routes
-	r.Route("/v1", func(r chi.Router) {
-		// Authentication middleware for API routes (unless disabled)
-		if !viper.GetBool("disable-auth") {
-			r.Use(auth.BearerTokenMiddleware)
-		}
-
-		// Agents
-		r.Route("/agents", func(r chi.Router) {
-			r.Get("/", h.ListAgents)
-			r.Post("/", h.CreateAgent)
-			r.Get("/{agentID}", h.GetAgent)
-			r.Patch("/{agentID}", h.UpdateAgent)
-			r.Delete("/{agentID}", h.DeleteAgent)
-
-			// Agent events
-			r.Get("/{agentID}/events", h.ListAgentEvents)
-			r.Post("/{agentID}/events", h.CreateAgentEvent)
-
-			// Agent todos
-			r.Get("/{agentID}/todos", h.ListAgentTodos)
-			r.Post("/{agentID}/todos", h.CreateAgentTodo)
-			r.Patch("/{agentID}/todos/{todoID}", h.UpdateAgentTodo)
-			r.Delete("/{agentID}/todos/{todoID}", h.DeleteAgentTodo)
-
-			// Agent commands
-			r.Get("/{agentID}/commands", h.ListAgentCommands)
-			r.Post("/{agentID}/commands", h.CreateAgentCommand)
-			r.Patch("/{agentID}/commands/{commandID}", h.UpdateAgentCommand)
-		})
-
-		// Tasks
-		r.Route("/tasks", func(r chi.Router) {
-			r.Get("/", h.ListTasks)
-			r.Post("/", h.CreateTask)
-			r.Get("/{taskID}", h.GetTask)
-			r.Patch("/{taskID}", h.UpdateTask)
-			r.Delete("/{taskID}", h.DeleteTask)
-		})
-
-		// Fleet operations
-		r.Route("/fleet", func(r chi.Router) {
-			r.Get("/status", h.GetFleetStatus)
-			r.Get("/recent-updates", h.GetRecentUpdates)
-		})
-
-		// Server-Sent Events
-		r.Get("/stream", h.SSEHandler)
+	authenticator := auth.New(db, auth.Config{
+		OperatorToken: operatorToken(),
+		Disabled:      viper.GetBool("disable-auth"),
 	})
-
-	// Web interface routes (optional)
-	r.Get("/", h.IndexPage)
-	r.Get("/agents", h.AgentsPage)
+	h.RegisterRoutes(r, authenticator)
 
 	// Static files
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
@@ -217,6 +186,7 @@ func runServer(cmd *cobra.Command, args []string) {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info().Msg("Shutting down server...")
+	stopScheduler()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -228,6 +198,22 @@ func runServer(cmd *cobra.Command, args []string) {
 	log.Info().Msg("Server exited")
 }
 
+// operatorToken returns the configured operator token. Without one a random
+// token is generated and printed, there is no default token every agent
+// could know.
+func operatorToken() string {
+	if token := viper.GetString("operator-token"); token != "" || viper.GetBool("disable-auth") {
+		return token
+	}
+
+	token, _, err := auth.GenerateToken()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate an operator token")
+	}
+	fmt.Fprintf(os.Stderr, "No --operator-token set, operator token for this run:\n\n  %s\n\n", token)
+	return token
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal().Err(err).Msg("Failed to execute command")
@@ -1,54 +1,305 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
 )
 
-// For simplicity, we'll use a hardcoded token. In production, this should be
-// a proper JWT validation or database lookup
-const validToken = "fleet-agent-token-123"
+// SessionCookie holds the operator token of a browser signed in to the web
+// interface
+const SessionCookie = "agent_fleet_token"
 
-// BearerTokenMiddleware validates Bearer token authentication
-func BearerTokenMiddleware(next http.Handler) http.Handler {
+// tokenPrefix marks tokens issued by the server, the part of the token
+// shown in listings is the prefix plus a few characters
+const tokenPrefix = "afl_"
+
+// Store looks up issued tokens and records the audit log
+type Store interface {
+	GetAPITokenByHash(hash string) (*models.APIToken, error)
+	TouchAPIToken(id string) error
+	CreateAuditEntry(entry *models.AuditEntry) error
+}
+
+// Principal is the caller of a request
+type Principal struct {
+	// TokenID is empty for the configured operator token
+	TokenID string
+	Name    string
+	Role    models.Role
+	// AgentID is the agent an agent token acts as
+	AgentID string
+}
+
+func (p *Principal) IsOperator() bool {
+	return p.Role == models.RoleOperator
+}
+
+// CanAccessAgent reports whether the caller may act on an agent's resources
+func (p *Principal) CanAccessAgent(agentID string) bool {
+	return p.IsOperator() || (p.Role == models.RoleAgent && p.AgentID == agentID)
+}
+
+// String identifies the caller in the audit log
+func (p *Principal) String() string {
+	if p.Role == models.RoleAgent {
+		return "agent:" + p.AgentID
+	}
+	return "operator:" + p.Name
+}
+
+type contextKey struct{}
+
+// PrincipalFromContext returns the caller of a request that went through the
+// authenticator
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+// Config configures the authenticator
+type Config struct {
+	// OperatorToken is accepted as an operator besides the issued tokens
+	OperatorToken string
+	// Disabled treats every request as coming from an operator
+	Disabled bool
+}
+
+// Authenticator resolves bearer tokens to principals and writes every call
+// to the audit log
+type Authenticator struct {
+	store  Store
+	config Config
+}
+
+func New(store Store, config Config) *Authenticator {
+	return &Authenticator{store: store, config: config}
+}
+
+// Middleware authenticates the request and records it in the audit log,
+// including rejected requests
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			writeErrorResponse(w, http.StatusUnauthorized, "MISSING_AUTH_TOKEN", "Authorization header is required")
-			return
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		principal, code, message := a.authenticate(r)
+		if principal == nil {
+			writeErrorResponse(ww, http.StatusUnauthorized, code, message)
+		} else {
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), contextKey{}, principal)))
 		}
 
-		// Check Bearer prefix
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			writeErrorResponse(w, http.StatusUnauthorized, "INVALID_AUTH_FORMAT", "Authorization header must start with 'Bearer '")
-			return
+		a.audit(r, principal, ww.Status(), time.Since(start))
+	})
+}
+
+// WebMiddleware authenticates the web interface with the token in the
+// session cookie and records the request in the audit log. The pages show
+// operator data, callers that are not operators are sent to the login page.
+func (a *Authenticator) WebMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		var principal *Principal
+		if a.config.Disabled {
+			principal = anonymousOperator()
+		} else if cookie, err := r.Cookie(SessionCookie); err == nil {
+			principal, _, _ = a.Resolve(cookie.Value)
+		}
+		if principal == nil || !principal.IsOperator() {
+			http.Redirect(ww, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		} else {
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), contextKey{}, principal)))
+		}
+
+		a.audit(r, principal, ww.Status(), time.Since(start))
+	})
+}
+
+// Disabled reports whether every caller is treated as an operator
+func (a *Authenticator) Disabled() bool {
+	return a.config.Disabled
+}
+
+func anonymousOperator() *Principal {
+	return &Principal{Name: "anonymous", Role: models.RoleOperator}
+}
+
+// authenticate returns the caller, or an error code and message
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, string, string) {
+	if a.config.Disabled {
+		return anonymousOperator(), "", ""
+	}
+
+	// Get Authorization header
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, "MISSING_AUTH_TOKEN", "Authorization header is required"
+	}
+
+	// Check Bearer prefix
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, "INVALID_AUTH_FORMAT", "Authorization header must start with 'Bearer '"
+	}
+
+	// Extract token
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		return nil, "EMPTY_TOKEN", "Bearer token cannot be empty"
+	}
+
+	return a.Resolve(token)
+}
+
+// Resolve returns the caller a token belongs to, or an error code and
+// message for unknown and revoked tokens
+func (a *Authenticator) Resolve(token string) (*Principal, string, string) {
+	if token == "" {
+		return nil, "EMPTY_TOKEN", "Bearer token cannot be empty"
+	}
+	if a.config.OperatorToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.config.OperatorToken)) == 1 {
+		return &Principal{Name: "bootstrap", Role: models.RoleOperator}, "", ""
+	}
+
+	issued, err := a.store.GetAPITokenByHash(HashToken(token))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up token")
+		return nil, "INVALID_TOKEN", "Invalid bearer token"
+	}
+	if issued == nil {
+		return nil, "INVALID_TOKEN", "Invalid bearer token"
+	}
+	if issued.RevokedAt != nil {
+		return nil, "REVOKED_TOKEN", "Bearer token has been revoked"
+	}
+
+	if err := a.store.TouchAPIToken(issued.ID); err != nil {
+		log.Warn().Err(err).Str("token_id", issued.ID).Msg("Failed to update token last use")
+	}
+
+	principal := &Principal{
+		TokenID: issued.ID,
+		Name:    issued.Name,
+		Role:    models.Role(issued.Role),
+	}
+	if issued.AgentID != nil {
+		principal.AgentID = *issued.AgentID
+	}
+
+	log.Debug().Str("principal", principal.String()).Str("token_id", issued.ID).Msg("Valid bearer token")
+	return principal, "", ""
+}
+
+func (a *Authenticator) audit(r *http.Request, principal *Principal, status int, duration time.Duration) {
+	entry := &models.AuditEntry{
+		Timestamp:  time.Now(),
+		Principal:  "anonymous",
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		DurationMs: duration.Milliseconds(),
+		RemoteAddr: r.RemoteAddr,
+	}
+	if principal != nil {
+		entry.Principal = principal.String()
+		entry.Role = string(principal.Role)
+		if principal.TokenID != "" {
+			entry.TokenID = &principal.TokenID
 		}
+	}
+	// Rejected requests were not routed past the authenticator
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && principal != nil {
+		entry.Route = rctx.RoutePattern()
+	}
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+
+	if err := a.store.CreateAuditEntry(entry); err != nil {
+		log.Error().Err(err).Str("path", entry.Path).Msg("Failed to write audit entry")
+	}
+}
 
-		// Extract token
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		if token == "" {
-			writeErrorResponse(w, http.StatusUnauthorized, "EMPTY_TOKEN", "Bearer token cannot be empty")
+// RequireOperator rejects callers that are not operators
+func RequireOperator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFromContext(r.Context())
+		if principal == nil || !principal.IsOperator() {
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "Operator token required")
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// Validate token (in production, this would be proper validation)
-		if token != validToken {
-			writeErrorResponse(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid bearer token")
+// RequireAgentAccess rejects callers other than operators and the agent in
+// the agentID URL parameter
+func RequireAgentAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFromContext(r.Context())
+		if principal == nil || !principal.CanAccessAgent(chi.URLParam(r, "agentID")) {
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "Agents may only access their own resources")
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		log.Debug().Str("token", token).Msg("Valid bearer token")
+// SetSessionCookie signs a browser in to the web interface with a token
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
 
-		// Token is valid, continue to next handler
-		next.ServeHTTP(w, r)
+// ClearSessionCookie signs a browser out of the web interface
+func ClearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
 	})
 }
 
+// GenerateToken returns a new random token and the prefix shown in listings
+func GenerateToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, token[:len(tokenPrefix)+6], nil
+}
+
+// HashToken returns the hash stored for a token. Tokens are random enough
+// that a plain SHA-256 is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // writeErrorResponse writes a standardized error response
 func writeErrorResponse(w http.ResponseWriter, statusCode int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
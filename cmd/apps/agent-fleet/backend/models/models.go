@@ -2,6 +2,9 @@ package models
 
 import (
 	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"math/big"
 	"regexp"
 	"strings"
@@ -51,10 +54,49 @@ type Agent struct {
 	LastCommit      *time.Time `json:"last_commit" db:"last_commit"`
 	Progress        int        `json:"progress" db:"progress"` // 0-100
 	PendingQuestion *string    `json:"pending_question" db:"pending_question"`
+	Capabilities    StringList `json:"capabilities" db:"capabilities"`       // e.g. go, frontend, docs
+	MaxConcurrency  int        `json:"max_concurrency" db:"max_concurrency"` // tasks the scheduler may assign at once
+	LastHeartbeat   *time.Time `json:"last_heartbeat" db:"last_heartbeat"`
 	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
 // Event represents an event in the agent's activity log
 type Event struct {
 	ID        string                 `json:"id" db:"id"`
@@ -79,15 +121,19 @@ type TodoItem struct {
 
 // Task represents a task that can be assigned to agents
 type Task struct {
-	ID              string     `json:"id" db:"id"`
-	Title           string     `json:"title" db:"title"`
-	Description     string     `json:"description" db:"description"`
-	AssignedAgentID *string    `json:"assigned_agent_id" db:"assigned_agent_id"`
-	Status          string     `json:"status" db:"status"`     // pending|assigned|in_progress|completed|failed
-	Priority        string     `json:"priority" db:"priority"` // low|medium|high|urgent
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	AssignedAt      *time.Time `json:"assigned_at" db:"assigned_at"`
-	CompletedAt     *time.Time `json:"completed_at" db:"completed_at"`
+	ID              string  `json:"id" db:"id"`
+	Title           string  `json:"title" db:"title"`
+	Description     string  `json:"description" db:"description"`
+	AssignedAgentID *string `json:"assigned_agent_id" db:"assigned_agent_id"`
+	Status          string  `json:"status" db:"status"`     // pending|assigned|in_progress|completed|failed
+	Priority        string  `json:"priority" db:"priority"` // low|medium|high|urgent
+	Type            string  `json:"type" db:"type"`         // free-form, e.g. bugfix|feature|refactor
+	// RequiredCapabilities must all be among the capabilities of the agent
+	// the scheduler assigns the task to
+	RequiredCapabilities StringList `json:"required_capabilities" db:"required_capabilities"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	AssignedAt           *time.Time `json:"assigned_at" db:"assigned_at"`
+	CompletedAt          *time.Time `json:"completed_at" db:"completed_at"`
 }
 
 // Command represents a command sent to an agent
@@ -102,6 +148,45 @@ type Command struct {
 	RespondedAt *time.Time `json:"responded_at" db:"responded_at"`
 }
 
+// APIToken is a credential for the API. Tokens with an agent ID act as that
+// agent, tokens without one are operator tokens. Only the SHA-256 hash of
+// the token is stored.
+type APIToken struct {
+	ID          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Role        string     `json:"role" db:"role"` // operator|agent
+	AgentID     *string    `json:"agent_id" db:"agent_id"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// AuditEntry records one API call and who made it
+type AuditEntry struct {
+	ID         int64     `json:"id" db:"id"`
+	Timestamp  time.Time `json:"timestamp" db:"timestamp"`
+	TokenID    *string   `json:"token_id" db:"token_id"`
+	Principal  string    `json:"principal" db:"principal"` // operator:<name>|agent:<id>|anonymous
+	Role       string    `json:"role" db:"role"`
+	Method     string    `json:"method" db:"method"`
+	Path       string    `json:"path" db:"path"`
+	Route      string    `json:"route" db:"route"`
+	Status     int       `json:"status" db:"status"`
+	DurationMs int64     `json:"duration_ms" db:"duration_ms"`
+	RemoteAddr string    `json:"remote_addr" db:"remote_addr"`
+}
+
+// AgentStatusChange is one entry of an agent's status history
+type AgentStatusChange struct {
+	ID        int64     `json:"id" db:"id"`
+	AgentID   string    `json:"agent_id" db:"agent_id"`
+	OldStatus *string   `json:"old_status" db:"old_status"`
+	NewStatus string    `json:"new_status" db:"new_status"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
 // FleetStatus represents the overall status of the agent fleet
 type FleetStatus struct {
 	TotalAgents           int `json:"total_agents"`
@@ -133,6 +218,8 @@ const (
 	EventTypeError    EventType = "error"
 	EventTypeInfo     EventType = "info"
 	EventTypeCommand  EventType = "command"
+	// EventTypeAssignment records a scheduler decision
+	EventTypeAssignment EventType = "assignment"
 )
 
 // TaskStatus represents valid task status values
@@ -156,6 +243,28 @@ const (
 	TaskPriorityUrgent TaskPriority = "urgent"
 )
 
+// Rank orders priorities, urgent being the highest
+func (p TaskPriority) Rank() int {
+	switch p {
+	case TaskPriorityUrgent:
+		return 3
+	case TaskPriorityHigh:
+		return 2
+	case TaskPriorityMedium:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Role represents the role of an API caller
+type Role string
+
+const (
+	RoleOperator Role = "operator"
+	RoleAgent    Role = "agent"
+)
+
 // CommandType represents valid command types
 type CommandType string
 
@@ -176,21 +285,25 @@ const (
 
 // CreateAgentRequest represents the request body for creating an agent
 type CreateAgentRequest struct {
-	Name     string `json:"name" validate:"required"`
-	Worktree string `json:"worktree" validate:"required"`
+	Name           string   `json:"name" validate:"required"`
+	Worktree       string   `json:"worktree" validate:"required"`
+	Capabilities   []string `json:"capabilities,omitempty"`
+	MaxConcurrency int      `json:"max_concurrency,omitempty"` // defaults to 1
 }
 
 // UpdateAgentRequest represents the request body for updating an agent
 type UpdateAgentRequest struct {
-	Name            *string `json:"name,omitempty"`
-	Status          *string `json:"status,omitempty"`
-	CurrentTask     *string `json:"current_task,omitempty"`
-	Worktree        *string `json:"worktree,omitempty"`
-	FilesChanged    *int    `json:"files_changed,omitempty"`
-	LinesAdded      *int    `json:"lines_added,omitempty"`
-	LinesRemoved    *int    `json:"lines_removed,omitempty"`
-	Progress        *int    `json:"progress,omitempty"`
-	PendingQuestion *string `json:"pending_question,omitempty"`
+	Name            *string   `json:"name,omitempty"`
+	Status          *string   `json:"status,omitempty"`
+	CurrentTask     *string   `json:"current_task,omitempty"`
+	Worktree        *string   `json:"worktree,omitempty"`
+	FilesChanged    *int      `json:"files_changed,omitempty"`
+	LinesAdded      *int      `json:"lines_added,omitempty"`
+	LinesRemoved    *int      `json:"lines_removed,omitempty"`
+	Progress        *int      `json:"progress,omitempty"`
+	PendingQuestion *string   `json:"pending_question,omitempty"`
+	Capabilities    *[]string `json:"capabilities,omitempty"`
+	MaxConcurrency  *int      `json:"max_concurrency,omitempty"`
 }
 
 // CreateEventRequest represents the request body for creating an event
@@ -215,19 +328,23 @@ type UpdateTodoRequest struct {
 
 // CreateTaskRequest represents the request body for creating a task
 type CreateTaskRequest struct {
-	Title           string  `json:"title" validate:"required"`
-	Description     string  `json:"description" validate:"required"`
-	Priority        string  `json:"priority" validate:"required"`
-	AssignedAgentID *string `json:"assigned_agent_id,omitempty"`
+	Title                string   `json:"title" validate:"required"`
+	Description          string   `json:"description" validate:"required"`
+	Priority             string   `json:"priority" validate:"required"`
+	Type                 string   `json:"type,omitempty"`
+	AssignedAgentID      *string  `json:"assigned_agent_id,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
 }
 
 // UpdateTaskRequest represents the request body for updating a task
 type UpdateTaskRequest struct {
-	Title           *string `json:"title,omitempty"`
-	Description     *string `json:"description,omitempty"`
-	Status          *string `json:"status,omitempty"`
-	Priority        *string `json:"priority,omitempty"`
-	AssignedAgentID *string `json:"assigned_agent_id,omitempty"`
+	Title                *string   `json:"title,omitempty"`
+	Description          *string   `json:"description,omitempty"`
+	Status               *string   `json:"status,omitempty"`
+	Priority             *string   `json:"priority,omitempty"`
+	Type                 *string   `json:"type,omitempty"`
+	AssignedAgentID      *string   `json:"assigned_agent_id,omitempty"`
+	RequiredCapabilities *[]string `json:"required_capabilities,omitempty"`
 }
 
 // CreateCommandRequest represents the request body for creating a command
@@ -242,6 +359,19 @@ type UpdateCommandRequest struct {
 	Status   *string `json:"status,omitempty"`
 }
 
+// CreateTokenRequest represents the request body for issuing a token. Without
+// an agent ID an operator token is issued.
+type CreateTokenRequest struct {
+	Name    string  `json:"name" validate:"required"`
+	AgentID *string `json:"agent_id,omitempty"`
+}
+
+// CreateTokenResponse contains the token itself, which is only shown once
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+	APIToken
+}
+
 // ListResponse represents a paginated list response
 type ListResponse struct {
 	Total  int `json:"total"`
@@ -277,11 +407,95 @@ type CommandsListResponse struct {
 	Commands []Command `json:"commands"`
 }
 
+// TokensListResponse represents the response for listing tokens
+type TokensListResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// AuditListResponse represents the response for listing the audit log
+type AuditListResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	ListResponse
+}
+
 // RecentUpdatesResponse represents the response for recent updates
 type RecentUpdatesResponse struct {
 	Updates []Event `json:"updates"`
 }
 
+// SchedulerDecision describes one assignment or release made by the scheduler
+type SchedulerDecision struct {
+	TaskID          string `json:"task_id"`
+	TaskTitle       string `json:"task_title"`
+	Action          string `json:"action"` // assigned|released
+	AgentID         string `json:"agent_id"`
+	PreviousAgentID string `json:"previous_agent_id,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// SchedulerRun is the outcome of one scheduling pass
+type SchedulerRun struct {
+	StartedAt time.Time           `json:"started_at"`
+	Decisions []SchedulerDecision `json:"decisions"`
+	// Unassigned counts pending tasks no agent could take
+	Unassigned int `json:"unassigned"`
+	// Unmatched lists pending tasks whose capabilities no agent has
+	Unmatched []string `json:"unmatched"`
+}
+
+// SchedulerStatus represents the scheduler configuration and its last run
+type SchedulerStatus struct {
+	Enabled          bool          `json:"enabled"`
+	Interval         string        `json:"interval"`
+	HeartbeatTimeout string        `json:"heartbeat_timeout"`
+	LastRun          *SchedulerRun `json:"last_run"`
+}
+
+// AnalyticsReport holds the fleet rollups for a time window
+type AnalyticsReport struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Days are the UTC days of the window, for charts
+	Days            []string             `json:"days"`
+	TasksCompleted  []DailyAgentCount    `json:"tasks_completed"`
+	WaitingFeedback []AgentDurationStats `json:"waiting_feedback"`
+	CommandLatency  []AgentDurationStats `json:"command_latency"`
+	TaskOutcomes    []TaskTypeOutcome    `json:"task_outcomes"`
+}
+
+// DailyAgentCount is the number of tasks an agent completed on a UTC day
+type DailyAgentCount struct {
+	Day       string `json:"day"`
+	AgentID   string `json:"agent_id"`
+	AgentName string `json:"agent_name"`
+	Count     int    `json:"count"`
+}
+
+// AgentDurationStats sums up durations per agent, like the time spent
+// waiting for feedback or until a command was acknowledged
+type AgentDurationStats struct {
+	AgentID   string `json:"agent_id"`
+	AgentName string `json:"agent_name"`
+	Count     int    `json:"count"`
+	// Open counts what hasn't ended yet: agents still waiting, commands not
+	// acknowledged. They are not part of the statistics.
+	Open          int     `json:"open"`
+	MeanSeconds   float64 `json:"mean_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	MaxSeconds    float64 `json:"max_seconds"`
+	TotalSeconds  float64 `json:"total_seconds"`
+}
+
+// TaskTypeOutcome counts how the tasks of one type ended
+type TaskTypeOutcome struct {
+	Type        string  `json:"type"`
+	Total       int     `json:"total"`
+	Completed   int     `json:"completed"`
+	Failed      int     `json:"failed"`
+	Open        int     `json:"open"`
+	FailureRate float64 `json:"failure_rate"` // failed / (completed + failed)
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error ErrorDetail `json:"error"`
@@ -0,0 +1,408 @@
+// Package scheduler assigns pending tasks to agents. A task goes to an idle
+// or active agent that has all the capabilities the task requires and is
+// below its max concurrency, urgent tasks first. Tasks of agents that are
+// in error or stopped sending heartbeats go back to the queue and are
+// assigned to another agent. Every decision is recorded as an assignment
+// event on the agent.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/database"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+const (
+	ActionAssigned = "assigned"
+	ActionReleased = "released"
+)
+
+// Config configures the scheduler
+type Config struct {
+	// Interval between scheduling passes. Zero disables the automatic
+	// passes, runs can still be triggered through the API.
+	Interval time.Duration
+	// HeartbeatTimeout is how long an agent may stay silent before its
+	// tasks are reassigned. Zero disables the check.
+	HeartbeatTimeout time.Duration
+}
+
+// Notifier is told about the scheduler decisions, to broadcast them
+type Notifier interface {
+	BroadcastTaskAssigned(task *models.Task, agentID string)
+	BroadcastAgentEventCreated(agentID string, event *models.Event)
+}
+
+type Scheduler struct {
+	db       *database.DB
+	config   Config
+	notifier Notifier
+	trigger  chan struct{}
+
+	// mu makes sure only one pass runs at a time
+	mu      sync.Mutex
+	running bool
+	lastRun *models.SchedulerRun
+}
+
+func New(db *database.DB, config Config) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		config:  config,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+func (s *Scheduler) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// Start runs a pass every interval and whenever one is triggered, until the
+// context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	if s.config.Interval <= 0 {
+		log.Info().Msg("Automatic task scheduling disabled")
+		return
+	}
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	log.Info().
+		Dur("interval", s.config.Interval).
+		Dur("heartbeat_timeout", s.config.HeartbeatTimeout).
+		Msg("Starting task scheduler")
+
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(); err != nil {
+				log.Error().Err(err).Msg("Scheduling pass failed")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.trigger:
+			}
+		}
+	}()
+}
+
+// Trigger asks for a pass without waiting for the next interval, for
+// example because a task was created or an agent became idle
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+		// A pass is already due
+	}
+}
+
+// Status returns the configuration and the outcome of the last pass
+func (s *Scheduler) Status() models.SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return models.SchedulerStatus{
+		Enabled:          s.running,
+		Interval:         s.config.Interval.String(),
+		HeartbeatTimeout: s.config.HeartbeatTimeout.String(),
+		LastRun:          s.lastRun,
+	}
+}
+
+// agentState is an agent and the open tasks it has during a pass
+type agentState struct {
+	agent *models.Agent
+	load  int
+	// unavailable says why the tasks of the agent have to be reassigned
+	unavailable string
+}
+
+func (a *agentState) capacity() int {
+	if a.agent.MaxConcurrency <= 0 {
+		return 1
+	}
+	return a.agent.MaxConcurrency
+}
+
+// accepts reports whether new tasks may be assigned to the agent
+func (a *agentState) accepts() bool {
+	if a.unavailable != "" || a.load >= a.capacity() {
+		return false
+	}
+	status := models.AgentStatus(a.agent.Status)
+	return status == models.AgentStatusIdle || status == models.AgentStatusActive
+}
+
+// Run makes one scheduling pass: it releases the tasks of unavailable
+// agents, then assigns the pending tasks by priority
+func (s *Scheduler) Run() (*models.SchedulerRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	run := &models.SchedulerRun{
+		StartedAt: now,
+		Decisions: []models.SchedulerDecision{},
+		Unmatched: []string{},
+	}
+
+	agents, err := s.db.ListAllAgents()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.db.ListOpenTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*agentState, len(agents))
+	byID := map[string]*agentState{}
+	for i := range agents {
+		state := &agentState{agent: &agents[i], unavailable: s.unavailableReason(&agents[i], now)}
+		states[i] = state
+		byID[agents[i].ID] = state
+	}
+
+	// Tasks taken away from an agent in this pass, by task ID
+	released := map[string]models.SchedulerDecision{}
+	var pending []*models.Task
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Status == string(models.TaskStatusPending) {
+			pending = append(pending, task)
+			continue
+		}
+		if task.AssignedAgentID == nil {
+			continue
+		}
+		state, ok := byID[*task.AssignedAgentID]
+		if !ok {
+			continue
+		}
+		if state.unavailable == "" {
+			state.load++
+			continue
+		}
+
+		ok, err := s.db.ReleaseTask(task.ID, state.agent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		decision := models.SchedulerDecision{
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			Action:    ActionReleased,
+			AgentID:   state.agent.ID,
+			Reason:    state.unavailable,
+		}
+		s.record(run, decision, task.Priority, fmt.Sprintf("Released task: %s (%s)", task.Title, state.unavailable))
+		released[task.ID] = decision
+
+		task.Status = string(models.TaskStatusPending)
+		task.AssignedAgentID = nil
+		pending = append(pending, task)
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		pi := models.TaskPriority(pending[i].Priority).Rank()
+		pj := models.TaskPriority(pending[j].Priority).Rank()
+		if pi != pj {
+			return pi > pj
+		}
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+
+	for _, task := range pending {
+		var candidates []*agentState
+		matched := false
+		for _, state := range states {
+			if !hasCapabilities(state.agent.Capabilities, task.RequiredCapabilities) {
+				continue
+			}
+			matched = true
+			if state.accepts() {
+				candidates = append(candidates, state)
+			}
+		}
+		if !matched {
+			run.Unmatched = append(run.Unmatched, task.ID)
+			continue
+		}
+		if len(candidates) == 0 {
+			run.Unassigned++
+			continue
+		}
+
+		best := pickAgent(candidates)
+		ok, err := s.db.AssignPendingTask(task.ID, best.agent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Assigned by hand or deleted since the tasks were listed
+			continue
+		}
+
+		decision := models.SchedulerDecision{
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			Action:    ActionAssigned,
+			AgentID:   best.agent.ID,
+			Reason:    assignmentReason(best, task),
+		}
+		message := fmt.Sprintf("Assigned task: %s", task.Title)
+		if previous, ok := released[task.ID]; ok {
+			decision.PreviousAgentID = previous.AgentID
+			message = fmt.Sprintf("Reassigned task: %s (%s on previous agent)", task.Title, previous.Reason)
+		}
+		best.load++
+		s.record(run, decision, task.Priority, message)
+
+		if s.notifier != nil {
+			assigned, err := s.db.GetTask(task.ID)
+			if err != nil {
+				return nil, err
+			}
+			if assigned != nil {
+				s.notifier.BroadcastTaskAssigned(assigned, best.agent.ID)
+			}
+		}
+	}
+
+	if len(run.Decisions) > 0 || run.Unassigned > 0 || len(run.Unmatched) > 0 {
+		log.Info().
+			Int("decisions", len(run.Decisions)).
+			Int("unassigned", run.Unassigned).
+			Int("unmatched", len(run.Unmatched)).
+			Msg("Scheduling pass completed")
+	}
+
+	s.lastRun = run
+	return run, nil
+}
+
+// unavailableReason returns why the tasks of an agent should be reassigned,
+// or an empty string
+func (s *Scheduler) unavailableReason(agent *models.Agent, now time.Time) string {
+	if agent.Status == string(models.AgentStatusError) {
+		return "agent in error"
+	}
+	if s.config.HeartbeatTimeout > 0 {
+		if silence := now.Sub(lastSeen(agent)); silence > s.config.HeartbeatTimeout {
+			return fmt.Sprintf("no heartbeat for %s", silence.Round(time.Second))
+		}
+	}
+	return ""
+}
+
+// lastSeen returns the last heartbeat of an agent. Agents that never sent
+// one, such as rows from before heartbeats were tracked, fall back to their
+// last update so they still time out.
+func lastSeen(agent *models.Agent) time.Time {
+	if agent.LastHeartbeat != nil {
+		return *agent.LastHeartbeat
+	}
+	if !agent.UpdatedAt.IsZero() {
+		return agent.UpdatedAt
+	}
+	return agent.CreatedAt
+}
+
+// record stores a decision as an event on the agent and adds it to the run
+func (s *Scheduler) record(run *models.SchedulerRun, decision models.SchedulerDecision, priority, message string) {
+	run.Decisions = append(run.Decisions, decision)
+
+	metadata := map[string]interface{}{
+		"task_id":    decision.TaskID,
+		"task_title": decision.TaskTitle,
+		"priority":   priority,
+		"action":     decision.Action,
+		"reason":     decision.Reason,
+	}
+	if decision.PreviousAgentID != "" {
+		metadata["previous_agent_id"] = decision.PreviousAgentID
+	}
+
+	event, err := s.db.CreateEvent(decision.AgentID, models.CreateEventRequest{
+		Type:     string(models.EventTypeAssignment),
+		Message:  message,
+		Metadata: metadata,
+	})
+	if err != nil {
+		// The decision is made, losing its event is not worth failing the pass
+		log.Error().Err(err).Str("task_id", decision.TaskID).Msg("Failed to record assignment event")
+		return
+	}
+
+	log.Info().
+		Str("task_id", decision.TaskID).
+		Str("agent_id", decision.AgentID).
+		Str("action", decision.Action).
+		Str("reason", decision.Reason).
+		Msg(message)
+
+	if s.notifier != nil {
+		s.notifier.BroadcastAgentEventCreated(decision.AgentID, event)
+	}
+}
+
+// pickAgent prefers idle agents, then the agent with the most free capacity
+// relative to its max concurrency, then the one registered first
+func pickAgent(candidates []*agentState) *agentState {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		aIdle := a.agent.Status == string(models.AgentStatusIdle)
+		bIdle := b.agent.Status == string(models.AgentStatusIdle)
+		if aIdle != bIdle {
+			return aIdle
+		}
+		// a.load/a.capacity < b.load/b.capacity
+		return a.load*b.capacity() < b.load*a.capacity()
+	})
+	return candidates[0]
+}
+
+func assignmentReason(state *agentState, task *models.Task) string {
+	reason := fmt.Sprintf("%s priority, agent %s with %d/%d tasks",
+		task.Priority, state.agent.Status, state.load, state.capacity())
+	if len(task.RequiredCapabilities) > 0 {
+		reason += ", has " + strings.Join(task.RequiredCapabilities, ", ")
+	}
+	return reason
+}
+
+// hasCapabilities reports whether all required capabilities are declared,
+// ignoring case
+func hasCapabilities(declared, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, d := range declared {
+			if strings.EqualFold(strings.TrimSpace(d), strings.TrimSpace(r)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
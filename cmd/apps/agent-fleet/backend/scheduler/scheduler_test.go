@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/agent-fleet/backend/models"
+)
+
+func TestUnavailableReason(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	heartbeat := func(d time.Duration) *time.Time {
+		t := ago(d)
+		return &t
+	}
+
+	tests := []struct {
+		name  string
+		agent models.Agent
+		want  string
+	}{
+		{
+			name:  "recent heartbeat",
+			agent: models.Agent{Status: "active", LastHeartbeat: heartbeat(time.Minute), UpdatedAt: ago(time.Hour)},
+		},
+		{
+			name:  "silent agent",
+			agent: models.Agent{Status: "active", LastHeartbeat: heartbeat(10 * time.Minute)},
+			want:  "no heartbeat for 10m0s",
+		},
+		{
+			name:  "error wins over heartbeat",
+			agent: models.Agent{Status: "error", LastHeartbeat: heartbeat(time.Second)},
+			want:  "agent in error",
+		},
+		{
+			name:  "no heartbeat, recently updated",
+			agent: models.Agent{Status: "idle", UpdatedAt: ago(time.Minute), CreatedAt: ago(time.Hour)},
+		},
+		{
+			name:  "no heartbeat, stale update",
+			agent: models.Agent{Status: "idle", UpdatedAt: ago(20 * time.Minute), CreatedAt: ago(time.Hour)},
+			want:  "no heartbeat for 20m0s",
+		},
+		{
+			name:  "no heartbeat or update, old agent",
+			agent: models.Agent{Status: "idle", CreatedAt: ago(time.Hour)},
+			want:  "no heartbeat for 1h0m0s",
+		},
+	}
+
+	s := &Scheduler{config: Config{HeartbeatTimeout: 5 * time.Minute}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.unavailableReason(&tt.agent, now); got != tt.want {
+				t.Errorf("unavailableReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	disabled := &Scheduler{}
+	stale := models.Agent{Status: "idle", CreatedAt: ago(time.Hour)}
+	if got := disabled.unavailableReason(&stale, now); got != "" {
+		t.Errorf("expected no timeout when disabled, got %q", got)
+	}
+}
@@ -29,9 +29,14 @@ const (
 
 // Config holds agent configuration
 type Config struct {
+	// AgentID is an agent registered by an operator. The agent then runs
+	// with that agent's token instead of registering itself.
+	AgentID              string
 	Name                 string
 	Worktree             string
 	Randomized           bool
+	Capabilities         []string
+	MaxConcurrency       int
 	TickInterval         time.Duration
 	CommandCheckInterval time.Duration
 }
@@ -114,14 +119,27 @@ func (a *Agent) Run(ctx context.Context) error {
 
 // register registers the agent with the backend
 func (a *Agent) register() error {
-	req := models.CreateAgentRequest{
-		Name:     a.config.Name,
-		Worktree: a.config.Worktree,
-	}
+	var agent *models.Agent
+	var err error
+	if a.config.AgentID != "" {
+		// Registered by an operator, which also checks the agent token
+		agent, err = a.client.GetAgent(a.config.AgentID)
+		if err != nil {
+			return err
+		}
+		a.config.Name = agent.Name
+	} else {
+		req := models.CreateAgentRequest{
+			Name:           a.config.Name,
+			Worktree:       a.config.Worktree,
+			Capabilities:   a.config.Capabilities,
+			MaxConcurrency: a.config.MaxConcurrency,
+		}
 
-	agent, err := a.client.CreateAgent(req)
-	if err != nil {
-		return err
+		agent, err = a.client.CreateAgent(req)
+		if err != nil {
+			return err
+		}
 	}
 
 	a.id = agent.ID
@@ -160,6 +178,11 @@ func (a *Agent) register() error {
 func (a *Agent) tick() error {
 	a.lastTick = time.Now()
 
+	// Let the scheduler know the agent is alive
+	if err := a.client.Heartbeat(a.id); err != nil {
+		log.Warn().Err(err).Str("agent", a.id).Msg("Failed to send heartbeat")
+	}
+
 	// Handle randomized behavior
 	if a.config.Randomized {
 		a.handleRandomizedBehavior()
@@ -190,6 +213,17 @@ func (a *Agent) handleIdleState() error {
 		Bool("has_scenario", a.scenario != nil).
 		Msg("Agent is idle")
 
+	// Tasks the scheduler assigned to this agent come first
+	assigned, err := a.client.ListAssignedTasks(a.id)
+	if err != nil {
+		return fmt.Errorf("failed to list assigned tasks: %w", err)
+	}
+	if len(assigned) > 0 {
+		task := assigned[0]
+		log.Info().Str("agent", a.id).Str("task", task.Title).Msg("Picking up task assigned by scheduler")
+		return a.startTask(&task)
+	}
+
 	// Check for available tasks
 	tasks, err := a.client.ListTasks("pending", "", 10, 0)
 	if err != nil {
@@ -144,6 +144,16 @@ func (c *Client) DeleteAgent(agentID string) error {
 	return c.parseResponse(resp, nil)
 }
 
+func (c *Client) Heartbeat(agentID string) error {
+	path := fmt.Sprintf("/agents/%s/heartbeat", agentID)
+	resp, err := c.makeRequest("POST", path, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.parseResponse(resp, nil)
+}
+
 // Event operations
 
 func (c *Client) CreateEvent(agentID string, req models.CreateEventRequest) (*models.Event, error) {
@@ -333,6 +343,25 @@ func (c *Client) ListTasks(status, priority string, limit, offset int) ([]models
 	return response.Tasks, nil
 }
 
+// ListAssignedTasks lists the tasks the scheduler assigned to an agent
+func (c *Client) ListAssignedTasks(agentID string) ([]models.Task, error) {
+	params := url.Values{}
+	params.Add("status", string(models.TaskStatusAssigned))
+	params.Add("assigned_agent_id", agentID)
+
+	resp, err := c.makeRequest("GET", "/tasks?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response models.TasksListResponse
+	if err := c.parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Tasks, nil
+}
+
 func (c *Client) GetTask(taskID string) (*models.Task, error) {
 	path := fmt.Sprintf("/tasks/%s", taskID)
 	resp, err := c.makeRequest("GET", path, nil)
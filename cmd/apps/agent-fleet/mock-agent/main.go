@@ -33,20 +33,26 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file")
 	rootCmd.PersistentFlags().StringP("server", "s", "http://localhost:8080", "Fleet backend server URL")
-	rootCmd.PersistentFlags().StringP("token", "t", "fleet-agent-token-123", "Authentication token")
+	rootCmd.PersistentFlags().StringP("token", "t", "", "Authentication token, an operator token unless --agent-id is set")
 	rootCmd.PersistentFlags().StringP("name", "n", "", "Agent name (random if not specified)")
+	rootCmd.PersistentFlags().String("agent-id", "", "Run as an agent registered by an operator, with that agent's token")
 	rootCmd.PersistentFlags().StringP("worktree", "w", "/tmp/mock-project", "Agent worktree path")
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (trace, debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolP("randomized", "r", false, "Enable randomized behavior mode")
+	rootCmd.PersistentFlags().StringSlice("capabilities", []string{}, "Capabilities the scheduler matches tasks against (e.g. go,frontend)")
+	rootCmd.PersistentFlags().Int("max-concurrency", 1, "Number of tasks the scheduler may assign to the agent at once")
 	rootCmd.PersistentFlags().IntP("tick-interval", "", 5, "Agent tick interval in seconds")
 	rootCmd.PersistentFlags().IntP("command-check-interval", "", 2, "Command check interval in seconds")
 
 	viper.BindPFlag("server", rootCmd.PersistentFlags().Lookup("server"))
 	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
 	viper.BindPFlag("name", rootCmd.PersistentFlags().Lookup("name"))
+	viper.BindPFlag("agent-id", rootCmd.PersistentFlags().Lookup("agent-id"))
 	viper.BindPFlag("worktree", rootCmd.PersistentFlags().Lookup("worktree"))
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("randomized", rootCmd.PersistentFlags().Lookup("randomized"))
+	viper.BindPFlag("capabilities", rootCmd.PersistentFlags().Lookup("capabilities"))
+	viper.BindPFlag("max-concurrency", rootCmd.PersistentFlags().Lookup("max-concurrency"))
 	viper.BindPFlag("tick-interval", rootCmd.PersistentFlags().Lookup("tick-interval"))
 	viper.BindPFlag("command-check-interval", rootCmd.PersistentFlags().Lookup("command-check-interval"))
 }
@@ -119,11 +125,17 @@ func runAgent(cmd *cobra.Command, args []string) {
 	serverURL := viper.GetString("server")
 	token := viper.GetString("token")
 	name := viper.GetString("name")
+	agentID := viper.GetString("agent-id")
 	worktree := viper.GetString("worktree")
 	randomized := viper.GetBool("randomized")
+	capabilities := viper.GetStringSlice("capabilities")
+	maxConcurrency := viper.GetInt("max-concurrency")
 	tickInterval := viper.GetInt("tick-interval")
 	commandCheckInterval := viper.GetInt("command-check-interval")
 
+	if token == "" {
+		log.Fatal().Msg("--token is required")
+	}
 	if name == "" {
 		name = generateAgentName()
 	}
@@ -140,9 +152,12 @@ func runAgent(cmd *cobra.Command, args []string) {
 
 	// Create agent configuration
 	config := agent.Config{
+		AgentID:              agentID,
 		Name:                 name,
 		Worktree:             worktree,
 		Randomized:           randomized,
+		Capabilities:         capabilities,
+		MaxConcurrency:       maxConcurrency,
 		TickInterval:         time.Duration(tickInterval) * time.Second,
 		CommandCheckInterval: time.Duration(commandCheckInterval) * time.Second,
 	}
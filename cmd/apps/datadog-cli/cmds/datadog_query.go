@@ -2,9 +2,11 @@ package cmds
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
 	"github.com/go-go-golems/glazed/pkg/middlewares"
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
@@ -44,8 +46,13 @@ func NewDatadogQueryCommand(description *cmds.CommandDescription, query string,
 		return nil, errors.Wrap(err, "could not create datadog parameter layer")
 	}
 
+	patternsLayer, err := datadog_layers.NewPatternsParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create patterns parameter layer")
+	}
+
 	// Add layers to command description
-	description.Layers.AppendLayers(datadogLayer, glazedLayer)
+	description.Layers.AppendLayers(datadogLayer, patternsLayer, glazedLayer)
 
 	return &DatadogQueryCommand{
 		CommandDescription: description,
@@ -64,10 +71,22 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 		Str("command", "datadog_query").
 		Msg("Starting Datadog query command execution")
 
+	patternsMode, err := patternsEnabled(parsedLayers)
+	if err != nil {
+		return err
+	}
+	if patternsMode {
+		report, err := RunPatterns(ctx, parsedLayers, d)
+		if err != nil {
+			return err
+		}
+		return AddPatternRows(ctx, gp, report)
+	}
+
 	// Extract Datadog settings
 	log.Debug().Msg("Extracting Datadog settings from parsed layers")
 	ddSettings := &datadog_layers.DatadogSettings{}
-	err := parsedLayers.InitializeStruct("datadog", ddSettings)
+	err = parsedLayers.InitializeStruct("datadog", ddSettings)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -83,7 +102,7 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 
 	// Validate Datadog settings
 	log.Debug().Msg("Validating Datadog settings")
-	err = ddSettings.Validate()
+	ddConfig, err := ddSettings.ClientConfig()
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -93,12 +112,7 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 
 	// Create Datadog client
 	log.Debug().Msg("Creating Datadog API client")
-	ddClient, err := client.NewDatadogClient(client.DatadogConfig{
-		APIKey:  ddSettings.APIKey,
-		AppKey:  ddSettings.AppKey,
-		Site:    ddSettings.Site,
-		RawHTTP: ddSettings.RawHTTP,
-	})
+	ddClient, err := client.NewDatadogClient(ddConfig)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -111,6 +125,89 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 	log.Debug().Msg("Extracting parameters for template rendering")
 	params := parsedLayers.GetDataMap()
 
+	renderedQuery, err := d.renderQuery(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("query", renderedQuery.Query).
+		Time("from", renderedQuery.From).
+		Time("to", renderedQuery.To).
+		Int("limit", renderedQuery.Limit).
+		Msg("Executing Datadog logs search")
+
+	// Execute the search
+	log.Info().
+		Str("final_query", renderedQuery.Query).
+		Time("from", renderedQuery.From).
+		Time("to", renderedQuery.To).
+		Int("limit", renderedQuery.Limit).
+		Str("sort", renderedQuery.Sort).
+		Msg("Executing final Datadog logs search")
+
+	err = client.ExecuteLogsSearch(ctx, ddClient, renderedQuery, func(ctx context.Context, row types.Row) error {
+		return gp.AddRow(ctx, row)
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("query", renderedQuery.Query).
+			Msg("Failed to execute logs search")
+		return errors.Wrap(err, "failed to execute logs search")
+	}
+
+	log.Info().Msg("Datadog query command execution completed successfully")
+	return nil
+}
+
+// PatternQuery renders the query for patterns mode. The returned WindowQuery
+// renders it again with from and to replaced, so that time ranges in the
+// query template follow the window.
+func (d *DatadogQueryCommand) PatternQuery(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+) (dd_types.DatadogQuery, WindowQuery, error) {
+	params := parsedLayers.GetDataMap()
+
+	query, err := d.renderQuery(ctx, params)
+	if err != nil {
+		return dd_types.DatadogQuery{}, nil, err
+	}
+
+	window := func(from, to time.Time) (dd_types.DatadogQuery, error) {
+		windowParams := make(map[string]interface{}, len(params))
+		for k, v := range params {
+			windowParams[k] = v
+		}
+		windowParams["from"] = from
+		windowParams["to"] = to
+		return d.renderQuery(ctx, windowParams)
+	}
+
+	return query, window, nil
+}
+
+// renderQuery renders the query template, parsing relative from/to times
+// and applying the subquery metadata
+func (d *DatadogQueryCommand) renderQuery(
+	ctx context.Context,
+	params map[string]interface{},
+) (dd_types.DatadogQuery, error) {
+	// Declared flags that weren't set and have no default are missing from
+	// the parsed layers, give them an empty value so that the template can
+	// test them with {{ if .flag }}
+	for _, definitions := range []*parameters.ParameterDefinitions{
+		d.Description().GetDefaultFlags(),
+		d.Description().GetDefaultArguments(),
+	} {
+		definitions.ForEach(func(p *parameters.ParameterDefinition) {
+			if _, ok := params[p.Name]; !ok {
+				params[p.Name] = nil
+			}
+		})
+	}
+
 	// Log parameter keys for debugging (without values to avoid exposing sensitive data)
 	paramKeys := make([]string, 0, len(params))
 	for key := range params {
@@ -150,7 +247,7 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 			Err(err).
 			Str("query_template", d.Query).
 			Msg("Failed to render query template")
-		return errors.Wrap(err, "failed to render query")
+		return dd_types.DatadogQuery{}, errors.Wrap(err, "failed to render query")
 	}
 
 	log.Debug().
@@ -180,37 +277,9 @@ func (d *DatadogQueryCommand) RunIntoGlazeProcessor(
 			Err(err).
 			Str("query", renderedQuery.Query).
 			Msg("Query validation failed")
-		return errors.Wrap(err, "invalid rendered query")
+		return dd_types.DatadogQuery{}, errors.Wrap(err, "invalid rendered query")
 	}
 	log.Debug().Msg("Query validation successful")
 
-	log.Info().
-		Str("query", renderedQuery.Query).
-		Time("from", renderedQuery.From).
-		Time("to", renderedQuery.To).
-		Int("limit", renderedQuery.Limit).
-		Msg("Executing Datadog logs search")
-
-	// Execute the search
-	log.Info().
-		Str("final_query", renderedQuery.Query).
-		Time("from", renderedQuery.From).
-		Time("to", renderedQuery.To).
-		Int("limit", renderedQuery.Limit).
-		Str("sort", renderedQuery.Sort).
-		Msg("Executing final Datadog logs search")
-
-	err = client.ExecuteLogsSearch(ctx, ddClient, renderedQuery, func(ctx context.Context, row types.Row) error {
-		return gp.AddRow(ctx, row)
-	})
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("query", renderedQuery.Query).
-			Msg("Failed to execute logs search")
-		return errors.Wrap(err, "failed to execute logs search")
-	}
-
-	log.Info().Msg("Datadog query command execution completed successfully")
-	return nil
+	return renderedQuery, nil
 }
@@ -1,7 +1,21 @@
 package mcp
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
 	"github.com/go-go-golems/clay/pkg/repositories"
+	glazed_cmds "github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/middlewares"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	datadog_cmds "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/cmds"
+	datadog_layers "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/layers"
+	"github.com/go-go-golems/go-go-mcp/pkg/embeddable"
+	"github.com/go-go-golems/go-go-mcp/pkg/protocol"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -19,25 +33,226 @@ func NewMcpCommands(repositories []*repositories.Repository) *McpCommands {
 
 // AddToRootCommand adds MCP commands to the root command
 func (m *McpCommands) AddToRootCommand(rootCmd *cobra.Command) {
-	mcpCmd := &cobra.Command{
-		Use:   "mcp",
-		Short: "MCP (Model Context Protocol) server commands",
-		Long:  `Commands for running datadog-cli as an MCP server to provide Datadog API access to AI models.`,
-	}
-
-	// Add serve command
-	serveCmd := &cobra.Command{
-		Use:   "serve",
-		Short: "Start MCP server for datadog-cli",
-		Long: `Start an MCP server that provides Datadog API capabilities to AI models.
-The server exposes datadog-cli functionality through the Model Context Protocol.`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement MCP server
-			cmd.Println("MCP server functionality not yet implemented")
-			return nil
+	mcpCmd := embeddable.NewMCPCommand(
+		embeddable.WithName("datadog-cli"),
+		embeddable.WithServerDescription("Datadog logs queries and log pattern analysis"),
+
+		embeddable.WithEnhancedTool("list_queries", m.listQueriesHandler,
+			embeddable.WithEnhancedDescription(`List the YAML-defined Datadog log queries that log_patterns can run by name, with their parameters.`),
+			embeddable.WithReadOnlyHint(true),
+			embeddable.WithIdempotentHint(true),
+		),
+
+		embeddable.WithEnhancedTool("log_patterns", m.logPatternsHandler,
+			embeddable.WithEnhancedDescription(`Group the log messages matched by a Datadog query into patterns instead of returning raw lines.
+
+Numbers, IDs, UUIDs, IP addresses and timestamps in messages are masked, so that lines only differing in those end up in one pattern. Each pattern comes with its count, its count per time bucket, and how often it occurred in a baseline window (by default the window of the same length right before).
+
+Patterns that did not occur in the baseline are flagged "new", patterns occurring spike_factor times more often than expected are flagged "spike". Flagged patterns are listed first.
+
+Either pass a raw Datadog search query, or the name of a YAML query (see list_queries) with its parameters.`),
+			embeddable.WithReadOnlyHint(true),
+			embeddable.WithStringProperty("query",
+				embeddable.PropertyDescription("Raw Datadog search query, e.g. service:web-api status:error"),
+			),
+			embeddable.WithStringProperty("command",
+				embeddable.PropertyDescription("Name of a YAML query to run instead of a raw query"),
+			),
+			embeddable.WithObjectProperty("parameters",
+				embeddable.PropertyDescription("Parameters of the YAML query, e.g. {\"service\": \"web-api\"}"),
+			),
+			embeddable.WithStringProperty("from",
+				embeddable.PropertyDescription("Start of the window (relative like -1h or absolute)"),
+				embeddable.DefaultString("-1h"),
+			),
+			embeddable.WithStringProperty("to",
+				embeddable.PropertyDescription("End of the window (relative like now or absolute)"),
+				embeddable.DefaultString("now"),
+			),
+			embeddable.WithStringProperty("bucket",
+				embeddable.PropertyDescription("Time bucket size for the per-bucket counts"),
+				embeddable.DefaultString("5m"),
+			),
+			embeddable.WithStringProperty("baseline",
+				embeddable.PropertyDescription("Baseline window: previous, none, or an offset like 24h or 7d"),
+				embeddable.DefaultString("previous"),
+			),
+			embeddable.WithNumberProperty("spike_factor",
+				embeddable.PropertyDescription("How many times more often than expected a pattern has to occur to be flagged as a spike"),
+				embeddable.DefaultNumber(3),
+				embeddable.Minimum(1),
+			),
+			embeddable.WithIntProperty("min_count",
+				embeddable.PropertyDescription("Minimum occurrences before a pattern is flagged"),
+				embeddable.DefaultNumber(5),
+				embeddable.Minimum(1),
+			),
+			embeddable.WithIntProperty("max_logs",
+				embeddable.PropertyDescription("Maximum number of logs to fetch per window"),
+				embeddable.DefaultNumber(5000),
+				embeddable.Minimum(1),
+				embeddable.Maximum(50000),
+			),
+			embeddable.WithIntProperty("max_patterns",
+				embeddable.PropertyDescription("Maximum number of patterns to return"),
+				embeddable.DefaultNumber(50),
+				embeddable.Minimum(1),
+				embeddable.Maximum(500),
+			),
+		),
+	)
+
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func (m *McpCommands) listQueriesHandler(ctx context.Context, args embeddable.Arguments) (*protocol.ToolResult, error) {
+	type queryInfo struct {
+		Name       string            `json:"name"`
+		Short      string            `json:"short"`
+		Query      string            `json:"query"`
+		Parameters map[string]string `json:"parameters"`
+	}
+
+	queries := []queryInfo{}
+	for _, command := range m.queryCommands() {
+		info := queryInfo{
+			Name:       command.Description().Name,
+			Short:      command.Description().Short,
+			Query:      command.Query,
+			Parameters: map[string]string{},
+		}
+		defaultLayer, ok := command.Description().Layers.Get(layers.DefaultSlug)
+		if ok {
+			defaultLayer.GetParameterDefinitions().ForEach(func(p *parameters.ParameterDefinition) {
+				info.Parameters[p.Name] = fmt.Sprintf("%s: %s", p.Type, p.Help)
+			})
+		}
+		queries = append(queries, info)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Name < queries[j].Name
+	})
+
+	return jsonResult(queries)
+}
+
+func (m *McpCommands) logPatternsHandler(ctx context.Context, args embeddable.Arguments) (*protocol.ToolResult, error) {
+	query := args.GetString("query", "")
+	commandName := args.GetString("command", "")
+
+	defaults := map[string]interface{}{
+		"from": args.GetString("from", "-1h"),
+		"to":   args.GetString("to", "now"),
+	}
+
+	var command datadog_cmds.PatternCommand
+	var description *glazed_cmds.CommandDescription
+	switch {
+	case query != "" && commandName != "":
+		return errorResult("pass either query or command, not both"), nil
+	case query != "":
+		queryCmd, err := datadog_cmds.NewQueryCommand()
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to create query command: %v", err)), nil
+		}
+		defaults["search-query"] = query
+		command, description = queryCmd, queryCmd.Description()
+	case commandName != "":
+		queryCmd, ok := m.findQueryCommand(commandName)
+		if !ok {
+			return errorResult(fmt.Sprintf("unknown query %q, see list_queries", commandName)), nil
+		}
+		if raw, ok := args.Raw()["parameters"].(map[string]interface{}); ok {
+			for k, v := range raw {
+				defaults[k] = v
+			}
+		}
+		command, description = queryCmd, queryCmd.Description()
+	default:
+		return errorResult("either query or command is required"), nil
+	}
+
+	parsedLayers, err := parseToolLayers(description, map[string]map[string]interface{}{
+		layers.DefaultSlug: defaults,
+		datadog_layers.PatternsSlug: {
+			"patterns":              true,
+			"patterns-bucket":       args.GetString("bucket", "5m"),
+			"patterns-baseline":     args.GetString("baseline", "previous"),
+			"patterns-spike-factor": args.GetFloat("spike_factor", 3),
+			"patterns-min-count":    args.GetInt("min_count", 5),
+			"patterns-max-logs":     args.GetInt("max_logs", 5000),
 		},
+	})
+	if err != nil {
+		return errorResult(fmt.Sprintf("invalid parameters: %v", err)), nil
 	}
 
-	mcpCmd.AddCommand(serveCmd)
-	rootCmd.AddCommand(mcpCmd)
+	report, err := datadog_cmds.RunPatterns(ctx, parsedLayers, command)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to cluster logs: %v", err)), nil
+	}
+
+	if maxPatterns := args.GetInt("max_patterns", 50); len(report.Patterns) > maxPatterns {
+		report.Patterns = report.Patterns[:maxPatterns]
+	}
+
+	return jsonResult(report)
+}
+
+// parseToolLayers fills a command's layers from tool arguments, the
+// Datadog credentials come from the environment and config file like for
+// the CLI commands
+func parseToolLayers(
+	description *glazed_cmds.CommandDescription,
+	values map[string]map[string]interface{},
+) (*layers.ParsedLayers, error) {
+	parsedLayers := layers.NewParsedLayers()
+	err := middlewares.ExecuteMiddlewares(
+		description.Layers,
+		parsedLayers,
+		middlewares.UpdateFromMap(values, parameters.WithParseStepSource("mcp")),
+		middlewares.WrapWithWhitelistedLayers(
+			[]string{datadog_layers.DatadogSlug},
+			middlewares.GatherFlagsFromViper(parameters.WithParseStepSource("viper")),
+		),
+		middlewares.SetFromDefaults(parameters.WithParseStepSource("defaults")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parsedLayers, nil
+}
+
+// queryCommands returns the YAML queries loaded from the repositories
+func (m *McpCommands) queryCommands() []*datadog_cmds.DatadogQueryCommand {
+	ret := []*datadog_cmds.DatadogQueryCommand{}
+	for _, repository := range m.repositories {
+		for _, command := range repository.CollectCommands([]string{}, true) {
+			if queryCmd, ok := command.(*datadog_cmds.DatadogQueryCommand); ok {
+				ret = append(ret, queryCmd)
+			}
+		}
+	}
+	return ret
+}
+
+func (m *McpCommands) findQueryCommand(name string) (*datadog_cmds.DatadogQueryCommand, bool) {
+	for _, command := range m.queryCommands() {
+		if command.Description().Name == name || command.Description().FullPath() == name {
+			return command, true
+		}
+	}
+	return nil, false
+}
+
+func jsonResult(v interface{}) (*protocol.ToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal result")
+	}
+	return protocol.NewToolResult(protocol.WithText(string(data))), nil
+}
+
+func errorResult(message string) *protocol.ToolResult {
+	return protocol.NewErrorToolResult(protocol.NewTextContent(message))
 }
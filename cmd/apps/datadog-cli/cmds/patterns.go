@@ -0,0 +1,214 @@
+package cmds
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	datadog "github.com/DataDog/datadog-api-client-go/v2/api/datadog"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/client"
+	datadog_layers "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/layers"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/patterns"
+	dd_types "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// WindowQuery renders a command's query for another time window. Patterns
+// mode uses it to fetch the baseline with the same filters.
+type WindowQuery func(from, to time.Time) (dd_types.DatadogQuery, error)
+
+// PatternCommand is implemented by commands that support patterns mode
+type PatternCommand interface {
+	// PatternQuery renders the query for the parsed parameters and returns
+	// a function rendering it for other time windows
+	PatternQuery(ctx context.Context, parsedLayers *layers.ParsedLayers) (dd_types.DatadogQuery, WindowQuery, error)
+}
+
+var _ PatternCommand = (*DatadogQueryCommand)(nil)
+var _ PatternCommand = (*QueryCommand)(nil)
+
+// patternsEnabled reports whether --patterns was given
+func patternsEnabled(parsedLayers *layers.ParsedLayers) (bool, error) {
+	settings := &datadog_layers.PatternsSettings{}
+	err := parsedLayers.InitializeStruct(datadog_layers.PatternsSlug, settings)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to initialize patterns settings")
+	}
+	return settings.Enabled, nil
+}
+
+// RunPatterns fetches the logs matched by a command's query, and by the same
+// query over the baseline window, and clusters them into patterns
+func RunPatterns(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	command PatternCommand,
+) (*patterns.Report, error) {
+	settings := &datadog_layers.PatternsSettings{}
+	err := parsedLayers.InitializeStruct(datadog_layers.PatternsSlug, settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize patterns settings")
+	}
+
+	bucketSize, err := settings.BucketSize()
+	if err != nil {
+		return nil, err
+	}
+
+	query, window, err := command.PatternQuery(ctx, parsedLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	ddClient, err := newDatadogClientFromLayers(parsedLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := patterns.Options{
+		From:        query.From,
+		To:          query.To,
+		BucketSize:  bucketSize,
+		SpikeFactor: settings.SpikeFactor,
+		MinCount:    settings.MinCount,
+	}
+
+	log.Info().
+		Str("query", query.Query).
+		Time("from", query.From).
+		Time("to", query.To).
+		Str("bucket", bucketSize.String()).
+		Msg("Fetching logs for pattern clustering")
+
+	entries, err := fetchEntries(ctx, ddClient, query, settings.MaxLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline []patterns.Entry
+	if query.From.IsZero() || query.To.IsZero() {
+		if settings.Baseline != "none" {
+			log.Warn().Msg("Query has no from/to time range, skipping baseline comparison")
+		}
+	} else {
+		baselineFrom, baselineTo, err := patterns.BaselineWindow(settings.Baseline, query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		if !baselineFrom.IsZero() {
+			baselineQuery, err := window(baselineFrom, baselineTo)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to render baseline query")
+			}
+
+			log.Info().
+				Str("query", baselineQuery.Query).
+				Time("from", baselineFrom).
+				Time("to", baselineTo).
+				Msg("Fetching baseline logs for pattern clustering")
+
+			baseline, err = fetchEntries(ctx, ddClient, baselineQuery, settings.MaxLogs)
+			if err != nil {
+				return nil, err
+			}
+			opts.BaselineFrom, opts.BaselineTo = baselineFrom, baselineTo
+		}
+	}
+
+	report, err := patterns.Cluster(entries, baseline, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.Query = query.Query
+
+	log.Info().
+		Int("logs", report.Logs).
+		Int("baseline_logs", report.BaselineLogs).
+		Int("patterns", len(report.Patterns)).
+		Msg("Pattern clustering completed")
+
+	return report, nil
+}
+
+// AddPatternRows outputs one row per pattern
+func AddPatternRows(ctx context.Context, gp middlewares.Processor, report *patterns.Report) error {
+	for _, p := range report.Patterns {
+		row := types.NewRow(
+			types.MRP("anomaly", p.Anomaly),
+			types.MRP("count", p.Count),
+		)
+		if report.BaselineFrom != nil {
+			row.Set("baseline_count", p.BaselineCount)
+			row.Set("expected", p.Expected)
+			row.Set("ratio", p.Ratio)
+		}
+		row.Set("trend", p.Trend())
+		row.Set("pattern", p.Template)
+		row.Set("services", strings.Join(p.Services, ", "))
+		row.Set("statuses", strings.Join(p.Statuses, ", "))
+		row.Set("first_seen", p.FirstSeen)
+		row.Set("last_seen", p.LastSeen)
+		row.Set("buckets", p.Buckets)
+		row.Set("sample", p.Sample)
+
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchEntries runs a logs search and keeps what clustering needs
+func fetchEntries(
+	ctx context.Context,
+	ddClient *datadog.APIClient,
+	query dd_types.DatadogQuery,
+	maxLogs int,
+) ([]patterns.Entry, error) {
+	if maxLogs > 0 {
+		query.Limit = maxLogs
+	}
+
+	entries := []patterns.Entry{}
+	err := client.ExecuteLogsSearch(ctx, ddClient, query, func(ctx context.Context, row types.Row) error {
+		if maxLogs > 0 && len(entries) >= maxLogs {
+			return nil
+		}
+		entries = append(entries, patterns.EntryFromRow(row))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute logs search")
+	}
+
+	if maxLogs > 0 && len(entries) >= maxLogs {
+		log.Warn().
+			Int("max_logs", maxLogs).
+			Str("query", query.Query).
+			Msg("Reached patterns-max-logs, pattern counts only cover part of the window")
+	}
+	return entries, nil
+}
+
+// newDatadogClientFromLayers creates a Datadog client from the datadog layer
+func newDatadogClientFromLayers(parsedLayers *layers.ParsedLayers) (*datadog.APIClient, error) {
+	ddSettings := &datadog_layers.DatadogSettings{}
+	err := parsedLayers.InitializeStruct(datadog_layers.DatadogSlug, ddSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize Datadog settings")
+	}
+	ddConfig, err := ddSettings.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ddClient, err := client.NewDatadogClient(ddConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Datadog client")
+	}
+	return ddClient, nil
+}
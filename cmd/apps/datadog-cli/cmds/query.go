@@ -2,6 +2,7 @@ package cmds
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
@@ -36,6 +37,11 @@ func NewQueryCommand() (*QueryCommand, error) {
 		return nil, errors.Wrap(err, "could not create datadog parameter layer")
 	}
 
+	patternsLayer, err := datadog_layers.NewPatternsParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create patterns parameter layer")
+	}
+
 	return &QueryCommand{
 		CommandDescription: cmds.NewCommandDescription(
 			"query",
@@ -47,6 +53,7 @@ Examples:
   datadog-cli logs query "service:web-api AND status:error"
   datadog-cli logs query "host:prod-* AND @timestamp:[now-1h TO now]"
   datadog-cli logs query "@error.message:*timeout*" --limit 50
+  datadog-cli logs query "status:error" --from -1h --patterns --patterns-baseline 24h
 `),
 			cmds.WithArguments(
 				parameters.NewParameterDefinition(
@@ -82,7 +89,7 @@ Examples:
 					parameters.WithHelp("Sort order (asc or desc)"),
 				),
 			),
-			cmds.WithLayersList(datadogLayer, glazedLayer),
+			cmds.WithLayersList(datadogLayer, patternsLayer, glazedLayer),
 		),
 	}, nil
 }
@@ -106,10 +113,22 @@ func (q *QueryCommand) RunIntoGlazeProcessor(
 		Str("command", "raw_query").
 		Msg("Starting raw Datadog query execution")
 
+	patternsMode, err := patternsEnabled(parsedLayers)
+	if err != nil {
+		return err
+	}
+	if patternsMode {
+		report, err := RunPatterns(ctx, parsedLayers, q)
+		if err != nil {
+			return err
+		}
+		return AddPatternRows(ctx, gp, report)
+	}
+
 	// Extract settings
 	log.Debug().Msg("Extracting query settings from parsed layers")
 	settings := &QuerySettings{}
-	err := parsedLayers.InitializeStruct(layers.DefaultSlug, settings)
+	err = parsedLayers.InitializeStruct(layers.DefaultSlug, settings)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -138,7 +157,7 @@ func (q *QueryCommand) RunIntoGlazeProcessor(
 
 	// Validate Datadog settings
 	log.Debug().Msg("Validating Datadog settings")
-	err = ddSettings.Validate()
+	ddConfig, err := ddSettings.ClientConfig()
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -148,12 +167,7 @@ func (q *QueryCommand) RunIntoGlazeProcessor(
 
 	// Create Datadog client
 	log.Debug().Msg("Creating Datadog API client")
-	ddClient, err := client.NewDatadogClient(client.DatadogConfig{
-		APIKey:  ddSettings.APIKey,
-		AppKey:  ddSettings.AppKey,
-		Site:    ddSettings.Site,
-		RawHTTP: ddSettings.RawHTTP,
-	})
+	ddClient, err := client.NewDatadogClient(ddConfig)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -234,3 +248,42 @@ func (q *QueryCommand) RunIntoGlazeProcessor(
 	log.Info().Msg("Raw Datadog query execution completed successfully")
 	return nil
 }
+
+// PatternQuery builds the search for patterns mode, the time window only
+// changes the from and to of the search
+func (q *QueryCommand) PatternQuery(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+) (dd_types.DatadogQuery, WindowQuery, error) {
+	settings := &QuerySettings{}
+	err := parsedLayers.InitializeStruct(layers.DefaultSlug, settings)
+	if err != nil {
+		return dd_types.DatadogQuery{}, nil, errors.Wrap(err, "failed to initialize query settings")
+	}
+
+	fromTime, err := utils.ParseTimeParameter(settings.From)
+	if err != nil {
+		return dd_types.DatadogQuery{}, nil, errors.Wrapf(err, "failed to parse from time: %s", settings.From)
+	}
+	toTime, err := utils.ParseTimeParameter(settings.To)
+	if err != nil {
+		return dd_types.DatadogQuery{}, nil, errors.Wrapf(err, "failed to parse to time: %s", settings.To)
+	}
+
+	query := dd_types.DatadogQuery{
+		Query: settings.SearchQuery,
+		From:  fromTime,
+		To:    toTime,
+		Limit: settings.Limit,
+		Sort:  settings.Sort,
+	}
+
+	window := func(from, to time.Time) (dd_types.DatadogQuery, error) {
+		windowQuery := query
+		windowQuery.From = from
+		windowQuery.To = to
+		return windowQuery, nil
+	}
+
+	return query, window, nil
+}
@@ -36,6 +36,11 @@ func NewRunCommand() (*RunCommand, error) {
 		return nil, errors.Wrap(err, "could not create datadog parameter layer")
 	}
 
+	patternsLayer, err := datadog_layers.NewPatternsParameterLayer()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create patterns parameter layer")
+	}
+
 	return &RunCommand{
 		CommandDescription: cmds.NewCommandDescription(
 			"run",
@@ -48,7 +53,7 @@ func NewRunCommand() (*RunCommand, error) {
 					parameters.WithRequired(true),
 				),
 			),
-			cmds.WithLayersList(datadogLayer, glazedLayer),
+			cmds.WithLayersList(datadogLayer, patternsLayer, glazedLayer),
 		),
 	}, nil
 }
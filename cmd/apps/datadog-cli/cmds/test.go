@@ -104,7 +104,7 @@ func (c *AuthTestCommand) RunIntoGlazeProcessor(
 		Msg("Testing authentication with provided credentials")
 
 	// Validate settings
-	err = ddSettings.Validate()
+	ddConfig, err := ddSettings.ClientConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Datadog settings validation failed")
 		return gp.AddRow(ctx, types.NewRow(
@@ -115,12 +115,7 @@ func (c *AuthTestCommand) RunIntoGlazeProcessor(
 	}
 
 	// Create client
-	ddClient, err := client.NewDatadogClient(client.DatadogConfig{
-		APIKey:  ddSettings.APIKey,
-		AppKey:  ddSettings.AppKey,
-		Site:    ddSettings.Site,
-		RawHTTP: ddSettings.RawHTTP,
-	})
+	ddClient, err := client.NewDatadogClient(ddConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Datadog client")
 		return gp.AddRow(ctx, types.NewRow(
@@ -278,19 +273,14 @@ func (c *LogsV1TestCommand) RunIntoGlazeProcessor(
 		Msg("Testing Logs API v1 with parameters")
 
 	// Validate settings
-	err = ddSettings.Validate()
+	ddConfig, err := ddSettings.ClientConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Datadog settings validation failed")
 		return errors.Wrap(err, "Datadog settings validation failed")
 	}
 
 	// Create client
-	ddClient, err := client.NewDatadogClient(client.DatadogConfig{
-		APIKey:  ddSettings.APIKey,
-		AppKey:  ddSettings.AppKey,
-		Site:    ddSettings.Site,
-		RawHTTP: ddSettings.RawHTTP,
-	})
+	ddClient, err := client.NewDatadogClient(ddConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Datadog client")
 		return errors.Wrap(err, "failed to create Datadog client")
@@ -491,19 +481,14 @@ func (c *LogsV2TestCommand) RunIntoGlazeProcessor(
 		Msg("Testing Logs API v2 with parameters")
 
 	// Validate settings
-	err = ddSettings.Validate()
+	ddConfig, err := ddSettings.ClientConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Datadog settings validation failed")
 		return errors.Wrap(err, "Datadog settings validation failed")
 	}
 
 	// Create client
-	ddClient, err := client.NewDatadogClient(client.DatadogConfig{
-		APIKey:  ddSettings.APIKey,
-		AppKey:  ddSettings.AppKey,
-		Site:    ddSettings.Site,
-		RawHTTP: ddSettings.RawHTTP,
-	})
+	ddClient, err := client.NewDatadogClient(ddConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Datadog client")
 		return errors.Wrap(err, "failed to create Datadog client")
@@ -20,8 +20,10 @@ import (
 	"github.com/go-go-golems/glazed/pkg/cmds/loaders"
 	"github.com/go-go-golems/glazed/pkg/cmds/logging"
 	"github.com/go-go-golems/glazed/pkg/help"
+	help_cmd "github.com/go-go-golems/glazed/pkg/help/cmd"
 	"github.com/go-go-golems/glazed/pkg/types"
 	datadog_cmds "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/cmds"
+	datadog_mcp "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/cmds/mcp"
 	datadog_layers "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/layers"
 	datadog_loaders "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/loaders"
 	"github.com/pkg/errors"
@@ -143,7 +145,7 @@ func initRootCmd() (*help.HelpSystem, error) {
 	err = clay_doc.AddDocToHelpSystem(helpSystem)
 	cobra.CheckErr(err)
 
-	helpSystem.SetupCobraRootCommand(rootCmd)
+	help_cmd.SetupCobraRootCommand(helpSystem, rootCmd)
 
 	err = clay.InitViper("datadog-cli", rootCmd)
 	cobra.CheckErr(err)
@@ -238,6 +240,9 @@ func initAllCommands(helpSystem *help.HelpSystem) error {
 		return err
 	}
 
+	// Add the MCP server, exposing the loaded queries and pattern analysis
+	datadog_mcp.NewMcpCommands(repositories_).AddToRootCommand(rootCmd)
+
 	// Create and add the unified command management group
 	commandManagementCmd, err := clay_commandmeta.NewCommandManagementCommandGroup(
 		allCommands,
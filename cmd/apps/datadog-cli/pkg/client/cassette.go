@@ -0,0 +1,261 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette holds recorded HTTP interactions with the Datadog API, so that
+// commands can be run again without network access or API keys.
+//
+// Only the method, path, body and status are recorded. Request headers, and
+// with them the API keys, are never written to a cassette.
+type Cassette struct {
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `yaml:"request"`
+	Response RecordedResponse `yaml:"response"`
+}
+
+// RecordedRequest is the part of a request used to match it on replay
+type RecordedRequest struct {
+	Method string `yaml:"method"`
+	// URL is the path and query, without the site's host
+	URL  string `yaml:"url"`
+	Body string `yaml:"body,omitempty"`
+}
+
+// RecordedResponse is replayed for a matching request
+type RecordedResponse struct {
+	Status      int    `yaml:"status"`
+	ContentType string `yaml:"content_type,omitempty"`
+	Body        string `yaml:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cassette %s", path)
+	}
+
+	cassette := &Cassette{}
+	if err := yaml.Unmarshal(data, cassette); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse cassette %s", path)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to a file
+func (c *Cassette) Save(path string) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return errors.Wrap(err, "failed to encode cassette")
+	}
+	if err := encoder.Close(); err != nil {
+		return errors.Wrap(err, "failed to encode cassette")
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// RecordingTransport sends requests to the Datadog API and appends each
+// interaction to a cassette file
+type RecordingTransport struct {
+	Next     http.RoundTripper
+	Path     string
+	cassette *Cassette
+	mu       sync.Mutex
+}
+
+var _ http.RoundTripper = (*RecordingTransport)(nil)
+
+// NewRecordingTransport creates a transport recording into a new cassette
+// at path, replacing an existing file
+func NewRecordingTransport(path string) *RecordingTransport {
+	return &RecordingTransport{
+		Next:     http.DefaultTransport,
+		Path:     path,
+		cassette: &Cassette{Interactions: []*Interaction{}},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Body:   prettyJSON(requestBody),
+		},
+		Response: RecordedResponse{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        prettyJSON(responseBody),
+		},
+	})
+
+	// Save after every interaction, commands don't tell the client when
+	// they are done
+	if err := t.cassette.Save(t.Path); err != nil {
+		return nil, errors.Wrapf(err, "failed to save cassette %s", t.Path)
+	}
+	log.Debug().
+		Str("cassette", t.Path).
+		Str("url", req.URL.RequestURI()).
+		Int("status", resp.StatusCode).
+		Msg("Recorded interaction")
+
+	return resp, nil
+}
+
+// ReplayTransport answers requests from a cassette without network access.
+//
+// Requests match a recorded interaction on method, path and JSON body, with
+// timestamps masked so that queries with relative times such as -1h still
+// match later. Each interaction is used once in recording order, a request
+// matching only used interactions gets the last of them again.
+type ReplayTransport struct {
+	cassette *Cassette
+	used     []bool
+	mu       sync.Mutex
+}
+
+var _ http.RoundTripper = (*ReplayTransport)(nil)
+
+// NewReplayTransport creates a transport replaying a cassette
+func NewReplayTransport(cassette *Cassette) *ReplayTransport {
+	return &ReplayTransport{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := matchKey(req.Method, req.URL.RequestURI(), requestBody)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := -1
+	for i, interaction := range t.cassette.Interactions {
+		r := interaction.Request
+		if matchKey(r.Method, r.URL, []byte(r.Body)) != key {
+			continue
+		}
+		match = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, errors.Errorf("no recorded interaction for %s %s with body %s",
+			req.Method, req.URL.RequestURI(), normalizeBody(requestBody))
+	}
+	t.used[match] = true
+
+	recorded := t.cassette.Interactions[match].Response
+	header := http.Header{}
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+
+	log.Debug().
+		Str("url", req.URL.RequestURI()).
+		Int("interaction", match).
+		Int("status", recorded.Status).
+		Msg("Replayed interaction")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the body of a request and puts it back
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read request body")
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func matchKey(method string, url string, body []byte) string {
+	return method + " " + url + " " + normalizeBody(body)
+}
+
+var rfc3339Regex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// normalizeBody renders JSON bodies canonically (sorted keys, no
+// whitespace) and masks timestamps
+func normalizeBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	return rfc3339Regex.ReplaceAllString(strings.TrimSpace(string(body)), "<time>")
+}
+
+// prettyJSON indents JSON bodies so that cassettes can be read and edited
+func prettyJSON(body []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return string(body)
+	}
+	return buf.String() + "\n"
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, transport http.RoundTripper, url string, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", "secret-api-key")
+	req.Header.Set("DD-APPLICATION-KEY", "secret-app-key")
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp, string(data)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), `"cursor"`) {
+			_, _ = w.Write([]byte(`{"data":[{"id":"2"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"1"}],"meta":{"page":{"after":"abc"}}}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	recorder := NewRecordingTransport(path)
+
+	firstPage := `{"filter":{"query":"service:web","from":"2025-01-01T10:00:00Z","to":"2025-01-01T11:00:00Z"}}`
+	secondPage := `{"filter":{"query":"service:web","from":"2025-01-01T10:00:00Z","to":"2025-01-01T11:00:00Z"},"page":{"cursor":"abc"}}`
+	post(t, recorder, server.URL+"/api/v2/logs/events/search", firstPage)
+	post(t, recorder, server.URL+"/api/v2/logs/events/search", secondPage)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Cassette contains the API keys:\n%s", data)
+	}
+	if strings.Contains(string(data), server.URL) {
+		t.Errorf("Cassette contains the host:\n%s", data)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("Expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+
+	// Replay on another host, with other times and another key order
+	replay := NewReplayTransport(cassette)
+	resp, body := post(t, replay, "https://api.datadoghq.eu/api/v2/logs/events/search",
+		`{"filter":{"to":"2026-03-04T12:00:00+01:00","from":"2026-03-04T11:00:00+01:00","query":"service:web"}}`)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, `"after": "abc"`) {
+		t.Errorf("Expected first page, got %s", body)
+	}
+
+	_, body = post(t, replay, "https://api.datadoghq.eu/api/v2/logs/events/search",
+		`{"page":{"cursor":"abc"},"filter":{"query":"service:web","from":"2026-03-04T10:00:00Z","to":"2026-03-04T11:00:00Z"}}`)
+	if !strings.Contains(body, `"id": "2"`) {
+		t.Errorf("Expected second page, got %s", body)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected replay not to call the server, got %d calls", calls)
+	}
+}
+
+func TestReplayUsesInteractionsInOrder(t *testing.T) {
+	request := RecordedRequest{Method: http.MethodPost, URL: "/api/v2/logs/events/search", Body: `{"filter":{"query":"*"}}`}
+	replay := NewReplayTransport(&Cassette{Interactions: []*Interaction{
+		{Request: request, Response: RecordedResponse{Status: 200, Body: "first"}},
+		{Request: request, Response: RecordedResponse{Status: 200, Body: "second"}},
+	}})
+
+	for _, expected := range []string{"first", "second", "second"} {
+		_, body := post(t, replay, "https://api.datadoghq.com/api/v2/logs/events/search", `{"filter":{"query":"*"}}`)
+		if body != expected {
+			t.Errorf("Expected %q, got %q", expected, body)
+		}
+	}
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	replay := NewReplayTransport(&Cassette{Interactions: []*Interaction{
+		{
+			Request:  RecordedRequest{Method: http.MethodPost, URL: "/api/v2/logs/events/search", Body: `{"filter":{"query":"service:web"}}`},
+			Response: RecordedResponse{Status: 200, Body: "{}"},
+		},
+	}})
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.datadoghq.com/api/v2/logs/events/search",
+		strings.NewReader(`{"filter":{"query":"service:api","from":"2026-03-04T10:00:00Z"}}`))
+	_, err := replay.RoundTrip(req)
+	if err == nil {
+		t.Fatal("Expected an error for a request missing from the cassette")
+	}
+	if !strings.Contains(err.Error(), `{"filter":{"from":"<time>","query":"service:api"}}`) {
+		t.Errorf("Expected the error to show the normalized body, got %v", err)
+	}
+}
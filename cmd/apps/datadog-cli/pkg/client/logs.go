@@ -12,6 +12,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// maxPageSize is the largest page the Logs Search API accepts, larger limits
+// are fetched over several pages
+const maxPageSize = 5000
+
 // ExecuteLogsSearch executes a Datadog logs search and streams results to a processor
 func ExecuteLogsSearch(
 	ctx context.Context,
@@ -62,7 +66,7 @@ func ExecuteLogsSearch(
 
 	// Set limit if provided
 	if query.Limit > 0 {
-		limit := int32(query.Limit)
+		limit := int32(min(query.Limit, maxPageSize))
 		searchRequest.Page = &datadogV2.LogsListRequestPage{
 			Limit: &limit,
 		}
@@ -83,17 +87,6 @@ func ExecuteLogsSearch(
 		Str("sort", query.Sort).
 		Msg("Executing Datadog logs search")
 
-	// Create auth context
-	log.Debug().Msg("Creating authentication context for API request")
-	auth := context.WithValue(
-		ctx,
-		datadog.ContextAPIKeys,
-		map[string]datadog.APIKey{
-			"apiKeyAuth": {Key: ""}, // Will be set by client
-			"appKeyAuth": {Key: ""}, // Will be set by client
-		},
-	)
-
 	// Execute the search with pagination
 	var cursor *string
 	totalProcessed := 0
@@ -121,7 +114,7 @@ func ExecuteLogsSearch(
 			Msg("Making API request to Datadog")
 
 		opts := datadogV2.NewListLogsOptionalParameters().WithBody(searchRequest)
-		resp, httpResp, err := logsApi.ListLogs(auth, *opts)
+		resp, httpResp, err := logsApi.ListLogs(ctx, *opts)
 		if err != nil {
 			logResponseBodyOnError(httpResp, err, "logs_search_v2")
 			return errors.Wrap(err, "failed to execute logs search")
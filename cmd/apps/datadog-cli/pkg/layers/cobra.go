@@ -15,11 +15,11 @@ import (
 
 func BuildCobraCommandWithDatadogMiddlewares(
 	cmd cmds.Command,
-	options ...cli.CobraParserOption,
+	options ...cli.CobraOption,
 ) (*cobra.Command, error) {
-	options_ := append([]cli.CobraParserOption{
+	options_ := append([]cli.CobraOption{
 		cli.WithCobraMiddlewaresFunc(GetCobraCommandDatadogMiddlewares),
-		cli.WithCobraShortHelpLayers(layers.DefaultSlug, DatadogSlug),
+		cli.WithCobraShortHelpLayers(layers.DefaultSlug, DatadogSlug, PatternsSlug),
 		cli.WithCreateCommandSettingsLayer(),
 		cli.WithProfileSettingsLayer(),
 	}, options...)
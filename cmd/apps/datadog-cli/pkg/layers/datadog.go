@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-go-golems/glazed/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/client"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -40,6 +41,17 @@ func NewDatadogParameterLayer() (layers.ParameterLayer, error) {
 				parameters.ParameterTypeBool,
 				parameters.WithHelp("Enable raw, un-redacted HTTP debug output"),
 			),
+			parameters.NewParameterDefinition(
+				"cassette",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Replay Datadog API responses from this cassette file instead of calling the API"),
+			),
+			parameters.NewParameterDefinition(
+				"record",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Call the Datadog API and record the responses into --cassette"),
+				parameters.WithDefault(false),
+			),
 		),
 	)
 }
@@ -57,10 +69,17 @@ func maskKey(key string) string {
 
 // DatadogSettings represents Datadog configuration parameters
 type DatadogSettings struct {
-	APIKey  string `glazed.parameter:"api-key"`
-	AppKey  string `glazed.parameter:"app-key"`
-	Site    string `glazed.parameter:"site"`
-	RawHTTP bool   `glazed.parameter:"raw-http-debug"`
+	APIKey   string `glazed.parameter:"api-key"`
+	AppKey   string `glazed.parameter:"app-key"`
+	Site     string `glazed.parameter:"site"`
+	RawHTTP  bool   `glazed.parameter:"raw-http-debug"`
+	Cassette string `glazed.parameter:"cassette"`
+	Record   bool   `glazed.parameter:"record"`
+}
+
+// Replaying reports whether responses come from a cassette
+func (d *DatadogSettings) Replaying() bool {
+	return d.Cassette != "" && !d.Record
 }
 
 // Validate checks if the Datadog settings are valid
@@ -71,8 +90,18 @@ func (d *DatadogSettings) Validate() error {
 		Bool("app_key_set", d.AppKey != "").
 		Str("app_key_prefix", maskKey(d.AppKey)).
 		Str("site", d.Site).
+		Str("cassette", d.Cassette).
+		Bool("record", d.Record).
 		Msg("Validating Datadog settings")
 
+	if d.Record && d.Cassette == "" {
+		return errors.New("record requires a cassette file to record into")
+	}
+	if d.Replaying() {
+		log.Debug().Str("cassette", d.Cassette).Msg("Replaying cassette, API keys are not required")
+		return nil
+	}
+
 	if d.APIKey == "" {
 		log.Error().
 			Msg("API key is missing - check DATADOG_CLI_API_KEY or DATADOG_API_KEY environment variables")
@@ -92,3 +121,36 @@ func (d *DatadogSettings) Validate() error {
 	log.Debug().Msg("Datadog settings validation successful")
 	return nil
 }
+
+// ClientConfig validates the settings and returns the client configuration,
+// with a transport recording or replaying the cassette if one is given
+func (d *DatadogSettings) ClientConfig() (client.DatadogConfig, error) {
+	if err := d.Validate(); err != nil {
+		return client.DatadogConfig{}, err
+	}
+
+	config := client.DatadogConfig{
+		APIKey:  d.APIKey,
+		AppKey:  d.AppKey,
+		Site:    d.Site,
+		RawHTTP: d.RawHTTP,
+	}
+
+	switch {
+	case d.Record:
+		log.Info().Str("cassette", d.Cassette).Msg("Recording Datadog API responses")
+		config.Transport = client.NewRecordingTransport(d.Cassette)
+	case d.Cassette != "":
+		cassette, err := client.LoadCassette(d.Cassette)
+		if err != nil {
+			return client.DatadogConfig{}, err
+		}
+		log.Info().
+			Str("cassette", d.Cassette).
+			Int("interactions", len(cassette.Interactions)).
+			Msg("Replaying Datadog API responses")
+		config.Transport = client.NewReplayTransport(cassette)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,84 @@
+package layers
+
+import (
+	"time"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/patterns"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const PatternsSlug = "patterns"
+
+// NewPatternsParameterLayer creates a parameter layer for clustering log
+// messages into patterns instead of returning the raw lines
+func NewPatternsParameterLayer() (layers.ParameterLayer, error) {
+	log.Debug().Msg("Creating patterns parameter layer")
+
+	return layers.NewParameterLayer(
+		PatternsSlug,
+		"Log pattern clustering",
+		layers.WithParameterDefinitions(
+			parameters.NewParameterDefinition(
+				"patterns",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Group log messages into patterns instead of returning raw logs"),
+				parameters.WithDefault(false),
+			),
+			parameters.NewParameterDefinition(
+				"patterns-bucket",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Time bucket size for pattern counts (e.g. 1m, 5m, 1h)"),
+				parameters.WithDefault("5m"),
+			),
+			parameters.NewParameterDefinition(
+				"patterns-baseline",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Baseline window: previous (same length before from), none, or an offset like 24h or 7d"),
+				parameters.WithDefault("previous"),
+			),
+			parameters.NewParameterDefinition(
+				"patterns-spike-factor",
+				parameters.ParameterTypeFloat,
+				parameters.WithHelp("Flag a pattern as spiking when it occurs this many times more often than in the baseline"),
+				parameters.WithDefault(3.0),
+			),
+			parameters.NewParameterDefinition(
+				"patterns-min-count",
+				parameters.ParameterTypeInteger,
+				parameters.WithHelp("Minimum occurrences before a pattern is flagged as new or spiking"),
+				parameters.WithDefault(5),
+			),
+			parameters.NewParameterDefinition(
+				"patterns-max-logs",
+				parameters.ParameterTypeInteger,
+				parameters.WithHelp("Maximum number of logs to fetch per window when clustering"),
+				parameters.WithDefault(5000),
+			),
+		),
+	)
+}
+
+// PatternsSettings represents the pattern clustering parameters
+type PatternsSettings struct {
+	Enabled     bool    `glazed.parameter:"patterns"`
+	Bucket      string  `glazed.parameter:"patterns-bucket"`
+	Baseline    string  `glazed.parameter:"patterns-baseline"`
+	SpikeFactor float64 `glazed.parameter:"patterns-spike-factor"`
+	MinCount    int     `glazed.parameter:"patterns-min-count"`
+	MaxLogs     int     `glazed.parameter:"patterns-max-logs"`
+}
+
+// BucketSize parses the bucket size
+func (p *PatternsSettings) BucketSize() (time.Duration, error) {
+	d, err := patterns.ParseDuration(p.Bucket)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid patterns-bucket %q", p.Bucket)
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("patterns-bucket %q must be positive", p.Bucket)
+	}
+	return d, nil
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/alias"
-	"github.com/go-go-golems/glazed/pkg/cmds/layers"
 	"github.com/go-go-golems/glazed/pkg/cmds/loaders"
 	datadog_cmds "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/cmds"
 	dd_types "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/types"
@@ -54,51 +53,31 @@ func (d *DatadogYAMLCommandLoader) loadDatadogCommand(
 		return nil, err
 	}
 
-	// Parse the full YAML to extract all data
-	var yamlData map[string]interface{}
-	err = yaml.Unmarshal(buf, &yamlData)
+	var commandDescription dd_types.DatadogCommandDescription
+	err = yaml.Unmarshal(buf, &commandDescription)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse YAML")
 	}
 
-	// Parse into CommandDescription
-	description := &cmds.CommandDescription{}
-	err = yaml.Unmarshal(buf, description)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse command description")
+	if commandDescription.Query == "" {
+		return nil, errors.New("query field is required in YAML file")
 	}
 
-	// Initialize Layers if nil (required for AppendLayers to work)
-	if description.Layers == nil {
-		description.Layers = layers.NewParameterLayers()
-	}
+	description := cmds.NewCommandDescription(
+		commandDescription.Name,
+		cmds.WithShort(commandDescription.Short),
+		cmds.WithLong(commandDescription.Long),
+		cmds.WithFlags(commandDescription.Flags...),
+		cmds.WithArguments(commandDescription.Arguments...),
+	)
 
 	// Apply additional options
 	for _, option := range options {
 		option(description)
 	}
 
-	// Extract query
-	query, ok := yamlData["query"].(string)
-	if !ok {
-		return nil, errors.New("query field is required in YAML file")
-	}
-
-	// Extract subqueries metadata
-	var subqueries dd_types.QueryMetadata
-	if subqueriesData, exists := yamlData["subqueries"]; exists {
-		subqueriesBytes, err := yaml.Marshal(subqueriesData)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to marshal subqueries")
-		}
-		err = yaml.Unmarshal(subqueriesBytes, &subqueries)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal subqueries")
-		}
-	}
-
 	// Create the Datadog query command
-	datadogCmd, err := datadog_cmds.NewDatadogQueryCommand(description, query, subqueries)
+	datadogCmd, err := datadog_cmds.NewDatadogQueryCommand(description, commandDescription.Query, commandDescription.Subqueries)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create datadog query command")
 	}
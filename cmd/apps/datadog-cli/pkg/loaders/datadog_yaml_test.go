@@ -0,0 +1,113 @@
+package loaders
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	cmd_middlewares "github.com/go-go-golems/glazed/pkg/cmds/middlewares"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	datadog_layers "github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/layers"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// rowCollector keeps the rows a command outputs
+type rowCollector struct {
+	rows []types.Row
+}
+
+var _ middlewares.Processor = (*rowCollector)(nil)
+
+func (c *rowCollector) AddRow(ctx context.Context, row types.Row) error {
+	c.rows = append(c.rows, row)
+	return nil
+}
+
+func (c *rowCollector) Close(ctx context.Context) error {
+	return nil
+}
+
+// runQueryFile loads a query file from queries/ and runs it against a
+// cassette, returning the output rows as JSON
+func runQueryFile(t *testing.T, queryFile string, cassette string, params map[string]interface{}) []byte {
+	t.Helper()
+
+	commands, err := NewDatadogYAMLCommandLoader().LoadCommands(os.DirFS("../../queries"), queryFile, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", queryFile, err)
+	}
+	if len(commands) != 1 {
+		t.Fatalf("Expected 1 command, got %d", len(commands))
+	}
+	command, ok := commands[0].(cmds.GlazeCommand)
+	if !ok {
+		t.Fatalf("Expected a glaze command, got %T", commands[0])
+	}
+
+	parsedLayers := layers.NewParsedLayers()
+	err = cmd_middlewares.ExecuteMiddlewares(
+		command.Description().Layers,
+		parsedLayers,
+		cmd_middlewares.UpdateFromMap(map[string]map[string]interface{}{
+			layers.DefaultSlug:         params,
+			datadog_layers.DatadogSlug: {"cassette": cassette},
+		}, parameters.WithParseStepSource("test")),
+		cmd_middlewares.SetFromDefaults(parameters.WithParseStepSource("defaults")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to parse parameters: %v", err)
+	}
+
+	ctx := context.Background()
+	gp := &rowCollector{}
+	if err := command.RunIntoGlazeProcessor(ctx, parsedLayers, gp); err != nil {
+		t.Fatalf("Failed to run %s: %v", queryFile, err)
+	}
+
+	rows, err := json.MarshalIndent(gp.rows, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(rows, '\n')
+}
+
+func compareGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	goldenPath := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file, run go test -update: %v", err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("Output differs from %s (run go test -update if intended)\nexpected:\n%s\nactual:\n%s",
+			goldenPath, expected, actual)
+	}
+}
+
+// The cassette has two pages, the second one still has a cursor but the
+// limit is reached, so a third request would fail the replay
+func TestServiceLogsPagination(t *testing.T) {
+	rows := runQueryFile(t, "service_logs.yaml", "testdata/service_logs.cassette.yaml", map[string]interface{}{
+		"service": "web-api",
+		"level":   []string{"error"},
+		"from":    "2025-01-01T10:00:00Z",
+		"to":      "2025-01-01T11:00:00Z",
+		"limit":   3,
+	})
+	compareGolden(t, "service_logs", rows)
+}
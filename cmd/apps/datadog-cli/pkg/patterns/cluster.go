@@ -0,0 +1,318 @@
+package patterns
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/pkg/errors"
+)
+
+// Anomaly labels set on patterns compared against a baseline window
+const (
+	AnomalyNew   = "new"
+	AnomalySpike = "spike"
+)
+
+// maxBuckets keeps the per-bucket counts readable when the bucket size is
+// much smaller than the queried window
+const maxBuckets = 500
+
+// Entry is the part of a log line that clustering looks at
+type Entry struct {
+	Timestamp time.Time
+	Message   string
+	Service   string
+	Status    string
+}
+
+// EntryFromRow extracts an Entry from a row produced by client.ExecuteLogsSearch
+func EntryFromRow(row types.Row) Entry {
+	entry := Entry{}
+	if v, ok := row.Get("timestamp"); ok {
+		if s, ok := v.(string); ok {
+			entry.Timestamp, _ = time.Parse(time.RFC3339, s)
+		}
+	}
+	if v, ok := row.Get("message"); ok {
+		entry.Message = fmt.Sprint(v)
+	}
+	if v, ok := row.Get("service"); ok {
+		entry.Service = fmt.Sprint(v)
+	}
+	if v, ok := row.Get("status"); ok {
+		entry.Status = fmt.Sprint(v)
+	}
+	return entry
+}
+
+// Options configure clustering
+type Options struct {
+	// From and To delimit the analysed window. When zero, the window spans
+	// the first to the last log line.
+	From time.Time
+	To   time.Time
+	// BucketSize is the width of the time buckets patterns are counted in
+	BucketSize time.Duration
+	// BaselineFrom and BaselineTo delimit the baseline window, leave them
+	// zero to skip the comparison
+	BaselineFrom time.Time
+	BaselineTo   time.Time
+	// SpikeFactor is how many times more often than expected from the
+	// baseline a pattern has to occur to be flagged as a spike
+	SpikeFactor float64
+	// MinCount is the number of occurrences below which a pattern is never
+	// flagged, to keep rare lines from drowning the real anomalies
+	MinCount int
+}
+
+// HasBaseline reports whether a baseline window is configured
+func (o Options) HasBaseline() bool {
+	return !o.BaselineFrom.IsZero() && o.BaselineTo.After(o.BaselineFrom)
+}
+
+// Pattern is a group of log lines sharing a template
+type Pattern struct {
+	Template      string    `json:"template"`
+	Count         int       `json:"count"`
+	BaselineCount int       `json:"baseline_count"`
+	Expected      float64   `json:"expected"`
+	Ratio         float64   `json:"ratio"`
+	Anomaly       string    `json:"anomaly,omitempty"`
+	Buckets       []int     `json:"buckets"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	Services      []string  `json:"services"`
+	Statuses      []string  `json:"statuses"`
+	Sample        string    `json:"sample"`
+
+	services map[string]bool
+	statuses map[string]bool
+}
+
+// Trend renders the bucket counts as a sparkline
+func (p *Pattern) Trend() string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+
+	peak := 0
+	for _, n := range p.Buckets {
+		if n > peak {
+			peak = n
+		}
+	}
+
+	var sb strings.Builder
+	for _, n := range p.Buckets {
+		switch {
+		case n == 0:
+			sb.WriteRune(' ')
+		case peak == 0:
+			sb.WriteRune(levels[0])
+		default:
+			sb.WriteRune(levels[(n*(len(levels)-1))/peak])
+		}
+	}
+	return sb.String()
+}
+
+// Report is the result of clustering a window against its baseline
+type Report struct {
+	Query        string     `json:"query"`
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	BaselineFrom *time.Time `json:"baseline_from,omitempty"`
+	BaselineTo   *time.Time `json:"baseline_to,omitempty"`
+	Bucket       string     `json:"bucket"`
+	Logs         int        `json:"logs"`
+	BaselineLogs int        `json:"baseline_logs"`
+	Patterns     []*Pattern `json:"patterns"`
+}
+
+// Cluster groups entries into patterns, counts them per bucket and compares
+// them against the baseline entries. Patterns flagged as new or spiking come
+// first, then the rest by count.
+func Cluster(entries []Entry, baseline []Entry, opts Options) (*Report, error) {
+	if opts.BucketSize <= 0 {
+		return nil, errors.New("bucket size must be positive")
+	}
+
+	from, to := opts.From, opts.To
+	if from.IsZero() || to.IsZero() {
+		from, to = timeRange(entries)
+	}
+
+	bucketCount := int(to.Sub(from) / opts.BucketSize)
+	if to.Sub(from)%opts.BucketSize != 0 || bucketCount == 0 {
+		bucketCount++
+	}
+	if bucketCount > maxBuckets {
+		return nil, errors.Errorf("bucket size %s is too small for a window of %s (more than %d buckets)",
+			opts.BucketSize, to.Sub(from), maxBuckets)
+	}
+
+	report := &Report{
+		From:         from,
+		To:           to,
+		Bucket:       opts.BucketSize.String(),
+		Logs:         len(entries),
+		BaselineLogs: len(baseline),
+	}
+
+	byTemplate := map[string]*Pattern{}
+	for _, entry := range entries {
+		template := Template(entry.Message)
+		p, ok := byTemplate[template]
+		if !ok {
+			p = &Pattern{
+				Template: template,
+				Buckets:  make([]int, bucketCount),
+				Sample:   entry.Message,
+				services: map[string]bool{},
+				statuses: map[string]bool{},
+			}
+			byTemplate[template] = p
+		}
+
+		p.Count++
+		if !entry.Timestamp.IsZero() {
+			if i := int(entry.Timestamp.Sub(from) / opts.BucketSize); i >= 0 && i < bucketCount {
+				p.Buckets[i]++
+			}
+			if p.FirstSeen.IsZero() || entry.Timestamp.Before(p.FirstSeen) {
+				p.FirstSeen = entry.Timestamp
+			}
+			if entry.Timestamp.After(p.LastSeen) {
+				p.LastSeen = entry.Timestamp
+			}
+		}
+		if entry.Service != "" {
+			p.services[entry.Service] = true
+		}
+		if entry.Status != "" {
+			p.statuses[entry.Status] = true
+		}
+	}
+
+	if opts.HasBaseline() {
+		bf, bt := opts.BaselineFrom, opts.BaselineTo
+		report.BaselineFrom, report.BaselineTo = &bf, &bt
+
+		baselineCounts := map[string]int{}
+		for _, entry := range baseline {
+			baselineCounts[Template(entry.Message)]++
+		}
+
+		// Scale the baseline to the length of the analysed window, so that
+		// a 24h baseline can be compared against the last hour
+		scale := float64(to.Sub(from)) / float64(bt.Sub(bf))
+		for template, p := range byTemplate {
+			p.BaselineCount = baselineCounts[template]
+			p.Expected = round(float64(p.BaselineCount)*scale, 2)
+			if p.Expected > 0 {
+				p.Ratio = round(float64(p.Count)/p.Expected, 2)
+			}
+			p.Anomaly = classify(p, opts)
+		}
+	}
+
+	for _, p := range byTemplate {
+		p.Services = sortedKeys(p.services)
+		p.Statuses = sortedKeys(p.statuses)
+		report.Patterns = append(report.Patterns, p)
+	}
+	sort.Slice(report.Patterns, func(i, j int) bool {
+		a, b := report.Patterns[i], report.Patterns[j]
+		if (a.Anomaly != "") != (b.Anomaly != "") {
+			return a.Anomaly != ""
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Template < b.Template
+	})
+
+	return report, nil
+}
+
+func classify(p *Pattern, opts Options) string {
+	if p.Count < opts.MinCount {
+		return ""
+	}
+	if p.BaselineCount == 0 {
+		return AnomalyNew
+	}
+	if opts.SpikeFactor > 0 && float64(p.Count) >= opts.SpikeFactor*p.Expected {
+		return AnomalySpike
+	}
+	return ""
+}
+
+// BaselineWindow computes the baseline window for the analysed window
+// [from, to). The spec is one of:
+//
+//	previous  the window of the same length right before from
+//	none      no baseline
+//	24h, 7d   the same window shifted back by that duration
+func BaselineWindow(spec string, from, to time.Time) (time.Time, time.Time, error) {
+	switch spec {
+	case "", "none":
+		return time.Time{}, time.Time{}, nil
+	case "previous":
+		return from.Add(-to.Sub(from)), from, nil
+	}
+
+	offset, err := ParseDuration(spec)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrapf(err, "invalid baseline %q (use previous, none or a duration like 24h or 7d)", spec)
+	}
+	if offset <= 0 {
+		return time.Time{}, time.Time{}, errors.Errorf("baseline offset %q must be positive", spec)
+	}
+	return from.Add(-offset), to.Add(-offset), nil
+}
+
+// ParseDuration parses a Go duration, also accepting days such as "7d"
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64); err == nil {
+			return time.Duration(days * float64(24*time.Hour)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func timeRange(entries []Entry) (time.Time, time.Time) {
+	var from, to time.Time
+	for _, entry := range entries {
+		if entry.Timestamp.IsZero() {
+			continue
+		}
+		if from.IsZero() || entry.Timestamp.Before(from) {
+			from = entry.Timestamp
+		}
+		if entry.Timestamp.After(to) {
+			to = entry.Timestamp
+		}
+	}
+	// Make the last line fall into the last bucket
+	return from, to.Add(time.Second)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func round(f float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(f*p) / p
+}
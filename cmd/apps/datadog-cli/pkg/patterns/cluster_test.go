@@ -0,0 +1,242 @@
+package patterns
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var windowStart = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// repeat returns n entries with the message, spread every step from start.
+// %d in the message is replaced by the index so that entries vary.
+func repeat(n int, message string, start time.Time, step time.Duration, service string) []Entry {
+	entries := make([]Entry, n)
+	for i := range entries {
+		text := message
+		if strings.Contains(message, "%d") {
+			text = fmt.Sprintf(message, 1000+i)
+		}
+		entries[i] = Entry{
+			Timestamp: start.Add(time.Duration(i) * step),
+			Message:   text,
+			Service:   service,
+			Status:    "error",
+		}
+	}
+	return entries
+}
+
+func concat(groups ...[]Entry) []Entry {
+	var entries []Entry
+	for _, group := range groups {
+		entries = append(entries, group...)
+	}
+	return entries
+}
+
+func TestClusterAgainstBaseline(t *testing.T) {
+	// One hour window against a two hour baseline right before it, the
+	// baseline counts are halved to get the expected counts
+	baselineStart := windowStart.Add(-2 * time.Hour)
+	entries := concat(
+		repeat(10, "user %d not found", windowStart, 6*time.Minute, "api"),
+		repeat(12, "timeout after %dms", windowStart.Add(45*time.Minute), time.Minute, "worker"),
+		repeat(5, "disk full on node-%d", windowStart.Add(20*time.Minute), time.Minute, "storage"),
+		repeat(1, "certificate expires in %d days", windowStart.Add(time.Minute), 0, "api"),
+	)
+	// One timeout logged by another service
+	entries[11].Service = "api"
+	baseline := concat(
+		repeat(20, "user %d not found", baselineStart, 6*time.Minute, "api"),
+		repeat(4, "timeout after %dms", baselineStart, 30*time.Minute, "worker"),
+		repeat(3, "gone away", baselineStart, time.Minute, "api"),
+	)
+
+	report, err := Cluster(entries, baseline, Options{
+		From:         windowStart,
+		To:           windowStart.Add(time.Hour),
+		BucketSize:   15 * time.Minute,
+		BaselineFrom: baselineStart,
+		BaselineTo:   windowStart,
+		SpikeFactor:  3,
+		MinCount:     3,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Logs != 28 || report.BaselineLogs != 27 || report.Bucket != "15m0s" {
+		t.Errorf("report logs %d, baseline logs %d, bucket %s", report.Logs, report.BaselineLogs, report.Bucket)
+	}
+	if report.BaselineFrom == nil || !report.BaselineFrom.Equal(baselineStart) {
+		t.Errorf("baseline from = %v, want %s", report.BaselineFrom, baselineStart)
+	}
+
+	type pattern struct {
+		template string
+		count    int
+		baseline int
+		expected float64
+		ratio    float64
+		anomaly  string
+		buckets  []int
+		services []string
+	}
+	var got []pattern
+	for _, p := range report.Patterns {
+		got = append(got, pattern{p.Template, p.Count, p.BaselineCount, p.Expected, p.Ratio, p.Anomaly, p.Buckets, p.Services})
+	}
+	want := []pattern{
+		// Anomalies first, by count
+		{"timeout after <num>ms", 12, 4, 2, 6, AnomalySpike, []int{0, 0, 0, 12}, []string{"api", "worker"}},
+		{"disk full on <id>", 5, 0, 0, 0, AnomalyNew, []int{0, 5, 0, 0}, []string{"storage"}},
+		// As often as in the baseline
+		{"user <num> not found", 10, 20, 10, 1, "", []int{3, 2, 3, 2}, []string{"api"}},
+		// New but below MinCount
+		{"certificate expires in <num> days", 1, 0, 0, 0, "", []int{1, 0, 0, 0}, []string{"api"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("patterns:\n got %+v\nwant %+v", got, want)
+	}
+
+	timeouts := report.Patterns[0]
+	if !timeouts.FirstSeen.Equal(windowStart.Add(45*time.Minute)) || !timeouts.LastSeen.Equal(windowStart.Add(56*time.Minute)) {
+		t.Errorf("timeouts seen %s to %s", timeouts.FirstSeen, timeouts.LastSeen)
+	}
+	if timeouts.Sample != "timeout after 1000ms" || !reflect.DeepEqual(timeouts.Statuses, []string{"error"}) {
+		t.Errorf("timeouts sample %q, statuses %v", timeouts.Sample, timeouts.Statuses)
+	}
+}
+
+func TestClusterSpikeThreshold(t *testing.T) {
+	// A 24h baseline shifted by a day, with the same length as the window
+	baselineFrom, baselineTo, err := BaselineWindow("24h", windowStart, windowStart.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		count       int
+		spikeFactor float64
+		want        string
+	}{
+		{count: 8, spikeFactor: 2, want: AnomalySpike},
+		{count: 7, spikeFactor: 2, want: ""},
+		{count: 4, spikeFactor: 2, want: ""},
+		{count: 40, spikeFactor: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d at %.0fx", tt.count, tt.spikeFactor), func(t *testing.T) {
+			entries := repeat(tt.count, "queue %d is backing up", windowStart, time.Minute, "worker")
+			baseline := repeat(4, "queue %d is backing up", baselineFrom, time.Minute, "worker")
+			report, err := Cluster(entries, baseline, Options{
+				From:         windowStart,
+				To:           windowStart.Add(time.Hour),
+				BucketSize:   time.Hour,
+				BaselineFrom: baselineFrom,
+				BaselineTo:   baselineTo,
+				SpikeFactor:  tt.spikeFactor,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p := report.Patterns[0]
+			if p.Expected != 4 || p.Anomaly != tt.want {
+				t.Errorf("expected %v, anomaly %q, want 4 and %q", p.Expected, p.Anomaly, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterWithoutBaseline(t *testing.T) {
+	entries := concat(
+		repeat(3, "user %d not found", windowStart, 10*time.Minute, "api"),
+		// Outside the window, counted but not bucketed
+		repeat(1, "user %d not found", windowStart.Add(-time.Hour), 0, "api"),
+		// Without timestamp
+		[]Entry{{Message: "user 7 not found"}},
+	)
+	report, err := Cluster(entries, nil, Options{
+		From:       windowStart,
+		To:         windowStart.Add(30 * time.Minute),
+		BucketSize: 10 * time.Minute,
+		MinCount:   1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.BaselineFrom != nil || len(report.Patterns) != 1 {
+		t.Fatalf("expected one pattern without baseline, got %d patterns, baseline %v", len(report.Patterns), report.BaselineFrom)
+	}
+	p := report.Patterns[0]
+	if p.Anomaly != "" || p.Count != 5 || !reflect.DeepEqual(p.Buckets, []int{1, 1, 1}) {
+		t.Errorf("pattern anomaly %q, count %d, buckets %v", p.Anomaly, p.Count, p.Buckets)
+	}
+	if !p.FirstSeen.Equal(windowStart.Add(-time.Hour)) {
+		t.Errorf("first seen = %s", p.FirstSeen)
+	}
+}
+
+func TestClusterWindowFromEntries(t *testing.T) {
+	entries := repeat(3, "user %d not found", windowStart, 25*time.Minute, "api")
+	report, err := Cluster(entries, nil, Options{BucketSize: 30 * time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The last line at 10:50 falls into the last bucket
+	if !report.From.Equal(windowStart) || !report.To.Equal(windowStart.Add(50*time.Minute+time.Second)) {
+		t.Errorf("window = %s to %s", report.From, report.To)
+	}
+	if got := report.Patterns[0].Buckets; !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("buckets = %v, want [2 1]", got)
+	}
+}
+
+func TestClusterBucketSize(t *testing.T) {
+	opts := Options{From: windowStart, To: windowStart.Add(24 * time.Hour)}
+	if _, err := Cluster(nil, nil, opts); err == nil {
+		t.Error("expected an error for a zero bucket size")
+	}
+	opts.BucketSize = time.Minute
+	if _, err := Cluster(nil, nil, opts); err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("expected an error for too many buckets, got %v", err)
+	}
+}
+
+func TestBaselineWindow(t *testing.T) {
+	from, to := windowStart, windowStart.Add(time.Hour)
+	tests := []struct {
+		spec     string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{spec: "none"},
+		{spec: ""},
+		{spec: "previous", wantFrom: from.Add(-time.Hour), wantTo: from},
+		{spec: "24h", wantFrom: from.Add(-24 * time.Hour), wantTo: to.Add(-24 * time.Hour)},
+		{spec: "7d", wantFrom: from.Add(-7 * 24 * time.Hour), wantTo: to.Add(-7 * 24 * time.Hour)},
+		{spec: "1.5d", wantFrom: from.Add(-36 * time.Hour), wantTo: to.Add(-36 * time.Hour)},
+		{spec: "-1h", wantErr: true},
+		{spec: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		gotFrom, gotTo, err := BaselineWindow(tt.spec, from, to)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("BaselineWindow(%q): expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("BaselineWindow(%q): unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if !gotFrom.Equal(tt.wantFrom) || !gotTo.Equal(tt.wantTo) {
+			t.Errorf("BaselineWindow(%q) = %s to %s, want %s to %s", tt.spec, gotFrom, gotTo, tt.wantFrom, tt.wantTo)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package patterns
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxTemplateLength caps templates so that huge messages (stack traces,
+// payload dumps) still group on their first line
+const maxTemplateLength = 200
+
+var (
+	uuidRegex      = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	timestampRegex = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	ipRegex        = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`)
+	tokenRegex     = regexp.MustCompile(`[A-Za-z0-9_\-.]*\d[A-Za-z0-9_\-.]*`)
+	hexRegex       = regexp.MustCompile(`^0[xX][0-9a-fA-F]+$`)
+	unitRegex      = regexp.MustCompile(`^\d+(\.\d+)?([a-zA-Z]{1,3})$`)
+	spaceRegex     = regexp.MustCompile(`\s+`)
+)
+
+// Template turns a log message into the pattern it is grouped under.
+//
+// Only the first line is used. UUIDs, timestamps, IP addresses, numbers and
+// identifiers mixing letters and digits are replaced by placeholders, so that
+// "user 4711 not found (req a8f3c2d9e1)" and "user 42 not found (req
+// 77b1e0c2aa)" both become "user <num> not found (req <id>)".
+func Template(message string) string {
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+
+	message = uuidRegex.ReplaceAllString(message, "<uuid>")
+	message = timestampRegex.ReplaceAllString(message, "<ts>")
+	message = ipRegex.ReplaceAllString(message, "<ip>")
+	message = tokenRegex.ReplaceAllStringFunc(message, maskToken)
+	message = strings.TrimSpace(spaceRegex.ReplaceAllString(message, " "))
+
+	if message == "" {
+		return "(empty)"
+	}
+	if runes := []rune(message); len(runes) > maxTemplateLength {
+		message = string(runes[:maxTemplateLength]) + "..."
+	}
+	return message
+}
+
+// maskToken masks a word containing at least one digit. Plain numbers and
+// hex literals become <num>, numbers keep a short unit ("12ms" becomes
+// "<num>ms"), anything longer that mixes letters and digits is treated as an
+// identifier. Short words such as "http2" or "v1" are kept.
+func maskToken(token string) string {
+	// A trailing dot ends the sentence rather than the token
+	trimmed := strings.TrimRight(token, ".")
+	return maskWord(trimmed) + token[len(trimmed):]
+}
+
+func maskWord(token string) string {
+	// Checked first, "0xff" would otherwise read as 0 with the unit "xff"
+	if hexRegex.MatchString(token) {
+		return "<num>"
+	}
+	if m := unitRegex.FindStringSubmatch(token); m != nil {
+		return "<num>" + m[2]
+	}
+
+	hasLetter := false
+	for _, r := range token {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+			break
+		}
+	}
+	if !hasLetter {
+		if strings.Trim(token, "-_.") == "" {
+			return token
+		}
+		return "<num>"
+	}
+	if len(token) < 6 {
+		return token
+	}
+	return "<id>"
+}
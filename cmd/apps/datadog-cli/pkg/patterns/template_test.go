@@ -0,0 +1,95 @@
+package patterns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"plain", "connection refused", "connection refused"},
+		{"empty", " \n stack", "(empty)"},
+		{"first line only", "panic: nil map\ngoroutine 1 [running]:", "panic: nil map"},
+		{"whitespace", "  too   many\tspaces ", "too many spaces"},
+
+		{"integer", "user 4711 not found", "user <num> not found"},
+		{"negative", "balance -250 below limit", "balance <num> below limit"},
+		{"decimal", "load 0.75 over 2 minutes", "load <num> over <num> minutes"},
+		{"unit", "took 12ms (limit 1.5s)", "took <num>ms (limit <num>s)"},
+		{"sentence end", "retrying in 30.", "retrying in <num>."},
+		{"version", "upgraded to 1.2.3", "upgraded to <num>"},
+
+		{"hex id", "req a8f3c2d9e1 failed", "req <id> failed"},
+		{"hex literal", "segfault at 0x7ffd5e8c", "segfault at <num>"},
+		{"short hex literal", "exit status 0x1f", "exit status <num>"},
+		{"sha", "deployed 3f2a9c1", "deployed <id>"},
+		{"short words kept", "http2 stream v1 e2e", "http2 stream v1 e2e"},
+
+		{"uuid", "order 5f0c6e4a-1d2b-4c3d-9e8f-0a1b2c3d4e5f shipped", "order <uuid> shipped"},
+		{"uppercase uuid", "trace 5F0C6E4A-1D2B-4C3D-9E8F-0A1B2C3D4E5F", "trace <uuid>"},
+		{"timestamp", "expired at 2024-05-01T10:00:00.123Z", "expired at <ts>"},
+		{"timestamp with offset", "job 2024-05-01 10:00:00+02:00 late", "job <ts> late"},
+
+		{"ip", "dial tcp 10.0.3.17: i/o timeout", "dial tcp <ip>: i/o timeout"},
+		{"ip and port", "upstream 192.168.1.20:8080 down", "upstream <ip> down"},
+
+		{"quoted word kept", `unknown field "email"`, `unknown field "email"`},
+		{"quoted id", `key "order-4711" not found`, `key "<id>" not found`},
+		{"quoted sentence", `error: "retry 3 of 5 failed"`, `error: "retry <num> of <num> failed"`},
+		{"single quotes", "table 'events_2024_05' missing", "table '<id>' missing"},
+		{"quoted uuid", `session="5f0c6e4a-1d2b-4c3d-9e8f-0a1b2c3d4e5f"`, `session="<uuid>"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Template(tt.message); got != tt.want {
+				t.Errorf("Template(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateGroupsVariants(t *testing.T) {
+	a := Template("user 4711 not found (req a8f3c2d9e1)")
+	b := Template("user 42 not found (req 77b1e0c2aa)")
+	if a != b {
+		t.Errorf("expected the same template, got %q and %q", a, b)
+	}
+}
+
+func TestTemplateTruncates(t *testing.T) {
+	got := Template(strings.Repeat("é", maxTemplateLength+10))
+	if want := strings.Repeat("é", maxTemplateLength) + "..."; got != want {
+		t.Errorf("expected %d runes and an ellipsis, got %d runes", maxTemplateLength, len([]rune(got)))
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"42", "<num>"},
+		{"3.14", "<num>"},
+		{"-1", "<num>"},
+		{"512KB", "<num>KB"},
+		{"250ms.", "<num>ms."},
+		{"7.", "<num>."},
+		{"0xff", "<num>"},
+		{"0XDEADBEEF", "<num>"},
+		{"deadbeef42", "<id>"},
+		{"user_123", "<id>"},
+		{"v1", "v1"},
+		{"x86", "x86"},
+		{"1234abcd", "<id>"},
+	}
+	for _, tt := range tests {
+		if got := maskToken(tt.token); got != tt.want {
+			t.Errorf("maskToken(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
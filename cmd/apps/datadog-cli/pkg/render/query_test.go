@@ -0,0 +1,120 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/datadog-cli/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// renderCase renders a query file with fixed parameters, the rendered query
+// is compared to testdata/<case>.golden
+type renderCase struct {
+	QueryFile string                 `yaml:"query_file"`
+	Params    map[string]interface{} `yaml:"params"`
+}
+
+// caseParams converts the case parameters to what the parameter layers
+// would pass to the template
+func caseParams(t *testing.T, description types.DatadogCommandDescription, c renderCase) map[string]interface{} {
+	t.Helper()
+
+	params := map[string]interface{}{}
+	for _, f := range description.Flags {
+		params[f.Name] = nil
+	}
+	for k, v := range c.Params {
+		switch v := v.(type) {
+		case []interface{}:
+			values := make([]string, len(v))
+			for i, value := range v {
+				values[i] = value.(string)
+			}
+			params[k] = values
+		default:
+			params[k] = v
+		}
+	}
+	for _, k := range []string{"from", "to"} {
+		if s, ok := params[k].(string); ok {
+			parsed, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				t.Fatalf("Invalid %s: %v", k, err)
+			}
+			params[k] = parsed
+		}
+	}
+	return params
+}
+
+func TestRenderQueryFiles(t *testing.T) {
+	cases, err := filepath.Glob("testdata/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("No test cases found in testdata")
+	}
+
+	for _, casePath := range cases {
+		name := strings.TrimSuffix(filepath.Base(casePath), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(casePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c renderCase
+			if err := yaml.Unmarshal(data, &c); err != nil {
+				t.Fatalf("Failed to parse case: %v", err)
+			}
+
+			data, err = os.ReadFile(c.QueryFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var description types.DatadogCommandDescription
+			if err := yaml.Unmarshal(data, &description); err != nil {
+				t.Fatalf("Failed to parse query file: %v", err)
+			}
+
+			query, err := RenderDatadogQuery(context.Background(), description.Query, caseParams(t, description, c))
+			if err != nil {
+				t.Fatalf("Failed to render query: %v", err)
+			}
+			if err := ValidateQuery(query.Query); err != nil {
+				t.Errorf("Rendered query is invalid: %v", err)
+			}
+
+			actual, err := json.MarshalIndent(query, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			goldenPath := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file, run go test -update: %v", err)
+			}
+			if string(expected) != string(actual) {
+				t.Errorf("Rendered query differs from %s (run go test -update if intended)\nexpected:\n%s\nactual:\n%s",
+					goldenPath, expected, actual)
+			}
+		})
+	}
+}
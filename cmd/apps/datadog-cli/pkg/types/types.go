@@ -2,6 +2,8 @@ package types
 
 import (
 	"time"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
 )
 
 // DatadogQuery represents a Datadog Logs Search API query
@@ -21,3 +23,14 @@ type QueryMetadata struct {
 	Sort    string   `yaml:"sort,omitempty"`
 	Aggs    []string `yaml:"aggs,omitempty"`
 }
+
+// DatadogCommandDescription is the YAML structure of a query file
+type DatadogCommandDescription struct {
+	Name       string                            `yaml:"name"`
+	Short      string                            `yaml:"short"`
+	Long       string                            `yaml:"long,omitempty"`
+	Flags      []*parameters.ParameterDefinition `yaml:"flags,omitempty"`
+	Arguments  []*parameters.ParameterDefinition `yaml:"arguments,omitempty"`
+	Query      string                            `yaml:"query"`
+	Subqueries QueryMetadata                     `yaml:"subqueries,omitempty"`
+}
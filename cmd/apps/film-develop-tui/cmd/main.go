@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/rs/zerolog"
@@ -13,8 +14,10 @@ import (
 )
 
 var (
-	logLevel string
-	version  = "dev"
+	logLevel     string
+	databasePath string
+	historyPath  string
+	version      = "dev"
 )
 
 func main() {
@@ -28,8 +31,14 @@ Features:
 - Film type and EI rating selection
 - Tank size calculation based on roll count and format
 - Chemical dilution calculations
-- Fixer capacity tracking
-- Development time display`,
+- Fixer capacity tracking across sessions
+- Development time display with temperature compensation
+- Session log with the actual step durations and notes
+
+Films, push/pull times, developers and temperature factors can be added
+in a YAML database that is merged over the built-in one, by default
+~/.config/film-develop-tui/database.yaml. Logged sessions and the fixer
+state are kept in ~/.config/film-develop-tui/sessions.json.`,
 		Version: version,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Setup logging
@@ -37,8 +46,17 @@ Features:
 
 			log.Info().Msg("Starting film development TUI")
 
+			if err := setupPaths(); err != nil {
+				return err
+			}
+
+			appState, err := pkg.LoadApplicationState(databasePath, historyPath)
+			if err != nil {
+				return err
+			}
+
 			// Create and run the application
-			model := pkg.NewModel()
+			model := pkg.NewModelWithState(appState)
 
 			p := tea.NewProgram(
 				model,
@@ -57,6 +75,8 @@ Features:
 
 	// Add flags
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Set the log level (debug, info, warn, error)")
+	rootCmd.Flags().StringVar(&databasePath, "database", "", "User YAML database (default ~/.config/film-develop-tui/database.yaml)")
+	rootCmd.Flags().StringVar(&historyPath, "history", "", "Session log (default ~/.config/film-develop-tui/sessions.json)")
 
 	// Execute the command
 	if err := rootCmd.Execute(); err != nil {
@@ -80,3 +100,22 @@ func setupLogging() {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
 }
+
+// setupPaths fills in the default database and session log paths
+func setupPaths() error {
+	if databasePath != "" && historyPath != "" {
+		return nil
+	}
+
+	configDir, err := pkg.DefaultConfigDir()
+	if err != nil {
+		return err
+	}
+	if databasePath == "" {
+		databasePath = filepath.Join(configDir, "database.yaml")
+	}
+	if historyPath == "" {
+		historyPath = filepath.Join(configDir, "sessions.json")
+	}
+	return nil
+}
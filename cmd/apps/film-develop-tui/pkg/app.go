@@ -19,7 +19,13 @@ type Model struct {
 
 // NewModel creates a new application model
 func NewModel() *Model {
-	sm := NewStateMachine()
+	return NewModelWithState(NewApplicationState())
+}
+
+// NewModelWithState creates a new application model for an application
+// state with loaded databases and log
+func NewModelWithState(appState *ApplicationState) *Model {
+	sm := NewStateMachineWithState(appState)
 	return &Model{
 		stateMachine: sm,
 		screen:       GetScreenForState(sm.GetCurrentState()),
@@ -1,5 +1,7 @@
 package pkg
 
+import "sort"
+
 // NewFilmDatabase creates a new film database with all the film data
 func NewFilmDatabase() *FilmDatabase {
 	return &FilmDatabase{
@@ -119,6 +121,7 @@ func NewChemicalDatabase() *ChemicalDatabase {
 				Time:      "",
 				Type:      "one_shot",
 				Capacity:  "",
+				Role:      RoleDeveloper,
 			},
 			"ilfostop": {
 				Name:      "ILFOSTOP",
@@ -127,6 +130,7 @@ func NewChemicalDatabase() *ChemicalDatabase {
 				Time:      "0:10",
 				Type:      "reusable",
 				Capacity:  "15 rolls per liter",
+				Role:      RoleStop,
 			},
 			"sprint_fixer": {
 				Name:      "SPRINT FIXER",
@@ -135,12 +139,28 @@ func NewChemicalDatabase() *ChemicalDatabase {
 				Time:      "2:30",
 				Type:      "reusable",
 				Capacity:  "24 rolls per liter",
+				Role:      RoleFixer,
 			},
 		},
 	}
 }
 
-// GetFilmOrder returns the films in the order they should be displayed
+// NewTemperatureDatabase creates the time compensation table for 18-24°C
+func NewTemperatureDatabase() *TemperatureDatabase {
+	return &TemperatureDatabase{
+		Factors: map[float64]float64{
+			18: 1.18,
+			19: 1.08,
+			20: 1.00,
+			21: 0.92,
+			22: 0.85,
+			23: 0.78,
+			24: 0.72,
+		},
+	}
+}
+
+// GetFilmOrder returns the built-in films in the order they should be displayed
 func GetFilmOrder() []string {
 	return []string{
 		"hp5_plus",
@@ -153,6 +173,29 @@ func GetFilmOrder() []string {
 	}
 }
 
+// GetFilmOrder returns the films in display order, the built-in films
+// first and then the user films sorted by ID
+func (fd *FilmDatabase) GetFilmOrder() []string {
+	var order []string
+	builtin := map[string]bool{}
+	for _, id := range GetFilmOrder() {
+		builtin[id] = true
+		if _, ok := fd.Films[id]; ok {
+			order = append(order, id)
+		}
+	}
+
+	var user []string
+	for id := range fd.Films {
+		if !builtin[id] {
+			user = append(user, id)
+		}
+	}
+	sort.Strings(user)
+
+	return append(order, user...)
+}
+
 // CalculateMixedTankSize calculates the tank size for mixed formats
 func CalculateMixedTankSize(format35mm, format120mm int, tankDB *TankDatabase) int {
 	size35mm := 0
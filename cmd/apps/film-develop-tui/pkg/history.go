@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionStep is a development step as it was timed
+type SessionStep struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Actual string `json:"actual"`
+	// Timed is false for a step that was skipped without starting the timer
+	Timed bool `json:"timed"`
+}
+
+// DevelopmentSession is a logged development of one or more rolls
+type DevelopmentSession struct {
+	Date        time.Time             `json:"date"`
+	FilmID      string                `json:"film_id"`
+	Film        string                `json:"film"`
+	EI          int                   `json:"ei"`
+	Roll        string                `json:"roll"`
+	Rolls       RollSetup             `json:"rolls"`
+	Developer   string                `json:"developer"`
+	Dilution    string                `json:"dilution"`
+	Temperature float64               `json:"temperature"`
+	Chemicals   []DilutionCalculation `json:"chemicals"`
+	Steps       []SessionStep         `json:"steps"`
+	FixerRolls  int                   `json:"fixer_rolls"`
+	Notes       string                `json:"notes"`
+}
+
+// DevelopmentLog is the persistent log of development sessions, together
+// with the state of the fixer batch the sessions are counted against
+type DevelopmentLog struct {
+	path     string
+	Fixer    FixerState           `json:"fixer"`
+	Sessions []DevelopmentSession `json:"sessions"`
+}
+
+// NewDevelopmentLog creates an empty log with a fresh batch of fixer. An
+// empty path keeps the log in memory only.
+func NewDevelopmentLog(path string) *DevelopmentLog {
+	return &DevelopmentLog{
+		path: path,
+		Fixer: FixerState{
+			CapacityPerLiter: 24,
+			UsedRolls:        0,
+			TotalCapacity:    24,
+		},
+		Sessions: []DevelopmentSession{},
+	}
+}
+
+// LoadDevelopmentLog reads the log, a missing file is an empty log
+func LoadDevelopmentLog(path string) (*DevelopmentLog, error) {
+	history := NewDevelopmentLog(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read development log: %w", err)
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("failed to parse development log %s: %w", path, err)
+	}
+	if history.Sessions == nil {
+		history.Sessions = []DevelopmentSession{}
+	}
+	return history, nil
+}
+
+// Path returns the file the log is saved to
+func (l *DevelopmentLog) Path() string {
+	return l.path
+}
+
+// Save writes the log
+func (l *DevelopmentLog) Save() error {
+	if l.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal development log: %w", err)
+	}
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write development log: %w", err)
+	}
+	return nil
+}
+
+// SessionsSinceMix returns the sessions that used the current fixer batch
+func (l *DevelopmentLog) SessionsSinceMix() []DevelopmentSession {
+	var sessions []DevelopmentSession
+	for _, session := range l.Sessions {
+		if session.FixerRolls > 0 && !session.Date.Before(l.Fixer.MixedAt) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// NewSession creates a session from the current setup and timer
+func (as *ApplicationState) NewSession(roll, notes string) DevelopmentSession {
+	session := DevelopmentSession{
+		Date:        time.Now(),
+		EI:          as.SelectedEI,
+		Roll:        roll,
+		Developer:   as.GetDeveloper().Name,
+		Dilution:    as.Dilution,
+		Temperature: as.Temperature,
+		Chemicals:   as.Calculations,
+		Steps:       []SessionStep{},
+		Notes:       notes,
+	}
+	if as.SelectedFilm != nil {
+		session.FilmID = as.SelectedFilm.ID
+		session.Film = as.SelectedFilm.Name
+	}
+	if as.RollSetup != nil {
+		session.Rolls = *as.RollSetup
+	}
+	if as.TimerState != nil {
+		for _, step := range as.TimerState.Steps {
+			session.Steps = append(session.Steps, SessionStep{
+				Name:   step.Name,
+				Target: FormatDuration(step.Duration),
+				Actual: FormatDuration(step.Actual),
+				Timed:  step.Started && step.Finished,
+			})
+		}
+	}
+	return session
+}
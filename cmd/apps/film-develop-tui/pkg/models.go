@@ -2,20 +2,40 @@ package pkg
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 	
 	"github.com/charmbracelet/lipgloss"
 )
 
+// DefaultDeveloper is the developer the Times20C of a film are for
+const DefaultDeveloper = "ilfosol_3"
+
 // Film represents a film type with its properties
 type Film struct {
-	ID          string                    `json:"id"`
-	Name        string                    `json:"name"`
-	EIRatings   []int                     `json:"ei_ratings"`
-	Times20C    map[string]map[int]string `json:"times_20c"`
-	Description string                    `json:"description"`
-	Icon        string                    `json:"icon"`
+	ID          string                    `json:"id" yaml:"-"`
+	Name        string                    `json:"name" yaml:"name"`
+	EIRatings   []int                     `json:"ei_ratings" yaml:"ei_ratings"`
+	Times20C    map[string]map[int]string `json:"times_20c" yaml:"times_20c"`
+	Description string                    `json:"description" yaml:"description"`
+	Icon        string                    `json:"icon" yaml:"icon"`
+	// Developers holds the 20°C times for developers other than the
+	// default, by developer ID, dilution and EI
+	Developers map[string]map[string]map[int]string `json:"developers,omitempty" yaml:"developers"`
+}
+
+// TimesFor returns the 20°C times by dilution and EI for a developer
+func (f *Film) TimesFor(developer string) map[string]map[int]string {
+	if times, ok := f.Developers[developer]; ok {
+		return times
+	}
+	if developer == DefaultDeveloper {
+		return f.Times20C
+	}
+	return nil
 }
 
 // FilmDatabase contains all available films
@@ -60,14 +80,22 @@ func (td *TankDatabase) GetTankSize(format string, rolls int) (int, bool) {
 	return size, ok
 }
 
+// Chemical roles
+const (
+	RoleDeveloper = "developer"
+	RoleStop      = "stop"
+	RoleFixer     = "fixer"
+)
+
 // Chemical represents a chemical with its properties
 type Chemical struct {
-	Name      string   `json:"name"`
-	Dilutions []string `json:"dilutions"`
-	Default   string   `json:"default"`
-	Time      string   `json:"time"`
-	Type      string   `json:"type"`
-	Capacity  string   `json:"capacity"`
+	Name      string   `json:"name" yaml:"name"`
+	Dilutions []string `json:"dilutions" yaml:"dilutions"`
+	Default   string   `json:"default" yaml:"default"`
+	Time      string   `json:"time" yaml:"time"`
+	Type      string   `json:"type" yaml:"type"`
+	Capacity  string   `json:"capacity" yaml:"capacity"`
+	Role      string   `json:"role" yaml:"role"`
 }
 
 // ChemicalDatabase contains all chemical information
@@ -81,6 +109,68 @@ func (cd *ChemicalDatabase) GetChemical(name string) (Chemical, bool) {
 	return chemical, ok
 }
 
+// GetDevelopers returns the IDs of the developers, the default first and
+// the others sorted
+func (cd *ChemicalDatabase) GetDevelopers() []string {
+	var ids []string
+	for id, chemical := range cd.Chemicals {
+		if chemical.Role == RoleDeveloper && id != DefaultDeveloper {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if _, ok := cd.Chemicals[DefaultDeveloper]; ok {
+		ids = append([]string{DefaultDeveloper}, ids...)
+	}
+	return ids
+}
+
+// TemperatureDatabase contains the development time factors by temperature
+// in °C, relative to 20°C
+type TemperatureDatabase struct {
+	Factors map[float64]float64 `json:"factors"`
+}
+
+// Range returns the lowest and highest temperature with a factor
+func (td *TemperatureDatabase) Range() (float64, float64) {
+	first := true
+	var lowest, highest float64
+	for celsius := range td.Factors {
+		if first || celsius < lowest {
+			lowest = celsius
+		}
+		if first || celsius > highest {
+			highest = celsius
+		}
+		first = false
+	}
+	return lowest, highest
+}
+
+// Factor returns the time factor for a temperature, interpolated between
+// the two nearest entries. Temperatures outside the table have no factor.
+func (td *TemperatureDatabase) Factor(celsius float64) (float64, bool) {
+	if factor, ok := td.Factors[celsius]; ok {
+		return factor, true
+	}
+
+	below, above := math.Inf(-1), math.Inf(1)
+	for t := range td.Factors {
+		if t < celsius && t > below {
+			below = t
+		}
+		if t > celsius && t < above {
+			above = t
+		}
+	}
+	if math.IsInf(below, 0) || math.IsInf(above, 0) {
+		return 0, false
+	}
+
+	fraction := (celsius - below) / (above - below)
+	return td.Factors[below] + fraction*(td.Factors[above]-td.Factors[below]), true
+}
+
 // DilutionCalculation represents a chemical dilution calculation
 type DilutionCalculation struct {
 	Chemical    string `json:"chemical"`
@@ -279,9 +369,10 @@ func (rs *RollSetup) TotalRolls() int {
 
 // FixerState represents the current state of the fixer
 type FixerState struct {
-	CapacityPerLiter int `json:"capacity_per_liter"`
-	UsedRolls        int `json:"used_rolls"`
-	TotalCapacity    int `json:"total_capacity"`
+	CapacityPerLiter int       `json:"capacity_per_liter"`
+	UsedRolls        int       `json:"used_rolls"`
+	TotalCapacity    int       `json:"total_capacity"`
+	MixedAt          time.Time `json:"mixed_at"`
 }
 
 // RemainingCapacity returns the remaining capacity of the fixer
@@ -299,32 +390,47 @@ func (fs *FixerState) UseFixer(rolls int) {
 	fs.UsedRolls += rolls
 }
 
+// MixNew starts a new batch of fixer
+func (fs *FixerState) MixNew() {
+	fs.UsedRolls = 0
+	fs.MixedAt = time.Now()
+}
+
 // ApplicationState represents the complete application state
 type ApplicationState struct {
 	SelectedFilm *Film                 `json:"selected_film"`
 	SelectedEI   int                   `json:"selected_ei"`
 	RollSetup    *RollSetup            `json:"roll_setup"`
+	Developer    string                `json:"developer"`
 	Dilution     string                `json:"dilution"`
+	Temperature  float64               `json:"temperature"`
 	Calculations []DilutionCalculation `json:"calculations"`
 	FixerState   *FixerState           `json:"fixer_state"`
-	TimerState   *TimerState           `json:"timer_state"`
-	FilmDB       *FilmDatabase         `json:"film_db"`
-	TankDB       *TankDatabase         `json:"tank_db"`
-	ChemicalDB   *ChemicalDatabase     `json:"chemical_db"`
+	// FixerApplied is set once the rolls of the current setup were
+	// counted against the fixer
+	FixerApplied  bool                 `json:"fixer_applied"`
+	TimerState    *TimerState          `json:"timer_state"`
+	FilmDB        *FilmDatabase        `json:"film_db"`
+	TankDB        *TankDatabase        `json:"tank_db"`
+	ChemicalDB    *ChemicalDatabase    `json:"chemical_db"`
+	TemperatureDB *TemperatureDatabase `json:"temperature_db"`
+	History       *DevelopmentLog      `json:"-"`
+	DatabasePath  string               `json:"-"`
 }
 
 // NewApplicationState creates a new application state with default values
 func NewApplicationState() *ApplicationState {
+	history := NewDevelopmentLog("")
 	return &ApplicationState{
-		Dilution: "1+9",
-		FixerState: &FixerState{
-			CapacityPerLiter: 24,
-			UsedRolls:        0,
-			TotalCapacity:    24,
-		},
-		FilmDB:     NewFilmDatabase(),
-		TankDB:     NewTankDatabase(),
-		ChemicalDB: NewChemicalDatabase(),
+		Developer:     DefaultDeveloper,
+		Dilution:      "1+9",
+		Temperature:   20,
+		FixerState:    &history.Fixer,
+		FilmDB:        NewFilmDatabase(),
+		TankDB:        NewTankDatabase(),
+		ChemicalDB:    NewChemicalDatabase(),
+		TemperatureDB: NewTemperatureDatabase(),
+		History:       history,
 	}
 }
 
@@ -338,18 +444,50 @@ func (as *ApplicationState) GetDevelopmentTime() string {
 	if as.SelectedFilm == nil {
 		return "--:--"
 	}
+	return as.GetFilmTime(as.SelectedFilm, as.SelectedEI)
+}
 
-	dilutionTimes, ok := as.SelectedFilm.Times20C[as.Dilution]
+// GetFilmTime returns the development time of a film at an EI with the
+// selected developer, dilution and temperature
+func (as *ApplicationState) GetFilmTime(film *Film, ei int) string {
+	dilutionTimes, ok := film.TimesFor(as.Developer)[as.Dilution]
 	if !ok {
 		return "--:--"
 	}
 
-	time, ok := dilutionTimes[as.SelectedEI]
+	time, ok := dilutionTimes[ei]
 	if !ok {
 		return "--:--"
 	}
 
-	return time
+	return as.CompensateTime(time)
+}
+
+// CompensateTime adjusts a 20°C time to the selected temperature, rounded
+// to 15 seconds
+func (as *ApplicationState) CompensateTime(time20C string) string {
+	if as.Temperature == 20 {
+		return time20C
+	}
+
+	duration, err := ParseDuration(time20C)
+	if err != nil {
+		return time20C
+	}
+	factor, ok := as.TemperatureDB.Factor(as.Temperature)
+	if !ok {
+		return "--:--"
+	}
+
+	return FormatDuration(time.Duration(float64(duration) * factor).Round(15 * time.Second))
+}
+
+// GetDeveloper returns the selected developer
+func (as *ApplicationState) GetDeveloper() Chemical {
+	if developer, ok := as.ChemicalDB.GetChemical(as.Developer); ok {
+		return developer
+	}
+	return Chemical{Name: strings.ToUpper(as.Developer), Dilutions: []string{as.Dilution}}
 }
 
 // CalculateChemicals calculates the chemical dilutions for the current setup
@@ -361,8 +499,8 @@ func (as *ApplicationState) CalculateChemicals() {
 	totalVolume := as.RollSetup.TotalVolume
 	as.Calculations = []DilutionCalculation{}
 
-	// ILFOSOL 3
-	as.Calculations = append(as.Calculations, calculateDilution("ILFOSOL 3", as.Dilution, totalVolume, as.GetDevelopmentTime()))
+	// Developer
+	as.Calculations = append(as.Calculations, calculateDilution(as.GetDeveloper().Name, as.Dilution, totalVolume, as.GetDevelopmentTime()))
 
 	// ILFOSTOP
 	ilfostop, _ := as.ChemicalDB.GetChemical("ilfostop")
@@ -380,19 +518,15 @@ func (as *ApplicationState) CalculateChemicals() {
 func calculateDilution(chemical, dilution string, totalVolume int, time string) DilutionCalculation {
 	var concentrate, water int
 
-	switch dilution {
-	case "1+9":
-		concentrate = totalVolume / 10
-		water = totalVolume - concentrate
-	case "1+14":
-		concentrate = totalVolume / 15
-		water = totalVolume - concentrate
-	case "1+19":
-		concentrate = totalVolume / 20
-		water = totalVolume - concentrate
-	case "1+4":
-		concentrate = totalVolume / 5
-		water = totalVolume - concentrate
+	// Dilutions are given as "concentrate+water" parts, e.g. "1+9"
+	parts := strings.SplitN(dilution, "+", 2)
+	if len(parts) == 2 {
+		conc, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		wat, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 == nil && err2 == nil && conc > 0 && wat >= 0 {
+			concentrate = totalVolume * conc / (conc + wat)
+			water = totalVolume - concentrate
+		}
 	}
 
 	return DilutionCalculation{
@@ -426,6 +560,7 @@ func FormatDuration(d time.Duration) string {
 type TimerStep struct {
 	Name     string        `json:"name"`
 	Duration time.Duration `json:"duration"`
+	Actual   time.Duration `json:"actual"`
 	Started  bool          `json:"started"`
 	Finished bool          `json:"finished"`
 }
@@ -506,6 +641,7 @@ func (ts *TimerState) StopTimer() {
 // CompleteCurrentStep marks the current step as complete and moves to next
 func (ts *TimerState) CompleteCurrentStep() {
 	if ts.CurrentStep < len(ts.Steps) {
+		ts.Steps[ts.CurrentStep].Actual = ts.GetCurrentElapsed()
 		ts.Steps[ts.CurrentStep].Finished = true
 		ts.CurrentStep++
 		ts.IsRunning = false
@@ -568,5 +704,6 @@ func (ts *TimerState) Reset() {
 	for i := range ts.Steps {
 		ts.Steps[i].Started = false
 		ts.Steps[i].Finished = false
+		ts.Steps[i].Actual = 0
 	}
 }
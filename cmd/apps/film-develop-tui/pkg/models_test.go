@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTemperatureFactor(t *testing.T) {
+	td := NewTemperatureDatabase()
+	tests := []struct {
+		celsius float64
+		factor  float64
+		ok      bool
+	}{
+		{20, 1, true},
+		{18, 1.18, true},
+		{24, 0.72, true},
+		{20.5, 0.96, true},
+		{18.25, 1.155, true},
+		{23.5, 0.75, true},
+		{17.9, 0, false},
+		{24.1, 0, false},
+	}
+	for _, tt := range tests {
+		factor, ok := td.Factor(tt.celsius)
+		if ok != tt.ok || math.Abs(factor-tt.factor) > 1e-9 {
+			t.Errorf("Factor(%v) = %v, %t, want %v, %t", tt.celsius, factor, ok, tt.factor, tt.ok)
+		}
+	}
+
+	// A sparse user table interpolates over the gap
+	sparse := &TemperatureDatabase{Factors: map[float64]float64{16: 1.4, 20: 1}}
+	if factor, ok := sparse.Factor(17); !ok || math.Abs(factor-1.3) > 1e-9 {
+		t.Errorf("Factor(17) = %v, %t, want 1.3", factor, ok)
+	}
+	if _, ok := (&TemperatureDatabase{}).Factor(20); ok {
+		t.Error("expected no factor from an empty table")
+	}
+}
+
+func TestCompensateTime(t *testing.T) {
+	as := NewApplicationState()
+	tests := []struct {
+		temperature float64
+		want        string
+	}{
+		{20, "6:30"},
+		{18, "7:45"},
+		{21, "6:00"},
+		{24, "4:45"},
+		{25, "--:--"},
+	}
+	for _, tt := range tests {
+		as.Temperature = tt.temperature
+		if got := as.CompensateTime("6:30"); got != tt.want {
+			t.Errorf("CompensateTime(6:30) at %v°C = %s, want %s", tt.temperature, got, tt.want)
+		}
+	}
+}
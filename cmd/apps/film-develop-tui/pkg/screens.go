@@ -110,7 +110,7 @@ func (s *MainScreen) renderMainContent(state *ApplicationState) string {
 	b.WriteString("\n\n")
 
 	// Chemicals Section
-	b.WriteString(sectionStyle.Render("Chemicals (20°C)"))
+	b.WriteString(sectionStyle.Render(fmt.Sprintf("Chemicals (%s)", formatTemperature(state.Temperature))))
 	b.WriteString("\n")
 	b.WriteString(s.renderChemicalModels(state))
 	b.WriteString("\n\n")
@@ -129,6 +129,10 @@ func (s *MainScreen) renderMainContent(state *ApplicationState) string {
 
 func (s *MainScreen) renderChemicalModels(state *ApplicationState) string {
 	chemicals := GetCalculatedChemicals(state.Calculations)
+	if len(state.Calculations) == 0 {
+		chemicals[0].Name = state.GetDeveloper().Name
+		chemicals[0].Dilution = state.Dilution
+	}
 	components := ChemicalModelsToComponents(chemicals)
 	
 	return s.renderChemicalComponents(components, false)
@@ -273,7 +277,7 @@ func (s *MainScreen) renderFixerUsage(state *ApplicationState) string {
 }
 
 func (s *MainScreen) renderActions() string {
-	return "[F] Film Type    [U] Fixer Usage    [S] Settings    [Q] Quit"
+	return "[F] Film Type    [U] Fixer Usage    [S] Settings    [H] History    [Q] Quit"
 }
 
 func (s *MainScreen) HandleInput(key string, sm *StateMachine) bool {
@@ -287,14 +291,22 @@ func (s *MainScreen) HandleInput(key string, sm *StateMachine) bool {
 	case "s":
 		sm.TransitionTo(SettingsState)
 		return true
+	case "h":
+		sm.TransitionTo(HistoryState)
+		return true
 	case "q":
 		return false
 	}
 	return true
 }
 
+// filmsPerPage is the number of films selectable with the number keys
+const filmsPerPage = 9
+
 // FilmSelectionScreen represents the film selection screen
-type FilmSelectionScreen struct{}
+type FilmSelectionScreen struct {
+	page int
+}
 
 func (s *FilmSelectionScreen) Render(state *ApplicationState) string {
 	var b strings.Builder
@@ -320,8 +332,9 @@ func (s *FilmSelectionScreen) renderFilmSelection(state *ApplicationState) strin
 	b.WriteString(sectionStyle.Render("Select Film Type"))
 	b.WriteString("\n\n")
 
-	filmOrder := GetFilmOrder()
-	for i, filmID := range filmOrder {
+	filmOrder := state.FilmDB.GetFilmOrder()
+	start, end := s.pageRange(len(filmOrder))
+	for i, filmID := range filmOrder[start:end] {
 		if film, ok := state.FilmDB.GetFilmByID(filmID); ok {
 			eiRatings := make([]string, len(film.EIRatings))
 			for j, ei := range film.EIRatings {
@@ -337,29 +350,58 @@ func (s *FilmSelectionScreen) renderFilmSelection(state *ApplicationState) strin
 		}
 	}
 
+	if pages := s.pageCount(len(filmOrder)); pages > 1 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("Page %d of %d", s.page+1, pages)))
+		b.WriteString("\n")
+	}
+
 	b.WriteString("\n")
 	b.WriteString(dimStyle.Render("[ESC] Back"))
 
 	return b.String()
 }
 
+func (s *FilmSelectionScreen) pageCount(films int) int {
+	return (films + filmsPerPage - 1) / filmsPerPage
+}
+
+// pageRange returns the range of the film order shown on the current page
+func (s *FilmSelectionScreen) pageRange(films int) (int, int) {
+	if pages := s.pageCount(films); s.page >= pages {
+		s.page = max(pages-1, 0)
+	}
+	start := s.page * filmsPerPage
+	return start, min(start+filmsPerPage, films)
+}
+
 func (s *FilmSelectionScreen) renderActions() string {
 	var b strings.Builder
 	b.WriteString(sectionStyle.Render("Actions"))
 	b.WriteString("\n\n")
 
-	b.WriteString(actionStyle.Render("[1-7] Select Film    [ESC] Back    [Q] Quit"))
+	b.WriteString(actionStyle.Render("[1-9] Select Film    [←→] Page    [ESC] Back    [Q] Quit"))
 
 	return b.String()
 }
 
 func (s *FilmSelectionScreen) HandleInput(key string, sm *StateMachine) bool {
 	switch strings.ToLower(key) {
-	case "1", "2", "3", "4", "5", "6", "7":
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 		index, _ := strconv.Atoi(key)
-		filmOrder := GetFilmOrder()
-		if index > 0 && index <= len(filmOrder) {
-			sm.HandleFilmSelection(filmOrder[index-1])
+		filmOrder := sm.appState.FilmDB.GetFilmOrder()
+		start, end := s.pageRange(len(filmOrder))
+		if index > 0 && start+index <= end {
+			sm.HandleFilmSelection(filmOrder[start+index-1])
+		}
+		return true
+	case "left":
+		if s.page > 0 {
+			s.page--
+		}
+		return true
+	case "right":
+		if s.page < s.pageCount(len(sm.appState.FilmDB.GetFilmOrder()))-1 {
+			s.page++
 		}
 		return true
 	case "esc":
@@ -436,14 +478,9 @@ func (s *EISelectionScreen) renderEISelection(state *ApplicationState) string {
 		return b.String()
 	}
 
-	dilution := "1+9"
+	dilution := fmt.Sprintf("%s %s, %s", state.GetDeveloper().Name, state.Dilution, formatTemperature(state.Temperature))
 	for i, ei := range state.SelectedFilm.EIRatings {
-		time := "--:--"
-		if dilutionTimes, ok := state.SelectedFilm.Times20C[dilution]; ok {
-			if t, ok := dilutionTimes[ei]; ok {
-				time = t
-			}
-		}
+		time := state.GetFilmTime(state.SelectedFilm, ei)
 
 		description := ""
 		switch {
@@ -695,7 +732,9 @@ func (s *MixedRollInputScreen) HandleInput(key string, sm *StateMachine) bool {
 }
 
 // CalculatedScreen represents the calculated results screen
-type CalculatedScreen struct{}
+type CalculatedScreen struct {
+	err error
+}
 
 func (s *CalculatedScreen) Render(state *ApplicationState) string {
 	var b strings.Builder
@@ -744,7 +783,7 @@ func (s *CalculatedScreen) renderMainContent(state *ApplicationState) string {
 	b.WriteString("\n\n")
 
 	// Chemicals Section
-	b.WriteString(sectionStyle.Render("Chemicals (20°C)"))
+	b.WriteString(sectionStyle.Render(fmt.Sprintf("Chemicals (%s)", formatTemperature(state.Temperature))))
 	b.WriteString("\n")
 	b.WriteString(s.renderChemicalModels(state))
 	b.WriteString("\n\n")
@@ -766,6 +805,14 @@ func (s *CalculatedScreen) renderMainContent(state *ApplicationState) string {
 	b.WriteString(fmt.Sprintf("This batch uses: %s         After use: %s remaining",
 		highlightStyle.Render(fmt.Sprintf("%d roll", batchRolls)),
 		highlightStyle.Render(fmt.Sprintf("%d rolls", remaining-batchRolls))))
+	if state.FixerApplied {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("This batch is counted in the fixer usage"))
+	}
+	if s.err != nil {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(s.err.Error()))
+	}
 
 	return b.String()
 }
@@ -927,7 +974,7 @@ func (s *CalculatedScreen) renderActions() string {
 func (s *CalculatedScreen) HandleInput(key string, sm *StateMachine) bool {
 	switch strings.ToLower(key) {
 	case "u":
-		sm.HandleFixerUsage()
+		s.err = sm.HandleFixerUsage()
 		return true
 	case "t":
 		sm.TransitionTo(TimerScreenState)
@@ -1054,6 +1101,9 @@ func (s *TimerScreen) renderStepsContent(state *ApplicationState) string {
 		}
 
 		stepText := fmt.Sprintf("%s %s (%s)", icon, step.Name, FormatDuration(step.Duration))
+		if step.Finished {
+			stepText += fmt.Sprintf(" took %s", FormatDuration(step.Actual))
+		}
 		b.WriteString(style.Render(stepText))
 		b.WriteString("\n")
 	}
@@ -1144,9 +1194,12 @@ func (s *TimerScreen) renderSteps(state *ApplicationState) string {
 }
 
 func (s *TimerScreen) renderActions(state *ApplicationState) string {
-	if state.TimerState == nil || state.TimerState.IsComplete {
+	if state.TimerState == nil {
 		return "[R] Reset    [ESC] Back    [Q] Quit"
 	}
+	if state.TimerState.IsComplete {
+		return "[L] Log Session    [R] Reset    [ESC] Back    [Q] Quit"
+	}
 
 	if state.TimerState.IsRunning {
 		if state.TimerState.IsPaused {
@@ -1185,6 +1238,11 @@ func (s *TimerScreen) HandleInput(key string, sm *StateMachine) bool {
 	case "r":
 		sm.appState.TimerState.Reset()
 		return true
+	case "l":
+		if sm.appState.TimerState.IsComplete {
+			sm.TransitionTo(LogSessionState)
+		}
+		return true
 	case "esc":
 		sm.GoBack()
 		return true
@@ -1211,6 +1269,14 @@ func GetScreenForState(state AppState) Screen {
 		return &CalculatedScreen{}
 	case TimerScreenState:
 		return &TimerScreen{}
+	case FixerTrackingState:
+		return &FixerTrackingScreen{}
+	case SettingsState:
+		return &SettingsScreen{}
+	case HistoryState:
+		return &HistoryScreen{}
+	case LogSessionState:
+		return &LogSessionScreen{}
 	default:
 		return &MainScreen{}
 	}
@@ -0,0 +1,439 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatTemperature formats a temperature like "20°C" or "20.5°C"
+func formatTemperature(celsius float64) string {
+	return fmt.Sprintf("%g°C", celsius)
+}
+
+// SettingsScreen selects the developer, dilution and temperature
+type SettingsScreen struct{}
+
+func (s *SettingsScreen) Render(state *ApplicationState) string {
+	var b strings.Builder
+
+	// Title
+	b.WriteString(titleStyle.Render("🎞️  Film Development Calculator"))
+	b.WriteString("\n\n")
+
+	// Main content in single box
+	mainContent := s.renderMainContent(state)
+	b.WriteString(mainBoxStyle.Render(mainContent))
+	b.WriteString("\n")
+
+	// Actions Section (borderless)
+	b.WriteString(actionsOnlyStyle.Render("[D] Developer    [L] Dilution    [↑↓] Temperature    [ESC] Back"))
+
+	return b.String()
+}
+
+func (s *SettingsScreen) renderMainContent(state *ApplicationState) string {
+	var b strings.Builder
+
+	b.WriteString(sectionStyle.Render("Chemistry"))
+	b.WriteString("\n")
+
+	developer := state.GetDeveloper()
+	b.WriteString(fmt.Sprintf("Developer:    %s\n", highlightStyle.Render(fmt.Sprintf("[ %s ]", developer.Name))))
+	b.WriteString(fmt.Sprintf("Dilution:     %s    %s\n",
+		highlightStyle.Render(fmt.Sprintf("[ %s ]", state.Dilution)),
+		dimStyle.Render(strings.Join(developer.Dilutions, " / "))))
+
+	factor := "no compensation"
+	if f, ok := state.TemperatureDB.Factor(state.Temperature); ok {
+		factor = fmt.Sprintf("time ×%.2f", f)
+	}
+	lowest, highest := state.TemperatureDB.Range()
+	b.WriteString(fmt.Sprintf("Temperature:  %s    %s\n",
+		highlightStyle.Render(fmt.Sprintf("[ %s ]", formatTemperature(state.Temperature))),
+		dimStyle.Render(fmt.Sprintf("%s, range %s-%s", factor, formatTemperature(lowest), formatTemperature(highest)))))
+	b.WriteString("\n")
+
+	if state.SelectedFilm != nil && state.SelectedEI > 0 {
+		b.WriteString(fmt.Sprintf("%s at EI %d: %s\n\n", state.SelectedFilm.Name, state.SelectedEI,
+			highlightStyle.Render(state.GetDevelopmentTime())))
+	}
+
+	b.WriteString(sectionStyle.Render("Databases"))
+	b.WriteString("\n")
+	database := "built-in only"
+	if state.DatabasePath != "" {
+		database = state.DatabasePath
+	}
+	history := "not saved"
+	if state.History.Path() != "" {
+		history = state.History.Path()
+	}
+	b.WriteString(fmt.Sprintf("Films:        %d (%d developers)\n", len(state.FilmDB.Films), len(state.ChemicalDB.GetDevelopers())))
+	b.WriteString(fmt.Sprintf("User database: %s\n", dimStyle.Render(database)))
+	b.WriteString(fmt.Sprintf("Session log:   %s", dimStyle.Render(history)))
+
+	return b.String()
+}
+
+func (s *SettingsScreen) HandleInput(key string, sm *StateMachine) bool {
+	switch strings.ToLower(key) {
+	case "d":
+		sm.HandleDeveloperChange()
+		return true
+	case "l":
+		sm.HandleDilutionChange()
+		return true
+	case "up", "+":
+		sm.HandleTemperatureChange(0.5)
+		return true
+	case "down", "-":
+		sm.HandleTemperatureChange(-0.5)
+		return true
+	case "esc":
+		sm.GoBack()
+		return true
+	case "q":
+		return false
+	}
+	return true
+}
+
+// FixerTrackingScreen shows the usage of the current fixer batch across
+// sessions
+type FixerTrackingScreen struct {
+	confirmMix bool
+	err        error
+}
+
+func (s *FixerTrackingScreen) Render(state *ApplicationState) string {
+	var b strings.Builder
+
+	// Title
+	b.WriteString(titleStyle.Render("🎞️  Film Development Calculator"))
+	b.WriteString("\n\n")
+
+	// Main content in single box
+	mainContent := s.renderMainContent(state)
+	b.WriteString(mainBoxStyle.Render(mainContent))
+	b.WriteString("\n")
+
+	// Actions Section (borderless)
+	actions := "[N] Mix New Fixer    [+/-] Correct Used Rolls    [ESC] Back    [Q] Quit"
+	if s.confirmMix {
+		actions = "[Y] Confirm New Fixer    [Any Key] Cancel"
+	}
+	b.WriteString(actionsOnlyStyle.Render(actions))
+
+	return b.String()
+}
+
+func (s *FixerTrackingScreen) renderMainContent(state *ApplicationState) string {
+	var b strings.Builder
+
+	fixer := state.FixerState
+	b.WriteString(sectionStyle.Render("Fixer Usage"))
+	b.WriteString("\n")
+
+	mixed := "unknown"
+	if !fixer.MixedAt.IsZero() {
+		mixed = fixer.MixedAt.Format("2006-01-02 15:04")
+	}
+	b.WriteString(fmt.Sprintf("Mixed:     %s\n", mixed))
+	b.WriteString(fmt.Sprintf("Capacity: %d rolls per liter    Used: %d rolls    Remaining: %s\n",
+		fixer.CapacityPerLiter, fixer.UsedRolls, s.renderRemaining(fixer)))
+	b.WriteString(renderCapacityBar(fixer))
+	b.WriteString("\n\n")
+
+	sessions := state.History.SessionsSinceMix()
+	b.WriteString(sectionStyle.Render(fmt.Sprintf("Sessions on this batch (%d)", len(sessions))))
+	b.WriteString("\n")
+	if len(sessions) == 0 {
+		b.WriteString(dimStyle.Render("No logged sessions"))
+	}
+	// Newest first, at most five
+	for i := len(sessions) - 1; i >= 0 && i >= len(sessions)-5; i-- {
+		session := sessions[i]
+		b.WriteString(fmt.Sprintf("%s  %-12s %-22s %d rolls\n",
+			session.Date.Format("2006-01-02"), session.Film, session.Rolls.String(), session.FixerRolls))
+	}
+
+	if s.err != nil {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(s.err.Error()))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (s *FixerTrackingScreen) renderRemaining(fixer *FixerState) string {
+	remaining := fmt.Sprintf("%d rolls", fixer.RemainingCapacity())
+	if fixer.RemainingCapacity() <= 0 {
+		return errorStyle.Render(remaining + " ⚠️  EXHAUSTED")
+	}
+	if fixer.RemainingCapacity() <= fixer.TotalCapacity/4 {
+		return errorStyle.Render(remaining)
+	}
+	return highlightStyle.Render(remaining)
+}
+
+// renderCapacityBar renders the used part of the fixer capacity as a bar
+func renderCapacityBar(fixer *FixerState) string {
+	const width = 40
+	if fixer.TotalCapacity <= 0 {
+		return ""
+	}
+
+	used := min(fixer.UsedRolls*width/fixer.TotalCapacity, width)
+	return fmt.Sprintf("[%s%s]", strings.Repeat("█", used), dimStyle.Render(strings.Repeat("░", width-used)))
+}
+
+func (s *FixerTrackingScreen) HandleInput(key string, sm *StateMachine) bool {
+	if s.confirmMix {
+		s.confirmMix = false
+		if strings.ToLower(key) == "y" {
+			s.err = sm.HandleMixFixer()
+		}
+		return true
+	}
+
+	switch strings.ToLower(key) {
+	case "n":
+		s.confirmMix = true
+		return true
+	case "+":
+		s.err = sm.HandleFixerCorrection(1)
+		return true
+	case "-":
+		s.err = sm.HandleFixerCorrection(-1)
+		return true
+	case "esc":
+		sm.GoBack()
+		return true
+	case "q":
+		return false
+	}
+	return true
+}
+
+// historyRows is the number of sessions listed at once
+const historyRows = 6
+
+// HistoryScreen lists the logged development sessions
+type HistoryScreen struct {
+	cursor int
+}
+
+func (s *HistoryScreen) Render(state *ApplicationState) string {
+	var b strings.Builder
+
+	// Title
+	b.WriteString(titleStyle.Render("🎞️  Development History"))
+	b.WriteString("\n\n")
+
+	// Main content in single box
+	mainContent := s.renderMainContent(state)
+	b.WriteString(mainBoxStyle.Render(mainContent))
+	b.WriteString("\n")
+
+	// Actions Section (borderless)
+	b.WriteString(actionsOnlyStyle.Render("[↑↓] Select Session    [ESC] Back    [Q] Quit"))
+
+	return b.String()
+}
+
+// session returns the i-th session, newest first
+func (s *HistoryScreen) session(state *ApplicationState, i int) DevelopmentSession {
+	sessions := state.History.Sessions
+	return sessions[len(sessions)-1-i]
+}
+
+func (s *HistoryScreen) renderMainContent(state *ApplicationState) string {
+	var b strings.Builder
+
+	count := len(state.History.Sessions)
+	b.WriteString(sectionStyle.Render(fmt.Sprintf("Sessions (%d)", count)))
+	b.WriteString("\n")
+	if count == 0 {
+		b.WriteString(dimStyle.Render("No logged sessions. Finish the timer and press [L] to log one."))
+		return b.String()
+	}
+
+	// Keep the cursor in the listed rows
+	first := max(0, min(s.cursor-historyRows/2, count-historyRows))
+	for i := first; i < count && i < first+historyRows; i++ {
+		session := s.session(state, i)
+		line := fmt.Sprintf("%s  %-12s EI %-5d %-22s %s",
+			session.Date.Format("2006-01-02 15:04"), session.Film, session.EI, session.Rolls.String(), session.Roll)
+		if i == s.cursor {
+			b.WriteString(highlightStyle.Render("▶ " + line))
+		} else {
+			b.WriteString(dimStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	b.WriteString(sectionStyle.Render("Details"))
+	b.WriteString("\n")
+	b.WriteString(s.renderDetails(s.session(state, s.cursor)))
+
+	return b.String()
+}
+
+func (s *HistoryScreen) renderDetails(session DevelopmentSession) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("Film:       %s at EI %d, %s\n", session.Film, session.EI, session.Rolls.String()))
+	if session.Roll != "" {
+		b.WriteString(fmt.Sprintf("Roll:       %s\n", session.Roll))
+	}
+	b.WriteString(fmt.Sprintf("Chemistry:  %s %s at %s\n", session.Developer, session.Dilution, formatTemperature(session.Temperature)))
+
+	for _, step := range session.Steps {
+		actual := step.Actual
+		if !step.Timed {
+			actual = "skipped"
+		}
+		line := fmt.Sprintf("  %-14s target %-6s actual %s", step.Name, step.Target, actual)
+		if target, err := ParseDuration(step.Target); err == nil && step.Timed {
+			if taken, err := ParseDuration(step.Actual); err == nil && taken != target {
+				diff := taken - target
+				sign := "+"
+				if diff < 0 {
+					sign = "-"
+					diff = -diff
+				}
+				line += fmt.Sprintf(" (%s%s)", sign, FormatDuration(diff))
+			}
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	if session.FixerRolls > 0 {
+		b.WriteString(fmt.Sprintf("Fixer:      %d rolls counted\n", session.FixerRolls))
+	}
+	if session.Notes != "" {
+		b.WriteString(fmt.Sprintf("Notes:      %s", session.Notes))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (s *HistoryScreen) HandleInput(key string, sm *StateMachine) bool {
+	switch strings.ToLower(key) {
+	case "up", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+		return true
+	case "down", "j":
+		if s.cursor < len(sm.appState.History.Sessions)-1 {
+			s.cursor++
+		}
+		return true
+	case "esc":
+		sm.GoBack()
+		return true
+	case "q":
+		return false
+	}
+	return true
+}
+
+// LogSessionScreen asks for the roll and the notes of a finished session
+type LogSessionScreen struct {
+	roll      string
+	notes     string
+	editNotes bool
+	err       error
+}
+
+func (s *LogSessionScreen) Render(state *ApplicationState) string {
+	var b strings.Builder
+
+	// Title
+	b.WriteString(titleStyle.Render("🎞️  Log Development Session"))
+	b.WriteString("\n\n")
+
+	// Main content in single box
+	mainContent := s.renderMainContent(state)
+	b.WriteString(mainBoxStyle.Render(mainContent))
+	b.WriteString("\n")
+
+	// Actions Section (borderless)
+	b.WriteString(actionsOnlyStyle.Render("[TAB] Next Field    [ENTER] Save    [ESC] Back"))
+
+	return b.String()
+}
+
+func (s *LogSessionScreen) renderMainContent(state *ApplicationState) string {
+	var b strings.Builder
+
+	session := state.NewSession(s.roll, s.notes)
+	b.WriteString(sectionStyle.Render("Session"))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("Film:       %s at EI %d, %s\n", session.Film, session.EI, session.Rolls.String()))
+	b.WriteString(fmt.Sprintf("Chemistry:  %s %s at %s\n", session.Developer, session.Dilution, formatTemperature(session.Temperature)))
+	for _, step := range session.Steps {
+		actual := step.Actual
+		if !step.Timed {
+			actual = "skipped"
+		}
+		b.WriteString(fmt.Sprintf("  %-14s target %-6s actual %s\n", step.Name, step.Target, actual))
+	}
+	if state.RollSetup != nil && !state.FixerApplied {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("Saving counts %d rolls against the fixer", state.RollSetup.TotalRolls())))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	b.WriteString(sectionStyle.Render("Notes"))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("Roll:   %s\n", s.renderField(s.roll, !s.editNotes)))
+	b.WriteString(fmt.Sprintf("Notes:  %s", s.renderField(s.notes, s.editNotes)))
+
+	if s.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(s.err.Error()))
+	}
+
+	return b.String()
+}
+
+func (s *LogSessionScreen) renderField(value string, active bool) string {
+	if active {
+		return highlightStyle.Render(fmt.Sprintf("[ %s█ ]", value))
+	}
+	return fmt.Sprintf("[ %s ]", value)
+}
+
+func (s *LogSessionScreen) HandleInput(key string, sm *StateMachine) bool {
+	field := &s.roll
+	if s.editNotes {
+		field = &s.notes
+	}
+
+	switch key {
+	case "tab", "up", "down":
+		s.editNotes = !s.editNotes
+	case "enter":
+		s.err = sm.HandleLogSession(strings.TrimSpace(s.roll), strings.TrimSpace(s.notes))
+	case "esc":
+		sm.GoBack()
+	case "backspace":
+		if len(*field) > 0 {
+			_, size := utf8.DecodeLastRuneInString(*field)
+			*field = (*field)[:len(*field)-size]
+		}
+	case "space":
+		*field += " "
+	default:
+		// Typed characters, including q, go into the field
+		if utf8.RuneCountInString(key) == 1 {
+			*field += key
+		}
+	}
+	return true
+}
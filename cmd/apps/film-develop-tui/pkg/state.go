@@ -13,6 +13,8 @@ const (
 	TimerScreenState
 	FixerTrackingState
 	SettingsState
+	HistoryState
+	LogSessionState
 )
 
 // String returns the string representation of the app state
@@ -36,6 +38,10 @@ func (s AppState) String() string {
 		return "FixerTracking"
 	case SettingsState:
 		return "Settings"
+	case HistoryState:
+		return "History"
+	case LogSessionState:
+		return "LogSession"
 	default:
 		return "Unknown"
 	}
@@ -50,10 +56,16 @@ type StateMachine struct {
 
 // NewStateMachine creates a new state machine
 func NewStateMachine() *StateMachine {
+	return NewStateMachineWithState(NewApplicationState())
+}
+
+// NewStateMachineWithState creates a new state machine for an application
+// state with loaded databases and log
+func NewStateMachineWithState(appState *ApplicationState) *StateMachine {
 	return &StateMachine{
 		currentState: MainScreenState,
 		history:      []AppState{},
-		appState:     NewApplicationState(),
+		appState:     appState,
 	}
 }
 
@@ -122,6 +134,8 @@ func (sm *StateMachine) HandleRollSelection(format string, rolls int) {
 
 	rollSetup.TotalVolume = tankSize
 	sm.appState.RollSetup = rollSetup
+	// New rolls haven't been counted against the fixer yet
+	sm.appState.FixerApplied = false
 	sm.appState.CalculateChemicals()
 	sm.TransitionTo(CalculatedScreenState)
 }
@@ -136,30 +150,138 @@ func (sm *StateMachine) HandleMixedRollSetup(format35mm, format120mm int) {
 	}
 
 	sm.appState.RollSetup = rollSetup
+	sm.appState.FixerApplied = false
 	sm.appState.CalculateChemicals()
 	sm.TransitionTo(CalculatedScreenState)
 }
 
-// HandleFixerUsage handles fixer usage
-func (sm *StateMachine) HandleFixerUsage() {
-	if sm.appState.RollSetup != nil {
-		totalRolls := sm.appState.RollSetup.TotalRolls()
-		sm.appState.FixerState.UseFixer(totalRolls)
+// HandleFixerUsage handles fixer usage, counting the rolls of the current
+// setup once
+func (sm *StateMachine) HandleFixerUsage() error {
+	if sm.appState.RollSetup == nil || sm.appState.FixerApplied {
+		return nil
+	}
+	totalRolls := sm.appState.RollSetup.TotalRolls()
+	sm.appState.FixerState.UseFixer(totalRolls)
+	sm.appState.FixerApplied = true
+	return sm.appState.History.Save()
+}
+
+// HandleMixFixer starts a new batch of fixer
+func (sm *StateMachine) HandleMixFixer() error {
+	sm.appState.FixerState.MixNew()
+	return sm.appState.History.Save()
+}
+
+// HandleFixerCorrection corrects the number of rolls used by the fixer
+func (sm *StateMachine) HandleFixerCorrection(rolls int) error {
+	fixer := sm.appState.FixerState
+	fixer.UsedRolls += rolls
+	if fixer.UsedRolls < 0 {
+		fixer.UsedRolls = 0
 	}
+	return sm.appState.History.Save()
 }
 
-// Reset resets the application state
+// HandleLogSession logs the current development session and shows the
+// history. The rolls are counted against the fixer unless that was done
+// already.
+func (sm *StateMachine) HandleLogSession(roll, notes string) error {
+	session := sm.appState.NewSession(roll, notes)
+	if sm.appState.RollSetup != nil && !sm.appState.FixerApplied {
+		sm.appState.FixerState.UseFixer(sm.appState.RollSetup.TotalRolls())
+		sm.appState.FixerApplied = true
+	}
+	if sm.appState.FixerApplied && sm.appState.RollSetup != nil {
+		session.FixerRolls = sm.appState.RollSetup.TotalRolls()
+	}
+
+	sm.appState.History.Sessions = append(sm.appState.History.Sessions, session)
+	if err := sm.appState.History.Save(); err != nil {
+		return err
+	}
+
+	sm.history = []AppState{MainScreenState}
+	sm.currentState = HistoryState
+	return nil
+}
+
+// HandleDeveloperChange selects the next developer with its default dilution
+func (sm *StateMachine) HandleDeveloperChange() {
+	developers := sm.appState.ChemicalDB.GetDevelopers()
+	if len(developers) == 0 {
+		return
+	}
+
+	next := developers[0]
+	for i, id := range developers {
+		if id == sm.appState.Developer {
+			next = developers[(i+1)%len(developers)]
+		}
+	}
+
+	sm.appState.Developer = next
+	sm.appState.Dilution = sm.appState.GetDeveloper().Default
+	sm.recalculate()
+}
+
+// HandleDilutionChange selects the next dilution of the developer
+func (sm *StateMachine) HandleDilutionChange() {
+	dilutions := sm.appState.GetDeveloper().Dilutions
+	if len(dilutions) == 0 {
+		return
+	}
+
+	next := dilutions[0]
+	for i, dilution := range dilutions {
+		if dilution == sm.appState.Dilution {
+			next = dilutions[(i+1)%len(dilutions)]
+		}
+	}
+
+	sm.appState.Dilution = next
+	sm.recalculate()
+}
+
+// HandleTemperatureChange changes the temperature within the compensation
+// table
+func (sm *StateMachine) HandleTemperatureChange(delta float64) {
+	lowest, highest := sm.appState.TemperatureDB.Range()
+	temperature := sm.appState.Temperature + delta
+	if temperature < lowest || temperature > highest {
+		return
+	}
+
+	sm.appState.Temperature = temperature
+	sm.recalculate()
+}
+
+// recalculate updates the calculations after a chemistry change
+func (sm *StateMachine) recalculate() {
+	if sm.appState.IsComplete() {
+		sm.appState.CalculateChemicals()
+	}
+}
+
+// Reset resets the application state, keeping the databases and the log
 func (sm *StateMachine) Reset() {
+	appState := NewApplicationState()
+	appState.FilmDB = sm.appState.FilmDB
+	appState.ChemicalDB = sm.appState.ChemicalDB
+	appState.TemperatureDB = sm.appState.TemperatureDB
+	appState.History = sm.appState.History
+	appState.FixerState = &sm.appState.History.Fixer
+
 	sm.currentState = MainScreenState
 	sm.history = []AppState{}
-	sm.appState = NewApplicationState()
+	sm.appState = appState
 }
 
 // GetValidTransitions returns valid transitions from the current state
 func (sm *StateMachine) GetValidTransitions() []AppState {
 	switch sm.currentState {
 	case MainScreenState:
-		return []AppState{FilmSelectionState, FixerTrackingState, SettingsState}
+		return []AppState{FilmSelectionState, FixerTrackingState, SettingsState, HistoryState}
 	case FilmSelectionState:
 		return []AppState{MainScreenState, EISelectionState}
 	case EISelectionState:
@@ -171,11 +293,15 @@ func (sm *StateMachine) GetValidTransitions() []AppState {
 	case CalculatedScreenState:
 		return []AppState{RollSelectionState, FilmSelectionState, TimerScreenState, MainScreenState}
 	case TimerScreenState:
-		return []AppState{CalculatedScreenState, MainScreenState}
+		return []AppState{CalculatedScreenState, MainScreenState, LogSessionState}
 	case FixerTrackingState:
 		return []AppState{MainScreenState}
 	case SettingsState:
 		return []AppState{MainScreenState}
+	case HistoryState:
+		return []AppState{MainScreenState}
+	case LogSessionState:
+		return []AppState{TimerScreenState, HistoryState}
 	default:
 		return []AppState{MainScreenState}
 	}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// developingHP5 returns a state machine with HP5 PLUS at EI 400 selected
+func developingHP5(t *testing.T) *StateMachine {
+	t.Helper()
+	history := NewDevelopmentLog(filepath.Join(t.TempDir(), "history.json"))
+	appState := NewApplicationState()
+	appState.History = history
+	appState.FixerState = &history.Fixer
+
+	sm := NewStateMachineWithState(appState)
+	sm.HandleFilmSelection("hp5_plus")
+	sm.HandleEISelection(400)
+	if sm.GetCurrentState() != RollSelectionState {
+		t.Fatalf("expected roll selection, got %s", sm.GetCurrentState())
+	}
+	return sm
+}
+
+func TestFixerCountsRollsOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		// settings changes made after the rolls were counted
+		change func(sm *StateMachine)
+	}{
+		{"no change", func(sm *StateMachine) {}},
+		{"developer", func(sm *StateMachine) { sm.HandleDeveloperChange() }},
+		{"dilution", func(sm *StateMachine) { sm.HandleDilutionChange() }},
+		{"temperature", func(sm *StateMachine) {
+			sm.HandleTemperatureChange(1)
+			sm.HandleTemperatureChange(-0.5)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := developingHP5(t)
+			sm.HandleRollSelection("35mm", 2)
+			appState := sm.GetApplicationState()
+
+			if err := sm.HandleFixerUsage(); err != nil {
+				t.Fatalf("HandleFixerUsage failed: %v", err)
+			}
+			tt.change(sm)
+			if err := sm.HandleFixerUsage(); err != nil {
+				t.Fatalf("HandleFixerUsage failed: %v", err)
+			}
+			if err := sm.HandleLogSession("roll 1", ""); err != nil {
+				t.Fatalf("HandleLogSession failed: %v", err)
+			}
+
+			if appState.FixerState.UsedRolls != 2 {
+				t.Errorf("fixer used %d rolls, want 2", appState.FixerState.UsedRolls)
+			}
+			sessions := appState.History.Sessions
+			if len(sessions) != 1 || sessions[0].FixerRolls != 2 {
+				t.Fatalf("expected one session with 2 fixer rolls, got %+v", sessions)
+			}
+
+			// The count is saved with the log
+			loaded, err := LoadDevelopmentLog(appState.History.Path())
+			if err != nil {
+				t.Fatalf("LoadDevelopmentLog failed: %v", err)
+			}
+			if loaded.Fixer.UsedRolls != 2 {
+				t.Errorf("saved fixer used %d rolls, want 2", loaded.Fixer.UsedRolls)
+			}
+		})
+	}
+}
+
+func TestLogSessionCountsFixer(t *testing.T) {
+	sm := developingHP5(t)
+	sm.HandleMixedRollSetup(1, 2)
+	sm.HandleTemperatureChange(1)
+
+	if err := sm.HandleLogSession("", "without fixer tracking"); err != nil {
+		t.Fatalf("HandleLogSession failed: %v", err)
+	}
+	appState := sm.GetApplicationState()
+	if appState.FixerState.UsedRolls != 3 || appState.History.Sessions[0].FixerRolls != 3 {
+		t.Errorf("fixer used %d rolls, session %d, want 3", appState.FixerState.UsedRolls, appState.History.Sessions[0].FixerRolls)
+	}
+	if sm.GetCurrentState() != HistoryState {
+		t.Errorf("expected the history, got %s", sm.GetCurrentState())
+	}
+}
+
+func TestNewRollsAreCountedAgain(t *testing.T) {
+	sm := developingHP5(t)
+	sm.HandleRollSelection("35mm", 2)
+	if err := sm.HandleFixerUsage(); err != nil {
+		t.Fatalf("HandleFixerUsage failed: %v", err)
+	}
+
+	// The next development in the same run of the app
+	sm.HandleRollSelection("120mm", 1)
+	if sm.GetApplicationState().FixerApplied {
+		t.Fatal("expected a new roll setup to reset the fixer flag")
+	}
+	sm.HandleDilutionChange()
+	if err := sm.HandleFixerUsage(); err != nil {
+		t.Fatalf("HandleFixerUsage failed: %v", err)
+	}
+
+	sm.HandleMixedRollSetup(2, 1)
+	if err := sm.HandleFixerUsage(); err != nil {
+		t.Fatalf("HandleFixerUsage failed: %v", err)
+	}
+
+	if used := sm.GetApplicationState().FixerState.UsedRolls; used != 6 {
+		t.Errorf("fixer used %d rolls, want 6", used)
+	}
+}
+
+func TestSettingsChangesRecalculate(t *testing.T) {
+	sm := developingHP5(t)
+	sm.HandleRollSelection("35mm", 2)
+	appState := sm.GetApplicationState()
+	if appState.Calculations[0].Time != "6:30" {
+		t.Fatalf("developer time = %s, want 6:30", appState.Calculations[0].Time)
+	}
+
+	sm.HandleDilutionChange()
+	if appState.Dilution != "1+14" || appState.Calculations[0].Time != "11:00" {
+		t.Errorf("after the dilution change %s takes %s, want 1+14 and 11:00", appState.Dilution, appState.Calculations[0].Time)
+	}
+
+	sm.HandleTemperatureChange(2)
+	if appState.Calculations[0].Time != "9:15" {
+		t.Errorf("at 22°C the developer takes %s, want 9:15", appState.Calculations[0].Time)
+	}
+
+	// Outside the compensation table
+	sm.HandleTemperatureChange(3)
+	if appState.Temperature != 22 {
+		t.Errorf("temperature = %v, want 22", appState.Temperature)
+	}
+}
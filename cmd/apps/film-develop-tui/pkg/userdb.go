@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UserDatabase is the user-editable YAML database that is merged over the
+// built-in films, developers and temperature table
+type UserDatabase struct {
+	Films        map[string]Film     `yaml:"films"`
+	Developers   map[string]Chemical `yaml:"developers"`
+	Temperatures map[float64]float64 `yaml:"temperatures"`
+}
+
+// DefaultConfigDir returns ~/.config/film-develop-tui
+func DefaultConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "film-develop-tui"), nil
+}
+
+// LoadUserDatabase reads a user database, a missing file is an empty database
+func LoadUserDatabase(path string) (*UserDatabase, error) {
+	db := &UserDatabase{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database: %w", err)
+	}
+	if err := yaml.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("failed to parse database %s: %w", path, err)
+	}
+	if err := db.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// LoadApplicationState creates the application state with the user
+// database merged over the built-in one and the development log loaded.
+// An empty path skips the database or keeps the log in memory only.
+func LoadApplicationState(databasePath, historyPath string) (*ApplicationState, error) {
+	state := NewApplicationState()
+
+	if databasePath != "" {
+		db, err := LoadUserDatabase(databasePath)
+		if err != nil {
+			return nil, err
+		}
+		db.Apply(state)
+		state.DatabasePath = databasePath
+	}
+
+	if historyPath != "" {
+		history, err := LoadDevelopmentLog(historyPath)
+		if err != nil {
+			return nil, err
+		}
+		state.History = history
+		state.FixerState = &history.Fixer
+	}
+
+	return state, nil
+}
+
+// Validate checks the times, dilutions and temperature factors
+func (db *UserDatabase) Validate() error {
+	for id, film := range db.Films {
+		times := map[string]map[string]map[int]string{DefaultDeveloper: film.Times20C}
+		for developer, developerTimes := range film.Developers {
+			times[developer] = developerTimes
+		}
+		for developer, dilutions := range times {
+			for dilution, eiTimes := range dilutions {
+				for ei, t := range eiTimes {
+					if _, err := ParseDuration(t); err != nil {
+						return fmt.Errorf("film %s: invalid time %q for %s %s at EI %d", id, t, developer, dilution, ei)
+					}
+				}
+			}
+		}
+	}
+
+	for id, developer := range db.Developers {
+		if len(developer.Dilutions) == 0 {
+			if _, ok := NewChemicalDatabase().Chemicals[id]; !ok {
+				return fmt.Errorf("developer %s: no dilutions", id)
+			}
+		}
+		for _, dilution := range developer.Dilutions {
+			if calculateDilution("", dilution, 100, "").Concentrate == 0 {
+				return fmt.Errorf("developer %s: invalid dilution %q (use e.g. 1+9)", id, dilution)
+			}
+		}
+	}
+
+	for celsius, factor := range db.Temperatures {
+		if factor <= 0 {
+			return fmt.Errorf("temperature %g: factor must be positive", celsius)
+		}
+	}
+	return nil
+}
+
+// Apply merges the user database into the application databases. A film
+// or developer with a built-in ID extends it: set fields replace the
+// built-in ones, while EI ratings and times are added to the built-in ones.
+// The built-in times are for ILFOSOL 3, times for other developers go in
+// the developers section of a film.
+func (db *UserDatabase) Apply(state *ApplicationState) {
+	for id, film := range db.Films {
+		state.FilmDB.Films[id] = mergeFilm(state.FilmDB.Films[id], film, id)
+	}
+
+	for id, developer := range db.Developers {
+		existing, ok := state.ChemicalDB.Chemicals[id]
+		if !ok {
+			existing = Chemical{Type: "one_shot"}
+		}
+		if developer.Name != "" {
+			existing.Name = developer.Name
+		}
+		if existing.Name == "" {
+			existing.Name = id
+		}
+		if len(developer.Dilutions) > 0 {
+			existing.Dilutions = developer.Dilutions
+		}
+		if developer.Default != "" {
+			existing.Default = developer.Default
+		}
+		if existing.Default == "" && len(existing.Dilutions) > 0 {
+			existing.Default = existing.Dilutions[0]
+		}
+		if developer.Type != "" {
+			existing.Type = developer.Type
+		}
+		if developer.Capacity != "" {
+			existing.Capacity = developer.Capacity
+		}
+		existing.Role = RoleDeveloper
+		state.ChemicalDB.Chemicals[id] = existing
+	}
+
+	for celsius, factor := range db.Temperatures {
+		state.TemperatureDB.Factors[celsius] = factor
+	}
+}
+
+func mergeFilm(base, user Film, id string) Film {
+	base.ID = id
+	if user.Name != "" {
+		base.Name = user.Name
+	}
+	if base.Name == "" {
+		base.Name = id
+	}
+	if user.Description != "" {
+		base.Description = user.Description
+	}
+	if user.Icon != "" {
+		base.Icon = user.Icon
+	}
+	if base.Icon == "" {
+		base.Icon = "🎞️"
+	}
+
+	// A push or pull time is enough to add its EI
+	ratings := map[int]bool{}
+	for _, ei := range append(base.EIRatings, user.EIRatings...) {
+		ratings[ei] = true
+	}
+	userTimes := []map[string]map[int]string{user.Times20C}
+	for _, times := range user.Developers {
+		userTimes = append(userTimes, times)
+	}
+	for _, times := range userTimes {
+		for _, eiTimes := range times {
+			for ei := range eiTimes {
+				ratings[ei] = true
+			}
+		}
+	}
+	base.EIRatings = nil
+	for ei := range ratings {
+		base.EIRatings = append(base.EIRatings, ei)
+	}
+	sort.Ints(base.EIRatings)
+
+	base.Times20C = mergeTimes(base.Times20C, user.Times20C)
+	developers := map[string]map[string]map[int]string{}
+	for developer, times := range base.Developers {
+		developers[developer] = mergeTimes(nil, times)
+	}
+	for developer, times := range user.Developers {
+		developers[developer] = mergeTimes(developers[developer], times)
+	}
+	base.Developers = developers
+
+	return base
+}
+
+// mergeTimes returns a copy of base with the times of user added, so that
+// the built-in database is never modified
+func mergeTimes(base, user map[string]map[int]string) map[string]map[int]string {
+	merged := map[string]map[int]string{}
+	for _, times := range []map[string]map[int]string{base, user} {
+		for dilution, eiTimes := range times {
+			if merged[dilution] == nil {
+				merged[dilution] = map[int]string{}
+			}
+			for ei, t := range eiTimes {
+				merged[dilution][ei] = t
+			}
+		}
+	}
+	return merged
+}
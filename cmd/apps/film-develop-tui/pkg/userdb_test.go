@@ -0,0 +1,210 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeFilm(t *testing.T) {
+	builtin := NewFilmDatabase().Films["hp5_plus"]
+
+	tests := []struct {
+		name      string
+		base      Film
+		user      Film
+		wantName  string
+		wantIcon  string
+		wantEIs   []int
+		wantTimes map[string]map[int]string
+		wantDevs  map[string]map[string]map[int]string
+	}{
+		{
+			name:      "push time adds its EI",
+			base:      builtin,
+			user:      Film{Times20C: map[string]map[int]string{"1+9": {1600: "17:00"}}},
+			wantName:  "HP5 PLUS",
+			wantIcon:  "📈",
+			wantEIs:   []int{200, 400, 800, 1600},
+			wantTimes: map[string]map[int]string{"1+9": {200: "5:00", 400: "6:30", 800: "13:30", 1600: "17:00"}, "1+14": {200: "7:00", 400: "11:00", 800: "19:30"}},
+			wantDevs:  map[string]map[string]map[int]string{},
+		},
+		{
+			name:      "user time replaces the built-in one",
+			base:      builtin,
+			user:      Film{Name: "HP5+", EIRatings: []int{400}, Times20C: map[string]map[int]string{"1+14": {400: "10:30"}}},
+			wantName:  "HP5+",
+			wantIcon:  "📈",
+			wantEIs:   []int{200, 400, 800},
+			wantTimes: map[string]map[int]string{"1+9": {200: "5:00", 400: "6:30", 800: "13:30"}, "1+14": {200: "7:00", 400: "10:30", 800: "19:30"}},
+			wantDevs:  map[string]map[string]map[int]string{},
+		},
+		{
+			name:      "times for another developer",
+			base:      builtin,
+			user:      Film{Developers: map[string]map[string]map[int]string{"rodinal": {"1+50": {400: "11:00", 320: "9:00"}}}},
+			wantName:  "HP5 PLUS",
+			wantIcon:  "📈",
+			wantEIs:   []int{200, 320, 400, 800},
+			wantTimes: builtin.Times20C,
+			wantDevs:  map[string]map[string]map[int]string{"rodinal": {"1+50": {320: "9:00", 400: "11:00"}}},
+		},
+		{
+			name:      "new film",
+			user:      Film{Times20C: map[string]map[int]string{"1+9": {100: "6:00"}}},
+			wantName:  "my_film",
+			wantIcon:  "🎞️",
+			wantEIs:   []int{100},
+			wantTimes: map[string]map[int]string{"1+9": {100: "6:00"}},
+			wantDevs:  map[string]map[string]map[int]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.base.ID
+			if id == "" {
+				id = "my_film"
+			}
+			got := mergeFilm(tt.base, tt.user, id)
+			if got.ID != id || got.Name != tt.wantName || got.Icon != tt.wantIcon {
+				t.Errorf("film = %s %q %s, want %s %q %s", got.ID, got.Name, got.Icon, id, tt.wantName, tt.wantIcon)
+			}
+			if !reflect.DeepEqual(got.EIRatings, tt.wantEIs) {
+				t.Errorf("EI ratings = %v, want %v", got.EIRatings, tt.wantEIs)
+			}
+			if !reflect.DeepEqual(got.Times20C, tt.wantTimes) {
+				t.Errorf("times = %v, want %v", got.Times20C, tt.wantTimes)
+			}
+			if !reflect.DeepEqual(got.Developers, tt.wantDevs) {
+				t.Errorf("developers = %v, want %v", got.Developers, tt.wantDevs)
+			}
+		})
+	}
+
+	// Merging never changes the built-in database
+	if fresh := NewFilmDatabase().Films["hp5_plus"]; !reflect.DeepEqual(builtin, fresh) {
+		t.Errorf("built-in film changed to %+v", builtin)
+	}
+}
+
+func TestMergeFilmKeepsBaseTimes(t *testing.T) {
+	db := NewFilmDatabase()
+	base := db.Films["hp5_plus"]
+	merged := mergeFilm(base, Film{Times20C: map[string]map[int]string{"1+9": {1600: "17:00"}}}, "hp5_plus")
+	merged.Times20C["1+9"][400] = "changed"
+	if base.Times20C["1+9"][400] != "6:30" || len(base.Times20C["1+9"]) != 3 {
+		t.Errorf("base times changed to %v", base.Times20C)
+	}
+}
+
+func TestUserDatabaseValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		db      UserDatabase
+		wantErr string
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			db: UserDatabase{
+				Films:        map[string]Film{"hp5_plus": {Times20C: map[string]map[int]string{"1+9": {1600: "17:00"}}}},
+				Developers:   map[string]Chemical{"rodinal": {Dilutions: []string{"1+25", "1+50"}}},
+				Temperatures: map[float64]float64{25: 0.66},
+			},
+		},
+		{
+			name: "built-in developer without dilutions",
+			db:   UserDatabase{Developers: map[string]Chemical{"ilfosol_3": {Name: "Ilfosol 3"}}},
+		},
+		{
+			name:    "invalid time",
+			db:      UserDatabase{Films: map[string]Film{"hp5_plus": {Times20C: map[string]map[int]string{"1+9": {1600: "17 minutes"}}}}},
+			wantErr: `film hp5_plus: invalid time "17 minutes" for ilfosol_3 1+9 at EI 1600`,
+		},
+		{
+			name:    "invalid time of another developer",
+			db:      UserDatabase{Films: map[string]Film{"tri_x": {Developers: map[string]map[string]map[int]string{"rodinal": {"1+50": {400: ""}}}}}},
+			wantErr: `film tri_x: invalid time "" for rodinal 1+50 at EI 400`,
+		},
+		{
+			name:    "new developer without dilutions",
+			db:      UserDatabase{Developers: map[string]Chemical{"hc110": {Name: "HC-110"}}},
+			wantErr: "developer hc110: no dilutions",
+		},
+		{
+			name:    "invalid dilution",
+			db:      UserDatabase{Developers: map[string]Chemical{"rodinal": {Dilutions: []string{"1:50"}}}},
+			wantErr: `developer rodinal: invalid dilution "1:50"`,
+		},
+		{
+			name:    "temperature factor",
+			db:      UserDatabase{Temperatures: map[float64]float64{25: 0}},
+			wantErr: "temperature 25: factor must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.db.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadApplicationState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.yaml")
+	content := `
+films:
+  hp5_plus:
+    times_20c:
+      "1+9":
+        1600: "17:00"
+developers:
+  rodinal:
+    name: Rodinal
+    dilutions: ["1+25", "1+50"]
+temperatures:
+  25: 0.66
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write database: %v", err)
+	}
+
+	state, err := LoadApplicationState(path, filepath.Join(dir, "history.json"))
+	if err != nil {
+		t.Fatalf("LoadApplicationState failed: %v", err)
+	}
+	film, _ := state.FilmDB.GetFilmByID("hp5_plus")
+	if !reflect.DeepEqual(film.EIRatings, []int{200, 400, 800, 1600}) {
+		t.Errorf("EI ratings = %v", film.EIRatings)
+	}
+	rodinal, ok := state.ChemicalDB.GetChemical("rodinal")
+	if !ok || rodinal.Default != "1+25" || rodinal.Role != RoleDeveloper {
+		t.Errorf("rodinal = %+v", rodinal)
+	}
+	if _, highest := state.TemperatureDB.Range(); highest != 25 {
+		t.Errorf("highest temperature = %v, want 25", highest)
+	}
+	if state.FixerState != &state.History.Fixer {
+		t.Error("expected the fixer state to be the one of the log")
+	}
+
+	if err := os.WriteFile(path, []byte("temperatures:\n  25: -1\n"), 0644); err != nil {
+		t.Fatalf("failed to write database: %v", err)
+	}
+	if _, err := LoadApplicationState(path, ""); err == nil || !strings.Contains(err.Error(), "invalid database") {
+		t.Errorf("expected an invalid database error, got %v", err)
+	}
+}
@@ -2,6 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -101,6 +105,20 @@ func (a *Auth) ValidateToken(tokenString string) (int, error) {
 	return claims.UserID, nil
 }
 
+// CalendarToken returns the secret token of a user's calendar feed URL.
+// Calendar apps can't log in, so the feed URL itself is the credential; it
+// stays valid until the JWT secret changes.
+func (a *Auth) CalendarToken(userID int) string {
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	fmt.Fprintf(mac, "calendar-feed:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ValidateCalendarToken checks the token of a user's calendar feed URL
+func (a *Auth) ValidateCalendarToken(userID int, token string) bool {
+	return hmac.Equal([]byte(token), []byte(a.CalendarToken(userID)))
+}
+
 // SetTokenCookie sets the authentication token as a cookie
 func (a *Auth) SetTokenCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
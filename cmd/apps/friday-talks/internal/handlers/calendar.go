@@ -2,24 +2,32 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/auth"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/models"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/services"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/templates"
 )
 
 // CalendarHandler handles calendar-related routes
 type CalendarHandler struct {
-	talkRepo models.TalkRepository
+	talkRepo    models.TalkRepository
+	feeds       *services.CalendarFeedService
+	authService *auth.Auth
 }
 
 // NewCalendarHandler creates a new CalendarHandler
-func NewCalendarHandler(talkRepo models.TalkRepository) *CalendarHandler {
+func NewCalendarHandler(talkRepo models.TalkRepository, feeds *services.CalendarFeedService, authService *auth.Auth) *CalendarHandler {
 	return &CalendarHandler{
-		talkRepo: talkRepo,
+		talkRepo:    talkRepo,
+		feeds:       feeds,
+		authService: authService,
 	}
 }
 
@@ -66,8 +74,66 @@ func (h *CalendarHandler) HandleCalendar(w http.ResponseWriter, r *http.Request)
 	}
 	nextMonthData := generateCalendarMonth(nextMonth, nextYear, now, h.talkRepo, r.Context())
 
+	// Subscription links
+	baseURL := requestBaseURL(r)
+	feeds := templates.CalendarFeeds{SeriesURL: baseURL + "/calendar/feed.ics"}
+	if user != nil {
+		feeds.UserURL = fmt.Sprintf("%s/calendar/users/%d/%s.ics", baseURL, user.ID, h.authService.CalendarToken(user.ID))
+	}
+
 	// Render calendar page
-	templates.Calendar(user, []templates.CalendarMonth{currentMonthData, nextMonthData}, month, year).Render(r.Context(), w)
+	templates.Calendar(user, []templates.CalendarMonth{currentMonthData, nextMonthData}, month, year, feeds).Render(r.Context(), w)
+}
+
+// HandleSeriesFeed serves the iCalendar feed of all talks
+func (h *CalendarHandler) HandleSeriesFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := h.feeds.SeriesFeed(r.Context(), requestBaseURL(r))
+	if err != nil {
+		http.Error(w, "Failed to generate calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeCalendarFeed(w, feed)
+}
+
+// HandleUserFeed serves a user's personal iCalendar feed. The URL carries a
+// token instead of requiring a login, since calendar apps can't log in.
+func (h *CalendarHandler) HandleUserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	token := strings.TrimSuffix(chi.URLParam(r, "token"), ".ics")
+	if !h.authService.ValidateCalendarToken(userID, token) {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	feed, err := h.feeds.UserFeed(r.Context(), userID, requestBaseURL(r))
+	if err != nil {
+		http.Error(w, "Failed to generate calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeCalendarFeed(w, feed)
+}
+
+// writeCalendarFeed writes an iCalendar response
+func writeCalendarFeed(w http.ResponseWriter, feed []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(feed)
+}
+
+// requestBaseURL returns the scheme and host the request was made to
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
 }
 
 // generateCalendarMonth creates a calendar month structure for the given month and year
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/auth"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/models"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/services"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/templates"
+)
+
+// HandlePlanSchedule shows the schedule proposed by the optimizer
+func (h *TalkHandler) HandlePlanSchedule(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Constraints from query parameters, defaults for missing or invalid values
+	constraints := services.DefaultScheduleConstraints()
+	query := r.URL.Query()
+	constraints.Weeks = intParam(query.Get("weeks"), constraints.Weeks, 1, 26)
+	constraints.SpeakerGapWeeks = intParam(query.Get("speaker_gap"), constraints.SpeakerGapWeeks, 1, 26)
+	constraints.TopicGapWeeks = intParam(query.Get("topic_gap"), constraints.TopicGapWeeks, 0, 26)
+
+	schedule, err := h.scheduler.ProposeSchedule(r.Context(), constraints)
+	if err != nil {
+		http.Error(w, "Failed to propose a schedule", http.StatusInternalServerError)
+		return
+	}
+
+	// Load speakers for display
+	speakers := make(map[int]*models.User)
+	loadSpeaker := func(talk *models.Talk) {
+		if talk == nil || talk.Speaker != nil {
+			return
+		}
+		if _, ok := speakers[talk.SpeakerID]; !ok {
+			speaker, err := h.userRepo.FindByID(r.Context(), talk.SpeakerID)
+			if err != nil {
+				speaker = nil
+			}
+			speakers[talk.SpeakerID] = speaker
+		}
+		talk.Speaker = speakers[talk.SpeakerID]
+	}
+	for _, slot := range schedule.Slots {
+		loadSpeaker(slot.Fixed)
+		if slot.Ranking != nil {
+			loadSpeaker(slot.Ranking.Talk)
+		}
+	}
+	for _, unplaced := range schedule.Unplaced {
+		loadSpeaker(unplaced.Talk)
+	}
+
+	templates.PlanSchedule(user, schedule, query.Get("error"), query.Get("success")).Render(r.Context(), w)
+}
+
+// HandleApplySchedule schedules the accepted slots of a proposed schedule
+func (h *TalkHandler) HandleApplySchedule(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	// Accepted slots are submitted as talkID:date
+	var assignments []services.ScheduleAssignment
+	for _, value := range r.Form["assign"] {
+		talkIDStr, dateStr, ok := strings.Cut(value, ":")
+		talkID, err := strconv.Atoi(talkIDStr)
+		if !ok || err != nil {
+			http.Error(w, "Invalid assignment", http.StatusBadRequest)
+			return
+		}
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			http.Error(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+		assignments = append(assignments, services.ScheduleAssignment{TalkID: talkID, Date: date})
+	}
+
+	if len(assignments) == 0 {
+		http.Redirect(w, r, "/schedule?error="+url.QueryEscape("Select at least one talk to schedule"), http.StatusSeeOther)
+		return
+	}
+
+	if err := h.scheduler.ApplySchedule(r.Context(), assignments); err != nil {
+		http.Redirect(w, r, "/schedule?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/schedule?success="+url.QueryEscape(fmt.Sprintf("Scheduled %d talks", len(assignments))), http.StatusSeeOther)
+}
+
+// intParam parses an integer query parameter within bounds
+func intParam(value string, defaultValue, min, max int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return defaultValue
+	}
+	return n
+}
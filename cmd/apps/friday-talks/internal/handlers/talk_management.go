@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -32,6 +33,7 @@ func (h *TalkHandler) HandleProposeTalk(w http.ResponseWriter, r *http.Request)
 
 		title := r.FormValue("title")
 		description := r.FormValue("description")
+		topic := strings.TrimSpace(r.FormValue("topic"))
 		preferredDates := r.Form["preferred_dates[]"]
 
 		// Validate input
@@ -51,6 +53,7 @@ func (h *TalkHandler) HandleProposeTalk(w http.ResponseWriter, r *http.Request)
 			Description:    description,
 			SpeakerID:      user.ID,
 			PreferredDates: preferredDates,
+			Topic:          topic,
 			Status:         models.TalkStatusProposed,
 		}
 
@@ -115,6 +118,7 @@ func (h *TalkHandler) HandleEditTalk(w http.ResponseWriter, r *http.Request) {
 
 		title := r.FormValue("title")
 		description := r.FormValue("description")
+		topic := strings.TrimSpace(r.FormValue("topic"))
 		preferredDates := r.Form["preferred_dates[]"]
 
 		// Validate input
@@ -132,6 +136,7 @@ func (h *TalkHandler) HandleEditTalk(w http.ResponseWriter, r *http.Request) {
 		talk.Title = title
 		talk.Description = description
 		talk.PreferredDates = preferredDates
+		talk.Topic = topic
 
 		if err := h.talkRepo.Update(r.Context(), talk); err != nil {
 			http.Error(w, "Failed to update talk", http.StatusInternalServerError)
@@ -219,9 +224,9 @@ func (h *TalkHandler) HandleScheduleTalk(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Only allow scheduling proposed talks
-	if talk.Status != models.TalkStatusProposed {
-		http.Redirect(w, r, "/talks/"+talkIDStr+"?error=Only proposed talks can be scheduled", http.StatusSeeOther)
+	// Proposed talks can be scheduled, scheduled talks moved to another date
+	if talk.Status != models.TalkStatusProposed && talk.Status != models.TalkStatusScheduled {
+		http.Redirect(w, r, "/talks/"+talkIDStr+"?error=Only proposed or scheduled talks can be scheduled", http.StatusSeeOther)
 		return
 	}
 
@@ -268,7 +273,8 @@ func (h *TalkHandler) HandleScheduleTalk(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		if scheduled {
+		// The talk's own date doesn't count as taken
+		if scheduled && (talk.ScheduledDate == nil || talk.ScheduledDate.Format("2006-01-02") != dateStr) {
 			http.Redirect(w, r, "/talks/"+talkIDStr+"/schedule?error=Date already has a scheduled talk", http.StatusSeeOther)
 			return
 		}
@@ -41,9 +41,27 @@ func runMigrations(db *sql.DB, migrationsFS fs.FS) error {
 		return errors.Wrap(err, "failed to load migrations")
 	}
 
+	// Track applied migrations, later migrations alter tables and can't be
+	// applied twice. The initial schema is idempotent, so databases created
+	// before the tracking table existed simply re-apply it once.
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return errors.Wrap(err, "failed to create schema_migrations table")
+	}
+
 	// Apply migrations using raw sql execution since migration package
 	// doesn't support embedding directly
 	for _, migrationPath := range migrations {
+		var applied int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name = ?`, migrationPath).Scan(&applied); err != nil {
+			return errors.Wrapf(err, "failed to check migration: %s", migrationPath)
+		}
+		if applied > 0 {
+			continue
+		}
+
 		content, err := fs.ReadFile(migrationsFS, migrationPath)
 		if err != nil {
 			return errors.Wrapf(err, "failed to read migration file: %s", migrationPath)
@@ -62,6 +80,10 @@ func runMigrations(db *sql.DB, migrationsFS fs.FS) error {
 			}
 		}
 
+		if _, err := db.Exec(`INSERT INTO schema_migrations (name) VALUES (?)`, migrationPath); err != nil {
+			return errors.Wrapf(err, "failed to record migration: %s", migrationPath)
+		}
+
 		fmt.Printf("Applied migration: %s\n", migrationPath)
 	}
 
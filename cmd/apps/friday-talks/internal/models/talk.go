@@ -29,7 +29,9 @@ type Talk struct {
 	Speaker        *User      `json:"speaker,omitempty"`
 	ScheduledDate  *time.Time `json:"scheduled_date"`
 	PreferredDates []string   `json:"preferred_dates"`
+	Topic          string     `json:"topic"`
 	Status         TalkStatus `json:"status"`
+	Sequence       int        `json:"sequence"` // Incremented when the date or status changes
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -60,7 +62,7 @@ func NewSQLiteTalkRepository(db *sql.DB) *SQLiteTalkRepository {
 func (r *SQLiteTalkRepository) FindByID(ctx context.Context, id int) (*Talk, error) {
 	query := `
 		SELECT t.id, t.title, t.description, t.speaker_id, t.scheduled_date, 
-		       t.preferred_dates, t.status, t.created_at, t.updated_at,
+		       t.preferred_dates, t.topic, t.status, t.schedule_sequence, t.created_at, t.updated_at,
 		       u.id, u.name, u.email, u.created_at, u.updated_at
 		FROM talks t
 		JOIN users u ON t.speaker_id = u.id
@@ -81,7 +83,9 @@ func (r *SQLiteTalkRepository) FindByID(ctx context.Context, id int) (*Talk, err
 		&talk.SpeakerID,
 		&scheduledDateSQL,
 		&preferredDatesJSON,
+		&talk.Topic,
 		&talk.Status,
+		&talk.Sequence,
 		&talk.CreatedAt,
 		&talk.UpdatedAt,
 		&speaker.ID,
@@ -116,8 +120,8 @@ func (r *SQLiteTalkRepository) FindByID(ctx context.Context, id int) (*Talk, err
 func (r *SQLiteTalkRepository) Create(ctx context.Context, talk *Talk) error {
 	query := `
 		INSERT INTO talks (title, description, speaker_id, scheduled_date, 
-		                 preferred_dates, status, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		                 preferred_dates, topic, status, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := time.Now()
@@ -136,6 +140,7 @@ func (r *SQLiteTalkRepository) Create(ctx context.Context, talk *Talk) error {
 		talk.SpeakerID,
 		talk.ScheduledDate,
 		preferredDatesJSON,
+		talk.Topic,
 		talk.Status,
 		now,
 		now,
@@ -157,17 +162,33 @@ func (r *SQLiteTalkRepository) Create(ctx context.Context, talk *Talk) error {
 	return nil
 }
 
-// Update updates an existing talk
+// Update updates an existing talk. The sequence is incremented when the
+// scheduled date or status changes.
 func (r *SQLiteTalkRepository) Update(ctx context.Context, talk *Talk) error {
 	query := `
 		UPDATE talks 
 		SET title = ?, description = ?, speaker_id = ?, scheduled_date = ?,
-		    preferred_dates = ?, status = ?, updated_at = ? 
+		    preferred_dates = ?, topic = ?, status = ?, schedule_sequence = ?, updated_at = ? 
 		WHERE id = ?
 	`
 
 	now := time.Now()
 
+	// Compare with the stored talk to detect moves
+	var storedDate sql.NullTime
+	var storedStatus TalkStatus
+	var sequence int
+	err := r.db.QueryRowContext(ctx, `SELECT scheduled_date, status, schedule_sequence FROM talks WHERE id = ?`, talk.ID).
+		Scan(&storedDate, &storedStatus, &sequence)
+	if err == sql.ErrNoRows {
+		return errors.New("talk not found")
+	} else if err != nil {
+		return errors.Wrap(err, "error querying talk")
+	}
+	if storedStatus != talk.Status || formatDate(storedDate) != formatDatePtr(talk.ScheduledDate) {
+		sequence++
+	}
+
 	// Convert preferred dates to JSON
 	preferredDatesJSON, err := json.Marshal(talk.PreferredDates)
 	if err != nil {
@@ -182,7 +203,9 @@ func (r *SQLiteTalkRepository) Update(ctx context.Context, talk *Talk) error {
 		talk.SpeakerID,
 		talk.ScheduledDate,
 		preferredDatesJSON,
+		talk.Topic,
 		talk.Status,
+		sequence,
 		now,
 		talk.ID,
 	)
@@ -191,11 +214,28 @@ func (r *SQLiteTalkRepository) Update(ctx context.Context, talk *Talk) error {
 		return errors.Wrap(err, "error updating talk")
 	}
 
+	talk.Sequence = sequence
 	talk.UpdatedAt = now
 
 	return nil
 }
 
+// formatDate formats a nullable date for comparison
+func formatDate(date sql.NullTime) string {
+	if !date.Valid {
+		return ""
+	}
+	return date.Time.Format("2006-01-02")
+}
+
+// formatDatePtr formats an optional date for comparison
+func formatDatePtr(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
 // Delete deletes a talk by ID
 func (r *SQLiteTalkRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM talks WHERE id = ?`
@@ -222,7 +262,9 @@ func scanTalk(row *sql.Row) (*Talk, error) {
 		&talk.SpeakerID,
 		&scheduledDateSQL,
 		&preferredDatesJSON,
+		&talk.Topic,
 		&talk.Status,
+		&talk.Sequence,
 		&talk.CreatedAt,
 		&talk.UpdatedAt,
 	)
@@ -260,7 +302,9 @@ func scanTalkRows(rows *sql.Rows) ([]*Talk, error) {
 			&talk.SpeakerID,
 			&scheduledDateSQL,
 			&preferredDatesJSON,
+			&talk.Topic,
 			&talk.Status,
+			&talk.Sequence,
 			&talk.CreatedAt,
 			&talk.UpdatedAt,
 		)
@@ -289,7 +333,7 @@ func scanTalkRows(rows *sql.Rows) ([]*Talk, error) {
 func (r *SQLiteTalkRepository) List(ctx context.Context) ([]*Talk, error) {
 	query := `
 		SELECT id, title, description, speaker_id, scheduled_date, 
-		       preferred_dates, status, created_at, updated_at
+		       preferred_dates, topic, status, schedule_sequence, created_at, updated_at
 		FROM talks
 		ORDER BY COALESCE(scheduled_date, created_at) DESC
 	`
@@ -316,7 +360,7 @@ func (r *SQLiteTalkRepository) List(ctx context.Context) ([]*Talk, error) {
 func (r *SQLiteTalkRepository) ListByStatus(ctx context.Context, status TalkStatus) ([]*Talk, error) {
 	query := `
 		SELECT id, title, description, speaker_id, scheduled_date, 
-		       preferred_dates, status, created_at, updated_at
+		       preferred_dates, topic, status, schedule_sequence, created_at, updated_at
 		FROM talks
 		WHERE status = ?
 		ORDER BY COALESCE(scheduled_date, created_at) DESC
@@ -344,7 +388,7 @@ func (r *SQLiteTalkRepository) ListByStatus(ctx context.Context, status TalkStat
 func (r *SQLiteTalkRepository) ListBySpeaker(ctx context.Context, speakerID int) ([]*Talk, error) {
 	query := `
 		SELECT id, title, description, speaker_id, scheduled_date, 
-		       preferred_dates, status, created_at, updated_at
+		       preferred_dates, topic, status, schedule_sequence, created_at, updated_at
 		FROM talks
 		WHERE speaker_id = ?
 		ORDER BY COALESCE(scheduled_date, created_at) DESC
@@ -377,7 +421,7 @@ func (r *SQLiteTalkRepository) FindProposedWithPreferredDate(ctx context.Context
 	dateStr := date.Format("2006-01-02")
 	query := `
 		SELECT id, title, description, speaker_id, scheduled_date, 
-		       preferred_dates, status, created_at, updated_at
+		       preferred_dates, topic, status, schedule_sequence, created_at, updated_at
 		FROM talks
 		WHERE status = ? AND preferred_dates LIKE ?
 		ORDER BY created_at
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/models"
+	"github.com/pkg/errors"
+)
+
+// CalendarFeedConfig configures the iCalendar feeds
+type CalendarFeedConfig struct {
+	TalkTime     string        // Start time of talks as HH:MM, empty for all-day events
+	TalkDuration time.Duration // Length of a talk when TalkTime is set
+}
+
+// CalendarFeedService renders talks as iCalendar (RFC 5545) feeds. Events
+// keep their UID when a talk moves and carry the talk's sequence, so
+// subscribed calendars update the event instead of adding a new one.
+// Canceled talks stay in the feeds with STATUS:CANCELLED.
+type CalendarFeedService struct {
+	talkRepo       models.TalkRepository
+	userRepo       models.UserRepository
+	attendanceRepo models.AttendanceRepository
+	config         CalendarFeedConfig
+}
+
+// NewCalendarFeedService creates a new CalendarFeedService
+func NewCalendarFeedService(
+	talkRepo models.TalkRepository,
+	userRepo models.UserRepository,
+	attendanceRepo models.AttendanceRepository,
+	config CalendarFeedConfig,
+) (*CalendarFeedService, error) {
+	if config.TalkTime != "" {
+		if _, err := time.Parse("15:04", config.TalkTime); err != nil {
+			return nil, errors.Wrapf(err, "invalid talk time %q, expected HH:MM", config.TalkTime)
+		}
+		if config.TalkDuration <= 0 {
+			config.TalkDuration = time.Hour
+		}
+	}
+
+	return &CalendarFeedService{
+		talkRepo:       talkRepo,
+		userRepo:       userRepo,
+		attendanceRepo: attendanceRepo,
+		config:         config,
+	}, nil
+}
+
+// SeriesFeed returns a feed of all talks that have a date
+func (c *CalendarFeedService) SeriesFeed(ctx context.Context, baseURL string) ([]byte, error) {
+	talks, err := c.talkRepo.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing talks")
+	}
+
+	return c.render(ctx, "Friday Talks", talks, baseURL)
+}
+
+// UserFeed returns a feed of the talks a user gives or has confirmed to attend
+func (c *CalendarFeedService) UserFeed(ctx context.Context, userID int, baseURL string) ([]byte, error) {
+	user, err := c.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error finding user %d", userID)
+	}
+
+	talks, err := c.talkRepo.ListBySpeaker(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing talks by speaker")
+	}
+
+	attendances, err := c.attendanceRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing attendance")
+	}
+
+	seen := make(map[int]bool)
+	for _, talk := range talks {
+		seen[talk.ID] = true
+	}
+	for _, attendance := range attendances {
+		if seen[attendance.TalkID] ||
+			(attendance.Status != models.AttendanceStatusConfirmed && attendance.Status != models.AttendanceStatusAttended) {
+			continue
+		}
+		talk, err := c.talkRepo.FindByID(ctx, attendance.TalkID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error finding talk %d", attendance.TalkID)
+		}
+		seen[talk.ID] = true
+		talks = append(talks, talk)
+	}
+
+	return c.render(ctx, fmt.Sprintf("Friday Talks (%s)", user.Name), talks, baseURL)
+}
+
+// render writes the talks with a date as VEVENTs
+func (c *CalendarFeedService) render(ctx context.Context, name string, talks []*models.Talk, baseURL string) ([]byte, error) {
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	now := time.Now().UTC().Format("20060102T150405Z")
+	speakers := make(map[int]*models.User)
+
+	var buf bytes.Buffer
+	writeICalLine(&buf, "BEGIN:VCALENDAR")
+	writeICalLine(&buf, "VERSION:2.0")
+	writeICalLine(&buf, "PRODID:-//Friday Talks//Calendar Feed//EN")
+	writeICalLine(&buf, "CALSCALE:GREGORIAN")
+	writeICalLine(&buf, "METHOD:PUBLISH")
+	writeICalLine(&buf, "X-WR-CALNAME:"+escapeICalText(name))
+	// Ask clients to refresh hourly so moved talks show up soon
+	writeICalLine(&buf, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	writeICalLine(&buf, "X-PUBLISHED-TTL:PT1H")
+
+	for _, talk := range talks {
+		if talk.ScheduledDate == nil {
+			continue
+		}
+
+		speaker := talk.Speaker
+		if speaker == nil {
+			if speakers[talk.SpeakerID] == nil {
+				user, err := c.userRepo.FindByID(ctx, talk.SpeakerID)
+				if err != nil {
+					return nil, errors.Wrapf(err, "error finding speaker %d", talk.SpeakerID)
+				}
+				speakers[talk.SpeakerID] = user
+			}
+			speaker = speakers[talk.SpeakerID]
+		}
+
+		talkURL := fmt.Sprintf("%s/talks/%d", baseURL, talk.ID)
+		status := "CONFIRMED"
+		if talk.Status == models.TalkStatusCanceled {
+			status = "CANCELLED"
+		}
+
+		writeICalLine(&buf, "BEGIN:VEVENT")
+		writeICalLine(&buf, fmt.Sprintf("UID:talk-%d@%s", talk.ID, host))
+		writeICalLine(&buf, "DTSTAMP:"+now)
+		writeICalLine(&buf, "LAST-MODIFIED:"+talk.UpdatedAt.UTC().Format("20060102T150405Z"))
+		writeICalLine(&buf, fmt.Sprintf("SEQUENCE:%d", talk.Sequence))
+		c.writeEventTimes(&buf, *talk.ScheduledDate)
+		writeICalLine(&buf, "SUMMARY:"+escapeICalText(talk.Title))
+		writeICalLine(&buf, "DESCRIPTION:"+escapeICalText(fmt.Sprintf("Speaker: %s\n\n%s\n\n%s", speaker.Name, talk.Description, talkURL)))
+		writeICalLine(&buf, "URL:"+talkURL)
+		writeICalLine(&buf, "STATUS:"+status)
+		if talk.Topic != "" {
+			writeICalLine(&buf, "CATEGORIES:"+escapeICalText(talk.Topic))
+		}
+		writeICalLine(&buf, "END:VEVENT")
+	}
+
+	writeICalLine(&buf, "END:VCALENDAR")
+	return buf.Bytes(), nil
+}
+
+// writeEventTimes writes an all-day event, or floating local times when a
+// talk time is configured
+func (c *CalendarFeedService) writeEventTimes(buf *bytes.Buffer, date time.Time) {
+	if c.config.TalkTime == "" {
+		writeICalLine(buf, "DTSTART;VALUE=DATE:"+date.Format("20060102"))
+		writeICalLine(buf, "DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"))
+		return
+	}
+
+	clock, _ := time.Parse("15:04", c.config.TalkTime)
+	start := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	writeICalLine(buf, "DTSTART:"+start.Format("20060102T150405"))
+	writeICalLine(buf, "DTEND:"+start.Add(c.config.TalkDuration).Format("20060102T150405"))
+}
+
+// writeICalLine writes a content line, folded at 75 octets as RFC 5545 requires
+func writeICalLine(buf *bytes.Buffer, line string) {
+	maxLen := 75
+	for len(line) > maxLen {
+		cut := maxLen
+		// Don't split UTF-8 sequences
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		maxLen = 74
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// escapeICalText escapes a TEXT property value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/models"
+	"github.com/pkg/errors"
+)
+
+// ScheduleConstraints configures the schedule optimizer
+type ScheduleConstraints struct {
+	Weeks           int // Number of upcoming Fridays to plan
+	SpeakerGapWeeks int // Minimum weeks between two talks of the same speaker
+	TopicGapWeeks   int // Minimum weeks between two talks on the same topic, 0 disables
+}
+
+// DefaultScheduleConstraints returns the constraints used when none are given
+func DefaultScheduleConstraints() ScheduleConstraints {
+	return ScheduleConstraints{
+		Weeks:           8,
+		SpeakerGapWeeks: 4,
+		TopicGapWeeks:   2,
+	}
+}
+
+// ScheduleSlot is one Friday of a proposed schedule
+type ScheduleSlot struct {
+	Date    time.Time
+	Ranking *TalkRanking // Proposed talk, nil if no talk fits
+	Fixed   *models.Talk // Talk already scheduled on this date
+}
+
+// UnplacedTalk is a proposed talk that didn't make it into the schedule
+type UnplacedTalk struct {
+	Talk   *models.Talk
+	Reason string
+}
+
+// ProposedSchedule is the result of the optimizer
+type ProposedSchedule struct {
+	Constraints ScheduleConstraints
+	Slots       []ScheduleSlot
+	Unplaced    []UnplacedTalk
+	TotalScore  float64
+	Exhaustive  bool // False if the search hit its limit and returned the best schedule found
+}
+
+// ScheduleAssignment schedules a talk on a date
+type ScheduleAssignment struct {
+	TalkID int
+	Date   time.Time
+}
+
+const (
+	// maxScheduleSearchNodes bounds the exhaustive search
+	maxScheduleSearchNodes = 500000
+	// weekDiscount makes the optimizer prefer giving popular talks earlier slots
+	weekDiscount = 0.98
+)
+
+// placement is a talk on a date, for checking the spacing constraints
+type placement struct {
+	date      time.Time
+	speakerID int
+	topic     string
+	title     string
+}
+
+// scheduleCandidate is a proposed talk that can be placed in a slot
+type scheduleCandidate struct {
+	ranking *TalkRanking
+	value   float64
+}
+
+// ProposeSchedule proposes talks for the upcoming Fridays. A talk can only
+// be placed on one of the speaker's preferred dates. The schedule maximizes
+// the ranking scores of the placed talks while keeping speakers and topics
+// spaced out, taking already scheduled talks into account.
+func (s *SchedulerService) ProposeSchedule(ctx context.Context, constraints ScheduleConstraints) (*ProposedSchedule, error) {
+	if constraints.Weeks <= 0 {
+		constraints.Weeks = DefaultScheduleConstraints().Weeks
+	}
+
+	dates := make([]time.Time, 0, constraints.Weeks)
+	for _, friday := range s.GetUpcomingFridays(constraints.Weeks) {
+		dates = append(dates, time.Date(friday.Year(), friday.Month(), friday.Day(), 0, 0, 0, 0, time.UTC))
+	}
+
+	// Scheduled and completed talks occupy their dates and count for spacing
+	var fixed []placement
+	fixedByDate := make(map[string]*models.Talk)
+	for _, status := range []models.TalkStatus{models.TalkStatusScheduled, models.TalkStatusCompleted} {
+		talks, err := s.talkRepo.ListByStatus(ctx, status)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing %s talks", status)
+		}
+		for _, talk := range talks {
+			if talk.ScheduledDate == nil {
+				continue
+			}
+			fixed = append(fixed, placementOf(talk, *talk.ScheduledDate))
+			fixedByDate[talk.ScheduledDate.Format("2006-01-02")] = talk
+		}
+	}
+
+	proposed, err := s.talkRepo.ListByStatus(ctx, models.TalkStatusProposed)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing proposed talks")
+	}
+
+	// Candidates for each open slot, best first
+	candidates := make([][]scheduleCandidate, len(dates))
+	for i, date := range dates {
+		if fixedByDate[date.Format("2006-01-02")] != nil {
+			continue
+		}
+		for _, talk := range proposed {
+			if !hasPreferredDate(talk, date) {
+				continue
+			}
+			ranking, err := s.rankTalk(ctx, talk, date)
+			if err != nil {
+				return nil, err
+			}
+			// Every placed talk beats an empty slot, even without votes
+			value := (ranking.FinalScore + 1) * math.Pow(weekDiscount, float64(i))
+			candidates[i] = append(candidates[i], scheduleCandidate{ranking: ranking, value: value})
+		}
+		sort.SliceStable(candidates[i], func(a, b int) bool {
+			return candidates[i][a].value > candidates[i][b].value
+		})
+	}
+
+	search := newScheduleSearch(constraints, dates, candidates, fixed)
+	search.run(0, 0)
+
+	schedule := &ProposedSchedule{
+		Constraints: constraints,
+		TotalScore:  search.bestScore,
+		Exhaustive:  search.nodes <= maxScheduleSearchNodes,
+	}
+	placed := make(map[int]bool)
+	for i, date := range dates {
+		slot := ScheduleSlot{Date: date, Fixed: fixedByDate[date.Format("2006-01-02")]}
+		if c := search.best[i]; c != nil {
+			slot.Ranking = c.ranking
+			placed[c.ranking.Talk.ID] = true
+		}
+		schedule.Slots = append(schedule.Slots, slot)
+	}
+
+	for _, talk := range proposed {
+		if !placed[talk.ID] {
+			schedule.Unplaced = append(schedule.Unplaced, UnplacedTalk{
+				Talk:   talk,
+				Reason: explainUnplaced(talk, schedule, fixed, constraints),
+			})
+		}
+	}
+
+	return schedule, nil
+}
+
+// ApplySchedule schedules the given assignments, typically the accepted
+// slots of a proposed schedule. All assignments are checked before any is
+// applied: dates must be upcoming Fridays and free, and talks must still be
+// proposed.
+func (s *SchedulerService) ApplySchedule(ctx context.Context, assignments []ScheduleAssignment) error {
+	today := time.Now().Format("2006-01-02")
+	talkIDs := make(map[int]bool)
+	dates := make(map[string]bool)
+	for _, assignment := range assignments {
+		dateStr := assignment.Date.Format("2006-01-02")
+		if assignment.Date.Weekday() != time.Friday || dateStr <= today {
+			return errors.Errorf("%s is not an upcoming Friday", assignment.Date.Format("January 2, 2006"))
+		}
+		if talkIDs[assignment.TalkID] || dates[dateStr] {
+			return errors.Errorf("talk %d or date %s is assigned twice", assignment.TalkID, dateStr)
+		}
+		talkIDs[assignment.TalkID] = true
+		dates[dateStr] = true
+
+		talk, err := s.talkRepo.FindByID(ctx, assignment.TalkID)
+		if err != nil {
+			return errors.Wrapf(err, "error finding talk %d", assignment.TalkID)
+		}
+		if talk.Status != models.TalkStatusProposed {
+			return errors.Errorf("talk '%s' is no longer proposed", talk.Title)
+		}
+
+		scheduled, err := s.IsDateScheduled(ctx, assignment.Date)
+		if err != nil {
+			return errors.Wrapf(err, "error checking if date is scheduled: %s", dateStr)
+		}
+		if scheduled {
+			return errors.Errorf("%s already has a scheduled talk", assignment.Date.Format("January 2, 2006"))
+		}
+	}
+
+	for _, assignment := range assignments {
+		if err := s.ScheduleTalk(ctx, assignment.TalkID, assignment.Date); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scheduleSearch is a branch and bound search over the open slots, in date
+// order. Candidates are tried best first, so the first schedule found is the
+// greedy one and the search only improves on it.
+type scheduleSearch struct {
+	constraints ScheduleConstraints
+	dates       []time.Time
+	candidates  [][]scheduleCandidate
+	fixed       []placement
+	bounds      []float64 // Best possible value of the slots from i on
+
+	chosen    []*scheduleCandidate
+	used      map[int]bool
+	best      []*scheduleCandidate
+	bestScore float64
+	nodes     int
+}
+
+func newScheduleSearch(constraints ScheduleConstraints, dates []time.Time, candidates [][]scheduleCandidate, fixed []placement) *scheduleSearch {
+	bounds := make([]float64, len(dates)+1)
+	for i := len(dates) - 1; i >= 0; i-- {
+		bounds[i] = bounds[i+1]
+		if len(candidates[i]) > 0 {
+			bounds[i] += candidates[i][0].value
+		}
+	}
+
+	return &scheduleSearch{
+		constraints: constraints,
+		dates:       dates,
+		candidates:  candidates,
+		fixed:       fixed,
+		bounds:      bounds,
+		chosen:      make([]*scheduleCandidate, len(dates)),
+		used:        make(map[int]bool),
+		best:        make([]*scheduleCandidate, len(dates)),
+		bestScore:   -1,
+	}
+}
+
+func (s *scheduleSearch) run(i int, score float64) {
+	s.nodes++
+	if s.nodes > maxScheduleSearchNodes {
+		return
+	}
+
+	if i == len(s.dates) {
+		if score > s.bestScore {
+			s.bestScore = score
+			copy(s.best, s.chosen)
+		}
+		return
+	}
+
+	// Even the best candidates for the remaining slots can't beat the best schedule
+	if score+s.bounds[i] <= s.bestScore {
+		return
+	}
+
+	for k := range s.candidates[i] {
+		c := &s.candidates[i][k]
+		if s.used[c.ranking.Talk.ID] || s.conflict(placementOf(c.ranking.Talk, s.dates[i]), i) != "" {
+			continue
+		}
+		s.used[c.ranking.Talk.ID] = true
+		s.chosen[i] = c
+		s.run(i+1, score+c.value)
+		s.chosen[i] = nil
+		s.used[c.ranking.Talk.ID] = false
+	}
+
+	// Leave the slot empty
+	s.run(i+1, score)
+}
+
+// conflict checks a placement in slot i against the fixed talks and the
+// talks chosen for earlier slots
+func (s *scheduleSearch) conflict(p placement, i int) string {
+	for _, other := range s.fixed {
+		if reason := spacingConflict(p, other, s.constraints); reason != "" {
+			return reason
+		}
+	}
+	for j := 0; j < i; j++ {
+		if c := s.chosen[j]; c != nil {
+			if reason := spacingConflict(p, placementOf(c.ranking.Talk, s.dates[j]), s.constraints); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// spacingConflict returns why two talks are too close, or "" if they aren't
+func spacingConflict(a, b placement, constraints ScheduleConstraints) string {
+	weeks := weeksBetween(a.date, b.date)
+	if a.speakerID == b.speakerID && weeks < constraints.SpeakerGapWeeks {
+		return fmt.Sprintf("speaker also gives '%s' on %s", b.title, b.date.Format("Jan 2"))
+	}
+	if a.topic != "" && a.topic == b.topic && weeks < constraints.TopicGapWeeks {
+		return fmt.Sprintf("'%s' on %s has the same topic", b.title, b.date.Format("Jan 2"))
+	}
+	return ""
+}
+
+// explainUnplaced tells organizers why a proposed talk wasn't placed
+func explainUnplaced(talk *models.Talk, schedule *ProposedSchedule, fixed []placement, constraints ScheduleConstraints) string {
+	var placements []placement
+	placements = append(placements, fixed...)
+	for _, slot := range schedule.Slots {
+		if slot.Ranking != nil {
+			placements = append(placements, placementOf(slot.Ranking.Talk, slot.Date))
+		}
+	}
+
+	var reasons []string
+	for _, slot := range schedule.Slots {
+		if !hasPreferredDate(talk, slot.Date) {
+			continue
+		}
+		day := slot.Date.Format("Jan 2")
+		switch {
+		case slot.Fixed != nil:
+			reasons = append(reasons, fmt.Sprintf("%s: already taken by '%s'", day, slot.Fixed.Title))
+		default:
+			reason := ""
+			p := placementOf(talk, slot.Date)
+			for _, other := range placements {
+				if other.date.Equal(slot.Date) {
+					continue
+				}
+				if reason = spacingConflict(p, other, constraints); reason != "" {
+					break
+				}
+			}
+			if reason == "" && slot.Ranking != nil {
+				reason = fmt.Sprintf("'%s' scores higher", slot.Ranking.Talk.Title)
+			}
+			if reason != "" {
+				reasons = append(reasons, day+": "+reason)
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		return fmt.Sprintf("None of the speaker's preferred dates are in the next %d weeks", constraints.Weeks)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func placementOf(talk *models.Talk, date time.Time) placement {
+	return placement{
+		date:      date,
+		speakerID: talk.SpeakerID,
+		topic:     strings.ToLower(strings.TrimSpace(talk.Topic)),
+		title:     talk.Title,
+	}
+}
+
+// weeksBetween returns the number of whole weeks between two dates
+func weeksBetween(a, b time.Time) int {
+	days := math.Abs(b.Sub(a).Hours() / 24)
+	return int(math.Round(days / 7))
+}
+
+func hasPreferredDate(talk *models.Talk, date time.Time) bool {
+	dateStr := date.Format("2006-01-02")
+	for _, preferred := range talk.PreferredDates {
+		if preferred == dateStr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/friday-talks/internal/models"
+	"github.com/pkg/errors"
+)
+
+// fakeTalkRepo keeps talks in memory, only what the scheduler uses
+type fakeTalkRepo struct {
+	models.TalkRepository
+	talks []*models.Talk
+}
+
+func (r *fakeTalkRepo) FindByID(ctx context.Context, id int) (*models.Talk, error) {
+	for _, talk := range r.talks {
+		if talk.ID == id {
+			return talk, nil
+		}
+	}
+	return nil, errors.Errorf("talk %d not found", id)
+}
+
+func (r *fakeTalkRepo) Update(ctx context.Context, talk *models.Talk) error {
+	return nil
+}
+
+func (r *fakeTalkRepo) ListByStatus(ctx context.Context, status models.TalkStatus) ([]*models.Talk, error) {
+	var talks []*models.Talk
+	for _, talk := range r.talks {
+		if talk.Status == status {
+			talks = append(talks, talk)
+		}
+	}
+	return talks, nil
+}
+
+// fakeVoteRepo returns a fixed interest count per talk and nobody available
+type fakeVoteRepo struct {
+	models.VoteRepository
+	interest map[int]int
+}
+
+func (r *fakeVoteRepo) GetTalkInterestCount(ctx context.Context, talkID int) (int, error) {
+	return r.interest[talkID], nil
+}
+
+func (r *fakeVoteRepo) GetAvailabilityForDate(ctx context.Context, talkID int, date time.Time) (map[int]bool, error) {
+	return map[int]bool{}, nil
+}
+
+// upcomingFridays returns the dates ProposeSchedule plans, as submitted by
+// the schedule form
+func upcomingFridays(n int) []time.Time {
+	var dates []time.Time
+	for _, friday := range (&SchedulerService{}).GetUpcomingFridays(n) {
+		dates = append(dates, time.Date(friday.Year(), friday.Month(), friday.Day(), 0, 0, 0, 0, time.UTC))
+	}
+	return dates
+}
+
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func proposedTalk(id, speakerID int, topic string, dates ...time.Time) *models.Talk {
+	talk := &models.Talk{ID: id, Title: "talk " + string(rune('A'+id-1)), SpeakerID: speakerID, Topic: topic, Status: models.TalkStatusProposed}
+	for _, date := range dates {
+		talk.PreferredDates = append(talk.PreferredDates, day(date))
+	}
+	return talk
+}
+
+func TestProposeSchedule(t *testing.T) {
+	f := upcomingFridays(4)
+	constraints := ScheduleConstraints{Weeks: 4, SpeakerGapWeeks: 3, TopicGapWeeks: 2}
+
+	tests := []struct {
+		name     string
+		talks    []*models.Talk
+		interest map[int]int
+		// Talk ID placed in each slot, 0 for an empty slot
+		want     []int
+		unplaced map[int]string // Part of the reason of unplaced talks
+	}{
+		{
+			name: "popular talk gets the earlier slot",
+			talks: []*models.Talk{
+				proposedTalk(1, 1, "", f[0], f[1]),
+				proposedTalk(2, 2, "", f[0], f[1]),
+			},
+			interest: map[int]int{1: 1, 2: 5},
+			want:     []int{2, 1, 0, 0},
+		},
+		{
+			name: "speaker gap",
+			talks: []*models.Talk{
+				proposedTalk(1, 1, "", f[0]),
+				proposedTalk(2, 1, "", f[1]),
+				proposedTalk(3, 2, "", f[1]),
+			},
+			interest: map[int]int{1: 5, 2: 5, 3: 1},
+			want:     []int{1, 3, 0, 0},
+			unplaced: map[int]string{2: "speaker also gives 'talk A'"},
+		},
+		{
+			name: "topic gap",
+			talks: []*models.Talk{
+				proposedTalk(1, 1, "Go", f[0]),
+				proposedTalk(2, 2, " go ", f[1]),
+				proposedTalk(3, 3, "go", f[2]),
+			},
+			interest: map[int]int{1: 3, 2: 3, 3: 3},
+			want:     []int{1, 0, 3, 0},
+			unplaced: map[int]string{2: "has the same topic"},
+		},
+		{
+			name: "search beats greedy",
+			talks: []*models.Talk{
+				// Greedy takes A first and then can't place C
+				proposedTalk(1, 1, "", f[0]),
+				proposedTalk(2, 2, "", f[0]),
+				proposedTalk(3, 1, "", f[1]),
+			},
+			interest: map[int]int{1: 5, 2: 4, 3: 5},
+			want:     []int{2, 3, 0, 0},
+			unplaced: map[int]string{1: "speaker also gives"},
+		},
+		{
+			name: "scheduled talk blocks its date and its speaker",
+			talks: []*models.Talk{
+				{ID: 1, Title: "talk A", SpeakerID: 1, Status: models.TalkStatusScheduled, ScheduledDate: &f[0]},
+				proposedTalk(2, 1, "", f[0], f[1]),
+				proposedTalk(3, 2, "", f[0], f[1]),
+			},
+			interest: map[int]int{2: 5, 3: 1},
+			want:     []int{0, 3, 0, 0},
+			unplaced: map[int]string{2: "already taken by 'talk A'"},
+		},
+		{
+			name: "no preferred date in range",
+			talks: []*models.Talk{
+				proposedTalk(1, 1, "", f[3].AddDate(0, 0, 28)),
+			},
+			want:     []int{0, 0, 0, 0},
+			unplaced: map[int]string{1: "None of the speaker's preferred dates"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSchedulerService(&fakeTalkRepo{talks: tt.talks}, &fakeVoteRepo{interest: tt.interest}, nil)
+			schedule, err := s.ProposeSchedule(context.Background(), constraints)
+			if err != nil {
+				t.Fatalf("ProposeSchedule: %v", err)
+			}
+			if !schedule.Exhaustive {
+				t.Error("expected an exhaustive search")
+			}
+			if len(schedule.Slots) != len(tt.want) {
+				t.Fatalf("expected %d slots, got %d", len(tt.want), len(schedule.Slots))
+			}
+			for i, slot := range schedule.Slots {
+				got := 0
+				if slot.Ranking != nil {
+					got = slot.Ranking.Talk.ID
+				}
+				if got != tt.want[i] {
+					t.Errorf("slot %s: got talk %d, want %d", day(slot.Date), got, tt.want[i])
+				}
+			}
+
+			if len(schedule.Unplaced) != len(tt.unplaced) {
+				t.Errorf("expected %d unplaced talks, got %d", len(tt.unplaced), len(schedule.Unplaced))
+			}
+			for _, unplaced := range schedule.Unplaced {
+				want, ok := tt.unplaced[unplaced.Talk.ID]
+				if !ok {
+					t.Errorf("talk %d unexpectedly unplaced: %s", unplaced.Talk.ID, unplaced.Reason)
+				} else if !strings.Contains(unplaced.Reason, want) {
+					t.Errorf("talk %d: reason %q does not mention %q", unplaced.Talk.ID, unplaced.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySchedule(t *testing.T) {
+	f := upcomingFridays(2)
+	lastFriday := f[0].AddDate(0, 0, -7)
+
+	tests := []struct {
+		name        string
+		assignments []ScheduleAssignment
+		wantErr     string
+	}{
+		{"upcoming fridays", []ScheduleAssignment{{TalkID: 1, Date: f[0]}, {TalkID: 2, Date: f[1]}}, ""},
+		{"past friday", []ScheduleAssignment{{TalkID: 1, Date: lastFriday}}, "not an upcoming Friday"},
+		{"not a friday", []ScheduleAssignment{{TalkID: 1, Date: f[0].AddDate(0, 0, 1)}}, "not an upcoming Friday"},
+		{"same date twice", []ScheduleAssignment{{TalkID: 1, Date: f[0]}, {TalkID: 2, Date: f[0]}}, "assigned twice"},
+		{"taken date", []ScheduleAssignment{{TalkID: 1, Date: f[1]}}, "already has a scheduled talk"},
+		{"talk not proposed", []ScheduleAssignment{{TalkID: 3, Date: f[0]}}, "no longer proposed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			talks := []*models.Talk{
+				proposedTalk(1, 1, ""),
+				proposedTalk(2, 2, ""),
+				{ID: 3, Title: "talk C", SpeakerID: 3, Status: models.TalkStatusCompleted},
+			}
+			if tt.name == "taken date" {
+				talks = append(talks, &models.Talk{ID: 4, SpeakerID: 4, Status: models.TalkStatusScheduled, ScheduledDate: &f[1]})
+			}
+			s := NewSchedulerService(&fakeTalkRepo{talks: talks}, &fakeVoteRepo{}, nil)
+
+			err := s.ApplySchedule(context.Background(), tt.assignments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				for _, talk := range talks[:2] {
+					if talk.Status != models.TalkStatusProposed {
+						t.Errorf("talk %d was scheduled although the schedule was refused", talk.ID)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplySchedule: %v", err)
+			}
+			for i, assignment := range tt.assignments {
+				talk := talks[assignment.TalkID-1]
+				if talk.Status != models.TalkStatusScheduled || talk.ScheduledDate == nil || !talk.ScheduledDate.Equal(tt.assignments[i].Date) {
+					t.Errorf("talk %d not scheduled on %s", talk.ID, day(assignment.Date))
+				}
+			}
+		})
+	}
+}
@@ -118,39 +118,48 @@ func (s *SchedulerService) rankTalks(ctx context.Context, talks []*models.Talk,
 	var rankings []*TalkRanking
 
 	for _, talk := range talks {
-		// Get interest level for this talk
-		interestCount, err := s.voteRepo.GetTalkInterestCount(ctx, talk.ID)
+		ranking, err := s.rankTalk(ctx, talk, date)
 		if err != nil {
-			return nil, errors.Wrapf(err, "error getting interest count for talk %d", talk.ID)
+			return nil, err
 		}
+		rankings = append(rankings, ranking)
+	}
 
-		// Get availability for this date
-		availability, err := s.voteRepo.GetAvailabilityForDate(ctx, talk.ID, date)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error getting availability for talk %d", talk.ID)
-		}
+	return rankings, nil
+}
 
-		// Calculate available users
-		availableUsers := 0
-		for _, available := range availability {
-			if available {
-				availableUsers++
-			}
-		}
+// rankTalk scores a single talk for a date
+func (s *SchedulerService) rankTalk(ctx context.Context, talk *models.Talk, date time.Time) (*TalkRanking, error) {
+	// Get interest level for this talk
+	interestCount, err := s.voteRepo.GetTalkInterestCount(ctx, talk.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting interest count for talk %d", talk.ID)
+	}
 
-		// Calculate final score - we prioritize talks with more interest and availability
-		// The exact formula can be adjusted based on specific requirements
-		finalScore := float64(interestCount*2) + float64(availableUsers)
+	// Get availability for this date
+	availability, err := s.voteRepo.GetAvailabilityForDate(ctx, talk.ID, date)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting availability for talk %d", talk.ID)
+	}
 
-		rankings = append(rankings, &TalkRanking{
-			Talk:           talk,
-			InterestScore:  interestCount,
-			AvailableUsers: availableUsers,
-			FinalScore:     finalScore,
-		})
+	// Calculate available users
+	availableUsers := 0
+	for _, available := range availability {
+		if available {
+			availableUsers++
+		}
 	}
 
-	return rankings, nil
+	// Calculate final score - we prioritize talks with more interest and availability
+	// The exact formula can be adjusted based on specific requirements
+	finalScore := float64(interestCount*2) + float64(availableUsers)
+
+	return &TalkRanking{
+		Talk:           talk,
+		InterestScore:  interestCount,
+		AvailableUsers: availableUsers,
+		FinalScore:     finalScore,
+	}, nil
 }
 
 // SuggestNextFriday finds the best date and talk for the next Friday sessions
@@ -29,19 +29,23 @@ var staticFiles embed.FS
 
 // Config holds the application configuration
 type Config struct {
-	Port       int
-	DBPath     string
-	JWTSecret  string
-	StaticPath string
+	Port         int
+	DBPath       string
+	JWTSecret    string
+	StaticPath   string
+	TalkTime     string
+	TalkDuration time.Duration
 }
 
 func main() {
 	// Default configuration
 	config := Config{
-		Port:       8080,
-		DBPath:     "friday-talks.db",
-		JWTSecret:  "your-secret-key", // In production, use a secure random key
-		StaticPath: "static",
+		Port:         8080,
+		DBPath:       "friday-talks.db",
+		JWTSecret:    "your-secret-key", // In production, use a secure random key
+		StaticPath:   "static",
+		TalkTime:     "15:00",
+		TalkDuration: time.Hour,
 	}
 
 	// Define root command
@@ -61,6 +65,8 @@ func main() {
 	rootCmd.Flags().StringVarP(&config.DBPath, "db", "d", config.DBPath, "Path to SQLite database file")
 	rootCmd.Flags().StringVarP(&config.JWTSecret, "jwt-secret", "j", config.JWTSecret, "Secret key for JWT tokens")
 	rootCmd.Flags().StringVarP(&config.StaticPath, "static", "s", config.StaticPath, "Path to static files")
+	rootCmd.Flags().StringVar(&config.TalkTime, "talk-time", config.TalkTime, "Start time of talks in calendar feeds (HH:MM, empty for all-day events)")
+	rootCmd.Flags().DurationVar(&config.TalkDuration, "talk-duration", config.TalkDuration, "Length of talks in calendar feeds")
 
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
@@ -99,6 +105,20 @@ func runServer(config Config) error {
 		repos.Attendance,
 	)
 
+	// Create calendar feed service
+	calendarFeedService, err := services.NewCalendarFeedService(
+		repos.Talk,
+		repos.User,
+		repos.Attendance,
+		services.CalendarFeedConfig{
+			TalkTime:     config.TalkTime,
+			TalkDuration: config.TalkDuration,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error creating calendar feed service: %w", err)
+	}
+
 	// Create notification config
 	notificationConfig := services.NotificationConfig{
 		Enabled:    false, // Disable for now, enable in production
@@ -121,7 +141,7 @@ func runServer(config Config) error {
 	// Create handlers
 	homeHandler := handlers.NewHomeHandler(repos.Talk)
 	authHandler := handlers.NewAuthHandler(repos.User, authService)
-	calendarHandler := handlers.NewCalendarHandler(repos.Talk)
+	calendarHandler := handlers.NewCalendarHandler(repos.Talk, calendarFeedService, authService)
 	talkHandler := handlers.NewTalkHandler(
 		repos.Talk,
 		repos.User,
@@ -170,6 +190,15 @@ func runServer(config Config) error {
 
 	// Calendar routes
 	r.Get("/calendar", calendarHandler.HandleCalendar)
+	r.Get("/calendar/feed.ics", calendarHandler.HandleSeriesFeed)
+	r.Get("/calendar/users/{id}/{token}", calendarHandler.HandleUserFeed)
+
+	// Schedule planning routes
+	r.Route("/schedule", func(r chi.Router) {
+		r.Use(authService.RequireAuth)
+		r.Get("/", talkHandler.HandlePlanSchedule)
+		r.Post("/apply", talkHandler.HandleApplySchedule)
+	})
 
 	// Talk routes
 	r.Route("/talks", func(r chi.Router) {
@@ -0,0 +1,6 @@
+-- Topic of a talk, used to keep the schedule diverse
+ALTER TABLE talks ADD COLUMN topic TEXT NOT NULL DEFAULT '';
+
+-- Incremented whenever the date or status of a talk changes, published as
+-- the iCalendar SEQUENCE so subscribed calendars pick up moved talks
+ALTER TABLE talks ADD COLUMN schedule_sequence INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE talks DROP COLUMN schedule_sequence;
+ALTER TABLE talks DROP COLUMN topic;
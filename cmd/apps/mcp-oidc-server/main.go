@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	zlog "github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/go-go-golems/go-go-labs/cmd/apps/mcp-oidc-server/pkg/idsrv"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/mcp-oidc-server/pkg/mockidp"
 	appserver "github.com/go-go-golems/go-go-labs/cmd/apps/mcp-oidc-server/pkg/server"
     "database/sql"
     _ "github.com/mattn/go-sqlite3"
@@ -31,6 +35,9 @@ func main() {
 		localUsers bool
 		sessionTTL time.Duration
 		devTokenFallback bool
+		upstream   idsrv.UpstreamConfig
+		upstreamScopes string
+		upstreamAllowedGroups string
 	)
 
 	rootCmd := &cobra.Command{
@@ -72,6 +79,18 @@ func main() {
 			// Configure authentication (Model C)
 			s.ConfigureAuth(localUsers, sessionTTL, devTokenFallback)
 
+			// Upstream SSO login (federation)
+			if upstream.Issuer != "" {
+				if dbPath == "" {
+					zlog.Fatal().Msg("--db is required with --upstream-issuer")
+				}
+				upstream.Scopes = splitList(upstreamScopes)
+				upstream.AllowedGroups = splitList(upstreamAllowedGroups)
+				if err := s.EnableUpstream(context.Background(), upstream); err != nil {
+					zlog.Fatal().Err(err).Str("upstream_issuer", upstream.Issuer).Msg("failed enabling upstream login")
+				}
+			}
+
 			mux := http.NewServeMux()
 			s.Routes(mux)
 			wrapped := s.LoggingMiddleware(mux)
@@ -91,10 +110,20 @@ func main() {
 	rootCmd.Flags().BoolVar(&localUsers, "local-users", true, "Enable local DB-backed users (Model C)")
 	rootCmd.Flags().DurationVar(&sessionTTL, "session-ttl", 12*time.Hour, "Server-side session TTL (Model C)")
 	rootCmd.Flags().BoolVar(&devTokenFallback, "dev-token-fallback", false, "Enable dev DB token fallback for /mcp")
+	rootCmd.Flags().StringVar(&upstream.Issuer, "upstream-issuer", getenv("UPSTREAM_ISSUER", ""), "Upstream OIDC issuer to delegate login to (requires --db)")
+	rootCmd.Flags().StringVar(&upstream.Name, "upstream-name", getenv("UPSTREAM_NAME", "SSO"), "Upstream provider name shown on the login page")
+	rootCmd.Flags().StringVar(&upstream.ClientID, "upstream-client-id", getenv("UPSTREAM_CLIENT_ID", ""), "Client ID registered at the upstream provider")
+	rootCmd.Flags().StringVar(&upstream.ClientSecret, "upstream-client-secret", getenv("UPSTREAM_CLIENT_SECRET", ""), "Client secret (prefer the UPSTREAM_CLIENT_SECRET env var)")
+	rootCmd.Flags().StringVar(&upstreamScopes, "upstream-scopes", getenv("UPSTREAM_SCOPES", "openid,profile,email"), "Comma separated scopes requested upstream")
+	rootCmd.Flags().StringVar(&upstream.SubjectClaim, "upstream-subject-claim", getenv("UPSTREAM_SUBJECT_CLAIM", "sub"), "Upstream claim used as local subject, e.g. sub|email|preferred_username")
+	rootCmd.Flags().StringVar(&upstream.SubjectPrefix, "upstream-subject-prefix", getenv("UPSTREAM_SUBJECT_PREFIX", "sso"), "Prefix of local subjects of upstream users, <prefix>:<claim>")
+	rootCmd.Flags().StringVar(&upstream.GroupsClaim, "upstream-groups-claim", getenv("UPSTREAM_GROUPS_CLAIM", "groups"), "Upstream claim holding the user's groups")
+	rootCmd.Flags().StringVar(&upstreamAllowedGroups, "upstream-allowed-groups", getenv("UPSTREAM_ALLOWED_GROUPS", ""), "Comma separated groups allowed to log in (empty allows all)")
 
     listCmd := &cobra.Command{
         Use:   "list-clients",
         Short: "List registered OAuth clients from SQLite",
+        Deprecated: "use 'clients list'",
         RunE: func(cmd *cobra.Command, args []string) error {
             if dbPath == "" {
                 zlog.Fatal().Msg("--db is required to list clients")
@@ -210,7 +239,128 @@ func main() {
 	usersCmd.AddCommand(usersAdd, usersDisable, usersSetPassword, usersList)
 	rootCmd.AddCommand(usersCmd)
 
+	// admin CLI: clients, sessions and signing keys; changes apply to a
+	// running server using the same DB
+	openAdmin := func() (*appserver.Server, error) {
+		if dbPath == "" { zlog.Fatal().Msg("--db is required") }
+		s, err := appserver.New(issuer); if err != nil { return nil, err }
+		if err := s.EnableSQLite(dbPath); err != nil { return nil, err }
+		return s, nil
+	}
+
+	clientsCmd := &cobra.Command{ Use: "clients", Short: "Manage registered OAuth clients (requires --db)" }
+	clientsList := &cobra.Command{ Use: "list", Short: "List clients", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		cs, err := s.ListClients(); if err != nil { return err }
+		for _, c := range cs {
+			zlog.Info().Str("client_id", c.ClientID).Str("name", c.Name).Strs("redirect_uris", c.RedirectURIs).Bool("disabled", c.Disabled).Int("active_grants", c.ActiveGrants).Time("created_at", c.CreatedAt).Msg("client")
+		}
+		return nil
+	}}
+	clientsDisable := &cobra.Command{ Use: "disable CLIENT_ID", Short: "Disable a client and refuse its tokens", Args: cobra.ExactArgs(1), RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		if err := s.SetClientDisabled(args[0], true); err != nil { return err }
+		zlog.Info().Str("client_id", args[0]).Msg("disabled client")
+		return nil
+	}}
+	clientsEnable := &cobra.Command{ Use: "enable CLIENT_ID", Short: "Re-enable a disabled client", Args: cobra.ExactArgs(1), RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		if err := s.SetClientDisabled(args[0], false); err != nil { return err }
+		zlog.Info().Str("client_id", args[0]).Msg("enabled client")
+		return nil
+	}}
+	clientsCmd.AddCommand(clientsList, clientsDisable, clientsEnable)
+	rootCmd.AddCommand(clientsCmd)
+
+	sessionsCmd := &cobra.Command{ Use: "sessions", Short: "List and revoke user sessions and grants (requires --db)" }
+	var sSubject string
+	sessionsList := &cobra.Command{ Use: "list", Short: "List login sessions and active grants", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		ss, err := s.ListSessions(sSubject); if err != nil { return err }
+		for _, sess := range ss {
+			// only a prefix of the session id, the full id is a credential
+			prefix := sess.SessionID
+			if len(prefix) > 8 { prefix = prefix[:8] }
+			zlog.Info().Str("session", prefix).Str("subject", sess.Subject).Str("provider", sess.Provider).Time("created_at", sess.CreatedAt).Time("expires_at", sess.ExpiresAt).Msg("login session")
+		}
+		gs, err := s.ListGrants(sSubject); if err != nil { return err }
+		for _, g := range gs {
+			zlog.Info().Str("request_id", g.RequestID).Str("subject", g.Subject).Str("client_id", g.ClientID).Strs("scopes", g.Scopes).Time("created_at", g.CreatedAt).Time("last_used_at", g.LastUsedAt).Msg("grant")
+		}
+		return nil
+	}}
+	sessionsList.Flags().StringVar(&sSubject, "subject", "", "Only show sessions of this user")
+	sessionsRevoke := &cobra.Command{ Use: "revoke", Short: "Revoke all sessions, grants and API tokens of a user", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		sum, err := s.RevokeSubject(sSubject); if err != nil { return err }
+		zlog.Info().Str("subject", sSubject).Int64("sessions", sum.Sessions).Int64("grants", sum.Grants).Int64("tokens", sum.Tokens).Msg("revoked user access")
+		return nil
+	}}
+	sessionsRevoke.Flags().StringVar(&sSubject, "subject", "", "User whose access is revoked")
+	_ = sessionsRevoke.MarkFlagRequired("subject")
+	sessionsCmd.AddCommand(sessionsList, sessionsRevoke)
+	rootCmd.AddCommand(sessionsCmd)
+
+	keysCmd := &cobra.Command{ Use: "keys", Short: "Manage ID token signing keys (requires --db)" }
+	keysList := &cobra.Command{ Use: "list", Short: "List signing keys", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		ks, err := s.ListSigningKeys(); if err != nil { return err }
+		now := time.Now()
+		for _, k := range ks {
+			ev := zlog.Info().Str("kid", k.KID).Str("status", k.Status(now)).Time("created_at", k.CreatedAt)
+			if k.RetireAt != nil { ev = ev.Time("retire_at", *k.RetireAt) }
+			ev.Msg("signing key")
+		}
+		return nil
+	}}
+	var kOverlap time.Duration
+	keysRotate := &cobra.Command{ Use: "rotate", Short: "Generate a new signing key, keeping the previous one in the JWKS for --overlap", RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openAdmin(); if err != nil { return err }
+		k, err := s.RotateSigningKey(kOverlap); if err != nil { return err }
+		zlog.Info().Str("kid", k.KID).Dur("overlap", kOverlap).Msg("new signing key active")
+		return nil
+	}}
+	keysRotate.Flags().DurationVar(&kOverlap, "overlap", 24*time.Hour, "How long rotated keys stay published; longer than the ID token lifetime")
+	keysCmd.AddCommand(keysList, keysRotate)
+	rootCmd.AddCommand(keysCmd)
+
+	// mock IdP: a local upstream provider for testing federation
+	var (
+		mAddr, mIssuer, mClientID, mClientSecret string
+		mUsers []string
+	)
+	mockIdPCmd := &cobra.Command{ Use: "mock-idp", Short: "Run a mock upstream OIDC provider for testing --upstream-issuer", RunE: func(cmd *cobra.Command, args []string) error {
+		zlog.Logger = zlog.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
+		var users []mockidp.User
+		for _, spec := range mUsers {
+			u, err := mockidp.ParseUser(spec); if err != nil { return err }
+			users = append(users, u)
+		}
+		idp, err := mockidp.New(mIssuer, mClientID, mClientSecret, users); if err != nil { return err }
+		mux := http.NewServeMux()
+		idp.Routes(mux)
+		zlog.Info().Str("addr", mAddr).Str("issuer", mIssuer).Str("client_id", mClientID).Int("users", len(users)).Msg("mock IdP listening")
+		return http.ListenAndServe(mAddr, mux)
+	}}
+	mockIdPCmd.Flags().StringVar(&mAddr, "addr", ":9090", "HTTP listen address")
+	mockIdPCmd.Flags().StringVar(&mIssuer, "issuer", "http://localhost:9090", "Issuer/base URL of the mock IdP")
+	mockIdPCmd.Flags().StringVar(&mClientID, "client-id", "mcp-oidc-server", "Accepted client ID")
+	mockIdPCmd.Flags().StringVar(&mClientSecret, "client-secret", "mock-secret", "Accepted client secret")
+	mockIdPCmd.Flags().StringArrayVar(&mUsers, "user", []string{"alice:engineering", "bob:sales"}, "User as name[:group1,group2] (repeatable)")
+	rootCmd.AddCommand(mockIdPCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		zlog.Fatal().Err(err).Msg("command error")
 	}
 }
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,459 @@
+package idsrv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/rs/zerolog/log"
+)
+
+// Access control for issued tokens. Fosite keeps tokens in memory, so the
+// CLI can't revoke them directly. Instead every token exchange records its
+// grant (the fosite request ID shared by the access and refresh tokens of an
+// authorization) in oauth_grants, and token use checks the grant and its
+// client against the DB.
+
+// Client is a registered OAuth client as shown by the admin CLI.
+type Client struct {
+	ClientID     string
+	Name         string
+	RedirectURIs []string
+	Disabled     bool
+	CreatedAt    time.Time
+	ActiveGrants int
+}
+
+// Grant is an authorization a user gave a client, covering the access and
+// refresh tokens issued for it.
+type Grant struct {
+	RequestID  string
+	Subject    string
+	ClientID   string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// RevocationSummary counts what RevokeSubject removed.
+type RevocationSummary struct {
+	Sessions int64
+	Grants   int64
+	Tokens   int64
+}
+
+// addColumnIfMissing migrates tables created by older versions
+func addColumnIfMissing(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err
+}
+
+// ClientDisabled reports whether a client was disabled with the admin CLI.
+func (s *Server) ClientDisabled(clientID string) (bool, error) {
+	if s.dbPath == "" {
+		return false, nil
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	var disabled int
+	err = db.QueryRow(`SELECT disabled FROM oauth_clients WHERE client_id = ?`, clientID).Scan(&disabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return disabled != 0, err
+}
+
+func (s *Server) checkClient(clientID string) error {
+	disabled, err := s.ClientDisabled(clientID)
+	if err != nil {
+		return fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())
+	}
+	if disabled {
+		return fosite.ErrInvalidClient.WithHint("The OAuth 2.0 Client has been disabled.")
+	}
+	return nil
+}
+
+// CheckAccess refuses token requests and tokens of disabled clients and of
+// revoked grants. Fosite only validates the tokens themselves.
+func (s *Server) CheckAccess(ar fosite.Requester) error {
+	if s.dbPath == "" || ar == nil {
+		return nil
+	}
+	if ar.GetClient() != nil {
+		if err := s.checkClient(ar.GetClient().GetID()); err != nil {
+			return err
+		}
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())
+	}
+	defer db.Close()
+	var revokedAt sql.NullTime
+	err = db.QueryRow(`SELECT revoked_at FROM oauth_grants WHERE request_id = ?`, ar.GetID()).Scan(&revokedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())
+	}
+	if revokedAt.Valid {
+		return fosite.ErrInvalidGrant.WithHintf("The grant was revoked at %s.", revokedAt.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// recordGrant stores or refreshes the grant of a successful token exchange
+func (s *Server) recordGrant(ar fosite.AccessRequester) error {
+	if s.dbPath == "" {
+		return nil
+	}
+	subject := ""
+	if ar.GetSession() != nil {
+		subject = ar.GetSession().GetSubject()
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	now := time.Now()
+	_, err = db.Exec(`INSERT INTO oauth_grants (request_id, subject, client_id, scopes, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT(request_id) DO UPDATE SET last_used_at = excluded.last_used_at`,
+		ar.GetID(), subject, ar.GetClient().GetID(), joinCSV(ar.GetGrantedScopes()), now, now)
+	return err
+}
+
+// introspectionCaller is who asks about a token. A caller authenticated
+// with an access token only learns about tokens of its own client and
+// subject, a client authenticated with its credentials acts as a resource
+// server and may introspect any token.
+type introspectionCaller struct {
+	clientID   string
+	subject    string
+	restricted bool
+}
+
+// allows reports whether the caller may see a token of client and subject
+func (c introspectionCaller) allows(clientID, subject string) bool {
+	return !c.restricted || (c.clientID == clientID && c.subject == subject)
+}
+
+// authenticateIntrospection checks the caller on top of fosite, which
+// accepts any valid access token and any client with the right secret:
+// access tokens of revoked grants and disabled clients are refused.
+func (s *Server) authenticateIntrospection(ctx context.Context, r *http.Request) (introspectionCaller, error) {
+	if bearer := fosite.AccessTokenFromRequest(r); bearer != "" {
+		_, ar, err := s.Provider.IntrospectToken(ctx, bearer, fosite.AccessToken, new(openid.DefaultSession))
+		if err != nil {
+			return introspectionCaller{}, fosite.ErrRequestUnauthorized.WithHint("HTTP Authorization header missing, malformed, or credentials used are invalid.")
+		}
+		if err := s.CheckAccess(ar); err != nil {
+			return introspectionCaller{}, fosite.ErrRequestUnauthorized.WithHint("The access token in the HTTP Authorization header is no longer valid.").WithWrap(err)
+		}
+		caller := introspectionCaller{restricted: true}
+		if ar.GetClient() != nil {
+			caller.clientID = ar.GetClient().GetID()
+		}
+		if ar.GetSession() != nil {
+			caller.subject = ar.GetSession().GetSubject()
+		}
+		return caller, nil
+	}
+
+	// Fosite checks the secret itself
+	if id, _, ok := r.BasicAuth(); ok && s.dbPath != "" {
+		clientID, err := url.QueryUnescape(id)
+		if err == nil {
+			if err := s.checkClient(clientID); err != nil {
+				return introspectionCaller{}, fosite.ErrRequestUnauthorized.WithHint("The OAuth 2.0 Client has been disabled.").WithWrap(err)
+			}
+		}
+		return introspectionCaller{clientID: clientID}, nil
+	}
+	return introspectionCaller{}, nil
+}
+
+// introspect implements RFC 7662. Callers authenticate with an access token
+// (Authorization: Bearer) or client credentials (HTTP Basic). API tokens from
+// oauth_tokens are introspected too. Access token callers only see their own
+// tokens, anything else is reported inactive.
+func (s *Server) introspect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	caller, err := s.authenticateIntrospection(ctx, r)
+	if err != nil {
+		rfc := fosite.ErrorToRFC6749Error(err)
+		log.Info().Str("endpoint", "/oauth2/introspect").Str("rfc_error", rfc.ErrorField).Str("rfc_hint", rfc.HintField).Msg("introspection: unauthorized caller")
+		s.Provider.WriteIntrospectionError(ctx, w, err)
+		return
+	}
+	ir, err := s.Provider.NewIntrospectionRequest(ctx, r, new(openid.DefaultSession))
+	if err != nil {
+		// The caller is authorized but fosite doesn't know the token
+		if errors.Is(err, fosite.ErrInactiveToken) && s.dbPath != "" {
+			if resp, ok := s.introspectAPIToken(r.PostForm.Get("token"), caller); ok {
+				log.Info().Str("endpoint", "/oauth2/introspect").Interface("sub", resp["sub"]).Msg("introspected API token")
+				w.Header().Set("Cache-Control", "no-store")
+				writeJSON(w, resp)
+				return
+			}
+		}
+		rfc := fosite.ErrorToRFC6749Error(err)
+		log.Info().Str("endpoint", "/oauth2/introspect").Str("rfc_error", rfc.ErrorField).Str("rfc_hint", rfc.HintField).Msg("introspection: inactive or unauthorized")
+		s.Provider.WriteIntrospectionError(ctx, w, err)
+		return
+	}
+	if err := s.CheckAccess(ir.GetAccessRequester()); err != nil {
+		log.Info().Err(err).Str("endpoint", "/oauth2/introspect").Str("request_id", ir.GetAccessRequester().GetID()).Msg("introspection: access revoked")
+		s.Provider.WriteIntrospectionResponse(ctx, w, &fosite.IntrospectionResponse{Active: false})
+		return
+	}
+	ar := ir.GetAccessRequester()
+	subject := ""
+	if ar.GetSession() != nil {
+		subject = ar.GetSession().GetSubject()
+	}
+	if !caller.allows(ar.GetClient().GetID(), subject) {
+		log.Info().Str("endpoint", "/oauth2/introspect").Str("caller_client_id", caller.clientID).Str("client_id", ar.GetClient().GetID()).Msg("introspection: token of another client or subject")
+		s.Provider.WriteIntrospectionResponse(ctx, w, &fosite.IntrospectionResponse{Active: false})
+		return
+	}
+	log.Info().Str("endpoint", "/oauth2/introspect").Str("client_id", ir.GetAccessRequester().GetClient().GetID()).Str("token_use", string(ir.GetTokenUse())).Msg("introspected token")
+	s.Provider.WriteIntrospectionResponse(ctx, w, ir)
+}
+
+func (s *Server) introspectAPIToken(token string, caller introspectionCaller) (map[string]any, bool) {
+	if token == "" {
+		return nil, false
+	}
+	tr, ok, err := s.GetToken(token)
+	if err != nil || !ok || time.Now().After(tr.ExpiresAt) {
+		return nil, false
+	}
+	if !caller.allows(tr.ClientID, tr.Subject) {
+		return nil, false
+	}
+	return map[string]any{
+		"active":     true,
+		"sub":        tr.Subject,
+		"client_id":  tr.ClientID,
+		"scope":      strings.Join(tr.Scopes, " "),
+		"exp":        tr.ExpiresAt.Unix(),
+		"token_type": "Bearer",
+	}, true
+}
+
+// revoke implements RFC 7009. OAuth tokens are revoked by fosite, which
+// authenticates the client. API tokens aren't bound to an OAuth client, so
+// presenting one is enough to revoke it.
+func (s *Server) revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method == http.MethodPost && s.dbPath != "" {
+		_ = r.ParseForm()
+		if token := r.PostForm.Get("token"); token != "" {
+			if tr, ok, err := s.GetToken(token); err == nil && ok {
+				if err := s.DeleteToken(token); err != nil {
+					log.Error().Err(err).Str("endpoint", "/oauth2/revoke").Msg("failed to delete API token")
+					s.Provider.WriteRevocationResponse(ctx, w, fosite.ErrServerError)
+					return
+				}
+				log.Info().Str("endpoint", "/oauth2/revoke").Str("subject", tr.Subject).Str("client_id", tr.ClientID).Msg("revoked API token")
+				s.Provider.WriteRevocationResponse(ctx, w, nil)
+				return
+			}
+		}
+	}
+	err := s.Provider.NewRevocationRequest(ctx, r)
+	if err != nil {
+		rfc := fosite.ErrorToRFC6749Error(err)
+		log.Warn().Err(err).Str("endpoint", "/oauth2/revoke").Str("rfc_error", rfc.ErrorField).Str("rfc_hint", rfc.HintField).Msg("revocation error")
+	} else {
+		log.Info().Str("endpoint", "/oauth2/revoke").Str("client_id", r.PostForm.Get("client_id")).Str("token_type_hint", r.PostForm.Get("token_type_hint")).Msg("revoked token")
+	}
+	s.Provider.WriteRevocationResponse(ctx, w, err)
+}
+
+// ListClients returns the registered clients with their number of active grants.
+func (s *Server) ListClients() ([]Client, error) {
+	if s.dbPath == "" {
+		return nil, fosite.ErrServerError.WithHint("db not enabled")
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT c.client_id, c.client_name, c.redirect_uris, c.disabled, c.created_at,
+            (SELECT COUNT(*) FROM oauth_grants g WHERE g.client_id = c.client_id AND g.revoked_at IS NULL)
+        FROM oauth_clients c ORDER BY c.client_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Client
+	for rows.Next() {
+		var c Client
+		var uris string
+		var disabled int
+		var createdAt sql.NullTime
+		if err := rows.Scan(&c.ClientID, &c.Name, &uris, &disabled, &createdAt, &c.ActiveGrants); err != nil {
+			return nil, err
+		}
+		c.RedirectURIs = splitCSV(uris)
+		c.Disabled = disabled != 0
+		c.CreatedAt = createdAt.Time
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SetClientDisabled disables or re-enables a registered client. Disabled
+// clients can't authorize, exchange or refresh tokens, and their existing
+// tokens are reported inactive until the client is enabled again.
+func (s *Server) SetClientDisabled(clientID string, disabled bool) error {
+	if s.dbPath == "" {
+		return fosite.ErrServerError.WithHint("db not enabled")
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	flag := 0
+	if disabled {
+		flag = 1
+	}
+	res, err := db.Exec(`UPDATE oauth_clients SET disabled = ? WHERE client_id = ?`, flag, clientID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("client %q is not registered", clientID)
+	}
+	return nil
+}
+
+// ListSessions returns unexpired login sessions, of one subject if given.
+func (s *Server) ListSessions(subject string) ([]Session, error) {
+	if s.dbPath == "" {
+		return nil, fosite.ErrServerError.WithHint("db not enabled")
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT session_id, subject, provider, id_token, refresh_token, expires_at, created_at FROM user_sessions
+        WHERE expires_at > ? AND (? = '' OR subject = ?) ORDER BY created_at DESC`, time.Now(), subject, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Session
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(&sess.SessionID, &sess.Subject, &sess.Provider, &sess.IDToken, &sess.RefreshToken, &sess.ExpiresAt, &sess.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, rows.Err()
+}
+
+// ListGrants returns grants that haven't been revoked, of one subject if
+// given. Fosite's refresh tokens don't expire by default, so grants stay
+// listed until revoked.
+func (s *Server) ListGrants(subject string) ([]Grant, error) {
+	if s.dbPath == "" {
+		return nil, fosite.ErrServerError.WithHint("db not enabled")
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT request_id, subject, client_id, scopes, created_at, last_used_at FROM oauth_grants
+        WHERE revoked_at IS NULL AND (? = '' OR subject = ?) ORDER BY last_used_at DESC`, subject, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Grant
+	for rows.Next() {
+		var g Grant
+		var scopes string
+		if err := rows.Scan(&g.RequestID, &g.Subject, &g.ClientID, &scopes, &g.CreatedAt, &g.LastUsedAt); err != nil {
+			return nil, err
+		}
+		g.Scopes = splitCSV(scopes)
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// RevokeSubject cuts a user's access: it deletes their login sessions and API
+// tokens and revokes their grants, which makes the access and refresh tokens
+// issued for them inactive. The user can log in again unless disabled.
+func (s *Server) RevokeSubject(subject string) (RevocationSummary, error) {
+	var summary RevocationSummary
+	if s.dbPath == "" {
+		return summary, fosite.ErrServerError.WithHint("db not enabled")
+	}
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return summary, err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return summary, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`DELETE FROM user_sessions WHERE subject = ?`, subject)
+	if err != nil {
+		return summary, err
+	}
+	summary.Sessions, _ = res.RowsAffected()
+	res, err = tx.Exec(`DELETE FROM oauth_tokens WHERE subject = ?`, subject)
+	if err != nil {
+		return summary, err
+	}
+	summary.Tokens, _ = res.RowsAffected()
+	res, err = tx.Exec(`UPDATE oauth_grants SET revoked_at = ? WHERE subject = ? AND revoked_at IS NULL`, time.Now(), subject)
+	if err != nil {
+		return summary, err
+	}
+	summary.Grants, _ = res.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return summary, err
+	}
+
+	log.Info().Str("component", "idsrv").Str("subject", subject).Int64("sessions", summary.Sessions).Int64("grants", summary.Grants).Int64("tokens", summary.Tokens).Msg("revoked subject access")
+	return summary, nil
+}
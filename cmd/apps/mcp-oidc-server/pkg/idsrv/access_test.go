@@ -0,0 +1,346 @@
+package idsrv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+)
+
+const testClientSecret = "resource-server-secret"
+
+// newTestServer serves the identity server with SQLite and two confidential
+// clients allowed to use client credentials
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New("http://idsrv.localhost")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.InitSQLite(filepath.Join(t.TempDir(), "idsrv.db")); err != nil {
+		t.Fatalf("InitSQLite: %v", err)
+	}
+	hash, err := s.cfg.GetSecretsHasher(context.Background()).Hash(context.Background(), []byte(testClientSecret))
+	if err != nil {
+		t.Fatalf("hash secret: %v", err)
+	}
+	for _, id := range []string{"svc-a", "svc-b"} {
+		s.store.Clients[id] = &fosite.DefaultClient{
+			ID:         id,
+			Secret:     hash,
+			GrantTypes: []string{"client_credentials"},
+			Scopes:     []string{"api"},
+		}
+		if err := s.persistClientToSQLite(id, id, nil); err != nil {
+			t.Fatalf("persist client: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	s.Routes(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func clientCredentialsToken(t *testing.T, ts *httptest.Server, clientID string) string {
+	t.Helper()
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {"api"}}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, testClientSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("token request: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.AccessToken == "" {
+		t.Fatalf("token response %d: %v", resp.StatusCode, err)
+	}
+	return body.AccessToken
+}
+
+// introspectAs returns the status and the active flag of an introspection,
+// authenticating with a bearer token or, if bearer is empty, as basicClient
+func introspectAs(t *testing.T, ts *httptest.Server, bearer, basicClient, token string) (int, bool) {
+	t.Helper()
+	form := url.Values{"token": {token}}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/oauth2/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	} else {
+		req.SetBasicAuth(basicClient, testClientSecret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("introspect request: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Active bool `json:"active"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body.Active
+}
+
+func TestIntrospectRestrictsBearerCallers(t *testing.T) {
+	s, ts := newTestServer(t)
+	tokenA := clientCredentialsToken(t, ts, "svc-a")
+	tokenB1 := clientCredentialsToken(t, ts, "svc-b")
+	tokenB2 := clientCredentialsToken(t, ts, "svc-b")
+
+	tests := []struct {
+		name        string
+		bearer      string
+		basicClient string
+		token       string
+		wantStatus  int
+		wantActive  bool
+	}{
+		{"own client token", tokenB1, "", tokenB2, http.StatusOK, true},
+		{"other client token", tokenB1, "", tokenA, http.StatusOK, false},
+		{"resource server credentials", "", "svc-a", tokenB1, http.StatusOK, true},
+		{"invalid bearer", "not-a-token", "", tokenA, http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, active := introspectAs(t, ts, tt.bearer, tt.basicClient, tt.token)
+			if status != tt.wantStatus || active != tt.wantActive {
+				t.Errorf("got status %d active %v, want %d %v", status, active, tt.wantStatus, tt.wantActive)
+			}
+		})
+	}
+
+	// Tokens of a disabled client no longer authenticate the caller
+	if err := s.SetClientDisabled("svc-b", true); err != nil {
+		t.Fatalf("disable client: %v", err)
+	}
+	if status, _ := introspectAs(t, ts, tokenB1, "", tokenB2); status != http.StatusUnauthorized {
+		t.Errorf("bearer of a disabled client: got status %d, want 401", status)
+	}
+	if status, _ := introspectAs(t, ts, "", "svc-b", tokenA); status != http.StatusUnauthorized {
+		t.Errorf("credentials of a disabled client: got status %d, want 401", status)
+	}
+}
+
+// tokenResponse is the body of a token endpoint response
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+}
+
+// postForm posts a form to an endpoint of the test server, authenticated as
+// basicClient unless empty
+func postForm(t *testing.T, ts *httptest.Server, path, basicClient string, form url.Values) (int, tokenResponse) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicClient != "" {
+		req.SetBasicAuth(basicClient, testClientSecret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	var body tokenResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+// authorizeDevClient logs subject in and runs the authorization code flow
+// of the public dev client with PKCE. It returns the token response, or
+// the error of the authorization redirect in tokenResponse.Error.
+func authorizeDevClient(t *testing.T, s *Server, ts *httptest.Server, subject, scope string) tokenResponse {
+	t.Helper()
+	sid, err := s.createSession(subject, time.Hour)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	redirectURI := s.Issuer + "/dev/callback"
+	query := url.Values{
+		"client_id":             {"dev-client"},
+		"response_type":         {"code"},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {scope},
+		"state":                 {"state-0123456789"},
+		"nonce":                 {"nonce-0123456789"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/oauth2/auth?"+query.Encode(), nil)
+	req.AddCookie(&http.Cookie{Name: cookieName, Value: sid})
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("authorize request: %v", err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize response %d without redirect: %v", resp.StatusCode, err)
+	}
+	if e := location.Query().Get("error"); e != "" {
+		return tokenResponse{Error: e}
+	}
+
+	status, tokens := postForm(t, ts, "/oauth2/token", "", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"dev-client"},
+		"code":          {location.Query().Get("code")},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("code exchange: status %d, error %q", status, tokens.Error)
+	}
+	return tokens
+}
+
+// refresh exchanges a refresh token of the dev client
+func refresh(t *testing.T, ts *httptest.Server, refreshToken string) (int, tokenResponse) {
+	t.Helper()
+	return postForm(t, ts, "/oauth2/token", "", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {"dev-client"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+func TestAuthorizeGrantsRegisteredScopes(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	tests := []struct {
+		scope       string
+		wantScope   string
+		wantRefresh bool
+		wantError   string
+	}{
+		{scope: "openid profile", wantScope: "openid profile"},
+		{scope: "openid offline_access", wantScope: "openid offline_access", wantRefresh: true},
+		// The dev client isn't registered for api
+		{scope: "openid api", wantError: "invalid_scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			tokens := authorizeDevClient(t, s, ts, "alice", tt.scope)
+			if tokens.Error != tt.wantError {
+				t.Fatalf("got error %q, want %q", tokens.Error, tt.wantError)
+			}
+			if tt.wantError != "" {
+				return
+			}
+			if tokens.Scope != tt.wantScope || tokens.IDToken == "" || (tokens.RefreshToken != "") != tt.wantRefresh {
+				t.Errorf("got scope %q, ID token %v, refresh token %v, want %q, true, %v",
+					tokens.Scope, tokens.IDToken != "", tokens.RefreshToken != "", tt.wantScope, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestRevokeSubject(t *testing.T) {
+	s, ts := newTestServer(t)
+	alice := authorizeDevClient(t, s, ts, "alice", "openid offline_access")
+	bob := authorizeDevClient(t, s, ts, "bob", "openid offline_access")
+	for _, subject := range []string{"alice", "bob"} {
+		if err := s.PersistToken(TokenRecord{Token: "api-" + subject, Subject: subject, ClientID: "svc-a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatalf("persist token: %v", err)
+		}
+	}
+
+	summary, err := s.RevokeSubject("alice")
+	if err != nil {
+		t.Fatalf("RevokeSubject: %v", err)
+	}
+	if want := (RevocationSummary{Sessions: 1, Grants: 1, Tokens: 1}); summary != want {
+		t.Errorf("got summary %+v, want %+v", summary, want)
+	}
+
+	// Alice's access token is inactive and her refresh token refused
+	if _, active := introspectAs(t, ts, "", "svc-a", alice.AccessToken); active {
+		t.Error("access token of a revoked subject is active")
+	}
+	if status, _ := refresh(t, ts, alice.RefreshToken); status == http.StatusOK {
+		t.Error("refresh token of a revoked subject was exchanged")
+	}
+	if _, ok, _ := s.GetToken("api-alice"); ok {
+		t.Error("API token of a revoked subject was kept")
+	}
+	if sessions, _ := s.ListSessions("alice"); len(sessions) != 0 {
+		t.Errorf("got %d sessions of a revoked subject, want 0", len(sessions))
+	}
+
+	// Bob keeps his access
+	if _, active := introspectAs(t, ts, "", "svc-a", bob.AccessToken); !active {
+		t.Error("access token of another subject is inactive")
+	}
+	if status, tokens := refresh(t, ts, bob.RefreshToken); status != http.StatusOK {
+		t.Errorf("refresh token of another subject: got status %d, error %q", status, tokens.Error)
+	}
+	if _, ok, _ := s.GetToken("api-bob"); !ok {
+		t.Error("API token of another subject was deleted")
+	}
+
+	// Revoking again finds nothing
+	if summary, err := s.RevokeSubject("alice"); err != nil || summary != (RevocationSummary{}) {
+		t.Errorf("second revocation: got %+v, %v", summary, err)
+	}
+}
+
+func TestRevokeEndpoint(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	// API tokens are revoked by presenting them
+	if err := s.PersistToken(TokenRecord{Token: "api-alice", Subject: "alice", ClientID: "svc-a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("persist token: %v", err)
+	}
+	if status, _ := postForm(t, ts, "/oauth2/revoke", "", url.Values{"token": {"api-alice"}}); status != http.StatusOK {
+		t.Errorf("revoke API token: got status %d, want 200", status)
+	}
+	if _, ok, _ := s.GetToken("api-alice"); ok {
+		t.Error("revoked API token still exists")
+	}
+
+	// OAuth tokens can only be revoked by their client, fosite answers
+	// other clients as for an unknown token
+	tokens := authorizeDevClient(t, s, ts, "alice", "openid offline_access")
+	postForm(t, ts, "/oauth2/revoke", "svc-a", url.Values{"token": {tokens.AccessToken}})
+	if _, active := introspectAs(t, ts, "", "svc-a", tokens.AccessToken); !active {
+		t.Error("another client revoked the access token")
+	}
+
+	// Revoking the refresh token revokes the access token issued with it
+	form := url.Values{"token": {tokens.RefreshToken}, "token_type_hint": {"refresh_token"}, "client_id": {"dev-client"}}
+	if status, body := postForm(t, ts, "/oauth2/revoke", "", form); status != http.StatusOK {
+		t.Errorf("revoke refresh token: got status %d, error %q", status, body.Error)
+	}
+	if _, active := introspectAs(t, ts, "", "svc-a", tokens.AccessToken); active {
+		t.Error("access token is active after revoking its refresh token")
+	}
+	if status, _ := refresh(t, ts, tokens.RefreshToken); status == http.StatusOK {
+		t.Error("revoked refresh token was exchanged")
+	}
+
+	// Unknown tokens are reported revoked (RFC 7009)
+	if status, _ := postForm(t, ts, "/oauth2/revoke", "svc-a", url.Values{"token": {"unknown"}}); status != http.StatusOK {
+		t.Errorf("revoke unknown token: got status %d, want 200", status)
+	}
+}
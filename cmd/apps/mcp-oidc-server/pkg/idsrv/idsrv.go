@@ -10,7 +10,6 @@ import (
     "encoding/json"
     "encoding/pem"
     "html/template"
-    "math/big"
     "net/http"
     "net/url"
     "strings"
@@ -41,7 +40,6 @@ func init() {
 }
 
 type Server struct {
-    PrivateKey *rsa.PrivateKey
     Issuer     string
     Provider   fosite.OAuth2Provider
     store      *storage.MemoryStore
@@ -51,12 +49,18 @@ type Server struct {
     // set when InitSQLite is called
     // guarded by mu for writes
     dbPath string
+    // signing keys, newest first; only used without SQLite, which
+    // stores them in oauth_keys
+    keys     []SigningKey
+    keyCache map[string]*rsa.PrivateKey
     // demo login
     User string
     Pass string
     // Model C: local users + sessions
     LocalUsersEnabled bool
     SessionTTL        time.Duration
+    // upstream OIDC login, set by EnableUpstream
+    upstream *upstreamProvider
 }
 
 func New(issuer string) (*Server, error) {
@@ -82,20 +86,44 @@ func New(issuer string) (*Server, error) {
         Public:        true,
     }
 
-    // Use high-level composer: pass the RSA private key (not the HMAC secret)
-    provider := compose.ComposeAllEnabled(cfg, mem, privateKey)
+    s := &Server{
+        Issuer: issuer,
+        store:  mem,
+        cfg:    cfg,
+        keys:   []SigningKey{{KID: "1", Key: privateKey, CreatedAt: time.Now()}},
+        User:   "admin",
+        Pass:   "password123",
+    }
+
+    // Same handlers as compose.ComposeAllEnabled, but the signing key is looked
+    // up for each token so that key rotations apply without recomposing
+    s.Provider = compose.Compose(
+        cfg,
+        mem,
+        &compose.CommonStrategy{
+            CoreStrategy:               compose.NewOAuth2HMACStrategy(cfg),
+            OpenIDConnectTokenStrategy: compose.NewOpenIDConnectStrategy(s.signingJWK, cfg),
+            Signer:                     &jwt.DefaultSigner{GetPrivateKey: s.signingJWK},
+        },
+        compose.OAuth2AuthorizeExplicitFactory,
+        compose.OAuth2AuthorizeImplicitFactory,
+        compose.OAuth2ClientCredentialsGrantFactory,
+        compose.OAuth2RefreshTokenGrantFactory,
+        compose.OAuth2ResourceOwnerPasswordCredentialsFactory,
+        compose.RFC7523AssertionGrantFactory,
+        compose.OpenIDConnectExplicitFactory,
+        compose.OpenIDConnectImplicitFactory,
+        compose.OpenIDConnectHybridFactory,
+        compose.OpenIDConnectRefreshFactory,
+        compose.OAuth2TokenIntrospectionFactory,
+        compose.OAuth2TokenRevocationFactory,
+        compose.OAuth2PKCEFactory,
+        compose.PushedAuthorizeHandlerFactory,
+    )
 
     log.Debug().Str("component", "idsrv").Str("issuer", issuer).Str("dev_client_id", devClientID).Str("dev_redirect", devRedirect).Msg("initialized identity server")
 
-    return &Server{
-        PrivateKey: privateKey,
-        Issuer:     issuer,
-        Provider:   provider,
-        store:      mem,
-        cfg:        cfg,
-        User:       "admin",
-        Pass:       "password123",
-    }, nil
+    return s, nil
 }
 
 func (s *Server) Routes(mux *http.ServeMux) {
@@ -103,9 +131,13 @@ func (s *Server) Routes(mux *http.ServeMux) {
     mux.HandleFunc("/.well-known/oauth-authorization-server", s.asMetadata)
     mux.HandleFunc("/jwks.json", s.jwks)
     mux.HandleFunc("/login", s.login)
+    mux.HandleFunc("/login/upstream", s.upstreamLogin)
+    mux.HandleFunc("/login/upstream/callback", s.upstreamCallback)
     mux.HandleFunc("/logout", s.logout)
     mux.HandleFunc("/oauth2/auth", s.authorize)
     mux.HandleFunc("/oauth2/token", s.token)
+    mux.HandleFunc("/oauth2/introspect", s.introspect)
+    mux.HandleFunc("/oauth2/revoke", s.revoke)
     mux.HandleFunc("/register", s.register)
 }
 
@@ -125,6 +157,9 @@ func (s *Server) oidcDiscovery(w http.ResponseWriter, r *http.Request) {
         "token_endpoint_auth_methods_supported": []string{"none"},
         "code_challenge_methods_supported":       []string{"S256"},
         "registration_endpoint":                  s.Issuer + "/register",
+        "introspection_endpoint":                 s.Issuer + "/oauth2/introspect",
+        "revocation_endpoint":                    s.Issuer + "/oauth2/revoke",
+        "revocation_endpoint_auth_methods_supported": []string{"none"},
     }
     writeJSON(w, j)
 }
@@ -142,33 +177,33 @@ func (s *Server) asMetadata(w http.ResponseWriter, r *http.Request) {
         "scopes_supported":                  []string{"openid", "profile", "offline_access"},
         "token_endpoint_auth_methods_supported": []string{"none"},
         "registration_endpoint":               s.Issuer + "/register",
+        "introspection_endpoint":              s.Issuer + "/oauth2/introspect",
+        "revocation_endpoint":                 s.Issuer + "/oauth2/revoke",
+        "revocation_endpoint_auth_methods_supported": []string{"none"},
     }
     writeJSON(w, j)
 }
 
-func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
-    log.Debug().Str("endpoint", "/jwks.json").Msg("serving JWKS")
-    pub := &s.PrivateKey.PublicKey
-    jwks := map[string]any{
-        "keys": []map[string]any{
-            {
-                "kty": "RSA", "alg": "RS256", "use": "sig", "kid": "1",
-                "n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
-                "e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
-            },
-        },
-    }
-    writeJSON(w, jwks)
-}
-
 const cookieName = "sid"
 
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
     switch r.Method {
     case http.MethodGet:
         log.Debug().Str("endpoint", "/login").Str("method", "GET").Str("return_to", r.URL.Query().Get("return_to")).Msg("render login")
+        // SSO only: skip the login form
+        if s.upstream != nil && !s.LocalUsersEnabled {
+            http.Redirect(w, r, "/login/upstream?return_to="+url.QueryEscape(r.URL.Query().Get("return_to")), http.StatusFound)
+            return
+        }
+        data := struct {
+            ReturnTo     string
+            UpstreamName string
+        }{ReturnTo: r.URL.Query().Get("return_to")}
+        if s.upstream != nil {
+            data.UpstreamName = s.upstream.cfg.Name
+        }
         w.Header().Set("Content-Type", "text/html; charset=utf-8")
-        _ = loginTemplate.Execute(w, struct{ ReturnTo string }{r.URL.Query().Get("return_to")})
+        _ = loginTemplate.Execute(w, data)
     case http.MethodPost:
         _ = r.ParseForm()
         u := r.FormValue("username")
@@ -189,7 +224,7 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
                 http.Redirect(w, r, rt, http.StatusFound)
                 return
             }
-        } else if u == s.User && p == s.Pass {
+        } else if s.upstream == nil && u == s.User && p == s.Pass {
             http.SetCookie(w, &http.Cookie{Name: cookieName, Value: "ok:"+u, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
             rt := r.FormValue("return_to")
             if rt == "" { rt = "/" }
@@ -266,12 +301,26 @@ func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
         s.Provider.WriteAuthorizeError(ctx, w, ar, err)
         return
     }
+    if err := s.checkClient(ar.GetClient().GetID()); err != nil {
+        log.Warn().Err(err).Str("endpoint", "/oauth2/auth").Str("client_id", q.Get("client_id")).Msg("client refused")
+        s.Provider.WriteAuthorizeError(ctx, w, ar, err)
+        return
+    }
     user, ok := s.lookupSessionSubject(r)
     if !ok {
         log.Debug().Str("endpoint", "/oauth2/auth").Msg("not logged in, redirect to /login")
         http.Redirect(w, r, "/login?return_to="+url.QueryEscape(r.URL.String()), http.StatusFound)
         return
     }
+    // There is no consent screen, so only scopes the client is registered
+    // for are granted: openid yields an ID token, offline_access a refresh
+    // token
+    scopeStrategy := s.cfg.GetScopeStrategy(ctx)
+    for _, scope := range ar.GetRequestedScopes() {
+        if scopeStrategy(ar.GetClient().GetScopes(), scope) {
+            ar.GrantScope(scope)
+        }
+    }
     now := time.Now()
     sess := &openid.DefaultSession{
         Subject:  user,
@@ -284,7 +333,8 @@ func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
             RequestedAt: now,
             Audience:    []string{ar.GetClient().GetID()},
         },
-        Headers: &jwt.Headers{Extra: map[string]any{"kid": "1"}},
+        // kid is set by the signer from the active signing key
+        Headers: &jwt.Headers{},
     }
     resp, err := s.Provider.NewAuthorizeResponse(ctx, ar, sess)
     if err != nil {
@@ -328,6 +378,12 @@ func (s *Server) token(w http.ResponseWriter, r *http.Request) {
         s.Provider.WriteAccessError(ctx, w, accessReq, err)
         return
     }
+    // Refresh tokens of revoked grants and disabled clients are refused
+    if err := s.CheckAccess(accessReq); err != nil {
+        log.Warn().Err(err).Str("endpoint", "/oauth2/token").Str("client_id", accessReq.GetClient().GetID()).Msg("access refused")
+        s.Provider.WriteAccessError(ctx, w, accessReq, err)
+        return
+    }
     resp, err := s.Provider.NewAccessResponse(ctx, accessReq)
     if err != nil {
         rfc := fosite.ErrorToRFC6749Error(err)
@@ -340,6 +396,9 @@ func (s *Server) token(w http.ResponseWriter, r *http.Request) {
         s.Provider.WriteAccessError(ctx, w, accessReq, err)
         return
     }
+    if err := s.recordGrant(accessReq); err != nil {
+        log.Error().Err(err).Str("endpoint", "/oauth2/token").Msg("failed to record grant")
+    }
     log.Info().Str("endpoint", "/oauth2/token").Str("grant_type", form.Get("grant_type")).Msg("token exchange success")
     s.Provider.WriteAccessResponse(ctx, w, accessReq, resp)
 }
@@ -363,6 +422,15 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
     if id == "" {
         id = "client-" + time.Now().Format("20060102-150405")
     }
+    if disabled, err := s.ClientDisabled(id); err != nil {
+        log.Error().Err(err).Str("endpoint", "/register").Str("client_id", id).Msg("failed to check client")
+        http.Error(w, "server error", http.StatusInternalServerError)
+        return
+    } else if disabled {
+        log.Warn().Str("endpoint", "/register").Str("client_id", id).Msg("refused re-registration of disabled client")
+        http.Error(w, "client is disabled", http.StatusForbidden)
+        return
+    }
     // persist in memory store
     s.mu.Lock()
     s.store.Clients[id] = &fosite.DefaultClient{
@@ -375,7 +443,7 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
     }
     s.mu.Unlock()
     // persist if enabled
-    if err := s.persistClientToSQLite(id, payload.RedirectURIs); err != nil {
+    if err := s.persistClientToSQLite(id, payload.ClientName, payload.RedirectURIs); err != nil {
         log.Error().Err(err).Str("endpoint", "/register").Str("client_id", id).Msg("failed to persist client")
     }
     log.Info().Str("endpoint", "/register").Str("client_id", id).Interface("redirect_uris", payload.RedirectURIs).Msg("dynamic client registered")
@@ -454,24 +522,35 @@ func (s *Server) InitSQLite(path string) error {
         duration_ms INTEGER NOT NULL
     );`); err != nil { return err }
 
-    // Load or persist signing key
-    var existingPEM []byte
-    var existingKID string
-    row := db.QueryRow(`SELECT kid, private_pem FROM oauth_keys LIMIT 1`)
-    _ = row.Scan(&existingKID, &existingPEM)
-    if len(existingPEM) > 0 {
-        pk, err := pemDecodeRSAPrivateKey(existingPEM)
-        if err != nil { return err }
-        s.PrivateKey = pk
-        // Recompose provider with persisted key
-        s.Provider = compose.ComposeAllEnabled(s.cfg, s.store, s.PrivateKey)
-        log.Info().Str("component", "idsrv").Str("db", path).Str("kid", existingKID).Msg("loaded signing key from sqlite")
+    // Revocation and admin: per-grant state so that revocations made with the
+    // CLI apply to a running server, rotated signing keys, disabled clients
+    if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS oauth_grants (
+        request_id TEXT PRIMARY KEY,
+        subject TEXT NOT NULL,
+        client_id TEXT NOT NULL,
+        scopes TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        last_used_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP
+    );`); err != nil { return err }
+    if err := addColumnIfMissing(db, "oauth_keys", "retire_at", "TIMESTAMP"); err != nil { return err }
+    if err := addColumnIfMissing(db, "oauth_clients", "client_name", "TEXT NOT NULL DEFAULT ''"); err != nil { return err }
+    if err := addColumnIfMissing(db, "oauth_clients", "disabled", "INTEGER NOT NULL DEFAULT 0"); err != nil { return err }
+    if err := addColumnIfMissing(db, "oauth_clients", "created_at", "TIMESTAMP"); err != nil { return err }
+
+    // Persist the in-memory signing key if the DB has none; keys are read from
+    // the DB from now on
+    var keyCount int
+    if err := db.QueryRow(`SELECT COUNT(*) FROM oauth_keys`).Scan(&keyCount); err != nil { return err }
+    if keyCount == 0 {
+        for _, k := range s.keys {
+            pemBytes, err := pemEncodeRSAPrivateKey(k.Key)
+            if err != nil { return err }
+            if _, err := db.Exec(`INSERT INTO oauth_keys (kid, private_pem, created_at, retire_at) VALUES (?, ?, ?, ?)`, k.KID, pemBytes, k.CreatedAt, k.RetireAt); err != nil { return err }
+            log.Info().Str("component", "idsrv").Str("db", path).Str("kid", k.KID).Msg("persisted new signing key to sqlite")
+        }
     } else {
-        // Persist current in-memory key
-        pemBytes, err := pemEncodeRSAPrivateKey(s.PrivateKey)
-        if err != nil { return err }
-        if _, err := db.Exec(`INSERT INTO oauth_keys (kid, private_pem, created_at) VALUES (?, ?, ?)`, "1", pemBytes, time.Now()); err != nil { return err }
-        log.Info().Str("component", "idsrv").Str("db", path).Str("kid", "1").Msg("persisted new signing key to sqlite")
+        log.Info().Str("component", "idsrv").Str("db", path).Int("keys", keyCount).Msg("using signing keys from sqlite")
     }
     // Load existing
     rows, err := db.Query(`SELECT client_id, redirect_uris FROM oauth_clients`)
@@ -509,12 +588,15 @@ func pemDecodeRSAPrivateKey(p []byte) (*rsa.PrivateKey, error) {
     return x509.ParsePKCS1PrivateKey(blk.Bytes)
 }
 
-func (s *Server) persistClientToSQLite(id string, redirects []string) error {
+func (s *Server) persistClientToSQLite(id, name string, redirects []string) error {
     if s.dbPath == "" { return nil }
     db, err := sqlOpen(s.dbPath)
     if err != nil { return err }
     defer db.Close()
-    _, err = db.Exec(`INSERT OR REPLACE INTO oauth_clients (client_id, redirect_uris) VALUES (?, ?)`, id, joinCSV(redirects))
+    // Upsert keeps the disabled flag of re-registered clients
+    _, err = db.Exec(`INSERT INTO oauth_clients (client_id, redirect_uris, client_name, created_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT(client_id) DO UPDATE SET redirect_uris = excluded.redirect_uris, client_name = excluded.client_name`,
+        id, joinCSV(redirects), name, time.Now())
     return err
 }
 
@@ -659,6 +741,18 @@ func (s *Server) verifyUserPassword(username, password string) (bool, error) {
     return match, err
 }
 
+// userDisabled reports whether a local user exists and is disabled
+func (s *Server) userDisabled(username string) (bool, error) {
+    if s.dbPath == "" { return false, nil }
+    db, err := sqlOpen(s.dbPath)
+    if err != nil { return false, err }
+    defer db.Close()
+    var disabled int
+    err = db.QueryRow(`SELECT disabled FROM users WHERE username = ?`, username).Scan(&disabled)
+    if err == sql.ErrNoRows { return false, nil }
+    return disabled != 0, err
+}
+
 func (s *Server) CreateUser(username, email, password string) error {
     if s.dbPath == "" { return fosite.ErrServerError.WithHint("db not enabled") }
     db, err := sqlOpen(s.dbPath)
@@ -720,6 +814,11 @@ type Session struct {
 }
 
 func (s *Server) createSession(subject string, ttl time.Duration) (string, error) {
+    return s.createSessionWithProvider(subject, ttl, "local", "", "")
+}
+
+// createSessionWithProvider creates a session, keeping the upstream tokens of federated logins
+func (s *Server) createSessionWithProvider(subject string, ttl time.Duration, provider, idToken, refreshToken string) (string, error) {
     if ttl <= 0 { ttl = 12 * time.Hour }
     if s.dbPath == "" { return "", fosite.ErrServerError.WithHint("db not enabled") }
     db, err := sqlOpen(s.dbPath)
@@ -727,7 +826,7 @@ func (s *Server) createSession(subject string, ttl time.Duration) (string, error
     defer db.Close()
     sid := randomID()
     exp := time.Now().Add(ttl)
-    _, err = db.Exec(`INSERT INTO user_sessions (session_id, subject, provider, id_token, refresh_token, expires_at, created_at) VALUES (?, ?, ?, '', '', ?, ?)`, sid, subject, "local", exp, time.Now())
+    _, err = db.Exec(`INSERT INTO user_sessions (session_id, subject, provider, id_token, refresh_token, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`, sid, subject, provider, idToken, refreshToken, exp, time.Now())
     if err != nil { return "", err }
     return sid, nil
 }
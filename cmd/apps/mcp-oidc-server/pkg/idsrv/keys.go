@@ -0,0 +1,202 @@
+package idsrv
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// SigningKey is an RSA key used to sign ID tokens. The newest key without a
+// retirement time signs new tokens. Rotated keys stay in the JWKS until
+// RetireAt, so tokens signed before a rotation can still be verified.
+type SigningKey struct {
+	KID       string
+	Key       *rsa.PrivateKey
+	CreatedAt time.Time
+	RetireAt  *time.Time
+}
+
+// Status is "active" for the signing key, "published" for rotated keys still
+// in the JWKS and "retired" for keys past their retirement time.
+func (k SigningKey) Status(now time.Time) string {
+	switch {
+	case k.RetireAt == nil:
+		return "active"
+	case now.Before(*k.RetireAt):
+		return "published"
+	default:
+		return "retired"
+	}
+}
+
+// signingJWK returns the active key for fosite's signer. Wrapping it in a JWK
+// makes the signer set the matching kid header.
+func (s *Server) signingJWK(ctx context.Context) (interface{}, error) {
+	key, err := s.activeSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return &jose.JSONWebKey{Key: key.Key, KeyID: key.KID, Algorithm: "RS256", Use: "sig"}, nil
+}
+
+func (s *Server) activeSigningKey() (SigningKey, error) {
+	keys, err := s.ListSigningKeys()
+	if err != nil {
+		return SigningKey{}, err
+	}
+	for _, k := range keys {
+		if k.RetireAt == nil {
+			return k, nil
+		}
+	}
+	return SigningKey{}, errors.New("no active signing key")
+}
+
+// ListSigningKeys returns all signing keys, newest first. With SQLite
+// enabled the keys are read from the database, so rotations done with the CLI
+// are picked up by a running server.
+func (s *Server) ListSigningKeys() ([]SigningKey, error) {
+	if s.dbPath == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return append([]SigningKey(nil), s.keys...), nil
+	}
+
+	db, err := sqlOpen(s.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT kid, private_pem, created_at, retire_at FROM oauth_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SigningKey
+	for rows.Next() {
+		var k SigningKey
+		var pemBytes []byte
+		var retireAt sql.NullTime
+		if err := rows.Scan(&k.KID, &pemBytes, &k.CreatedAt, &retireAt); err != nil {
+			return nil, err
+		}
+		if retireAt.Valid {
+			k.RetireAt = &retireAt.Time
+		}
+		if k.Key, err = s.parseCachedKey(k.KID, pemBytes); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// parseCachedKey decodes a PEM key once per kid, keys are read on every
+// token signature and JWKS request
+func (s *Server) parseCachedKey(kid string, pemBytes []byte) (*rsa.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pk, ok := s.keyCache[kid]; ok {
+		return pk, nil
+	}
+	pk, err := pemDecodeRSAPrivateKey(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	if s.keyCache == nil {
+		s.keyCache = map[string]*rsa.PrivateKey{}
+	}
+	s.keyCache[kid] = pk
+	return pk, nil
+}
+
+// RotateSigningKey generates a new signing key. The previous keys stay
+// published in the JWKS for the overlap duration, which should be longer
+// than the ID token lifetime.
+func (s *Server) RotateSigningKey(overlap time.Duration) (SigningKey, error) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	now := time.Now()
+	retireAt := now.Add(overlap)
+	key := SigningKey{KID: newKID(now), Key: pk, CreatedAt: now}
+
+	if s.dbPath == "" {
+		s.mu.Lock()
+		for i := range s.keys {
+			if s.keys[i].RetireAt == nil {
+				s.keys[i].RetireAt = &retireAt
+			}
+		}
+		s.keys = append([]SigningKey{key}, s.keys...)
+		s.mu.Unlock()
+	} else {
+		pemBytes, err := pemEncodeRSAPrivateKey(pk)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		db, err := sqlOpen(s.dbPath)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			return SigningKey{}, err
+		}
+		if _, err := tx.Exec(`UPDATE oauth_keys SET retire_at = ? WHERE retire_at IS NULL`, retireAt); err != nil {
+			_ = tx.Rollback()
+			return SigningKey{}, err
+		}
+		if _, err := tx.Exec(`INSERT INTO oauth_keys (kid, private_pem, created_at) VALUES (?, ?, ?)`, key.KID, pemBytes, key.CreatedAt); err != nil {
+			_ = tx.Rollback()
+			return SigningKey{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return SigningKey{}, err
+		}
+	}
+
+	log.Info().Str("component", "idsrv").Str("kid", key.KID).Time("previous_retire_at", retireAt).Msg("rotated signing key")
+	return key, nil
+}
+
+// newKID derives a key ID from the creation time, with a random suffix
+func newKID(t time.Time) string {
+	return t.UTC().Format("20060102-150405") + "-" + randomID()[:6]
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Str("endpoint", "/jwks.json").Msg("serving JWKS")
+	keys, err := s.ListSigningKeys()
+	if err != nil {
+		log.Error().Str("endpoint", "/jwks.json").Err(err).Msg("failed loading signing keys")
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	published := []map[string]any{}
+	for _, k := range keys {
+		if k.Status(now) == "retired" {
+			continue
+		}
+		pub := &k.Key.PublicKey
+		published = append(published, map[string]any{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": k.KID,
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	writeJSON(w, map[string]any{"keys": published})
+}
@@ -0,0 +1,127 @@
+package idsrv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+func fetchJWKS(t *testing.T, ts *httptest.Server) jose.JSONWebKeySet {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/jwks.json")
+	if err != nil {
+		t.Fatalf("JWKS request: %v", err)
+	}
+	defer resp.Body.Close()
+	var jwks jose.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		t.Fatalf("decode JWKS: %v", err)
+	}
+	return jwks
+}
+
+func publishedKIDs(jwks jose.JSONWebKeySet) []string {
+	kids := []string{}
+	for _, k := range jwks.Keys {
+		kids = append(kids, k.KeyID)
+	}
+	return kids
+}
+
+// verifyIDToken checks an ID token against the JWKS and returns its kid
+func verifyIDToken(t *testing.T, jwks jose.JSONWebKeySet, idToken string) (string, bool) {
+	t.Helper()
+	jws, err := jose.ParseSigned(idToken)
+	if err != nil || len(jws.Signatures) != 1 {
+		t.Fatalf("parse ID token: %v", err)
+	}
+	kid := jws.Signatures[0].Header.KeyID
+	keys := jwks.Key(kid)
+	if len(keys) == 0 {
+		return kid, false
+	}
+	_, err = jws.Verify(keys[0].Public())
+	return kid, err == nil
+}
+
+func TestRotateSigningKey(t *testing.T) {
+	s, ts := newTestServer(t)
+	initial, err := s.activeSigningKey()
+	if err != nil {
+		t.Fatalf("activeSigningKey: %v", err)
+	}
+	before := authorizeDevClient(t, s, ts, "alice", "openid")
+
+	rotated, err := s.RotateSigningKey(time.Hour)
+	if err != nil {
+		t.Fatalf("RotateSigningKey: %v", err)
+	}
+	after := authorizeDevClient(t, s, ts, "alice", "openid")
+
+	// Within the overlap both keys are published, tokens signed before and
+	// after the rotation verify
+	jwks := fetchJWKS(t, ts)
+	if got, want := publishedKIDs(jwks), []string{rotated.KID, initial.KID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got published kids %v, want %v", got, want)
+	}
+	if kid, ok := verifyIDToken(t, jwks, before.IDToken); kid != initial.KID || !ok {
+		t.Errorf("ID token from before the rotation: got kid %s, verified %v, want %s, true", kid, ok, initial.KID)
+	}
+	if kid, ok := verifyIDToken(t, jwks, after.IDToken); kid != rotated.KID || !ok {
+		t.Errorf("ID token from after the rotation: got kid %s, verified %v, want %s, true", kid, ok, rotated.KID)
+	}
+
+	// Without overlap the previous key is retired at once, the initial key
+	// keeps its retirement time
+	latest, err := s.RotateSigningKey(0)
+	if err != nil {
+		t.Fatalf("RotateSigningKey: %v", err)
+	}
+	keys, err := s.ListSigningKeys()
+	if err != nil {
+		t.Fatalf("ListSigningKeys: %v", err)
+	}
+	now := time.Now()
+	var statuses []string
+	for _, k := range keys {
+		statuses = append(statuses, k.KID+" "+k.Status(now))
+	}
+	want := []string{latest.KID + " active", rotated.KID + " retired", initial.KID + " published"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("got keys %v, want %v", statuses, want)
+	}
+	jwks = fetchJWKS(t, ts)
+	if got, want := publishedKIDs(jwks), []string{latest.KID, initial.KID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got published kids %v, want %v", got, want)
+	}
+	if _, ok := verifyIDToken(t, jwks, after.IDToken); ok {
+		t.Error("ID token signed with a retired key verifies")
+	}
+}
+
+func TestRotateSigningKeyInMemory(t *testing.T) {
+	s, err := New("http://idsrv.localhost")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	rotated, err := s.RotateSigningKey(time.Hour)
+	if err != nil {
+		t.Fatalf("RotateSigningKey: %v", err)
+	}
+	keys, err := s.ListSigningKeys()
+	if err != nil {
+		t.Fatalf("ListSigningKeys: %v", err)
+	}
+	now := time.Now()
+	if len(keys) != 2 || keys[0].KID != rotated.KID || keys[0].Status(now) != "active" || keys[1].Status(now) != "published" {
+		t.Errorf("got keys %+v", keys)
+	}
+	if active, err := s.activeSigningKey(); err != nil || active.KID != rotated.KID {
+		t.Errorf("got active key %s, %v, want %s", active.KID, err, rotated.KID)
+	}
+}
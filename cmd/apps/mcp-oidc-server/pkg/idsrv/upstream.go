@@ -0,0 +1,418 @@
+package idsrv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+// UpstreamConfig configures login through an upstream OIDC provider, e.g. the
+// company SSO. The server acts as a broker: users authenticate upstream, and
+// MCP clients still get tokens issued by this server.
+type UpstreamConfig struct {
+	// Name is shown on the login button
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// SubjectClaim is the upstream claim identifying the user, e.g. sub,
+	// email or preferred_username
+	SubjectClaim string
+	// SubjectPrefix is prepended to the claim to form the local subject,
+	// "<prefix>:<claim>", so upstream users can't take over local users of
+	// the same name
+	SubjectPrefix string
+	GroupsClaim  string
+	// AllowedGroups restricts login to members of any of these groups; empty
+	// allows every user the upstream provider authenticates
+	AllowedGroups []string
+}
+
+type upstreamProvider struct {
+	cfg         UpstreamConfig
+	oauth       oauth2.Config
+	jwksURI     string
+	userinfoURI string
+	httpClient  *http.Client
+
+	mu      sync.Mutex
+	keys    jose.JSONWebKeySet
+	pending map[string]pendingLogin
+}
+
+// pendingLogin is an upstream login in progress, keyed by OAuth state
+type pendingLogin struct {
+	verifier  string
+	nonce     string
+	returnTo  string
+	expiresAt time.Time
+}
+
+const (
+	pendingLoginTTL = 10 * time.Minute
+	// maxPendingLogins bounds the logins waiting for the upstream callback,
+	// the oldest is dropped when a new one would exceed it
+	maxPendingLogins = 1000
+)
+
+// EnableUpstream fetches the upstream provider's discovery document and
+// enables /login/upstream. Sessions are stored in SQLite, InitSQLite must
+// have been called.
+func (s *Server) EnableUpstream(ctx context.Context, cfg UpstreamConfig) error {
+	if s.dbPath == "" {
+		return errors.New("upstream login requires SQLite sessions (--db)")
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return errors.New("upstream issuer and client id are required")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "SSO"
+	}
+	if cfg.SubjectClaim == "" {
+		cfg.SubjectClaim = "sub"
+	}
+	if cfg.SubjectPrefix == "" {
+		cfg.SubjectPrefix = "sso"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	discoveryURL := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, httpClient, discoveryURL, &discovery); err != nil {
+		return fmt.Errorf("fetching upstream discovery: %w", err)
+	}
+	if discovery.Issuer != cfg.Issuer {
+		return fmt.Errorf("upstream discovery issuer %q does not match %q", discovery.Issuer, cfg.Issuer)
+	}
+
+	p := &upstreamProvider{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: discovery.AuthorizationEndpoint, TokenURL: discovery.TokenEndpoint},
+			RedirectURL:  s.Issuer + "/login/upstream/callback",
+			Scopes:       cfg.Scopes,
+		},
+		jwksURI:     discovery.JWKSURI,
+		userinfoURI: discovery.UserinfoEndpoint,
+		httpClient:  httpClient,
+		pending:     map[string]pendingLogin{},
+	}
+	if err := p.refreshKeys(ctx); err != nil {
+		return fmt.Errorf("fetching upstream JWKS: %w", err)
+	}
+
+	s.upstream = p
+	log.Info().Str("component", "idsrv").Str("upstream_issuer", cfg.Issuer).Str("client_id", cfg.ClientID).Str("redirect_uri", p.oauth.RedirectURL).
+		Str("subject_claim", cfg.SubjectClaim).Str("subject_prefix", cfg.SubjectPrefix).Strs("allowed_groups", cfg.AllowedGroups).Msg("upstream login enabled")
+	return nil
+}
+
+// upstreamLogin starts an authorization code flow with PKCE at the upstream provider
+func (s *Server) upstreamLogin(w http.ResponseWriter, r *http.Request) {
+	p := s.upstream
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	state, nonce, verifier := randomID(), randomID(), oauth2.GenerateVerifier()
+	p.addPending(state, pendingLogin{
+		verifier:  verifier,
+		nonce:     nonce,
+		returnTo:  localReturnTo(r.URL.Query().Get("return_to")),
+		expiresAt: time.Now().Add(pendingLoginTTL),
+	})
+	authURL := p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce))
+	log.Debug().Str("endpoint", "/login/upstream").Str("upstream_issuer", p.cfg.Issuer).Msg("redirecting to upstream provider")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// upstreamCallback completes the upstream flow, maps the claims to a local
+// subject and starts a session
+func (s *Server) upstreamCallback(w http.ResponseWriter, r *http.Request) {
+	p := s.upstream
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	pending, ok := p.takePending(q.Get("state"))
+	if !ok {
+		log.Warn().Str("endpoint", "/login/upstream/callback").Msg("unknown or expired state")
+		http.Error(w, "login expired, please try again", http.StatusBadRequest)
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		log.Warn().Str("endpoint", "/login/upstream/callback").Str("error", e).Str("error_description", q.Get("error_description")).Msg("upstream login failed")
+		http.Error(w, "upstream login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), oauth2.HTTPClient, p.httpClient)
+	tok, err := p.oauth.Exchange(ctx, q.Get("code"), oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		log.Error().Err(err).Str("endpoint", "/login/upstream/callback").Msg("upstream code exchange failed")
+		http.Error(w, "upstream login failed", http.StatusBadGateway)
+		return
+	}
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	claims, err := p.verifyIDToken(ctx, rawIDToken, pending.nonce)
+	if err != nil {
+		log.Warn().Err(err).Str("endpoint", "/login/upstream/callback").Msg("invalid upstream ID token")
+		http.Error(w, "upstream login failed", http.StatusUnauthorized)
+		return
+	}
+	if err := p.mergeUserinfo(ctx, tok, claims); err != nil {
+		log.Warn().Err(err).Str("endpoint", "/login/upstream/callback").Msg("upstream userinfo failed")
+	}
+
+	subject, err := p.mapSubject(claims)
+	if err != nil {
+		log.Warn().Err(err).Str("endpoint", "/login/upstream/callback").Interface("upstream_sub", claims["sub"]).Msg("login refused")
+		http.Error(w, "access denied: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	// Disabling a local user named like the subject blocks the SSO login
+	if disabled, err := s.userDisabled(subject); err != nil {
+		log.Error().Err(err).Str("endpoint", "/login/upstream/callback").Msg("user lookup error")
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	} else if disabled {
+		log.Warn().Str("endpoint", "/login/upstream/callback").Str("subject", subject).Msg("login refused, user disabled")
+		http.Error(w, "access denied: user disabled", http.StatusForbidden)
+		return
+	}
+
+	sid, err := s.createSessionWithProvider(subject, s.SessionTTL, "upstream:"+p.cfg.Issuer, rawIDToken, tok.RefreshToken)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint", "/login/upstream/callback").Str("subject", subject).Msg("session create error")
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Value: sid, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	log.Info().Str("endpoint", "/login/upstream/callback").Str("subject", subject).Interface("upstream_sub", claims["sub"]).Str("return_to", pending.returnTo).Msg("login success (upstream), redirecting")
+	http.Redirect(w, r, pending.returnTo, http.StatusFound)
+}
+
+func (p *upstreamProvider) addPending(state string, login pendingLogin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	oldest := ""
+	for k, v := range p.pending {
+		if now.After(v.expiresAt) {
+			delete(p.pending, k)
+			continue
+		}
+		if oldest == "" || v.expiresAt.Before(p.pending[oldest].expiresAt) {
+			oldest = k
+		}
+	}
+	if len(p.pending) >= maxPendingLogins {
+		delete(p.pending, oldest)
+	}
+	p.pending[state] = login
+}
+
+func (p *upstreamProvider) takePending(state string) (pendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	if !ok || time.Now().After(login.expiresAt) {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+func (p *upstreamProvider) refreshKeys(ctx context.Context) error {
+	var keys jose.JSONWebKeySet
+	if err := getJSON(ctx, p.httpClient, p.jwksURI, &keys); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *upstreamProvider) key(kid string) (jose.JSONWebKey, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys.Keys {
+		if kid == "" || k.KeyID == kid {
+			return k, true
+		}
+	}
+	return jose.JSONWebKey{}, false
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an upstream ID token and returns its claims
+func (p *upstreamProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]any, error) {
+	if raw == "" {
+		return nil, errors.New("no id_token in token response")
+	}
+	tok, err := josejwt.ParseSigned(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(tok.Headers) != 1 || tok.Headers[0].Algorithm != string(jose.RS256) {
+		return nil, errors.New("unsupported ID token signature")
+	}
+	kid := tok.Headers[0].KeyID
+	key, ok := p.key(kid)
+	if !ok {
+		// The provider may have rotated its keys
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok = p.key(kid); !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	var std josejwt.Claims
+	claims := map[string]any{}
+	if err := tok.Claims(key.Key, &std, &claims); err != nil {
+		return nil, err
+	}
+	if err := std.ValidateWithLeeway(josejwt.Expected{
+		Issuer:   p.cfg.Issuer,
+		Audience: josejwt.Audience{p.cfg.ClientID},
+		Time:     time.Now(),
+	}, time.Minute); err != nil {
+		return nil, err
+	}
+	if std.Expiry == nil {
+		return nil, errors.New("ID token has no expiry")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return claims, nil
+}
+
+// mergeUserinfo adds userinfo claims missing from the ID token, some
+// providers only return groups or email there
+func (p *upstreamProvider) mergeUserinfo(ctx context.Context, tok *oauth2.Token, claims map[string]any) error {
+	_, hasSubject := claims[p.cfg.SubjectClaim]
+	_, hasGroups := claims[p.cfg.GroupsClaim]
+	if p.userinfoURI == "" || (hasSubject && (hasGroups || len(p.cfg.AllowedGroups) == 0)) {
+		return nil
+	}
+	userinfo := map[string]any{}
+	if err := getJSON(ctx, p.oauth.Client(ctx, tok), p.userinfoURI, &userinfo); err != nil {
+		return err
+	}
+	if userinfo["sub"] != claims["sub"] {
+		return errors.New("userinfo subject does not match the ID token")
+	}
+	for k, v := range userinfo {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	return nil
+}
+
+// mapSubject returns the local subject "<prefix>:<claim>" for upstream
+// claims, if the user is in one of the allowed groups
+func (p *upstreamProvider) mapSubject(claims map[string]any) (string, error) {
+	name, _ := claims[p.cfg.SubjectClaim].(string)
+	if name == "" {
+		return "", fmt.Errorf("claim %q missing", p.cfg.SubjectClaim)
+	}
+	subject := p.cfg.SubjectPrefix + ":" + name
+	if len(p.cfg.AllowedGroups) == 0 {
+		return subject, nil
+	}
+	groups := claimStrings(claims[p.cfg.GroupsClaim])
+	for _, allowed := range p.cfg.AllowedGroups {
+		for _, g := range groups {
+			if g == allowed {
+				return subject, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s is not in an allowed group", name)
+}
+
+// claimStrings reads a list claim, sent as a JSON array or a space or comma
+// separated string depending on the provider
+func claimStrings(v any) []string {
+	switch t := v.(type) {
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		return strings.FieldsFunc(t, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+	return nil
+}
+
+// localReturnTo only allows redirects within this server after login.
+// Browsers treat a backslash like a slash and drop tabs and newlines, so
+// "/\evil.com", or "/" followed by a tab and "/evil.com", would leave the
+// server just like "//evil.com".
+func localReturnTo(rt string) string {
+	if !strings.HasPrefix(rt, "/") || strings.HasPrefix(rt, "//") {
+		return "/"
+	}
+	if strings.ContainsFunc(rt, func(r rune) bool { return r == '\\' || unicode.IsControl(r) }) {
+		return "/"
+	}
+	u, err := url.Parse(rt)
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return "/"
+	}
+	return rt
+}
+
+func getJSON(ctx context.Context, client *http.Client, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,498 @@
+package idsrv
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/mcp-oidc-server/pkg/mockidp"
+	"github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"golang.org/x/oauth2"
+)
+
+func TestLocalReturnTo(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/mcp", "/mcp"},
+		{"/oauth2/auth?client_id=x&redirect_uri=http%3A%2F%2Flocalhost", "/oauth2/auth?client_id=x&redirect_uri=http%3A%2F%2Flocalhost"},
+		{"/path/with//double", "/path/with//double"},
+		{"mcp", "/"},
+		{"https://evil.com", "/"},
+		{"//evil.com", "/"},
+		{"///evil.com", "/"},
+		{`/\evil.com`, "/"},
+		{`\\evil.com`, "/"},
+		{`/\/evil.com`, "/"},
+		{`/path\..\evil`, "/"},
+		{"/\t/evil.com", "/"},
+		{"/\n/evil.com", "/"},
+		{"javascript:alert(1)", "/"},
+	}
+	for _, tt := range tests {
+		if got := localReturnTo(tt.in); got != tt.want {
+			t.Errorf("localReturnTo(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAddPendingBoundsLogins(t *testing.T) {
+	p := &upstreamProvider{pending: map[string]pendingLogin{}}
+	now := time.Now()
+
+	p.addPending("expired", pendingLogin{expiresAt: now.Add(-time.Second)})
+	for i := 0; i < maxPendingLogins; i++ {
+		p.addPending(fmt.Sprintf("state-%d", i), pendingLogin{expiresAt: now.Add(time.Minute + time.Duration(i)*time.Millisecond)})
+	}
+	if _, ok := p.pending["expired"]; ok {
+		t.Error("expected the expired login to be dropped")
+	}
+	if len(p.pending) != maxPendingLogins {
+		t.Fatalf("expected %d pending logins, got %d", maxPendingLogins, len(p.pending))
+	}
+
+	p.addPending("newest", pendingLogin{expiresAt: now.Add(pendingLoginTTL)})
+	if len(p.pending) != maxPendingLogins {
+		t.Errorf("expected the map to stay at %d logins, got %d", maxPendingLogins, len(p.pending))
+	}
+	if _, ok := p.pending["state-0"]; ok {
+		t.Error("expected the oldest login to be dropped")
+	}
+	if _, ok := p.takePending("newest"); !ok {
+		t.Error("expected the newest login to be kept")
+	}
+}
+
+const (
+	mockClientID     = "mcp-oidc-server"
+	mockClientSecret = "mock-secret"
+)
+
+var noRedirects = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// newMockIdP serves a mock upstream provider with users given as
+// "name[:group1,group2]"
+func newMockIdP(t *testing.T, specs ...string) *httptest.Server {
+	t.Helper()
+	var users []mockidp.User
+	for _, spec := range specs {
+		u, err := mockidp.ParseUser(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	mux := http.NewServeMux()
+	idpTS := httptest.NewServer(mux)
+	t.Cleanup(idpTS.Close)
+	idp, err := mockidp.New(idpTS.URL, mockClientID, mockClientSecret, users)
+	if err != nil {
+		t.Fatalf("mockidp.New: %v", err)
+	}
+	idp.Routes(mux)
+	return idpTS
+}
+
+// upstreamLogin logs user in at the mock IdP through /login/upstream and
+// returns the callback response. tamper can change the provider before the
+// callback, state is the pending login.
+func upstreamLogin(t *testing.T, s *Server, ts, idpTS *httptest.Server, user string, tamper func(p *upstreamProvider, state string)) (*http.Response, string) {
+	t.Helper()
+	resp, err := noRedirects.Get(ts.URL + "/login/upstream?return_to=" + url.QueryEscape("/oauth2/auth?client_id=dev-client"))
+	if err != nil {
+		t.Fatalf("login request: %v", err)
+	}
+	resp.Body.Close()
+	authURL, err := resp.Location()
+	if err != nil || !strings.HasPrefix(authURL.String(), idpTS.URL+"/authorize?") {
+		t.Fatalf("login redirect: %v, %v", authURL, err)
+	}
+	if tamper != nil {
+		tamper(s.upstream, authURL.Query().Get("state"))
+	}
+
+	// Pick the user at the mock IdP
+	form := authURL.Query()
+	form.Set("user", user)
+	resp, err = noRedirects.PostForm(idpTS.URL+"/authorize", form)
+	if err != nil {
+		t.Fatalf("mock IdP authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := resp.Location()
+	if err != nil || callback.Path != "/login/upstream/callback" {
+		t.Fatalf("mock IdP redirect: %v, %v", callback, err)
+	}
+
+	// The callback URL is on the issuer, send it to the test server
+	resp, err = noRedirects.Get(ts.URL + callback.Path + "?" + callback.RawQuery)
+	if err != nil {
+		t.Fatalf("callback request: %v", err)
+	}
+	resp.Body.Close()
+	return resp, callback.RawQuery
+}
+
+func TestUpstreamLogin(t *testing.T) {
+	idpTS := newMockIdP(t, "admin:engineering", "bob:sales,staff")
+
+	tests := []struct {
+		name        string
+		user        string
+		tamper      func(p *upstreamProvider, state string)
+		wantStatus  int
+		wantSubject string
+	}{
+		{
+			// The upstream admin is not the local admin
+			name:        "allowed group",
+			user:        "admin",
+			wantStatus:  http.StatusFound,
+			wantSubject: "sso:admin",
+		},
+		{name: "not in an allowed group", user: "bob", wantStatus: http.StatusForbidden},
+		{name: "unknown user", user: "carol", wantStatus: http.StatusUnauthorized},
+		{
+			name:       "bad issuer",
+			user:       "admin",
+			tamper:     func(p *upstreamProvider, _ string) { p.cfg.Issuer = "http://other-idp.localhost" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			// Only the expected audience changes, the client still authenticates
+			name:       "bad audience",
+			user:       "admin",
+			tamper:     func(p *upstreamProvider, _ string) { p.cfg.ClientID = "other-client" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "nonce mismatch",
+			user: "admin",
+			tamper: func(p *upstreamProvider, state string) {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				login := p.pending[state]
+				login.nonce = "other-nonce"
+				p.pending[state] = login
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired login",
+			user: "admin",
+			tamper: func(p *upstreamProvider, state string) {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				login := p.pending[state]
+				login.expiresAt = time.Now().Add(-time.Second)
+				p.pending[state] = login
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ts := newTestServer(t)
+			if err := s.CreateUser("admin", "admin@localhost", "password123"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			err := s.EnableUpstream(context.Background(), UpstreamConfig{
+				Issuer:        idpTS.URL,
+				ClientID:      mockClientID,
+				ClientSecret:  mockClientSecret,
+				SubjectClaim:  "preferred_username",
+				AllowedGroups: []string{"engineering"},
+			})
+			if err != nil {
+				t.Fatalf("EnableUpstream: %v", err)
+			}
+
+			resp, query := upstreamLogin(t, s, ts, idpTS, tt.user, tt.tamper)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			sessions, err := s.ListSessions("")
+			if err != nil {
+				t.Fatalf("ListSessions: %v", err)
+			}
+			if tt.wantSubject == "" {
+				if len(sessions) != 0 {
+					t.Errorf("got sessions %+v after a refused login", sessions)
+				}
+				return
+			}
+
+			if len(sessions) != 1 || sessions[0].Subject != tt.wantSubject || sessions[0].Provider != "upstream:"+idpTS.URL {
+				t.Fatalf("got sessions %+v, want one of %s", sessions, tt.wantSubject)
+			}
+			if location := resp.Header.Get("Location"); location != "/oauth2/auth?client_id=dev-client" {
+				t.Errorf("got redirect to %q", location)
+			}
+			var sid string
+			for _, c := range resp.Cookies() {
+				if c.Name == cookieName {
+					sid = c.Value
+				}
+			}
+			if sid != sessions[0].SessionID {
+				t.Errorf("got session cookie %q, want %q", sid, sessions[0].SessionID)
+			}
+
+			// The state can't be replayed
+			replay, err := noRedirects.Get(ts.URL + "/login/upstream/callback?" + query)
+			if err != nil {
+				t.Fatalf("replay request: %v", err)
+			}
+			replay.Body.Close()
+			if replay.StatusCode != http.StatusBadRequest {
+				t.Errorf("replayed callback: got status %d, want 400", replay.StatusCode)
+			}
+		})
+	}
+}
+
+func TestUpstreamLoginDisabledUser(t *testing.T) {
+	idpTS := newMockIdP(t, "alice")
+	s, ts := newTestServer(t)
+	if err := s.EnableUpstream(context.Background(), UpstreamConfig{Issuer: idpTS.URL, ClientID: mockClientID, ClientSecret: mockClientSecret}); err != nil {
+		t.Fatalf("EnableUpstream: %v", err)
+	}
+	// The default subject claim is sub, "mock|alice" at the mock IdP
+	if err := s.CreateUser("sso:mock|alice", "", "password123"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := s.DisableUser("sso:mock|alice"); err != nil {
+		t.Fatalf("DisableUser: %v", err)
+	}
+	if resp, _ := upstreamLogin(t, s, ts, idpTS, "alice", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+		{Key: &rotatedKey.PublicKey, KeyID: "k2", Algorithm: "RS256", Use: "sig"},
+	}}
+	jwksTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { writeJSON(w, published) }))
+	defer jwksTS.Close()
+
+	sign := func(alg jose.SignatureAlgorithm, signingKey any, kid string, claims map[string]any) string {
+		t.Helper()
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: &jose.JSONWebKey{Key: signingKey, KeyID: kid}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	now := time.Now()
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{"iss": "http://idp.localhost", "aud": "client", "sub": "alice", "nonce": "n", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "valid", raw: sign(jose.RS256, key, "k1", claims(nil))},
+		// Unknown kids trigger a JWKS refresh
+		{name: "rotated upstream key", raw: sign(jose.RS256, rotatedKey, "k2", claims(nil))},
+		{name: "within leeway", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))},
+		{name: "expired", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()})), wantErr: "expired"},
+		{name: "no expiry", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"exp": nil})), wantErr: "no expiry"},
+		{name: "bad issuer", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"iss": "http://other.localhost"})), wantErr: "issuer"},
+		{name: "bad audience", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"aud": "other-client"})), wantErr: "audience"},
+		{name: "nonce mismatch", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"nonce": "other"})), wantErr: "nonce mismatch"},
+		{name: "no nonce", raw: sign(jose.RS256, key, "k1", claims(map[string]any{"nonce": nil})), wantErr: "nonce mismatch"},
+		{name: "wrong key", raw: sign(jose.RS256, rotatedKey, "k1", claims(nil)), wantErr: "cryptographic primitive"},
+		{name: "unknown key", raw: sign(jose.RS256, key, "k3", claims(nil)), wantErr: `unknown signing key "k3"`},
+		{name: "HMAC", raw: sign(jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "k1", claims(nil)), wantErr: "unsupported ID token signature"},
+		{name: "missing", raw: "", wantErr: "no id_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &upstreamProvider{
+				cfg:        UpstreamConfig{Issuer: "http://idp.localhost", ClientID: "client"},
+				jwksURI:    jwksTS.URL,
+				httpClient: jwksTS.Client(),
+				keys:       jose.JSONWebKeySet{Keys: published.Keys[:1]},
+			}
+			got, err := p.verifyIDToken(context.Background(), tt.raw, "n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got["sub"] != "alice" {
+				t.Errorf("got claims %v, %v", got, err)
+			}
+		})
+	}
+}
+
+// mockIdPToken runs the code flow at the mock IdP for user and returns the
+// access token
+func mockIdPToken(t *testing.T, idpTS *httptest.Server, user string) *oauth2.Token {
+	t.Helper()
+	redirectURI := "http://client.localhost/callback"
+	resp, err := noRedirects.PostForm(idpTS.URL+"/authorize", url.Values{
+		"client_id": {mockClientID}, "redirect_uri": {redirectURI}, "response_type": {"code"}, "user": {user},
+	})
+	if err != nil {
+		t.Fatalf("mock IdP authorize: %v", err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("mock IdP redirect: %v", err)
+	}
+	cfg := oauth2.Config{
+		ClientID:     mockClientID,
+		ClientSecret: mockClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: idpTS.URL + "/token"},
+		RedirectURL:  redirectURI,
+	}
+	tok, err := cfg.Exchange(context.Background(), location.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("mock IdP token: %v", err)
+	}
+	return tok
+}
+
+func TestMergeUserinfo(t *testing.T) {
+	idpTS := newMockIdP(t, "alice:engineering,staff")
+	tok := mockIdPToken(t, idpTS, "alice")
+	invalid := &oauth2.Token{AccessToken: "invalid", TokenType: "Bearer"}
+
+	tests := []struct {
+		name    string
+		cfg     UpstreamConfig
+		tok     *oauth2.Token
+		claims  map[string]any
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			// Userinfo only adds missing claims
+			name:   "groups from userinfo",
+			cfg:    UpstreamConfig{SubjectClaim: "email", GroupsClaim: "groups", AllowedGroups: []string{"engineering"}},
+			tok:    tok,
+			claims: map[string]any{"sub": "mock|alice", "email": "id-token@example.com"},
+			want: map[string]any{
+				"sub": "mock|alice", "email": "id-token@example.com", "preferred_username": "alice",
+				"email_verified": true, "groups": []any{"engineering", "staff"},
+			},
+		},
+		{
+			name:   "subject from userinfo",
+			cfg:    UpstreamConfig{SubjectClaim: "preferred_username", GroupsClaim: "groups"},
+			tok:    tok,
+			claims: map[string]any{"sub": "mock|alice"},
+			want: map[string]any{
+				"sub": "mock|alice", "email": "alice@example.com", "preferred_username": "alice",
+				"email_verified": true, "groups": []any{"engineering", "staff"},
+			},
+		},
+		{
+			// Without allowed groups, the groups aren't needed
+			name:   "complete ID token",
+			cfg:    UpstreamConfig{SubjectClaim: "sub", GroupsClaim: "groups"},
+			tok:    invalid,
+			claims: map[string]any{"sub": "mock|alice"},
+			want:   map[string]any{"sub": "mock|alice"},
+		},
+		{
+			name:    "subject mismatch",
+			cfg:     UpstreamConfig{SubjectClaim: "email", GroupsClaim: "groups"},
+			tok:     tok,
+			claims:  map[string]any{"sub": "mock|bob"},
+			wantErr: "userinfo subject does not match",
+		},
+		{
+			name:    "invalid access token",
+			cfg:     UpstreamConfig{SubjectClaim: "email", GroupsClaim: "groups"},
+			tok:     invalid,
+			claims:  map[string]any{"sub": "mock|alice"},
+			wantErr: "401",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &upstreamProvider{cfg: tt.cfg, userinfoURI: idpTS.URL + "/userinfo"}
+			err := p.mergeUserinfo(context.Background(), tt.tok, tt.claims)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeUserinfo: %v", err)
+			}
+			if !reflect.DeepEqual(tt.claims, tt.want) {
+				t.Errorf("got claims %v, want %v", tt.claims, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapSubject(t *testing.T) {
+	p := &upstreamProvider{cfg: UpstreamConfig{SubjectPrefix: "sso", SubjectClaim: "preferred_username", GroupsClaim: "groups", AllowedGroups: []string{"engineering"}}}
+	tests := []struct {
+		claims  map[string]any
+		want    string
+		wantErr string
+	}{
+		{claims: map[string]any{"preferred_username": "admin", "groups": []any{"staff", "engineering"}}, want: "sso:admin"},
+		{claims: map[string]any{"preferred_username": "admin", "groups": "staff,engineering"}, want: "sso:admin"},
+		{claims: map[string]any{"preferred_username": "admin", "groups": []any{"staff"}}, wantErr: "admin is not in an allowed group"},
+		{claims: map[string]any{"preferred_username": "admin"}, wantErr: "not in an allowed group"},
+		{claims: map[string]any{"sub": "admin", "groups": []any{"engineering"}}, wantErr: `claim "preferred_username" missing`},
+	}
+	for _, tt := range tests {
+		got, err := p.mapSubject(tt.claims)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("mapSubject(%v) = %q, %v, want error %q", tt.claims, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("mapSubject(%v) = %q, %v, want %q", tt.claims, got, err, tt.want)
+		}
+	}
+}
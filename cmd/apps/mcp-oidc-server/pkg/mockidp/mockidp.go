@@ -0,0 +1,351 @@
+// Package mockidp is a minimal OIDC provider for testing upstream login
+// locally. Users pick an identity from a list, there are no passwords.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed static/*
+var staticFiles embed.FS
+
+var authorizeTemplate = template.Must(template.New("authorize.html").
+	Funcs(template.FuncMap{"join": strings.Join}).
+	ParseFS(staticFiles, "static/authorize.html"))
+
+const (
+	codeTTL  = 2 * time.Minute
+	tokenTTL = time.Hour
+)
+
+// User is an identity offered by the mock IdP
+type User struct {
+	Name   string
+	Email  string
+	Groups []string
+}
+
+// Subject is the stable sub claim, deliberately different from the username
+// so subject claim mapping can be tested
+func (u User) Subject() string {
+	return "mock|" + u.Name
+}
+
+// ParseUser parses "name[:group1,group2]"
+func ParseUser(spec string) (User, error) {
+	name, groups, _ := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return User{}, fmt.Errorf("invalid user %q, expected name[:group1,group2]", spec)
+	}
+	u := User{Name: name, Email: name + "@example.com"}
+	for _, g := range strings.Split(groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			u.Groups = append(u.Groups, g)
+		}
+	}
+	return u, nil
+}
+
+// IdP serves discovery, JWKS, authorize, token and userinfo endpoints
+type IdP struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	kid   string
+	users []User
+
+	mu     sync.Mutex
+	codes  map[string]authCode
+	tokens map[string]issuedToken
+}
+
+type authCode struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+type issuedToken struct {
+	user      User
+	expiresAt time.Time
+}
+
+// New creates a mock IdP with a fresh signing key
+func New(issuer, clientID, clientSecret string, users []User) (*IdP, error) {
+	if len(users) == 0 {
+		return nil, fmt.Errorf("mock IdP needs at least one user")
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &IdP{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		kid:          "mock-" + randomID()[:8],
+		users:        users,
+		codes:        map[string]authCode{},
+		tokens:       map[string]issuedToken{},
+	}, nil
+}
+
+// Routes registers the provider endpoints
+func (p *IdP) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks.json", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+}
+
+func (p *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"userinfo_endpoint":                     p.Issuer + "/userinfo",
+		"jwks_uri":                              p.Issuer + "/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"claims_supported":                      []string{"sub", "email", "preferred_username", "groups"},
+	})
+}
+
+func (p *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &p.key.PublicKey, KeyID: p.kid, Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+// authorize shows the user picker on GET and issues a code on POST
+func (p *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	q := r.Form
+	if q.Get("client_id") != p.ClientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	if _, err := url.ParseRequestURI(redirectURI); err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" {
+		redirectWith(w, r, redirectURI, url.Values{"error": {"unsupported_response_type"}, "state": {q.Get("state")}})
+		return
+	}
+	if q.Get("code_challenge") != "" && q.Get("code_challenge_method") != "S256" {
+		redirectWith(w, r, redirectURI, url.Values{"error": {"invalid_request"}, "error_description": {"only S256 is supported"}, "state": {q.Get("state")}})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		params := map[string]string{}
+		for _, name := range []string{"client_id", "redirect_uri", "response_type", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			if v := q.Get(name); v != "" {
+				params[name] = v
+			}
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = authorizeTemplate.Execute(w, struct {
+			Params map[string]string
+			Users  []User
+		}{params, p.users})
+		return
+	}
+
+	user, ok := p.findUser(q.Get("user"))
+	if !ok {
+		redirectWith(w, r, redirectURI, url.Values{"error": {"access_denied"}, "state": {q.Get("state")}})
+		return
+	}
+	code := randomID()
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		user:          user,
+		redirectURI:   redirectURI,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+	log.Info().Str("component", "mockidp").Str("user", user.Name).Strs("groups", user.Groups).Msg("issued authorization code")
+	redirectWith(w, r, redirectURI, url.Values{"code": {code}, "state": {q.Get("state")}})
+}
+
+// token exchanges an authorization code for an ID token and access token
+func (p *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		// client_secret_basic values are form-encoded
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if code.codeChallenge != "" {
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+			tokenError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+	}
+
+	now := time.Now()
+	idToken, err := p.signIDToken(code.user, code.nonce, now)
+	if err != nil {
+		log.Error().Err(err).Str("component", "mockidp").Msg("signing ID token failed")
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	accessToken := randomID()
+	p.mu.Lock()
+	p.tokens[accessToken] = issuedToken{user: code.user, expiresAt: now.Add(tokenTTL)}
+	p.mu.Unlock()
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (p *IdP) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	p.mu.Lock()
+	tok, found := p.tokens[accessToken]
+	p.mu.Unlock()
+	if !ok || !found || time.Now().After(tok.expiresAt) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, userClaims(tok.user))
+}
+
+func (p *IdP) signIDToken(user User, nonce string, now time.Time) (string, error) {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: &jose.JSONWebKey{Key: p.key, KeyID: p.kid}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+	claims := userClaims(user)
+	claims["iss"] = p.Issuer
+	claims["aud"] = p.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(tokenTTL).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return josejwt.Signed(signer).Claims(claims).CompactSerialize()
+}
+
+func (p *IdP) findUser(name string) (User, bool) {
+	for _, u := range p.users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+func userClaims(u User) map[string]any {
+	groups := u.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+	return map[string]any{
+		"sub":                u.Subject(),
+		"preferred_username": u.Name,
+		"email":              u.Email,
+		"email_verified":     true,
+		"groups":             groups,
+	}
+}
+
+func redirectWith(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q[k] = v
+		}
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
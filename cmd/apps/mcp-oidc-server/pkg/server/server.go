@@ -341,12 +341,26 @@ func (s *Server) ConfigureAuth(localUsers bool, sessionTTL time.Duration, devTok
 	s.devTokenFallbackEnabled = devTokenFallbackEnabled
 }
 
+// EnableUpstream delegates login to an upstream OIDC provider; requires EnableSQLite.
+func (s *Server) EnableUpstream(ctx context.Context, cfg idsrv.UpstreamConfig) error {
+	return s.ids.EnableUpstream(ctx, cfg)
+}
+
 // User management proxies (Model C)
 func (s *Server) CreateUser(username, email, password string) error { return s.ids.CreateUser(username, email, password) }
 func (s *Server) DisableUser(username string) error { return s.ids.DisableUser(username) }
 func (s *Server) SetPassword(username, password string) error { return s.ids.SetPassword(username, password) }
 func (s *Server) ListUsers() ([]idsrv.User, error) { return s.ids.ListUsers() }
 
+// Admin: clients, sessions and signing keys
+func (s *Server) ListClients() ([]idsrv.Client, error) { return s.ids.ListClients() }
+func (s *Server) SetClientDisabled(clientID string, disabled bool) error { return s.ids.SetClientDisabled(clientID, disabled) }
+func (s *Server) ListSessions(subject string) ([]idsrv.Session, error) { return s.ids.ListSessions(subject) }
+func (s *Server) ListGrants(subject string) ([]idsrv.Grant, error) { return s.ids.ListGrants(subject) }
+func (s *Server) RevokeSubject(subject string) (idsrv.RevocationSummary, error) { return s.ids.RevokeSubject(subject) }
+func (s *Server) ListSigningKeys() ([]idsrv.SigningKey, error) { return s.ids.ListSigningKeys() }
+func (s *Server) RotateSigningKey(overlap time.Duration) (idsrv.SigningKey, error) { return s.ids.RotateSigningKey(overlap) }
+
 func writeJSONWithPreview(w http.ResponseWriter, r *http.Request, endpoint string, v any) {
 	b, _ := json.Marshal(v)
 	preview := string(b)
@@ -459,6 +473,12 @@ func (s *Server) mcpAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		_ = tt
+		// Tokens of revoked grants and disabled clients are still valid for fosite
+		if err := s.ids.CheckAccess(ar); err != nil {
+			log.Warn().Str("endpoint", "mcp").Str("reason", "access revoked").Err(err).Msg("unauthorized")
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
 		// Trace subject and client_id
 		subject := ""
 		if sess.Claims != nil { subject = sess.Claims.Subject }
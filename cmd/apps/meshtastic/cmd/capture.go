@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/capture"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/client"
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/protocol"
+)
+
+var (
+	captureRaw          bool
+	replaySpeed         float64
+	replayNoWait        bool
+	replayWriteTimeout  time.Duration
+	replayCorruptRate   float64
+	replayTruncateRate  float64
+	replayNoiseRate     float64
+	replaySplitRate     float64
+	replaySeed          int64
+	replayConfigTimeout time.Duration
+)
+
+// captureCmd represents the capture command
+var captureCmd = &cobra.Command{
+	Use:   "capture",
+	Short: "Inspect and replay recorded device sessions",
+	Long: `Sessions are recorded with the global --capture flag, which works with any
+command that talks to a device:
+
+  meshtastic --capture session.jsonl listen
+
+A capture holds the raw bytes read from and written to the device with
+timestamps. It can be inspected and replayed against the client, optionally
+with corrupted, truncated or split frames.`,
+}
+
+// captureInspectCmd represents the capture inspect command
+var captureInspectCmd = &cobra.Command{
+	Use:   "inspect FILE",
+	Short: "Show the frames in a capture",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCaptureInspect,
+}
+
+// captureReplayCmd represents the capture replay command
+var captureReplayCmd = &cobra.Command{
+	Use:   "replay FILE",
+	Short: "Replay a capture against the client",
+	Long: `Feed a capture to a client as if it came from the device and print what the
+client makes of it.
+
+Examples:
+  meshtastic capture replay session.jsonl
+  meshtastic capture replay session.jsonl --speed 0
+  meshtastic capture replay session.jsonl --corrupt-rate 0.1 --split-rate 0.5 --seed 7`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCaptureReplay,
+}
+
+func runCaptureInspect(cmd *cobra.Command, args []string) error {
+	c, err := capture.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	rxBytes, txBytes := len(c.Bytes(capture.DirectionRx)), len(c.Bytes(capture.DirectionTx))
+	fmt.Printf("Device:   %s\n", c.Header.Device)
+	fmt.Printf("Started:  %s\n", c.Header.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Duration: %s\n", c.Duration().Round(time.Millisecond))
+	fmt.Printf("Records:  %d (%d bytes received, %d bytes sent)\n\n", len(c.Records), rxBytes, txBytes)
+
+	var offset time.Duration
+	rx := protocol.NewFrameParser(func(frame *protocol.Frame) {
+		fromRadio, err := frame.FromRadioMessage()
+		if err != nil {
+			fmt.Printf("%10s  rx  invalid frame (%d bytes): %v\n", formatOffset(offset), frame.PayloadLength(), err)
+			return
+		}
+		fmt.Printf("%10s  rx  %s\n", formatOffset(offset), describeFromRadio(fromRadio))
+	}, nil)
+	tx := protocol.NewFrameParser(func(frame *protocol.Frame) {
+		toRadio, err := frame.ToRadioMessage()
+		if err != nil {
+			fmt.Printf("%10s  tx  invalid frame (%d bytes): %v\n", formatOffset(offset), frame.PayloadLength(), err)
+			return
+		}
+		fmt.Printf("%10s  tx  %s\n", formatOffset(offset), describeToRadio(toRadio))
+	}, nil)
+
+	for _, r := range c.Records {
+		offset = r.Offset
+		if captureRaw {
+			fmt.Printf("%10s  %s  %d bytes % x\n", formatOffset(offset), r.Direction, len(r.Data), r.Data)
+			continue
+		}
+		if r.Direction == capture.DirectionRx {
+			rx.ProcessBytes(r.Data)
+		} else {
+			tx.ProcessBytes(r.Data)
+		}
+	}
+
+	return nil
+}
+
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("+%.3fs", d.Seconds())
+}
+
+func describePacket(packet *pb.MeshPacket) string {
+	decoded := packet.GetDecoded()
+	if decoded == nil {
+		return fmt.Sprintf("packet !%08x -> !%08x encrypted", packet.GetFrom(), packet.GetTo())
+	}
+	desc := fmt.Sprintf("packet !%08x -> !%08x %s", packet.GetFrom(), packet.GetTo(), decoded.GetPortnum())
+	if decoded.GetPortnum() == pb.PortNum_TEXT_MESSAGE_APP {
+		desc += fmt.Sprintf(" %q", decoded.GetPayload())
+	}
+	return desc
+}
+
+func describeFromRadio(m *pb.FromRadio) string {
+	switch payload := m.GetPayloadVariant().(type) {
+	case *pb.FromRadio_Packet:
+		return describePacket(payload.Packet)
+	case *pb.FromRadio_MyInfo:
+		return fmt.Sprintf("my_info !%08x", payload.MyInfo.GetMyNodeNum())
+	case *pb.FromRadio_NodeInfo:
+		return fmt.Sprintf("node_info !%08x %s", payload.NodeInfo.GetNum(), payload.NodeInfo.GetUser().GetLongName())
+	case *pb.FromRadio_Config:
+		return "config " + oneofName(payload.Config)
+	case *pb.FromRadio_ModuleConfig:
+		return "module_config " + oneofName(payload.ModuleConfig)
+	case *pb.FromRadio_Channel:
+		return fmt.Sprintf("channel %d %s", payload.Channel.GetIndex(), payload.Channel.GetRole())
+	case *pb.FromRadio_ConfigCompleteId:
+		return fmt.Sprintf("config_complete_id %d", payload.ConfigCompleteId)
+	case *pb.FromRadio_Rebooted:
+		return "rebooted"
+	default:
+		return fmt.Sprintf("%T", payload)
+	}
+}
+
+// oneofName returns the name of the field set in a message's payload oneof
+func oneofName(m proto.Message) string {
+	r := m.ProtoReflect()
+	oneofs := r.Descriptor().Oneofs()
+	if oneofs.Len() == 0 {
+		return ""
+	}
+	if field := r.WhichOneof(oneofs.Get(0)); field != nil {
+		return string(field.Name())
+	}
+	return ""
+}
+
+func describeToRadio(m *pb.ToRadio) string {
+	switch payload := m.GetPayloadVariant().(type) {
+	case *pb.ToRadio_Packet:
+		return describePacket(payload.Packet)
+	case *pb.ToRadio_WantConfigId:
+		return fmt.Sprintf("want_config_id %d", payload.WantConfigId)
+	default:
+		return fmt.Sprintf("%T", payload)
+	}
+}
+
+func runCaptureReplay(cmd *cobra.Command, args []string) error {
+	c, err := capture.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	opts := capture.DefaultReplayOptions()
+	opts.Speed = replaySpeed
+	opts.WaitForWrites = !replayNoWait
+	opts.WriteTimeout = replayWriteTimeout
+	opts.CorruptRate = replayCorruptRate
+	opts.TruncateRate = replayTruncateRate
+	opts.NoiseRate = replayNoiseRate
+	opts.SplitRate = replaySplitRate
+	opts.Seed = replaySeed
+
+	replay := capture.NewReplay(c, opts)
+	sc := client.NewStreamClient(replay, "replay://"+args[0])
+
+	var messages, positions, telemetry int
+	sc.SetOnMessage(func(packet *pb.MeshPacket) {
+		messages++
+		fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05.000"), describePacket(packet))
+	})
+	sc.SetOnPosition(func(*pb.Position) { positions++ })
+	sc.SetOnTelemetry(func(*pb.Telemetry) { telemetry++ })
+
+	connected := make(chan error, 1)
+	go func() {
+		connected <- sc.Connect(context.Background())
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-connected:
+		if err != nil {
+			fmt.Printf("Connect failed: %v\n", err)
+		} else {
+			fmt.Printf("Connected: node !%08x, %d nodes, %d channels\n",
+				sc.GetMyInfo().GetMyNodeNum(), len(sc.GetNodes()), len(sc.GetChannels()))
+			select {
+			case <-replay.Done():
+				// Let the client process the last frames
+				time.Sleep(100 * time.Millisecond)
+			case <-sigChan:
+			}
+		}
+	case <-time.After(replayConfigTimeout):
+		fmt.Println("Timed out waiting for configuration")
+	case <-sigChan:
+	}
+
+	// Closing the replay unblocks the client's reader
+	replay.Close()
+	if err := sc.Disconnect(); err != nil {
+		log.Error().Err(err).Msg("Error disconnecting")
+	}
+
+	fmt.Printf("\n%d packets, %d positions, %d telemetry reports\n", messages, positions, telemetry)
+	if faults := replay.Faults(); len(faults) > 0 {
+		fmt.Printf("Injected %d faults:\n", len(faults))
+		for _, f := range faults {
+			fmt.Printf("  record %d: %s at byte %d\n", f.Record, f.Kind, f.Offset)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	captureInspectCmd.Flags().BoolVar(&captureRaw, "raw", false, "Show raw records instead of decoded frames")
+
+	captureReplayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "Playback speed (0 replays without delays)")
+	captureReplayCmd.Flags().BoolVar(&replayNoWait, "no-wait", false, "Don't hold device data back until the client writes")
+	captureReplayCmd.Flags().DurationVar(&replayWriteTimeout, "write-timeout", 2*time.Second, "Go on without a recorded client write after this long (0 waits forever)")
+	captureReplayCmd.Flags().Float64Var(&replayCorruptRate, "corrupt-rate", 0, "Chance per record of flipping a byte")
+	captureReplayCmd.Flags().Float64Var(&replayTruncateRate, "truncate-rate", 0, "Chance per record of cutting it short")
+	captureReplayCmd.Flags().Float64Var(&replayNoiseRate, "noise-rate", 0, "Chance per record of inserting non-frame bytes")
+	captureReplayCmd.Flags().Float64Var(&replaySplitRate, "split-rate", 0, "Chance per record of splitting it across reads")
+	captureReplayCmd.Flags().Int64Var(&replaySeed, "seed", 1, "Random seed for injected faults")
+	captureReplayCmd.Flags().DurationVar(&replayConfigTimeout, "config-timeout", 30*time.Second, "How long to wait for the device configuration")
+
+	captureCmd.AddCommand(captureInspectCmd)
+	captureCmd.AddCommand(captureReplayCmd)
+
+	rootCmd.AddCommand(captureCmd)
+}
@@ -32,6 +32,7 @@ var connectCmd = &cobra.Command{
 Examples:
   meshtastic connect --port /dev/ttyUSB0
   meshtastic connect --host 192.168.1.100
+  meshtastic connect --host localhost:4403
   meshtastic connect --ble-scan`,
 	RunE: runConnect,
 }
@@ -44,8 +45,12 @@ func runConnect(cmd *cobra.Command, args []string) error {
 	// Determine connection method
 	var config *client.Config
 	if host != "" {
-		// TODO: Implement TCP connection
-		return errors.New("TCP connection not yet implemented")
+		config = &client.Config{
+			Host:        host,
+			Timeout:     connectTimeout,
+			DebugSerial: globalConfig.DebugSerial,
+			HexDump:     globalConfig.HexDump,
+		}
 	} else if bleAddress != "" {
 		// TODO: Implement BLE connection
 		return errors.New("BLE connection not yet implemented")
@@ -135,7 +140,7 @@ func runBLEScan(cmd *cobra.Command, args []string) error {
 
 func init() {
 	connectCmd.Flags().StringVarP(&port, "port", "p", "", "Serial port (e.g., /dev/ttyUSB0, COM3)")
-	connectCmd.Flags().StringVar(&host, "host", "", "TCP/IP host (e.g., 192.168.1.100, meshtastic.local)")
+	connectCmd.Flags().StringVar(&host, "host", "", "TCP/IP host, port 4403 unless given (e.g., 192.168.1.100, meshtastic.local:4403)")
 	connectCmd.Flags().StringVar(&bleAddress, "ble-address", "", "BLE device address")
 	connectCmd.Flags().BoolVar(&bleScan, "ble-scan", false, "Scan for BLE devices")
 	connectCmd.Flags().DurationVar(&connectTimeout, "timeout", 30*time.Second, "Connection timeout")
@@ -0,0 +1,437 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/client"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/deviceadapter"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/meshbus"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/store"
+)
+
+var (
+	historyDB      string
+	historyNode    string
+	historyChannel int
+	historySince   string
+	historyUntil   string
+	historyLimit   int
+	historyFormat  string
+	historyOutput  string
+	historyAll     bool
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Record and query mesh history",
+	Long: `Record packets, node info, positions and telemetry into an SQLite database
+and query them later. Run "history record" on the machine attached to the
+device and use the other subcommands to look back across days.`,
+}
+
+// historyRecordCmd represents the history record command
+var historyRecordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record mesh events into the history database",
+	Long: `Connect to the device and persist every packet, node info update, position
+and telemetry report until interrupted.
+
+Examples:
+  meshtastic history record --db base.db
+  meshtastic --host 192.168.1.50 history record --db /var/lib/meshtastic/base.db`,
+	RunE: runHistoryRecord,
+}
+
+// historyMessagesCmd represents the history messages command
+var historyMessagesCmd = &cobra.Command{
+	Use:   "messages",
+	Short: "Show message history",
+	Long: `Show stored packets, optionally filtered by node, channel and time.
+
+Examples:
+  meshtastic history messages
+  meshtastic history messages --node !a4c138f4 --since 24h
+  meshtastic history messages --channel 1 --limit 50
+  meshtastic history messages --all --since 2024-05-01`,
+	RunE: runHistoryMessages,
+}
+
+// historyNodesCmd represents the history nodes command
+var historyNodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List nodes seen in the history",
+	RunE:  runHistoryNodes,
+}
+
+// historyPositionsCmd represents the history positions command
+var historyPositionsCmd = &cobra.Command{
+	Use:   "positions",
+	Short: "Show or export position history",
+	Long: `Show stored positions or export them as GPX or GeoJSON tracks.
+
+Examples:
+  meshtastic history positions --node !a4c138f4
+  meshtastic history positions --format gpx --output tracks.gpx
+  meshtastic history positions --format geojson --since 168h > week.geojson`,
+	RunE: runHistoryPositions,
+}
+
+// historyTelemetryCmd represents the history telemetry command
+var historyTelemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Show or export telemetry history",
+	Long: `Show stored telemetry or export it as a CSV time series.
+
+Examples:
+  meshtastic history telemetry --node !a4c138f4 --since 24h
+  meshtastic history telemetry --format csv --output telemetry.csv`,
+	RunE: runHistoryTelemetry,
+}
+
+func runHistoryRecord(cmd *cobra.Command, args []string) error {
+	s, err := store.Open(historyDB)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	bus, err := meshbus.NewBus(nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create event bus")
+	}
+	if err := s.Subscribe(bus); err != nil {
+		return err
+	}
+	if err := bus.Start(); err != nil {
+		return errors.Wrap(err, "failed to start event bus")
+	}
+	defer bus.Stop()
+
+	config := &client.Config{
+		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
+		Timeout:     globalConfig.Timeout,
+		DebugSerial: globalConfig.DebugSerial,
+		HexDump:     globalConfig.HexDump,
+	}
+
+	meshtasticClient, err := client.NewRobustMeshtasticClient(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create robust client")
+	}
+
+	adapter := deviceadapter.NewDeviceAdapter(historyDeviceID(), meshtasticClient, bus)
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), globalConfig.Timeout)
+	defer connectCancel()
+
+	if err := adapter.Start(connectCtx); err != nil {
+		meshtasticClient.Close()
+		return err
+	}
+	defer adapter.Stop()
+
+	fmt.Printf("Recording %s into %s\n", meshtasticClient.DevicePath(), s.Path())
+	fmt.Println("Press Ctrl+C to stop")
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	fmt.Println("\nShutting down...")
+	log.Info().Interface("stats", adapter.GetStatistics()).Msg("Recording stopped")
+
+	return nil
+}
+
+// historyDeviceID names the device in stored rows after the host or port
+func historyDeviceID() string {
+	if globalConfig.Host != "" {
+		return strings.ReplaceAll(globalConfig.Host, ":", "_")
+	}
+	return filepath.Base(globalConfig.Port)
+}
+
+// parseHistoryTime accepts a duration back from now ("24h") or a date
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time %q (use a duration like 24h or a date like 2006-01-02)", value)
+}
+
+func historyFilter(cmd *cobra.Command) (store.Filter, error) {
+	filter := store.Filter{Limit: historyLimit}
+
+	if historyNode != "" {
+		nodeID, err := parseNodeID(historyNode)
+		if err != nil {
+			return filter, err
+		}
+		filter.Node = nodeID
+	}
+	if cmd.Flags().Changed("channel") {
+		channel := uint32(historyChannel)
+		filter.Channel = &channel
+	}
+
+	var err error
+	if filter.Since, err = parseHistoryTime(historySince); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseHistoryTime(historyUntil); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// openHistory opens the database and the output for a query command
+func openHistory() (*store.Store, io.Writer, func(), error) {
+	if _, err := os.Stat(historyDB); err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "history database %s not found", historyDB)
+	}
+
+	s, err := store.Open(historyDB)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if historyOutput == "" || historyOutput == "-" {
+		return s, os.Stdout, func() { s.Close() }, nil
+	}
+
+	f, err := os.Create(historyOutput)
+	if err != nil {
+		s.Close()
+		return nil, nil, nil, errors.Wrapf(err, "failed to create %s", historyOutput)
+	}
+	return s, f, func() {
+		f.Close()
+		s.Close()
+	}, nil
+}
+
+func formatNode(names map[uint32]string, num uint32) string {
+	if num == 0xffffffff {
+		return "broadcast"
+	}
+	if name, ok := names[num]; ok && name != store.NodeID(num) {
+		return fmt.Sprintf("%s (%s)", store.NodeID(num), name)
+	}
+	return store.NodeID(num)
+}
+
+func runHistoryMessages(cmd *cobra.Command, args []string) error {
+	filter, err := historyFilter(cmd)
+	if err != nil {
+		return err
+	}
+	filter.TextOnly = !historyAll
+
+	s, w, closeFn, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	messages, err := s.Messages(filter)
+	if err != nil {
+		return err
+	}
+	names, err := s.NodeNames()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		content := m.Text
+		if content == "" {
+			content = fmt.Sprintf("[%s, %d bytes]", m.Port, len(m.Payload))
+		}
+		arrow := "->"
+		if m.Direction == "outbound" {
+			arrow = "=>"
+		}
+		fmt.Fprintf(w, "[%s] ch%d %s %s %s: %s\n",
+			m.ReceivedAt.Local().Format("2006-01-02 15:04:05"), m.Channel,
+			formatNode(names, m.From), arrow, formatNode(names, m.To), content)
+	}
+	if len(messages) == 0 {
+		fmt.Fprintln(w, "No messages found")
+	}
+
+	return nil
+}
+
+func runHistoryNodes(cmd *cobra.Command, args []string) error {
+	s, w, closeFn, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	nodes, err := s.Nodes()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%-10s %-20s %-6s %-16s %-20s %-20s\n", "ID", "Name", "Short", "Hardware", "First Seen", "Last Heard")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%-10s %-20s %-6s %-16s %-20s %-20s\n",
+			store.NodeID(n.Num), n.LongName, n.ShortName, n.HwModel,
+			n.FirstSeen.Local().Format("2006-01-02 15:04:05"),
+			n.LastHeard.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	return nil
+}
+
+func runHistoryPositions(cmd *cobra.Command, args []string) error {
+	filter, err := historyFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	s, w, closeFn, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	positions, err := s.Positions(filter)
+	if err != nil {
+		return err
+	}
+	names, err := s.NodeNames()
+	if err != nil {
+		return err
+	}
+
+	switch historyFormat {
+	case "gpx":
+		return store.WriteGPX(w, positions, names)
+	case "geojson":
+		return store.WriteGeoJSON(w, positions, names)
+	case "table":
+		for _, p := range positions {
+			altitude := ""
+			if p.Altitude != nil {
+				altitude = fmt.Sprintf("%dm", *p.Altitude)
+			}
+			fmt.Fprintf(w, "[%s] %s %.6f, %.6f %s\n",
+				p.Time().Local().Format("2006-01-02 15:04:05"), formatNode(names, p.NodeNum),
+				p.Latitude, p.Longitude, altitude)
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown format %q (use table, gpx or geojson)", historyFormat)
+	}
+}
+
+func runHistoryTelemetry(cmd *cobra.Command, args []string) error {
+	filter, err := historyFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	s, w, closeFn, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	points, err := s.Telemetry(filter)
+	if err != nil {
+		return err
+	}
+	names, err := s.NodeNames()
+	if err != nil {
+		return err
+	}
+
+	switch historyFormat {
+	case "csv":
+		return store.WriteTelemetryCSV(w, points, names)
+	case "table":
+		for _, t := range points {
+			var values []string
+			if t.BatteryLevel != nil {
+				values = append(values, fmt.Sprintf("battery %d%%", *t.BatteryLevel))
+			}
+			if t.Voltage != nil {
+				values = append(values, fmt.Sprintf("%.2fV", *t.Voltage))
+			}
+			if t.ChannelUtilization != nil {
+				values = append(values, fmt.Sprintf("ch util %.1f%%", *t.ChannelUtilization))
+			}
+			if t.AirUtilTx != nil {
+				values = append(values, fmt.Sprintf("air tx %.1f%%", *t.AirUtilTx))
+			}
+			if t.Temperature != nil {
+				values = append(values, fmt.Sprintf("%.1f°C", *t.Temperature))
+			}
+			if t.RelativeHumidity != nil {
+				values = append(values, fmt.Sprintf("%.1f%% RH", *t.RelativeHumidity))
+			}
+			if t.BarometricPressure != nil {
+				values = append(values, fmt.Sprintf("%.1f hPa", *t.BarometricPressure))
+			}
+			fmt.Fprintf(w, "[%s] %s %s: %s\n",
+				t.ReceivedAt.Local().Format("2006-01-02 15:04:05"), formatNode(names, t.NodeNum),
+				t.Kind, strings.Join(values, ", "))
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown format %q (use table or csv)", historyFormat)
+	}
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringVar(&historyDB, "db", "meshtastic-history.db", "Path to the history database")
+
+	for _, c := range []*cobra.Command{historyMessagesCmd, historyPositionsCmd, historyTelemetryCmd} {
+		c.Flags().StringVar(&historyNode, "node", "", "Only show entries for this node (e.g. !a4c138f4)")
+		c.Flags().StringVar(&historySince, "since", "", "Only show entries after this time (duration like 24h or a date)")
+		c.Flags().StringVar(&historyUntil, "until", "", "Only show entries before this time (duration like 1h or a date)")
+		c.Flags().IntVar(&historyLimit, "limit", 0, "Only show the most recent entries (0 for all)")
+	}
+	for _, c := range []*cobra.Command{historyMessagesCmd, historyNodesCmd, historyPositionsCmd, historyTelemetryCmd} {
+		c.Flags().StringVarP(&historyOutput, "output", "o", "", "Write output to a file instead of stdout")
+	}
+
+	historyMessagesCmd.Flags().IntVar(&historyChannel, "channel", 0, "Only show messages on this channel index")
+	historyMessagesCmd.Flags().BoolVar(&historyAll, "all", false, "Include non-text packets")
+	historyPositionsCmd.Flags().StringVar(&historyFormat, "format", "table", "Output format (table, gpx, geojson)")
+	historyTelemetryCmd.Flags().StringVar(&historyFormat, "format", "table", "Output format (table, csv)")
+
+	historyCmd.AddCommand(historyRecordCmd)
+	historyCmd.AddCommand(historyMessagesCmd)
+	historyCmd.AddCommand(historyNodesCmd)
+	historyCmd.AddCommand(historyPositionsCmd)
+	historyCmd.AddCommand(historyTelemetryCmd)
+
+	rootCmd.AddCommand(historyCmd)
+}
@@ -106,6 +106,8 @@ func runInfo(cmd *cobra.Command, args []string) error {
 func createAndConnectClient() (*client.RobustMeshtasticClient, error) {
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -30,6 +30,8 @@ func runListen(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -55,7 +57,7 @@ func runListen(cmd *cobra.Command, args []string) error {
 		return errors.New("device not connected")
 	}
 
-	fmt.Printf("Listening for messages on port: %s\n", globalConfig.Port)
+	fmt.Printf("Listening for messages on: %s\n", meshtasticClient.DevicePath())
 	fmt.Println("Press Ctrl+C to stop")
 
 	// Set up signal handling for graceful shutdown
@@ -403,6 +403,7 @@ func cmdHelp(cmdArgs []string) error {
 	fmt.Println()
 	fmt.Println(colorInfo("Examples:"))
 	fmt.Printf("  %s\n", colorCommand("connect /dev/ttyACM0"))
+	fmt.Printf("  %s\n", colorCommand("connect tcp://localhost:4403"))
 	fmt.Printf("  %s\n", colorCommand("send 0x12345678 Hello world"))
 	fmt.Printf("  %s\n", colorCommand("send 0xFFFFFFFF Broadcast message"))
 	fmt.Println()
@@ -464,6 +465,10 @@ func cmdConnect(ctx context.Context, cmdArgs []string) error {
 			DebugSerial: args.LogLevel == "debug",
 			HexDump:     args.LogLevel == "debug",
 		}
+		if strings.HasPrefix(devicePath, "tcp://") {
+			config.DevicePath = ""
+			config.Host = strings.TrimPrefix(devicePath, "tcp://")
+		}
 
 		// Create robust client - this will fail immediately if device doesn't exist
 		robustClient, err := client.NewRobustMeshtasticClient(config)
@@ -473,7 +478,7 @@ func cmdConnect(ctx context.Context, cmdArgs []string) error {
 		}
 
 		// Create device adapter
-		deviceID := fmt.Sprintf("dev_%s", strings.ReplaceAll(strings.TrimPrefix(devicePath, "/dev/"), "/", "_"))
+		deviceID := fmt.Sprintf("dev_%s", strings.NewReplacer("/dev/", "", "tcp://", "tcp_", "/", "_", ":", "_").Replace(devicePath))
 		newAdapter := deviceadapter.NewDeviceAdapter(deviceID, robustClient, bus)
 
 		// Start adapter with timeout context
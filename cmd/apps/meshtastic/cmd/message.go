@@ -95,6 +95,8 @@ func runMessageSend(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -155,6 +157,8 @@ func runMessageListen(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -294,6 +298,8 @@ func runMessageReply(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -368,6 +374,8 @@ func runMessagePrivate(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
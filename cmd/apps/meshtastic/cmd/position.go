@@ -78,6 +78,8 @@ func runPositionGet(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -205,6 +207,8 @@ func runPositionSet(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -275,6 +279,8 @@ func runPositionClear(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -342,6 +348,8 @@ func runPositionRequest(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -444,6 +452,8 @@ func runPositionBroadcast(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -19,6 +19,7 @@ type GlobalConfig struct {
 	Timeout     time.Duration
 	DebugSerial bool
 	HexDump     bool
+	CaptureFile string
 }
 
 var globalConfig GlobalConfig
@@ -80,6 +81,7 @@ func init() {
 	rootCmd.PersistentFlags().DurationVar(&globalConfig.Timeout, "timeout", 10*time.Second, "Operation timeout")
 	rootCmd.PersistentFlags().BoolVar(&globalConfig.DebugSerial, "debug-serial", false, "Enable verbose serial communication logging")
 	rootCmd.PersistentFlags().BoolVar(&globalConfig.HexDump, "hex-dump", false, "Enable hex dump logging of raw serial data")
+	rootCmd.PersistentFlags().StringVar(&globalConfig.CaptureFile, "capture", "", "Record the raw device session to a capture file")
 
 	// Add subcommands
 	rootCmd.AddCommand(connectCmd)
@@ -27,6 +27,8 @@ func runSend(cmd *cobra.Command, args []string) error {
 	// Create robust client
 	config := &client.Config{
 		DevicePath:  globalConfig.Port,
+		Host:        globalConfig.Host,
+		CaptureFile: globalConfig.CaptureFile,
 		Timeout:     globalConfig.Timeout,
 		DebugSerial: globalConfig.DebugSerial,
 		HexDump:     globalConfig.HexDump,
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/client"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/simulator"
+)
+
+var (
+	simulateListen            string
+	simulateConfigFile        string
+	simulateNeighbors         int
+	simulateTelemetryInterval time.Duration
+	simulatePositionInterval  time.Duration
+	simulateMessageDelay      time.Duration
+	simulateMessageJitter     time.Duration
+	simulateNoEcho            bool
+	simulateSeed              int64
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Run a simulated Meshtastic device",
+	Long: `Run a software Meshtastic device that serves the stream API over TCP,
+like a networked device on port 4403. Virtual neighbors broadcast telemetry and
+positions and answer text messages, so every command can be tried without a radio.
+
+Examples:
+  meshtastic simulate
+  meshtastic simulate --neighbors 8 --telemetry-interval 5s
+  meshtastic simulate --config mesh.yaml --listen :14403
+
+  # In another terminal
+  meshtastic listen --host localhost
+  meshtastic send --host localhost "hello mesh"`,
+	RunE: runSimulate,
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	config := simulator.DefaultConfig()
+	if simulateConfigFile != "" {
+		var err error
+		config, err = simulator.LoadConfig(simulateConfigFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("neighbors") {
+		config.Neighbors = simulator.GenerateNeighbors(config, simulateNeighbors)
+	}
+	if flags.Changed("telemetry-interval") {
+		config.TelemetryInterval = simulateTelemetryInterval
+	}
+	if flags.Changed("position-interval") {
+		config.PositionInterval = simulatePositionInterval
+	}
+	if flags.Changed("message-delay") {
+		config.MessageDelay = simulateMessageDelay
+	}
+	if flags.Changed("message-jitter") {
+		config.MessageJitter = simulateMessageJitter
+	}
+	if flags.Changed("no-echo") {
+		config.Echo = !simulateNoEcho
+	}
+	if flags.Changed("seed") {
+		config.Seed = simulateSeed
+	}
+
+	node, err := simulator.NewNode(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create simulated node")
+	}
+	defer node.Close()
+
+	fmt.Printf("Simulated node !%08x (%s) with %d neighbors listening on %s\n",
+		config.NodeNum, config.LongName, len(config.Neighbors), simulateListen)
+	for _, n := range config.Neighbors {
+		fmt.Printf("  !%08x %-16s snr %5.1f  hops %d\n", n.NodeNum, n.LongName, n.SNR, n.HopsAway)
+	}
+	fmt.Println("Press Ctrl+C to stop")
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- node.ListenAndServe(simulateListen)
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-sigChan:
+		log.Info().Msg("Stopping simulated node")
+		return nil
+	}
+}
+
+func init() {
+	simulateCmd.Flags().StringVar(&simulateListen, "listen", fmt.Sprintf(":%d", client.DefaultTCPPort), "Address to serve the stream API on")
+	simulateCmd.Flags().StringVar(&simulateConfigFile, "config", "", "YAML file describing the node and its neighbors")
+	simulateCmd.Flags().IntVar(&simulateNeighbors, "neighbors", 3, "Number of generated virtual neighbors")
+	simulateCmd.Flags().DurationVar(&simulateTelemetryInterval, "telemetry-interval", 30*time.Second, "Telemetry broadcast interval per neighbor (0 disables)")
+	simulateCmd.Flags().DurationVar(&simulatePositionInterval, "position-interval", 60*time.Second, "Position broadcast interval per neighbor (0 disables)")
+	simulateCmd.Flags().DurationVar(&simulateMessageDelay, "message-delay", 500*time.Millisecond, "Delay before packets from neighbors arrive")
+	simulateCmd.Flags().DurationVar(&simulateMessageJitter, "message-jitter", 250*time.Millisecond, "Random extra delay added to each packet")
+	simulateCmd.Flags().BoolVar(&simulateNoEcho, "no-echo", false, "Don't let neighbors answer text messages")
+	simulateCmd.Flags().Int64Var(&simulateSeed, "seed", 1, "Random seed for telemetry and movement")
+
+	rootCmd.AddCommand(simulateCmd)
+}
@@ -0,0 +1,327 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/protocol"
+)
+
+// Format identifies capture files in their header line
+const Format = "meshtastic-capture"
+
+// Version is the capture file format version
+const Version = 1
+
+// Direction tells whether bytes came from the device or were sent to it
+type Direction string
+
+const (
+	// DirectionRx is data read from the device
+	DirectionRx Direction = "rx"
+	// DirectionTx is data written to the device
+	DirectionTx Direction = "tx"
+)
+
+// Header is the first line of a capture file
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Device    string    `json:"device,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// Record is one chunk of raw bytes as it was read from or written to the
+// stream. Chunks don't line up with frames: a read can hold several frames or
+// part of one.
+type Record struct {
+	Offset    time.Duration // since the start of the capture
+	Direction Direction
+	Data      []byte
+}
+
+// recordLine is the on-disk form of a Record, one JSON object per line
+type recordLine struct {
+	OffsetUS  int64     `json:"offset_us"`
+	Direction Direction `json:"dir"`
+	Data      string    `json:"data"`
+}
+
+// Capture is a recorded session
+type Capture struct {
+	Header  Header
+	Records []Record
+}
+
+// Load reads a capture file
+func Load(path string) (*Capture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open capture %s", path)
+	}
+	defer f.Close()
+
+	c, err := Read(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read capture %s", path)
+	}
+	return c, nil
+}
+
+// Read parses a capture from r
+func Read(r io.Reader) (*Capture, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	c := &Capture{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Bytes()
+		if len(text) == 0 {
+			continue
+		}
+
+		if line == 1 {
+			if err := json.Unmarshal(text, &c.Header); err != nil {
+				return nil, errors.Wrap(err, "invalid capture header")
+			}
+			if c.Header.Format != Format {
+				return nil, errors.Errorf("not a capture file (format %q)", c.Header.Format)
+			}
+			if c.Header.Version != Version {
+				return nil, errors.Errorf("unsupported capture version %d", c.Header.Version)
+			}
+			continue
+		}
+
+		var rl recordLine
+		if err := json.Unmarshal(text, &rl); err != nil {
+			return nil, errors.Wrapf(err, "invalid record on line %d", line)
+		}
+		if rl.Direction != DirectionRx && rl.Direction != DirectionTx {
+			return nil, errors.Errorf("invalid direction %q on line %d", rl.Direction, line)
+		}
+		data, err := hex.DecodeString(rl.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid data on line %d", line)
+		}
+		c.Records = append(c.Records, Record{
+			Offset:    time.Duration(rl.OffsetUS) * time.Microsecond,
+			Direction: rl.Direction,
+			Data:      data,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read capture")
+	}
+	if line == 0 {
+		return nil, errors.New("empty capture")
+	}
+
+	return c, nil
+}
+
+// Bytes returns all data in one direction concatenated
+func (c *Capture) Bytes(dir Direction) []byte {
+	var data []byte
+	for _, r := range c.Records {
+		if r.Direction == dir {
+			data = append(data, r.Data...)
+		}
+	}
+	return data
+}
+
+// Duration returns the offset of the last record
+func (c *Capture) Duration() time.Duration {
+	if len(c.Records) == 0 {
+		return 0
+	}
+	return c.Records[len(c.Records)-1].Offset
+}
+
+// FromRadio decodes the frames the device sent. Frames that don't decode are
+// skipped, as the client would skip them.
+func (c *Capture) FromRadio() []*pb.FromRadio {
+	var messages []*pb.FromRadio
+	parser := protocol.NewFrameParser(func(frame *protocol.Frame) {
+		if m, err := frame.FromRadioMessage(); err == nil {
+			messages = append(messages, m)
+		}
+	}, nil)
+	parser.ProcessBytes(c.Bytes(DirectionRx))
+	return messages
+}
+
+// ToRadio decodes the frames the client sent
+func (c *Capture) ToRadio() []*pb.ToRadio {
+	var messages []*pb.ToRadio
+	parser := protocol.NewFrameParser(func(frame *protocol.Frame) {
+		if m, err := frame.ToRadioMessage(); err == nil {
+			messages = append(messages, m)
+		}
+	}, nil)
+	parser.ProcessBytes(c.Bytes(DirectionTx))
+	return messages
+}
+
+// Writer appends records to a capture file. It is safe for concurrent use,
+// since reads and writes on a stream happen on different goroutines.
+type Writer struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closer  io.Closer
+	start   time.Time
+	records int
+	err     error
+	closed  bool
+}
+
+// NewWriter writes the capture header to w and returns a writer for records
+func NewWriter(w io.Writer, device string) (*Writer, error) {
+	cw := &Writer{
+		w:     bufio.NewWriter(w),
+		start: time.Now(),
+	}
+
+	header, err := json.Marshal(Header{
+		Format:    Format,
+		Version:   Version,
+		Device:    device,
+		StartedAt: cw.start.UTC(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal capture header")
+	}
+	if err := cw.writeLine(header); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// Create creates a capture file at path
+func Create(path, device string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create capture %s", path)
+	}
+
+	cw, err := NewWriter(f, device)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cw.closer = f
+
+	return cw, nil
+}
+
+func (cw *Writer) writeLine(line []byte) error {
+	if _, err := cw.w.Write(line); err != nil {
+		return errors.Wrap(err, "failed to write capture")
+	}
+	if err := cw.w.WriteByte('\n'); err != nil {
+		return errors.Wrap(err, "failed to write capture")
+	}
+	// Flush every record so a crash keeps everything up to the crash
+	return errors.Wrap(cw.w.Flush(), "failed to write capture")
+}
+
+// Record appends a chunk of data. After the first write error, later
+// records are dropped and the error is returned by Close.
+func (cw *Writer) Record(dir Direction, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.closed || cw.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(recordLine{
+		OffsetUS:  time.Since(cw.start).Microseconds(),
+		Direction: dir,
+		Data:      hex.EncodeToString(data),
+	})
+	if err == nil {
+		err = cw.writeLine(line)
+	}
+	if err != nil {
+		cw.err = err
+		return
+	}
+	cw.records++
+}
+
+// Records returns the number of records written
+func (cw *Writer) Records() int {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.records
+}
+
+// Close flushes the capture and closes the file if the writer created it
+func (cw *Writer) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.closed {
+		return nil
+	}
+	cw.closed = true
+
+	err := cw.err
+	if flushErr := cw.w.Flush(); err == nil && flushErr != nil {
+		err = errors.Wrap(flushErr, "failed to write capture")
+	}
+	if cw.closer != nil {
+		if closeErr := cw.closer.Close(); err == nil && closeErr != nil {
+			err = errors.Wrap(closeErr, "failed to close capture")
+		}
+	}
+
+	return err
+}
+
+// tappedStream records everything read from and written to a stream
+type tappedStream struct {
+	io.ReadWriteCloser
+	w *Writer
+}
+
+// Tap returns a stream that records all traffic of stream into w. Closing the
+// tapped stream closes stream but not w, so a client can reconnect onto a new
+// stream and keep recording into the same capture.
+func Tap(stream io.ReadWriteCloser, w *Writer) io.ReadWriteCloser {
+	return &tappedStream{ReadWriteCloser: stream, w: w}
+}
+
+func (t *tappedStream) Read(p []byte) (int, error) {
+	n, err := t.ReadWriteCloser.Read(p)
+	if n > 0 {
+		t.w.Record(DirectionRx, p[:n])
+	}
+	return n, err
+}
+
+func (t *tappedStream) Write(p []byte) (int, error) {
+	n, err := t.ReadWriteCloser.Write(p)
+	if n > 0 {
+		t.w.Record(DirectionTx, p[:n])
+	}
+	return n, err
+}
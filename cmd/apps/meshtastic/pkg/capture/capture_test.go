@@ -0,0 +1,178 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+type bufferStream struct {
+	bytes.Buffer
+	written bytes.Buffer
+}
+
+func (b *bufferStream) Write(p []byte) (int, error) { return b.written.Write(p) }
+func (b *bufferStream) Close() error                { return nil }
+
+func TestTapRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, "test")
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+
+	stream := &bufferStream{}
+	stream.Buffer.Write([]byte{0x94, 0xc3, 0x00, 0x01, 0x08})
+
+	tapped := Tap(stream, w)
+	if _, err := tapped.Write([]byte{0x94, 0xc3, 0x00, 0x00}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(tapped, buf); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if _, err := io.ReadFull(tapped, buf[:2]); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if w.Records() != 3 {
+		t.Fatalf("expected 3 records, got %d", w.Records())
+	}
+
+	c, err := Read(&out)
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	if c.Header.Device != "test" {
+		t.Fatalf("unexpected device %q", c.Header.Device)
+	}
+	if got := c.Bytes(DirectionRx); !bytes.Equal(got, []byte{0x94, 0xc3, 0x00, 0x01, 0x08}) {
+		t.Fatalf("unexpected rx bytes % x", got)
+	}
+	if got := c.Bytes(DirectionTx); !bytes.Equal(got, []byte{0x94, 0xc3, 0x00, 0x00}) {
+		t.Fatalf("unexpected tx bytes % x", got)
+	}
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+	for _, input := range []string{
+		"",
+		`{"format":"something-else","version":1}`,
+		`{"format":"meshtastic-capture","version":99}`,
+		"{\"format\":\"meshtastic-capture\",\"version\":1}\n{\"offset_us\":0,\"dir\":\"up\",\"data\":\"00\"}",
+		"{\"format\":\"meshtastic-capture\",\"version\":1}\n{\"offset_us\":0,\"dir\":\"rx\",\"data\":\"zz\"}",
+	} {
+		if _, err := Read(bytes.NewBufferString(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func testCapture() *Capture {
+	return &Capture{Records: []Record{
+		{Offset: 0, Direction: DirectionTx, Data: []byte("want")},
+		{Offset: 10 * time.Millisecond, Direction: DirectionRx, Data: []byte("first response")},
+		{Offset: 20 * time.Millisecond, Direction: DirectionRx, Data: []byte("second response")},
+	}}
+}
+
+func readAll(t *testing.T, r *Replay) []byte {
+	t.Helper()
+	var out []byte
+	buf := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+	}
+}
+
+func TestReplayWaitsForWrites(t *testing.T) {
+	opts := DefaultReplayOptions()
+	opts.Speed = 0
+	opts.EOF = true
+	r := NewReplay(testCapture(), opts)
+
+	read := make(chan []byte)
+	go func() { read <- readAll(t, r) }()
+
+	select {
+	case <-read:
+		t.Fatalf("replay answered before the client wrote")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, err := r.Write([]byte("want")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	select {
+	case out := <-read:
+		if string(out) != "first responsesecond response" {
+			t.Fatalf("unexpected data %q", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("replay did not answer the write")
+	}
+	if string(r.Written()) != "want" || r.Writes() != 1 {
+		t.Fatalf("unexpected writes %q", r.Written())
+	}
+}
+
+func TestReplayWriteTimeout(t *testing.T) {
+	opts := DefaultReplayOptions()
+	opts.Speed = 0
+	opts.EOF = true
+	opts.WriteTimeout = 10 * time.Millisecond
+	r := NewReplay(testCapture(), opts)
+
+	start := time.Now()
+	if out := readAll(t, r); string(out) != "first responsesecond response" {
+		t.Fatalf("unexpected data %q", out)
+	}
+	if time.Since(start) < opts.WriteTimeout {
+		t.Fatalf("replay did not wait for the write")
+	}
+}
+
+func TestReplayFaults(t *testing.T) {
+	opts := DefaultReplayOptions()
+	opts.Speed = 0
+	opts.EOF = true
+	opts.WaitForWrites = false
+	opts.Faults = []Fault{
+		{Record: 1, Kind: FaultTruncate, Offset: 5},
+		{Record: 2, Kind: FaultCorrupt, Offset: 0},
+	}
+
+	r := NewReplay(testCapture(), opts)
+	out := readAll(t, r)
+	expected := append([]byte("first"), []byte("second response")...)
+	expected[5] ^= 0xff
+	if !bytes.Equal(out, expected) {
+		t.Fatalf("unexpected data %q", out)
+	}
+	if len(r.Faults()) != 2 {
+		t.Fatalf("expected 2 faults, got %v", r.Faults())
+	}
+
+	// Random faults are reproducible with the same seed
+	opts.Faults = nil
+	opts.NoiseRate = 0.5
+	opts.CorruptRate = 0.5
+	opts.SplitRate = 0.5
+	opts.Seed = 7
+	first := readAll(t, NewReplay(testCapture(), opts))
+	second := readAll(t, NewReplay(testCapture(), opts))
+	if !bytes.Equal(first, second) {
+		t.Fatalf("same seed gave different data: %q and %q", first, second)
+	}
+}
@@ -0,0 +1,375 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Fault kinds the replay can inject into device data
+const (
+	// FaultCorrupt flips one byte
+	FaultCorrupt = "corrupt"
+	// FaultTruncate cuts the chunk short, leaving a partial frame
+	FaultTruncate = "truncate"
+	// FaultNoise inserts bytes that aren't part of any frame, like device log output
+	FaultNoise = "noise"
+	// FaultSplit delivers the chunk in two reads with a pause in between
+	FaultSplit = "split"
+)
+
+// Fault is a modification of one device record during replay
+type Fault struct {
+	Record int    `json:"record"` // index into Capture.Records
+	Kind   string `json:"kind"`
+	Offset int    `json:"offset"` // byte position in the record's data
+}
+
+// ReplayOptions controls timing and fault injection of a replay
+type ReplayOptions struct {
+	// Speed scales the recorded timing: 1 is real time, 10 is ten times
+	// faster, 0 delivers data as fast as it is read
+	Speed float64
+
+	// WaitForWrites holds back device data that was recorded after the
+	// client's nth write until the client has written n times, so responses
+	// don't arrive before the requests that caused them
+	WaitForWrites bool
+
+	// WriteTimeout is how long device data waits for a client write before
+	// playback goes on without it, for sessions where the client sent
+	// something the replaying client doesn't. Zero waits forever.
+	WriteTimeout time.Duration
+
+	// EOF makes Read return io.EOF after the last record. Otherwise reads
+	// block until Close, like an idle device.
+	EOF bool
+
+	// Chance per device record of each fault
+	CorruptRate  float64
+	TruncateRate float64
+	NoiseRate    float64
+	SplitRate    float64
+
+	// SplitDelay is the pause between the two halves of a split record
+	SplitDelay time.Duration
+
+	// Faults are injected in addition to the random ones
+	Faults []Fault
+
+	// Seed makes random faults reproducible
+	Seed int64
+}
+
+// DefaultReplayOptions replays in real time without faults
+func DefaultReplayOptions() ReplayOptions {
+	return ReplayOptions{
+		Speed:         1,
+		WaitForWrites: true,
+		SplitDelay:    20 * time.Millisecond,
+		Seed:          1,
+	}
+}
+
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// Replay is an io.ReadWriteCloser that plays back the device side of a
+// capture. It can replace the serial port of a client through SetStream.
+// Writes are collected and can be compared with what was recorded.
+type Replay struct {
+	capture *Capture
+	opts    ReplayOptions
+	rng     *rand.Rand
+	faults  map[int][]Fault
+
+	mu       sync.Mutex
+	next     int
+	pending  []chunk
+	txPassed int
+	txMissed int
+	waiting  time.Time // when playback started waiting for a client write
+
+	// Timing is anchored to a point in the capture and the wall clock time it
+	// maps to. Waiting for client writes moves the anchor.
+	anchorWall   time.Time
+	anchorOffset time.Duration
+
+	written    bytes.Buffer
+	writeTimes []time.Time
+	injected   []Fault
+
+	closed   bool
+	wake     chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewReplay creates a replay of c. Playback starts immediately.
+func NewReplay(c *Capture, opts ReplayOptions) *Replay {
+	if opts.Speed < 0 {
+		opts.Speed = 0
+	}
+	if opts.SplitDelay <= 0 {
+		opts.SplitDelay = 20 * time.Millisecond
+	}
+
+	faults := make(map[int][]Fault)
+	for _, f := range opts.Faults {
+		faults[f.Record] = append(faults[f.Record], f)
+	}
+
+	return &Replay{
+		capture:    c,
+		opts:       opts,
+		rng:        rand.New(rand.NewSource(opts.Seed)),
+		faults:     faults,
+		anchorWall: time.Now(),
+		wake:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Read implements io.Reader
+func (r *Replay) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		if r.closed {
+			return 0, io.ErrClosedPipe
+		}
+
+		if len(r.pending) == 0 {
+			if ok, deadline := r.schedule(); !ok {
+				if r.next >= len(r.capture.Records) {
+					r.doneOnce.Do(func() { close(r.done) })
+					if r.opts.EOF {
+						return 0, io.EOF
+					}
+				}
+				r.wait(deadline)
+				continue
+			}
+		}
+
+		head := &r.pending[0]
+		if time.Now().Before(head.due) {
+			r.wait(head.due)
+			continue
+		}
+
+		n := copy(p, head.data)
+		head.data = head.data[n:]
+		if len(head.data) == 0 {
+			r.pending = r.pending[1:]
+		}
+		return n, nil
+	}
+}
+
+// schedule queues the next device record, passing over client records on the
+// way. It returns false if there is nothing to deliver yet, with the time to
+// check again if that is known.
+func (r *Replay) schedule() (bool, time.Time) {
+	for r.next < len(r.capture.Records) {
+		rec := r.capture.Records[r.next]
+
+		if rec.Direction == DirectionTx {
+			if r.opts.WaitForWrites {
+				var written time.Time
+				if matched := r.txPassed - r.txMissed; len(r.writeTimes) > matched {
+					written = r.writeTimes[matched]
+				} else {
+					if r.opts.WriteTimeout <= 0 {
+						return false, time.Time{}
+					}
+					if r.waiting.IsZero() {
+						r.waiting = time.Now()
+					}
+					if giveUp := r.waiting.Add(r.opts.WriteTimeout); time.Now().Before(giveUp) {
+						return false, giveUp
+					}
+					// The client's next write is matched with the next
+					// recorded one instead
+					written = time.Now()
+					r.txMissed++
+				}
+				r.waiting = time.Time{}
+				// The client may write later than it did in the capture; the
+				// device's answer comes after the write either way
+				if r.dueAt(rec.Offset).Before(written) {
+					r.anchorWall = written
+					r.anchorOffset = rec.Offset
+				}
+			}
+			r.txPassed++
+			r.next++
+			continue
+		}
+
+		r.pending = r.inject(r.next, rec.Data, r.dueAt(rec.Offset))
+		r.next++
+		return true, time.Time{}
+	}
+	return false, time.Time{}
+}
+
+func (r *Replay) dueAt(offset time.Duration) time.Time {
+	if r.opts.Speed == 0 {
+		return time.Time{}
+	}
+	return r.anchorWall.Add(time.Duration(float64(offset-r.anchorOffset) / r.opts.Speed))
+}
+
+// inject applies the faults for a record and returns the chunks to deliver
+func (r *Replay) inject(index int, data []byte, due time.Time) []chunk {
+	data = append([]byte(nil), data...)
+	if len(data) == 0 {
+		return []chunk{{data: data, due: due}}
+	}
+
+	faults := append([]Fault(nil), r.faults[index]...)
+	for _, kind := range []struct {
+		name string
+		rate float64
+	}{
+		{FaultNoise, r.opts.NoiseRate},
+		{FaultCorrupt, r.opts.CorruptRate},
+		{FaultTruncate, r.opts.TruncateRate},
+		{FaultSplit, r.opts.SplitRate},
+	} {
+		if kind.rate > 0 && r.rng.Float64() < kind.rate {
+			faults = append(faults, Fault{Record: index, Kind: kind.name, Offset: r.rng.Intn(len(data))})
+		}
+	}
+
+	chunks := []chunk{{data: data, due: due}}
+	for _, f := range faults {
+		last := &chunks[len(chunks)-1]
+		if f.Offset < 0 || f.Offset >= len(last.data) {
+			f.Offset = len(last.data) / 2
+		}
+
+		switch f.Kind {
+		case FaultCorrupt:
+			last.data[f.Offset] ^= 0xff
+		case FaultTruncate:
+			if f.Offset == 0 {
+				f.Offset = 1
+			}
+			last.data = last.data[:f.Offset]
+		case FaultNoise:
+			noise := make([]byte, 1+r.rng.Intn(16))
+			for i := range noise {
+				noise[i] = byte('a' + r.rng.Intn(26))
+			}
+			last.data = append(last.data[:f.Offset:f.Offset], append(noise, last.data[f.Offset:]...)...)
+		case FaultSplit:
+			if f.Offset == 0 {
+				f.Offset = 1
+			}
+			if f.Offset >= len(last.data) {
+				continue
+			}
+			rest := chunk{data: last.data[f.Offset:], due: due.Add(r.opts.SplitDelay)}
+			if due.IsZero() {
+				rest.due = time.Now().Add(r.opts.SplitDelay)
+			}
+			last.data = last.data[:f.Offset]
+			chunks = append(chunks, rest)
+		default:
+			continue
+		}
+
+		r.injected = append(r.injected, f)
+	}
+
+	return chunks
+}
+
+// wait releases the lock until something changes or the deadline passes
+func (r *Replay) wait(deadline time.Time) {
+	wake := r.wake
+	r.mu.Unlock()
+	defer r.mu.Lock()
+
+	if deadline.IsZero() {
+		<-wake
+		return
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-wake:
+	case <-timer.C:
+	}
+}
+
+// notify wakes up a blocked Read. Callers hold the lock.
+func (r *Replay) notify() {
+	close(r.wake)
+	r.wake = make(chan struct{})
+}
+
+// Write implements io.Writer. Data is kept for Written.
+func (r *Replay) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	r.written.Write(p)
+	r.writeTimes = append(r.writeTimes, time.Now())
+	r.notify()
+
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (r *Replay) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		r.notify()
+	}
+	return nil
+}
+
+// Done is closed once all device data has been read
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+// Written returns everything the client wrote
+func (r *Replay) Written() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.written.Bytes()...)
+}
+
+// Writes returns the number of writes the client made
+func (r *Replay) Writes() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.writeTimes)
+}
+
+// Faults returns the faults injected so far
+func (r *Replay) Faults() []Fault {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Fault(nil), r.injected...)
+}
@@ -50,6 +50,8 @@ type MeshtasticClient struct {
 // Config represents client configuration
 type Config struct {
 	DevicePath  string
+	Host        string // TCP host[:port]; takes precedence over DevicePath
+	CaptureFile string // record the raw session to this file
 	Timeout     time.Duration
 	DebugOutput interface{}
 	DebugSerial bool
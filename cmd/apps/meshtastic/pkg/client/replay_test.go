@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/capture"
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+)
+
+// The fixture was recorded with --capture against the simulator: the client
+// connects, sends "ping" to !5107a002 and gets the echo back, while the
+// neighbors broadcast telemetry and positions.
+const sessionFixture = "testdata/simulator-session.jsonl"
+
+type replayResult struct {
+	mu        sync.Mutex
+	texts     []string
+	positions int
+	telemetry int
+}
+
+func (r *replayResult) counts() ([]string, int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.texts...), r.positions, r.telemetry
+}
+
+// replaySession connects a stream client to a replay of the fixture, sends
+// the recorded text message and waits until the replay is exhausted
+func replaySession(t *testing.T, opts capture.ReplayOptions) (*StreamClient, *capture.Replay, *replayResult, error) {
+	t.Helper()
+
+	c, err := capture.Load(sessionFixture)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	replay := capture.NewReplay(c, opts)
+	sc := NewStreamClient(replay, "replay://"+sessionFixture)
+	sc.timeout = 5 * time.Second
+
+	result := &replayResult{}
+	sc.SetOnMessage(func(packet *pb.MeshPacket) {
+		if decoded := packet.GetDecoded(); decoded.GetPortnum() == pb.PortNum_TEXT_MESSAGE_APP {
+			result.mu.Lock()
+			result.texts = append(result.texts, string(decoded.GetPayload()))
+			result.mu.Unlock()
+		}
+	})
+	sc.SetOnPosition(func(*pb.Position) {
+		result.mu.Lock()
+		result.positions++
+		result.mu.Unlock()
+	})
+	sc.SetOnTelemetry(func(*pb.Telemetry) {
+		result.mu.Lock()
+		result.telemetry++
+		result.mu.Unlock()
+	})
+
+	t.Cleanup(func() {
+		replay.Close()
+		sc.Disconnect()
+	})
+
+	if err := sc.Connect(context.Background()); err != nil {
+		return sc, replay, result, err
+	}
+	if err := sc.SendText("ping", 0x5107a002); err != nil {
+		t.Fatalf("failed to send text: %v", err)
+	}
+
+	select {
+	case <-replay.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("replay did not finish")
+	}
+	// Done fires when the last chunk is handed out, not when it is processed
+	time.Sleep(50 * time.Millisecond)
+
+	return sc, replay, result, nil
+}
+
+func TestReplaySession(t *testing.T) {
+	opts := capture.DefaultReplayOptions()
+	opts.Speed = 0
+
+	sc, replay, result, err := replaySession(t, opts)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	if sc.GetMyInfo().GetMyNodeNum() != 0x5107a001 {
+		t.Fatalf("unexpected node num %x", sc.GetMyInfo().GetMyNodeNum())
+	}
+	if len(sc.GetNodes()) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(sc.GetNodes()))
+	}
+	if len(sc.GetChannels()) != 1 {
+		t.Fatalf("expected 1 channel, got %d", len(sc.GetChannels()))
+	}
+
+	texts, positions, telemetry := result.counts()
+	if len(texts) != 2 || texts[0] != "V1: ping" || texts[1] != "anyone on channel 0?" {
+		t.Fatalf("unexpected texts %q", texts)
+	}
+	if positions != 3 {
+		t.Fatalf("expected 3 positions, got %d", positions)
+	}
+	if telemetry != 6 {
+		t.Fatalf("expected 6 telemetry reports, got %d", telemetry)
+	}
+
+	// The client's writes decode the same way as the recorded ones
+	written := &capture.Capture{Records: []capture.Record{{Direction: capture.DirectionTx, Data: replay.Written()}}}
+	toRadio := written.ToRadio()
+	if len(toRadio) != 2 {
+		t.Fatalf("expected 2 frames written, got %d", len(toRadio))
+	}
+	if toRadio[0].GetWantConfigId() == 0 {
+		t.Fatalf("expected want_config first, got %v", toRadio[0])
+	}
+	if string(toRadio[1].GetPacket().GetDecoded().GetPayload()) != "ping" {
+		t.Fatalf("expected ping packet, got %v", toRadio[1])
+	}
+}
+
+func TestReplaySessionWithNoiseAndSplits(t *testing.T) {
+	opts := capture.DefaultReplayOptions()
+	opts.Speed = 0
+	opts.NoiseRate = 1
+	opts.SplitRate = 1
+	opts.SplitDelay = time.Millisecond
+	opts.Seed = 42
+
+	sc, replay, result, err := replaySession(t, opts)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if len(replay.Faults()) == 0 {
+		t.Fatalf("expected faults to be injected")
+	}
+
+	// Noise between frames is treated as device log output and splits are
+	// reassembled, so nothing is lost. Noise that lands inside a frame
+	// corrupts that frame.
+	if sc.GetMyInfo() == nil {
+		t.Fatalf("expected my_info despite faults")
+	}
+	texts, _, telemetry := result.counts()
+	if len(texts) == 0 && telemetry == 0 {
+		t.Fatalf("expected some packets to survive")
+	}
+}
+
+func TestReplaySessionCorrupted(t *testing.T) {
+	c, err := capture.Load(sessionFixture)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	// Corrupt every device record after the configuration: the client has to
+	// survive garbage without losing the connection
+	opts := capture.DefaultReplayOptions()
+	opts.Speed = 0
+	config := -1
+	for i, r := range c.Records {
+		if r.Direction == capture.DirectionRx && bytes.Contains(r.Data, []byte("Simulated Node")) {
+			config = i
+		}
+	}
+	if config < 0 {
+		t.Fatalf("fixture has no configuration record")
+	}
+	for i := config + 1; i < len(c.Records); i++ {
+		if c.Records[i].Direction == capture.DirectionRx {
+			opts.Faults = append(opts.Faults,
+				capture.Fault{Record: i, Kind: capture.FaultCorrupt, Offset: 3},
+				capture.Fault{Record: i, Kind: capture.FaultTruncate, Offset: len(c.Records[i].Data) - 2},
+			)
+		}
+	}
+
+	sc, _, result, err := replaySession(t, opts)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if !sc.IsConnected() {
+		t.Fatalf("expected client to stay connected")
+	}
+	if texts, _, _ := result.counts(); len(texts) != 0 {
+		t.Fatalf("expected corrupted packets to be dropped, got %q", texts)
+	}
+}
@@ -2,12 +2,14 @@ package client
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/capture"
 	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/serial/discovery"
 )
@@ -27,6 +29,9 @@ type RobustMeshtasticClient struct {
 
 	// Heartbeat
 	heartbeatManager *HeartbeatManager
+
+	// Session capture, if recording
+	capture *capture.Writer
 }
 
 // NewRobustMeshtasticClient creates a new robust Meshtastic client
@@ -40,6 +45,17 @@ func NewRobustMeshtasticClient(config *Config) (*RobustMeshtasticClient, error)
 		return nil, errors.Wrap(err, "invalid configuration")
 	}
 
+	// Networked devices speak the same stream protocol over TCP
+	if config.Host != "" {
+		tcpClient, err := NewTCPClient(config.Host, config.Timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create TCP client")
+		}
+		config.DevicePath = tcpClient.DevicePath()
+
+		return newRobustClient(config, tcpClient, tcpClient.StreamClient)
+	}
+
 	// Create serial configuration
 	serialConfig := &SerialConfig{
 		DevicePath:   config.DevicePath,
@@ -59,17 +75,38 @@ func NewRobustMeshtasticClient(config *Config) (*RobustMeshtasticClient, error)
 		return nil, errors.Wrap(err, "failed to create serial client")
 	}
 
+	return newRobustClient(config, serialClient, serialClient.StreamClient)
+}
+
+// newRobustClient wraps a connected transport and its stream client
+func newRobustClient(config *Config, transport SerialInterface, stream *StreamClient) (*RobustMeshtasticClient, error) {
+	// Record the session if requested
+	var captureWriter *capture.Writer
+	if config.CaptureFile != "" {
+		var err error
+		captureWriter, err = capture.Create(config.CaptureFile, config.DevicePath)
+		if err != nil {
+			transport.Close()
+			return nil, errors.Wrap(err, "failed to create capture")
+		}
+		stream.SetStreamTap(func(s io.ReadWriteCloser) io.ReadWriteCloser {
+			return capture.Tap(s, captureWriter)
+		})
+		log.Info().Str("file", config.CaptureFile).Msg("Recording session")
+	}
+
 	// Create robust client
 	client := &RobustMeshtasticClient{
-		SerialInterface:   serialClient,
+		SerialInterface:   transport,
 		config:            config,
 		stateHandler:      NewDefaultStateHandler(),
-		connectionManager: NewConnectionManager(serialClient),
-		heartbeatManager:  NewHeartbeatManager(serialClient),
+		connectionManager: NewConnectionManager(transport),
+		heartbeatManager:  NewHeartbeatManager(transport),
+		capture:           captureWriter,
 	}
 
 	// Set up state handler
-	serialClient.StreamClient.stateHandler = client.stateHandler
+	stream.stateHandler = client.stateHandler
 
 	// Set up connection manager
 	client.connectionManager.SetOnStateChange(client.stateHandler.OnStateChange)
@@ -138,11 +175,18 @@ func (rmc *RobustMeshtasticClient) Close() error {
 	}
 
 	// Close underlying serial interface
+	var err error
 	if rmc.SerialInterface != nil {
-		return rmc.SerialInterface.Close()
+		err = rmc.SerialInterface.Close()
 	}
 
-	return nil
+	if rmc.capture != nil {
+		if captureErr := rmc.capture.Close(); captureErr != nil {
+			log.Error().Err(captureErr).Msg("Error closing capture")
+		}
+	}
+
+	return err
 }
 
 // DefaultStateHandler handles state transitions
@@ -561,7 +605,7 @@ func ValidateConfig(config *Config) error {
 		return errors.New("config cannot be nil")
 	}
 
-	if config.DevicePath == "" {
+	if config.DevicePath == "" && config.Host == "" {
 		// Try to auto-discover
 		devicePath, err := discovery.FindBestMeshtasticPort()
 		if err != nil {
@@ -19,6 +19,7 @@ import (
 type StreamClient struct {
 	// Core state
 	stream       io.ReadWriteCloser
+	tap          func(io.ReadWriteCloser) io.ReadWriteCloser
 	parser       *protocol.FrameParser
 	builder      *protocol.FrameBuilder
 	state        DeviceState
@@ -137,6 +138,14 @@ func (sc *StreamClient) Connect(ctx context.Context) error {
 	return nil
 }
 
+// restart replaces the context cancelled by Disconnect, so that Connect can
+// start the loops again
+func (sc *StreamClient) restart() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.ctx, sc.cancel = context.WithCancel(context.Background())
+}
+
 // Disconnect implements MeshInterface
 func (sc *StreamClient) Disconnect() error {
 	log.Info().Str("device", sc.devicePath).Msg("Disconnecting from device")
@@ -262,9 +271,21 @@ func (sc *StreamClient) GetStream() io.ReadWriteCloser {
 func (sc *StreamClient) SetStream(stream io.ReadWriteCloser) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	if sc.tap != nil {
+		stream = sc.tap(stream)
+	}
 	sc.stream = stream
 }
 
+// SetStreamTap wraps the current stream and every stream set after a
+// reconnect, e.g. to record the session
+func (sc *StreamClient) SetStreamTap(tap func(io.ReadWriteCloser) io.ReadWriteCloser) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.tap = tap
+	sc.stream = tap(sc.stream)
+}
+
 // WaitForConfig implements StreamInterface
 func (sc *StreamClient) WaitForConfig(timeout time.Duration) error {
 	sc.configTimeout = NewTimeout(timeout)
@@ -456,6 +477,12 @@ func (sc *StreamClient) readerLoop() {
 		default:
 			n, err := sc.stream.Read(buffer)
 			if err != nil {
+				if sc.ctx.Err() != nil {
+					// Read was interrupted by Disconnect
+					log.Debug().Msg("Reader loop cancelled")
+					return
+				}
+
 				if err == io.EOF {
 					log.Warn().Msg("Device disconnected (EOF)")
 					sc.handleDisconnect(err)
@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTCPPort is the port Meshtastic devices serve the stream API on
+const DefaultTCPPort = 4403
+
+// TCPClient implements SerialInterface over a TCP connection to a networked
+// Meshtastic device (or a simulated one). The device speaks the same framing
+// on port 4403 as it does over serial.
+type TCPClient struct {
+	*StreamClient
+
+	address     string
+	dialTimeout time.Duration
+
+	// connMu guards conn, which reconnects replace while the read loop runs,
+	// and closing, which is set while the client disconnects on purpose so
+	// the read error caused by closing the socket doesn't trigger a reconnect
+	connMu  sync.Mutex
+	conn    net.Conn
+	closing bool
+
+	// Reconnection handling
+	reconnectAttempts    int
+	maxReconnectAttempts int
+	reconnectDelay       time.Duration
+	backoffMultiplier    float64
+	maxReconnectDelay    time.Duration
+
+	// Connection state
+	connectionStart time.Time
+	lastReconnect   time.Time
+}
+
+// TCPAddress returns host:port for a host that may or may not include a port
+func TCPAddress(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(DefaultTCPPort))
+}
+
+// NewTCPClient dials the device at host (port 4403 unless given) and creates
+// a client on top of the connection
+func NewTCPClient(host string, dialTimeout time.Duration) (*TCPClient, error) {
+	if host == "" {
+		return nil, errors.New("host cannot be empty")
+	}
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
+
+	address := TCPAddress(host)
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", address)
+	}
+
+	return newTCPClient(conn, address, dialTimeout), nil
+}
+
+// onceConn is a net.Conn that can be closed more than once. Disconnect and
+// Close both close the stream, which net.Conn reports as an error.
+type onceConn struct {
+	net.Conn
+	once sync.Once
+	err  error
+}
+
+func (c *onceConn) Close() error {
+	c.once.Do(func() { c.err = c.Conn.Close() })
+	return c.err
+}
+
+func newTCPClient(conn net.Conn, address string, dialTimeout time.Duration) *TCPClient {
+	conn = &onceConn{Conn: conn}
+	tc := &TCPClient{
+		StreamClient:         NewStreamClient(conn, "tcp://"+address),
+		address:              address,
+		dialTimeout:          dialTimeout,
+		conn:                 conn,
+		maxReconnectAttempts: 10,
+		reconnectDelay:       2 * time.Second,
+		backoffMultiplier:    1.5,
+		maxReconnectDelay:    30 * time.Second,
+		connectionStart:      time.Now(),
+	}
+
+	// Initialize message queue
+	tc.messageQueue = NewFlowControlledQueue(100, 10, 30*time.Second)
+
+	// Set up disconnect handler for reconnection
+	tc.SetOnDisconnect(tc.handleDisconnectWithReconnect)
+
+	return tc
+}
+
+// Address returns the host:port the client is connected to
+func (tc *TCPClient) Address() string {
+	return tc.address
+}
+
+// Connect implements SerialInterface. After a Disconnect, the socket is
+// dialed again and reconnects are enabled again.
+func (tc *TCPClient) Connect(ctx context.Context) error {
+	log.Info().Str("address", tc.address).Msg("Connecting to TCP device")
+
+	tc.connMu.Lock()
+	reopen := tc.closing
+	tc.closing = false
+	tc.connMu.Unlock()
+
+	if reopen {
+		if err := tc.dial(); err != nil {
+			return err
+		}
+		tc.restart()
+	}
+
+	tc.connectionStart = time.Now()
+
+	return tc.StreamClient.Connect(ctx)
+}
+
+// Disconnect implements SerialInterface
+func (tc *TCPClient) Disconnect() error {
+	tc.connMu.Lock()
+	tc.closing = true
+	conn := tc.conn
+	tc.connMu.Unlock()
+
+	// Unblock the reader loop, which otherwise waits for data forever
+	if conn != nil {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+
+	return tc.StreamClient.Disconnect()
+}
+
+// Reconnect implements SerialInterface
+func (tc *TCPClient) Reconnect() error {
+	log.Info().
+		Str("address", tc.address).
+		Int("attempt", tc.reconnectAttempts+1).
+		Int("max", tc.maxReconnectAttempts).
+		Msg("Attempting to reconnect")
+
+	if tc.reconnectAttempts >= tc.maxReconnectAttempts {
+		return errors.New("maximum reconnection attempts exceeded")
+	}
+
+	tc.reconnectAttempts++
+	tc.stats.Reconnects++
+	tc.lastReconnect = time.Now()
+
+	delay := time.Duration(float64(tc.reconnectDelay) *
+		(tc.backoffMultiplier * float64(tc.reconnectAttempts)))
+	if delay > tc.maxReconnectDelay {
+		delay = tc.maxReconnectDelay
+	}
+
+	log.Info().
+		Str("address", tc.address).
+		Dur("delay", delay).
+		Msg("Waiting before reconnection attempt")
+
+	time.Sleep(delay)
+
+	tc.connMu.Lock()
+	closing := tc.closing
+	old := tc.conn
+	tc.connMu.Unlock()
+	if closing {
+		return errors.New("client was disconnected while waiting to reconnect")
+	}
+	if old != nil {
+		old.Close()
+	}
+
+	if err := tc.dial(); err != nil {
+		return err
+	}
+	tc.reconnectAttempts = 0
+
+	log.Info().Str("address", tc.address).Msg("Successfully reconnected")
+
+	return nil
+}
+
+// dial opens a new connection to the device and hands it to the stream client
+func (tc *TCPClient) dial() error {
+	conn, err := net.DialTimeout("tcp", tc.address, tc.dialTimeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to reconnect to %s", tc.address)
+	}
+
+	stream := &onceConn{Conn: conn}
+	tc.connMu.Lock()
+	tc.conn = stream
+	tc.connMu.Unlock()
+	tc.SetStream(stream)
+	return nil
+}
+
+// GetReconnectAttempts implements SerialInterface
+func (tc *TCPClient) GetReconnectAttempts() int {
+	return tc.reconnectAttempts
+}
+
+// ResetReconnectAttempts implements SerialInterface
+func (tc *TCPClient) ResetReconnectAttempts() {
+	tc.reconnectAttempts = 0
+}
+
+// GetSerialConfig implements SerialInterface. TCP connections have no serial
+// settings, so only the device path is filled in.
+func (tc *TCPClient) GetSerialConfig() *SerialConfig {
+	return &SerialConfig{DevicePath: tc.DevicePath()}
+}
+
+// SetSerialConfig implements SerialInterface
+func (tc *TCPClient) SetSerialConfig(config *SerialConfig) error {
+	return errors.New("serial configuration is not supported for TCP connections")
+}
+
+// Flush implements SerialInterface. Writes go straight to the socket, so
+// there is nothing to flush.
+func (tc *TCPClient) Flush() error {
+	tc.connMu.Lock()
+	defer tc.connMu.Unlock()
+	if tc.conn == nil {
+		return errors.New("connection not open")
+	}
+	return nil
+}
+
+// GetStatistics implements SerialInterface
+func (tc *TCPClient) GetStatistics() ConnectionStatistics {
+	stats := tc.stats
+	stats.ConnectDuration = time.Since(tc.connectionStart)
+	if !tc.lastReconnect.IsZero() {
+		stats.LastReconnect = tc.lastReconnect
+	}
+	return stats
+}
+
+// Close implements SerialInterface
+func (tc *TCPClient) Close() error {
+	log.Info().Str("address", tc.address).Msg("Closing TCP connection")
+
+	if err := tc.Disconnect(); err != nil {
+		log.Error().Err(err).Msg("Error closing stream client")
+	}
+	tc.connMu.Lock()
+	tc.conn = nil
+	tc.connMu.Unlock()
+
+	return nil
+}
+
+func (tc *TCPClient) handleDisconnectWithReconnect(err error) {
+	tc.connMu.Lock()
+	closing := tc.closing
+	tc.connMu.Unlock()
+	if closing {
+		return
+	}
+
+	log.Warn().Err(err).Str("address", tc.address).Msg("Connection lost, attempting reconnection")
+
+	tc.changeState(StateReconnecting)
+
+	go func() {
+		if err := tc.Reconnect(); err != nil {
+			log.Error().Err(err).Msg("Reconnection failed")
+			tc.changeState(StateError)
+		} else {
+			if err := tc.Connect(tc.ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to restart connection after reconnection")
+				tc.changeState(StateError)
+			}
+		}
+	}()
+}
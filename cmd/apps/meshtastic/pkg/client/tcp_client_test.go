@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/simulator"
+)
+
+func startSimulator(t *testing.T, config *simulator.Config) (*simulator.Node, string) {
+	t.Helper()
+
+	node, err := simulator.NewNode(config)
+	if err != nil {
+		t.Fatalf("failed to create simulator: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go node.Serve(l)
+	t.Cleanup(func() { node.Close() })
+
+	return node, l.Addr().String()
+}
+
+func testSimulatorConfig() *simulator.Config {
+	config := simulator.DefaultConfig()
+	config.MessageDelay = 10 * time.Millisecond
+	config.MessageJitter = 0
+	config.TelemetryInterval = 0
+	config.PositionInterval = 0
+	return config
+}
+
+func TestTCPAddress(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.10":     "192.168.1.10:4403",
+		"meshtastic.local": "meshtastic.local:4403",
+		"localhost:14403":  "localhost:14403",
+		"[fe80::1]:4403":   "[fe80::1]:4403",
+		"fe80::1":          "[fe80::1]:4403",
+	}
+	for host, expected := range tests {
+		if got := TCPAddress(host); got != expected {
+			t.Errorf("TCPAddress(%q) = %q, expected %q", host, got, expected)
+		}
+	}
+}
+
+func TestRobustClientOverTCP(t *testing.T) {
+	config := testSimulatorConfig()
+	node, addr := startSimulator(t, config)
+
+	c, err := NewRobustMeshtasticClient(&Config{Host: addr, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if !strings.HasPrefix(c.DevicePath(), "tcp://") {
+		t.Errorf("unexpected device path %s", c.DevicePath())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	if got := c.GetMyInfo().GetMyNodeNum(); got != config.NodeNum {
+		t.Errorf("expected node num %08x, got %08x", config.NodeNum, got)
+	}
+	nodes := c.GetNodes()
+	if len(nodes) != len(config.Neighbors)+1 {
+		t.Errorf("expected %d nodes, got %d", len(config.Neighbors)+1, len(nodes))
+	}
+	if name := nodes[config.Neighbors[0].NodeNum].GetUser().GetLongName(); name != config.Neighbors[0].LongName {
+		t.Errorf("unexpected neighbor name %q", name)
+	}
+	if c.GetConfig().GetLora() == nil {
+		t.Error("expected LoRa config")
+	}
+
+	replies := make(chan *pb.MeshPacket, 10)
+	c.SetOnMessage(func(packet *pb.MeshPacket) {
+		if packet.GetDecoded().GetPortnum() == pb.PortNum_TEXT_MESSAGE_APP {
+			replies <- packet
+		}
+	})
+
+	target := config.Neighbors[1]
+	if err := c.SendText("hello", target.NodeNum); err != nil {
+		t.Fatalf("failed to send text: %v", err)
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.From != target.NodeNum || reply.To != config.NodeNum {
+			t.Errorf("unexpected reply route %08x -> %08x", reply.From, reply.To)
+		}
+		if text := string(reply.GetDecoded().GetPayload()); text != target.ShortName+": hello" {
+			t.Errorf("unexpected reply %q", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reply")
+	}
+
+	received := node.Received()
+	if len(received) != 1 || string(received[0].GetDecoded().GetPayload()) != "hello" {
+		t.Errorf("simulator did not record the sent packet: %v", received)
+	}
+}
+
+func TestSimulatorBroadcastsTelemetry(t *testing.T) {
+	config := testSimulatorConfig()
+	config.TelemetryInterval = 20 * time.Millisecond
+	config.PositionInterval = 20 * time.Millisecond
+	_, addr := startSimulator(t, config)
+
+	c, err := NewTCPClient(addr, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	telemetry := make(chan *pb.Telemetry, 100)
+	positions := make(chan *pb.Position, 100)
+	c.SetOnTelemetry(func(tm *pb.Telemetry) {
+		select {
+		case telemetry <- tm:
+		default:
+		}
+	})
+	c.SetOnPosition(func(p *pb.Position) {
+		select {
+		case positions <- p:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	select {
+	case tm := <-telemetry:
+		if level := tm.GetDeviceMetrics().GetBatteryLevel(); level == 0 || level > 100 {
+			t.Errorf("unexpected battery level %d", level)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for telemetry")
+	}
+
+	select {
+	case p := <-positions:
+		if p.GetLatitudeI() == 0 || p.GetLongitudeI() == 0 {
+			t.Errorf("expected a position, got %v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for position")
+	}
+}
+
+func TestTCPClientReconnectsAfterDisconnectAndConnect(t *testing.T) {
+	config := testSimulatorConfig()
+	_, addr := startSimulator(t, config)
+
+	c, err := NewTCPClient(addr, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+	c.reconnectDelay = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err := c.Disconnect(); err != nil {
+		t.Fatalf("failed to disconnect: %v", err)
+	}
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect again: %v", err)
+	}
+	if got := c.GetMyInfo().GetMyNodeNum(); got != config.NodeNum {
+		t.Errorf("expected node num %08x, got %08x", config.NodeNum, got)
+	}
+
+	// Drop the socket under the read loop, the client has to reconnect
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.GetStatistics().Reconnects == 0 || !c.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatalf("client did not reconnect, state %v", c.GetStatistics())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
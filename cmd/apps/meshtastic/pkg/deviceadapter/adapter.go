@@ -37,6 +37,10 @@ type DeviceAdapter struct {
 	lastChannels map[uint32]*pb.Channel
 	lastConfig   *pb.LocalConfig
 
+	// Sender of the packet being dispatched. The client calls the message
+	// handler before the position/telemetry handlers for the same packet.
+	lastPacketFrom uint32
+
 	// Statistics
 	stats *Statistics
 }
@@ -75,12 +79,26 @@ func NewDeviceAdapter(id string, client *client.RobustMeshtasticClient, bus *mes
 // Start starts the device adapter
 func (d *DeviceAdapter) Start(ctx context.Context) error {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	if d.running {
+		d.mu.Unlock()
 		return errors.New("adapter is already running")
 	}
+	d.running = true
+	d.mu.Unlock()
+
+	// The lock isn't held from here on: client callbacks fire while connecting
+	// and publishing events takes the lock too
+	if err := d.start(ctx); err != nil {
+		d.mu.Lock()
+		d.running = false
+		d.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
 
+func (d *DeviceAdapter) start(ctx context.Context) error {
 	log.Info().Str("device_id", d.id).Msg("Starting device adapter")
 
 	// Set up client callbacks
@@ -102,8 +120,9 @@ func (d *DeviceAdapter) Start(ctx context.Context) error {
 	// Publish device connected event
 	d.publishDeviceConnected()
 
-	d.running = true
+	d.mu.Lock()
 	d.stats.LastActivity = time.Now()
+	d.mu.Unlock()
 
 	log.Info().Str("device_id", d.id).Msg("Device adapter started successfully")
 
@@ -113,11 +132,12 @@ func (d *DeviceAdapter) Start(ctx context.Context) error {
 // Stop stops the device adapter
 func (d *DeviceAdapter) Stop() error {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	if !d.running {
+		d.mu.Unlock()
 		return nil
 	}
+	d.running = false
+	d.mu.Unlock()
 
 	log.Info().Str("device_id", d.id).Msg("Stopping device adapter")
 
@@ -140,8 +160,6 @@ func (d *DeviceAdapter) Stop() error {
 		log.Error().Err(err).Str("device_id", d.id).Msg("Error closing client")
 	}
 
-	d.running = false
-
 	log.Info().Str("device_id", d.id).Msg("Device adapter stopped")
 
 	return nil
@@ -171,6 +189,7 @@ func (d *DeviceAdapter) setupClientCallbacks() {
 		d.mu.Lock()
 		d.stats.MessagesReceived++
 		d.stats.LastActivity = time.Now()
+		d.lastPacketFrom = packet.From
 		d.mu.Unlock()
 
 		log.Debug().
@@ -209,6 +228,7 @@ func (d *DeviceAdapter) setupClientCallbacks() {
 	d.client.SetOnPosition(func(position *pb.Position) {
 		d.mu.Lock()
 		d.stats.LastActivity = time.Now()
+		nodeID := d.lastPacketFrom
 		d.mu.Unlock()
 
 		log.Debug().
@@ -217,10 +237,6 @@ func (d *DeviceAdapter) setupClientCallbacks() {
 			Int32("lon_i", position.GetLongitudeI()).
 			Msg("Position updated")
 
-		// For position events, we need to determine the node ID
-		// This is a simplified implementation
-		nodeID := uint32(0) // We'd need to track this properly
-
 		// Create and publish event
 		event := events.NewPositionUpdatedEvent(nodeID, position, nil)
 		d.publishEvent(events.EventPositionUpdated, event)
@@ -230,16 +246,13 @@ func (d *DeviceAdapter) setupClientCallbacks() {
 	d.client.SetOnTelemetry(func(telemetry *pb.Telemetry) {
 		d.mu.Lock()
 		d.stats.LastActivity = time.Now()
+		nodeID := d.lastPacketFrom
 		d.mu.Unlock()
 
 		log.Debug().
 			Str("device_id", d.id).
 			Msg("Telemetry received")
 
-		// For telemetry events, we need to determine the node ID
-		// This is a simplified implementation
-		nodeID := uint32(0) // We'd need to track this properly
-
 		// Create and publish event
 		event := events.NewTelemetryReceivedEvent(nodeID, telemetry)
 		d.publishEvent(events.EventTelemetryReceived, event)
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+)
+
+// encoding/json can write protobuf messages but can't read back oneof fields
+// such as MeshPacket.PayloadVariant or Telemetry.Variant. Events carrying
+// those messages encode them with protojson so subscribers can decode them.
+
+var protoMarshaler = protojson.MarshalOptions{UseProtoNames: true}
+var protoUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+func marshalProto(m proto.Message) (json.RawMessage, error) {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return json.RawMessage("null"), nil
+	}
+	data, err := protoMarshaler.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal protobuf message")
+	}
+	return data, nil
+}
+
+// unmarshalProto decodes data into m and reports whether data held a message
+func unmarshalProto(data json.RawMessage, m proto.Message) (bool, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return false, nil
+	}
+	if err := protoUnmarshaler.Unmarshal(data, m); err != nil {
+		return false, errors.Wrap(err, "failed to unmarshal protobuf message")
+	}
+	return true, nil
+}
+
+func unmarshalPacket(data json.RawMessage) (*pb.MeshPacket, error) {
+	packet := &pb.MeshPacket{}
+	ok, err := unmarshalProto(data, packet)
+	if !ok {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (e MeshPacketRxEvent) MarshalJSON() ([]byte, error) {
+	type alias MeshPacketRxEvent
+	packet, err := marshalProto(e.Packet)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		alias
+		Packet json.RawMessage `json:"packet"`
+	}{alias(e), packet})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (e *MeshPacketRxEvent) UnmarshalJSON(data []byte) error {
+	type alias MeshPacketRxEvent
+	aux := struct {
+		*alias
+		Packet json.RawMessage `json:"packet"`
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	packet, err := unmarshalPacket(aux.Packet)
+	e.Packet = packet
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (e MeshPacketTxEvent) MarshalJSON() ([]byte, error) {
+	type alias MeshPacketTxEvent
+	packet, err := marshalProto(e.Packet)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		alias
+		Packet json.RawMessage `json:"packet"`
+	}{alias(e), packet})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (e *MeshPacketTxEvent) UnmarshalJSON(data []byte) error {
+	type alias MeshPacketTxEvent
+	aux := struct {
+		*alias
+		Packet json.RawMessage `json:"packet"`
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	packet, err := unmarshalPacket(aux.Packet)
+	e.Packet = packet
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (e TelemetryReceivedEvent) MarshalJSON() ([]byte, error) {
+	type alias TelemetryReceivedEvent
+	telemetry, err := marshalProto(e.Telemetry)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		alias
+		Telemetry json.RawMessage `json:"telemetry"`
+	}{alias(e), telemetry})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (e *TelemetryReceivedEvent) UnmarshalJSON(data []byte) error {
+	type alias TelemetryReceivedEvent
+	aux := struct {
+		*alias
+		Telemetry json.RawMessage `json:"telemetry"`
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	telemetry := &pb.Telemetry{}
+	ok, err := unmarshalProto(aux.Telemetry, telemetry)
+	if ok {
+		e.Telemetry = telemetry
+	}
+	return err
+}
@@ -181,6 +181,13 @@ func (b *Bus) AddHandler(handlerName, subscribeTopic string, handler message.NoP
 	// Mark handler as added
 	b.handlers[handlerName] = true
 
+	// Handlers added after the router started aren't picked up until they are run
+	if b.running {
+		if err := b.router.RunHandlers(b.ctx); err != nil {
+			return errors.Wrap(err, "failed to run handler")
+		}
+	}
+
 	return nil
 }
 
@@ -213,6 +220,13 @@ func (b *Bus) AddRouterHandler(handlerName, subscribeTopic, publishTopic string,
 	// Mark handler as added
 	b.handlers[handlerName] = true
 
+	// Handlers added after the router started aren't picked up until they are run
+	if b.running {
+		if err := b.router.RunHandlers(b.ctx); err != nil {
+			return errors.Wrap(err, "failed to run handler")
+		}
+	}
+
 	return nil
 }
 
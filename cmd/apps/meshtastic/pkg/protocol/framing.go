@@ -389,6 +389,23 @@ func (fb *FrameBuilder) BuildFrame(toRadio *pb.ToRadio) ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to marshal ToRadio message")
 	}
 
+	return fb.buildFrame(payload)
+}
+
+// BuildFromRadioFrame builds a frame from a FromRadio message, as sent by a
+// device to its client. Used by simulated devices.
+func (fb *FrameBuilder) BuildFromRadioFrame(fromRadio *pb.FromRadio) ([]byte, error) {
+	payload, err := proto.Marshal(fromRadio)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal FromRadio message")
+	}
+
+	return fb.buildFrame(payload)
+}
+
+// buildFrame wraps a marshaled payload in a frame header
+func (fb *FrameBuilder) buildFrame(payload []byte) ([]byte, error) {
+
 	if len(payload) > MAX_PAYLOAD_SIZE {
 		return nil, errors.Errorf("payload too large: %d bytes (max %d)", len(payload), MAX_PAYLOAD_SIZE)
 	}
@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Neighbor describes a virtual node the simulated device can hear
+type Neighbor struct {
+	NodeNum   uint32  `yaml:"node_num"`
+	LongName  string  `yaml:"long_name"`
+	ShortName string  `yaml:"short_name"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+	Altitude  int32   `yaml:"altitude"`
+	SNR       float32 `yaml:"snr"`
+	Battery   uint32  `yaml:"battery"`
+	HopsAway  uint32  `yaml:"hops_away"`
+}
+
+// Config describes the simulated device and the mesh around it
+type Config struct {
+	NodeNum   uint32  `yaml:"node_num"`
+	LongName  string  `yaml:"long_name"`
+	ShortName string  `yaml:"short_name"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+	Altitude  int32   `yaml:"altitude"`
+
+	Neighbors []Neighbor `yaml:"neighbors"`
+
+	// How often every neighbor broadcasts device metrics and its position.
+	// Zero disables the broadcasts.
+	TelemetryInterval time.Duration `yaml:"telemetry_interval"`
+	PositionInterval  time.Duration `yaml:"position_interval"`
+
+	// Latency of packets from neighbors, plus up to MessageJitter of noise
+	MessageDelay  time.Duration `yaml:"message_delay"`
+	MessageJitter time.Duration `yaml:"message_jitter"`
+
+	// Echo makes neighbors answer text messages sent by the client
+	Echo bool `yaml:"echo"`
+
+	// PositionDrift is the maximum distance in degrees a neighbor moves
+	// between position broadcasts
+	PositionDrift float64 `yaml:"position_drift"`
+
+	Seed int64 `yaml:"seed"`
+}
+
+// DefaultConfig returns a node with three neighbors around it
+func DefaultConfig() *Config {
+	config := &Config{
+		NodeNum:           0x5107a001,
+		LongName:          "Simulated Node",
+		ShortName:         "SIM",
+		Latitude:          37.7749,
+		Longitude:         -122.4194,
+		Altitude:          16,
+		TelemetryInterval: 30 * time.Second,
+		PositionInterval:  60 * time.Second,
+		MessageDelay:      500 * time.Millisecond,
+		MessageJitter:     250 * time.Millisecond,
+		Echo:              true,
+		PositionDrift:     0.0005,
+		Seed:              1,
+	}
+	config.Neighbors = GenerateNeighbors(config, 3)
+	return config
+}
+
+// GenerateNeighbors places count neighbors on a circle of about 1km around
+// the configured node, with SNR falling off for the farther hops
+func GenerateNeighbors(config *Config, count int) []Neighbor {
+	neighbors := make([]Neighbor, 0, count)
+	for i := 0; i < count; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(count)
+		hops := uint32(i / 4)
+		neighbors = append(neighbors, Neighbor{
+			NodeNum:   config.NodeNum + uint32(i) + 1,
+			LongName:  fmt.Sprintf("Virtual Node %d", i+1),
+			ShortName: fmt.Sprintf("V%d", i+1),
+			Latitude:  config.Latitude + 0.01*math.Sin(angle),
+			Longitude: config.Longitude + 0.01*math.Cos(angle),
+			Altitude:  config.Altitude + int32(i*5),
+			SNR:       10 - float32(i)*2.5,
+			Battery:   uint32(100 - (i*15)%60),
+			HopsAway:  hops,
+		})
+	}
+	return neighbors
+}
+
+// LoadConfig reads a YAML configuration on top of DefaultConfig. Neighbors
+// listed in the file replace the default ones.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read simulator config")
+	}
+
+	config := DefaultConfig()
+	config.Neighbors = nil
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse simulator config")
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the configuration for duplicate or missing node numbers
+func (c *Config) Validate() error {
+	if c.NodeNum == 0 {
+		return errors.New("node_num must be set")
+	}
+
+	seen := map[uint32]bool{c.NodeNum: true}
+	for i, n := range c.Neighbors {
+		if n.NodeNum == 0 {
+			return errors.Errorf("neighbor %d: node_num must be set", i)
+		}
+		if seen[n.NodeNum] {
+			return errors.Errorf("neighbor %d: duplicate node_num !%08x", i, n.NodeNum)
+		}
+		seen[n.NodeNum] = true
+	}
+
+	if c.TelemetryInterval < 0 || c.PositionInterval < 0 || c.MessageDelay < 0 || c.MessageJitter < 0 {
+		return errors.New("intervals and delays cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,601 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/protocol"
+)
+
+const broadcastNum = 0xFFFFFFFF
+
+// neighborState is the part of a neighbor that changes while simulating
+type neighborState struct {
+	Neighbor
+	battery float64
+}
+
+// Node is a software Meshtastic device. It speaks the stream protocol over
+// any connection (TCP on port 4403 like networked devices, or an in-memory
+// pipe), answers want_config with its node database and config, and relays
+// traffic from virtual neighbors: telemetry, positions and replies to text
+// messages.
+type Node struct {
+	config  *Config
+	builder *protocol.FrameBuilder
+	started time.Time
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	neighbors []*neighborState
+	sessions  map[*session]struct{}
+	listeners []net.Listener
+	received  []*pb.MeshPacket
+	packetID  uint32
+	closed    bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNode creates a simulated node and starts its neighbors' broadcasts
+func NewNode(config *Config) (*Node, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid simulator config")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Node{
+		config:   config,
+		builder:  protocol.NewFrameBuilder(),
+		started:  time.Now(),
+		rng:      rand.New(rand.NewSource(config.Seed)),
+		sessions: make(map[*session]struct{}),
+		packetID: uint32(config.Seed) + 1,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, neighbor := range config.Neighbors {
+		n.neighbors = append(n.neighbors, &neighborState{Neighbor: neighbor, battery: float64(neighbor.Battery)})
+	}
+
+	n.wg.Add(1)
+	go n.broadcastLoop()
+
+	return n, nil
+}
+
+// NodeNum returns the node number of the simulated device
+func (n *Node) NodeNum() uint32 {
+	return n.config.NodeNum
+}
+
+// ListenAndServe listens on addr (e.g. ":4403") and serves clients until
+// the node is closed
+func (n *Node) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", addr)
+	}
+	return n.Serve(l)
+}
+
+// Serve accepts client connections on l until l or the node is closed
+func (n *Node) Serve(l net.Listener) error {
+	n.mu.Lock()
+	n.listeners = append(n.listeners, l)
+	n.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if n.ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "accept failed")
+		}
+
+		log.Info().Str("remote", conn.RemoteAddr().String()).Msg("Simulator client connected")
+		go n.ServeConn(conn)
+	}
+}
+
+// Pipe returns the client end of an in-memory connection to the node
+func (n *Node) Pipe() net.Conn {
+	clientConn, nodeConn := net.Pipe()
+	go n.ServeConn(nodeConn)
+	return clientConn
+}
+
+// ServeConn runs a client session on conn until it is closed
+func (n *Node) ServeConn(conn io.ReadWriteCloser) {
+	s := &session{node: n, conn: conn}
+	s.parser = protocol.NewFrameParser(s.handleFrame, nil)
+
+	n.mu.Lock()
+	n.sessions[s] = struct{}{}
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.sessions, s)
+		n.mu.Unlock()
+		conn.Close()
+	}()
+
+	buffer := make([]byte, 1024)
+	for {
+		count, err := conn.Read(buffer)
+		if count > 0 {
+			s.parser.ProcessBytes(buffer[:count])
+		}
+		if err != nil {
+			if err != io.EOF && n.ctx.Err() == nil {
+				log.Debug().Err(err).Msg("Simulator session ended")
+			}
+			return
+		}
+		if s.isClosed() {
+			return
+		}
+	}
+}
+
+// Close stops the broadcasts and disconnects all clients
+func (n *Node) Close() error {
+	n.cancel()
+
+	n.mu.Lock()
+	n.closed = true
+	for _, l := range n.listeners {
+		l.Close()
+	}
+	for s := range n.sessions {
+		s.conn.Close()
+	}
+	n.mu.Unlock()
+
+	n.wg.Wait()
+	return nil
+}
+
+// Received returns the packets sent by clients, in order
+func (n *Node) Received() []*pb.MeshPacket {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*pb.MeshPacket(nil), n.received...)
+}
+
+// InjectText delivers a text message from a neighbor to all clients after
+// the configured message delay. to may be the simulated node or broadcast.
+func (n *Node) InjectText(from, to uint32, text string) error {
+	if n.neighbor(from) == nil {
+		return errors.Errorf("unknown neighbor !%08x", from)
+	}
+	n.deliverLater(n.textPacket(from, to, text))
+	return nil
+}
+
+func (n *Node) neighbor(num uint32) *neighborState {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, neighbor := range n.neighbors {
+		if neighbor.NodeNum == num {
+			return neighbor
+		}
+	}
+	return nil
+}
+
+func (n *Node) nextPacketID() uint32 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.packetID++
+	return n.packetID
+}
+
+// delay returns the message delay plus random jitter
+func (n *Node) delay() time.Duration {
+	d := n.config.MessageDelay
+	if n.config.MessageJitter > 0 {
+		n.mu.Lock()
+		d += time.Duration(n.rng.Int63n(int64(n.config.MessageJitter)))
+		n.mu.Unlock()
+	}
+	return d
+}
+
+// deliverLater sends a packet to all clients after the message delay
+func (n *Node) deliverLater(packet *pb.MeshPacket) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(n.delay()):
+		}
+		n.deliver(packet)
+	}()
+}
+
+// deliver sends a packet to all clients that finished the config handshake
+func (n *Node) deliver(packet *pb.MeshPacket) {
+	fromRadio := &pb.FromRadio{
+		Id:             n.nextPacketID(),
+		PayloadVariant: &pb.FromRadio_Packet{Packet: packet},
+	}
+
+	n.mu.Lock()
+	sessions := make([]*session, 0, len(n.sessions))
+	for s := range n.sessions {
+		sessions = append(sessions, s)
+	}
+	n.mu.Unlock()
+
+	for _, s := range sessions {
+		if !s.isConfigured() {
+			continue
+		}
+		if err := s.send(fromRadio); err != nil {
+			log.Debug().Err(err).Msg("Failed to deliver packet to simulator client")
+		}
+	}
+}
+
+func (n *Node) handlePacket(packet *pb.MeshPacket) {
+	if packet.From == 0 {
+		packet.From = n.config.NodeNum
+	}
+
+	n.mu.Lock()
+	n.received = append(n.received, packet)
+	n.mu.Unlock()
+
+	decoded := packet.GetDecoded()
+	log.Debug().
+		Uint32("to", packet.To).
+		Str("port", decoded.GetPortnum().String()).
+		Msg("Simulator received packet")
+
+	if decoded.GetPortnum() != pb.PortNum_TEXT_MESSAGE_APP || !n.config.Echo {
+		return
+	}
+
+	text := string(decoded.GetPayload())
+	for _, neighbor := range n.responders(packet.To) {
+		reply := n.textPacket(neighbor.NodeNum, packet.To, fmt.Sprintf("%s: %s", neighbor.ShortName, text))
+		if packet.To != broadcastNum {
+			reply.To = n.config.NodeNum
+		}
+		n.deliverLater(reply)
+	}
+}
+
+// responders returns the neighbors that answer a text message sent to "to"
+func (n *Node) responders(to uint32) []Neighbor {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var result []Neighbor
+	for _, neighbor := range n.neighbors {
+		if to == broadcastNum || to == neighbor.NodeNum {
+			result = append(result, neighbor.Neighbor)
+		}
+	}
+	return result
+}
+
+func (n *Node) broadcastLoop() {
+	defer n.wg.Done()
+
+	var telemetry, position <-chan time.Time
+	if n.config.TelemetryInterval > 0 {
+		ticker := time.NewTicker(n.config.TelemetryInterval)
+		defer ticker.Stop()
+		telemetry = ticker.C
+	}
+	if n.config.PositionInterval > 0 {
+		ticker := time.NewTicker(n.config.PositionInterval)
+		defer ticker.Stop()
+		position = ticker.C
+	}
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-telemetry:
+			for _, packet := range n.telemetryPackets() {
+				n.deliverLater(packet)
+			}
+		case <-position:
+			for _, packet := range n.positionPackets() {
+				n.deliverLater(packet)
+			}
+		}
+	}
+}
+
+// telemetryPackets drains the neighbors' batteries a little and returns
+// their device metrics broadcasts
+func (n *Node) telemetryPackets() []*pb.MeshPacket {
+	n.mu.Lock()
+	metrics := make(map[uint32]*pb.DeviceMetrics, len(n.neighbors))
+	var order []uint32
+	for _, neighbor := range n.neighbors {
+		neighbor.battery -= n.rng.Float64() * 0.5
+		if neighbor.battery < 5 {
+			neighbor.battery = 100
+		}
+		metrics[neighbor.NodeNum] = n.deviceMetrics(neighbor)
+		order = append(order, neighbor.NodeNum)
+	}
+	n.mu.Unlock()
+
+	packets := make([]*pb.MeshPacket, 0, len(order))
+	for _, num := range order {
+		telemetry := &pb.Telemetry{
+			Time:    uint32(time.Now().Unix()),
+			Variant: &pb.Telemetry_DeviceMetrics{DeviceMetrics: metrics[num]},
+		}
+		packets = append(packets, n.dataPacket(num, broadcastNum, pb.PortNum_TELEMETRY_APP, mustMarshal(telemetry)))
+	}
+	return packets
+}
+
+// positionPackets moves every neighbor a little and returns their position
+// broadcasts
+func (n *Node) positionPackets() []*pb.MeshPacket {
+	n.mu.Lock()
+	positions := make(map[uint32]*pb.Position, len(n.neighbors))
+	var order []uint32
+	for _, neighbor := range n.neighbors {
+		drift := n.config.PositionDrift
+		neighbor.Latitude += (n.rng.Float64()*2 - 1) * drift
+		neighbor.Longitude += (n.rng.Float64()*2 - 1) * drift
+		positions[neighbor.NodeNum] = position(neighbor.Latitude, neighbor.Longitude, neighbor.Altitude)
+		order = append(order, neighbor.NodeNum)
+	}
+	n.mu.Unlock()
+
+	packets := make([]*pb.MeshPacket, 0, len(order))
+	for _, num := range order {
+		packets = append(packets, n.dataPacket(num, broadcastNum, pb.PortNum_POSITION_APP, mustMarshal(positions[num])))
+	}
+	return packets
+}
+
+// deviceMetrics must be called with n.mu held
+func (n *Node) deviceMetrics(neighbor *neighborState) *pb.DeviceMetrics {
+	battery := uint32(neighbor.battery)
+	voltage := float32(3.3 + neighbor.battery/100*0.9)
+	channelUtil := float32(5 + n.rng.Float64()*20)
+	airUtil := float32(n.rng.Float64() * 5)
+	uptime := uint32(time.Since(n.started).Seconds())
+	return &pb.DeviceMetrics{
+		BatteryLevel:       &battery,
+		Voltage:            &voltage,
+		ChannelUtilization: &channelUtil,
+		AirUtilTx:          &airUtil,
+		UptimeSeconds:      &uptime,
+	}
+}
+
+func (n *Node) textPacket(from, to uint32, text string) *pb.MeshPacket {
+	return n.dataPacket(from, to, pb.PortNum_TEXT_MESSAGE_APP, []byte(text))
+}
+
+func (n *Node) dataPacket(from, to uint32, port pb.PortNum, payload []byte) *pb.MeshPacket {
+	var snr float32
+	var hops uint32
+	if neighbor := n.neighbor(from); neighbor != nil {
+		snr = neighbor.SNR
+		hops = neighbor.HopsAway
+	}
+	if hops > 3 {
+		hops = 3
+	}
+
+	return &pb.MeshPacket{
+		From:     from,
+		To:       to,
+		Id:       n.nextPacketID(),
+		RxTime:   uint32(time.Now().Unix()),
+		RxSnr:    snr,
+		RxRssi:   int32(-110 + snr*3),
+		HopStart: 3,
+		HopLimit: 3 - hops,
+		PayloadVariant: &pb.MeshPacket_Decoded{
+			Decoded: &pb.Data{
+				Portnum: port,
+				Payload: payload,
+			},
+		},
+	}
+}
+
+// configMessages returns the messages answering a want_config request
+func (n *Node) configMessages(configID uint32) []*pb.FromRadio {
+	now := uint32(time.Now().Unix())
+	messages := []*pb.FromRadio{
+		{PayloadVariant: &pb.FromRadio_MyInfo{MyInfo: &pb.MyNodeInfo{
+			MyNodeNum:     n.config.NodeNum,
+			MinAppVersion: 30200,
+			PioEnv:        "simulator",
+		}}},
+		{PayloadVariant: &pb.FromRadio_NodeInfo{NodeInfo: &pb.NodeInfo{
+			Num:       n.config.NodeNum,
+			User:      user(n.config.NodeNum, n.config.LongName, n.config.ShortName),
+			Position:  position(n.config.Latitude, n.config.Longitude, n.config.Altitude),
+			LastHeard: now,
+		}}},
+	}
+
+	n.mu.Lock()
+	for _, neighbor := range n.neighbors {
+		hops := neighbor.HopsAway
+		messages = append(messages, &pb.FromRadio{PayloadVariant: &pb.FromRadio_NodeInfo{NodeInfo: &pb.NodeInfo{
+			Num:           neighbor.NodeNum,
+			User:          user(neighbor.NodeNum, neighbor.LongName, neighbor.ShortName),
+			Position:      position(neighbor.Latitude, neighbor.Longitude, neighbor.Altitude),
+			Snr:           neighbor.SNR,
+			LastHeard:     now,
+			DeviceMetrics: n.deviceMetrics(neighbor),
+			HopsAway:      &hops,
+		}}})
+	}
+	n.mu.Unlock()
+
+	messages = append(messages,
+		&pb.FromRadio{PayloadVariant: &pb.FromRadio_Config{Config: &pb.Config{
+			PayloadVariant: &pb.Config_Device{Device: &pb.Config_DeviceConfig{
+				Role: pb.Config_DeviceConfig_CLIENT,
+			}},
+		}}},
+		&pb.FromRadio{PayloadVariant: &pb.FromRadio_Config{Config: &pb.Config{
+			PayloadVariant: &pb.Config_Lora{Lora: &pb.Config_LoRaConfig{
+				UsePreset:   true,
+				ModemPreset: pb.Config_LoRaConfig_LONG_FAST,
+				Region:      pb.Config_LoRaConfig_US,
+				HopLimit:    3,
+				TxEnabled:   true,
+			}},
+		}}},
+		&pb.FromRadio{PayloadVariant: &pb.FromRadio_Channel{Channel: &pb.Channel{
+			Index:    0,
+			Role:     pb.Channel_PRIMARY,
+			Settings: &pb.ChannelSettings{Psk: []byte{1}},
+		}}},
+		&pb.FromRadio{PayloadVariant: &pb.FromRadio_ConfigCompleteId{ConfigCompleteId: configID}},
+	)
+
+	for _, m := range messages {
+		m.Id = n.nextPacketID()
+	}
+	return messages
+}
+
+func user(num uint32, longName, shortName string) *pb.User {
+	return &pb.User{
+		Id:        fmt.Sprintf("!%08x", num),
+		LongName:  longName,
+		ShortName: shortName,
+		HwModel:   pb.HardwareModel_PORTDUINO,
+	}
+}
+
+func position(lat, lon float64, alt int32) *pb.Position {
+	latI := int32(lat * 1e7)
+	lonI := int32(lon * 1e7)
+	return &pb.Position{
+		LatitudeI:  &latI,
+		LongitudeI: &lonI,
+		Altitude:   &alt,
+		Time:       uint32(time.Now().Unix()),
+	}
+}
+
+func mustMarshal(msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// session is one client connected to the node
+type session struct {
+	node   *Node
+	conn   io.ReadWriteCloser
+	parser *protocol.FrameParser
+
+	mu         sync.Mutex
+	configured bool
+	closed     bool
+}
+
+func (s *session) isConfigured() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.configured
+}
+
+func (s *session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// send writes a single frame; the lock keeps concurrent frames from interleaving
+func (s *session) send(fromRadio *pb.FromRadio) error {
+	frame, err := s.node.builder.BuildFromRadioFrame(fromRadio)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("session closed")
+	}
+	_, err = s.conn.Write(frame)
+	return err
+}
+
+func (s *session) handleFrame(frame *protocol.Frame) {
+	toRadio, err := frame.ToRadioMessage()
+	if err != nil {
+		log.Warn().Err(err).Msg("Simulator received invalid ToRadio frame")
+		return
+	}
+
+	switch payload := toRadio.PayloadVariant.(type) {
+	case *pb.ToRadio_WantConfigId:
+		// Write from a separate goroutine: on synchronous pipes the client
+		// may still be writing while we answer
+		go s.sendConfig(payload.WantConfigId)
+	case *pb.ToRadio_Packet:
+		s.node.handlePacket(payload.Packet)
+	case *pb.ToRadio_Disconnect:
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+	case *pb.ToRadio_Heartbeat:
+	default:
+		log.Debug().Str("type", fmt.Sprintf("%T", payload)).Msg("Simulator ignored ToRadio message")
+	}
+}
+
+func (s *session) sendConfig(configID uint32) {
+	for _, m := range s.node.configMessages(configID) {
+		if err := s.send(m); err != nil {
+			log.Debug().Err(err).Msg("Failed to send config to simulator client")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.configured = true
+	s.mu.Unlock()
+}
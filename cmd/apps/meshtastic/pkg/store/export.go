@@ -0,0 +1,236 @@
+package store
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// groupByNode splits positions into per-node tracks, keeping the order in
+// which nodes first appear
+func groupByNode(positions []Position) ([]uint32, map[uint32][]Position) {
+	var order []uint32
+	tracks := make(map[uint32][]Position)
+	for _, p := range positions {
+		if _, ok := tracks[p.NodeNum]; !ok {
+			order = append(order, p.NodeNum)
+		}
+		tracks[p.NodeNum] = append(tracks[p.NodeNum], p)
+	}
+	return order, tracks
+}
+
+func nodeName(names map[uint32]string, num uint32) string {
+	if name, ok := names[num]; ok && name != "" {
+		return name
+	}
+	return NodeID(num)
+}
+
+type gpxDocument struct {
+	XMLName xml.Name   `xml:"gpx"`
+	Version string     `xml:"version,attr"`
+	Creator string     `xml:"creator,attr"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	Tracks  []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Desc     string       `xml:"desc"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Time string   `xml:"time"`
+	Sat  uint32   `xml:"sat,omitempty"`
+}
+
+// WriteGPX writes positions as a GPX document with one track per node
+func WriteGPX(w io.Writer, positions []Position, names map[uint32]string) error {
+	doc := gpxDocument{
+		Version: "1.1",
+		Creator: "meshtastic",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+	}
+
+	order, tracks := groupByNode(positions)
+	for _, num := range order {
+		segment := gpxSegment{}
+		for _, p := range tracks[num] {
+			point := gpxPoint{
+				Lat:  p.Latitude,
+				Lon:  p.Longitude,
+				Time: p.Time().UTC().Format(time.RFC3339),
+				Sat:  p.SatsInView,
+			}
+			if p.Altitude != nil {
+				ele := float64(*p.Altitude)
+				point.Ele = &ele
+			}
+			segment.Points = append(segment.Points, point)
+		}
+		doc.Tracks = append(doc.Tracks, gpxTrack{
+			Name:     nodeName(names, num),
+			Desc:     NodeID(num),
+			Segments: []gpxSegment{segment},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "failed to write GPX")
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return errors.Wrap(err, "failed to encode GPX")
+	}
+	_, err := io.WriteString(w, "\n")
+	return errors.Wrap(err, "failed to write GPX")
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONCoordinates returns [lon, lat] or [lon, lat, alt]
+func geoJSONCoordinates(p Position) []float64 {
+	if p.Altitude != nil {
+		return []float64{p.Longitude, p.Latitude, float64(*p.Altitude)}
+	}
+	return []float64{p.Longitude, p.Latitude}
+}
+
+// WriteGeoJSON writes positions as a GeoJSON FeatureCollection. Each node gets
+// a Point feature for its latest position and, if it moved, a LineString
+// feature for its track.
+func WriteGeoJSON(w io.Writer, positions []Position, names map[uint32]string) error {
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+
+	order, tracks := groupByNode(positions)
+	for _, num := range order {
+		track := tracks[num]
+		last := track[len(track)-1]
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: geoJSONCoordinates(last),
+			},
+			Properties: map[string]interface{}{
+				"node_id": NodeID(num),
+				"name":    nodeName(names, num),
+				"time":    last.Time().UTC().Format(time.RFC3339),
+			},
+		})
+
+		if len(track) < 2 {
+			continue
+		}
+
+		coordinates := make([][]float64, 0, len(track))
+		times := make([]string, 0, len(track))
+		for _, p := range track {
+			coordinates = append(coordinates, geoJSONCoordinates(p))
+			times = append(times, p.Time().UTC().Format(time.RFC3339))
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: coordinates,
+			},
+			Properties: map[string]interface{}{
+				"node_id": NodeID(num),
+				"name":    nodeName(names, num),
+				"times":   times,
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(collection), "failed to encode GeoJSON")
+}
+
+var telemetryCSVHeader = []string{
+	"time", "node_id", "name", "kind",
+	"battery_level", "voltage", "channel_utilization", "air_util_tx", "uptime_seconds",
+	"temperature", "relative_humidity", "barometric_pressure", "gas_resistance", "iaq",
+}
+
+func formatUint(v *uint32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*v), 10)
+}
+
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 32)
+}
+
+// WriteTelemetryCSV writes telemetry as a CSV time series with one row per
+// report. Metrics a report didn't include are left empty.
+func WriteTelemetryCSV(w io.Writer, points []TelemetryPoint, names map[uint32]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(telemetryCSVHeader); err != nil {
+		return errors.Wrap(err, "failed to write CSV header")
+	}
+
+	for _, t := range points {
+		record := []string{
+			t.ReceivedAt.UTC().Format(time.RFC3339),
+			NodeID(t.NodeNum),
+			nodeName(names, t.NodeNum),
+			t.Kind,
+			formatUint(t.BatteryLevel),
+			formatFloat(t.Voltage),
+			formatFloat(t.ChannelUtilization),
+			formatFloat(t.AirUtilTx),
+			formatUint(t.UptimeSeconds),
+			formatFloat(t.Temperature),
+			formatFloat(t.RelativeHumidity),
+			formatFloat(t.BarometricPressure),
+			formatFloat(t.GasResistance),
+			formatUint(t.IAQ),
+		}
+		if err := cw.Write(record); err != nil {
+			return errors.Wrap(err, "failed to write CSV record")
+		}
+	}
+
+	cw.Flush()
+	return errors.Wrap(cw.Error(), "failed to write CSV")
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Filter narrows history queries. Zero values match everything.
+type Filter struct {
+	Node     uint32  // sender or recipient for messages, reporting node otherwise
+	Channel  *uint32 // channel index, messages only
+	Since    time.Time
+	Until    time.Time
+	TextOnly bool // messages only
+	Limit    int  // most recent entries only
+}
+
+// Message is a stored mesh packet
+type Message struct {
+	ID         int64
+	DeviceID   string
+	Direction  string
+	ReceivedAt time.Time
+	PacketID   uint32
+	From       uint32
+	To         uint32
+	Channel    uint32
+	Port       string
+	Text       string
+	Payload    []byte
+	Encrypted  bool
+	SNR        float64
+	RSSI       int32
+	HopLimit   uint32
+	HopStart   uint32
+}
+
+// Node is the latest stored information about a node
+type Node struct {
+	Num       uint32
+	UserID    string
+	LongName  string
+	ShortName string
+	HwModel   string
+	Role      string
+	SNR       float64
+	HopsAway  uint32
+	FirstSeen time.Time
+	LastHeard time.Time
+}
+
+// DisplayName returns the long name, falling back to the short name and the
+// node ID
+func (n Node) DisplayName() string {
+	if n.LongName != "" {
+		return n.LongName
+	}
+	if n.ShortName != "" {
+		return n.ShortName
+	}
+	return NodeID(n.Num)
+}
+
+// Position is a stored position report
+type Position struct {
+	NodeNum      uint32
+	ReceivedAt   time.Time
+	PositionTime time.Time
+	Latitude     float64
+	Longitude    float64
+	Altitude     *int32
+	SatsInView   uint32
+}
+
+// Time returns the time the position was taken, or when it was received if
+// the node didn't report one
+func (p Position) Time() time.Time {
+	if !p.PositionTime.IsZero() {
+		return p.PositionTime
+	}
+	return p.ReceivedAt
+}
+
+// TelemetryPoint is a stored telemetry report. Metrics the report didn't
+// include are nil.
+type TelemetryPoint struct {
+	NodeNum            uint32
+	ReceivedAt         time.Time
+	Kind               string
+	BatteryLevel       *uint32
+	Voltage            *float64
+	ChannelUtilization *float64
+	AirUtilTx          *float64
+	UptimeSeconds      *uint32
+	Temperature        *float64
+	RelativeHumidity   *float64
+	BarometricPressure *float64
+	GasResistance      *float64
+	IAQ                *uint32
+}
+
+// NodeID formats a node number the way Meshtastic displays it
+func NodeID(num uint32) string {
+	return fmt.Sprintf("!%08x", num)
+}
+
+// whereClause builds the WHERE clause shared by the time series queries
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+func (w *whereClause) timeRange(column string, f Filter) {
+	if !f.Since.IsZero() {
+		w.add(column+" >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		w.add(column+" < ?", f.Until.UTC())
+	}
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// limitedQuery returns the query for the newest limit rows in chronological
+// order, or all rows if limit is zero
+func limitedQuery(query string, limit int) string {
+	if limit <= 0 {
+		return query + " ORDER BY received_at, id"
+	}
+	return "SELECT * FROM (" + query + " ORDER BY received_at DESC, id DESC LIMIT " +
+		strconv.Itoa(limit) + ") ORDER BY received_at, id"
+}
+
+// Messages returns stored packets matching the filter, oldest first
+func (s *Store) Messages(f Filter) ([]Message, error) {
+	w := &whereClause{}
+	if f.Node != 0 {
+		w.add("(from_node = ? OR to_node = ?)", f.Node, f.Node)
+	}
+	if f.Channel != nil {
+		w.add("channel = ?", *f.Channel)
+	}
+	if f.TextOnly {
+		w.add("text IS NOT NULL")
+	}
+	w.timeRange("received_at", f)
+
+	query := limitedQuery(`SELECT id, device_id, direction, received_at, packet_id, from_node, to_node, channel,
+		portnum, text, payload, encrypted, rx_snr, rx_rssi, hop_limit, hop_start FROM packets`+w.String(), f.Limit)
+
+	rows, err := s.db.Query(query, w.args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query messages")
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		var text sql.NullString
+		var snr sql.NullFloat64
+		var rssi, hopLimit, hopStart sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.DeviceID, &m.Direction, &m.ReceivedAt, &m.PacketID, &m.From, &m.To, &m.Channel,
+			&m.Port, &text, &m.Payload, &m.Encrypted, &snr, &rssi, &hopLimit, &hopStart); err != nil {
+			return nil, errors.Wrap(err, "failed to scan message")
+		}
+		m.Text = text.String
+		m.SNR = snr.Float64
+		m.RSSI = int32(rssi.Int64)
+		m.HopLimit = uint32(hopLimit.Int64)
+		m.HopStart = uint32(hopStart.Int64)
+		messages = append(messages, m)
+	}
+	return messages, errors.Wrap(rows.Err(), "failed to read messages")
+}
+
+// Nodes returns all known nodes, most recently heard first
+func (s *Store) Nodes() ([]Node, error) {
+	rows, err := s.db.Query(`SELECT node_num, user_id, long_name, short_name, hw_model, role, snr, hops_away,
+		first_seen, last_heard FROM nodes ORDER BY last_heard DESC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query nodes")
+	}
+	defer rows.Close()
+
+	var nodes []Node
+	for rows.Next() {
+		var n Node
+		var userID, longName, shortName, hwModel, role sql.NullString
+		var snr sql.NullFloat64
+		var hopsAway sql.NullInt64
+		if err := rows.Scan(&n.Num, &userID, &longName, &shortName, &hwModel, &role, &snr, &hopsAway,
+			&n.FirstSeen, &n.LastHeard); err != nil {
+			return nil, errors.Wrap(err, "failed to scan node")
+		}
+		n.UserID = userID.String
+		n.LongName = longName.String
+		n.ShortName = shortName.String
+		n.HwModel = hwModel.String
+		n.Role = role.String
+		n.SNR = snr.Float64
+		n.HopsAway = uint32(hopsAway.Int64)
+		nodes = append(nodes, n)
+	}
+	return nodes, errors.Wrap(rows.Err(), "failed to read nodes")
+}
+
+// NodeNames returns the display name of every known node
+func (s *Store) NodeNames() (map[uint32]string, error) {
+	nodes, err := s.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint32]string, len(nodes))
+	for _, n := range nodes {
+		names[n.Num] = n.DisplayName()
+	}
+	return names, nil
+}
+
+// Positions returns stored positions matching the filter, oldest first
+func (s *Store) Positions(f Filter) ([]Position, error) {
+	w := &whereClause{}
+	if f.Node != 0 {
+		w.add("node_num = ?", f.Node)
+	}
+	w.timeRange("received_at", f)
+
+	query := limitedQuery(`SELECT id, node_num, received_at, position_time, latitude, longitude, altitude,
+		sats_in_view FROM positions`+w.String(), f.Limit)
+
+	rows, err := s.db.Query(query, w.args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query positions")
+	}
+	defer rows.Close()
+
+	var positions []Position
+	for rows.Next() {
+		var p Position
+		var id int64
+		var positionTime sql.NullTime
+		var altitude, sats sql.NullInt64
+		if err := rows.Scan(&id, &p.NodeNum, &p.ReceivedAt, &positionTime, &p.Latitude, &p.Longitude,
+			&altitude, &sats); err != nil {
+			return nil, errors.Wrap(err, "failed to scan position")
+		}
+		p.PositionTime = positionTime.Time
+		if altitude.Valid {
+			alt := int32(altitude.Int64)
+			p.Altitude = &alt
+		}
+		p.SatsInView = uint32(sats.Int64)
+		positions = append(positions, p)
+	}
+	return positions, errors.Wrap(rows.Err(), "failed to read positions")
+}
+
+// Telemetry returns stored telemetry matching the filter, oldest first
+func (s *Store) Telemetry(f Filter) ([]TelemetryPoint, error) {
+	w := &whereClause{}
+	if f.Node != 0 {
+		w.add("node_num = ?", f.Node)
+	}
+	w.timeRange("received_at", f)
+
+	query := limitedQuery(`SELECT id, node_num, received_at, kind, battery_level, voltage, channel_utilization,
+		air_util_tx, uptime_seconds, temperature, relative_humidity, barometric_pressure, gas_resistance, iaq
+		FROM telemetry`+w.String(), f.Limit)
+
+	rows, err := s.db.Query(query, w.args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query telemetry")
+	}
+	defer rows.Close()
+
+	var points []TelemetryPoint
+	for rows.Next() {
+		var t TelemetryPoint
+		var id int64
+		var battery, uptime, iaq sql.NullInt64
+		var voltage, chUtil, airUtil, temp, humidity, pressure, gas sql.NullFloat64
+		if err := rows.Scan(&id, &t.NodeNum, &t.ReceivedAt, &t.Kind, &battery, &voltage, &chUtil, &airUtil, &uptime,
+			&temp, &humidity, &pressure, &gas, &iaq); err != nil {
+			return nil, errors.Wrap(err, "failed to scan telemetry")
+		}
+		t.BatteryLevel = nullUint32(battery)
+		t.Voltage = nullFloat64(voltage)
+		t.ChannelUtilization = nullFloat64(chUtil)
+		t.AirUtilTx = nullFloat64(airUtil)
+		t.UptimeSeconds = nullUint32(uptime)
+		t.Temperature = nullFloat64(temp)
+		t.RelativeHumidity = nullFloat64(humidity)
+		t.BarometricPressure = nullFloat64(pressure)
+		t.GasResistance = nullFloat64(gas)
+		t.IAQ = nullUint32(iaq)
+		points = append(points, t)
+	}
+	return points, errors.Wrap(rows.Err(), "failed to read telemetry")
+}
+
+func nullUint32(v sql.NullInt64) *uint32 {
+	if !v.Valid {
+		return nil
+	}
+	u := uint32(v.Int64)
+	return &u
+}
+
+func nullFloat64(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
@@ -0,0 +1,380 @@
+package store
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/events"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/meshbus"
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS packets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT NOT NULL UNIQUE,
+	device_id TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	received_at DATETIME NOT NULL,
+	packet_id INTEGER NOT NULL,
+	from_node INTEGER NOT NULL,
+	to_node INTEGER NOT NULL,
+	channel INTEGER NOT NULL,
+	portnum TEXT NOT NULL,
+	text TEXT,
+	payload BLOB,
+	encrypted INTEGER NOT NULL DEFAULT 0,
+	rx_snr REAL,
+	rx_rssi INTEGER,
+	hop_limit INTEGER,
+	hop_start INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_packets_received_at ON packets(received_at);
+CREATE INDEX IF NOT EXISTS idx_packets_from_node ON packets(from_node, received_at);
+CREATE INDEX IF NOT EXISTS idx_packets_to_node ON packets(to_node, received_at);
+CREATE INDEX IF NOT EXISTS idx_packets_channel ON packets(channel, received_at);
+
+CREATE TABLE IF NOT EXISTS nodes (
+	node_num INTEGER PRIMARY KEY,
+	user_id TEXT,
+	long_name TEXT,
+	short_name TEXT,
+	hw_model TEXT,
+	role TEXT,
+	snr REAL,
+	hops_away INTEGER,
+	first_seen DATETIME NOT NULL,
+	last_heard DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS positions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT NOT NULL UNIQUE,
+	device_id TEXT NOT NULL,
+	node_num INTEGER NOT NULL,
+	received_at DATETIME NOT NULL,
+	position_time DATETIME,
+	latitude REAL NOT NULL,
+	longitude REAL NOT NULL,
+	altitude INTEGER,
+	sats_in_view INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_positions_node ON positions(node_num, received_at);
+
+CREATE TABLE IF NOT EXISTS telemetry (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT NOT NULL UNIQUE,
+	device_id TEXT NOT NULL,
+	node_num INTEGER NOT NULL,
+	received_at DATETIME NOT NULL,
+	kind TEXT NOT NULL,
+	battery_level INTEGER,
+	voltage REAL,
+	channel_utilization REAL,
+	air_util_tx REAL,
+	uptime_seconds INTEGER,
+	temperature REAL,
+	relative_humidity REAL,
+	barometric_pressure REAL,
+	gas_resistance REAL,
+	iaq INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_telemetry_node ON telemetry(node_num, received_at);
+`
+
+// Store persists mesh events in an SQLite database so history survives
+// restarts of the process that is connected to the device
+type Store struct {
+	db   *sql.DB
+	path string
+
+	// SQLite allows a single writer; bus handlers run concurrently
+	writeMu sync.Mutex
+}
+
+// Open opens (or creates) the database at path and applies the schema
+func Open(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrapf(err, "failed to create directory %s", dir)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database %s", path)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create schema")
+	}
+
+	return &Store{db: db, path: path}, nil
+}
+
+// Path returns the database file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Subscribe registers handlers that persist events from every device on the
+// bus. Handlers should be added before the bus is started.
+func (s *Store) Subscribe(bus *meshbus.Bus) error {
+	eventTypes := []string{
+		events.EventMeshPacketRx,
+		events.EventMeshPacketTx,
+		events.EventNodeInfoUpdated,
+		events.EventPositionUpdated,
+		events.EventTelemetryReceived,
+	}
+
+	for _, eventType := range eventTypes {
+		if err := bus.AddHandler(
+			"store_"+eventType,
+			meshbus.BuildBroadcastTopic(eventType),
+			s.HandleMessage,
+		); err != nil {
+			return errors.Wrapf(err, "failed to add store handler for %s", eventType)
+		}
+	}
+
+	return nil
+}
+
+// HandleMessage persists the event carried by a bus message. Malformed
+// events are logged and dropped so they aren't redelivered forever.
+func (s *Store) HandleMessage(msg *message.Message) error {
+	envelope, err := events.FromJSON(msg.Payload)
+	if err != nil {
+		log.Error().Err(err).Str("message_id", msg.UUID).Msg("Failed to decode event for store")
+		return nil
+	}
+
+	if err := s.Record(envelope); err != nil {
+		log.Error().Err(err).Str("event_type", envelope.Type).Str("event_id", envelope.EventID).Msg("Failed to store event")
+	}
+	return nil
+}
+
+// Record persists a single event envelope. Events of types the store doesn't
+// keep are ignored, and recording the same event twice is a no-op.
+func (s *Store) Record(envelope *events.Envelope) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	switch envelope.Type {
+	case events.EventMeshPacketRx:
+		var event events.MeshPacketRxEvent
+		if err := envelope.GetData(&event); err != nil {
+			return errors.Wrap(err, "failed to decode packet event")
+		}
+		return s.recordPacket(envelope, events.DirectionInbound, event.Packet)
+
+	case events.EventMeshPacketTx:
+		var event events.MeshPacketTxEvent
+		if err := envelope.GetData(&event); err != nil {
+			return errors.Wrap(err, "failed to decode packet event")
+		}
+		return s.recordPacket(envelope, events.DirectionOutbound, event.Packet)
+
+	case events.EventNodeInfoUpdated:
+		var event events.NodeInfoUpdatedEvent
+		if err := envelope.GetData(&event); err != nil {
+			return errors.Wrap(err, "failed to decode node info event")
+		}
+		return s.recordNodeInfo(envelope, event.NodeInfo)
+
+	case events.EventPositionUpdated:
+		var event events.PositionUpdatedEvent
+		if err := envelope.GetData(&event); err != nil {
+			return errors.Wrap(err, "failed to decode position event")
+		}
+		return s.recordPosition(envelope, event.NodeID, event.Position)
+
+	case events.EventTelemetryReceived:
+		var event events.TelemetryReceivedEvent
+		if err := envelope.GetData(&event); err != nil {
+			return errors.Wrap(err, "failed to decode telemetry event")
+		}
+		return s.recordTelemetry(envelope, event.NodeID, event.Telemetry)
+	}
+
+	return nil
+}
+
+func (s *Store) recordPacket(envelope *events.Envelope, direction string, packet *pb.MeshPacket) error {
+	if packet == nil {
+		return errors.New("packet event without packet")
+	}
+
+	port := "ENCRYPTED"
+	var text sql.NullString
+	var payload []byte
+	if decoded := packet.GetDecoded(); decoded != nil {
+		port = decoded.GetPortnum().String()
+		payload = decoded.GetPayload()
+		if decoded.GetPortnum() == pb.PortNum_TEXT_MESSAGE_APP {
+			text = sql.NullString{String: string(payload), Valid: true}
+		}
+	} else {
+		payload = packet.GetEncrypted()
+	}
+
+	var snr, rssi, hopLimit, hopStart interface{}
+	if direction == events.DirectionInbound {
+		snr, rssi = packet.GetRxSnr(), packet.GetRxRssi()
+		hopLimit, hopStart = packet.GetHopLimit(), packet.GetHopStart()
+	}
+
+	receivedAt := envelope.Timestamp.UTC()
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO packets (
+			event_id, device_id, direction, received_at, packet_id, from_node, to_node,
+			channel, portnum, text, payload, encrypted, rx_snr, rx_rssi, hop_limit, hop_start
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		envelope.EventID, envelope.DeviceID, direction, receivedAt, packet.GetId(), packet.GetFrom(), packet.GetTo(),
+		packet.GetChannel(), port, text, payload, packet.GetDecoded() == nil, snr, rssi, hopLimit, hopStart,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert packet")
+	}
+
+	if direction == events.DirectionInbound && packet.GetFrom() != 0 {
+		return s.touchNode(packet.GetFrom(), receivedAt)
+	}
+	return nil
+}
+
+// touchNode records that a node was heard, creating a bare entry for nodes
+// the device hasn't sent node info for yet
+func (s *Store) touchNode(nodeNum uint32, heard time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO nodes (node_num, first_seen, last_heard) VALUES (?, ?, ?)
+		ON CONFLICT(node_num) DO UPDATE SET last_heard = MAX(last_heard, excluded.last_heard)`,
+		nodeNum, heard, heard,
+	)
+	return errors.Wrap(err, "failed to update node")
+}
+
+func (s *Store) recordNodeInfo(envelope *events.Envelope, nodeInfo *pb.NodeInfo) error {
+	if nodeInfo == nil {
+		return errors.New("node info event without node info")
+	}
+
+	heard := envelope.Timestamp.UTC()
+	if nodeInfo.GetLastHeard() != 0 {
+		heard = time.Unix(int64(nodeInfo.GetLastHeard()), 0).UTC()
+	}
+
+	var userID, longName, shortName, hwModel, role sql.NullString
+	if user := nodeInfo.GetUser(); user != nil {
+		userID = sql.NullString{String: user.GetId(), Valid: true}
+		longName = sql.NullString{String: user.GetLongName(), Valid: true}
+		shortName = sql.NullString{String: user.GetShortName(), Valid: true}
+		hwModel = sql.NullString{String: user.GetHwModel().String(), Valid: true}
+		role = sql.NullString{String: user.GetRole().String(), Valid: true}
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO nodes (
+			node_num, user_id, long_name, short_name, hw_model, role, snr, hops_away, first_seen, last_heard
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(node_num) DO UPDATE SET
+			user_id = COALESCE(excluded.user_id, user_id),
+			long_name = COALESCE(excluded.long_name, long_name),
+			short_name = COALESCE(excluded.short_name, short_name),
+			hw_model = COALESCE(excluded.hw_model, hw_model),
+			role = COALESCE(excluded.role, role),
+			snr = excluded.snr,
+			hops_away = excluded.hops_away,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_heard = MAX(last_heard, excluded.last_heard)`,
+		nodeInfo.GetNum(), userID, longName, shortName, hwModel, role,
+		nodeInfo.GetSnr(), nodeInfo.GetHopsAway(), heard, heard,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert node")
+	}
+
+	// Node info from the device's node DB carries the last known position
+	if position := nodeInfo.GetPosition(); position != nil {
+		return s.recordPosition(envelope, nodeInfo.GetNum(), position)
+	}
+	return nil
+}
+
+func (s *Store) recordPosition(envelope *events.Envelope, nodeNum uint32, position *pb.Position) error {
+	if position == nil || position.LatitudeI == nil || position.LongitudeI == nil {
+		return nil
+	}
+	if nodeNum == 0 {
+		log.Debug().Str("event_id", envelope.EventID).Msg("Skipping position without sender")
+		return nil
+	}
+
+	var positionTime sql.NullTime
+	if position.GetTime() != 0 {
+		positionTime = sql.NullTime{Time: time.Unix(int64(position.GetTime()), 0).UTC(), Valid: true}
+	}
+
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO positions (
+			event_id, device_id, node_num, received_at, position_time, latitude, longitude, altitude, sats_in_view
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		envelope.EventID, envelope.DeviceID, nodeNum, envelope.Timestamp.UTC(), positionTime,
+		float64(position.GetLatitudeI())/1e7, float64(position.GetLongitudeI())/1e7,
+		position.Altitude, position.GetSatsInView(),
+	)
+	return errors.Wrap(err, "failed to insert position")
+}
+
+func (s *Store) recordTelemetry(envelope *events.Envelope, nodeNum uint32, telemetry *pb.Telemetry) error {
+	if telemetry == nil {
+		return errors.New("telemetry event without telemetry")
+	}
+	if nodeNum == 0 {
+		log.Debug().Str("event_id", envelope.EventID).Msg("Skipping telemetry without sender")
+		return nil
+	}
+
+	var kind string
+	var columns []interface{}
+	switch {
+	case telemetry.GetDeviceMetrics() != nil:
+		m := telemetry.GetDeviceMetrics()
+		kind = events.TelemetryTypeDevice
+		columns = []interface{}{m.BatteryLevel, m.Voltage, m.ChannelUtilization, m.AirUtilTx, m.UptimeSeconds, nil, nil, nil, nil, nil}
+	case telemetry.GetEnvironmentMetrics() != nil:
+		m := telemetry.GetEnvironmentMetrics()
+		kind = events.TelemetryTypeEnvironment
+		columns = []interface{}{nil, m.Voltage, nil, nil, nil, m.Temperature, m.RelativeHumidity, m.BarometricPressure, m.GasResistance, m.Iaq}
+	default:
+		// Power, air quality and local stats aren't kept yet
+		return nil
+	}
+
+	args := append([]interface{}{envelope.EventID, envelope.DeviceID, nodeNum, envelope.Timestamp.UTC(), kind}, columns...)
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO telemetry (
+			event_id, device_id, node_num, received_at, kind,
+			battery_level, voltage, channel_utilization, air_util_tx, uptime_seconds,
+			temperature, relative_humidity, barometric_pressure, gas_resistance, iaq
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		args...,
+	)
+	return errors.Wrap(err, "failed to insert telemetry")
+}
@@ -0,0 +1,328 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/client"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/deviceadapter"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/events"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/meshbus"
+	pb "github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/pb"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/meshtastic/pkg/simulator"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func envelope(t *testing.T, eventType string, at time.Time, data interface{}) *events.Envelope {
+	t.Helper()
+
+	e, err := events.NewEnvelope(eventType, "base", events.SourceDeviceAdapter, data)
+	if err != nil {
+		t.Fatalf("failed to create envelope: %v", err)
+	}
+	// Round-trip through JSON like the bus does
+	payload, err := e.ToJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+	e, err = events.FromJSON(payload)
+	if err != nil {
+		t.Fatalf("failed to unmarshal envelope: %v", err)
+	}
+	e.Timestamp = at
+	return e
+}
+
+func textPacket(from, to, channel uint32, text string) *pb.MeshPacket {
+	return &pb.MeshPacket{
+		From:    from,
+		To:      to,
+		Channel: channel,
+		Id:      from ^ uint32(len(text)),
+		RxSnr:   6.5,
+		RxRssi:  -80,
+		PayloadVariant: &pb.MeshPacket_Decoded{
+			Decoded: &pb.Data{Portnum: pb.PortNum_TEXT_MESSAGE_APP, Payload: []byte(text)},
+		},
+	}
+}
+
+func position(lat, lon float64, alt int32) *pb.Position {
+	return &pb.Position{
+		LatitudeI:  proto.Int32(int32(lat * 1e7)),
+		LongitudeI: proto.Int32(int32(lon * 1e7)),
+		Altitude:   proto.Int32(alt),
+	}
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	s := openTestStore(t)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []*events.Envelope{
+		envelope(t, events.EventNodeInfoUpdated, base, events.NewNodeInfoUpdatedEvent(&pb.NodeInfo{
+			Num:  0x1111,
+			User: &pb.User{Id: "!00001111", LongName: "Hilltop", ShortName: "HILL"},
+		}, nil, true)),
+		envelope(t, events.EventMeshPacketRx, base.Add(time.Minute), events.NewMeshPacketRxEvent(textPacket(0x1111, 0xffffffff, 0, "morning"))),
+		envelope(t, events.EventMeshPacketRx, base.Add(2*time.Minute), events.NewMeshPacketRxEvent(textPacket(0x2222, 0x1111, 1, "direct"))),
+		envelope(t, events.EventMeshPacketRx, base.Add(3*time.Minute), events.NewMeshPacketRxEvent(textPacket(0x2222, 0xffffffff, 0, "evening"))),
+		envelope(t, events.EventPositionUpdated, base.Add(4*time.Minute), events.NewPositionUpdatedEvent(0x1111, position(47.1, 8.5, 420), nil)),
+		envelope(t, events.EventPositionUpdated, base.Add(5*time.Minute), events.NewPositionUpdatedEvent(0x1111, position(47.2, 8.6, 430), nil)),
+		envelope(t, events.EventTelemetryReceived, base.Add(6*time.Minute), events.NewTelemetryReceivedEvent(0x2222, &pb.Telemetry{
+			Variant: &pb.Telemetry_DeviceMetrics{DeviceMetrics: &pb.DeviceMetrics{
+				BatteryLevel: proto.Uint32(87),
+				Voltage:      proto.Float32(4.1),
+			}},
+		})),
+		envelope(t, events.EventTelemetryReceived, base.Add(7*time.Minute), events.NewTelemetryReceivedEvent(0x2222, &pb.Telemetry{
+			Variant: &pb.Telemetry_EnvironmentMetrics{EnvironmentMetrics: &pb.EnvironmentMetrics{
+				Temperature: proto.Float32(21.5),
+			}},
+		})),
+	}
+	for _, e := range records {
+		if err := s.Record(e); err != nil {
+			t.Fatalf("failed to record %s: %v", e.Type, err)
+		}
+	}
+	// Recording an event twice must not duplicate it
+	if err := s.Record(records[1]); err != nil {
+		t.Fatalf("failed to record duplicate: %v", err)
+	}
+
+	all, err := s.Messages(Filter{})
+	if err != nil {
+		t.Fatalf("failed to query messages: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(all))
+	}
+	if all[0].Text != "morning" || all[0].Direction != events.DirectionInbound || all[0].SNR != 6.5 || all[0].RSSI != -80 {
+		t.Errorf("unexpected first message %+v", all[0])
+	}
+
+	node, err := s.Messages(Filter{Node: 0x1111})
+	if err != nil {
+		t.Fatalf("failed to query messages: %v", err)
+	}
+	if len(node) != 2 || node[0].Text != "morning" || node[1].Text != "direct" {
+		t.Errorf("unexpected messages for node: %+v", node)
+	}
+
+	channel := uint32(0)
+	latest, err := s.Messages(Filter{Channel: &channel, Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to query messages: %v", err)
+	}
+	if len(latest) != 1 || latest[0].Text != "evening" {
+		t.Errorf("unexpected latest message on channel 0: %+v", latest)
+	}
+
+	since, err := s.Messages(Filter{Since: base.Add(90 * time.Second), Until: base.Add(150 * time.Second)})
+	if err != nil {
+		t.Fatalf("failed to query messages: %v", err)
+	}
+	if len(since) != 1 || since[0].Text != "direct" {
+		t.Errorf("unexpected messages in time range: %+v", since)
+	}
+
+	nodes, err := s.Nodes()
+	if err != nil {
+		t.Fatalf("failed to query nodes: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+	names := map[uint32]string{}
+	for _, n := range nodes {
+		names[n.Num] = n.DisplayName()
+	}
+	if names[0x1111] != "Hilltop" || names[0x2222] != "!00002222" {
+		t.Errorf("unexpected node names %v", names)
+	}
+
+	positions, err := s.Positions(Filter{Node: 0x1111})
+	if err != nil {
+		t.Fatalf("failed to query positions: %v", err)
+	}
+	if len(positions) != 2 || positions[1].Latitude != 47.2 || *positions[1].Altitude != 430 {
+		t.Errorf("unexpected positions %+v", positions)
+	}
+
+	telemetry, err := s.Telemetry(Filter{})
+	if err != nil {
+		t.Fatalf("failed to query telemetry: %v", err)
+	}
+	if len(telemetry) != 2 {
+		t.Fatalf("expected 2 telemetry points, got %d", len(telemetry))
+	}
+	if telemetry[0].Kind != events.TelemetryTypeDevice || *telemetry[0].BatteryLevel != 87 || telemetry[0].Temperature != nil {
+		t.Errorf("unexpected device telemetry %+v", telemetry[0])
+	}
+	if telemetry[1].Kind != events.TelemetryTypeEnvironment || telemetry[1].BatteryLevel != nil {
+		t.Errorf("unexpected environment telemetry %+v", telemetry[1])
+	}
+}
+
+func TestExport(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	alt := int32(420)
+	positions := []Position{
+		{NodeNum: 0x1111, ReceivedAt: at, Latitude: 47.1, Longitude: 8.5, Altitude: &alt},
+		{NodeNum: 0x2222, ReceivedAt: at, Latitude: 46.9, Longitude: 7.4},
+		{NodeNum: 0x1111, ReceivedAt: at.Add(time.Minute), Latitude: 47.2, Longitude: 8.6},
+	}
+	names := map[uint32]string{0x1111: "Hilltop"}
+
+	var gpx bytes.Buffer
+	if err := WriteGPX(&gpx, positions, names); err != nil {
+		t.Fatalf("failed to write GPX: %v", err)
+	}
+	for _, want := range []string{
+		"<name>Hilltop</name>",
+		"<name>!00002222</name>",
+		`<trkpt lat="47.1" lon="8.5">`,
+		"<ele>420</ele>",
+		"<time>2024-05-01T12:01:00Z</time>",
+	} {
+		if !strings.Contains(gpx.String(), want) {
+			t.Errorf("GPX missing %q:\n%s", want, gpx.String())
+		}
+	}
+
+	var geo bytes.Buffer
+	if err := WriteGeoJSON(&geo, positions, names); err != nil {
+		t.Fatalf("failed to write GeoJSON: %v", err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates interface{}
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(geo.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	var types []string
+	for _, f := range collection.Features {
+		types = append(types, f.Geometry.Type)
+	}
+	if strings.Join(types, ",") != "Point,LineString,Point" {
+		t.Errorf("unexpected features %v", types)
+	}
+	if coords := fmt.Sprint(collection.Features[0].Geometry.Coordinates); coords != "[8.6 47.2]" {
+		t.Errorf("expected latest position as [lon,lat], got %s", coords)
+	}
+
+	battery := uint32(87)
+	temp := 21.5
+	var csv bytes.Buffer
+	if err := WriteTelemetryCSV(&csv, []TelemetryPoint{
+		{NodeNum: 0x1111, ReceivedAt: at, Kind: "device", BatteryLevel: &battery},
+		{NodeNum: 0x1111, ReceivedAt: at.Add(time.Minute), Kind: "environment", Temperature: &temp},
+	}, names); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", csv.String())
+	}
+	if lines[1] != "2024-05-01T12:00:00Z,!00001111,Hilltop,device,87,,,,,,,,," {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if lines[2] != "2024-05-01T12:01:00Z,!00001111,Hilltop,environment,,,,,,21.5,,,," {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+}
+
+func TestStoreRecordsBusEvents(t *testing.T) {
+	config := simulator.DefaultConfig()
+	config.MessageDelay = 10 * time.Millisecond
+	config.MessageJitter = 0
+	config.TelemetryInterval = 20 * time.Millisecond
+	config.PositionInterval = 20 * time.Millisecond
+
+	node, err := simulator.NewNode(config)
+	if err != nil {
+		t.Fatalf("failed to create simulator: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go node.Serve(l)
+	defer node.Close()
+
+	s := openTestStore(t)
+
+	bus, err := meshbus.NewBus(nil)
+	if err != nil {
+		t.Fatalf("failed to create bus: %v", err)
+	}
+	if err := bus.Start(); err != nil {
+		t.Fatalf("failed to start bus: %v", err)
+	}
+	defer bus.Stop()
+	if err := s.Subscribe(bus); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	c, err := client.NewRobustMeshtasticClient(&client.Config{Host: l.Addr().String(), Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	adapter := deviceadapter.NewDeviceAdapter("sim", c, bus)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := adapter.Start(ctx); err != nil {
+		t.Fatalf("failed to start adapter: %v", err)
+	}
+	defer adapter.Stop()
+
+	if err := node.InjectText(config.Neighbors[0].NodeNum, 0xffffffff, "from the hill"); err != nil {
+		t.Fatalf("failed to inject text: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages, _ := s.Messages(Filter{TextOnly: true})
+		positions, _ := s.Positions(Filter{Node: config.Neighbors[1].NodeNum})
+		telemetry, _ := s.Telemetry(Filter{Node: config.Neighbors[2].NodeNum})
+		nodes, _ := s.Nodes()
+		if len(messages) > 0 && len(positions) > 1 && len(telemetry) > 0 && len(nodes) >= len(config.Neighbors) {
+			if messages[0].Text != "from the hill" || messages[0].From != config.Neighbors[0].NodeNum {
+				t.Errorf("unexpected message %+v", messages[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out: %d messages, %d positions, %d telemetry, %d nodes",
+				len(messages), len(positions), len(telemetry), len(nodes))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/analysis"
+)
+
+var (
+	alertSince       time.Duration
+	alertCommand     string
+	alertMinSeverity string
+	alertRepeat      time.Duration
+	alertEvery       time.Duration
+	alertStatePath   string
+
+	alertCmd = &cobra.Command{
+		Use:   "alert",
+		Short: "Check the recent readings against the thresholds and alert",
+		Long: `Check the readings of the last --since against the thresholds and run
+--command for every out-of-spec event. Without --command the alerts are
+printed.
+
+The command is run with sh -c. The alert message is passed on stdin and
+the event in environment variables: POLL_MODEM_SEVERITY,
+POLL_MODEM_DIRECTION, POLL_MODEM_CHANNEL, POLL_MODEM_METRIC,
+POLL_MODEM_VALUE, POLL_MODEM_LIMIT, POLL_MODEM_START, POLL_MODEM_END,
+POLL_MODEM_ONGOING and POLL_MODEM_MESSAGE.
+
+An alert for the same channel, metric and severity is not repeated
+within --repeat. Use --every to keep checking, or run it from cron.`,
+		RunE: runAlert,
+	}
+)
+
+func init() {
+	alertCmd.Flags().DurationVar(&alertSince, "since", 15*time.Minute, "Length of the checked window")
+	alertCmd.Flags().StringVar(&alertCommand, "command", "", "Command run for each alert")
+	alertCmd.Flags().StringVar(&alertMinSeverity, "min-severity", "warning", "Lowest severity alerted: warning or critical")
+	alertCmd.Flags().DurationVar(&alertRepeat, "repeat", 6*time.Hour, "Minimum time between repeated alerts")
+	alertCmd.Flags().DurationVar(&alertEvery, "every", 0, "Check repeatedly at this interval instead of once")
+	alertCmd.Flags().StringVar(&alertStatePath, "state", "", "Alert state file (default ~/.config/poll-modem/alerts.json)")
+	addAnalysisFlags(alertCmd)
+
+	rootCmd.AddCommand(alertCmd)
+}
+
+func runAlert(cmd *cobra.Command, args []string) error {
+	minSeverity, err := analysis.ParseSeverity(alertMinSeverity)
+	if err != nil {
+		return err
+	}
+	thresholds, err := loadThresholds()
+	if err != nil {
+		return err
+	}
+
+	statePath := alertStatePath
+	if statePath == "" {
+		statePath, err = analysis.DefaultAlertStatePath()
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if alertEvery <= 0 {
+		return checkAlerts(ctx, statePath, thresholds, minSeverity)
+	}
+
+	ticker := time.NewTicker(alertEvery)
+	defer ticker.Stop()
+	for {
+		if err := checkAlerts(ctx, statePath, thresholds, minSeverity); err != nil {
+			log.Error().Err(err).Msg("Alert check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkAlerts(ctx context.Context, statePath string, thresholds analysis.Thresholds, minSeverity analysis.Severity) error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	now := time.Now()
+	report, err := analysis.BuildReport(db, now.Add(-alertSince), now, alertSince, thresholds)
+	if err != nil {
+		return err
+	}
+
+	state, err := analysis.LoadAlertState(statePath)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, e := range report.Events {
+		if e.Severity < minSeverity || !state.ShouldSend(e, now, alertRepeat) {
+			continue
+		}
+		if alertCommand == "" {
+			fmt.Println(analysis.AlertMessage(e))
+		} else if err := analysis.RunAlertCommand(ctx, alertCommand, e); err != nil {
+			// Not marked as sent, so it's retried on the next check
+			log.Error().Err(err).Msg("Failed to send alert")
+			continue
+		}
+		state.MarkSent(e, now)
+		sent++
+	}
+	log.Debug().Int("events", len(report.Events)).Int("sent", sent).Msg("Checked alerts")
+
+	return state.Save()
+}
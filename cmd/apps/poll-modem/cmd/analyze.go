@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/analysis"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+)
+
+var (
+	dbPath         string
+	thresholdsPath string
+	analyzeSince   time.Duration
+	analyzeUntil   string
+	analyzeBucket  time.Duration
+	analyzeJSON    bool
+
+	analyzeCmd = &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze the stored modem readings",
+		Long: `Analyze the readings stored in the poll-modem database.
+
+The window ends at --until (default now) and covers --since before it.
+Codeword error rates are computed per --bucket from the differences
+between consecutive counter readings.`,
+	}
+
+	analyzeTrendsCmd = &cobra.Command{
+		Use:   "trends",
+		Short: "Show per-channel power and SNR trends",
+		RunE:  runAnalyzeTrends,
+	}
+
+	analyzeErrorsCmd = &cobra.Command{
+		Use:   "errors",
+		Short: "Show per-channel correctable and uncorrectable codeword rates",
+		RunE:  runAnalyzeErrors,
+	}
+
+	analyzeSpecCmd = &cobra.Command{
+		Use:   "spec",
+		Short: "Show out-of-spec events against the DOCSIS thresholds",
+		RunE:  runAnalyzeSpec,
+	}
+)
+
+func init() {
+	analyzeCmd.PersistentFlags().DurationVar(&analyzeSince, "since", 24*time.Hour, "Length of the analyzed window")
+	analyzeCmd.PersistentFlags().StringVar(&analyzeUntil, "until", "", "End of the analyzed window (RFC3339 or 2006-01-02 15:04, default now)")
+	analyzeCmd.PersistentFlags().DurationVar(&analyzeBucket, "bucket", time.Hour, "Bucket size of the error rates")
+	analyzeCmd.PersistentFlags().BoolVar(&analyzeJSON, "json", false, "Output JSON instead of a table")
+	addAnalysisFlags(analyzeCmd)
+
+	analyzeCmd.AddCommand(analyzeTrendsCmd, analyzeErrorsCmd, analyzeSpecCmd)
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+// addAnalysisFlags adds the flags shared by the analysis commands
+func addAnalysisFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Path of the database (default ~/.config/poll-modem/history.db)")
+	cmd.PersistentFlags().StringVar(&thresholdsPath, "thresholds", "", "YAML file overriding the default thresholds")
+}
+
+func openDatabase() (*modem.Database, error) {
+	if dbPath == "" {
+		return modem.NewDatabase()
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, errors.Wrap(err, "failed to open database")
+	}
+	return modem.OpenDatabase(dbPath)
+}
+
+func loadThresholds() (analysis.Thresholds, error) {
+	if thresholdsPath == "" {
+		return analysis.DefaultThresholds(), nil
+	}
+	return analysis.LoadThresholds(thresholdsPath)
+}
+
+// parseTime parses a time given on the command line, in local time unless
+// it has an offset
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time %q", s)
+}
+
+// buildAnalysisReport analyzes the window given by the analyze flags
+func buildAnalysisReport() (*analysis.Report, error) {
+	to := time.Now()
+	if analyzeUntil != "" {
+		var err error
+		to, err = parseTime(analyzeUntil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	thresholds, err := loadThresholds()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return analysis.BuildReport(db, to.Add(-analyzeSince), to, analyzeBucket, thresholds)
+}
+
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+type trendOutput struct {
+	Direction   analysis.Direction `json:"direction"`
+	Channel     string             `json:"channel"`
+	Metric      analysis.Metric    `json:"metric"`
+	Unit        string             `json:"unit"`
+	Frequency   string             `json:"frequency"`
+	Modulation  string             `json:"modulation"`
+	Samples     int                `json:"samples"`
+	Min         float64            `json:"min"`
+	Mean        float64            `json:"mean"`
+	Max         float64            `json:"max"`
+	First       float64            `json:"first"`
+	Last        float64            `json:"last"`
+	SlopePerDay float64            `json:"slope_per_day"`
+}
+
+func runAnalyzeTrends(cmd *cobra.Command, args []string) error {
+	report, err := buildAnalysisReport()
+	if err != nil {
+		return err
+	}
+
+	trends := []*analysis.Trend{}
+	for i := range report.Trends {
+		if report.Trends[i].Metric != analysis.MetricLock {
+			trends = append(trends, &report.Trends[i])
+		}
+	}
+
+	if analyzeJSON {
+		out := []trendOutput{}
+		for _, t := range trends {
+			out = append(out, trendOutput{
+				Direction:   t.Direction,
+				Channel:     t.ChannelID,
+				Metric:      t.Metric,
+				Unit:        t.Metric.Unit(),
+				Frequency:   t.Frequency,
+				Modulation:  t.Modulation,
+				Samples:     t.Samples,
+				Min:         t.Min,
+				Mean:        t.Mean,
+				Max:         t.Max,
+				First:       t.First,
+				Last:        t.Last,
+				SlopePerDay: t.SlopePerDay,
+			})
+		}
+		return writeJSON(out)
+	}
+	if len(trends) == 0 {
+		fmt.Println("No readings in the window")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DIRECTION\tCHANNEL\tMETRIC\tSAMPLES\tMIN\tMEAN\tMAX\tLAST\tSLOPE/DAY\tTREND")
+	for _, t := range trends {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%+.2f\t%s\n",
+			t.Direction, t.ChannelID, t.Metric, t.Samples, t.Min, t.Mean, t.Max, t.Last, t.SlopePerDay,
+			report.TrendSparkline(t))
+	}
+	return w.Flush()
+}
+
+type errorBucketOutput struct {
+	Start                time.Time `json:"start"`
+	Unerrored            uint64    `json:"unerrored"`
+	Correctable          uint64    `json:"correctable"`
+	Uncorrectable        uint64    `json:"uncorrectable"`
+	CorrectablePercent   float64   `json:"correctable_percent"`
+	UncorrectablePercent float64   `json:"uncorrectable_percent"`
+}
+
+type errorRateOutput struct {
+	Channel string `json:"channel"`
+	Samples int    `json:"samples"`
+	Resets  int    `json:"resets"`
+	errorBucketOutput
+	Buckets []errorBucketOutput `json:"buckets"`
+}
+
+func newErrorBucketOutput(b analysis.ErrorBucket) errorBucketOutput {
+	return errorBucketOutput{
+		Start:                b.Start,
+		Unerrored:            b.Unerrored,
+		Correctable:          b.Correctable,
+		Uncorrectable:        b.Uncorrectable,
+		CorrectablePercent:   b.CorrectablePercent(),
+		UncorrectablePercent: b.UncorrectablePercent(),
+	}
+}
+
+func runAnalyzeErrors(cmd *cobra.Command, args []string) error {
+	report, err := buildAnalysisReport()
+	if err != nil {
+		return err
+	}
+
+	if analyzeJSON {
+		rates := []errorRateOutput{}
+		for _, rate := range report.ErrorRates {
+			out := errorRateOutput{
+				Channel:           rate.ChannelID,
+				Samples:           rate.Samples,
+				Resets:            rate.Resets,
+				errorBucketOutput: newErrorBucketOutput(rate.ErrorBucket),
+				Buckets:           []errorBucketOutput{},
+			}
+			for _, b := range rate.Buckets {
+				out.Buckets = append(out.Buckets, newErrorBucketOutput(b))
+			}
+			rates = append(rates, out)
+		}
+		return writeJSON(rates)
+	}
+	if len(report.ErrorRates) == 0 {
+		fmt.Println("No codeword readings in the window")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tSAMPLES\tRESETS\tCODEWORDS\tCORRECTABLE\tUNCORRECTABLE\tUNCORRECTABLE/"+analysis.FormatDuration(analyzeBucket))
+	for _, rate := range report.ErrorRates {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s (%.3f%%)\t%s (%.4f%%)\t%s\n",
+			rate.ChannelID, rate.Samples, rate.Resets, analysis.FormatCount(rate.Total()),
+			analysis.FormatCount(rate.Correctable), rate.CorrectablePercent(),
+			analysis.FormatCount(rate.Uncorrectable), rate.UncorrectablePercent(),
+			analysis.ErrorSparkline(rate))
+	}
+	return w.Flush()
+}
+
+type eventOutput struct {
+	Severity    string             `json:"severity"`
+	Direction   analysis.Direction `json:"direction"`
+	Channel     string             `json:"channel"`
+	Metric      analysis.Metric    `json:"metric"`
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	Ongoing     bool               `json:"ongoing"`
+	Readings    int                `json:"readings"`
+	Worst       float64            `json:"worst"`
+	Limit       float64            `json:"limit"`
+	Description string             `json:"description"`
+}
+
+func newEventOutput(e analysis.Event) eventOutput {
+	return eventOutput{
+		Severity:    e.Severity.String(),
+		Direction:   e.Direction,
+		Channel:     e.ChannelID,
+		Metric:      e.Metric,
+		Start:       e.Start,
+		End:         e.End,
+		Ongoing:     e.Ongoing,
+		Readings:    e.Readings,
+		Worst:       e.Worst,
+		Limit:       e.Limit,
+		Description: e.Description(),
+	}
+}
+
+func runAnalyzeSpec(cmd *cobra.Command, args []string) error {
+	report, err := buildAnalysisReport()
+	if err != nil {
+		return err
+	}
+
+	if analyzeJSON {
+		events := []eventOutput{}
+		for _, e := range report.Events {
+			events = append(events, newEventOutput(e))
+		}
+		return writeJSON(events)
+	}
+	if len(report.Events) == 0 {
+		fmt.Printf("No out-of-spec readings in %d polls\n", report.Polls)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tSTART\tDURATION\tREADINGS\tDESCRIPTION")
+	for _, e := range report.Events {
+		duration := analysis.FormatDuration(e.Duration())
+		if e.Ongoing {
+			duration += " (ongoing)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			e.Severity, e.Start.Format("2006-01-02 15:04"), duration, e.Readings, e.Description())
+	}
+	return w.Flush()
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+)
+
+var (
+	driversDetect bool
+
+	driversCmd = &cobra.Command{
+		Use:   "drivers",
+		Short: "List the modem drivers",
+		Long: `List the modem drivers. With --detect the modem at --url is probed and
+the driver that supports it is marked.`,
+		RunE: runDrivers,
+	}
+)
+
+func init() {
+	driversCmd.Flags().BoolVar(&driversDetect, "detect", false, "Probe the modem at --url")
+	rootCmd.AddCommand(driversCmd)
+}
+
+func runDrivers(cmd *cobra.Command, args []string) error {
+	detected := ""
+	if driversDetect {
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		driver, err := modem.DetectDriver(ctx, url)
+		if err != nil {
+			return err
+		}
+		detected = driver.Name()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DRIVER\tDESCRIPTION")
+	for _, d := range modem.Drivers() {
+		name := d.Name
+		if name == detected {
+			name += " *"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, d.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if detected != "" {
+		fmt.Printf("\n* detected at %s\n", url)
+	}
+	return nil
+}
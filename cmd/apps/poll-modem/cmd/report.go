@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/analysis"
+)
+
+var (
+	reportDate   string
+	reportFormat string
+	reportOutput string
+	reportBucket time.Duration
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Generate a daily signal quality report",
+		Long: `Generate a report of one day of readings, with the out-of-spec events,
+per-channel power and SNR trends with sparklines, codeword error rates
+and the thresholds they were checked against.
+
+The format is markdown unless --format html is given or --output ends
+in .html.`,
+		RunE: runReport,
+	}
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&reportDate, "date", "", "Day of the report as YYYY-MM-DD (default today)")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "", "Report format: markdown or html")
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Output file (default stdout)")
+	reportCmd.Flags().DurationVar(&reportBucket, "bucket", time.Hour, "Bucket size of the error rates")
+	addAnalysisFlags(reportCmd)
+
+	rootCmd.AddCommand(reportCmd)
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if reportDate != "" {
+		var err error
+		day, err = time.ParseInLocation("2006-01-02", reportDate, time.Local)
+		if err != nil {
+			return errors.Wrapf(err, "invalid date %q", reportDate)
+		}
+	}
+	to := day.AddDate(0, 0, 1)
+	if to.After(now) {
+		to = now
+	}
+
+	format := reportFormat
+	if format == "" {
+		format = "markdown"
+		if ext := strings.ToLower(filepath.Ext(reportOutput)); ext == ".html" || ext == ".htm" {
+			format = "html"
+		}
+	}
+	var write func(io.Writer, *analysis.Report) error
+	switch format {
+	case "markdown", "md":
+		write = analysis.WriteMarkdown
+	case "html":
+		write = analysis.WriteHTML
+	default:
+		return errors.Errorf("unknown report format %q (use markdown or html)", format)
+	}
+
+	thresholds, err := loadThresholds()
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := analysis.BuildReport(db, day, to, reportBucket, thresholds)
+	if err != nil {
+		return err
+	}
+
+	if reportOutput == "" {
+		return write(os.Stdout, report)
+	}
+
+	f, err := os.Create(reportOutput)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	defer f.Close()
+
+	if err := write(f, report); err != nil {
+		return err
+	}
+	log.Info().Str("file", reportOutput).Int("events", len(report.Events)).Msg("Report written")
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	clay "github.com/go-go-golems/clay/pkg"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/tui"
 )
 
@@ -18,6 +20,7 @@ var (
 	pollInterval time.Duration
 	username     string
 	password     string
+	driverName   string
 
 	rootCmd = &cobra.Command{
 		Use:   "poll-modem",
@@ -33,7 +36,10 @@ The application continuously polls the modem endpoint and displays:
 
 Use tab/shift+tab to navigate between different views.
 
-If the modem requires authentication, provide username and password flags.`,
+If the modem requires authentication, provide username and password flags.
+
+The driver for the modem is detected unless --driver is given, run
+"poll-modem drivers" to list them.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return logging.InitLoggerFromViper()
 		},
@@ -56,6 +62,7 @@ func init() {
 	rootCmd.PersistentFlags().DurationVarP(&pollInterval, "interval", "i", 30*time.Second, "Poll interval (e.g., 30s, 1m, 5m)")
 	rootCmd.PersistentFlags().StringVarP(&username, "username", "n", "", "Modem username for authentication")
 	rootCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "Modem password for authentication")
+	rootCmd.PersistentFlags().StringVarP(&driverName, "driver", "d", "auto", "Modem driver (auto detects it)")
 }
 
 func runTUI(cmd *cobra.Command, args []string) error {
@@ -68,11 +75,17 @@ func runTUI(cmd *cobra.Command, args []string) error {
 		baseURL = "http://192.168.0.1"
 	}
 
-	app := tui.NewApp(baseURL, pollInterval, username, password)
+	driver, err := newDriver(cmd.Context(), baseURL)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("driver", driver.Name()).Msg("Using modem driver")
+
+	app := tui.NewApp(driver, pollInterval)
 	defer app.Cleanup() // Ensure cleanup is called when function exits
 
 	p := tea.NewProgram(app, tea.WithAltScreen())
-	_, err := p.Run()
+	_, err = p.Run()
 
 	if err != nil {
 		return fmt.Errorf("failed to run TUI: %w", err)
@@ -80,3 +93,25 @@ func runTUI(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// newDriver creates the modem driver chosen with --driver, with the
+// credentials of the flags
+func newDriver(ctx context.Context, baseURL string) (modem.ModemDriver, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	driver, err := modem.NewDriver(ctx, driverName, baseURL)
+	if err != nil && (driverName == "" || driverName == "auto") {
+		// Start anyway when the modem can't be reached, the TUI shows the
+		// fetch errors until it comes back
+		log.Warn().Err(err).Msg("Could not detect the modem driver, using jst")
+		driver, err = modem.NewDriver(ctx, "jst", baseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if username != "" && password != "" {
+		driver.SetCredentials(username, password)
+	}
+	return driver, nil
+}
@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// AlertState remembers when an alert was last sent for an event, so that
+// an ongoing problem doesn't alert on every check
+type AlertState struct {
+	path string
+	Sent map[string]time.Time `json:"sent"`
+}
+
+// DefaultAlertStatePath returns ~/.config/poll-modem/alerts.json
+func DefaultAlertStatePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user home directory")
+	}
+
+	configDir := filepath.Join(homeDir, ".config", "poll-modem")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create config directory")
+	}
+
+	return filepath.Join(configDir, "alerts.json"), nil
+}
+
+// LoadAlertState reads the alert state, a missing file is an empty state
+func LoadAlertState(path string) (*AlertState, error) {
+	state := &AlertState{path: path, Sent: map[string]time.Time{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read alert state")
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse alert state %s", path)
+	}
+	if state.Sent == nil {
+		state.Sent = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+// Save writes the alert state, dropping entries older than a week
+func (s *AlertState) Save() error {
+	for key, sent := range s.Sent {
+		if time.Since(sent) > 7*24*time.Hour {
+			delete(s.Sent, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal alert state")
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write alert state")
+	}
+	return nil
+}
+
+func alertKey(e Event) string {
+	return fmt.Sprintf("%s/%s/%s/%s", e.Direction, e.ChannelID, e.Metric, e.Severity)
+}
+
+// ShouldSend reports whether the alert for an event wasn't sent within the
+// repeat interval
+func (s *AlertState) ShouldSend(e Event, now time.Time, repeat time.Duration) bool {
+	sent, ok := s.Sent[alertKey(e)]
+	return !ok || now.Sub(sent) >= repeat
+}
+
+// MarkSent records that the alert for an event was sent
+func (s *AlertState) MarkSent(e Event, now time.Time) {
+	s.Sent[alertKey(e)] = now
+}
+
+// AlertMessage is the one line message of an alert
+func AlertMessage(e Event) string {
+	status := "ended " + e.End.Format(timeFormat)
+	if e.Ongoing {
+		status = "ongoing"
+	}
+	return fmt.Sprintf("[%s] %s since %s (%s, %s)",
+		e.Severity, e.Description(), e.Start.Format(timeFormat), plural(e.Readings, "reading"), status)
+}
+
+// RunAlertCommand runs command with sh -c. The event is passed in
+// POLL_MODEM_* environment variables and the message on stdin.
+func RunAlertCommand(ctx context.Context, command string, e Event) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = strings.NewReader(AlertMessage(e) + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"POLL_MODEM_SEVERITY="+e.Severity.String(),
+		"POLL_MODEM_DIRECTION="+string(e.Direction),
+		"POLL_MODEM_CHANNEL="+e.ChannelID,
+		"POLL_MODEM_METRIC="+string(e.Metric),
+		fmt.Sprintf("POLL_MODEM_VALUE=%g", e.Worst),
+		fmt.Sprintf("POLL_MODEM_LIMIT=%g", e.Limit),
+		"POLL_MODEM_START="+e.Start.Format(time.RFC3339),
+		"POLL_MODEM_END="+e.End.Format(time.RFC3339),
+		fmt.Sprintf("POLL_MODEM_ONGOING=%t", e.Ongoing),
+		"POLL_MODEM_MESSAGE="+AlertMessage(e),
+	)
+
+	log.Debug().Str("command", command).Str("event", alertKey(e)).Msg("Running alert command")
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "alert command failed for %s", alertKey(e))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAlertStateShouldSend(t *testing.T) {
+	state, err := LoadAlertState(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatalf("LoadAlertState failed: %v", err)
+	}
+
+	event := Event{SeriesKey: SeriesKey{Downstream, "1", MetricSNR}, Severity: SeverityWarning}
+	now := t0
+	if !state.ShouldSend(event, now, time.Hour) {
+		t.Fatal("expected an alert that was never sent to be sent")
+	}
+	state.MarkSent(event, now)
+
+	critical := event
+	critical.Severity = SeverityCritical
+	otherChannel := event
+	otherChannel.ChannelID = "2"
+
+	tests := []struct {
+		name  string
+		event Event
+		after time.Duration
+		want  bool
+	}{
+		{"within the repeat interval", event, 10 * time.Minute, false},
+		{"at the repeat interval", event, time.Hour, true},
+		{"after the repeat interval", event, 2 * time.Hour, true},
+		{"escalated to critical", critical, 10 * time.Minute, true},
+		{"other channel", otherChannel, 10 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		if got := state.ShouldSend(tt.event, now.Add(tt.after), time.Hour); got != tt.want {
+			t.Errorf("%s: ShouldSend = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlertStateSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	state, err := LoadAlertState(path)
+	if err != nil {
+		t.Fatalf("LoadAlertState failed: %v", err)
+	}
+
+	recent := Event{SeriesKey: SeriesKey{Upstream, "1", MetricPower}, Severity: SeverityCritical}
+	old := Event{SeriesKey: SeriesKey{Downstream, "3", MetricLock}, Severity: SeverityCritical}
+	now := time.Now()
+	state.MarkSent(recent, now.Add(-time.Hour))
+	state.MarkSent(old, now.Add(-8*24*time.Hour))
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadAlertState(path)
+	if err != nil {
+		t.Fatalf("LoadAlertState failed: %v", err)
+	}
+	if loaded.ShouldSend(recent, now, 6*time.Hour) {
+		t.Error("expected the recent alert to be remembered")
+	}
+	if !loaded.ShouldSend(old, now, 30*24*time.Hour) {
+		t.Error("expected alerts older than a week to be dropped")
+	}
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+	"github.com/pkg/errors"
+)
+
+// maxBuckets caps the number of error rate buckets of a window
+const maxBuckets = 1000
+
+// ErrorBucket counts the codewords received in a time bucket
+type ErrorBucket struct {
+	Start         time.Time
+	Unerrored     uint64
+	Correctable   uint64
+	Uncorrectable uint64
+}
+
+// Total returns the number of codewords received in the bucket
+func (b ErrorBucket) Total() uint64 {
+	return b.Unerrored + b.Correctable + b.Uncorrectable
+}
+
+// CorrectablePercent returns the share of correctable codewords
+func (b ErrorBucket) CorrectablePercent() float64 {
+	return percent(b.Correctable, b.Total())
+}
+
+// UncorrectablePercent returns the share of uncorrectable codewords
+func (b ErrorBucket) UncorrectablePercent() float64 {
+	return percent(b.Uncorrectable, b.Total())
+}
+
+func percent(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// ErrorRate is the codeword error history of a downstream channel. The modem
+// reports counters since it booted, the rates are computed from the
+// differences between consecutive readings.
+type ErrorRate struct {
+	ChannelID string
+	Samples   int
+	// Resets counts the counters going backwards, usually a modem reboot
+	Resets int
+	// Totals over the window
+	ErrorBucket
+	Buckets []ErrorBucket
+}
+
+type counters struct {
+	time                                  time.Time
+	unerrored, correctable, uncorrectable uint64
+}
+
+// parseCounter parses a codeword counter like "1234567" or "1,234,567"
+func parseCounter(s string) (uint64, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	v, err := strconv.ParseUint(s, 10, 64)
+	return v, err == nil
+}
+
+// ComputeErrorRates buckets the codeword counters of each channel
+func ComputeErrorRates(data []modem.ErrorExport, from, to time.Time, bucket time.Duration) ([]ErrorRate, error) {
+	if bucket <= 0 {
+		return nil, errors.New("bucket size must be positive")
+	}
+	n := int((to.Sub(from) + bucket - 1) / bucket)
+	if n > maxBuckets {
+		return nil, errors.Errorf("%s buckets over %s are too many (max %d), use a larger bucket", bucket, to.Sub(from), maxBuckets)
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	readings := map[string][]counters{}
+	for _, e := range data {
+		unerrored, ok1 := parseCounter(e.UnerroredCodewords)
+		correctable, ok2 := parseCounter(e.CorrectableCodewords)
+		uncorrectable, ok3 := parseCounter(e.UncorrectableCodewords)
+		if !ok1 || !ok2 || !ok3 {
+			continue
+		}
+		readings[e.ChannelID] = append(readings[e.ChannelID], counters{e.Timestamp, unerrored, correctable, uncorrectable})
+	}
+
+	ret := []ErrorRate{}
+	for channelID, rs := range readings {
+		sort.SliceStable(rs, func(i, j int) bool { return rs[i].time.Before(rs[j].time) })
+
+		rate := ErrorRate{
+			ChannelID: channelID,
+			Samples:   len(rs),
+			Buckets:   make([]ErrorBucket, n),
+		}
+		rate.Start = from
+		for i := range rate.Buckets {
+			rate.Buckets[i].Start = from.Add(time.Duration(i) * bucket)
+		}
+
+		for i := 1; i < len(rs); i++ {
+			prev, cur := rs[i-1], rs[i]
+			// A reading before the window only serves as the base of the
+			// first delta, resets outside the window aren't counted
+			if cur.time.Before(from) || !cur.time.Before(to) {
+				continue
+			}
+
+			delta := ErrorBucket{
+				Unerrored:     cur.unerrored - prev.unerrored,
+				Correctable:   cur.correctable - prev.correctable,
+				Uncorrectable: cur.uncorrectable - prev.uncorrectable,
+			}
+			if cur.unerrored < prev.unerrored || cur.correctable < prev.correctable || cur.uncorrectable < prev.uncorrectable {
+				// The counters restarted from zero in between
+				rate.Resets++
+				delta = ErrorBucket{
+					Unerrored:     cur.unerrored,
+					Correctable:   cur.correctable,
+					Uncorrectable: cur.uncorrectable,
+				}
+			}
+
+			b := &rate.Buckets[min(int(cur.time.Sub(from)/bucket), n-1)]
+			b.Unerrored += delta.Unerrored
+			b.Correctable += delta.Correctable
+			b.Uncorrectable += delta.Uncorrectable
+			rate.Unerrored += delta.Unerrored
+			rate.Correctable += delta.Correctable
+			rate.Uncorrectable += delta.Uncorrectable
+		}
+
+		ret = append(ret, rate)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return channelLess(ret[i].ChannelID, ret[j].ChannelID)
+	})
+	return ret, nil
+}
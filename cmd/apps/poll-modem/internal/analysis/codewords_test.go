@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+)
+
+var t0 = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// at returns the time minutes after t0
+func at(minutes int) time.Time {
+	return t0.Add(time.Duration(minutes) * time.Minute)
+}
+
+// counterReading is a reading of the codeword counters of channel 1
+func counterReading(minutes int, unerrored, correctable, uncorrectable string) modem.ErrorExport {
+	return modem.ErrorExport{
+		Timestamp:              at(minutes),
+		ChannelID:              "1",
+		UnerroredCodewords:     unerrored,
+		CorrectableCodewords:   correctable,
+		UncorrectableCodewords: uncorrectable,
+	}
+}
+
+// bucketCounts formats buckets as unerrored/correctable/uncorrectable
+func bucketCounts(buckets []ErrorBucket) []string {
+	ret := make([]string, len(buckets))
+	for i, b := range buckets {
+		ret[i] = fmt.Sprintf("%d/%d/%d", b.Unerrored, b.Correctable, b.Uncorrectable)
+	}
+	return ret
+}
+
+func TestComputeErrorRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []modem.ErrorExport
+		minutes int // window length from t0
+		samples int
+		resets  int
+		totals  string
+		buckets []string
+		starts  []int
+	}{
+		{
+			name: "deltas per bucket",
+			data: []modem.ErrorExport{
+				counterReading(5, "2000", "20", "0"),
+				counterReading(15, "3000", "20", "1"),
+				counterReading(59, "3500", "25", "1"),
+			},
+			minutes: 60,
+			samples: 3,
+			totals:  "1500/5/1",
+			buckets: []string{"0/0/0", "1000/0/1", "0/0/0", "500/5/0"},
+			starts:  []int{0, 15, 30, 45},
+		},
+		{
+			name: "reading before the window is the base",
+			data: []modem.ErrorExport{
+				counterReading(-5, "1000", "10", "0"),
+				counterReading(5, "2000", "20", "0"),
+			},
+			minutes: 60,
+			samples: 2,
+			totals:  "1000/10/0",
+			buckets: []string{"1000/10/0", "0/0/0", "0/0/0", "0/0/0"},
+		},
+		{
+			name: "readings at or after the end are ignored",
+			data: []modem.ErrorExport{
+				counterReading(50, "1000", "10", "0"),
+				counterReading(60, "2000", "20", "0"),
+				counterReading(70, "9000", "90", "9"),
+			},
+			minutes: 60,
+			samples: 3,
+			totals:  "0/0/0",
+			buckets: []string{"0/0/0", "0/0/0", "0/0/0", "0/0/0"},
+		},
+		{
+			name: "counter reset",
+			data: []modem.ErrorExport{
+				counterReading(0, "5000", "50", "5"),
+				counterReading(10, "6000", "60", "5"),
+				// The modem rebooted in between
+				counterReading(20, "100", "1", "0"),
+				counterReading(40, "1100", "2", "0"),
+			},
+			minutes: 60,
+			samples: 4,
+			resets:  1,
+			totals:  "2100/12/0",
+			buckets: []string{"1000/10/0", "100/1/0", "1000/1/0", "0/0/0"},
+		},
+		{
+			name: "reset outside the window",
+			data: []modem.ErrorExport{
+				counterReading(-20, "5000", "50", "5"),
+				counterReading(-10, "100", "1", "0"),
+				counterReading(10, "600", "3", "0"),
+			},
+			minutes: 60,
+			samples: 3,
+			totals:  "500/2/0",
+			buckets: []string{"500/2/0", "0/0/0", "0/0/0", "0/0/0"},
+		},
+		{
+			name: "single counter going backwards",
+			data: []modem.ErrorExport{
+				counterReading(0, "5000", "50", "5"),
+				counterReading(10, "6000", "60", "2"),
+			},
+			minutes: 60,
+			samples: 2,
+			resets:  1,
+			totals:  "6000/60/2",
+			buckets: []string{"6000/60/2", "0/0/0", "0/0/0", "0/0/0"},
+		},
+		{
+			name: "partial last bucket",
+			data: []modem.ErrorExport{
+				counterReading(0, "0", "0", "0"),
+				counterReading(49, "700", "7", "0"),
+			},
+			minutes: 50,
+			samples: 2,
+			totals:  "700/7/0",
+			buckets: []string{"0/0/0", "0/0/0", "0/0/0", "700/7/0"},
+			starts:  []int{0, 15, 30, 45},
+		},
+		{
+			name: "unparsable readings are skipped",
+			data: []modem.ErrorExport{
+				counterReading(0, "1,000,000", "10", "0"),
+				counterReading(10, "", "20", "0"),
+				counterReading(20, "n/a", "20", "0"),
+				counterReading(30, "1,002,000", "30", "1"),
+			},
+			minutes: 60,
+			samples: 2,
+			totals:  "2000/20/1",
+			buckets: []string{"0/0/0", "0/0/0", "2000/20/1", "0/0/0"},
+		},
+		{
+			name:    "single reading",
+			data:    []modem.ErrorExport{counterReading(10, "1000", "10", "0")},
+			minutes: 30,
+			samples: 1,
+			totals:  "0/0/0",
+			buckets: []string{"0/0/0", "0/0/0"},
+		},
+		{
+			name:    "empty window",
+			data:    []modem.ErrorExport{counterReading(0, "1000", "10", "0")},
+			minutes: 0,
+			samples: 1,
+			totals:  "0/0/0",
+			buckets: []string{"0/0/0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ComputeErrorRates(tt.data, t0, at(tt.minutes), 15*time.Minute)
+			if err != nil {
+				t.Fatalf("ComputeErrorRates failed: %v", err)
+			}
+			if len(rates) != 1 {
+				t.Fatalf("expected one channel, got %d", len(rates))
+			}
+			rate := rates[0]
+			if rate.Samples != tt.samples || rate.Resets != tt.resets {
+				t.Errorf("samples %d, resets %d, want %d and %d", rate.Samples, rate.Resets, tt.samples, tt.resets)
+			}
+			if got := bucketCounts([]ErrorBucket{rate.ErrorBucket})[0]; got != tt.totals {
+				t.Errorf("totals = %s, want %s", got, tt.totals)
+			}
+			if got := bucketCounts(rate.Buckets); !reflect.DeepEqual(got, tt.buckets) {
+				t.Errorf("buckets = %v, want %v", got, tt.buckets)
+			}
+			for i, minutes := range tt.starts {
+				if !rate.Buckets[i].Start.Equal(at(minutes)) {
+					t.Errorf("bucket %d starts at %s, want %s", i, rate.Buckets[i].Start, at(minutes))
+				}
+			}
+		})
+	}
+}
+
+func TestComputeErrorRatesSortsChannels(t *testing.T) {
+	var data []modem.ErrorExport
+	for _, id := range []string{"10", "OFDM", "2", "1"} {
+		r := counterReading(0, "0", "0", "0")
+		r.ChannelID = id
+		data = append(data, r)
+	}
+	rates, err := ComputeErrorRates(data, t0, at(60), time.Hour)
+	if err != nil {
+		t.Fatalf("ComputeErrorRates failed: %v", err)
+	}
+	var ids []string
+	for _, rate := range rates {
+		ids = append(ids, rate.ChannelID)
+	}
+	if want := []string{"1", "2", "10", "OFDM"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("channels = %v, want %v", ids, want)
+	}
+}
+
+func TestComputeErrorRatesBucketSize(t *testing.T) {
+	if _, err := ComputeErrorRates(nil, t0, at(60), 0); err == nil {
+		t.Error("expected an error for a zero bucket size")
+	}
+	_, err := ComputeErrorRates(nil, t0, t0.Add(30*24*time.Hour), time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "too many") {
+		t.Errorf("expected an error for too many buckets, got %v", err)
+	}
+}
+
+func TestErrorBucketPercent(t *testing.T) {
+	b := ErrorBucket{Unerrored: 9890, Correctable: 100, Uncorrectable: 10}
+	if b.Total() != 10000 || b.CorrectablePercent() != 1 || b.UncorrectablePercent() != 0.1 {
+		t.Errorf("total %d, correctable %v%%, uncorrectable %v%%", b.Total(), b.CorrectablePercent(), b.UncorrectablePercent())
+	}
+	if (ErrorBucket{}).UncorrectablePercent() != 0 {
+		t.Error("expected 0% for an empty bucket")
+	}
+}
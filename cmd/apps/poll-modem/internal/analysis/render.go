@@ -0,0 +1,414 @@
+package analysis
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const timeFormat = "2006-01-02 15:04:05"
+
+// FormatDuration formats a duration rounded to seconds, or minutes when
+// long, without zero units: "1h", "40m", "9h35m"
+func FormatDuration(d time.Duration) string {
+	if d >= time.Hour {
+		d = d.Round(time.Minute)
+	} else {
+		d = d.Round(time.Second)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// FormatCount formats a number with thousands separators
+func FormatCount(n uint64) string {
+	s := fmt.Sprintf("%d", n)
+	var sb strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// Describe formats a limit like "-7 .. 7 dBmV" or ">= 33 dB"
+func (l Limit) Describe(unit string) string {
+	switch {
+	case l.Min != nil && l.Max != nil:
+		return fmt.Sprintf("%g .. %g %s", *l.Min, *l.Max, unit)
+	case l.Min != nil:
+		return fmt.Sprintf(">= %g %s", *l.Min, unit)
+	case l.Max != nil:
+		return fmt.Sprintf("<= %g %s", *l.Max, unit)
+	}
+	return "not checked"
+}
+
+// ThresholdRow is one metric of the thresholds table
+type ThresholdRow struct {
+	Name     string
+	Warning  string
+	Critical string
+}
+
+// ThresholdRows describes the thresholds the report was checked against
+func (r *Report) ThresholdRows() []ThresholdRow {
+	row := func(name string, m MetricThresholds, unit string) ThresholdRow {
+		return ThresholdRow{name, m.Warning.Describe(unit), m.Critical.Describe(unit)}
+	}
+	t := r.Thresholds
+	ret := []ThresholdRow{
+		row("Downstream power", t.DownstreamPower, "dBmV"),
+		row("Downstream SNR", t.DownstreamSNR, "dB"),
+		row("Upstream power", t.UpstreamPower, "dBmV"),
+		row(fmt.Sprintf("Correctable codewords per %s", FormatDuration(r.Bucket)), t.CorrectablePercent, "%"),
+		row(fmt.Sprintf("Uncorrectable codewords per %s", FormatDuration(r.Bucket)), t.UncorrectablePercent, "%"),
+	}
+	if t.Unlocked {
+		ret = append(ret, ThresholdRow{"Lock status", "", "not locked"})
+	}
+	return ret
+}
+
+func minMeanMax(t *Trend) string {
+	if t == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f / %.1f / %.1f", t.Min, t.Mean, t.Max)
+}
+
+func eventWorst(e Event) string {
+	if e.Metric == MetricLock {
+		return "not locked"
+	}
+	return fmt.Sprintf("%.3g %s", e.Worst, e.Metric.Unit())
+}
+
+func eventLimit(e Event) string {
+	if e.Metric == MetricLock {
+		return "locked"
+	}
+	return fmt.Sprintf("%g %s", e.Limit, e.Metric.Unit())
+}
+
+func eventEnd(e Event) string {
+	if e.Ongoing {
+		return "ongoing"
+	}
+	return e.End.Format(timeFormat)
+}
+
+func codewordSummary(b ErrorBucket) string {
+	return fmt.Sprintf("%s codewords, %s correctable (%.4f%%), %s uncorrectable (%.4f%%)",
+		FormatCount(b.Total()),
+		FormatCount(b.Correctable), b.CorrectablePercent(),
+		FormatCount(b.Uncorrectable), b.UncorrectablePercent())
+}
+
+func modemSummary(r *Report) string {
+	cm := r.CableModem
+	if cm == nil {
+		return "unknown"
+	}
+	details := []string{}
+	if cm.HWVersion != "" {
+		details = append(details, "hardware "+cm.HWVersion)
+	}
+	if cm.DownloadVersion != "" {
+		details = append(details, "firmware "+cm.DownloadVersion)
+	}
+	summary := strings.TrimSpace(cm.Vendor + " " + cm.Model)
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+	return summary
+}
+
+// plural formats a count with a noun, adding an s unless the count is one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// WriteMarkdown writes the report as markdown, with unicode sparklines
+func WriteMarkdown(w io.Writer, r *Report) error {
+	var sb strings.Builder
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&sb, format, args...)
+	}
+
+	p("# Cable modem signal report\n\n")
+	p("- **Period:** %s to %s\n", r.From.Format(timeFormat), r.To.Format(timeFormat))
+	p("- **Modem:** %s\n", modemSummary(r))
+	if r.Polls > 0 {
+		p("- **Readings:** %d polls from %s to %s\n", r.Polls, r.FirstReading.Format(timeFormat), r.LastReading.Format(timeFormat))
+	} else {
+		p("- **Readings:** none\n")
+	}
+	p("- **Generated:** %s\n\n", r.Generated.Format(timeFormat))
+
+	downstream, upstream := r.Channels(Downstream), r.Channels(Upstream)
+	p("## Summary\n\n")
+	p("- %d critical and %d warning out-of-spec events\n", r.EventCount(SeverityCritical), r.EventCount(SeverityWarning))
+	p("- Downstream: %s, %d with out-of-spec readings\n", plural(len(downstream), "channel"), channelsWithEvents(downstream))
+	p("- Upstream: %s, %d with out-of-spec readings\n", plural(len(upstream), "channel"), channelsWithEvents(upstream))
+	p("- Downstream codewords: %s\n", codewordSummary(r.TotalCodewords()))
+	if resets := r.Resets(); resets > 0 {
+		p("- The codeword counters were reset %s, usually a modem reboot\n", plural(resets, "time"))
+	}
+	p("\n")
+
+	p("## Out-of-spec events\n\n")
+	if len(r.Events) == 0 {
+		p("No out-of-spec readings.\n\n")
+	} else {
+		p("| Severity | Start | End | Duration | Readings | Worst | Limit | Description |\n")
+		p("|---|---|---|---|---|---|---|---|\n")
+		for _, e := range r.Events {
+			p("| %s | %s | %s | %s | %d | %s | %s | %s |\n",
+				e.Severity, e.Start.Format(timeFormat), eventEnd(e), FormatDuration(e.Duration()),
+				e.Readings, eventWorst(e), eventLimit(e), e.Description())
+		}
+		p("\n")
+	}
+
+	p("## Downstream channels\n\n")
+	p("| Channel | Frequency | Modulation | SNR min / avg / max (dB) | SNR | Power min / avg / max (dBmV) | Power | Locked | Events |\n")
+	p("|---|---|---|---|---|---|---|---|---|\n")
+	for _, c := range downstream {
+		p("| %s | %s | %s | %s | `%s` | %s | `%s` | %.1f%% | %s |\n",
+			c.ChannelID, c.Frequency, c.Modulation,
+			minMeanMax(c.SNR), r.TrendSparkline(c.SNR),
+			minMeanMax(c.Power), r.TrendSparkline(c.Power),
+			c.LockedPercent, channelEvents(c))
+	}
+	p("\n")
+
+	p("## Upstream channels\n\n")
+	p("| Channel | Frequency | Modulation | Power min / avg / max (dBmV) | Power | Locked | Events |\n")
+	p("|---|---|---|---|---|---|---|\n")
+	for _, c := range upstream {
+		p("| %s | %s | %s | %s | `%s` | %.1f%% | %s |\n",
+			c.ChannelID, c.Frequency, c.Modulation,
+			minMeanMax(c.Power), r.TrendSparkline(c.Power),
+			c.LockedPercent, channelEvents(c))
+	}
+	p("\n")
+
+	p("## Codeword errors\n\n")
+	p("| Channel | Codewords | Correctable | Uncorrectable | Uncorrectable per %s | Resets |\n", FormatDuration(r.Bucket))
+	p("|---|---|---|---|---|---|\n")
+	for _, rate := range r.ErrorRates {
+		p("| %s | %s | %s (%.4f%%) | %s (%.4f%%) | `%s` | %d |\n",
+			rate.ChannelID, FormatCount(rate.Total()),
+			FormatCount(rate.Correctable), rate.CorrectablePercent(),
+			FormatCount(rate.Uncorrectable), rate.UncorrectablePercent(),
+			ErrorSparkline(rate), rate.Resets)
+	}
+	p("\n")
+
+	p("## Thresholds\n\n")
+	p("| Metric | Warning outside | Critical outside |\n")
+	p("|---|---|---|\n")
+	for _, t := range r.ThresholdRows() {
+		p("| %s | %s | %s |\n", t.Name, t.Warning, t.Critical)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func channelsWithEvents(channels []*ChannelSummary) int {
+	n := 0
+	for _, c := range channels {
+		if c.Events > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func channelEvents(c *ChannelSummary) string {
+	if c.Events == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d (%s)", c.Events, c.Worst)
+}
+
+// svgSparkline draws values as an inline SVG line, NaN values are gaps
+func svgSparkline(values []float64) template.HTML {
+	const width, height = 160.0, 28.0
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return ""
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg class="spark" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`, width, height, width, height)
+	type point struct{ x, y float64 }
+	var segment []point
+	flush := func() {
+		switch {
+		case len(segment) == 1:
+			// A single point is drawn as a dot
+			fmt.Fprintf(&sb, `<circle r="1.5" cx="%.1f" cy="%.1f"/>`, segment[0].x, segment[0].y)
+		case len(segment) > 1:
+			coordinates := make([]string, len(segment))
+			for i, p := range segment {
+				coordinates[i] = fmt.Sprintf("%.1f,%.1f", p.x, p.y)
+			}
+			fmt.Fprintf(&sb, `<polyline points="%s"/>`, strings.Join(coordinates, " "))
+		}
+		segment = nil
+	}
+	step := width / math.Max(float64(len(values)-1), 1)
+	for i, v := range values {
+		if math.IsNaN(v) {
+			flush()
+			continue
+		}
+		segment = append(segment, point{
+			x: float64(i) * step,
+			y: height - 2 - (v-lo)/(hi-lo)*(height-4),
+		})
+	}
+	flush()
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time":          func(t time.Time) string { return t.Format(timeFormat) },
+	"duration":      FormatDuration,
+	"count":         FormatCount,
+	"minMeanMax":    minMeanMax,
+	"eventWorst":    eventWorst,
+	"eventLimit":    eventLimit,
+	"eventEnd":      eventEnd,
+	"channelEvents": channelEvents,
+	"codewords":     codewordSummary,
+	"modem":         modemSummary,
+	"withEvents":    channelsWithEvents,
+	"plural":        plural,
+	"trend": func(r *Report, t *Trend) template.HTML {
+		if t == nil {
+			return ""
+		}
+		return svgSparkline(Downsample(t.Points, r.From, r.To, sparklineWidth))
+	},
+	"errorTrend": func(rate ErrorRate) template.HTML {
+		values := make([]float64, len(rate.Buckets))
+		for i, b := range rate.Buckets {
+			values[i] = float64(b.Uncorrectable)
+			if b.Total() == 0 {
+				values[i] = math.NaN()
+			}
+		}
+		return svgSparkline(values)
+	},
+	"critical":   func() Severity { return SeverityCritical },
+	"warning":    func() Severity { return SeverityWarning },
+	"downstream": func() Direction { return Downstream },
+	"upstream":   func() Direction { return Upstream },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Cable modem signal report {{ time .From }} to {{ time .To }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 14px; }
+th { background: #f0f0f0; }
+tr.critical td { background: #fde2e2; }
+tr.warning td { background: #fff4d6; }
+svg.spark polyline { fill: none; stroke: #2a6fdb; stroke-width: 1.5; }
+svg.spark circle { fill: #2a6fdb; }
+</style>
+</head>
+<body>
+<h1>Cable modem signal report</h1>
+<ul>
+<li><b>Period:</b> {{ time .From }} to {{ time .To }}</li>
+<li><b>Modem:</b> {{ modem . }}</li>
+<li><b>Readings:</b> {{ if .Polls }}{{ .Polls }} polls from {{ time .FirstReading }} to {{ time .LastReading }}{{ else }}none{{ end }}</li>
+<li><b>Generated:</b> {{ time .Generated }}</li>
+</ul>
+{{ $downstream := .Channels downstream }}{{ $upstream := .Channels upstream }}
+<h2>Summary</h2>
+<ul>
+<li>{{ .EventCount critical }} critical and {{ .EventCount warning }} warning out-of-spec events</li>
+<li>Downstream: {{ plural (len $downstream) "channel" }}, {{ withEvents $downstream }} with out-of-spec readings</li>
+<li>Upstream: {{ plural (len $upstream) "channel" }}, {{ withEvents $upstream }} with out-of-spec readings</li>
+<li>Downstream codewords: {{ codewords .TotalCodewords }}</li>
+{{ with .Resets }}<li>The codeword counters were reset {{ plural . "time" }}, usually a modem reboot</li>{{ end }}
+</ul>
+
+<h2>Out-of-spec events</h2>
+{{ if .Events }}
+<table>
+<tr><th>Severity</th><th>Start</th><th>End</th><th>Duration</th><th>Readings</th><th>Worst</th><th>Limit</th><th>Description</th></tr>
+{{ range .Events }}<tr class="{{ .Severity }}"><td>{{ .Severity }}</td><td>{{ time .Start }}</td><td>{{ eventEnd . }}</td><td>{{ duration .Duration }}</td><td>{{ .Readings }}</td><td>{{ eventWorst . }}</td><td>{{ eventLimit . }}</td><td>{{ .Description }}</td></tr>
+{{ end }}</table>
+{{ else }}<p>No out-of-spec readings.</p>{{ end }}
+
+<h2>Downstream channels</h2>
+<table>
+<tr><th>Channel</th><th>Frequency</th><th>Modulation</th><th>SNR min / avg / max (dB)</th><th>SNR</th><th>Power min / avg / max (dBmV)</th><th>Power</th><th>Locked</th><th>Events</th></tr>
+{{ range $downstream }}<tr class="{{ if .Events }}{{ .Worst }}{{ end }}"><td>{{ .ChannelID }}</td><td>{{ .Frequency }}</td><td>{{ .Modulation }}</td><td>{{ minMeanMax .SNR }}</td><td>{{ trend $ .SNR }}</td><td>{{ minMeanMax .Power }}</td><td>{{ trend $ .Power }}</td><td>{{ printf "%.1f%%" .LockedPercent }}</td><td>{{ channelEvents . }}</td></tr>
+{{ end }}</table>
+
+<h2>Upstream channels</h2>
+<table>
+<tr><th>Channel</th><th>Frequency</th><th>Modulation</th><th>Power min / avg / max (dBmV)</th><th>Power</th><th>Locked</th><th>Events</th></tr>
+{{ range $upstream }}<tr class="{{ if .Events }}{{ .Worst }}{{ end }}"><td>{{ .ChannelID }}</td><td>{{ .Frequency }}</td><td>{{ .Modulation }}</td><td>{{ minMeanMax .Power }}</td><td>{{ trend $ .Power }}</td><td>{{ printf "%.1f%%" .LockedPercent }}</td><td>{{ channelEvents . }}</td></tr>
+{{ end }}</table>
+
+<h2>Codeword errors</h2>
+<table>
+<tr><th>Channel</th><th>Codewords</th><th>Correctable</th><th>Uncorrectable</th><th>Uncorrectable per {{ duration .Bucket }}</th><th>Resets</th></tr>
+{{ range .ErrorRates }}<tr><td>{{ .ChannelID }}</td><td>{{ count .Total }}</td><td>{{ count .Correctable }} ({{ printf "%.4f%%" .CorrectablePercent }})</td><td>{{ count .Uncorrectable }} ({{ printf "%.4f%%" .UncorrectablePercent }})</td><td>{{ errorTrend . }}</td><td>{{ .Resets }}</td></tr>
+{{ end }}</table>
+
+<h2>Thresholds</h2>
+<table>
+<tr><th>Metric</th><th>Warning outside</th><th>Critical outside</th></tr>
+{{ range .ThresholdRows }}<tr><td>{{ .Name }}</td><td>{{ .Warning }}</td><td>{{ .Critical }}</td></tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a self-contained HTML page with SVG
+// sparklines
+func WriteHTML(w io.Writer, r *Report) error {
+	if err := htmlTemplate.Execute(w, r); err != nil {
+		return errors.Wrap(err, "failed to render HTML report")
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+	"github.com/pkg/errors"
+)
+
+// Report is the analysis of the readings of a time window
+type Report struct {
+	From       time.Time
+	To         time.Time
+	Generated  time.Time
+	CableModem *modem.CableModem
+	// Polls counts the distinct reading times
+	Polls        int
+	FirstReading time.Time
+	LastReading  time.Time
+	Bucket       time.Duration
+	Thresholds   Thresholds
+	Trends       []Trend
+	ErrorRates   []ErrorRate
+	Events       []Event
+}
+
+// BuildReport loads the readings of a window from the database and
+// analyzes them
+func BuildReport(db *modem.Database, from, to time.Time, bucket time.Duration, thresholds Thresholds) (*Report, error) {
+	if !to.After(from) {
+		return nil, errors.Errorf("empty time window %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	data, err := db.ExportRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	cableModem, err := db.LatestCableModem(to)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := ComputeErrorRates(data.Errors, from, to, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	series := CollectSeries(data)
+	report := &Report{
+		From:       from,
+		To:         to,
+		Generated:  time.Now(),
+		CableModem: cableModem,
+		Bucket:     bucket,
+		Thresholds: thresholds,
+		Trends:     ComputeTrends(series),
+		ErrorRates: rates,
+		Events:     FindEvents(series, rates, bucket, thresholds),
+	}
+
+	polls := map[int64]bool{}
+	for _, ch := range data.Downstream {
+		polls[ch.Timestamp.UnixNano()] = true
+		if report.FirstReading.IsZero() || ch.Timestamp.Before(report.FirstReading) {
+			report.FirstReading = ch.Timestamp
+		}
+		if ch.Timestamp.After(report.LastReading) {
+			report.LastReading = ch.Timestamp
+		}
+	}
+	report.Polls = len(polls)
+
+	return report, nil
+}
+
+// EventCount counts the events of a severity
+func (r *Report) EventCount(severity Severity) int {
+	n := 0
+	for _, e := range r.Events {
+		if e.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// ChannelSummary combines the trends of a channel
+type ChannelSummary struct {
+	Direction  Direction
+	ChannelID  string
+	Frequency  string
+	Modulation string
+	Power      *Trend
+	SNR        *Trend
+	// LockedPercent is the share of readings with the channel locked
+	LockedPercent float64
+	// Events counts the out-of-spec events of the channel
+	Events int
+	// Worst is the worst severity of the channel's events
+	Worst Severity
+}
+
+// Channels returns a summary per channel of a direction
+func (r *Report) Channels(direction Direction) []*ChannelSummary {
+	ret := []*ChannelSummary{}
+	byID := map[string]*ChannelSummary{}
+	for i := range r.Trends {
+		t := &r.Trends[i]
+		if t.Direction != direction {
+			continue
+		}
+		c, ok := byID[t.ChannelID]
+		if !ok {
+			c = &ChannelSummary{
+				Direction:     direction,
+				ChannelID:     t.ChannelID,
+				Frequency:     t.Frequency,
+				Modulation:    t.Modulation,
+				LockedPercent: 100,
+			}
+			byID[t.ChannelID] = c
+			ret = append(ret, c)
+		}
+		switch t.Metric {
+		case MetricPower:
+			c.Power = t
+		case MetricSNR:
+			c.SNR = t
+		case MetricLock:
+			c.LockedPercent = t.Mean * 100
+		}
+	}
+	for _, e := range r.Events {
+		if c, ok := byID[e.ChannelID]; ok && e.Direction == direction {
+			c.Events++
+			if e.Severity > c.Worst {
+				c.Worst = e.Severity
+			}
+		}
+	}
+	return ret
+}
+
+// TotalCodewords sums the codeword counts of all channels
+func (r *Report) TotalCodewords() ErrorBucket {
+	total := ErrorBucket{Start: r.From}
+	for _, rate := range r.ErrorRates {
+		total.Unerrored += rate.Unerrored
+		total.Correctable += rate.Correctable
+		total.Uncorrectable += rate.Uncorrectable
+	}
+	return total
+}
+
+// Resets returns the largest number of counter resets seen on a channel,
+// which is usually the number of modem reboots
+func (r *Report) Resets() int {
+	n := 0
+	for _, rate := range r.ErrorRates {
+		n = max(n, rate.Resets)
+	}
+	return n
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as block characters, NaN values are blanks
+func Sparkline(values []float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(' ')
+		case hi == lo:
+			sb.WriteRune(sparkBlocks[0])
+		default:
+			i := int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+			sb.WriteRune(sparkBlocks[i])
+		}
+	}
+	return sb.String()
+}
+
+// sparklineWidth is the number of points of the report sparklines
+const sparklineWidth = 48
+
+// TrendSparkline draws the trend over the report window
+func (r *Report) TrendSparkline(t *Trend) string {
+	if t == nil {
+		return ""
+	}
+	return Sparkline(Downsample(t.Points, r.From, r.To, sparklineWidth))
+}
+
+// ErrorSparkline draws the uncorrectable codewords per bucket, buckets
+// without readings are blanks
+func ErrorSparkline(rate ErrorRate) string {
+	values := make([]float64, len(rate.Buckets))
+	for i, b := range rate.Buckets {
+		values[i] = float64(b.Uncorrectable)
+		if b.Total() == 0 {
+			values[i] = math.NaN()
+		}
+	}
+	return Sparkline(values)
+}
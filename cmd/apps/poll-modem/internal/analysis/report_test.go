@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+)
+
+// series returns the readings of a metric, one every ten minutes from t0
+func series(direction Direction, channelID string, metric Metric, values ...float64) *Series {
+	s := &Series{SeriesKey: SeriesKey{direction, channelID, metric}}
+	for i, v := range values {
+		s.Points = append(s.Points, Point{Time: at(10 * i), Value: v})
+	}
+	return s
+}
+
+// describe formats an event with the minutes of its start and end
+func describe(e Event) string {
+	return fmt.Sprintf("%s/%s/%s %s %d-%d x%d worst %.4g limit %g ongoing=%t",
+		e.Direction, e.ChannelID, e.Metric, e.Severity,
+		int(e.Start.Sub(t0).Minutes()), int(e.End.Sub(t0).Minutes()),
+		e.Readings, e.Worst, e.Limit, e.Ongoing)
+}
+
+func TestFindEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		series     []*Series
+		rates      []ErrorRate
+		thresholds func(*Thresholds)
+		want       []string
+	}{
+		{
+			name:   "in spec",
+			series: []*Series{series(Downstream, "1", MetricSNR, 38, 37, 39)},
+		},
+		{
+			name:   "ended and ongoing",
+			series: []*Series{series(Downstream, "1", MetricSNR, 38, 32, 29, 31, 38, 32)},
+			want: []string{
+				"downstream/1/snr critical 10-30 x3 worst 29 limit 30 ongoing=false",
+				"downstream/1/snr warning 50-50 x1 worst 32 limit 33 ongoing=true",
+			},
+		},
+		{
+			name: "worst value of the worst severity",
+			series: []*Series{
+				series(Downstream, "2", MetricPower, 8, 10, 9, 0, -8, -12, -9, -16, -10),
+			},
+			want: []string{
+				"downstream/2/power critical 40-80 x5 worst -16 limit -15 ongoing=true",
+				"downstream/2/power warning 0-20 x3 worst 10 limit 7 ongoing=false",
+			},
+		},
+		{
+			name: "upstream power",
+			series: []*Series{
+				series(Upstream, "3", MetricPower, 45, 49, 52, 50, 44),
+			},
+			want: []string{"upstream/3/power critical 10-30 x3 worst 52 limit 51 ongoing=false"},
+		},
+		{
+			name:   "unlocked channel",
+			series: []*Series{series(Upstream, "1", MetricLock, 1, 0, 0, 1)},
+			want:   []string{"upstream/1/lock critical 10-20 x2 worst 0 limit 1 ongoing=false"},
+		},
+		{
+			name:       "unlocked check disabled",
+			series:     []*Series{series(Upstream, "1", MetricLock, 1, 0, 0, 1)},
+			thresholds: func(th *Thresholds) { th.Unlocked = false },
+		},
+		{
+			name:   "unchecked metric",
+			series: []*Series{series(Upstream, "1", MetricSNR, 0, 0)},
+		},
+		{
+			name: "error rates",
+			rates: []ErrorRate{{
+				ChannelID: "4",
+				Buckets: []ErrorBucket{
+					{Start: at(0), Unerrored: 10000},
+					{Start: at(15), Unerrored: 9800, Correctable: 200},
+					// No readings, the event goes on
+					{Start: at(30)},
+					{Start: at(45), Unerrored: 9000, Correctable: 980, Uncorrectable: 20},
+				},
+			}},
+			// The correctable event starts as a warning and becomes critical
+			want: []string{
+				"downstream/4/correctable_percent critical 15-60 x2 worst 9.8 limit 5 ongoing=true",
+				"downstream/4/uncorrectable_percent critical 45-60 x1 worst 0.2 limit 0.1 ongoing=true",
+			},
+		},
+		{
+			name: "sorted by severity and start",
+			series: []*Series{
+				series(Downstream, "2", MetricSNR, 32, 38),
+				series(Downstream, "1", MetricSNR, 38, 32),
+				series(Downstream, "3", MetricPower, 20),
+			},
+			want: []string{
+				"downstream/3/power critical 0-0 x1 worst 20 limit 15 ongoing=true",
+				"downstream/2/snr warning 0-0 x1 worst 32 limit 33 ongoing=false",
+				"downstream/1/snr warning 10-10 x1 worst 32 limit 33 ongoing=true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds := DefaultThresholds()
+			if tt.thresholds != nil {
+				tt.thresholds(&thresholds)
+			}
+			var got []string
+			for _, e := range FindEvents(tt.series, tt.rates, 15*time.Minute, thresholds) {
+				got = append(got, describe(e))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// storePoll stores one poll of a modem with one downstream, upstream and
+// error channel
+func storePoll(t *testing.T, db *modem.Database, sessionID int64, minutes int, snr, power, uncorrectable string) {
+	t.Helper()
+	info := &modem.ModemInfo{
+		CableModem: modem.CableModem{Model: "CGM4331COM", Vendor: "Technicolor"},
+		Downstream: []modem.Channel{
+			{ChannelID: "1", LockStatus: "Locked", Frequency: "597 MHz", SNR: snr, PowerLevel: "1.2 dBmV", Modulation: "256QAM"},
+		},
+		Upstream: []modem.Channel{
+			{ChannelID: "1", LockStatus: "Locked", Frequency: "17 MHz", PowerLevel: power, Modulation: "ATDMA"},
+		},
+		ErrorCodewords: []modem.ErrorChannel{
+			{ChannelID: "1", UnerroredCodewords: fmt.Sprint(100000 * (minutes + 100)), CorrectableCodewords: "10", UncorrectableCodewords: uncorrectable},
+		},
+		LastUpdated: at(minutes),
+	}
+	if err := db.StoreModemInfo(sessionID, info); err != nil {
+		t.Fatalf("StoreModemInfo failed: %v", err)
+	}
+}
+
+func openTestDatabase(t *testing.T) (*modem.Database, int64) {
+	t.Helper()
+	db, err := modem.OpenDatabase(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("OpenDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sessionID, err := db.StartSession()
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	return db, sessionID
+}
+
+func TestBuildReport(t *testing.T) {
+	db, sessionID := openTestDatabase(t)
+	// Before the window, not loaded
+	storePoll(t, db, sessionID, -10, "20 dB", "40 dBmV", "0")
+	storePoll(t, db, sessionID, 0, "38.5 dB", "40 dBmV", "0")
+	storePoll(t, db, sessionID, 10, "31.9 dB", "49.5 dBmV", "0")
+	storePoll(t, db, sessionID, 20, "38.1 dB", "41 dBmV", "5000")
+	storePoll(t, db, sessionID, 30, "38.4 dB", "41 dBmV", "5000")
+	// At the end of the window, excluded
+	storePoll(t, db, sessionID, 60, "10 dB", "60 dBmV", "9000")
+
+	report, err := BuildReport(db, t0, at(60), 15*time.Minute, DefaultThresholds())
+	if err != nil {
+		t.Fatalf("BuildReport failed: %v", err)
+	}
+
+	if report.Polls != 4 || !report.FirstReading.Equal(at(0)) || !report.LastReading.Equal(at(30)) {
+		t.Errorf("polls %d from %s to %s", report.Polls, report.FirstReading, report.LastReading)
+	}
+	if report.CableModem == nil || report.CableModem.Model != "CGM4331COM" {
+		t.Errorf("cable modem = %+v", report.CableModem)
+	}
+
+	var events []string
+	for _, e := range report.Events {
+		events = append(events, describe(e))
+	}
+	want := []string{
+		"downstream/1/uncorrectable_percent critical 15-30 x1 worst 0.4975 limit 0.1 ongoing=false",
+		"downstream/1/snr warning 10-10 x1 worst 31.9 limit 33 ongoing=false",
+		"upstream/1/power warning 10-10 x1 worst 49.5 limit 48 ongoing=false",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events:\n got %q\nwant %q", events, want)
+	}
+
+	if len(report.ErrorRates) != 1 {
+		t.Fatalf("expected one error rate, got %d", len(report.ErrorRates))
+	}
+	rate := report.ErrorRates[0]
+	// The first reading of the window is the base of the deltas
+	if got := bucketCounts(rate.Buckets); !reflect.DeepEqual(got, []string{"1000000/0/0", "1000000/0/5000", "1000000/0/0", "0/0/0"}) {
+		t.Errorf("buckets = %v", got)
+	}
+
+	channels := report.Channels(Downstream)
+	if len(channels) != 1 || channels[0].SNR == nil || channels[0].SNR.Min != 31.9 || channels[0].LockedPercent != 100 {
+		t.Fatalf("downstream channels = %+v", channels)
+	}
+	if channels[0].Events != 2 || channels[0].Worst != SeverityCritical {
+		t.Errorf("channel events %d, worst %s", channels[0].Events, channels[0].Worst)
+	}
+}
+
+func TestBuildReportEmptyWindow(t *testing.T) {
+	db, _ := openTestDatabase(t)
+	if _, err := BuildReport(db, t0, t0, time.Hour, DefaultThresholds()); err == nil {
+		t.Error("expected an error for an empty window")
+	}
+
+	report, err := BuildReport(db, t0, at(60), time.Hour, DefaultThresholds())
+	if err != nil {
+		t.Fatalf("BuildReport failed: %v", err)
+	}
+	if report.Polls != 0 || report.CableModem != nil || len(report.Events) != 0 {
+		t.Errorf("expected an empty report, got %d polls, %d events", report.Polls, len(report.Events))
+	}
+}
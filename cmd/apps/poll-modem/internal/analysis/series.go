@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/poll-modem/internal/modem"
+)
+
+// Direction of a channel
+type Direction string
+
+const (
+	Downstream Direction = "downstream"
+	Upstream   Direction = "upstream"
+)
+
+// Metric is a channel measurement
+type Metric string
+
+const (
+	MetricPower                Metric = "power"
+	MetricSNR                  Metric = "snr"
+	MetricLock                 Metric = "lock"
+	MetricCorrectablePercent   Metric = "correctable_percent"
+	MetricUncorrectablePercent Metric = "uncorrectable_percent"
+)
+
+// Unit returns the unit of a metric
+func (m Metric) Unit() string {
+	switch m {
+	case MetricPower:
+		return "dBmV"
+	case MetricSNR:
+		return "dB"
+	case MetricCorrectablePercent, MetricUncorrectablePercent:
+		return "%"
+	}
+	return ""
+}
+
+// Point is one reading of a metric
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// SeriesKey identifies the readings of one metric of one channel
+type SeriesKey struct {
+	Direction Direction
+	ChannelID string
+	Metric    Metric
+}
+
+// Series are the readings of one metric of one channel over time
+type Series struct {
+	SeriesKey
+	Frequency  string
+	Modulation string
+	Points     []Point
+}
+
+var numberRegex = regexp.MustCompile(`[-+]?\d+(\.\d+)?`)
+
+// ParseValue extracts the number from a modem value like "38.6 dB" or
+// "-2.1 dBmV"
+func ParseValue(s string) (float64, bool) {
+	match := numberRegex.FindString(s)
+	if match == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// IsLocked reports whether a lock status means the channel is locked
+func IsLocked(status string) bool {
+	return strings.EqualFold(strings.TrimSpace(status), "locked")
+}
+
+// CollectSeries groups the exported channel readings by channel and metric
+func CollectSeries(data *modem.ExportResult) []*Series {
+	byKey := map[SeriesKey]*Series{}
+	get := func(key SeriesKey, frequency, modulation string) *Series {
+		s, ok := byKey[key]
+		if !ok {
+			s = &Series{SeriesKey: key}
+			byKey[key] = s
+		}
+		// Keep the latest frequency and modulation
+		if frequency != "" {
+			s.Frequency = frequency
+		}
+		if modulation != "" {
+			s.Modulation = modulation
+		}
+		return s
+	}
+	add := func(key SeriesKey, frequency, modulation string, t time.Time, value string) {
+		v, ok := ParseValue(value)
+		if !ok {
+			return
+		}
+		s := get(key, frequency, modulation)
+		s.Points = append(s.Points, Point{Time: t, Value: v})
+	}
+	// The lock series is 1 while the channel is locked and 0 otherwise
+	addLock := func(key SeriesKey, frequency, modulation string, t time.Time, status string) {
+		v := 0.0
+		if IsLocked(status) {
+			v = 1
+		}
+		s := get(key, frequency, modulation)
+		s.Points = append(s.Points, Point{Time: t, Value: v})
+	}
+
+	for _, ch := range data.Downstream {
+		add(SeriesKey{Downstream, ch.ChannelID, MetricPower}, ch.Frequency, ch.Modulation, ch.Timestamp, ch.PowerLevel)
+		add(SeriesKey{Downstream, ch.ChannelID, MetricSNR}, ch.Frequency, ch.Modulation, ch.Timestamp, ch.SNR)
+		addLock(SeriesKey{Downstream, ch.ChannelID, MetricLock}, ch.Frequency, ch.Modulation, ch.Timestamp, ch.LockStatus)
+	}
+	for _, ch := range data.Upstream {
+		add(SeriesKey{Upstream, ch.ChannelID, MetricPower}, ch.Frequency, ch.Modulation, ch.Timestamp, ch.PowerLevel)
+		addLock(SeriesKey{Upstream, ch.ChannelID, MetricLock}, ch.Frequency, ch.Modulation, ch.Timestamp, ch.LockStatus)
+	}
+
+	ret := make([]*Series, 0, len(byKey))
+	for _, s := range byKey {
+		sort.SliceStable(s.Points, func(i, j int) bool {
+			return s.Points[i].Time.Before(s.Points[j].Time)
+		})
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].SeriesKey.less(ret[j].SeriesKey)
+	})
+	return ret
+}
+
+func (k SeriesKey) less(o SeriesKey) bool {
+	if k.Direction != o.Direction {
+		return k.Direction == Downstream
+	}
+	if k.ChannelID != o.ChannelID {
+		return channelLess(k.ChannelID, o.ChannelID)
+	}
+	return k.Metric < o.Metric
+}
+
+// channelLess sorts channel IDs numerically when they are numbers
+func channelLess(a, b string) bool {
+	ai, aErr := strconv.Atoi(a)
+	bi, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return ai < bi
+	}
+	if (aErr == nil) != (bErr == nil) {
+		return aErr == nil
+	}
+	return a < b
+}
+
+// Downsample averages points into at most n buckets of equal time width,
+// empty buckets are NaN
+func Downsample(points []Point, from, to time.Time, n int) []float64 {
+	ret := make([]float64, n)
+	counts := make([]int, n)
+	width := to.Sub(from) / time.Duration(n)
+	if width <= 0 {
+		width = 1
+	}
+	for _, p := range points {
+		if p.Time.Before(from) || !p.Time.Before(to) {
+			continue
+		}
+		i := int(p.Time.Sub(from) / width)
+		if i >= n {
+			i = n - 1
+		}
+		ret[i] += p.Value
+		counts[i]++
+	}
+	for i := range ret {
+		if counts[i] == 0 {
+			ret[i] = math.NaN()
+		} else {
+			ret[i] /= float64(counts[i])
+		}
+	}
+	return ret
+}
@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Event is a run of consecutive out-of-spec readings of a channel metric.
+// It ends with the first reading back in spec.
+type Event struct {
+	SeriesKey
+	// Severity is the worst severity during the event
+	Severity Severity
+	// Start and End are the first and last out-of-spec reading
+	Start time.Time
+	End   time.Time
+	// Readings counts the out-of-spec readings
+	Readings int
+	// Worst is the reading furthest out of spec, Limit the bound it violates
+	Worst float64
+	Limit float64
+	// Ongoing is set when the last reading of the window is out of spec
+	Ongoing bool
+}
+
+// Duration returns the time from the first to the last out-of-spec reading
+func (e Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// Description describes the event for reports and alert messages
+func (e Event) Description() string {
+	channel := fmt.Sprintf("%s channel %s", e.Direction, e.ChannelID)
+	switch e.Metric {
+	case MetricLock:
+		return fmt.Sprintf("%s not locked", channel)
+	case MetricCorrectablePercent, MetricUncorrectablePercent:
+		name := "correctable"
+		if e.Metric == MetricUncorrectablePercent {
+			name = "uncorrectable"
+		}
+		return fmt.Sprintf("%s %.3g%% %s codewords (limit %.3g%%)", channel, e.Worst, name, e.Limit)
+	}
+
+	name := "power"
+	if e.Metric == MetricSNR {
+		name = "SNR"
+	}
+	relation := "above"
+	if e.Worst < e.Limit {
+		relation = "below"
+	}
+	return fmt.Sprintf("%s %s %.1f %s %s %.1f %s", channel, name, e.Worst, e.Metric.Unit(), relation, e.Limit, e.Metric.Unit())
+}
+
+// thresholdsFor returns the thresholds of a metric, nil if it isn't checked
+func (t Thresholds) thresholdsFor(key SeriesKey) *MetricThresholds {
+	switch {
+	case key.Direction == Downstream && key.Metric == MetricPower:
+		return &t.DownstreamPower
+	case key.Direction == Downstream && key.Metric == MetricSNR:
+		return &t.DownstreamSNR
+	case key.Direction == Upstream && key.Metric == MetricPower:
+		return &t.UpstreamPower
+	case key.Metric == MetricCorrectablePercent:
+		return &t.CorrectablePercent
+	case key.Metric == MetricUncorrectablePercent:
+		return &t.UncorrectablePercent
+	}
+	return nil
+}
+
+// eventBuilder turns a sequence of checked readings into events
+type eventBuilder struct {
+	key     SeriesKey
+	current *Event
+	events  []Event
+}
+
+func (b *eventBuilder) add(t time.Time, value float64, severity Severity, limit float64) {
+	if severity == SeverityOK {
+		b.close(false)
+		return
+	}
+	if b.current == nil {
+		b.current = &Event{
+			SeriesKey: b.key,
+			Severity:  severity,
+			Start:     t,
+			Worst:     value,
+			Limit:     limit,
+		}
+	}
+	e := b.current
+	e.End = t
+	e.Readings++
+	if severity > e.Severity || (severity == e.Severity && math.Abs(value-limit) > math.Abs(e.Worst-e.Limit)) {
+		e.Severity, e.Worst, e.Limit = severity, value, limit
+	}
+}
+
+func (b *eventBuilder) close(ongoing bool) {
+	if b.current != nil {
+		b.current.Ongoing = ongoing
+		b.events = append(b.events, *b.current)
+		b.current = nil
+	}
+}
+
+// FindEvents checks the readings and codeword error rates against the
+// thresholds, and groups the out-of-spec readings into events
+func FindEvents(series []*Series, rates []ErrorRate, bucket time.Duration, thresholds Thresholds) []Event {
+	events := []Event{}
+
+	for _, s := range series {
+		b := &eventBuilder{key: s.SeriesKey}
+		if s.Metric == MetricLock {
+			if !thresholds.Unlocked {
+				continue
+			}
+			for _, p := range s.Points {
+				severity := SeverityOK
+				if p.Value == 0 {
+					severity = SeverityCritical
+				}
+				b.add(p.Time, p.Value, severity, 1)
+			}
+		} else {
+			metricThresholds := thresholds.thresholdsFor(s.SeriesKey)
+			if metricThresholds == nil {
+				continue
+			}
+			for _, p := range s.Points {
+				severity, limit := metricThresholds.Check(p.Value)
+				b.add(p.Time, p.Value, severity, limit)
+			}
+		}
+		b.close(true)
+		events = append(events, b.events...)
+	}
+
+	for _, rate := range rates {
+		for _, metric := range []Metric{MetricCorrectablePercent, MetricUncorrectablePercent} {
+			key := SeriesKey{Downstream, rate.ChannelID, metric}
+			metricThresholds := thresholds.thresholdsFor(key)
+			b := &eventBuilder{key: key}
+			for _, eb := range rate.Buckets {
+				// Buckets without readings neither start nor end an event
+				if eb.Total() == 0 {
+					continue
+				}
+				value := eb.CorrectablePercent()
+				if metric == MetricUncorrectablePercent {
+					value = eb.UncorrectablePercent()
+				}
+				severity, limit := metricThresholds.Check(value)
+				b.add(eb.Start, value, severity, limit)
+			}
+			b.close(true)
+			for _, e := range b.events {
+				e.End = e.End.Add(bucket)
+				events = append(events, e)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Severity != events[j].Severity {
+			return events[i].Severity > events[j].Severity
+		}
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].SeriesKey.less(events[j].SeriesKey)
+	})
+	return events
+}
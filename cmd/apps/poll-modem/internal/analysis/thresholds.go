@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of an out-of-spec reading
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "ok"
+	}
+}
+
+// ParseSeverity parses "warning" or "critical"
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityOK, errors.Errorf("unknown severity %q (expected warning or critical)", s)
+}
+
+// Limit is an allowed range, a nil bound is not checked
+type Limit struct {
+	Min *float64 `yaml:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty"`
+}
+
+// Check returns the violated bound, if any
+func (l Limit) Check(value float64) (float64, bool) {
+	if l.Min != nil && value < *l.Min {
+		return *l.Min, true
+	}
+	if l.Max != nil && value > *l.Max {
+		return *l.Max, true
+	}
+	return 0, false
+}
+
+// MetricThresholds are the warning and critical ranges of a metric
+type MetricThresholds struct {
+	Warning  Limit `yaml:"warning"`
+	Critical Limit `yaml:"critical"`
+}
+
+// Check returns the severity of a value and the bound it violates
+func (m MetricThresholds) Check(value float64) (Severity, float64) {
+	if limit, ok := m.Critical.Check(value); ok {
+		return SeverityCritical, limit
+	}
+	if limit, ok := m.Warning.Check(value); ok {
+		return SeverityWarning, limit
+	}
+	return SeverityOK, 0
+}
+
+// Thresholds are the limits readings are checked against
+type Thresholds struct {
+	// DownstreamPower in dBmV
+	DownstreamPower MetricThresholds `yaml:"downstream_power"`
+	// DownstreamSNR in dB
+	DownstreamSNR MetricThresholds `yaml:"downstream_snr"`
+	// UpstreamPower (transmit power) in dBmV
+	UpstreamPower MetricThresholds `yaml:"upstream_power"`
+	// CorrectablePercent is the share of correctable codewords per bucket
+	CorrectablePercent MetricThresholds `yaml:"correctable_percent"`
+	// UncorrectablePercent is the share of uncorrectable codewords per bucket
+	UncorrectablePercent MetricThresholds `yaml:"uncorrectable_percent"`
+	// Unlocked flags channels whose lock status isn't "Locked"
+	Unlocked bool `yaml:"unlocked"`
+}
+
+func f(v float64) *float64 {
+	return &v
+}
+
+// DefaultThresholds returns the commonly cited DOCSIS 3.0/3.1 ranges:
+// downstream power -7..+7 dBmV (-15..+15 still works), downstream SNR of
+// at least 33 dB for 256-QAM, upstream power 35..48 dBmV (above 51 the
+// modem is running out of headroom).
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		DownstreamPower: MetricThresholds{
+			Warning:  Limit{Min: f(-7), Max: f(7)},
+			Critical: Limit{Min: f(-15), Max: f(15)},
+		},
+		DownstreamSNR: MetricThresholds{
+			Warning:  Limit{Min: f(33)},
+			Critical: Limit{Min: f(30)},
+		},
+		UpstreamPower: MetricThresholds{
+			Warning:  Limit{Min: f(35), Max: f(48)},
+			Critical: Limit{Min: f(30), Max: f(51)},
+		},
+		CorrectablePercent: MetricThresholds{
+			Warning:  Limit{Max: f(1)},
+			Critical: Limit{Max: f(5)},
+		},
+		UncorrectablePercent: MetricThresholds{
+			Warning:  Limit{Max: f(0.01)},
+			Critical: Limit{Max: f(0.1)},
+		},
+		Unlocked: true,
+	}
+}
+
+// LoadThresholds reads a YAML file overriding the default thresholds, an
+// empty path returns the defaults
+func LoadThresholds(path string) (Thresholds, error) {
+	thresholds := DefaultThresholds()
+	if path == "" {
+		return thresholds, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return thresholds, errors.Wrap(err, "failed to read thresholds file")
+	}
+	if err := yaml.Unmarshal(data, &thresholds); err != nil {
+		return thresholds, errors.Wrapf(err, "failed to parse thresholds file %s", path)
+	}
+	return thresholds, nil
+}
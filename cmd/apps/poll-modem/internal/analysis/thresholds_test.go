@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeThresholds(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write thresholds: %v", err)
+	}
+	return path
+}
+
+func TestLoadThresholds(t *testing.T) {
+	defaults, err := LoadThresholds("")
+	if err != nil {
+		t.Fatalf("LoadThresholds failed: %v", err)
+	}
+	if *defaults.DownstreamSNR.Warning.Min != 33 || !defaults.Unlocked {
+		t.Errorf("expected the default thresholds, got %+v", defaults)
+	}
+
+	path := writeThresholds(t, `
+downstream_snr:
+  warning:
+    min: 35
+upstream_power:
+  critical:
+    max: 53
+unlocked: false
+`)
+	thresholds, err := LoadThresholds(path)
+	if err != nil {
+		t.Fatalf("LoadThresholds failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"overridden snr warning", thresholds.DownstreamSNR.Warning.Min, 35},
+		{"default snr critical", thresholds.DownstreamSNR.Critical.Min, 30},
+		{"overridden upstream critical max", thresholds.UpstreamPower.Critical.Max, 53},
+		{"default upstream critical min", thresholds.UpstreamPower.Critical.Min, 30},
+		{"default upstream warning max", thresholds.UpstreamPower.Warning.Max, 48},
+		{"default uncorrectable", thresholds.UncorrectablePercent.Critical.Max, 0.1},
+	}
+	for _, tt := range tests {
+		if tt.got == nil || *tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if thresholds.Unlocked {
+		t.Error("expected the unlocked check to be disabled")
+	}
+	// The overrides don't change the defaults
+	if *DefaultThresholds().DownstreamSNR.Warning.Min != 33 {
+		t.Error("loading thresholds changed the defaults")
+	}
+}
+
+func TestLoadThresholdsErrors(t *testing.T) {
+	if _, err := LoadThresholds(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if _, err := LoadThresholds(writeThresholds(t, "downstream_snr: [1, 2]")); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
+
+func TestMetricThresholdsCheck(t *testing.T) {
+	m := DefaultThresholds().DownstreamPower
+	tests := []struct {
+		value    float64
+		severity Severity
+		limit    float64
+	}{
+		{0, SeverityOK, 0},
+		{7, SeverityOK, 0},
+		{7.1, SeverityWarning, 7},
+		{-8, SeverityWarning, -7},
+		{15.5, SeverityCritical, 15},
+		{-20, SeverityCritical, -15},
+	}
+	for _, tt := range tests {
+		severity, limit := m.Check(tt.value)
+		if severity != tt.severity || limit != tt.limit {
+			t.Errorf("Check(%v) = %s %v, want %s %v", tt.value, severity, limit, tt.severity, tt.limit)
+		}
+	}
+}
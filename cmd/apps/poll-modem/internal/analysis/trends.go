@@ -0,0 +1,76 @@
+package analysis
+
+// Trend summarizes a metric of a channel over a time window
+type Trend struct {
+	SeriesKey
+	Frequency  string
+	Modulation string
+	Samples    int
+	Min        float64
+	Max        float64
+	Mean       float64
+	First      float64
+	Last       float64
+	// SlopePerDay is the least squares slope of the readings, in units per day
+	SlopePerDay float64
+	Points      []Point
+}
+
+// ComputeTrends summarizes each series, series without readings are skipped
+func ComputeTrends(series []*Series) []Trend {
+	ret := []Trend{}
+	for _, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		t := Trend{
+			SeriesKey:  s.SeriesKey,
+			Frequency:  s.Frequency,
+			Modulation: s.Modulation,
+			Samples:    len(s.Points),
+			Min:        s.Points[0].Value,
+			Max:        s.Points[0].Value,
+			First:      s.Points[0].Value,
+			Last:       s.Points[len(s.Points)-1].Value,
+			Points:     s.Points,
+		}
+		sum := 0.0
+		for _, p := range s.Points {
+			sum += p.Value
+			if p.Value < t.Min {
+				t.Min = p.Value
+			}
+			if p.Value > t.Max {
+				t.Max = p.Value
+			}
+		}
+		t.Mean = sum / float64(len(s.Points))
+		t.SlopePerDay = slopePerDay(s.Points)
+
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+// slopePerDay fits a line through the points
+func slopePerDay(points []Point) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	start := points[0].Time
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Time.Sub(start).Hours() / 24
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
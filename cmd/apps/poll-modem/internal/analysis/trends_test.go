@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSlopePerDay(t *testing.T) {
+	points := func(step time.Duration, values ...float64) []Point {
+		ret := make([]Point, len(values))
+		for i, v := range values {
+			ret[i] = Point{Time: t0.Add(time.Duration(i) * step), Value: v}
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name   string
+		points []Point
+		want   float64
+	}{
+		{"no points", nil, 0},
+		{"one point", points(time.Hour, 38), 0},
+		{"flat", points(time.Hour, 38, 38, 38), 0},
+		{"rising a dB per day", points(6*time.Hour, 30, 30.25, 30.5, 30.75, 31), 1},
+		{"falling", points(24*time.Hour, 5, 4.5, 4), -0.5},
+		{"noise around a slope", points(12*time.Hour, 1, 0, 3, 2), 1.2},
+		{"same time", []Point{{t0, 1}, {t0, 5}}, 0},
+		{"irregular spacing", []Point{{t0, 10}, {t0.Add(time.Hour), 10.125}, {t0.Add(48 * time.Hour), 16}}, 3},
+	}
+	for _, tt := range tests {
+		if got := slopePerDay(tt.points); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: slopePerDay = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComputeTrends(t *testing.T) {
+	trends := ComputeTrends([]*Series{
+		series(Downstream, "1", MetricSNR, 38, 36, 40, 38),
+		{SeriesKey: SeriesKey{Downstream, "2", MetricSNR}},
+	})
+	if len(trends) != 1 {
+		t.Fatalf("expected series without readings to be skipped, got %d trends", len(trends))
+	}
+	tr := trends[0]
+	if tr.Samples != 4 || tr.Min != 36 || tr.Max != 40 || tr.Mean != 38 || tr.First != 38 || tr.Last != 38 {
+		t.Errorf("trend = %+v", tr)
+	}
+}
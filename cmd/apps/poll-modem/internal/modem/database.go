@@ -23,6 +23,11 @@ func NewDatabase() (*Database, error) {
 		return nil, err
 	}
 
+	return OpenDatabase(dbPath)
+}
+
+// OpenDatabase opens the database at dbPath and initializes tables
+func OpenDatabase(dbPath string) (*Database, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to open database")
@@ -255,6 +260,52 @@ func (d *Database) ExportData(mode ExportMode, sessionID int64) (*ExportResult,
 		args = []interface{}{}
 	}
 
+	return d.export(whereClause, args...)
+}
+
+// ExportRange exports all data recorded from from (inclusive) to to (exclusive)
+func (d *Database) ExportRange(from, to time.Time) (*ExportResult, error) {
+	// julianday compares the instants, the stored timestamps can carry
+	// different UTC offsets across DST changes
+	return d.export(
+		"WHERE julianday(timestamp) >= julianday(?) AND julianday(timestamp) < julianday(?) ORDER BY timestamp",
+		from, to)
+}
+
+// LatestCableModem returns the last cable modem information recorded before
+// the given time, or nil if there is none
+func (d *Database) LatestCableModem(before time.Time) (*CableModem, error) {
+	row := d.db.QueryRow(`SELECT hw_version, vendor, boot_version, core_version, model, product_type, flash_part, download_version
+		FROM cable_modem_info WHERE julianday(timestamp) < julianday(?) ORDER BY timestamp DESC LIMIT 1`, before)
+
+	// Older rows can have NULL columns
+	var values [8]sql.NullString
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err := row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query cable modem info")
+	}
+
+	cm := CableModem{
+		HWVersion:       values[0].String,
+		Vendor:          values[1].String,
+		BOOTVersion:     values[2].String,
+		CoreVersion:     values[3].String,
+		Model:           values[4].String,
+		ProductType:     values[5].String,
+		FlashPart:       values[6].String,
+		DownloadVersion: values[7].String,
+	}
+	return &cm, nil
+}
+
+func (d *Database) export(whereClause string, args ...interface{}) (*ExportResult, error) {
 	result := &ExportResult{}
 
 	// Export downstream channels
@@ -0,0 +1,144 @@
+package modem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ModemDriver reads the status of one kind of modem
+type ModemDriver interface {
+	// Name returns the name of the driver, as passed to --driver
+	Name() string
+	// SetCredentials sets the username and password used by LoginAndFetch
+	SetCredentials(username, password string)
+	// FetchModemInfo reads the modem status with the current session. It
+	// returns a LogoutError when the modem requires a login.
+	FetchModemInfo(ctx context.Context) (*ModemInfo, error)
+	// LoginAndFetch logs in and reads the modem status
+	LoginAndFetch(ctx context.Context) (*ModemInfo, error)
+	// Logout ends the session and removes any stored session data
+	Logout() error
+}
+
+// LogoutError represents an error when the user is logged out and needs to authenticate
+type LogoutError struct {
+	Message string
+}
+
+func (e LogoutError) Error() string {
+	return e.Message
+}
+
+// IsLogoutError checks if an error is a LogoutError
+func IsLogoutError(err error) bool {
+	_, ok := err.(LogoutError)
+	return ok
+}
+
+// Driver describes a modem driver
+type Driver struct {
+	Name        string
+	Description string
+	New         func(baseURL string) ModemDriver
+	// Probe checks, without logging in, whether the modem at baseURL is
+	// supported by the driver
+	Probe func(ctx context.Context, httpClient *http.Client, baseURL string) (bool, error)
+}
+
+// drivers are the known drivers, in the order they are probed
+var drivers = []Driver{
+	{
+		Name:        "jst",
+		Description: "Gateways with the .jst web UI (Technicolor and Arris XB gateways, e.g. CGM4331)",
+		New:         func(baseURL string) ModemDriver { return NewJSTDriver(baseURL) },
+		Probe:       probeJST,
+	},
+	{
+		Name:        "hnap",
+		Description: "Modems with the HNAP JSON status API (Motorola MB8600/MB8611 and similar)",
+		New:         func(baseURL string) ModemDriver { return NewHNAPDriver(baseURL) },
+		Probe:       probeHNAP,
+	},
+}
+
+// Drivers returns the known drivers
+func Drivers() []Driver {
+	return drivers
+}
+
+// NewDriver creates the driver with the given name for the modem at
+// baseURL. The name "auto" detects the driver.
+func NewDriver(ctx context.Context, name, baseURL string) (ModemDriver, error) {
+	if name == "" || name == "auto" {
+		return DetectDriver(ctx, baseURL)
+	}
+
+	names := []string{}
+	for _, d := range drivers {
+		if d.Name == name {
+			return d.New(baseURL), nil
+		}
+		names = append(names, d.Name)
+	}
+	return nil, errors.Errorf("unknown driver %q (known drivers: auto, %s)", name, strings.Join(names, ", "))
+}
+
+// DetectDriver probes the modem at baseURL with each driver and returns the
+// first that supports it
+func DetectDriver(ctx context.Context, baseURL string) (ModemDriver, error) {
+	httpClient := newProbeClient()
+
+	var probeErr error
+	for _, d := range drivers {
+		ok, err := d.Probe(ctx, httpClient, baseURL)
+		if err != nil {
+			log.Debug().Err(err).Str("driver", d.Name).Msg("Probe failed")
+			probeErr = err
+			continue
+		}
+		log.Debug().Str("driver", d.Name).Bool("supported", ok).Msg("Probed modem")
+		if ok {
+			return d.New(baseURL), nil
+		}
+	}
+
+	if probeErr != nil {
+		return nil, errors.Wrapf(probeErr, "could not detect the modem at %s", baseURL)
+	}
+	return nil, errors.Errorf("no driver supports the modem at %s, use --driver to choose one", baseURL)
+}
+
+func newProbeClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// probeGet fetches a page for a probe, the body is capped at 1MB
+func probeGet(ctx context.Context, httpClient *http.Client, pageURL string) (*http.Response, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to create probe request")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to make probe request")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to read probe response")
+	}
+	return resp, string(body), nil
+}
@@ -0,0 +1,63 @@
+package modem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectDriver(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	hnapWithSettings := &hnapServer{t: t, dialect: "Moto", status: "hnap/status_moto.json", deviceSettings: true}
+	hnapWithoutSettings := &hnapServer{t: t, dialect: "Moto", status: "hnap/status_moto.json"}
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"jst", newJSTServer(t, "admin", "secret").URL, "jst"},
+		{"hnap device settings", hnapWithSettings.start().URL, "hnap"},
+		{"hnap start page", hnapWithoutSettings.start().URL, "hnap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, err := DetectDriver(context.Background(), tt.url)
+			if err != nil {
+				t.Fatalf("DetectDriver failed: %v", err)
+			}
+			if driver.Name() != tt.expected {
+				t.Fatalf("expected driver %s, got %s", tt.expected, driver.Name())
+			}
+		})
+	}
+}
+
+func TestDetectDriverUnknownModem(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>Router</body></html>"))
+	}))
+	defer srv.Close()
+
+	if _, err := DetectDriver(context.Background(), srv.URL); err == nil {
+		t.Fatalf("expected no driver to support the modem")
+	}
+}
+
+func TestNewDriver(t *testing.T) {
+	// A named driver is created without probing the modem
+	driver, err := NewDriver(context.Background(), "hnap", "http://192.0.2.1")
+	if err != nil {
+		t.Fatalf("NewDriver failed: %v", err)
+	}
+	if driver.Name() != "hnap" {
+		t.Fatalf("expected the hnap driver, got %s", driver.Name())
+	}
+
+	if _, err := NewDriver(context.Background(), "unknown", "http://192.0.2.1"); err == nil {
+		t.Fatalf("expected an error for an unknown driver")
+	}
+}
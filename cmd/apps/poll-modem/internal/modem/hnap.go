@@ -0,0 +1,459 @@
+package modem
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const hnapNamespace = "http://purenetworks.com/HNAP1/"
+
+// hnapDialect names the status actions of a modem family. Motorola modems
+// use GetMotoStatus* actions, Arris modems GetCustomerStatus*.
+type hnapDialect struct {
+	prefix string
+}
+
+var hnapDialects = []hnapDialect{{"Moto"}, {"Customer"}}
+
+func (d hnapDialect) action(name string) string {
+	return "Get" + d.prefix + "Status" + name
+}
+
+func (d hnapDialect) channelField(direction string) string {
+	return d.prefix + "Conn" + direction + "Channel"
+}
+
+// errHNAPUnsupportedDialect is returned when the modem doesn't know the
+// status actions of a dialect
+var errHNAPUnsupportedDialect = errors.New("status actions not supported")
+
+// hnapDeviceSettings is the unauthenticated device description served at /HNAP1/
+type hnapDeviceSettings struct {
+	VendorName      string `xml:"VendorName"`
+	ModelName       string `xml:"ModelName"`
+	FirmwareVersion string `xml:"FirmwareVersion"`
+}
+
+// HNAPDriver reads the status of modems with an HNAP JSON API. Requests are
+// POSTed to /HNAP1/ and signed with an HMAC-MD5 of the private key derived
+// from the login challenge.
+type HNAPDriver struct {
+	httpClient *http.Client
+	baseURL    string
+	username   string
+	password   string
+
+	// privateKey and uid are the session, empty when not logged in
+	privateKey string
+	uid        string
+
+	// dialect is the index of the hnapDialects entry the modem understood last
+	dialect int
+
+	device        *hnapDeviceSettings
+	deviceFetched bool
+}
+
+var _ ModemDriver = &HNAPDriver{}
+
+// NewHNAPDriver creates a driver for the modem at baseURL
+func NewHNAPDriver(baseURL string) *HNAPDriver {
+	return &HNAPDriver{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    baseURL,
+	}
+}
+
+// Name returns the name of the driver
+func (c *HNAPDriver) Name() string {
+	return "hnap"
+}
+
+// probeHNAP checks for the HNAP device settings, or a start page loading
+// the HNAP scripts
+func probeHNAP(ctx context.Context, httpClient *http.Client, baseURL string) (bool, error) {
+	resp, body, err := probeGet(ctx, httpClient, baseURL+"/HNAP1/")
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusOK && strings.Contains(body, hnapNamespace) {
+		return true, nil
+	}
+
+	_, body, err = probeGet(ctx, httpClient, baseURL+"/")
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(body, "HNAP1"), nil
+}
+
+// SetCredentials sets the username and password for authentication
+func (c *HNAPDriver) SetCredentials(username, password string) {
+	c.username = username
+	c.password = password
+}
+
+// Logout forgets the session
+func (c *HNAPDriver) Logout() error {
+	c.privateKey = ""
+	c.uid = ""
+	return nil
+}
+
+func hmacMD5(key, message string) string {
+	mac := hmac.New(md5.New, []byte(key))
+	mac.Write([]byte(message))
+	return strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+}
+
+// hnapAuth computes the HNAP_AUTH header of a request
+func hnapAuth(privateKey, action string, now time.Time) string {
+	if privateKey == "" {
+		privateKey = "withoutloginkey"
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli()%2000000000000, 10)
+	return hmacMD5(privateKey, timestamp+soapAction(action)) + " " + timestamp
+}
+
+func soapAction(action string) string {
+	return `"` + hnapNamespace + action + `"`
+}
+
+// call posts an HNAP action and decodes the JSON response into ret
+func (c *HNAPDriver) call(ctx context.Context, action string, body interface{}, ret interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal HNAP request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/HNAP1/", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("SOAPAction", soapAction(action))
+	req.Header.Set("HNAP_AUTH", hnapAuth(c.privateKey, action, time.Now()))
+	if c.uid != "" {
+		req.AddCookie(&http.Cookie{Name: "uid", Value: c.uid})
+		req.AddCookie(&http.Cookie{Name: "PrivateKey", Value: c.privateKey})
+	}
+
+	log.Debug().Str("action", action).Bool("logged_in", c.uid != "").Msg("Sending HNAP request")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to make request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read response body")
+	}
+	log.Debug().Str("action", action).Int("status_code", resp.StatusCode).Int("body_length", len(respBody)).Msg("Received HNAP response")
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return LogoutError{Message: fmt.Sprintf("access forbidden (%d) - authentication required", resp.StatusCode)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	if err := json.Unmarshal(respBody, ret); err != nil {
+		return errors.Wrapf(err, "failed to parse %s response", action)
+	}
+	return nil
+}
+
+type hnapLoginResponse struct {
+	LoginResponse struct {
+		Challenge   string `json:"Challenge"`
+		Cookie      string `json:"Cookie"`
+		PublicKey   string `json:"PublicKey"`
+		LoginResult string `json:"LoginResult"`
+	} `json:"LoginResponse"`
+}
+
+func hnapLoginRequest(action, username, password string) map[string]interface{} {
+	return map[string]interface{}{
+		"Login": map[string]string{
+			"Action":        action,
+			"Username":      username,
+			"LoginPassword": password,
+			"Captcha":       "",
+			"PrivateLogin":  "LoginPassword",
+		},
+	}
+}
+
+// Login performs the HNAP challenge login
+func (c *HNAPDriver) Login(ctx context.Context) error {
+	if c.username == "" || c.password == "" {
+		return errors.New("username and password must be set before login")
+	}
+	_ = c.Logout()
+
+	var challenge hnapLoginResponse
+	if err := c.call(ctx, "Login", hnapLoginRequest("request", c.username, ""), &challenge); err != nil {
+		return errors.Wrap(err, "failed to request login challenge")
+	}
+	if challenge.LoginResponse.LoginResult != "OK" {
+		return fmt.Errorf("login challenge failed: %s", challenge.LoginResponse.LoginResult)
+	}
+
+	lr := challenge.LoginResponse
+	privateKey := hmacMD5(lr.PublicKey+c.password, lr.Challenge)
+	c.privateKey, c.uid = privateKey, lr.Cookie
+
+	var result hnapLoginResponse
+	err := c.call(ctx, "Login", hnapLoginRequest("login", c.username, hmacMD5(privateKey, lr.Challenge)), &result)
+	if err != nil {
+		_ = c.Logout()
+		return errors.Wrap(err, "failed to log in")
+	}
+	if result.LoginResponse.LoginResult != "OK" {
+		_ = c.Logout()
+		return fmt.Errorf("login failed: %s", result.LoginResponse.LoginResult)
+	}
+
+	log.Debug().Str("username", c.username).Msg("HNAP login successful")
+	return nil
+}
+
+// LoginAndFetch performs login and then fetches modem information
+func (c *HNAPDriver) LoginAndFetch(ctx context.Context) (*ModemInfo, error) {
+	if c.username == "" || c.password == "" {
+		return nil, errors.New("username and password must be set before login")
+	}
+
+	if err := c.Login(ctx); err != nil {
+		return nil, errors.Wrap(err, "authentication failed")
+	}
+	return c.FetchModemInfo(ctx)
+}
+
+// FetchModemInfo fetches the status with the current session, trying the
+// dialects until the modem understands one
+func (c *HNAPDriver) FetchModemInfo(ctx context.Context) (*ModemInfo, error) {
+	c.fetchDevice(ctx)
+
+	for i := range hnapDialects {
+		index := (c.dialect + i) % len(hnapDialects)
+		info, err := c.fetchStatus(ctx, hnapDialects[index])
+		if errors.Is(err, errHNAPUnsupportedDialect) {
+			log.Debug().Str("dialect", hnapDialects[index].prefix).Msg("HNAP dialect not supported")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.dialect = index
+		return info, nil
+	}
+
+	return nil, errors.New("the modem supports none of the known HNAP status actions")
+}
+
+// fetchDevice reads the device settings once, they are only used for the
+// vendor and model
+func (c *HNAPDriver) fetchDevice(ctx context.Context) {
+	if c.deviceFetched {
+		return
+	}
+	c.deviceFetched = true
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/HNAP1/", nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to fetch HNAP device settings")
+		return
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Settings hnapDeviceSettings `xml:"Body>GetDeviceSettingsResponse"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		log.Debug().Err(err).Msg("Failed to parse HNAP device settings")
+		return
+	}
+	c.device = &envelope.Settings
+}
+
+func (c *HNAPDriver) fetchStatus(ctx context.Context, dialect hnapDialect) (*ModemInfo, error) {
+	softwareAction := dialect.action("Software")
+	downstreamAction := dialect.action("DownstreamChannelInfo")
+	upstreamAction := dialect.action("UpstreamChannelInfo")
+
+	request := map[string]interface{}{
+		"GetMultipleHNAPs": map[string]string{
+			softwareAction:   "",
+			downstreamAction: "",
+			upstreamAction:   "",
+		},
+	}
+	var response struct {
+		Responses map[string]json.RawMessage `json:"GetMultipleHNAPsResponse"`
+	}
+	if err := c.call(ctx, "GetMultipleHNAPs", request, &response); err != nil {
+		return nil, err
+	}
+
+	var result string
+	_ = json.Unmarshal(response.Responses["GetMultipleHNAPsResult"], &result)
+	if result == "UN-AUTH" {
+		return nil, LogoutError{Message: "access forbidden (logged out) - authentication required"}
+	}
+
+	fields := func(action string) (map[string]string, bool) {
+		raw, ok := response.Responses[action+"Response"]
+		if !ok {
+			return nil, false
+		}
+		ret := map[string]string{}
+		if err := json.Unmarshal(raw, &ret); err != nil {
+			log.Debug().Err(err).Str("action", action).Msg("Failed to parse HNAP action response")
+			return nil, false
+		}
+		return ret, true
+	}
+
+	downstream, ok := fields(downstreamAction)
+	if !ok {
+		return nil, errHNAPUnsupportedDialect
+	}
+	upstream, _ := fields(upstreamAction)
+	software, _ := fields(softwareAction)
+
+	info := &ModemInfo{
+		LastUpdated: time.Now(),
+		CableModem: CableModem{
+			Model:           software["StatusSoftwareModelName"],
+			HWVersion:       software["StatusSoftwareHdVer"],
+			ProductType:     software["StatusSoftwareSpecVer"],
+			DownloadVersion: software["StatusSoftwareSfVer"],
+		},
+	}
+	if c.device != nil {
+		info.CableModem.Vendor = c.device.VendorName
+		if c.device.ModelName != "" {
+			info.CableModem.Model = c.device.ModelName
+		}
+		if info.CableModem.DownloadVersion == "" {
+			info.CableModem.DownloadVersion = c.device.FirmwareVersion
+		}
+	}
+
+	info.Downstream, info.ErrorCodewords = parseHNAPDownstream(downstream[dialect.channelField("Downstream")])
+	info.Upstream = parseHNAPUpstream(upstream[dialect.channelField("Upstream")])
+
+	log.Debug().
+		Str("dialect", dialect.prefix).
+		Int("total_downstream", len(info.Downstream)).
+		Int("total_upstream", len(info.Upstream)).
+		Msg("Successfully parsed HNAP status")
+	return info, nil
+}
+
+// splitHNAPChannels splits a channel list like
+// "1^Locked^QAM256^20^483.0^1.2^43.3^0^0^|+|2^Locked^..." into fields
+func splitHNAPChannels(s string) [][]string {
+	var rows [][]string
+	for _, row := range strings.Split(s, "|+|") {
+		if strings.TrimSpace(row) == "" {
+			continue
+		}
+		fields := strings.Split(row, "^")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// hnapFrequency formats a frequency in MHz, Arris modems report it in Hz
+func hnapFrequency(s string) string {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	if v > 100000 {
+		v /= 1e6
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64) + " MHz"
+}
+
+// withUnit appends the unit the .jst pages show, so that values from all
+// drivers parse the same
+func withUnit(value, unit string) string {
+	if value == "" {
+		return ""
+	}
+	return value + " " + unit
+}
+
+// parseHNAPDownstream parses the downstream channel list. The fields are
+// index, lock status, modulation, channel ID, frequency, power, SNR,
+// corrected and uncorrected codewords. Unerrored codewords aren't reported.
+func parseHNAPDownstream(s string) ([]Channel, []ErrorChannel) {
+	var channels []Channel
+	var errorChannels []ErrorChannel
+	for _, f := range splitHNAPChannels(s) {
+		if len(f) < 9 {
+			log.Debug().Strs("fields", f).Msg("Skipping short HNAP downstream channel")
+			continue
+		}
+		channels = append(channels, Channel{
+			ChannelID:  f[3],
+			LockStatus: f[1],
+			Frequency:  hnapFrequency(f[4]),
+			SNR:        withUnit(f[6], "dB"),
+			PowerLevel: withUnit(f[5], "dBmV"),
+			Modulation: f[2],
+		})
+		errorChannels = append(errorChannels, ErrorChannel{
+			ChannelID:              f[3],
+			CorrectableCodewords:   f[7],
+			UncorrectableCodewords: f[8],
+		})
+	}
+	return channels, errorChannels
+}
+
+// parseHNAPUpstream parses the upstream channel list. The fields are index,
+// lock status, channel type, channel ID, symbol rate, frequency and power.
+func parseHNAPUpstream(s string) []Channel {
+	var channels []Channel
+	for _, f := range splitHNAPChannels(s) {
+		if len(f) < 7 {
+			log.Debug().Strs("fields", f).Msg("Skipping short HNAP upstream channel")
+			continue
+		}
+		channels = append(channels, Channel{
+			ChannelID:   f[3],
+			LockStatus:  f[1],
+			Frequency:   hnapFrequency(f[5]),
+			PowerLevel:  withUnit(f[6], "dBmV"),
+			SymbolRate:  f[4],
+			ChannelType: f[2],
+		})
+	}
+	return channels
+}
@@ -0,0 +1,209 @@
+package modem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// hnapServer emulates the HNAP API of a modem, serving a saved status
+// response
+type hnapServer struct {
+	t        *testing.T
+	username string
+	password string
+	// dialect is the status action prefix the modem understands
+	dialect string
+	status  string
+	// deviceSettings serves the device settings at GET /HNAP1/
+	deviceSettings bool
+	requireLogin   bool
+
+	privateKey string
+	loggedIn   bool
+}
+
+const (
+	testChallenge = "7FKC5S7WEA4RBGQTXNYB"
+	testPublicKey = "PG6UJKPSQLD3VSQXPYX8"
+	testUID       = "0ZkMpbzJ6pyoP8hYR9xA"
+)
+
+func (s *hnapServer) start() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /HNAP1/", func(w http.ResponseWriter, r *http.Request) {
+		if !s.deviceSettings {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write(readFixture(s.t, "hnap/device_settings.xml"))
+	})
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head><script src="/js/SOAP/SOAPAction.js"></script><script>var HNAP_URL = "/HNAP1/";</script></head></html>`))
+	})
+	mux.HandleFunc("POST /HNAP1/", s.handleAction)
+
+	srv := httptest.NewServer(mux)
+	s.t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *hnapServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(strings.Trim(r.Header.Get("SOAPAction"), `"`), hnapNamespace)
+
+	key := "withoutloginkey"
+	if cookie, err := r.Cookie("uid"); err == nil && cookie.Value == testUID && s.privateKey != "" {
+		key = s.privateKey
+	}
+	auth := strings.Fields(r.Header.Get("HNAP_AUTH"))
+	if len(auth) != 2 || auth[0] != hmacMD5(key, auth[1]+r.Header.Get("SOAPAction")) {
+		http.Error(w, "bad HNAP_AUTH", http.StatusUnauthorized)
+		return
+	}
+
+	var body map[string]map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "Login":
+		login := body["Login"]
+		if login["Action"] == "request" {
+			s.privateKey = hmacMD5(testPublicKey+s.password, testChallenge)
+			s.writeJSON(w, map[string]interface{}{"LoginResponse": map[string]string{
+				"Challenge": testChallenge, "Cookie": testUID, "PublicKey": testPublicKey, "LoginResult": "OK",
+			}})
+			return
+		}
+		result := "FAILED"
+		if login["Username"] == s.username && login["LoginPassword"] == hmacMD5(s.privateKey, testChallenge) {
+			s.loggedIn = true
+			result = "OK"
+		}
+		s.writeJSON(w, map[string]interface{}{"LoginResponse": map[string]string{"LoginResult": result}})
+
+	case "GetMultipleHNAPs":
+		if s.requireLogin && (!s.loggedIn || key == "withoutloginkey") {
+			s.writeJSON(w, map[string]interface{}{"GetMultipleHNAPsResponse": map[string]string{"GetMultipleHNAPsResult": "UN-AUTH"}})
+			return
+		}
+		if _, ok := body["GetMultipleHNAPs"]["Get"+s.dialect+"StatusDownstreamChannelInfo"]; !ok {
+			s.writeJSON(w, map[string]interface{}{"GetMultipleHNAPsResponse": map[string]string{"GetMultipleHNAPsResult": "ERROR"}})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(readFixture(s.t, s.status))
+
+	default:
+		http.Error(w, "unknown action "+action, http.StatusNotFound)
+	}
+}
+
+func (s *hnapServer) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.t.Errorf("failed to write response: %v", err)
+	}
+}
+
+func TestHMACMD5(t *testing.T) {
+	// RFC 2104 test vector
+	if got := hmacMD5("Jefe", "what do ya want for nothing?"); got != "750C783E6AB0B503EAA86E310A5DB738" {
+		t.Fatalf("unexpected HMAC-MD5: %s", got)
+	}
+}
+
+func TestHNAPDriverLoginAndFetch(t *testing.T) {
+	s := &hnapServer{t: t, username: "admin", password: "motorola", dialect: "Moto", status: "hnap/status_moto.json", deviceSettings: true, requireLogin: true}
+	srv := s.start()
+	ctx := context.Background()
+
+	driver := NewHNAPDriver(srv.URL)
+	if _, err := driver.FetchModemInfo(ctx); !IsLogoutError(err) {
+		t.Fatalf("expected a logout error before login, got %v", err)
+	}
+
+	driver.SetCredentials("admin", "motorola")
+	info, err := driver.LoginAndFetch(ctx)
+	if err != nil {
+		t.Fatalf("LoginAndFetch failed: %v", err)
+	}
+
+	expectedModem := CableModem{Vendor: "Motorola", Model: "MB8600", HWVersion: "V1.0", ProductType: "DOCSIS 3.1", DownloadVersion: "8600-19.3.18"}
+	if info.CableModem != expectedModem {
+		t.Fatalf("unexpected cable modem info: %+v", info.CableModem)
+	}
+
+	if len(info.Downstream) != 4 {
+		t.Fatalf("expected 4 downstream channels, got %d", len(info.Downstream))
+	}
+	expectedDownstream := Channel{ChannelID: "20", LockStatus: "Locked", Frequency: "483 MHz", SNR: "43.3 dB", PowerLevel: "1.2 dBmV", Modulation: "QAM256"}
+	if info.Downstream[0] != expectedDownstream {
+		t.Fatalf("unexpected downstream channel: %+v", info.Downstream[0])
+	}
+	if info.Downstream[2].LockStatus != "Not Locked" || info.Downstream[2].PowerLevel != "-12.5 dBmV" {
+		t.Fatalf("unexpected unlocked downstream channel: %+v", info.Downstream[2])
+	}
+
+	expectedErrors := ErrorChannel{ChannelID: "21", CorrectableCodewords: "22", UncorrectableCodewords: "3"}
+	if len(info.ErrorCodewords) != 4 || info.ErrorCodewords[1] != expectedErrors {
+		t.Fatalf("unexpected error channels: %+v", info.ErrorCodewords)
+	}
+
+	expectedUpstream := Channel{ChannelID: "1", LockStatus: "Locked", Frequency: "35.6 MHz", PowerLevel: "44.5 dBmV", SymbolRate: "5120", ChannelType: "SC-QAM"}
+	if len(info.Upstream) != 3 || info.Upstream[0] != expectedUpstream {
+		t.Fatalf("unexpected upstream channels: %+v", info.Upstream)
+	}
+
+	// Polling reuses the session
+	if _, err := driver.FetchModemInfo(ctx); err != nil {
+		t.Fatalf("fetch after login failed: %v", err)
+	}
+}
+
+func TestHNAPDriverWrongPassword(t *testing.T) {
+	s := &hnapServer{t: t, username: "admin", password: "motorola", dialect: "Moto", status: "hnap/status_moto.json", requireLogin: true}
+	srv := s.start()
+
+	driver := NewHNAPDriver(srv.URL)
+	driver.SetCredentials("admin", "wrong")
+	// The private key derived from the wrong password doesn't sign the
+	// second login request correctly
+	if _, err := driver.LoginAndFetch(context.Background()); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Fatalf("expected a failed login, got %v", err)
+	}
+	if driver.uid != "" || driver.privateKey != "" {
+		t.Fatalf("expected the session to be cleared after a failed login")
+	}
+}
+
+func TestHNAPDriverCustomerDialect(t *testing.T) {
+	s := &hnapServer{t: t, dialect: "Customer", status: "hnap/status_customer.json"}
+	srv := s.start()
+
+	driver := NewHNAPDriver(srv.URL)
+	info, err := driver.FetchModemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("FetchModemInfo failed: %v", err)
+	}
+	if driver.dialect != 1 {
+		t.Fatalf("expected the Customer dialect to be remembered, got %d", driver.dialect)
+	}
+
+	if info.CableModem.Model != "S33" || info.CableModem.DownloadVersion != "TB01.03.001.10_012022_212.S3" {
+		t.Fatalf("unexpected cable modem info: %+v", info.CableModem)
+	}
+	// Arris modems report frequencies in Hz
+	if len(info.Downstream) != 2 || info.Downstream[0].Frequency != "399 MHz" || info.Downstream[0].SNR != "40 dB" {
+		t.Fatalf("unexpected downstream channels: %+v", info.Downstream)
+	}
+	if len(info.Upstream) != 1 || info.Upstream[0].Frequency != "16.4 MHz" || info.Upstream[0].PowerLevel != "44.0 dBmV" {
+		t.Fatalf("unexpected upstream channels: %+v", info.Upstream)
+	}
+}
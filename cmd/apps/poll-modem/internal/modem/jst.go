@@ -17,21 +17,6 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// LogoutError represents an error when the user is logged out and needs to authenticate
-type LogoutError struct {
-	Message string
-}
-
-func (e LogoutError) Error() string {
-	return e.Message
-}
-
-// IsLogoutError checks if an error is a LogoutError
-func IsLogoutError(err error) bool {
-	_, ok := err.(LogoutError)
-	return ok
-}
-
 // StoredCookie represents a cookie that can be serialized to JSON
 type StoredCookie struct {
 	Name     string    `json:"name"`
@@ -43,8 +28,10 @@ type StoredCookie struct {
 	HttpOnly bool      `json:"httpOnly"`
 }
 
-// Client represents the modem HTTP client
-type Client struct {
+// JSTDriver reads the network_setup.jst status page of gateways with the
+// .jst web UI (Technicolor and Arris XB gateways), logging in with a form
+// and keeping the session cookies in ~/.config/poll-modem/cookies.json
+type JSTDriver struct {
 	httpClient *http.Client
 	baseURL    string
 	headers    map[string]string
@@ -53,9 +40,11 @@ type Client struct {
 	password   string
 }
 
-// NewClient creates a new modem client
-func NewClient(baseURL string) *Client {
-	return &Client{
+var _ ModemDriver = &JSTDriver{}
+
+// NewJSTDriver creates a driver for the gateway at baseURL
+func NewJSTDriver(baseURL string) *JSTDriver {
+	return &JSTDriver{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -76,14 +65,28 @@ func NewClient(baseURL string) *Client {
 	}
 }
 
+// Name returns the name of the driver
+func (c *JSTDriver) Name() string {
+	return "jst"
+}
+
+// probeJST checks whether the start page links to .jst pages
+func probeJST(ctx context.Context, httpClient *http.Client, baseURL string) (bool, error) {
+	resp, body, err := probeGet(ctx, httpClient, baseURL+"/")
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(resp.Header.Get("Location"), ".jst") || strings.Contains(body, ".jst"), nil
+}
+
 // SetCredentials sets the username and password for authentication
-func (c *Client) SetCredentials(username, password string) {
+func (c *JSTDriver) SetCredentials(username, password string) {
 	c.username = username
 	c.password = password
 }
 
 // getCookieFilePath returns the path to the cookie storage file
-func (c *Client) getCookieFilePath() (string, error) {
+func (c *JSTDriver) getCookieFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get user home directory")
@@ -98,7 +101,7 @@ func (c *Client) getCookieFilePath() (string, error) {
 }
 
 // saveCookies saves the current cookies to the config file
-func (c *Client) saveCookies() error {
+func (c *JSTDriver) saveCookies() error {
 	cookieFile, err := c.getCookieFilePath()
 	if err != nil {
 		return err
@@ -131,7 +134,7 @@ func (c *Client) saveCookies() error {
 }
 
 // loadCookies loads cookies from the config file
-func (c *Client) loadCookies() error {
+func (c *JSTDriver) loadCookies() error {
 	cookieFile, err := c.getCookieFilePath()
 	if err != nil {
 		return err
@@ -180,7 +183,7 @@ func (c *Client) loadCookies() error {
 }
 
 // mergeCookies adds new cookies to the existing cookie jar, replacing any existing cookies with the same name
-func (c *Client) mergeCookies(newCookies []*http.Cookie) {
+func (c *JSTDriver) mergeCookies(newCookies []*http.Cookie) {
 	for _, newCookie := range newCookies {
 		// Find and replace existing cookie with same name, or append if not found
 		found := false
@@ -198,7 +201,7 @@ func (c *Client) mergeCookies(newCookies []*http.Cookie) {
 }
 
 // clearCookies removes all cookies from the jar and optionally deletes the cookie file
-func (c *Client) clearCookies(deleteFile bool) error {
+func (c *JSTDriver) clearCookies(deleteFile bool) error {
 	c.cookies = nil
 	log.Debug().Msg("Cleared all cookies from memory")
 
@@ -219,12 +222,12 @@ func (c *Client) clearCookies(deleteFile bool) error {
 }
 
 // Logout clears the session and removes stored cookies
-func (c *Client) Logout() error {
+func (c *JSTDriver) Logout() error {
 	return c.clearCookies(true)
 }
 
 // Login performs authentication with the modem
-func (c *Client) Login(ctx context.Context) error {
+func (c *JSTDriver) Login(ctx context.Context) error {
 	if c.username == "" || c.password == "" {
 		return errors.New("username and password must be set before login")
 	}
@@ -442,7 +445,7 @@ func (c *Client) Login(ctx context.Context) error {
 }
 
 // LoginAndFetch performs login and then fetches modem information
-func (c *Client) LoginAndFetch(ctx context.Context) (*ModemInfo, error) {
+func (c *JSTDriver) LoginAndFetch(ctx context.Context) (*ModemInfo, error) {
 	if c.username == "" || c.password == "" {
 		return nil, errors.New("username and password must be set before login")
 	}
@@ -457,7 +460,7 @@ func (c *Client) LoginAndFetch(ctx context.Context) (*ModemInfo, error) {
 }
 
 // FetchModemInfo fetches and parses modem information
-func (c *Client) FetchModemInfo(ctx context.Context) (*ModemInfo, error) {
+func (c *JSTDriver) FetchModemInfo(ctx context.Context) (*ModemInfo, error) {
 	// Load saved cookies first
 	if err := c.loadCookies(); err != nil {
 		log.Debug().Err(err).Msg("Failed to load cookies from file, will proceed without them")
@@ -481,7 +484,7 @@ func (c *Client) FetchModemInfo(ctx context.Context) (*ModemInfo, error) {
 }
 
 // fetchModemInfoInternal performs the actual data fetching
-func (c *Client) fetchModemInfoInternal(ctx context.Context) (*ModemInfo, error) {
+func (c *JSTDriver) fetchModemInfoInternal(ctx context.Context) (*ModemInfo, error) {
 	connectionStatusURL := c.baseURL + "/network_setup.jst"
 	req, err := http.NewRequestWithContext(ctx, "GET", connectionStatusURL, nil)
 	if err != nil {
@@ -607,7 +610,7 @@ func (c *Client) fetchModemInfoInternal(ctx context.Context) (*ModemInfo, error)
 }
 
 // parseModemInfo parses the HTML document and extracts modem information
-func (c *Client) parseModemInfo(doc *goquery.Document) (*ModemInfo, error) {
+func (c *JSTDriver) parseModemInfo(doc *goquery.Document) (*ModemInfo, error) {
 	info := &ModemInfo{
 		LastUpdated: time.Now(),
 	}
@@ -663,7 +666,7 @@ func (c *Client) parseModemInfo(doc *goquery.Document) (*ModemInfo, error) {
 }
 
 // parseCableModem extracts cable modem hardware information
-func (c *Client) parseCableModem(doc *goquery.Document) (*CableModem, error) {
+func (c *JSTDriver) parseCableModem(doc *goquery.Document) (*CableModem, error) {
 	modem := &CableModem{}
 
 	// Find the Cable Modem section
@@ -699,7 +702,7 @@ func (c *Client) parseCableModem(doc *goquery.Document) (*CableModem, error) {
 }
 
 // parseDownstreamChannels extracts downstream channel information
-func (c *Client) parseDownstreamChannels(doc *goquery.Document) ([]Channel, error) {
+func (c *JSTDriver) parseDownstreamChannels(doc *goquery.Document) ([]Channel, error) {
 	var channels []Channel
 
 	// Find the downstream table
@@ -713,7 +716,7 @@ func (c *Client) parseDownstreamChannels(doc *goquery.Document) ([]Channel, erro
 }
 
 // parseUpstreamChannels extracts upstream channel information
-func (c *Client) parseUpstreamChannels(doc *goquery.Document) ([]Channel, error) {
+func (c *JSTDriver) parseUpstreamChannels(doc *goquery.Document) ([]Channel, error) {
 	var channels []Channel
 
 	// Find the upstream table
@@ -727,7 +730,7 @@ func (c *Client) parseUpstreamChannels(doc *goquery.Document) ([]Channel, error)
 }
 
 // parseChannelTable parses a channel table (downstream or upstream)
-func (c *Client) parseChannelTable(table *goquery.Selection, isDownstream bool) []Channel {
+func (c *JSTDriver) parseChannelTable(table *goquery.Selection, isDownstream bool) []Channel {
 	var channels []Channel
 	var channelIDs []string
 	var lockStatuses []string
@@ -792,7 +795,7 @@ func (c *Client) parseChannelTable(table *goquery.Selection, isDownstream bool)
 }
 
 // parseErrorCodewords extracts error codeword information
-func (c *Client) parseErrorCodewords(doc *goquery.Document) ([]ErrorChannel, error) {
+func (c *JSTDriver) parseErrorCodewords(doc *goquery.Document) ([]ErrorChannel, error) {
 	var errorChannels []ErrorChannel
 
 	// Find the error codewords table
@@ -0,0 +1,116 @@
+package modem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", path))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+// newJSTServer serves the saved .jst pages, the status page only with the
+// session cookie set by a successful login
+func newJSTServer(t *testing.T, username, password string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/index.jst", http.StatusFound)
+	})
+	mux.HandleFunc("GET /index.jst", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(readFixture(t, "jst/index.jst"))
+	})
+	mux.HandleFunc("POST /check.jst", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("username") != username || r.FormValue("password") != password {
+			http.Redirect(w, r, "/index.jst", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "DUKSID", Value: "session-1", Path: "/"})
+		http.Redirect(w, r, "/at_a_glance.jst", http.StatusFound)
+	})
+	mux.HandleFunc("GET /at_a_glance.jst", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>At a Glance</body></html>"))
+	})
+	mux.HandleFunc("GET /network_setup.jst", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("DUKSID"); err != nil || cookie.Value != "session-1" {
+			_, _ = w.Write(readFixture(t, "jst/logged_out.jst"))
+			return
+		}
+		_, _ = w.Write(readFixture(t, "jst/network_setup.jst"))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestJSTDriverLoginAndFetch(t *testing.T) {
+	// The driver keeps its cookies in ~/.config/poll-modem
+	t.Setenv("HOME", t.TempDir())
+	srv := newJSTServer(t, "admin", "secret")
+	ctx := context.Background()
+
+	driver := NewJSTDriver(srv.URL)
+	if _, err := driver.FetchModemInfo(ctx); !IsLogoutError(err) {
+		t.Fatalf("expected a logout error before login, got %v", err)
+	}
+
+	driver.SetCredentials("admin", "secret")
+	info, err := driver.LoginAndFetch(ctx)
+	if err != nil {
+		t.Fatalf("LoginAndFetch failed: %v", err)
+	}
+
+	cm := info.CableModem
+	if cm.Vendor != "Technicolor" || cm.Model != "CGM4331COM" || cm.HWVersion != "2.1" || cm.DownloadVersion != "Prod_23.2_231009" {
+		t.Fatalf("unexpected cable modem info: %+v", cm)
+	}
+
+	if len(info.Downstream) != 4 {
+		t.Fatalf("expected 4 downstream channels, got %d", len(info.Downstream))
+	}
+	expectedDownstream := Channel{ChannelID: "3", LockStatus: "Not Locked", Frequency: "519 MHz", SNR: "0.0 dB", PowerLevel: "-12.5 dBmV", Modulation: "256 QAM"}
+	if info.Downstream[2] != expectedDownstream {
+		t.Fatalf("unexpected downstream channel: %+v", info.Downstream[2])
+	}
+
+	if len(info.Upstream) != 2 {
+		t.Fatalf("expected 2 upstream channels, got %d", len(info.Upstream))
+	}
+	expectedUpstream := Channel{ChannelID: "2", LockStatus: "Locked", Frequency: "29.2 MHz", PowerLevel: "45.0 dBmV", Modulation: "64QAM", SymbolRate: "5120 KSym/sec", ChannelType: "TDMA_AND_ATDMA"}
+	if info.Upstream[1] != expectedUpstream {
+		t.Fatalf("unexpected upstream channel: %+v", info.Upstream[1])
+	}
+
+	if len(info.ErrorCodewords) != 4 {
+		t.Fatalf("expected 4 error channels, got %d", len(info.ErrorCodewords))
+	}
+	expectedErrors := ErrorChannel{ChannelID: "2", UnerroredCodewords: "1592268891", CorrectableCodewords: "998", UncorrectableCodewords: "12"}
+	if info.ErrorCodewords[1] != expectedErrors {
+		t.Fatalf("unexpected error channel: %+v", info.ErrorCodewords[1])
+	}
+
+	// A new driver reuses the stored session cookies
+	if _, err := NewJSTDriver(srv.URL).FetchModemInfo(ctx); err != nil {
+		t.Fatalf("fetch with stored cookies failed: %v", err)
+	}
+}
+
+func TestJSTDriverWrongPassword(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := newJSTServer(t, "admin", "secret")
+
+	driver := NewJSTDriver(srv.URL)
+	driver.SetCredentials("admin", "wrong")
+	if _, err := driver.LoginAndFetch(context.Background()); !IsLogoutError(err) {
+		t.Fatalf("expected a logout error with a wrong password, got %v", err)
+	}
+}
@@ -68,7 +68,7 @@ type exportModeSelectMsg struct{}
 
 // App represents the main TUI application
 type App struct {
-	client       *modem.Client
+	client       modem.ModemDriver
 	database     *modem.Database
 	sessionID    int64
 	modemInfo    *modem.ModemInfo
@@ -162,18 +162,13 @@ var defaultKeys = keyMap{
 	),
 }
 
-// NewApp creates a new TUI application
-func NewApp(baseURL string, pollInterval time.Duration, username, password string) *App {
+// NewApp creates a new TUI application polling the modem through driver
+func NewApp(driver modem.ModemDriver, pollInterval time.Duration) *App {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 	log.Info().Msg("Creating new app")
 
-	client := modem.NewClient(baseURL)
-	if username != "" && password != "" {
-		client.SetCredentials(username, password)
-	}
-
 	// Initialize database
 	database, err := modem.NewDatabase()
 	if err != nil {
@@ -191,7 +186,7 @@ func NewApp(baseURL string, pollInterval time.Duration, username, password strin
 	}
 
 	app := &App{
-		client:            client,
+		client:            driver,
 		database:          database,
 		sessionID:         sessionID,
 		pollInterval:      pollInterval,
@@ -8,6 +8,8 @@ import { PRDetailPage } from './components/PRDetailPage';
 import { FilesPage } from './components/FilesPage';
 import { FileDetailPage } from './components/FileDetailPage';
 import { NotesPage } from './components/NotesPage';
+import { SymbolTimelinePage } from './components/SymbolTimelinePage';
+import { ChurnPage } from './components/ChurnPage';
 import './App.css';
 
 function App() {
@@ -23,6 +25,8 @@ function App() {
           <Route path="files" element={<FilesPage />} />
           <Route path="files/:id" element={<FileDetailPage />} />
           <Route path="notes" element={<NotesPage />} />
+          <Route path="symbols/timeline" element={<SymbolTimelinePage />} />
+          <Route path="churn" element={<ChurnPage />} />
         </Route>
       </Routes>
     </BrowserRouter>
@@ -1,7 +1,31 @@
-import type { Commit, CommitDetails, PR, PRDetails, File, FileWithHistory, AnalysisNote, Stats } from '../types';
+import type {
+  Commit,
+  CommitDetails,
+  PR,
+  PRDetails,
+  File,
+  FileWithHistory,
+  AnalysisNote,
+  Stats,
+  SymbolTimeline,
+  SymbolChurn,
+  FileChurn,
+  FileCoupling,
+  ChurnFilters,
+} from '../types';
 
 const API_BASE = '/api';
 
+function churnParams(filters: ChurnFilters): URLSearchParams {
+  const params = new URLSearchParams();
+  Object.entries(filters).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      params.append(key, String(value));
+    }
+  });
+  return params;
+}
+
 async function fetchJSON<T>(url: string): Promise<T> {
   const response = await fetch(url);
   if (!response.ok) {
@@ -80,5 +104,26 @@ export const api = {
     }
     return fetchJSON<AnalysisNote[]>(`${API_BASE}/notes?${params}`);
   },
-};
 
+  // Symbols
+  async getSymbolTimeline(symbol: string, file?: string): Promise<SymbolTimeline> {
+    const params = new URLSearchParams({ symbol });
+    if (file) {
+      params.append('file', file);
+    }
+    return fetchJSON<SymbolTimeline>(`${API_BASE}/symbols/timeline?${params}`);
+  },
+
+  // Churn and hotspots
+  async getSymbolChurn(filters: ChurnFilters = {}): Promise<SymbolChurn[] | null> {
+    return fetchJSON<SymbolChurn[] | null>(`${API_BASE}/churn/symbols?${churnParams(filters)}`);
+  },
+
+  async getFileChurn(filters: ChurnFilters = {}): Promise<FileChurn[] | null> {
+    return fetchJSON<FileChurn[] | null>(`${API_BASE}/churn/files?${churnParams(filters)}`);
+  },
+
+  async getFileCoupling(filters: ChurnFilters = {}): Promise<FileCoupling[] | null> {
+    return fetchJSON<FileCoupling[] | null>(`${API_BASE}/churn/coupling?${churnParams(filters)}`);
+  },
+};
@@ -0,0 +1,193 @@
+import { useEffect, useState } from 'react';
+import { Link } from 'react-router-dom';
+import { api } from '../api/client';
+import type { ChurnFilters, FileChurn, FileCoupling, SymbolChurn } from '../types';
+import { SortableTable } from './SortableTable';
+import type { Column } from './SortableTable';
+
+type Tab = 'symbols' | 'files' | 'coupling';
+
+const formatDate = (date: string) => (date ? new Date(date).toLocaleDateString() : '');
+
+const symbolColumns: Column<SymbolChurn>[] = [
+  {
+    key: 'symbol',
+    label: 'Symbol',
+    value: (s) => s.symbol_name,
+    render: (s) => (
+      <Link to={`/symbols/timeline?symbol=${encodeURIComponent(s.symbol_name)}&file=${encodeURIComponent(s.file_path)}`}>
+        <code>{s.symbol_name}</code>
+      </Link>
+    ),
+  },
+  { key: 'kind', label: 'Kind', value: (s) => s.symbol_kind, render: (s) => <span className="tag">{s.symbol_kind}</span> },
+  {
+    key: 'file',
+    label: 'File',
+    value: (s) => s.file_path,
+    render: (s) => (
+      <Link to={`/files/${s.file_id}`}>
+        <code className="file-path">{s.file_path}</code>
+      </Link>
+    ),
+  },
+  { key: 'changes', label: 'Changes', value: (s) => s.changes, align: 'right' },
+  { key: 'authors', label: 'Authors', value: (s) => s.authors, align: 'right' },
+  { key: 'last_change', label: 'Last Change', value: (s) => s.last_change, render: (s) => formatDate(s.last_change) },
+];
+
+const fileColumns: Column<FileChurn>[] = [
+  {
+    key: 'file',
+    label: 'File',
+    value: (f) => f.path,
+    render: (f) => (
+      <Link to={`/files/${f.id}`}>
+        <code className="file-path">{f.path}</code>
+      </Link>
+    ),
+  },
+  { key: 'commits', label: 'Commits', value: (f) => f.commits, align: 'right' },
+  { key: 'authors', label: 'Authors', value: (f) => f.authors, align: 'right' },
+  { key: 'additions', label: 'Additions', value: (f) => f.additions, align: 'right' },
+  { key: 'deletions', label: 'Deletions', value: (f) => f.deletions, align: 'right' },
+  { key: 'churn', label: 'Churn', value: (f) => f.churn, align: 'right' },
+  { key: 'symbols', label: 'Symbols', value: (f) => f.symbols, align: 'right' },
+  { key: 'last_change', label: 'Last Change', value: (f) => f.last_change, render: (f) => formatDate(f.last_change) },
+];
+
+const couplingColumns: Column<FileCoupling>[] = [
+  {
+    key: 'file_a',
+    label: 'File',
+    value: (c) => c.file_a.path,
+    render: (c) => (
+      <Link to={`/files/${c.file_a.id}`}>
+        <code className="file-path">{c.file_a.path}</code>
+      </Link>
+    ),
+  },
+  {
+    key: 'file_b',
+    label: 'Changes With',
+    value: (c) => c.file_b.path,
+    render: (c) => (
+      <Link to={`/files/${c.file_b.id}`}>
+        <code className="file-path">{c.file_b.path}</code>
+      </Link>
+    ),
+  },
+  { key: 'shared', label: 'Shared Commits', value: (c) => c.shared_commits, align: 'right' },
+  { key: 'commits_a', label: 'Commits A', value: (c) => c.commits_a, align: 'right' },
+  { key: 'commits_b', label: 'Commits B', value: (c) => c.commits_b, align: 'right' },
+  { key: 'coupling', label: 'Coupling', value: (c) => c.coupling, render: (c) => `${Math.round(c.coupling * 100)}%`, align: 'right' },
+];
+
+export function ChurnPage() {
+  const [tab, setTab] = useState<Tab>('symbols');
+  const [prefix, setPrefix] = useState('');
+  const [since, setSince] = useState('');
+  const [filters, setFilters] = useState<ChurnFilters>({});
+
+  const [symbols, setSymbols] = useState<SymbolChurn[]>([]);
+  const [files, setFiles] = useState<FileChurn[]>([]);
+  const [coupling, setCoupling] = useState<FileCoupling[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    setLoading(true);
+    setError(null);
+    const request =
+      tab === 'symbols'
+        ? api.getSymbolChurn(filters).then((rows) => setSymbols(rows ?? []))
+        : tab === 'files'
+          ? api.getFileChurn(filters).then((rows) => setFiles(rows ?? []))
+          : api.getFileCoupling(filters).then((rows) => setCoupling(rows ?? []));
+    request.catch((err) => setError(err.message)).finally(() => setLoading(false));
+  }, [tab, filters]);
+
+  const handleFilter = (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setFilters({ prefix, since });
+  };
+
+  const renderTable = () => {
+    switch (tab) {
+      case 'symbols':
+        return (
+          <SortableTable
+            columns={symbolColumns}
+            rows={symbols}
+            rowKey={(s) => `${s.file_id}-${s.symbol_name}-${s.symbol_kind}`}
+            defaultSort="changes"
+          />
+        );
+      case 'files':
+        return <SortableTable columns={fileColumns} rows={files} rowKey={(f) => String(f.id)} defaultSort="churn" />;
+      case 'coupling':
+        return (
+          <SortableTable
+            columns={couplingColumns}
+            rows={coupling}
+            rowKey={(c) => `${c.file_a.id}-${c.file_b.id}`}
+            defaultSort="coupling"
+          />
+        );
+    }
+  };
+
+  return (
+    <div>
+      <div className="page-header">
+        <h2>Churn</h2>
+        <p>Hotspots: the symbols and files that change most, and the files that change together</p>
+      </div>
+
+      <div className="search-box">
+        <form onSubmit={handleFilter} style={{ display: 'flex', gap: '0.5rem' }}>
+          <input
+            type="text"
+            className="search-input"
+            placeholder="Filter by path prefix..."
+            value={prefix}
+            onChange={(e) => setPrefix(e.target.value)}
+          />
+          <input
+            type="date"
+            className="search-input"
+            style={{ maxWidth: '12rem' }}
+            value={since}
+            onChange={(e) => setSince(e.target.value)}
+          />
+          <button type="submit" className="toggle-button">
+            Apply
+          </button>
+        </form>
+      </div>
+
+      <div style={{ marginBottom: '1rem' }}>
+        {(['symbols', 'files', 'coupling'] as Tab[]).map((t) => (
+          <button
+            key={t}
+            className={`toggle-button ${tab === t ? 'active' : ''}`}
+            onClick={() => setTab(t)}
+            style={{ marginRight: '0.5rem' }}
+          >
+            {t === 'symbols' ? 'Symbols' : t === 'files' ? 'Files' : 'Co-change Coupling'}
+          </button>
+        ))}
+      </div>
+
+      <div className="card">
+        {loading ? (
+          <div className="loading">Loading churn...</div>
+        ) : error ? (
+          <div className="error">Error loading churn: {error}</div>
+        ) : (
+          renderTable()
+        )}
+      </div>
+    </div>
+  );
+}
@@ -180,7 +180,13 @@ export function CommitDetailPage() {
                 {symbols.map((symbol, idx) => (
                   <tr key={idx} style={{ borderBottom: '1px solid #ecf0f1' }}>
                     <td style={{ padding: '0.5rem' }}>
-                      <code>{symbol.symbol_name}</code>
+                      <Link
+                        to={`/symbols/timeline?symbol=${encodeURIComponent(symbol.symbol_name)}&file=${encodeURIComponent(
+                          files.find((f) => f.file_id === symbol.file_id)?.path ?? ''
+                        )}`}
+                      >
+                        <code>{symbol.symbol_name}</code>
+                      </Link>
                     </td>
                     <td style={{ padding: '0.5rem' }}>
                       <span className="tag">{symbol.symbol_kind}</span>
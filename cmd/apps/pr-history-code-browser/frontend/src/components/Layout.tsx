@@ -38,6 +38,11 @@ export function Layout() {
                 Notes
               </Link>
             </li>
+            <li>
+              <Link to="/churn" className={isActive('/churn')}>
+                Churn
+              </Link>
+            </li>
           </ul>
         </div>
       </nav>
@@ -0,0 +1,72 @@
+import { useState } from 'react';
+import type { ReactNode } from 'react';
+
+export interface Column<T> {
+  key: string;
+  label: string;
+  // value is what the column sorts by
+  value: (row: T) => string | number;
+  render?: (row: T) => ReactNode;
+  align?: 'left' | 'right';
+}
+
+interface SortableTableProps<T> {
+  columns: Column<T>[];
+  rows: T[];
+  rowKey: (row: T) => string;
+  defaultSort?: string;
+}
+
+export function SortableTable<T>({ columns, rows, rowKey, defaultSort }: SortableTableProps<T>) {
+  const [sortKey, setSortKey] = useState(defaultSort ?? columns[0]?.key);
+  const [descending, setDescending] = useState(true);
+
+  const handleSort = (key: string) => {
+    if (key === sortKey) {
+      setDescending(!descending);
+    } else {
+      setSortKey(key);
+      setDescending(true);
+    }
+  };
+
+  const column = columns.find((c) => c.key === sortKey);
+  const sorted = column
+    ? [...rows].sort((a, b) => {
+        const va = column.value(a);
+        const vb = column.value(b);
+        const cmp = va < vb ? -1 : va > vb ? 1 : 0;
+        return descending ? -cmp : cmp;
+      })
+    : rows;
+
+  return (
+    <table style={{ width: '100%', borderCollapse: 'collapse' }}>
+      <thead>
+        <tr style={{ borderBottom: '2px solid #ecf0f1', textAlign: 'left' }}>
+          {columns.map((c) => (
+            <th
+              key={c.key}
+              onClick={() => handleSort(c.key)}
+              style={{ padding: '0.5rem', cursor: 'pointer', userSelect: 'none', textAlign: c.align ?? 'left' }}
+            >
+              {c.label}
+              {c.key === sortKey && (descending ? ' ▼' : ' ▲')}
+            </th>
+          ))}
+        </tr>
+      </thead>
+      <tbody>
+        {sorted.map((row) => (
+          <tr key={rowKey(row)} style={{ borderBottom: '1px solid #ecf0f1' }}>
+            {columns.map((c) => (
+              <td key={c.key} style={{ padding: '0.5rem', textAlign: c.align ?? 'left' }}>
+                {c.render ? c.render(row) : c.value(row)}
+              </td>
+            ))}
+          </tr>
+        ))}
+      </tbody>
+    </table>
+  );
+}
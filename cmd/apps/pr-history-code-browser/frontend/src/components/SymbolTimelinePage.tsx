@@ -0,0 +1,160 @@
+import { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { api } from '../api/client';
+import type { SymbolTimeline, SymbolVersion } from '../types';
+
+const lineageLabels: Record<string, string> = {
+  renamed: 'Renamed',
+  moved: 'Moved',
+  renamed_and_moved: 'Renamed and moved',
+};
+
+function VersionCard({ version }: { version: SymbolVersion }) {
+  const [showSource, setShowSource] = useState(false);
+  const { commit } = version;
+
+  return (
+    <div className="card">
+      <div className="card-header">
+        <div>
+          <span className={`change-type ${version.change === 'added' ? 'A' : version.change === 'removed' ? 'D' : 'M'}`}>
+            {version.change}
+          </span>{' '}
+          <Link to={`/commits/${commit.hash}`}>
+            <span className="commit-hash">{commit.hash.substring(0, 8)}</span>
+          </Link>{' '}
+          {commit.subject}
+        </div>
+        {version.snippet && (
+          <button className="toggle-button" onClick={() => setShowSource(!showSource)}>
+            {showSource ? 'Show diff' : 'Show source'}
+          </button>
+        )}
+      </div>
+      <div className="commit-meta" style={{ marginBottom: '0.5rem' }}>
+        <span>👤 {commit.author_name}</span>
+        <span>📅 {new Date(commit.committed_at).toLocaleString()}</span>
+        <span>
+          <code className="file-path">{version.file_path}</code>
+          {version.start_line > 0 && ` L${version.start_line}-${version.end_line}`}
+        </span>
+      </div>
+      {version.lineage && (
+        <div className="lineage-note">
+          {lineageLabels[version.lineage]} from <code>{version.previous_name}</code> in{' '}
+          <code className="file-path">{version.previous_path}</code>
+        </div>
+      )}
+      {showSource ? (
+        <pre className="diff">
+          {version.snippet.split('\n').map((line, idx) => (
+            <div key={idx} className="diff-line">
+              {line}
+            </div>
+          ))}
+        </pre>
+      ) : (
+        <pre className="diff">
+          {(version.diff ?? []).map((line, idx) => (
+            <div
+              key={idx}
+              className={`diff-line ${line.op === '+' ? 'diff-add' : line.op === '-' ? 'diff-del' : ''}`}
+            >
+              {line.op} {line.text}
+            </div>
+          ))}
+        </pre>
+      )}
+    </div>
+  );
+}
+
+export function SymbolTimelinePage() {
+  const [searchParams, setSearchParams] = useSearchParams();
+  const symbol = searchParams.get('symbol') ?? '';
+  const file = searchParams.get('file') ?? '';
+
+  const [timeline, setTimeline] = useState<SymbolTimeline | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    if (!symbol) {
+      setLoading(false);
+      return;
+    }
+    setLoading(true);
+    setError(null);
+    api
+      .getSymbolTimeline(symbol, file)
+      .then(setTimeline)
+      .catch((err) => setError(err.message))
+      .finally(() => setLoading(false));
+  }, [symbol, file]);
+
+  if (!symbol) {
+    return <div className="error">No symbol given</div>;
+  }
+
+  if (loading) {
+    return <div className="loading">Loading symbol timeline...</div>;
+  }
+
+  if (error) {
+    return <div className="error">Error loading symbol timeline: {error}</div>;
+  }
+
+  if (!timeline) {
+    return <div className="error">Symbol not found</div>;
+  }
+
+  const versions = timeline.versions ?? [];
+  const locations = timeline.locations ?? [];
+
+  return (
+    <div>
+      <Link to="/churn" className="back-link">
+        ← Back to churn
+      </Link>
+
+      <div className="page-header">
+        <h2>
+          <code>{timeline.symbol_name}</code>
+        </h2>
+        <p>
+          {versions.length} versions in <code className="file-path">{timeline.file_path}</code>
+        </p>
+      </div>
+
+      {locations.length > 1 && (
+        <div className="card">
+          <div className="card-header">
+            <h3 className="card-title">Defined in {locations.length} files</h3>
+          </div>
+          <div>
+            {locations.map((location) => (
+              <button
+                key={location.file_id}
+                className={`toggle-button ${location.file_path === timeline.file_path ? 'active' : ''}`}
+                onClick={() => setSearchParams({ symbol, file: location.file_path })}
+                style={{ marginRight: '0.5rem', marginBottom: '0.5rem' }}
+              >
+                {location.file_path} ({location.versions})
+              </button>
+            ))}
+          </div>
+        </div>
+      )}
+
+      {!timeline.indexed && (
+        <div className="error">
+          This database has no symbol sources. Build it with the <code>index</code> command to see the timeline.
+        </div>
+      )}
+
+      {versions.map((version, idx) => (
+        <VersionCard key={`${version.commit.id}-${idx}`} version={version} />
+      ))}
+    </div>
+  );
+}
@@ -125,3 +125,79 @@ export interface FileWithHistory {
   notes: AnalysisNote[];
 }
 
+
+export interface DiffLine {
+  op: ' ' | '+' | '-';
+  text: string;
+}
+
+export interface SymbolVersion {
+  commit: Commit;
+  file_id: number;
+  file_path: string;
+  symbol_name: string;
+  symbol_kind: string;
+  change: 'added' | 'modified' | 'removed';
+  start_line: number;
+  end_line: number;
+  snippet: string;
+  diff?: DiffLine[];
+  lineage?: 'renamed' | 'moved' | 'renamed_and_moved';
+  previous_name?: string;
+  previous_path?: string;
+}
+
+export interface SymbolLocation {
+  file_id: number;
+  file_path: string;
+  symbol_kind: string;
+  versions: number;
+}
+
+export interface SymbolTimeline {
+  symbol_name: string;
+  file_path: string;
+  versions: SymbolVersion[] | null;
+  locations: SymbolLocation[] | null;
+  indexed: boolean;
+}
+
+export interface SymbolChurn {
+  symbol_name: string;
+  symbol_kind: string;
+  file_id: number;
+  file_path: string;
+  changes: number;
+  authors: number;
+  first_change: string;
+  last_change: string;
+}
+
+export interface FileChurn {
+  id: number;
+  path: string;
+  commits: number;
+  authors: number;
+  additions: number;
+  deletions: number;
+  churn: number;
+  symbols: number;
+  last_change: string;
+}
+
+export interface FileCoupling {
+  file_a: File;
+  file_b: File;
+  shared_commits: number;
+  commits_a: number;
+  commits_b: number;
+  coupling: number;
+}
+
+export interface ChurnFilters {
+  prefix?: string;
+  since?: string;
+  limit?: number;
+  min_shared?: number;
+  max_files?: number;
+}
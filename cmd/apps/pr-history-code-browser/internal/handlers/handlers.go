@@ -208,8 +208,73 @@ func (h *Handler) HandleSearchSymbols(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, symbols)
 }
 
+// HandleGetSymbolTimeline returns every version of a symbol with diffs,
+// following renames and moves
+func (h *Handler) HandleGetSymbolTimeline(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "Symbol name required", http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := h.db.GetSymbolTimeline(symbol, r.URL.Query().Get("file"))
+	if err != nil {
+		log.Error().Err(err).Str("symbol", symbol).Msg("Failed to get symbol timeline")
+		http.Error(w, "Failed to get symbol timeline", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, timeline)
+}
+
+// HandleGetSymbolChurn returns the most changed symbols
+func (h *Handler) HandleGetSymbolChurn(w http.ResponseWriter, r *http.Request) {
+	churn, err := h.db.GetSymbolChurn(getChurnOptions(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get symbol churn")
+		http.Error(w, "Failed to get symbol churn", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, churn)
+}
+
+// HandleGetFileChurn returns the most changed files
+func (h *Handler) HandleGetFileChurn(w http.ResponseWriter, r *http.Request) {
+	churn, err := h.db.GetFileChurn(getChurnOptions(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get file churn")
+		http.Error(w, "Failed to get file churn", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, churn)
+}
+
+// HandleGetFileCoupling returns the files most often changed together
+func (h *Handler) HandleGetFileCoupling(w http.ResponseWriter, r *http.Request) {
+	coupling, err := h.db.GetFileCoupling(getChurnOptions(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get file coupling")
+		http.Error(w, "Failed to get file coupling", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, coupling)
+}
+
 // Helper functions
 
+func getChurnOptions(r *http.Request) models.ChurnOptions {
+	return models.ChurnOptions{
+		PathPrefix:        r.URL.Query().Get("prefix"),
+		Since:             r.URL.Query().Get("since"),
+		Limit:             getIntParam(r, "limit", 100),
+		MinShared:         getIntParam(r, "min_shared", 2),
+		MaxFilesPerCommit: getIntParam(r, "max_files", 30),
+	}
+}
+
 func respondJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -0,0 +1,278 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gitRepo runs git commands in a repository
+type gitRepo struct {
+	dir string
+}
+
+func (g *gitRepo) run(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = g.dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// resolve returns the commit hash of a revision
+func (g *gitRepo) resolve(rev string) (string, error) {
+	output, err := g.run("rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isAncestor reports whether ancestor is reachable from rev
+func (g *gitRepo) isAncestor(ancestor, rev string) bool {
+	_, err := g.run("merge-base", "--is-ancestor", ancestor, rev)
+	return err == nil
+}
+
+// revList returns the commits reachable from head and not from exclude,
+// parents first
+func (g *gitRepo) revList(head, exclude string) ([]string, error) {
+	args := []string{"rev-list", "--reverse", "--topo-order", head}
+	if exclude != "" {
+		args = append(args, "^"+exclude)
+	}
+	output, err := g.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// commitInfo is the metadata of a commit as stored in the commits table
+type commitInfo struct {
+	Hash           string
+	Parents        []string
+	AuthorName     string
+	AuthorEmail    string
+	AuthoredAt     string
+	CommitterName  string
+	CommitterEmail string
+	CommittedAt    string
+	Subject        string
+	Body           string
+}
+
+// commitInfoFormat separates the fields with NUL, the body comes last as it
+// may contain anything
+const commitInfoFormat = "%H%x00%P%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI%x00%s%x00%b"
+
+func (g *gitRepo) commitInfo(hash string) (*commitInfo, error) {
+	output, err := g.run("show", "-s", "--format="+commitInfoFormat, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(string(output), "\x00", 10)
+	if len(fields) != 10 {
+		return nil, fmt.Errorf("unexpected git show output for %s", hash)
+	}
+	return &commitInfo{
+		Hash:           fields[0],
+		Parents:        strings.Fields(fields[1]),
+		AuthorName:     fields[2],
+		AuthorEmail:    fields[3],
+		AuthoredAt:     fields[4],
+		CommitterName:  fields[5],
+		CommitterEmail: fields[6],
+		CommittedAt:    fields[7],
+		Subject:        fields[8],
+		Body:           strings.TrimSpace(fields[9]),
+	}, nil
+}
+
+// fileChange is one file in a commit's diff against its first parent
+type fileChange struct {
+	// ChangeType is A, M, D or R like git's name-status
+	ChangeType string
+	Path       string
+	OldPath    string
+	Additions  int
+	Deletions  int
+	Binary     bool
+	// Changed are the hunks on the new side of the diff
+	Changed []lineRange
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// changes returns the files a commit changed. Merge commits are not diffed,
+// like git log does by default.
+func (g *gitRepo) changes(hash string) ([]*fileChange, error) {
+	output, err := g.run("diff-tree", "-r", "--root", "-M", "-U0", "-p", "--no-commit-id", "--no-ext-diff", "--no-color", hash)
+	if err != nil {
+		return nil, err
+	}
+	return parseDiff(output)
+}
+
+// parseDiff parses the output of git diff-tree -p
+func parseDiff(output []byte) ([]*fileChange, error) {
+	var changes []*fileChange
+	var current *fileChange
+	inHunk := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "diff --git ") {
+			oldPath, newPath := splitDiffHeader(strings.TrimPrefix(line, "diff --git "))
+			current = &fileChange{ChangeType: "M", Path: newPath, OldPath: oldPath}
+			changes = append(changes, current)
+			inHunk = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if inHunk {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				current.Additions++
+				continue
+			case strings.HasPrefix(line, "-"):
+				current.Deletions++
+				continue
+			case strings.HasPrefix(line, `\`):
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			current.Changed = append(current.Changed, lineRange{Start: start, Count: count})
+			inHunk = true
+		case strings.HasPrefix(line, "new file mode"):
+			current.ChangeType = "A"
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.ChangeType = "D"
+		case strings.HasPrefix(line, "rename from "):
+			current.ChangeType = "R"
+			current.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			current.Path = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "--- a/"):
+			current.OldPath = strings.TrimRight(strings.TrimPrefix(line, "--- a/"), "\t")
+		case strings.HasPrefix(line, "+++ b/"):
+			current.Path = strings.TrimRight(strings.TrimPrefix(line, "+++ b/"), "\t")
+		case strings.HasPrefix(line, "Binary files "):
+			current.Binary = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		// Only renames keep the old path, deleted files keep their path
+		if change.ChangeType == "D" {
+			change.Path = change.OldPath
+		}
+		if change.ChangeType != "R" {
+			change.OldPath = ""
+		}
+	}
+	return changes, nil
+}
+
+// splitDiffHeader splits "a/old b/new". Paths with spaces are ambiguous in
+// the header, they are fixed up by the ---/+++ and rename lines.
+func splitDiffHeader(header string) (string, string) {
+	if i := strings.Index(header, " b/"); i >= 0 && strings.HasPrefix(header, "a/") {
+		return header[2:i], header[i+3:]
+	}
+	return header, header
+}
+
+// blobReader reads file contents through a long-running git cat-file
+type blobReader struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+func (g *gitRepo) newBlobReader() (*blobReader, error) {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = g.dir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	return &blobReader{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// read returns the contents of path at a commit, or nil if it doesn't exist
+func (b *blobReader) read(commit, path string) ([]byte, error) {
+	if _, err := fmt.Fprintf(b.stdin, "%s:%s\n", commit, path); err != nil {
+		return nil, err
+	}
+
+	header, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) == 2 && fields[1] == "missing" {
+		return nil, nil
+	}
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected git cat-file output %q", header)
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected git cat-file output %q", header)
+	}
+
+	// Contents are followed by a newline
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(b.stdout, content); err != nil {
+		return nil, err
+	}
+	if fields[1] != "blob" {
+		return nil, nil
+	}
+	return content[:size], nil
+}
+
+func (b *blobReader) Close() error {
+	b.stdin.Close()
+	return b.cmd.Wait()
+}
@@ -0,0 +1,91 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// diffOutput is git diff-tree -r --root -M -U0 -p output for an added,
+// modified, deleted, renamed and binary file
+const diffOutput = `diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..3b18e51
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,3 @@
++package p
++
++func New() {}
+diff --git a/main.go b/main.go
+index 3b18e51..5d2a1c9 100644
+--- a/main.go
++++ b/main.go
+@@ -3 +3 @@ package p
+-func A() int { return 1 }
++func A() int { return 2 }
+@@ -7,4 +6,0 @@ func A() int { return 1 }
+-func B() int {
+-	return 2
+-}
+-
+@@ -20,0 +17,2 @@ func C() int {
++// trailing comment
++// no newline
+\ No newline at end of file
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 3b18e51..0000000
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package p
+-
+diff --git a/pkg/a.go b/pkg/sub dir/b.go
+similarity index 90%
+rename from pkg/a.go
+rename to pkg/sub dir/b.go
+index 1111111..2222222 100644
+--- a/pkg/a.go
++++ b/pkg/sub dir/b.go
+@@ -1 +1 @@
+-package pkg
++package sub
+diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestParseDiff(t *testing.T) {
+	changes, err := parseDiff([]byte(diffOutput))
+	if err != nil {
+		t.Fatalf("parseDiff failed: %v", err)
+	}
+
+	var got []fileChange
+	for _, change := range changes {
+		got = append(got, *change)
+	}
+	want := []fileChange{
+		{ChangeType: "A", Path: "new.go", Additions: 3, Changed: []lineRange{{Start: 1, Count: 3}}},
+		{
+			ChangeType: "M", Path: "main.go", Additions: 3, Deletions: 5,
+			Changed: []lineRange{{Start: 3, Count: 1}, {Start: 6, Count: 0}, {Start: 17, Count: 2}},
+		},
+		{ChangeType: "D", Path: "old.go", Deletions: 2, Changed: []lineRange{{Start: 0, Count: 0}}},
+		{
+			ChangeType: "R", Path: "pkg/sub dir/b.go", OldPath: "pkg/a.go", Additions: 1, Deletions: 1,
+			Changed: []lineRange{{Start: 1, Count: 1}},
+		},
+		{ChangeType: "M", Path: "logo.png", Binary: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDiff:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseDiffInvalidHunk(t *testing.T) {
+	_, err := parseDiff([]byte("diff --git a/x.go b/x.go\n@@ broken @@\n"))
+	if err == nil {
+		t.Error("expected an error for an invalid hunk header")
+	}
+}
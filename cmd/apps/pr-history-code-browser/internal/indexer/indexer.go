@@ -0,0 +1,215 @@
+// Package indexer builds the history database the browser serves from a git
+// repository: commits, the files they changed and the symbols they touched.
+// Runs are incremental, commits already in the database are skipped.
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Options configures an indexing run
+type Options struct {
+	// RepoPath is the git repository to index
+	RepoPath string
+	// DBPath is the SQLite database, created if it doesn't exist
+	DBPath string
+	// Ref is the revision whose history is indexed
+	Ref string
+	// Full walks the whole history instead of starting from the last
+	// indexed commit. Commits already indexed are still skipped.
+	Full bool
+	// Extractors extract the symbols of changed files, the first one
+	// supporting a path is used
+	Extractors []Extractor
+}
+
+// DefaultExtractors are the symbol extractors used when none are configured
+func DefaultExtractors() []Extractor {
+	return []Extractor{GoExtractor{}}
+}
+
+// Result summarizes an indexing run
+type Result struct {
+	Head     string        `json:"head"`
+	Indexed  int           `json:"indexed"`
+	Skipped  int           `json:"skipped"`
+	Files    int           `json:"files"`
+	Symbols  int           `json:"symbols"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Run indexes the history of opts.Ref into opts.DBPath
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	start := time.Now()
+
+	repoPath, err := filepath.Abs(opts.RepoPath)
+	if err != nil {
+		return nil, err
+	}
+	repo := &gitRepo{dir: repoPath}
+	if opts.Ref == "" {
+		opts.Ref = "HEAD"
+	}
+	if opts.Extractors == nil {
+		opts.Extractors = DefaultExtractors()
+	}
+
+	head, err := repo.resolve(opts.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", opts.Ref, err)
+	}
+
+	db, err := openStore(opts.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	// Start from the last indexed head if it is part of this history,
+	// otherwise walk everything and skip what is already there
+	exclude := ""
+	if !opts.Full {
+		last, err := db.state(lastCommitKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index state: %w", err)
+		}
+		if last != "" && repo.isAncestor(last, head) {
+			exclude = last
+		}
+	}
+
+	hashes, err := repo.revList(head, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+	log.Info().Str("head", head).Str("from", exclude).Int("commits", len(hashes)).Msg("Indexing commits")
+
+	blobs, err := repo.newBlobReader()
+	if err != nil {
+		return nil, err
+	}
+	defer blobs.Close()
+
+	result := &Result{Head: head}
+	for i, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		exists, err := db.hasCommit(hash)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+
+		commit, err := indexCommit(repo, blobs, opts.Extractors, hash)
+		if err != nil {
+			return result, err
+		}
+		if err := db.writeCommit(commit); err != nil {
+			return result, err
+		}
+
+		result.Indexed++
+		result.Files += len(commit.Changes)
+		for _, symbols := range commit.Symbols {
+			result.Symbols += len(symbols)
+		}
+		if (i+1)%500 == 0 {
+			log.Info().Int("done", i+1).Int("total", len(hashes)).Msg("Indexing commits")
+		}
+	}
+
+	if err := db.setState(lastCommitKey, head); err != nil {
+		return result, fmt.Errorf("failed to save index state: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// indexCommit reads a commit's metadata, changes and touched symbols
+func indexCommit(repo *gitRepo, blobs *blobReader, extractors []Extractor, hash string) (*indexedCommit, error) {
+	info, err := repo.commitInfo(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	commit := &indexedCommit{Info: info, Symbols: map[string][]symbolChange{}}
+	if len(info.Parents) > 1 {
+		return commit, nil
+	}
+
+	commit.Changes, err = repo.changes(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff commit %s: %w", hash, err)
+	}
+
+	parent := ""
+	if len(info.Parents) == 1 {
+		parent = info.Parents[0]
+	}
+	for _, change := range commit.Changes {
+		if change.Binary {
+			continue
+		}
+		symbols, err := changeSymbols(blobs, extractors, hash, parent, change)
+		if err != nil {
+			// Files that don't parse are indexed without symbols
+			log.Debug().Err(err).Str("commit", hash).Str("path", change.Path).Msg("Failed to extract symbols")
+			continue
+		}
+		if len(symbols) > 0 {
+			commit.Symbols[change.Path] = symbols
+		}
+	}
+
+	return commit, nil
+}
+
+// changeSymbols returns the symbols a file change touched
+func changeSymbols(blobs *blobReader, extractors []Extractor, hash, parent string, change *fileChange) ([]symbolChange, error) {
+	oldPath := change.Path
+	if change.OldPath != "" {
+		oldPath = change.OldPath
+	}
+
+	extractor := extractorFor(extractors, change.Path)
+	if extractor == nil {
+		return nil, nil
+	}
+
+	var oldSymbols, newSymbols []Symbol
+	var oldSrc, newSrc []byte
+	var err error
+	if change.ChangeType != "A" && parent != "" {
+		if oldSrc, err = blobs.read(parent, oldPath); err != nil {
+			return nil, err
+		}
+		if oldSrc != nil {
+			if oldSymbols, err = extractor.Extract(oldPath, oldSrc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if change.ChangeType != "D" {
+		if newSrc, err = blobs.read(hash, change.Path); err != nil {
+			return nil, err
+		}
+		if newSrc != nil {
+			if newSymbols, err = extractor.Extract(change.Path, newSrc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return touchedSymbols(oldSymbols, newSymbols, oldSrc, newSrc, change.Changed), nil
+}
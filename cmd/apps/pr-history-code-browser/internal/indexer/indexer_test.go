@@ -0,0 +1,149 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo is a git repository in a temporary directory
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commit writes the files, removes those with empty contents and commits
+func (r *testRepo) commit(subject string, files map[string]string) string {
+	r.t.Helper()
+	for path, content := range files {
+		full := filepath.Join(r.dir, path)
+		if content == "" {
+			r.git("rm", "-q", path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		r.git("add", path)
+	}
+	r.git("commit", "-q", "-m", subject)
+	return r.git("rev-parse", "HEAD")
+}
+
+// touched returns the symbols stored for a commit as "change name"
+func touched(t *testing.T, dbPath, hash string) []string {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`
+		SELECT sv.change, sv.symbol_name, f.path
+		FROM symbol_versions sv
+		JOIN commits c ON c.id = sv.commit_id
+		JOIN files f ON f.id = sv.file_id
+		WHERE c.hash = ?
+		ORDER BY f.path, sv.symbol_name
+	`, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var change, name, path string
+		if err := rows.Scan(&change, &name, &path); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, change+" "+path+":"+name)
+	}
+	return result
+}
+
+func TestRunIncremental(t *testing.T) {
+	repo := newTestRepo(t)
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	run := func(full bool) *Result {
+		t.Helper()
+		result, err := Run(context.Background(), Options{RepoPath: repo.dir, DBPath: dbPath, Full: full})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return result
+	}
+
+	first := repo.commit("add p", map[string]string{"p.go": source, "README.md": "# p\n"})
+	result := run(false)
+	if result.Indexed != 1 || result.Skipped != 0 || result.Files != 2 || result.Symbols != 3 {
+		t.Errorf("first run: %+v", result)
+	}
+	if got, want := touched(t, dbPath, first), []string{"added p.go:A", "added p.go:B", "added p.go:C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first commit touched %q, want %q", got, want)
+	}
+
+	// Deleting B only touches B
+	second := repo.commit("remove B", map[string]string{
+		"p.go": strings.Replace(source, "func B() int {\n\treturn 2\n}\n\n", "", 1),
+	})
+	// Moving the file touches nothing, modifying q.go after the move does
+	repo.git("mv", "p.go", "q.go")
+	third := repo.commit("move p.go", nil)
+	fourth := repo.commit("change C", map[string]string{
+		"q.go": strings.Replace(strings.Replace(source, "func B() int {\n\treturn 2\n}\n\n", "", 1), "return 3", "return 30", 1),
+	})
+
+	// Only the new commits are indexed
+	result = run(false)
+	if result.Indexed != 3 || result.Skipped != 0 {
+		t.Errorf("incremental run: %+v", result)
+	}
+	if got, want := touched(t, dbPath, second), []string{"removed p.go:B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second commit touched %q, want %q", got, want)
+	}
+	if got := touched(t, dbPath, third); len(got) != 0 {
+		t.Errorf("move touched %q", got)
+	}
+	if got, want := touched(t, dbPath, fourth), []string{"modified q.go:C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fourth commit touched %q, want %q", got, want)
+	}
+
+	if result := run(false); result.Indexed != 0 || result.Skipped != 0 {
+		t.Errorf("run without new commits: %+v", result)
+	}
+	// A full run walks everything and skips what is there
+	if result := run(true); result.Indexed != 0 || result.Skipped != 4 {
+		t.Errorf("full run: %+v", result)
+	}
+}
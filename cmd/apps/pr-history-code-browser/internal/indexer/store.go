@@ -0,0 +1,241 @@
+package indexer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema creates the tables the browser reads. Databases built by
+// build_history_index.py already have them.
+const schema = `
+CREATE TABLE IF NOT EXISTS commits (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	hash TEXT NOT NULL UNIQUE,
+	parents TEXT,
+	author_name TEXT,
+	author_email TEXT,
+	authored_at TEXT,
+	committer_name TEXT,
+	committer_email TEXT,
+	committed_at TEXT,
+	subject TEXT,
+	body TEXT,
+	document_summary TEXT
+);
+
+CREATE TABLE IF NOT EXISTS files (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	path TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS commit_files (
+	commit_id INTEGER NOT NULL REFERENCES commits(id),
+	file_id INTEGER NOT NULL REFERENCES files(id),
+	change_type TEXT,
+	old_path TEXT,
+	additions INTEGER DEFAULT 0,
+	deletions INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS commit_symbols (
+	commit_id INTEGER NOT NULL REFERENCES commits(id),
+	file_id INTEGER NOT NULL REFERENCES files(id),
+	symbol_name TEXT NOT NULL,
+	symbol_kind TEXT
+);
+
+-- symbol_versions keeps the source of each symbol a commit touched, for the
+-- symbol timeline. It is only filled by the Go indexer.
+CREATE TABLE IF NOT EXISTS symbol_versions (
+	commit_id INTEGER NOT NULL REFERENCES commits(id),
+	file_id INTEGER NOT NULL REFERENCES files(id),
+	symbol_name TEXT NOT NULL,
+	symbol_kind TEXT NOT NULL,
+	change TEXT NOT NULL,
+	start_line INTEGER,
+	end_line INTEGER,
+	snippet TEXT
+);
+
+CREATE TABLE IF NOT EXISTS prs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT,
+	status TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	updated_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS pr_changelog (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pr_id INTEGER REFERENCES prs(id),
+	commit_id INTEGER REFERENCES commits(id),
+	file_id INTEGER REFERENCES files(id),
+	action TEXT,
+	details TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS analysis_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	commit_id INTEGER REFERENCES commits(id),
+	file_id INTEGER REFERENCES files(id),
+	note_type TEXT,
+	note TEXT,
+	tags TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS index_state (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_commit_files_commit ON commit_files(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_files_file ON commit_files(file_id);
+CREATE INDEX IF NOT EXISTS idx_commit_symbols_commit ON commit_symbols(commit_id);
+CREATE INDEX IF NOT EXISTS idx_commit_symbols_name ON commit_symbols(symbol_name);
+CREATE INDEX IF NOT EXISTS idx_symbol_versions_name ON symbol_versions(symbol_name, file_id);
+CREATE INDEX IF NOT EXISTS idx_symbol_versions_commit ON symbol_versions(commit_id);
+`
+
+// lastCommitKey is the index_state key of the last indexed head
+const lastCommitKey = "last_commit"
+
+// store writes the index to the database
+type store struct {
+	conn *sql.DB
+}
+
+func openStore(dbPath string) (*store, error) {
+	conn, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &store{conn: conn}, nil
+}
+
+func (s *store) Close() error {
+	return s.conn.Close()
+}
+
+func (s *store) state(key string) (string, error) {
+	var value string
+	err := s.conn.QueryRow(`SELECT value FROM index_state WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *store) setState(key, value string) error {
+	_, err := s.conn.Exec(`
+		INSERT INTO index_state (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
+
+func (s *store) hasCommit(hash string) (bool, error) {
+	var id int64
+	err := s.conn.QueryRow(`SELECT id FROM commits WHERE hash = ?`, hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// indexedCommit is what is written for one commit
+type indexedCommit struct {
+	Info    *commitInfo
+	Changes []*fileChange
+	// Symbols are the symbols touched in each changed file, by path
+	Symbols map[string][]symbolChange
+}
+
+// writeCommit stores a commit with its files and symbols in one transaction
+func (s *store) writeCommit(c *indexedCommit) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO commits (hash, parents, author_name, author_email, authored_at,
+		                     committer_name, committer_email, committed_at, subject, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.Info.Hash, strings.Join(c.Info.Parents, " "), c.Info.AuthorName, c.Info.AuthorEmail, c.Info.AuthoredAt,
+		c.Info.CommitterName, c.Info.CommitterEmail, c.Info.CommittedAt, c.Info.Subject, c.Info.Body)
+	if err != nil {
+		return fmt.Errorf("failed to insert commit %s: %w", c.Info.Hash, err)
+	}
+	commitID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range c.Changes {
+		fileID, err := fileID(tx, change.Path)
+		if err != nil {
+			return fmt.Errorf("failed to insert file %s: %w", change.Path, err)
+		}
+
+		var oldPath interface{}
+		if change.OldPath != "" {
+			oldPath = change.OldPath
+		}
+		_, err = tx.Exec(`
+			INSERT INTO commit_files (commit_id, file_id, change_type, old_path, additions, deletions)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, commitID, fileID, change.ChangeType, oldPath, change.Additions, change.Deletions)
+		if err != nil {
+			return fmt.Errorf("failed to insert change of %s: %w", change.Path, err)
+		}
+
+		for _, symbol := range c.Symbols[change.Path] {
+			_, err = tx.Exec(`
+				INSERT INTO commit_symbols (commit_id, file_id, symbol_name, symbol_kind)
+				VALUES (?, ?, ?, ?)
+			`, commitID, fileID, symbol.Name, symbol.Kind)
+			if err != nil {
+				return fmt.Errorf("failed to insert symbol %s: %w", symbol.Name, err)
+			}
+
+			_, err = tx.Exec(`
+				INSERT INTO symbol_versions (commit_id, file_id, symbol_name, symbol_kind, change, start_line, end_line, snippet)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, commitID, fileID, symbol.Name, symbol.Kind, symbol.Change, symbol.StartLine, symbol.EndLine, symbol.Snippet)
+			if err != nil {
+				return fmt.Errorf("failed to insert version of symbol %s: %w", symbol.Name, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fileID returns the id of a path, adding it to the files table if needed
+func fileID(tx *sql.Tx, path string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM files WHERE path = ?`, path).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`INSERT INTO files (path) VALUES (?)`, path)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
@@ -0,0 +1,219 @@
+package indexer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Symbol is a named definition in a source file
+type Symbol struct {
+	Name      string
+	Kind      string
+	StartLine int
+	EndLine   int
+}
+
+// Extractor finds the symbols defined in the files of one language
+type Extractor interface {
+	// Supports reports whether the extractor handles the file at path
+	Supports(path string) bool
+	// Extract returns the symbols defined in src
+	Extract(path string, src []byte) ([]Symbol, error)
+}
+
+// GoExtractor extracts functions, methods, types, constants and variables
+// from Go files. Methods are named Receiver.Method.
+type GoExtractor struct{}
+
+// Supports implements Extractor
+func (GoExtractor) Supports(path string) bool {
+	return filepath.Ext(path) == ".go"
+}
+
+// Extract implements Extractor
+func (GoExtractor) Extract(path string, src []byte) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			symbol := Symbol{Name: d.Name.Name, Kind: "function", StartLine: line(d.Pos()), EndLine: line(d.End())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol.Name = receiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+				symbol.Kind = "method"
+			}
+			symbols = append(symbols, symbol)
+
+		case *ast.GenDecl:
+			kind := ""
+			switch d.Tok {
+			case token.TYPE:
+				kind = "type"
+			case token.CONST:
+				kind = "const"
+			case token.VAR:
+				kind = "var"
+			default:
+				continue
+			}
+
+			for _, spec := range d.Specs {
+				// A single spec covers the keyword and its doc comment,
+				// grouped specs only their own lines
+				start, end := spec.Pos(), spec.End()
+				if !d.Lparen.IsValid() {
+					start, end = d.Pos(), d.End()
+				}
+
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, Symbol{Name: s.Name.Name, Kind: kind, StartLine: line(start), EndLine: line(end)})
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{Name: name.Name, Kind: kind, StartLine: line(start), EndLine: line(end)})
+					}
+				}
+			}
+		}
+	}
+
+	return symbols, nil
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// and type parameters
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return "?"
+}
+
+// extractorFor returns the first extractor that supports path
+func extractorFor(extractors []Extractor, path string) Extractor {
+	for _, extractor := range extractors {
+		if extractor.Supports(path) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// Change kinds of a touched symbol
+const (
+	SymbolAdded    = "added"
+	SymbolModified = "modified"
+	SymbolRemoved  = "removed"
+)
+
+// maxSnippetLines bounds the source stored for one symbol version
+const maxSnippetLines = 400
+
+// symbolChange is a symbol a commit touched, with its source after the
+// commit, or before it for removed symbols
+type symbolChange struct {
+	Symbol
+	Change  string
+	Snippet string
+}
+
+// touchedSymbols returns the symbols a change touched: symbols of the new
+// version overlapping changed lines, and symbols of the old version that no
+// longer exist
+func touchedSymbols(oldSymbols, newSymbols []Symbol, oldSrc, newSrc []byte, changed []lineRange) []symbolChange {
+	key := func(s Symbol) string {
+		return s.Kind + "\x00" + s.Name
+	}
+	previous := map[string]bool{}
+	for _, symbol := range oldSymbols {
+		previous[key(symbol)] = true
+	}
+
+	seen := map[string]bool{}
+	var result []symbolChange
+	for _, symbol := range newSymbols {
+		if seen[key(symbol)] {
+			continue
+		}
+		for _, r := range changed {
+			if r.overlaps(symbol.StartLine, symbol.EndLine) {
+				seen[key(symbol)] = true
+				change := SymbolModified
+				if !previous[key(symbol)] {
+					change = SymbolAdded
+				}
+				result = append(result, symbolChange{Symbol: symbol, Change: change, Snippet: snippet(newSrc, symbol)})
+				break
+			}
+		}
+	}
+
+	current := map[string]bool{}
+	for _, symbol := range newSymbols {
+		current[key(symbol)] = true
+	}
+	for _, symbol := range oldSymbols {
+		if !current[key(symbol)] && !seen[key(symbol)] {
+			seen[key(symbol)] = true
+			result = append(result, symbolChange{Symbol: symbol, Change: SymbolRemoved, Snippet: snippet(oldSrc, symbol)})
+		}
+	}
+
+	return result
+}
+
+// snippet returns the lines of a symbol
+func snippet(src []byte, symbol Symbol) string {
+	lines := strings.Split(string(src), "\n")
+	start, end := symbol.StartLine-1, symbol.EndLine
+	if start < 0 || start >= len(lines) {
+		return ""
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if end-start > maxSnippetLines {
+		end = start + maxSnippetLines
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// lineRange is a range of lines in the new version of a file. An empty range
+// marks lines removed after Start.
+type lineRange struct {
+	Start int
+	Count int
+}
+
+// overlaps reports whether the lines changed a symbol. Lines removed after
+// Start only changed symbols spanning both Start and the line after it, not
+// the symbols ending right before or starting right after them.
+func (r lineRange) overlaps(start, end int) bool {
+	if r.Count == 0 {
+		return start <= r.Start && end >= r.Start+1
+	}
+	return start <= r.Start+r.Count-1 && end >= r.Start
+}
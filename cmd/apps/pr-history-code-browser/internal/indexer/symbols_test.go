@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// source has the functions A, B and C on lines 3-5, 7-9 and 11-13
+const source = `package p
+
+func A() int {
+	return 1
+}
+
+func B() int {
+	return 2
+}
+
+func C() int {
+	return 3
+}
+`
+
+func TestGoExtractor(t *testing.T) {
+	src := `package p
+
+// T is a type
+type T struct{}
+
+func (t *T) M() {}
+
+func (G[K]) N() {}
+
+const (
+	X = 1
+	Y = 2
+)
+
+var _, Z = 1, 2
+`
+	symbols, err := GoExtractor{}.Extract("p.go", []byte(src))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	want := []Symbol{
+		{Name: "T", Kind: "type", StartLine: 4, EndLine: 4},
+		{Name: "T.M", Kind: "method", StartLine: 6, EndLine: 6},
+		{Name: "G.N", Kind: "method", StartLine: 8, EndLine: 8},
+		{Name: "X", Kind: "const", StartLine: 11, EndLine: 11},
+		{Name: "Y", Kind: "const", StartLine: 12, EndLine: 12},
+		{Name: "Z", Kind: "var", StartLine: 15, EndLine: 15},
+	}
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("symbols:\n got %+v\nwant %+v", symbols, want)
+	}
+
+	if _, err := (GoExtractor{}).Extract("broken.go", []byte("package p\nfunc {")); err == nil {
+		t.Error("expected an error for a file that doesn't parse")
+	}
+}
+
+func TestLineRangeOverlaps(t *testing.T) {
+	// A symbol on lines 3-5
+	tests := []struct {
+		r    lineRange
+		want bool
+	}{
+		{lineRange{Start: 1, Count: 2}, false},
+		{lineRange{Start: 1, Count: 3}, true},
+		{lineRange{Start: 4, Count: 1}, true},
+		{lineRange{Start: 5, Count: 10}, true},
+		{lineRange{Start: 6, Count: 1}, false},
+
+		// Removed lines inside the symbol
+		{lineRange{Start: 3, Count: 0}, true},
+		{lineRange{Start: 4, Count: 0}, true},
+		// Removed right before or after it
+		{lineRange{Start: 2, Count: 0}, false},
+		{lineRange{Start: 5, Count: 0}, false},
+		{lineRange{Start: 0, Count: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.overlaps(3, 5); got != tt.want {
+			t.Errorf("%+v overlaps 3-5 = %t, want %t", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestTouchedSymbols(t *testing.T) {
+	tests := []struct {
+		name    string
+		newSrc  string
+		changed []lineRange
+		want    []string
+	}{
+		{
+			name:    "add",
+			newSrc:  source + "\nfunc D() int {\n\treturn 4\n}\n",
+			changed: []lineRange{{Start: 14, Count: 4}},
+			want:    []string{"added function D 15-17"},
+		},
+		{
+			name:    "modify",
+			newSrc:  strings.Replace(source, "return 2", "return 20", 1),
+			changed: []lineRange{{Start: 8, Count: 1}},
+			want:    []string{"modified function B 7-9"},
+		},
+		{
+			name:    "remove a line",
+			newSrc:  strings.Replace(source, "\treturn 1\n", "", 1),
+			changed: []lineRange{{Start: 3, Count: 0}},
+			want:    []string{"modified function A 3-4"},
+		},
+		{
+			// The lines before and after the deleted function are only
+			// neighbours, A and C didn't change
+			name:    "delete",
+			newSrc:  strings.Replace(source, "func B() int {\n\treturn 2\n}\n\n", "", 1),
+			changed: []lineRange{{Start: 6, Count: 0}},
+			want:    []string{"removed function B 7-9"},
+		},
+		{
+			name:    "delete, diffed from the blank line before",
+			newSrc:  strings.Replace(source, "\nfunc B() int {\n\treturn 2\n}\n", "", 1),
+			changed: []lineRange{{Start: 5, Count: 0}},
+			want:    []string{"removed function B 7-9"},
+		},
+		{
+			name:    "rename",
+			newSrc:  strings.Replace(source, "func B()", "func B2()", 1),
+			changed: []lineRange{{Start: 7, Count: 1}},
+			want:    []string{"added function B2 7-9", "removed function B 7-9"},
+		},
+		{
+			// A renamed or moved file without changes touches nothing
+			name:   "unchanged",
+			newSrc: source,
+		},
+	}
+
+	oldSymbols, err := GoExtractor{}.Extract("p.go", []byte(source))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSymbols, err := GoExtractor{}.Extract("p.go", []byte(tt.newSrc))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			var got []string
+			for _, c := range touchedSymbols(oldSymbols, newSymbols, []byte(source), []byte(tt.newSrc), tt.changed) {
+				got = append(got, fmt.Sprintf("%s %s %s %d-%d", c.Change, c.Kind, c.Name, c.StartLine, c.EndLine))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("touched symbols = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTouchedSymbolsSnippets(t *testing.T) {
+	oldSymbols, _ := GoExtractor{}.Extract("p.go", []byte(source))
+	newSrc := strings.Replace(source, "func B() int {\n\treturn 2\n}\n\n", "", 1)
+	newSymbols, _ := GoExtractor{}.Extract("p.go", []byte(newSrc))
+
+	changes := touchedSymbols(oldSymbols, newSymbols, []byte(source), []byte(newSrc), []lineRange{{Start: 6, Count: 0}})
+	// Removed symbols keep their source from before the commit
+	if len(changes) != 1 || changes[0].Snippet != "func B() int {\n\treturn 2\n}" {
+		t.Errorf("changes = %+v", changes)
+	}
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+)
+
+// ChurnOptions filters the churn metrics
+type ChurnOptions struct {
+	// PathPrefix restricts the metrics to files under a path
+	PathPrefix string
+	// Since restricts the metrics to commits at or after a date
+	Since string
+	Limit int
+	// MinShared is the minimum number of commits two files must share to be
+	// reported as coupled
+	MinShared int
+	// MaxFilesPerCommit leaves out commits touching more files, like bulk
+	// imports and reformats, from the coupling
+	MaxFilesPerCommit int
+}
+
+// SymbolChurn is how often a symbol changed
+type SymbolChurn struct {
+	SymbolName  string `json:"symbol_name"`
+	SymbolKind  string `json:"symbol_kind"`
+	FileID      int64  `json:"file_id"`
+	FilePath    string `json:"file_path"`
+	Changes     int    `json:"changes"`
+	Authors     int    `json:"authors"`
+	FirstChange string `json:"first_change"`
+	LastChange  string `json:"last_change"`
+}
+
+// FileChurn is how often and how much a file changed
+type FileChurn struct {
+	File
+	Commits    int    `json:"commits"`
+	Authors    int    `json:"authors"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+	Churn      int    `json:"churn"`
+	Symbols    int    `json:"symbols"`
+	LastChange string `json:"last_change"`
+}
+
+// FileCoupling is how often two files change together
+type FileCoupling struct {
+	FileA         File `json:"file_a"`
+	FileB         File `json:"file_b"`
+	SharedCommits int  `json:"shared_commits"`
+	CommitsA      int  `json:"commits_a"`
+	CommitsB      int  `json:"commits_b"`
+	// Coupling is the shared commits over the average commits of both files
+	Coupling float64 `json:"coupling"`
+}
+
+// churnFilter returns the conditions on commits c and files f for opts
+func churnFilter(opts ChurnOptions) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+	if opts.PathPrefix != "" {
+		where += ` AND f.path LIKE ?`
+		args = append(args, opts.PathPrefix+"%")
+	}
+	if opts.Since != "" {
+		where += ` AND c.committed_at >= ?`
+		args = append(args, opts.Since)
+	}
+	return where, args
+}
+
+// GetSymbolChurn retrieves the most changed symbols
+func (db *DB) GetSymbolChurn(opts ChurnOptions) ([]SymbolChurn, error) {
+	where, args := churnFilter(opts)
+	query := `
+		SELECT cs.symbol_name, cs.symbol_kind, cs.file_id, f.path,
+		       COUNT(DISTINCT cs.commit_id), COUNT(DISTINCT c.author_email),
+		       MIN(c.committed_at), MAX(c.committed_at)
+		FROM commit_symbols cs
+		JOIN commits c ON cs.commit_id = c.id
+		JOIN files f ON cs.file_id = f.id
+		WHERE 1=1` + where + `
+		GROUP BY cs.symbol_name, cs.symbol_kind, cs.file_id, f.path
+		ORDER BY 5 DESC, 6 DESC, cs.symbol_name
+		LIMIT ?
+	`
+	rows, err := db.conn.Query(query, append(args, opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var churn []SymbolChurn
+	for rows.Next() {
+		var s SymbolChurn
+		// Commits without a date leave the range empty
+		var firstChange, lastChange sql.NullString
+		err := rows.Scan(&s.SymbolName, &s.SymbolKind, &s.FileID, &s.FilePath, &s.Changes, &s.Authors, &firstChange, &lastChange)
+		if err != nil {
+			return nil, err
+		}
+		s.FirstChange = firstChange.String
+		s.LastChange = lastChange.String
+		churn = append(churn, s)
+	}
+
+	return churn, rows.Err()
+}
+
+// GetFileChurn retrieves the most changed files
+func (db *DB) GetFileChurn(opts ChurnOptions) ([]FileChurn, error) {
+	where, args := churnFilter(opts)
+	query := `
+		SELECT f.id, f.path,
+		       COUNT(DISTINCT cf.commit_id), COUNT(DISTINCT c.author_email),
+		       COALESCE(SUM(cf.additions), 0), COALESCE(SUM(cf.deletions), 0),
+		       (SELECT COUNT(DISTINCT cs.symbol_name) FROM commit_symbols cs WHERE cs.file_id = f.id),
+		       MAX(c.committed_at)
+		FROM commit_files cf
+		JOIN commits c ON cf.commit_id = c.id
+		JOIN files f ON cf.file_id = f.id
+		WHERE 1=1` + where + `
+		GROUP BY f.id, f.path
+		ORDER BY 3 DESC, SUM(cf.additions) + SUM(cf.deletions) DESC, f.path
+		LIMIT ?
+	`
+	rows, err := db.conn.Query(query, append(args, opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var churn []FileChurn
+	for rows.Next() {
+		var fc FileChurn
+		var lastChange sql.NullString
+		err := rows.Scan(&fc.ID, &fc.Path, &fc.Commits, &fc.Authors, &fc.Additions, &fc.Deletions, &fc.Symbols, &lastChange)
+		if err != nil {
+			return nil, err
+		}
+		fc.LastChange = lastChange.String
+		fc.Churn = fc.Additions + fc.Deletions
+		churn = append(churn, fc)
+	}
+
+	return churn, rows.Err()
+}
+
+// GetFileCoupling retrieves the pairs of files most often changed together
+func (db *DB) GetFileCoupling(opts ChurnOptions) ([]FileCoupling, error) {
+	where, args := churnFilter(opts)
+	query := `
+		WITH small_commits AS (
+			SELECT commit_id FROM commit_files GROUP BY commit_id HAVING COUNT(*) <= ?
+		),
+		changes AS (
+			SELECT DISTINCT cf.commit_id, cf.file_id
+			FROM commit_files cf
+			JOIN small_commits sc ON cf.commit_id = sc.commit_id
+			JOIN commits c ON cf.commit_id = c.id
+			JOIN files f ON cf.file_id = f.id
+			WHERE 1=1` + where + `
+		),
+		totals AS (
+			SELECT file_id, COUNT(*) AS commits FROM changes GROUP BY file_id
+		)
+		SELECT a.file_id, fa.path, b.file_id, fb.path, COUNT(*) AS shared, ta.commits, tb.commits
+		FROM changes a
+		JOIN changes b ON a.commit_id = b.commit_id AND a.file_id < b.file_id
+		JOIN totals ta ON ta.file_id = a.file_id
+		JOIN totals tb ON tb.file_id = b.file_id
+		JOIN files fa ON fa.id = a.file_id
+		JOIN files fb ON fb.id = b.file_id
+		GROUP BY a.file_id, b.file_id
+		HAVING shared >= ?
+		ORDER BY shared DESC, fa.path, fb.path
+		LIMIT ?
+	`
+	args = append([]interface{}{opts.MaxFilesPerCommit}, args...)
+	rows, err := db.conn.Query(query, append(args, opts.MinShared, opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coupling []FileCoupling
+	for rows.Next() {
+		var fc FileCoupling
+		err := rows.Scan(&fc.FileA.ID, &fc.FileA.Path, &fc.FileB.ID, &fc.FileB.Path, &fc.SharedCommits, &fc.CommitsA, &fc.CommitsB)
+		if err != nil {
+			return nil, err
+		}
+		average := float64(fc.CommitsA+fc.CommitsB) / 2
+		fc.Coupling = math.Round(float64(fc.SharedCommits)/average*100) / 100
+		coupling = append(coupling, fc)
+	}
+
+	return coupling, rows.Err()
+}
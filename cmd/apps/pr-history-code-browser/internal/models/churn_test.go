@@ -0,0 +1,198 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// changed returns a Go file with the functions, the first one changed
+func changed(n int, names ...string) string {
+	return strings.Replace(goFile(names...), "total := 0", fmt.Sprintf("total := %d", n), 1)
+}
+
+// churnRepo has commits right before, at and after the start of 2024-05-02
+func churnRepo(t *testing.T) *DB {
+	repo := newTestRepo(t)
+	repo.commit("2024-05-01T23:59:59Z", "alice@example.com", map[string]string{"p.go": goFile("Sum", "Other")})
+	repo.commit("2024-05-02T00:00:00Z", "bob@example.com", map[string]string{"p.go": changed(1, "Sum", "Other"), "q.go": goFile("Q")})
+	repo.commit("2024-05-03T12:00:00Z", "alice@example.com", map[string]string{"p.go": changed(2, "Sum", "Other"), "q.go": changed(2, "Q")})
+	// Touches three files
+	repo.commit("2024-05-04T12:00:00Z", "carol@example.com", map[string]string{"p.go": changed(3, "Sum", "Other"), "q.go": changed(3, "Q"), "r.go": goFile("R")})
+	return repo.index()
+}
+
+func TestGetSymbolChurn(t *testing.T) {
+	db := churnRepo(t)
+	tests := []struct {
+		since string
+		want  []string
+	}{
+		{
+			since: "",
+			want: []string{
+				"p.go:Sum 4 changes by 3 from 2024-05-01T23:59:59+00:00 to 2024-05-04T12:00:00+00:00",
+				"q.go:Q 3 changes by 3 from 2024-05-02T00:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"p.go:Other 1 changes by 1 from 2024-05-01T23:59:59+00:00 to 2024-05-01T23:59:59+00:00",
+				"r.go:R 1 changes by 1 from 2024-05-04T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			// A date starts the window at midnight, commits at midnight are in
+			// it. Ties are ordered by name.
+			since: "2024-05-02",
+			want: []string{
+				"q.go:Q 3 changes by 3 from 2024-05-02T00:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"p.go:Sum 3 changes by 3 from 2024-05-02T00:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"r.go:R 1 changes by 1 from 2024-05-04T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			since: "2024-05-02T00:00:00",
+			want: []string{
+				"q.go:Q 3 changes by 3 from 2024-05-02T00:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"p.go:Sum 3 changes by 3 from 2024-05-02T00:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"r.go:R 1 changes by 1 from 2024-05-04T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			since: "2024-05-02T00:00:01",
+			want: []string{
+				"q.go:Q 2 changes by 2 from 2024-05-03T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"p.go:Sum 2 changes by 2 from 2024-05-03T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+				"r.go:R 1 changes by 1 from 2024-05-04T12:00:00+00:00 to 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			since: "2024-05-05",
+		},
+	}
+	for _, tt := range tests {
+		t.Run("since "+tt.since, func(t *testing.T) {
+			churn, err := db.GetSymbolChurn(ChurnOptions{Since: tt.since, Limit: 100})
+			if err != nil {
+				t.Fatalf("GetSymbolChurn failed: %v", err)
+			}
+			var got []string
+			for _, s := range churn {
+				got = append(got, fmt.Sprintf("%s:%s %d changes by %d from %s to %s", s.FilePath, s.SymbolName, s.Changes, s.Authors, s.FirstChange, s.LastChange))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("symbol churn:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+
+	churn, err := db.GetSymbolChurn(ChurnOptions{PathPrefix: "q", Limit: 100})
+	if err != nil {
+		t.Fatalf("GetSymbolChurn failed: %v", err)
+	}
+	if len(churn) != 1 || churn[0].SymbolName != "Q" {
+		t.Errorf("symbol churn under q = %+v", churn)
+	}
+	if churn, _ := db.GetSymbolChurn(ChurnOptions{Limit: 1}); len(churn) != 1 || churn[0].SymbolName != "Sum" {
+		t.Errorf("limited symbol churn = %+v", churn)
+	}
+}
+
+func TestGetFileChurn(t *testing.T) {
+	db := churnRepo(t)
+	tests := []struct {
+		since string
+		want  []string
+	}{
+		{
+			since: "",
+			want: []string{
+				"p.go 4 commits by 3 +20 -3 churn 23, 2 symbols, last 2024-05-04T12:00:00+00:00",
+				"q.go 3 commits by 3 +11 -2 churn 13, 1 symbols, last 2024-05-04T12:00:00+00:00",
+				"r.go 1 commits by 1 +9 -0 churn 9, 1 symbols, last 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			since: "2024-05-02",
+			want: []string{
+				"q.go 3 commits by 3 +11 -2 churn 13, 1 symbols, last 2024-05-04T12:00:00+00:00",
+				"p.go 3 commits by 3 +3 -3 churn 6, 2 symbols, last 2024-05-04T12:00:00+00:00",
+				"r.go 1 commits by 1 +9 -0 churn 9, 1 symbols, last 2024-05-04T12:00:00+00:00",
+			},
+		},
+		{
+			since: "2024-05-03",
+			want: []string{
+				"p.go 2 commits by 2 +2 -2 churn 4, 2 symbols, last 2024-05-04T12:00:00+00:00",
+				"q.go 2 commits by 2 +2 -2 churn 4, 1 symbols, last 2024-05-04T12:00:00+00:00",
+				"r.go 1 commits by 1 +9 -0 churn 9, 1 symbols, last 2024-05-04T12:00:00+00:00",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("since "+tt.since, func(t *testing.T) {
+			churn, err := db.GetFileChurn(ChurnOptions{Since: tt.since, Limit: 100})
+			if err != nil {
+				t.Fatalf("GetFileChurn failed: %v", err)
+			}
+			var got []string
+			for _, f := range churn {
+				got = append(got, fmt.Sprintf("%s %d commits by %d +%d -%d churn %d, %d symbols, last %s", f.Path, f.Commits, f.Authors, f.Additions, f.Deletions, f.Churn, f.Symbols, f.LastChange))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("file churn:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFileCoupling(t *testing.T) {
+	db := churnRepo(t)
+	tests := []struct {
+		name string
+		opts ChurnOptions
+		want []string
+	}{
+		{
+			name: "all commits",
+			opts: ChurnOptions{MinShared: 1, MaxFilesPerCommit: 30},
+			want: []string{"p.go q.go 3 of 4/3: 0.86", "p.go r.go 1 of 4/1: 0.4", "q.go r.go 1 of 3/1: 0.5"},
+		},
+		{
+			name: "min shared",
+			opts: ChurnOptions{MinShared: 2, MaxFilesPerCommit: 30},
+			want: []string{"p.go q.go 3 of 4/3: 0.86"},
+		},
+		{
+			// The commit touching three files is left out of the coupling
+			name: "max files per commit",
+			opts: ChurnOptions{MinShared: 1, MaxFilesPerCommit: 2},
+			want: []string{"p.go q.go 2 of 3/2: 0.8"},
+		},
+		{
+			// Only the commits in the window count towards the totals
+			name: "since",
+			opts: ChurnOptions{Since: "2024-05-03", MinShared: 1, MaxFilesPerCommit: 30},
+			want: []string{"p.go q.go 2 of 2/2: 1", "p.go r.go 1 of 2/1: 0.67", "q.go r.go 1 of 2/1: 0.67"},
+		},
+		{
+			name: "since, at the boundary",
+			opts: ChurnOptions{Since: "2024-05-02", MinShared: 1, MaxFilesPerCommit: 30},
+			want: []string{"p.go q.go 3 of 3/3: 1", "p.go r.go 1 of 3/1: 0.5", "q.go r.go 1 of 3/1: 0.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Limit = 100
+			coupling, err := db.GetFileCoupling(tt.opts)
+			if err != nil {
+				t.Fatalf("GetFileCoupling failed: %v", err)
+			}
+			var got []string
+			for _, c := range coupling {
+				got = append(got, fmt.Sprintf("%s %s %d of %d/%d: %v", c.FileA.Path, c.FileB.Path, c.SharedCommits, c.CommitsA, c.CommitsB, c.Coupling))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("file coupling:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// SymbolVersion is a symbol as one commit left it
+type SymbolVersion struct {
+	Commit     Commit `json:"commit"`
+	FileID     int64  `json:"file_id"`
+	FilePath   string `json:"file_path"`
+	SymbolName string `json:"symbol_name"`
+	SymbolKind string `json:"symbol_kind"`
+	Change     string `json:"change"` // added|modified|removed
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Snippet    string `json:"snippet"`
+	// Diff is the change from the previous version in the timeline
+	Diff []DiffLine `json:"diff,omitempty"`
+	// Lineage is set on the version a symbol was renamed or moved to:
+	// renamed|moved|renamed_and_moved
+	Lineage      string `json:"lineage,omitempty"`
+	PreviousName string `json:"previous_name,omitempty"`
+	PreviousPath string `json:"previous_path,omitempty"`
+}
+
+// DiffLine is one line of a diff
+type DiffLine struct {
+	Op   string `json:"op"` // " "|"+"|"-"
+	Text string `json:"text"`
+}
+
+// SymbolLocation is a file a symbol name is defined in
+type SymbolLocation struct {
+	FileID     int64  `json:"file_id"`
+	FilePath   string `json:"file_path"`
+	SymbolKind string `json:"symbol_kind"`
+	Versions   int    `json:"versions"`
+}
+
+// SymbolTimeline is the evolution of a symbol, oldest version first,
+// following renames and moves
+type SymbolTimeline struct {
+	SymbolName string          `json:"symbol_name"`
+	FilePath   string          `json:"file_path"`
+	Versions   []SymbolVersion `json:"versions"`
+	// Locations are all files defining a symbol with this name
+	Locations []SymbolLocation `json:"locations"`
+	// Indexed is false for databases without symbol_versions, built by
+	// build_history_index.py or before the table existed
+	Indexed bool `json:"indexed"`
+}
+
+const (
+	// renameSimilarity is how similar a removed and an added symbol of the
+	// same commit must be to be considered the same symbol
+	renameSimilarity = 0.6
+	// maxLineageHops bounds how many renames and moves are followed
+	maxLineageHops = 20
+)
+
+// hasSymbolVersions reports whether the database has symbol sources
+func (db *DB) hasSymbolVersions() (bool, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'symbol_versions'`).Scan(&count)
+	return count > 0, err
+}
+
+// symbolVersions retrieves versions of symbols, in history order. The Go
+// indexer stores commits parents first, so commit ids follow the history.
+func (db *DB) symbolVersions(where string, args ...interface{}) ([]SymbolVersion, error) {
+	query := `
+		SELECT c.id, c.hash, c.author_name, c.author_email, c.committed_at, c.subject,
+		       sv.file_id, f.path, sv.symbol_name, sv.symbol_kind, sv.change,
+		       sv.start_line, sv.end_line, sv.snippet
+		FROM symbol_versions sv
+		JOIN commits c ON sv.commit_id = c.id
+		JOIN files f ON sv.file_id = f.id
+		WHERE ` + where + `
+		ORDER BY c.id
+	`
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []SymbolVersion
+	for rows.Next() {
+		var v SymbolVersion
+		var startLine, endLine sql.NullInt64
+		var snippet sql.NullString
+		err := rows.Scan(&v.Commit.ID, &v.Commit.Hash, &v.Commit.AuthorName, &v.Commit.AuthorEmail, &v.Commit.CommittedAt, &v.Commit.Subject,
+			&v.FileID, &v.FilePath, &v.SymbolName, &v.SymbolKind, &v.Change,
+			&startLine, &endLine, &snippet)
+		if err != nil {
+			return nil, err
+		}
+		v.StartLine = int(startLine.Int64)
+		v.EndLine = int(endLine.Int64)
+		v.Snippet = snippet.String
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// getSymbolLocations retrieves the files defining a symbol name, most
+// recently changed first
+func (db *DB) getSymbolLocations(symbolName string) ([]SymbolLocation, error) {
+	query := `
+		SELECT cs.file_id, f.path, cs.symbol_kind, COUNT(*)
+		FROM commit_symbols cs
+		JOIN files f ON cs.file_id = f.id
+		WHERE cs.symbol_name = ?
+		GROUP BY cs.file_id, f.path, cs.symbol_kind
+		ORDER BY MAX(cs.commit_id) DESC
+	`
+	rows, err := db.conn.Query(query, symbolName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []SymbolLocation
+	for rows.Next() {
+		var l SymbolLocation
+		if err := rows.Scan(&l.FileID, &l.FilePath, &l.SymbolKind, &l.Versions); err != nil {
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+
+	return locations, rows.Err()
+}
+
+// filePathLineage returns a path and the paths the file had before git
+// renames, newest first
+func (db *DB) filePathLineage(path string) ([]string, error) {
+	paths := []string{path}
+	seen := map[string]bool{path: true}
+	for len(paths) <= maxLineageHops {
+		var oldPath sql.NullString
+		err := db.conn.QueryRow(`
+			SELECT cf.old_path
+			FROM commit_files cf
+			JOIN files f ON cf.file_id = f.id
+			WHERE f.path = ? AND cf.change_type = 'R' AND cf.old_path IS NOT NULL
+			ORDER BY cf.commit_id DESC
+			LIMIT 1
+		`, paths[len(paths)-1]).Scan(&oldPath)
+		if err == sql.ErrNoRows || (err == nil && seen[oldPath.String]) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[oldPath.String] = true
+		paths = append(paths, oldPath.String)
+	}
+	return paths, nil
+}
+
+// GetSymbolTimeline retrieves every version of a symbol with the diffs
+// between them. Without a file path, the most recently changed definition
+// with that name is used. The timeline follows the file through git renames,
+// and the symbol through renames and moves: a removed symbol and a similar
+// symbol added in the same commit are considered the same symbol.
+func (db *DB) GetSymbolTimeline(symbolName, filePath string) (*SymbolTimeline, error) {
+	timeline := &SymbolTimeline{SymbolName: symbolName, FilePath: filePath}
+
+	locations, err := db.getSymbolLocations(symbolName)
+	if err != nil {
+		return nil, err
+	}
+	timeline.Locations = locations
+	if timeline.FilePath == "" && len(locations) > 0 {
+		timeline.FilePath = locations[0].FilePath
+	}
+
+	timeline.Indexed, err = db.hasSymbolVersions()
+	if err != nil || !timeline.Indexed {
+		return timeline, err
+	}
+
+	paths, err := db.filePathLineage(timeline.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{symbolName}
+	for _, path := range paths {
+		args = append(args, path)
+	}
+	versions, err := db.symbolVersions(`sv.symbol_name = ? AND f.path IN (?`+strings.Repeat(", ?", len(paths)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Follow the symbol back through the renames and moves that created it
+	for hops := 0; hops < maxLineageHops && len(versions) > 0 && versions[0].Change == "added"; hops++ {
+		origin, err := db.findLineage(versions[0], "removed")
+		if err != nil {
+			return nil, err
+		}
+		if origin == nil {
+			break
+		}
+		earlier, err := db.symbolVersions(`sv.symbol_name = ? AND sv.file_id = ? AND sv.symbol_kind = ? AND c.id <= ?`,
+			origin.SymbolName, origin.FileID, origin.SymbolKind, origin.Commit.ID)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(earlier, versions...)
+	}
+
+	// And forward through the renames and moves that replaced it
+	for hops := 0; hops < maxLineageHops && len(versions) > 0 && versions[len(versions)-1].Change == "removed"; hops++ {
+		successor, err := db.findLineage(versions[len(versions)-1], "added")
+		if err != nil {
+			return nil, err
+		}
+		if successor == nil {
+			break
+		}
+		later, err := db.symbolVersions(`sv.symbol_name = ? AND sv.file_id = ? AND sv.symbol_kind = ? AND c.id >= ?`,
+			successor.SymbolName, successor.FileID, successor.SymbolKind, successor.Commit.ID)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, later...)
+	}
+
+	// The removal half of a rename or move is not shown on its own, the
+	// version it became is diffed against it
+	var result []SymbolVersion
+	var previous *SymbolVersion
+	for i := range versions {
+		v := versions[i]
+		if v.Change == "removed" && i+1 < len(versions) && versions[i+1].Commit.ID == v.Commit.ID {
+			previous = &versions[i]
+			continue
+		}
+
+		switch {
+		case previous == nil:
+			v.Diff = diffLines("", v.Snippet)
+		case v.Change == "removed":
+			v.Diff = diffLines(v.Snippet, "")
+		default:
+			v.Diff = diffLines(previous.Snippet, v.Snippet)
+		}
+
+		if previous != nil {
+			renamed := previous.SymbolName != v.SymbolName
+			moved := previous.FilePath != v.FilePath
+			switch {
+			case renamed && moved:
+				v.Lineage = "renamed_and_moved"
+			case renamed:
+				v.Lineage = "renamed"
+			case moved:
+				v.Lineage = "moved"
+			}
+			if renamed || moved {
+				v.PreviousName = previous.SymbolName
+				v.PreviousPath = previous.FilePath
+			}
+		}
+
+		result = append(result, v)
+		previous = &versions[i]
+	}
+
+	timeline.Versions = result
+	return timeline, nil
+}
+
+// findLineage finds the other half of a rename or move: the symbol with
+// the opposite change in the same commit that is most similar to v
+func (db *DB) findLineage(v SymbolVersion, change string) (*SymbolVersion, error) {
+	candidates, err := db.symbolVersions(`sv.commit_id = ? AND sv.symbol_kind = ? AND sv.change = ? AND NOT (sv.symbol_name = ? AND sv.file_id = ?)`,
+		v.Commit.ID, v.SymbolKind, change, v.SymbolName, v.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *SymbolVersion
+	bestScore := renameSimilarity
+	for i := range candidates {
+		score := similarity(candidates[i].SymbolName, candidates[i].Snippet, v.SymbolName, v.Snippet)
+		if score >= bestScore {
+			best, bestScore = &candidates[i], score
+		}
+	}
+	return best, nil
+}
+
+// similarity compares two symbol sources line by line, ignoring their names.
+// It returns the share of lines they have in common.
+func similarity(nameA, a, nameB, b string) float64 {
+	a = strings.ReplaceAll(a, lastPart(nameA), "\x00")
+	b = strings.ReplaceAll(b, lastPart(nameB), "\x00")
+
+	common, linesA, linesB := 0, 0, 0
+	for _, line := range diffLines(a, b) {
+		switch line.Op {
+		case " ":
+			common++
+			linesA++
+			linesB++
+		case "-":
+			linesA++
+		case "+":
+			linesB++
+		}
+	}
+	if linesA+linesB == 0 {
+		return 0
+	}
+	return float64(2*common) / float64(linesA+linesB)
+}
+
+// lastPart returns the method name of Receiver.Method
+func lastPart(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// diffLines returns the line diff from a to b
+func diffLines(a, b string) []DiffLine {
+	dmp := diffmatchpatch.New()
+	charsA, charsB, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(charsA, charsB, false), lines)
+
+	var result []DiffLine
+	for _, d := range diffs {
+		op := " "
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = "+"
+		case diffmatchpatch.DiffDelete:
+			op = "-"
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(d.Text, "\n"), "\n") {
+			if d.Text == "" {
+				continue
+			}
+			result = append(result, DiffLine{Op: op, Text: line})
+		}
+	}
+	return result
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/pr-history-code-browser/internal/indexer"
+)
+
+// testRepo is a git repository in a temporary directory, indexed into a
+// database by the Go indexer
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git(nil, "init", "-q")
+	return r
+}
+
+func (r *testRepo) git(env []string, args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+	}
+}
+
+// commit writes the files, removes those with empty contents and commits
+// them as author at date
+func (r *testRepo) commit(date, author string, files map[string]string) {
+	r.t.Helper()
+	for path, content := range files {
+		full := filepath.Join(r.dir, path)
+		if content == "" {
+			r.git(nil, "rm", "-q", path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		r.git(nil, "add", path)
+	}
+	r.git([]string{
+		"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date,
+		"GIT_AUTHOR_EMAIL=" + author, "GIT_COMMITTER_EMAIL=" + author,
+	}, "commit", "-q", "--allow-empty", "-m", "commit at "+date)
+}
+
+// index indexes the repository and opens the database
+func (r *testRepo) index() *DB {
+	r.t.Helper()
+	dbPath := filepath.Join(r.t.TempDir(), "history.db")
+	if _, err := indexer.Run(context.Background(), indexer.Options{RepoPath: r.dir, DBPath: dbPath}); err != nil {
+		r.t.Fatalf("indexing failed: %v", err)
+	}
+	db, err := NewDB(dbPath)
+	if err != nil {
+		r.t.Fatalf("NewDB failed: %v", err)
+	}
+	r.t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// goFile returns a Go file defining the functions, each summing its
+// arguments in a few lines so that renamed copies stay similar
+func goFile(names ...string) string {
+	src := "package p\n"
+	for _, name := range names {
+		src += fmt.Sprintf("\nfunc %s(values []int) int {\n\ttotal := 0\n\tfor _, v := range values {\n\t\ttotal += v\n\t}\n\treturn total\n}\n", name)
+	}
+	return src
+}
+
+// step is one commit of a lineage test
+type step struct {
+	files map[string]string
+	// move renames a file with git mv before the commit
+	move [2]string
+}
+
+func TestGetSymbolTimeline(t *testing.T) {
+	tests := []struct {
+		name   string
+		steps  []step
+		symbol string
+		path   string
+		want   []string
+	}{
+		{
+			name: "modified",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": strings.Replace(goFile("Sum", "Other"), "total := 0", "total := 1", 1)}},
+			},
+			symbol: "Sum",
+			path:   "p.go",
+			want:   []string{"added p.go:Sum", "modified p.go:Sum"},
+		},
+		{
+			name: "renamed",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": goFile("Total", "Other")}},
+			},
+			symbol: "Total",
+			path:   "p.go",
+			want:   []string{"added p.go:Sum", "added p.go:Total renamed from p.go:Sum"},
+		},
+		{
+			// The timeline of the old name follows it to the new one
+			name: "renamed, from the old name",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": goFile("Total", "Other")}},
+				{files: map[string]string{"p.go": strings.Replace(goFile("Total", "Other"), "total := 0", "total := 1", 1)}},
+			},
+			symbol: "Sum",
+			path:   "p.go",
+			want:   []string{"added p.go:Sum", "added p.go:Total renamed from p.go:Sum", "modified p.go:Total"},
+		},
+		{
+			name: "moved to another file",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": goFile("Other"), "q.go": goFile("Sum")}},
+			},
+			symbol: "Sum",
+			path:   "q.go",
+			want:   []string{"added p.go:Sum", "added q.go:Sum moved from p.go:Sum"},
+		},
+		{
+			name: "renamed and moved",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": goFile("Other"), "sub/q.go": goFile("Total")}},
+			},
+			symbol: "Total",
+			path:   "sub/q.go",
+			want:   []string{"added p.go:Sum", "added sub/q.go:Total renamed_and_moved from p.go:Sum"},
+		},
+		{
+			// git renames of the file are followed without touching the
+			// symbol, the next change shows where it went
+			name: "file renamed",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{move: [2]string{"p.go", "q.go"}},
+				{files: map[string]string{"q.go": strings.Replace(goFile("Sum", "Other"), "total := 0", "total := 1", 1)}},
+			},
+			symbol: "Sum",
+			path:   "q.go",
+			want:   []string{"added p.go:Sum", "modified q.go:Sum moved from p.go:Sum"},
+		},
+		{
+			// A different function added as one is removed is not a rename
+			name: "replaced",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum")}},
+				{files: map[string]string{"p.go": "package p\n\nfunc Max(a, b int) int {\n\tif a > b {\n\t\treturn a\n\t}\n\treturn b\n}\n"}},
+			},
+			symbol: "Max",
+			path:   "p.go",
+			want:   []string{"added p.go:Max"},
+		},
+		{
+			name: "removed",
+			steps: []step{
+				{files: map[string]string{"p.go": goFile("Sum", "Other")}},
+				{files: map[string]string{"p.go": goFile("Other")}},
+			},
+			symbol: "Sum",
+			path:   "p.go",
+			want:   []string{"added p.go:Sum", "removed p.go:Sum"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepo(t)
+			for i, s := range tt.steps {
+				if s.move[0] != "" {
+					repo.git(nil, "mv", s.move[0], s.move[1])
+				}
+				repo.commit(fmt.Sprintf("2024-05-01T%02d:00:00Z", i), "dev@example.com", s.files)
+			}
+			db := repo.index()
+
+			timeline, err := db.GetSymbolTimeline(tt.symbol, tt.path)
+			if err != nil {
+				t.Fatalf("GetSymbolTimeline failed: %v", err)
+			}
+			if !timeline.Indexed {
+				t.Fatal("expected an indexed timeline")
+			}
+			var got []string
+			for _, v := range timeline.Versions {
+				version := v.Change + " " + v.FilePath + ":" + v.SymbolName
+				if v.Lineage != "" {
+					version += " " + v.Lineage + " from " + v.PreviousPath + ":" + v.PreviousName
+				}
+				got = append(got, version)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versions:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSymbolTimelineDiffs(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("2024-05-01T00:00:00Z", "dev@example.com", map[string]string{"p.go": goFile("Sum")})
+	repo.commit("2024-05-01T01:00:00Z", "dev@example.com", map[string]string{"p.go": goFile("Total")})
+	db := repo.index()
+
+	// Without a path, the most recently changed definition is used
+	timeline, err := db.GetSymbolTimeline("Total", "")
+	if err != nil {
+		t.Fatalf("GetSymbolTimeline failed: %v", err)
+	}
+	if timeline.FilePath != "p.go" || len(timeline.Locations) != 1 || len(timeline.Versions) != 2 {
+		t.Fatalf("timeline = %+v", timeline)
+	}
+
+	// The first version is all additions, the rename is diffed against the
+	// version it replaced
+	for _, line := range timeline.Versions[0].Diff {
+		if line.Op != "+" {
+			t.Errorf("first version diff has %+v", line)
+		}
+	}
+	want := []DiffLine{
+		{Op: "-", Text: "func Sum(values []int) int {"},
+		{Op: "+", Text: "func Total(values []int) int {"},
+	}
+	var changed []DiffLine
+	for _, line := range timeline.Versions[1].Diff {
+		if line.Op != " " {
+			changed = append(changed, line)
+		}
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("rename diff = %+v, want %+v", changed, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	sum := "func Sum(values []int) int {\n\treturn len(values)\n}"
+	tests := []struct {
+		name  string
+		nameB string
+		b     string
+		want  float64
+	}{
+		{"same source", "Sum", sum, 1},
+		// Names are ignored
+		{"renamed", "Total", strings.ReplaceAll(sum, "Sum", "Total"), 1},
+		{"renamed method", "T.Total", strings.ReplaceAll(sum, "Sum", "Total"), 1},
+		{"one line changed", "Sum", strings.Replace(sum, "len(values)", "0", 1), 2.0 / 3},
+		{"different", "Max", "func Max() {}", 0},
+		{"empty", "Sum", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := similarity("Sum", sum, tt.nameB, tt.b); got != tt.want {
+				t.Errorf("similarity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
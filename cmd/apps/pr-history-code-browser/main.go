@@ -14,6 +14,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/pr-history-code-browser/internal/handlers"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/pr-history-code-browser/internal/indexer"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/pr-history-code-browser/internal/models"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
@@ -67,12 +68,60 @@ Provides an interactive interface to browse commits, files, PRs, and analysis no
 	// Mark db flag as required
 	rootCmd.MarkFlagRequired("db")
 
+	rootCmd.AddCommand(newIndexCommand())
+
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal().Err(err).Msg("Command execution failed")
 	}
 }
 
+// newIndexCommand creates the command that builds or refreshes the database
+// from a git repository
+func newIndexCommand() *cobra.Command {
+	opts := indexer.Options{
+		RepoPath: ".",
+		Ref:      "HEAD",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Index the history of a git repository into the database",
+		Long: `Walks the history of a git repository and fills the commits, files,
+commit_files and commit_symbols tables. Symbols are extracted from Go files.
+
+The database is created if it doesn't exist. Later runs continue from the last
+indexed commit, commits already in the database are never indexed twice.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			result, err := indexer.Run(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			log.Info().
+				Str("head", result.Head).
+				Int("indexed", result.Indexed).
+				Int("skipped", result.Skipped).
+				Int("files", result.Files).
+				Int("symbols", result.Symbols).
+				Dur("duration", result.Duration).
+				Msg("Index updated")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.DBPath, "db", "d", "", "Path to SQLite database file (required)")
+	cmd.Flags().StringVarP(&opts.RepoPath, "repo", "r", opts.RepoPath, "Path to the git repository")
+	cmd.Flags().StringVar(&opts.Ref, "ref", opts.Ref, "Revision whose history is indexed")
+	cmd.Flags().BoolVar(&opts.Full, "full", false, "Walk the whole history instead of starting from the last indexed commit")
+	cmd.MarkFlagRequired("db")
+
+	return cmd
+}
+
 func runServer(config Config) error {
 	// Open database
 	db, err := models.NewDB(config.DBPath)
@@ -130,6 +179,12 @@ func runServer(config Config) error {
 		// Symbols (new cross-referencing endpoints)
 		r.Get("/symbols/history", handler.HandleGetSymbolHistory)
 		r.Get("/symbols/search", handler.HandleSearchSymbols)
+		r.Get("/symbols/timeline", handler.HandleGetSymbolTimeline)
+
+		// Churn and hotspots
+		r.Get("/churn/symbols", handler.HandleGetSymbolChurn)
+		r.Get("/churn/files", handler.HandleGetFileChurn)
+		r.Get("/churn/coupling", handler.HandleGetFileCoupling)
 	})
 
 	// Serve frontend
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/prompt-middleware/internal/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// replay runs recorded LLM responses through the parse phase of a pipeline and
+// exits non-zero if any recording doesn't produce its expected results.
+func main() {
+	logLevelStr := flag.String("log-level", "warn", "Log level (debug, info, warn, error)")
+	pipelinePath := flag.String("pipeline", "", "Pipeline YAML file (default: built-in default pipeline)")
+	recordingsPath := flag.String("recordings", "recordings.yaml", "Recordings YAML file or directory of recording files")
+	flag.Parse()
+
+	logLevel, err := zerolog.ParseLevel(*logLevelStr)
+	if err != nil {
+		logLevel = zerolog.WarnLevel
+	}
+	output := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	log.Logger = zerolog.New(output).Level(logLevel).With().Timestamp().Logger()
+
+	config := middleware.DefaultPipelineConfig()
+	if *pipelinePath != "" {
+		config, err = middleware.LoadPipelineConfig(*pipelinePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load pipeline")
+		}
+	}
+
+	pipeline, err := middleware.DefaultRegistry().BuildPipeline(config)
+	if err != nil {
+		log.Fatal().Err(err).Str("pipeline", config.Name).Msg("Failed to build pipeline")
+	}
+
+	recordings, err := middleware.LoadRecordings(*recordingsPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load recordings")
+	}
+	if len(recordings) == 0 {
+		log.Fatal().Str("recordings", *recordingsPath).Msg("No recordings found")
+	}
+
+	results := middleware.Replay(pipeline, recordings)
+	fmt.Print(middleware.FormatReplayResults(results))
+	for _, result := range results {
+		if !result.Passed {
+			os.Exit(1)
+		}
+	}
+}
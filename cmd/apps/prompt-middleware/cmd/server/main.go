@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag" // Added for command-line flags
+	"fmt"
+	"html"
 	"net/http"
 	"os"   // Added for zerolog console writer
 	"sync" // Added for mutex
@@ -29,6 +31,10 @@ type AppState struct {
 	InitialFragments      []middleware.PromptFragment
 	UserQuery             string
 	FinalPrompt           string
+	PipelineName          string
+	RecordingsPath        string
+	ResponseOverride      string             // Pasted LLM response, used instead of the mock if set
+	ParseContext          middleware.Context // Context after the prompt phase, where parsing starts
 	LLMResponse           string
 	ProcessedResponse     string
 	FinalContext          middleware.Context
@@ -38,6 +44,8 @@ type AppState struct {
 func main() {
 	// --- Logging Setup ---
 	logLevelStr := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	pipelinePath := flag.String("pipeline", "", "Pipeline YAML file (default: built-in default pipeline)")
+	recordingsPath := flag.String("recordings", "recordings.yaml", "File recorded request/response pairs are appended to")
 	flag.Parse()
 
 	logLevel, err := zerolog.ParseLevel(*logLevelStr)
@@ -53,27 +61,33 @@ func main() {
 
 	log.Info().Str("logLevel", logLevel.String()).Msg("Logger initialized")
 
-	// Initialize default middlewares and their initial state
-	defaultMiddlewares := []middleware.Middleware{
-		middleware.NewSystemInstructionMiddleware(""),
-		middleware.NewThinkingModeMiddleware(),
-		middleware.NewTokenCounterMiddleware(),
+	// Load the pipeline definition, or fall back to the default middlewares
+	pipelineConfig := middleware.DefaultPipelineConfig()
+	if *pipelinePath != "" {
+		pipelineConfig, err = middleware.LoadPipelineConfig(*pipelinePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load pipeline")
+		}
+	}
+	pipelineMiddlewares, err := middleware.DefaultRegistry().Instantiate(pipelineConfig)
+	if err != nil {
+		log.Fatal().Err(err).Str("pipeline", pipelineConfig.Name).Msg("Failed to create pipeline middlewares")
 	}
 
 	configuredMiddlewares := make(map[string]*MiddlewareState)
-	order := make([]string, 0, len(defaultMiddlewares))
-	log.Debug().Msg("Configuring default middlewares")
-	for _, mw := range defaultMiddlewares {
-		id := mw.ID()
+	order := make([]string, 0, len(pipelineMiddlewares))
+	log.Debug().Str("pipeline", pipelineConfig.Name).Msg("Configuring pipeline middlewares")
+	for _, mw := range pipelineMiddlewares {
+		id := mw.Middleware.ID()
 		configuredMiddlewares[id] = &MiddlewareState{
-			Instance: mw,
-			Enabled:  true, // Start with all default middlewares enabled
+			Instance: mw.Middleware,
+			Enabled:  mw.Enabled,
 		}
 		order = append(order, id)
 		log.Debug().
 			Str("middlewareId", id).
-			Str("middlewareName", mw.Name()).
-			Bool("enabled", true).
+			Str("middlewareName", mw.Middleware.Name()).
+			Bool("enabled", mw.Enabled).
 			Msg("Configured middleware")
 	}
 	log.Info().Strs("middlewareOrder", order).Msg("Initial middleware order")
@@ -85,6 +99,8 @@ func main() {
 	appState := &AppState{
 		ConfiguredMiddlewares: configuredMiddlewares,
 		Order:                 order,
+		PipelineName:          pipelineConfig.Name,
+		RecordingsPath:        *recordingsPath,
 		CurrentContext:        initialCtx,
 		UserQuery:             "Explain Go interfaces.",
 		InitialFragments:      []middleware.PromptFragment{},
@@ -103,6 +119,7 @@ func main() {
 	http.HandleFunc("/process", appState.handleProcess)                   // Endpoint to trigger processing
 	http.HandleFunc("/toggleMiddleware", appState.handleToggleMiddleware) // Endpoint to toggle middleware
 	http.HandleFunc("/updateQuery", appState.handleUpdateQuery)           // Endpoint to update user query
+	http.HandleFunc("/record", appState.handleRecord)                     // Endpoint to record the current request/response pair
 
 	log.Info().Msg("Starting server on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -179,6 +196,9 @@ func (s *AppState) createPageData() ui.PageData {
 		Middlewares:       mwData,
 		InitialContext:    initialCtxCopy,
 		UserQuery:         s.UserQuery,
+		PipelineName:      s.PipelineName,
+		RecordingsPath:    s.RecordingsPath,
+		ResponseOverride:  s.ResponseOverride,
 		FinalPrompt:       s.FinalPrompt,
 		LLMResponse:       s.LLMResponse,
 		ProcessedResponse: s.ProcessedResponse,
@@ -220,11 +240,18 @@ func (s *AppState) processPipeline() {
 	log.Info().Msg("Prompt phase completed")
 	log.Debug().Str("finalPrompt", s.FinalPrompt).Interface("contextAfterPrompt", promptCtx).Msg("Prompt phase results")
 
-	// --- LLM Call (Mock) ---
-	log.Info().Msg("Executing mock LLM call...")
-	llmResponse := middleware.MockLLM(promptCtx, s.FinalPrompt, s.UserQuery)
+	s.ParseContext = promptCtx
+
+	// --- LLM Call (Mock, unless a real response was pasted) ---
+	llmResponse := s.ResponseOverride
+	if llmResponse == "" {
+		log.Info().Msg("Executing mock LLM call...")
+		llmResponse = middleware.MockLLM(promptCtx, s.FinalPrompt, s.UserQuery)
+		log.Info().Msg("Mock LLM call completed")
+	} else {
+		log.Info().Msg("Using pasted LLM response")
+	}
 	s.LLMResponse = llmResponse // Store the raw response
-	log.Info().Msg("Mock LLM call completed")
 	log.Debug().Str("llmResponse", s.LLMResponse).Msg("LLM response received")
 
 	// --- Parse Phase ---
@@ -254,6 +281,7 @@ func (s *AppState) handleProcess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.UserQuery = r.FormValue("userQuery")
+	s.ResponseOverride = r.FormValue("llmResponse")
 	thinkingMode := r.FormValue("thinkingMode") == "on"
 	s.CurrentContext.Set(middleware.ThinkingModeContextKey, thinkingMode) // Use Set for orderedmap
 	log.Info().Str("userQuery", s.UserQuery).Bool("thinkingMode", thinkingMode).Msg("Updated state from process request")
@@ -356,3 +384,35 @@ func (s *AppState) handleUpdateQuery(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msg("Successfully rendered results panel component")
 	}
 }
+
+// handleRecord saves the current request/response pair to the recordings
+// file, to be replayed as a regression test with cmd/replay.
+func (s *AppState) handleRecord(w http.ResponseWriter, r *http.Request) {
+	log.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("Received record request")
+	if r.Method != http.MethodPost {
+		log.Warn().Str("method", r.Method).Msg("Method not allowed for /record")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Error().Err(err).Msg("Failed to parse form")
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	name := r.FormValue("recordingName")
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", s.PipelineName, time.Now().Format("20060102-150405"))
+	}
+	recording := middleware.NewRecording(name, s.ParseContext, s.FinalPrompt, s.LLMResponse, s.ProcessedResponse, s.FinalContext)
+	err := middleware.AppendRecording(s.RecordingsPath, recording)
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Error().Err(err).Str("path", s.RecordingsPath).Msg("Failed to save recording")
+		http.Error(w, "Failed to save recording", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "Recorded %s to %s", html.EscapeString(name), html.EscapeString(s.RecordingsPath))
+}
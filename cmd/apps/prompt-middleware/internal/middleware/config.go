@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineConfig is a pipeline defined in a YAML file. Middlewares run in the
+// order they are listed for the prompt phase, and in reverse for the parse phase.
+//
+//	name: json-answers
+//	middlewares:
+//	  - id: system-instruction
+//	    params:
+//	      instructions: You answer in JSON.
+//	  - id: token-counter
+//	    enabled: false
+type PipelineConfig struct {
+	Name        string             `yaml:"name"`
+	Description string             `yaml:"description,omitempty"`
+	Middlewares []MiddlewareConfig `yaml:"middlewares"`
+}
+
+// MiddlewareConfig configures one middleware of a pipeline.
+type MiddlewareConfig struct {
+	ID string `yaml:"id"`
+	// Enabled defaults to true
+	Enabled *bool  `yaml:"enabled,omitempty"`
+	Params  Params `yaml:"params,omitempty"`
+}
+
+// IsEnabled reports whether the middleware is enabled, which is the default.
+func (c MiddlewareConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// LoadPipelineConfig reads a pipeline definition from a YAML file.
+func LoadPipelineConfig(path string) (*PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline %s: %w", path, err)
+	}
+	var config PipelineConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline %s: %w", path, err)
+	}
+	if len(config.Middlewares) == 0 {
+		return nil, fmt.Errorf("pipeline %s has no middlewares", path)
+	}
+	for i, mw := range config.Middlewares {
+		if mw.ID == "" {
+			return nil, fmt.Errorf("pipeline %s: middleware %d has no id", path, i)
+		}
+	}
+	log.Debug().Str("path", path).Str("name", config.Name).Int("middlewareCount", len(config.Middlewares)).Msg("Loaded pipeline config")
+	return &config, nil
+}
+
+// ConfiguredMiddleware is a middleware instantiated from a pipeline file.
+type ConfiguredMiddleware struct {
+	Middleware Middleware
+	Enabled    bool
+}
+
+// Instantiate creates the middlewares of a pipeline, disabled ones included,
+// in pipeline order.
+func (r *Registry) Instantiate(config *PipelineConfig) ([]ConfiguredMiddleware, error) {
+	result := make([]ConfiguredMiddleware, 0, len(config.Middlewares))
+	seen := map[string]bool{}
+	for _, mwConfig := range config.Middlewares {
+		if seen[mwConfig.ID] {
+			return nil, fmt.Errorf("middleware %s is listed twice", mwConfig.ID)
+		}
+		seen[mwConfig.ID] = true
+
+		mw, err := r.Create(mwConfig.ID, mwConfig.Params)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ConfiguredMiddleware{Middleware: mw, Enabled: mwConfig.IsEnabled()})
+	}
+	return result, nil
+}
+
+// BuildPipeline creates a pipeline of the enabled middlewares of a config.
+func (r *Registry) BuildPipeline(config *PipelineConfig) (*MiddlewarePipeline, error) {
+	middlewares, err := r.Instantiate(config)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := NewMiddlewarePipeline()
+	for _, mw := range middlewares {
+		if mw.Enabled {
+			pipeline.Use(mw.Middleware)
+		}
+	}
+	return pipeline, nil
+}
+
+// DefaultPipelineConfig is the pipeline used when no pipeline file is given.
+func DefaultPipelineConfig() *PipelineConfig {
+	return &PipelineConfig{
+		Name: "default",
+		Middlewares: []MiddlewareConfig{
+			{ID: "system-instruction"},
+			{ID: "thinking-mode"},
+			{ID: "token-counter"},
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a file into a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPipelineConfig(t *testing.T) {
+	for _, path := range []string{"../../pipelines/default.yaml", "../../pipelines/json-answers.yaml"} {
+		config, err := LoadPipelineConfig(path)
+		if err != nil {
+			t.Fatalf("LoadPipelineConfig(%s) failed: %v", path, err)
+		}
+		if _, err := DefaultRegistry().BuildPipeline(config); err != nil {
+			t.Errorf("BuildPipeline(%s) failed: %v", path, err)
+		}
+	}
+
+	config, err := LoadPipelineConfig(writeFile(t, "p.yaml", `
+name: p
+middlewares:
+  - id: system-instruction
+  - id: thinking-mode
+    enabled: false
+  - id: token-counter
+    enabled: true
+`))
+	if err != nil {
+		t.Fatalf("LoadPipelineConfig failed: %v", err)
+	}
+	pipeline, err := DefaultRegistry().BuildPipeline(config)
+	if err != nil {
+		t.Fatalf("BuildPipeline failed: %v", err)
+	}
+	// Disabled middlewares are left out of the pipeline
+	var ids []string
+	for _, mw := range pipeline.Middlewares() {
+		ids = append(ids, mw.ID())
+	}
+	if want := []string{"system-instruction", "token-counter"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("pipeline middlewares = %v, want %v", ids, want)
+	}
+}
+
+func TestLoadPipelineConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid yaml", "middlewares: [", "failed to parse pipeline"},
+		{"no middlewares", "name: empty\n", "has no middlewares"},
+		{"middleware without id", "middlewares:\n  - params: {}\n", "middleware 0 has no id"},
+		{"middlewares not a list", "middlewares: token-counter\n", "failed to parse pipeline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPipelineConfig(writeFile(t, "p.yaml", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := LoadPipelineConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read pipeline") {
+		t.Errorf("expected a read error for a missing file, got %v", err)
+	}
+}
+
+func TestBuildPipelineErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		middlewares []MiddlewareConfig
+		wantErr     string
+	}{
+		{
+			name:        "unknown middleware",
+			middlewares: []MiddlewareConfig{{ID: "spell-checker"}},
+			wantErr:     `unknown middleware "spell-checker"`,
+		},
+		{
+			name:        "listed twice",
+			middlewares: []MiddlewareConfig{{ID: "token-counter"}, {ID: "token-counter"}},
+			wantErr:     "middleware token-counter is listed twice",
+		},
+		{
+			// Disabled middlewares are still checked
+			name:        "invalid disabled middleware",
+			middlewares: []MiddlewareConfig{{ID: "context-truncation", Enabled: new(bool)}},
+			wantErr:     "middleware context-truncation: max_tokens must be positive",
+		},
+		{
+			name:        "wrong parameter type",
+			middlewares: []MiddlewareConfig{{ID: "system-instruction", Params: Params{"instructions": 42}}},
+			wantErr:     "middleware system-instruction: parameter instructions must be a string, got int",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DefaultRegistry().BuildPipeline(&PipelineConfig{Name: tt.name, Middlewares: tt.middlewares})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	params := Params{
+		"name":    "x",
+		"count":   3,
+		"float":   4.0,
+		"half":    1.5,
+		"list":    []interface{}{"a", "b"},
+		"mixed":   []interface{}{"a", 1},
+		"nothing": nil,
+	}
+
+	if s, err := params.String("name", "def"); err != nil || s != "x" {
+		t.Errorf("String(name) = %q, %v", s, err)
+	}
+	if s, err := params.String("nothing", "def"); err != nil || s != "def" {
+		t.Errorf("String(nothing) = %q, %v", s, err)
+	}
+	if _, err := params.String("count", ""); err == nil {
+		t.Error("expected an error for a number as a string")
+	}
+
+	if n, err := params.Int("count", 0); err != nil || n != 3 {
+		t.Errorf("Int(count) = %d, %v", n, err)
+	}
+	// Numbers decoded from JSON are floats
+	if n, err := params.Int("float", 0); err != nil || n != 4 {
+		t.Errorf("Int(float) = %d, %v", n, err)
+	}
+	if n, err := params.Int("missing", 7); err != nil || n != 7 {
+		t.Errorf("Int(missing) = %d, %v", n, err)
+	}
+	if _, err := params.Int("half", 0); err == nil {
+		t.Error("expected an error for a fraction as an integer")
+	}
+	if _, err := params.Int("name", 0); err == nil {
+		t.Error("expected an error for a string as an integer")
+	}
+
+	if list, err := params.Strings("list", nil); err != nil || !reflect.DeepEqual(list, []string{"a", "b"}) {
+		t.Errorf("Strings(list) = %v, %v", list, err)
+	}
+	if _, err := params.Strings("mixed", nil); err == nil || !strings.Contains(err.Error(), "mixed[1]") {
+		t.Errorf("expected an error for a list with a number, got %v", err)
+	}
+	if _, err := params.Strings("name", nil); err == nil {
+		t.Error("expected an error for a string as a list")
+	}
+}
@@ -0,0 +1,390 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// --- FewShotMiddleware ---
+
+// FewShotExample is an example input and the output expected for it.
+type FewShotExample struct {
+	Input  string `yaml:"input" json:"input"`
+	Output string `yaml:"output" json:"output"`
+}
+
+type FewShotMiddleware struct {
+	Header   string
+	Examples []FewShotExample
+}
+
+// NewFewShotMiddleware creates a middleware that injects examples before the query.
+func NewFewShotMiddleware(header string, examples []FewShotExample) *FewShotMiddleware {
+	if header == "" {
+		header = "Here are some examples of how to respond:"
+	}
+	log.Debug().Str("middlewareId", "few-shot").Int("exampleCount", len(examples)).Msg("Creating FewShotMiddleware")
+	return &FewShotMiddleware{Header: header, Examples: examples}
+}
+
+func newFewShotMiddlewareFromParams(params Params) (Middleware, error) {
+	header, err := params.String("header", "")
+	if err != nil {
+		return nil, err
+	}
+	var examples []FewShotExample
+	if err := decodeParam(params, "examples", &examples); err != nil {
+		return nil, err
+	}
+	examplesFile, err := params.String("examples_file", "")
+	if err != nil {
+		return nil, err
+	}
+	if examplesFile != "" {
+		data, err := os.ReadFile(examplesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read examples file: %w", err)
+		}
+		var fileExamples []FewShotExample
+		if err := yaml.Unmarshal(data, &fileExamples); err != nil {
+			return nil, fmt.Errorf("failed to parse examples file %s: %w", examplesFile, err)
+		}
+		examples = append(examples, fileExamples...)
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("no examples given, set examples or examples_file")
+	}
+	return NewFewShotMiddleware(header, examples), nil
+}
+
+var _ Middleware = &FewShotMiddleware{}
+
+func (m *FewShotMiddleware) ID() string   { return "few-shot" }
+func (m *FewShotMiddleware) Name() string { return "Few-Shot Examples" }
+func (m *FewShotMiddleware) Description() string {
+	return "Injects example inputs and outputs before the user query."
+}
+
+func (m *FewShotMiddleware) Prompt(ctx Context, fragments []PromptFragment) (Context, []PromptFragment) {
+	var builder strings.Builder
+	builder.WriteString(m.Header)
+	for i, example := range m.Examples {
+		fmt.Fprintf(&builder, "\n\nExample %d:\nInput: %s\nOutput: %s", i+1, strings.TrimSpace(example.Input), strings.TrimSpace(example.Output))
+	}
+
+	examplesFragment := PromptFragment{
+		Content: builder.String(),
+		Metadata: PromptFragmentMetadata{
+			ID:       m.ID(),
+			Type:     "examples",
+			Position: "middle",
+			Priority: 70, // Above the user query so examples come first
+		},
+	}
+	log.Trace().Str("middlewareId", m.ID()).Interface("fragmentAdded", examplesFragment).Msg("Appending few-shot examples fragment")
+	return ctx, append(fragments, examplesFragment)
+}
+
+func (m *FewShotMiddleware) Parse(ctx Context, response string) (Context, string) {
+	// This middleware doesn't modify the response
+	return ctx, response
+}
+
+// --- OutputFormatMiddleware ---
+
+type OutputFormatMiddleware struct {
+	Schema       map[string]interface{}
+	Instructions string
+	schemaLoader gojsonschema.JSONLoader
+}
+
+const ParsedOutputContextKey = "parsedOutput"
+const OutputValidContextKey = "outputValid"
+const OutputErrorsContextKey = "outputErrors"
+
+// NewOutputFormatMiddleware creates a middleware that asks for JSON output
+// matching a JSON schema and validates the response against it.
+func NewOutputFormatMiddleware(schema map[string]interface{}, instructions string) (*OutputFormatMiddleware, error) {
+	loader := gojsonschema.NewGoLoader(schema)
+	if _, err := gojsonschema.NewSchema(loader); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	if instructions == "" {
+		instructions = "Respond only with a JSON document matching this JSON schema, without any other text:"
+	}
+	log.Debug().Str("middlewareId", "output-format").Msg("Creating OutputFormatMiddleware")
+	return &OutputFormatMiddleware{Schema: schema, Instructions: instructions, schemaLoader: loader}, nil
+}
+
+func newOutputFormatMiddlewareFromParams(params Params) (Middleware, error) {
+	instructions, err := params.String("instructions", "")
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := decodeParam(params, "schema", &schema); err != nil {
+		return nil, err
+	}
+	schemaFile, err := params.String("schema_file", "")
+	if err != nil {
+		return nil, err
+	}
+	if schemaFile != "" {
+		if schema != nil {
+			return nil, fmt.Errorf("set either schema or schema_file")
+		}
+		data, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %w", err)
+		}
+		// YAML is a superset of JSON, so this reads both
+		if err := yaml.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema file %s: %w", schemaFile, err)
+		}
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("no schema given, set schema or schema_file")
+	}
+	return NewOutputFormatMiddleware(schema, instructions)
+}
+
+var _ Middleware = &OutputFormatMiddleware{}
+
+func (m *OutputFormatMiddleware) ID() string   { return "output-format" }
+func (m *OutputFormatMiddleware) Name() string { return "Output Format" }
+func (m *OutputFormatMiddleware) Description() string {
+	return "Requests JSON output matching a schema, and extracts and validates the JSON in the response."
+}
+
+func (m *OutputFormatMiddleware) Prompt(ctx Context, fragments []PromptFragment) (Context, []PromptFragment) {
+	schemaJSON, err := json.MarshalIndent(m.Schema, "", "  ")
+	if err != nil {
+		log.Error().Err(err).Str("middlewareId", m.ID()).Msg("Failed to marshal schema")
+		return ctx, fragments
+	}
+	formatFragment := PromptFragment{
+		Content: m.Instructions + "\n```json\n" + string(schemaJSON) + "\n```",
+		Metadata: PromptFragmentMetadata{
+			ID:       m.ID(),
+			Type:     "instruction",
+			Position: "end",
+			Priority: 90,
+		},
+	}
+	log.Trace().Str("middlewareId", m.ID()).Interface("fragmentAdded", formatFragment).Msg("Appending output format fragment")
+	return ctx, append(fragments, formatFragment)
+}
+
+var jsonFenceRegex = regexp.MustCompile("(?s)```(?:json)?\\s*\n(.*?)```")
+
+// extractJSON returns the JSON document of a response: the content of a
+// fenced code block, or the text from the first { or [ to the last } or ].
+func extractJSON(response string) string {
+	if match := jsonFenceRegex.FindStringSubmatch(response); len(match) > 1 {
+		return strings.TrimSpace(match[1])
+	}
+	start := strings.IndexAny(response, "{[")
+	end := strings.LastIndexAny(response, "}]")
+	if start < 0 || end < start {
+		return strings.TrimSpace(response)
+	}
+	return response[start : end+1]
+}
+
+func (m *OutputFormatMiddleware) Parse(ctx Context, response string) (Context, string) {
+	log.Debug().Str("middlewareId", m.ID()).Msg("Validating response against output schema")
+	newCtx := CloneContext(ctx)
+
+	extracted := extractJSON(response)
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(extracted), &parsed); err != nil {
+		log.Debug().Err(err).Str("middlewareId", m.ID()).Msg("Response is not valid JSON")
+		newCtx.Set(OutputValidContextKey, false)
+		newCtx.Set(OutputErrorsContextKey, []string{"invalid JSON: " + err.Error()})
+		return newCtx, response
+	}
+
+	result, err := gojsonschema.Validate(m.schemaLoader, gojsonschema.NewGoLoader(parsed))
+	if err != nil {
+		log.Error().Err(err).Str("middlewareId", m.ID()).Msg("Failed to validate response")
+		newCtx.Set(OutputValidContextKey, false)
+		newCtx.Set(OutputErrorsContextKey, []string{err.Error()})
+		return newCtx, response
+	}
+
+	errors := []string{}
+	for _, resultError := range result.Errors() {
+		errors = append(errors, resultError.String())
+	}
+	newCtx.Set(ParsedOutputContextKey, parsed)
+	newCtx.Set(OutputValidContextKey, result.Valid())
+	newCtx.Set(OutputErrorsContextKey, errors)
+	log.Debug().Str("middlewareId", m.ID()).Bool("valid", result.Valid()).Strs("errors", errors).Msg("Validated response against output schema")
+
+	return newCtx, extracted
+}
+
+// --- ContextTruncationMiddleware ---
+
+type ContextTruncationMiddleware struct {
+	MaxTokens int
+	// KeepTypes are fragment types that are never dropped or shortened
+	KeepTypes []string
+}
+
+const DroppedFragmentsContextKey = "droppedFragments"
+const TruncatedFragmentsContextKey = "truncatedFragments"
+
+// NewContextTruncationMiddleware creates a middleware that keeps the prompt
+// within a token budget.
+func NewContextTruncationMiddleware(maxTokens int, keepTypes []string) *ContextTruncationMiddleware {
+	log.Debug().Str("middlewareId", "context-truncation").Int("maxTokens", maxTokens).Strs("keepTypes", keepTypes).Msg("Creating ContextTruncationMiddleware")
+	return &ContextTruncationMiddleware{MaxTokens: maxTokens, KeepTypes: keepTypes}
+}
+
+func newContextTruncationMiddlewareFromParams(params Params) (Middleware, error) {
+	maxTokens, err := params.Int("max_tokens", 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxTokens <= 0 {
+		return nil, fmt.Errorf("max_tokens must be positive")
+	}
+	keepTypes, err := params.Strings("keep_types", []string{"system", "query"})
+	if err != nil {
+		return nil, err
+	}
+	return NewContextTruncationMiddleware(maxTokens, keepTypes), nil
+}
+
+var _ Middleware = &ContextTruncationMiddleware{}
+
+func (m *ContextTruncationMiddleware) ID() string   { return "context-truncation" }
+func (m *ContextTruncationMiddleware) Name() string { return "Context Truncation" }
+func (m *ContextTruncationMiddleware) Description() string {
+	return "Drops or shortens the lowest priority fragments to fit the prompt into a token budget."
+}
+
+func (m *ContextTruncationMiddleware) Prompt(ctx Context, fragments []PromptFragment) (Context, []PromptFragment) {
+	total := 0
+	for _, f := range fragments {
+		total += estimateTokens(f.Content)
+	}
+	excess := total - m.MaxTokens
+	newCtx := CloneContext(ctx)
+	if excess <= 0 {
+		log.Debug().Str("middlewareId", m.ID()).Int("tokens", total).Int("maxTokens", m.MaxTokens).Msg("Prompt within token budget")
+		return newCtx, fragments
+	}
+
+	keep := map[string]bool{}
+	for _, t := range m.KeepTypes {
+		keep[t] = true
+	}
+
+	// Lowest priority first, and among equal priorities the latest added
+	candidates := []int{}
+	for i, f := range fragments {
+		if !keep[f.Metadata.Type] {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		pa, pb := fragments[candidates[a]].Metadata.Priority, fragments[candidates[b]].Metadata.Priority
+		if pa != pb {
+			return pa < pb
+		}
+		return candidates[a] > candidates[b]
+	})
+
+	result := make([]PromptFragment, len(fragments))
+	copy(result, fragments)
+	dropped := map[int]bool{}
+	droppedIDs := []string{}
+	truncatedIDs := []string{}
+	for _, i := range candidates {
+		if excess <= 0 {
+			break
+		}
+		tokens := estimateTokens(result[i].Content)
+		if tokens <= excess {
+			dropped[i] = true
+			droppedIDs = append(droppedIDs, fragmentName(result[i]))
+			excess -= tokens
+			continue
+		}
+		result[i].Content = truncateToTokens(result[i].Content, tokens-excess)
+		truncatedIDs = append(truncatedIDs, fragmentName(result[i]))
+		excess = 0
+	}
+
+	kept := make([]PromptFragment, 0, len(result)-len(dropped))
+	for i, f := range result {
+		if !dropped[i] {
+			kept = append(kept, f)
+		}
+	}
+
+	if excess > 0 {
+		log.Warn().Str("middlewareId", m.ID()).Int("excessTokens", excess).Msg("Prompt still exceeds token budget, only kept fragments are left")
+	}
+	newCtx.Set(DroppedFragmentsContextKey, droppedIDs)
+	newCtx.Set(TruncatedFragmentsContextKey, truncatedIDs)
+	log.Debug().Str("middlewareId", m.ID()).Int("tokens", total).Int("maxTokens", m.MaxTokens).Strs("dropped", droppedIDs).Strs("truncated", truncatedIDs).Msg("Truncated prompt to token budget")
+	return newCtx, kept
+}
+
+func (m *ContextTruncationMiddleware) Parse(ctx Context, response string) (Context, string) {
+	// This middleware doesn't modify the response
+	return ctx, response
+}
+
+// fragmentName identifies a fragment in truncation reports.
+func fragmentName(f PromptFragment) string {
+	if f.Metadata.ID != "" {
+		return f.Metadata.ID
+	}
+	return f.Metadata.Type
+}
+
+// truncateToTokens shortens text to about tokens tokens, on a rune boundary.
+func truncateToTokens(text string, tokens int) string {
+	const marker = " [...]"
+	maxLen := tokens*4 - len(marker)
+	if maxLen <= 0 {
+		return ""
+	}
+	if len(text) <= maxLen {
+		return text
+	}
+	for maxLen > 0 && !utf8.RuneStart(text[maxLen]) {
+		maxLen--
+	}
+	return text[:maxLen] + marker
+}
+
+// decodeParam converts a structured parameter into out by round-tripping it
+// through YAML. Missing parameters leave out untouched.
+func decodeParam(params Params, key string, out interface{}) error {
+	v, ok := params[key]
+	if !ok || v == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("parameter %s: %w", key, err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parameter %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLibraryMiddlewareErrors(t *testing.T) {
+	examplesFile := writeFile(t, "examples.yaml", "- input: a\n  output: b\n")
+	schemaFile := writeFile(t, "schema.json", `{"type": "object"}`)
+	brokenFile := writeFile(t, "broken.yaml", "- input: [\n")
+	missingFile := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		id      string
+		params  Params
+		wantErr string
+	}{
+		{"few-shot", Params{}, "no examples given"},
+		{"few-shot", Params{"examples": "not a list"}, "parameter examples"},
+		{"few-shot", Params{"examples_file": missingFile}, "failed to read examples file"},
+		{"few-shot", Params{"examples_file": brokenFile}, "failed to parse examples file"},
+		{"few-shot", Params{"header": []interface{}{}}, "parameter header must be a string"},
+		{"output-format", Params{}, "no schema given"},
+		{"output-format", Params{"schema": map[string]interface{}{"type": "object"}, "schema_file": schemaFile}, "set either schema or schema_file"},
+		{"output-format", Params{"schema_file": missingFile}, "failed to read schema file"},
+		{"output-format", Params{"schema_file": brokenFile}, "failed to parse schema file"},
+		{"output-format", Params{"schema": map[string]interface{}{"type": "no-such-type"}}, "invalid JSON schema"},
+		{"context-truncation", Params{}, "max_tokens must be positive"},
+		{"context-truncation", Params{"max_tokens": "100"}, "parameter max_tokens must be an integer"},
+		{"context-truncation", Params{"max_tokens": 100, "keep_types": "system"}, "parameter keep_types must be a list"},
+	}
+	registry := DefaultRegistry()
+	for _, tt := range tests {
+		t.Run(tt.id+" "+tt.wantErr, func(t *testing.T) {
+			_, err := registry.Create(tt.id, tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Examples from the file are added to the inline ones
+	mw, err := registry.Create("few-shot", Params{"examples": []interface{}{map[string]interface{}{"input": "x", "output": "y"}}, "examples_file": examplesFile})
+	if err != nil {
+		t.Fatalf("Create(few-shot) failed: %v", err)
+	}
+	want := []FewShotExample{{Input: "x", Output: "y"}, {Input: "a", Output: "b"}}
+	if examples := mw.(*FewShotMiddleware).Examples; !reflect.DeepEqual(examples, want) {
+		t.Errorf("examples = %+v, want %+v", examples, want)
+	}
+	if _, err := registry.Create("output-format", Params{"schema_file": schemaFile}); err != nil {
+		t.Errorf("Create(output-format) with a schema file failed: %v", err)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"Here:\n```\n[1, 2]\n```\nDone {not this}", `[1, 2]`},
+		{`The answer is {"a": {"b": 2}} as requested.`, `{"a": {"b": 2}}`},
+		{"  no json  ", "no json"},
+		{"} backwards {", "} backwards {"},
+	}
+	for _, tt := range tests {
+		if got := extractJSON(tt.response); got != tt.want {
+			t.Errorf("extractJSON(%q) = %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestContextTruncation(t *testing.T) {
+	fragment := func(id, fragmentType string, priority int, content string) PromptFragment {
+		return PromptFragment{Content: content, Metadata: PromptFragmentMetadata{ID: id, Type: fragmentType, Priority: priority}}
+	}
+	fragments := []PromptFragment{
+		fragment("system", "system", 100, strings.Repeat("s", 40)),
+		fragment("examples", "examples", 70, strings.Repeat("e", 80)),
+		fragment("notes", "notes", 10, strings.Repeat("n", 40)),
+		fragment("query", "query", 50, strings.Repeat("q", 40)),
+	}
+
+	// 50 tokens over a budget of 30: notes (10) is dropped, examples (20)
+	// shortened to 10, system and query are kept
+	ctx, kept := NewContextTruncationMiddleware(30, []string{"system", "query"}).Prompt(mapToContext(nil), fragments)
+	var ids []string
+	for _, f := range kept {
+		ids = append(ids, f.Metadata.ID)
+	}
+	if want := []string{"system", "examples", "query"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("kept fragments = %v, want %v", ids, want)
+	}
+	if content := kept[1].Content; content != strings.Repeat("e", 34)+" [...]" {
+		t.Errorf("truncated examples = %q", content)
+	}
+	if dropped, _ := ctx.Get(DroppedFragmentsContextKey); !reflect.DeepEqual(dropped, []string{"notes"}) {
+		t.Errorf("dropped = %v", dropped)
+	}
+	if truncated, _ := ctx.Get(TruncatedFragmentsContextKey); !reflect.DeepEqual(truncated, []string{"examples"}) {
+		t.Errorf("truncated = %v", truncated)
+	}
+
+	// Within budget nothing changes
+	if _, kept := NewContextTruncationMiddleware(100, nil).Prompt(mapToContext(nil), fragments); len(kept) != len(fragments) {
+		t.Errorf("kept %d of %d fragments within budget", len(kept), len(fragments))
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Params are the parameters a middleware is configured with in a pipeline file.
+type Params map[string]interface{}
+
+// String returns a string parameter, or def if it is not set.
+func (p Params) String(key string, def string) (string, error) {
+	v, ok := p[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter %s must be a string, got %T", key, v)
+	}
+	return s, nil
+}
+
+// Int returns an integer parameter, or def if it is not set.
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("parameter %s must be an integer, got %v", key, n)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("parameter %s must be an integer, got %T", key, v)
+	}
+}
+
+// Strings returns a list of strings parameter, or def if it is not set.
+func (p Params) Strings(key string, def []string) ([]string, error) {
+	v, ok := p[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parameter %s must be a list, got %T", key, v)
+	}
+	result := make([]string, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter %s[%d] must be a string, got %T", key, i, item)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Factory creates a middleware from its pipeline file parameters.
+type Factory func(params Params) (Middleware, error)
+
+// Registry maps middleware IDs to the factories creating them.
+type Registry struct {
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]Factory{}}
+}
+
+// DefaultRegistry creates a registry with all built-in middlewares.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("system-instruction", func(params Params) (Middleware, error) {
+		instructions, err := params.String("instructions", "")
+		if err != nil {
+			return nil, err
+		}
+		return NewSystemInstructionMiddleware(instructions), nil
+	})
+	r.Register("thinking-mode", func(params Params) (Middleware, error) {
+		return NewThinkingModeMiddleware(), nil
+	})
+	r.Register("token-counter", func(params Params) (Middleware, error) {
+		return NewTokenCounterMiddleware(), nil
+	})
+	r.Register("few-shot", newFewShotMiddlewareFromParams)
+	r.Register("output-format", newOutputFormatMiddlewareFromParams)
+	r.Register("context-truncation", newContextTruncationMiddlewareFromParams)
+	return r
+}
+
+// Register adds a factory for a middleware ID, replacing any previous one.
+func (r *Registry) Register(id string, factory Factory) {
+	log.Debug().Str("middlewareId", id).Msg("Registering middleware factory")
+	r.factories[id] = factory
+}
+
+// Create instantiates the middleware registered under id.
+func (r *Registry) Create(id string, params Params) (Middleware, error) {
+	factory, ok := r.factories[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown middleware %q", id)
+	}
+	if params == nil {
+		params = Params{}
+	}
+	mw, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("middleware %s: %w", id, err)
+	}
+	return mw, nil
+}
+
+// IDs returns the registered middleware IDs, sorted.
+func (r *Registry) IDs() []string {
+	ids := make([]string, 0, len(r.factories))
+	for id := range r.factories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Recording is a recorded LLM request/response pair. Replaying it runs the
+// response through the parse phase of a pipeline and checks the expectations,
+// which makes recordings regression tests for parse middlewares.
+type Recording struct {
+	Name string `yaml:"name"`
+	// Prompt is the final prompt the response was recorded for, kept for reference
+	Prompt string `yaml:"prompt,omitempty"`
+	// Context is the context the parse phase starts with
+	Context  map[string]interface{} `yaml:"context,omitempty"`
+	Response string                 `yaml:"response"`
+	Expected Expectation            `yaml:"expected"`
+}
+
+// Expectation is what the parse phase must produce for a recording.
+type Expectation struct {
+	// Response is the processed response, not checked if nil
+	Response *string `yaml:"response,omitempty"`
+	// Context holds the keys the final context must contain, other keys are ignored
+	Context map[string]interface{} `yaml:"context,omitempty"`
+}
+
+// ReplayResult is the outcome of replaying one recording.
+type ReplayResult struct {
+	Name     string
+	Passed   bool
+	Failures []string
+	Response string
+	Context  Context
+}
+
+// NewRecording records a pipeline run. The expectations are the processed
+// response and the context keys the parse phase added or changed.
+func NewRecording(name string, parseContext Context, prompt, response, processedResponse string, finalContext Context) Recording {
+	startMap := contextToMap(parseContext)
+	finalMap := contextToMap(finalContext)
+
+	expectedContext := map[string]interface{}{}
+	for key, value := range finalMap {
+		if before, ok := startMap[key]; !ok || !reflect.DeepEqual(before, value) {
+			expectedContext[key] = value
+		}
+	}
+
+	return Recording{
+		Name:     name,
+		Prompt:   prompt,
+		Context:  startMap,
+		Response: response,
+		Expected: Expectation{
+			Response: &processedResponse,
+			Context:  expectedContext,
+		},
+	}
+}
+
+// LoadRecordings reads recordings from a YAML file holding a list of them, or
+// from all .yaml and .yml files of a directory.
+func LoadRecordings(path string) ([]Recording, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadRecordingsFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var recordings []Recording
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		fileRecordings, err := loadRecordingsFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, fileRecordings...)
+	}
+	return recordings, nil
+}
+
+func loadRecordingsFile(path string) ([]Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recordings %s: %w", path, err)
+	}
+	var recordings []Recording
+	if err := yaml.Unmarshal(data, &recordings); err != nil {
+		return nil, fmt.Errorf("failed to parse recordings %s: %w", path, err)
+	}
+	for i := range recordings {
+		if recordings[i].Name == "" {
+			recordings[i].Name = fmt.Sprintf("%s#%d", filepath.Base(path), i+1)
+		}
+	}
+	log.Debug().Str("path", path).Int("recordingCount", len(recordings)).Msg("Loaded recordings")
+	return recordings, nil
+}
+
+// AppendRecording adds a recording to a recordings file, creating it if needed.
+func AppendRecording(path string, recording Recording) error {
+	var recordings []Recording
+	if _, err := os.Stat(path); err == nil {
+		recordings, err = loadRecordingsFile(path)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	recordings = append(recordings, recording)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(recordings); err != nil {
+		return fmt.Errorf("failed to marshal recordings: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write recordings %s: %w", path, err)
+	}
+	log.Info().Str("path", path).Str("name", recording.Name).Int("recordingCount", len(recordings)).Msg("Saved recording")
+	return nil
+}
+
+// Replay runs each recording's response through the parse phase of the
+// pipeline and checks the results against the recording's expectations.
+func Replay(pipeline *MiddlewarePipeline, recordings []Recording) []ReplayResult {
+	results := make([]ReplayResult, 0, len(recordings))
+	for _, recording := range recordings {
+		log.Debug().Str("recording", recording.Name).Msg("Replaying recording")
+		finalContext, response := pipeline.ExecuteParsePhase(mapToContext(recording.Context), recording.Response)
+
+		result := ReplayResult{Name: recording.Name, Response: response, Context: finalContext}
+		if expected := recording.Expected.Response; expected != nil && *expected != response {
+			result.Failures = append(result.Failures, fmt.Sprintf("response: expected %q, got %q", *expected, response))
+		}
+
+		finalMap := contextToMap(finalContext)
+		for _, key := range sortedKeys(recording.Expected.Context) {
+			expected := normalizeValue(recording.Expected.Context[key])
+			actual, ok := finalMap[key]
+			if !ok {
+				result.Failures = append(result.Failures, fmt.Sprintf("context %s: missing, expected %s", key, formatValue(expected)))
+				continue
+			}
+			if !reflect.DeepEqual(expected, actual) {
+				result.Failures = append(result.Failures, fmt.Sprintf("context %s: expected %s, got %s", key, formatValue(expected), formatValue(actual)))
+			}
+		}
+
+		result.Passed = len(result.Failures) == 0
+		log.Debug().Str("recording", recording.Name).Bool("passed", result.Passed).Strs("failures", result.Failures).Msg("Replayed recording")
+		results = append(results, result)
+	}
+	return results
+}
+
+// contextToMap converts a context to plain values, as they are stored in
+// recordings.
+func contextToMap(ctx Context) map[string]interface{} {
+	result := map[string]interface{}{}
+	if ctx == nil {
+		return result
+	}
+	for pair := ctx.Oldest(); pair != nil; pair = pair.Next() {
+		result[pair.Key] = normalizeValue(pair.Value)
+	}
+	return result
+}
+
+// mapToContext converts recorded values to a context, with sorted keys.
+func mapToContext(values map[string]interface{}) Context {
+	ctx := orderedmap.New[string, interface{}]()
+	for _, key := range sortedKeys(values) {
+		ctx.Set(key, values[key])
+	}
+	return ctx
+}
+
+// normalizeValue round-trips a value through JSON so that values recorded in
+// YAML and values set by middlewares compare equal, e.g. []string and
+// []interface{}, or int and float64.
+func normalizeValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return normalized
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatReplayResults renders replay results as a test report.
+func FormatReplayResults(results []ReplayResult) string {
+	var builder strings.Builder
+	passed := 0
+	for _, result := range results {
+		if result.Passed {
+			passed++
+			fmt.Fprintf(&builder, "PASS %s\n", result.Name)
+			continue
+		}
+		fmt.Fprintf(&builder, "FAIL %s\n", result.Name)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(&builder, "     %s\n", failure)
+		}
+	}
+	fmt.Fprintf(&builder, "\n%d/%d recordings passed\n", passed, len(results))
+	return builder.String()
+}
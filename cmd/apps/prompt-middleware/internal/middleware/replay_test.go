@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func jsonAnswersPipeline(t *testing.T) *MiddlewarePipeline {
+	t.Helper()
+	config, err := LoadPipelineConfig("../../pipelines/json-answers.yaml")
+	if err != nil {
+		t.Fatalf("LoadPipelineConfig failed: %v", err)
+	}
+	pipeline, err := DefaultRegistry().BuildPipeline(config)
+	if err != nil {
+		t.Fatalf("BuildPipeline failed: %v", err)
+	}
+	return pipeline
+}
+
+func TestReplayRecordings(t *testing.T) {
+	recordings, err := LoadRecordings("testdata/recordings")
+	if err != nil {
+		t.Fatalf("LoadRecordings failed: %v", err)
+	}
+	if len(recordings) != 4 {
+		t.Fatalf("loaded %d recordings, want 4", len(recordings))
+	}
+
+	results := Replay(jsonAnswersPipeline(t), recordings)
+	for _, result := range results {
+		if !result.Passed {
+			t.Errorf("recording %s failed: %v", result.Name, result.Failures)
+		}
+	}
+
+	channel := results[0]
+	if channel.Name != "channel" || channel.Response != `{"answer": "A typed conduit between goroutines.", "confidence": 0.8}` {
+		t.Errorf("channel: name %q, response %q", channel.Name, channel.Response)
+	}
+	parsed, _ := channel.Context.Get(ParsedOutputContextKey)
+	want := map[string]interface{}{"answer": "A typed conduit between goroutines.", "confidence": 0.8}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("parsed output = %v, want %v", parsed, want)
+	}
+
+	if report := FormatReplayResults(results); !strings.HasSuffix(report, "\n4/4 recordings passed\n") {
+		t.Errorf("report = %q", report)
+	}
+}
+
+func TestReplayFailures(t *testing.T) {
+	recordings, err := LoadRecordings("testdata/recordings/json-answers.yaml")
+	if err != nil {
+		t.Fatalf("LoadRecordings failed: %v", err)
+	}
+	recording := recordings[0]
+	wrongResponse := "{}"
+	recording.Expected.Response = &wrongResponse
+	recording.Expected.Context = map[string]interface{}{
+		OutputValidContextKey: false,
+		"missing":             "value",
+		// Recorded as YAML, compared after normalizing
+		ResponseTokensContextKey: 33,
+	}
+
+	results := Replay(jsonAnswersPipeline(t), []Recording{recording})
+	want := []string{
+		`response: expected "{}", got "{\"answer\": \"A typed conduit between goroutines.\", \"confidence\": 0.8}"`,
+		`context missing: missing, expected "value"`,
+		`context outputValid: expected false, got true`,
+	}
+	if results[0].Passed || !reflect.DeepEqual(results[0].Failures, want) {
+		t.Errorf("failures:\n got %q\nwant %q", results[0].Failures, want)
+	}
+
+	report := FormatReplayResults(results)
+	if !strings.HasPrefix(report, "FAIL channel\n     response: expected") || !strings.HasSuffix(report, "\n0/1 recordings passed\n") {
+		t.Errorf("report = %q", report)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	pipeline := jsonAnswersPipeline(t)
+	ctx := mapToContext(map[string]interface{}{ThinkingModeContextKey: true})
+	query := PromptFragment{Content: "What is a map?", Metadata: PromptFragmentMetadata{ID: "user-query", Type: "query", Position: "middle", Priority: 50}}
+	promptContext, _, prompt := pipeline.ExecutePromptPhase(ctx, []PromptFragment{query})
+	response := "<thinking>hash table</thinking>\n{\"answer\": \"A hash table.\", \"confidence\": 0.7}"
+	finalContext, processed := pipeline.ExecuteParsePhase(promptContext, response)
+
+	recording := NewRecording("map", promptContext, prompt, response, processed, finalContext)
+	if _, ok := recording.Expected.Context[PromptTokensContextKey]; ok {
+		t.Error("keys set before the parse phase should not be expected")
+	}
+	if recording.Expected.Context[ExtractedThinkingContextKey] != "hash table" {
+		t.Errorf("expected context = %v", recording.Expected.Context)
+	}
+
+	// Recordings are appended to the file, and replay from it
+	path := filepath.Join(t.TempDir(), "recordings.yaml")
+	for i := 0; i < 2; i++ {
+		if err := AppendRecording(path, recording); err != nil {
+			t.Fatalf("AppendRecording failed: %v", err)
+		}
+	}
+	recordings, err := LoadRecordings(path)
+	if err != nil {
+		t.Fatalf("LoadRecordings failed: %v", err)
+	}
+	if len(recordings) != 2 {
+		t.Fatalf("loaded %d recordings, want 2", len(recordings))
+	}
+	for _, result := range Replay(pipeline, recordings) {
+		if !result.Passed {
+			t.Errorf("recording %s failed: %v", result.Name, result.Failures)
+		}
+	}
+}
+
+func TestLoadRecordingsErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if _, err := LoadRecordings(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if _, err := LoadRecordings(write("map.yaml", "name: not a list\n")); err == nil || !strings.Contains(err.Error(), "failed to parse recordings") {
+		t.Errorf("expected a parse error for a file that isn't a list, got %v", err)
+	}
+	if err := AppendRecording(filepath.Join(dir, "map.yaml"), Recording{Name: "x"}); err == nil {
+		t.Error("expected AppendRecording to refuse a file it can't parse")
+	}
+
+	// Directories are read file by file, unnamed recordings get a name
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "a.yml"), []byte("- response: a\n- name: b\n  response: b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "notes.txt"), []byte("not a recording"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recordings, err := LoadRecordings(sub)
+	if err != nil {
+		t.Fatalf("LoadRecordings failed: %v", err)
+	}
+	if len(recordings) != 2 || recordings[0].Name != "a.yml#1" || recordings[1].Name != "b" {
+		t.Errorf("recordings = %+v", recordings)
+	}
+
+	// A broken file fails the whole directory
+	if _, err := LoadRecordings(dir); err == nil {
+		t.Error("expected an error for a directory with a broken file")
+	}
+}
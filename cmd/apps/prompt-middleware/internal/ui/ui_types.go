@@ -17,6 +17,9 @@ type PageData struct {
 	Middlewares       []MiddlewareData // List of middlewares for configuration panel
 	InitialContext    middleware.Context
 	UserQuery         string
+	PipelineName      string // Name of the pipeline definition the middlewares come from
+	RecordingsPath    string // File recordings are appended to
+	ResponseOverride  string // Pasted LLM response used instead of the mock
 	FinalPrompt       string
 	LLMResponse       string // Raw LLM response (before parsing)
 	ProcessedResponse string // Response after middleware parsing
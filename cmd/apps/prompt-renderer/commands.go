@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// setupHeadlessLogging keeps stderr quiet for scripts unless --log-level is given
+func setupHeadlessLogging(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("log-level") {
+		logLevel = "warn"
+	}
+	setupLogging()
+}
+
+func newRenderCommand() *cobra.Command {
+	var (
+		selectionPath string
+		varsPath      string
+		vars          []string
+		variants      []string
+		enabled       []string
+		outputPath    string
+		copyPrompt    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render TEMPLATE_ID",
+		Short: "Render a prompt without the TUI",
+		Long: `Render a template to stdout, for use from scripts.
+
+Selections start from the template defaults: the first variant of each
+section with all its bullets, toggles off. A selection file saved by the TUI
+(e.g. ~/.local/share/prompt-builder/last.yml) and the flags override them.
+Variable values are validated against their types, invalid values fail the
+command.`,
+		Example: `  prompt-renderer render code-review --var language=go --var code_snippet=@main.go
+  prompt-renderer render code-review --vars vars.yml --variant introduction=detailed
+  prompt-renderer render with-context --selection last.yml --enable context_request --copy`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			setupHeadlessLogging(cmd)
+
+			dslFile, err := loadDSLFile()
+			if err != nil {
+				return err
+			}
+
+			templateDef, err := findTemplate(dslFile, args[0])
+			if err != nil {
+				return err
+			}
+
+			selection := CreateDefaultSelection(templateDef)
+			if selectionPath != "" {
+				saved, err := ReadSelectionFile(selectionPath)
+				if err != nil {
+					return err
+				}
+				if saved.TemplateID != "" && saved.TemplateID != templateDef.ID {
+					return fmt.Errorf("selection file %s is for template '%s', not '%s'", selectionPath, saved.TemplateID, templateDef.ID)
+				}
+				for name, value := range saved.Variables {
+					selection.Variables[name] = value
+				}
+				for sectionID, sectionSelection := range saved.Sections {
+					selection.Sections[sectionID] = sectionSelection
+				}
+			}
+
+			if varsPath != "" {
+				fileVars, err := readVariablesFile(varsPath)
+				if err != nil {
+					return err
+				}
+				for name, value := range fileVars {
+					selection.Variables[name] = value
+				}
+			}
+
+			flagVars, err := parseVarFlags(vars)
+			if err != nil {
+				return err
+			}
+			for name, value := range flagVars {
+				selection.Variables[name] = value
+			}
+
+			for _, v := range variants {
+				sectionID, variantID, ok := strings.Cut(v, "=")
+				if !ok {
+					return fmt.Errorf("invalid --variant %q, expected section=variant", v)
+				}
+				if err := selectVariant(templateDef, selection, sectionID, variantID); err != nil {
+					return err
+				}
+			}
+
+			for _, sectionID := range enabled {
+				sectionSelection, ok := selection.Sections[sectionID]
+				if !ok {
+					return fmt.Errorf("section '%s' is not defined by template '%s'", sectionID, templateDef.ID)
+				}
+				sectionSelection.VariantEnabled = true
+				selection.Sections[sectionID] = sectionSelection
+			}
+
+			if errs := ValidateSelection(templateDef, selection); len(errs) > 0 {
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				}
+				return fmt.Errorf("invalid selection for template '%s'", templateDef.ID)
+			}
+
+			prompt, err := NewPromptRenderer(dslFile).RenderPrompt(templateDef, selection)
+			if err != nil {
+				return errors.Wrap(err, "failed to render prompt")
+			}
+
+			if copyPrompt {
+				if err := NewClipboardManager().CopyToClipboard(prompt); err != nil {
+					return err
+				}
+			}
+			if outputPath != "" {
+				return errors.Wrapf(os.WriteFile(outputPath, []byte(prompt+"\n"), 0644), "failed to write %s", outputPath)
+			}
+			if !copyPrompt {
+				fmt.Println(prompt)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&selectionPath, "selection", "s", "", "Selection file saved by the TUI")
+	cmd.Flags().StringVar(&varsPath, "vars", "", "YAML file of variable values")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Variable value as name=value, @filename reads a file (repeatable)")
+	cmd.Flags().StringArrayVar(&variants, "variant", nil, "Variant of a section as section=variant (repeatable)")
+	cmd.Flags().StringArrayVar(&enabled, "enable", nil, "Enable the toggle of a section (repeatable)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the prompt to a file instead of stdout")
+	cmd.Flags().BoolVar(&copyPrompt, "copy", false, "Copy the prompt to the clipboard instead of printing it")
+
+	return cmd
+}
+
+func newLintCommand() *cobra.Command {
+	var (
+		strict        bool
+		templateID    string
+		selectionPath string
+		varsPath      string
+		vars          []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check every template of the DSL file",
+		Long: `Check every template of the DSL file for structural errors, unknown
+variables in template content, broken @filename references in content and
+variable defaults, empty file globs in defaults, and duplicate IDs. Unused
+variables are warnings.
+
+The values render would be given can be checked too: those of a selection
+file against its template, and those of --vars and --var against every
+template defining them, or only --template. Their types are validated and
+their @filename references and file globs must resolve.
+
+Exits non-zero if errors are found, or warnings with --strict.`,
+		Example: `  prompt-renderer lint --dsl templates.yml
+  prompt-renderer lint --template code-review --vars vars.yml --var code_snippet=@main.go
+  prompt-renderer lint --selection ~/.local/share/prompt-builder/last.yml --strict`,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			setupHeadlessLogging(cmd)
+
+			path := dslFilePath
+			if path == "" {
+				var err error
+				if path, err = FindDefaultDSLFile(); err != nil {
+					return err
+				}
+			}
+
+			dslFile, err := ReadDSLFile(path)
+			if err != nil {
+				return err
+			}
+
+			errorCount, warningCount := 0, 0
+			report := func(source string, issues []LintIssue) {
+				for _, issue := range issues {
+					fmt.Printf("%s: %s\n", source, issue)
+					if issue.Severity == "error" {
+						errorCount++
+					} else {
+						warningCount++
+					}
+				}
+			}
+			report(path, LintDSLFile(dslFile))
+
+			if selectionPath != "" {
+				saved, err := ReadSelectionFile(selectionPath)
+				if err != nil {
+					return err
+				}
+				report(selectionPath, LintValues(dslFile, saved.TemplateID, saved.Variables))
+			}
+			if varsPath != "" {
+				fileVars, err := readVariablesFile(varsPath)
+				if err != nil {
+					return err
+				}
+				report(varsPath, LintValues(dslFile, templateID, fileVars))
+			}
+			if len(vars) > 0 {
+				flagVars, err := parseVarFlags(vars)
+				if err != nil {
+					return err
+				}
+				report("--var", LintValues(dslFile, templateID, flagVars))
+			}
+
+			if errorCount > 0 || (strict && warningCount > 0) {
+				return fmt.Errorf("%s: %d errors, %d warnings", path, errorCount, warningCount)
+			}
+			fmt.Printf("%s: %d templates OK", path, len(dslFile.Templates))
+			if warningCount > 0 {
+				fmt.Printf(", %d warnings", warningCount)
+			}
+			fmt.Println()
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "Treat warnings as errors")
+	cmd.Flags().StringVarP(&templateID, "template", "t", "", "Check --vars and --var values against this template only")
+	cmd.Flags().StringVarP(&selectionPath, "selection", "s", "", "Selection file saved by the TUI whose values are checked")
+	cmd.Flags().StringVar(&varsPath, "vars", "", "YAML file of variable values to check")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Variable value to check as name=value (repeatable)")
+
+	return cmd
+}
+
+// findTemplate returns the template with the given ID
+func findTemplate(dslFile *DSLFile, id string) (*TemplateDefinition, error) {
+	ids := make([]string, 0, len(dslFile.Templates))
+	for i := range dslFile.Templates {
+		if dslFile.Templates[i].ID == id {
+			return &dslFile.Templates[i], nil
+		}
+		ids = append(ids, dslFile.Templates[i].ID)
+	}
+	sort.Strings(ids)
+	return nil, fmt.Errorf("template '%s' not found (available: %s)", id, strings.Join(ids, ", "))
+}
+
+// selectVariant selects a section's variant, with all its bullets like the
+// default selection
+func selectVariant(templateDef *TemplateDefinition, selection *SelectionState, sectionID, variantID string) error {
+	section := findSection(templateDef, sectionID)
+	if section == nil {
+		return fmt.Errorf("section '%s' is not defined by template '%s'", sectionID, templateDef.ID)
+	}
+	variant := findVariant(section, variantID)
+	if variant == nil {
+		return fmt.Errorf("section '%s' has no variant '%s'", sectionID, variantID)
+	}
+
+	sectionSelection := selection.Sections[sectionID]
+	if sectionSelection.Variant == variantID && sectionSelection.SelectedBullets != nil {
+		return nil
+	}
+	sectionSelection.Variant = variantID
+	sectionSelection.SelectedBullets = make(map[string]bool)
+	for i := range variant.Bullets {
+		sectionSelection.SelectedBullets[fmt.Sprintf("%s_%d", variantID, i)] = true
+	}
+	selection.Sections[sectionID] = sectionSelection
+	return nil
+}
+
+// parseVarFlags parses --var name=value flags
+func parseVarFlags(vars []string) (map[string]string, error) {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --var %q, expected name=value", v)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// readVariablesFile reads a YAML map of variable values. Booleans and numbers
+// are accepted and converted to their text form.
+func readVariablesFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read variables file: %s", path)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse variables file: %s", path)
+	}
+
+	vars := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("variable '%s' in %s must be a scalar", name, path)
+		case nil:
+			vars[name] = ""
+		default:
+			vars[name] = fmt.Sprint(value)
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// LintIssue is a problem found in a DSL file
+type LintIssue struct {
+	Severity string // "error" or "warning"
+	Template string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	if i.Template == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: template '%s': %s", i.Severity, i.Template, i.Message)
+}
+
+// LintDSLFile checks a DSL file like ParseDSLFile does, but reports every
+// problem instead of stopping at the first one. It also type-checks the
+// templates: variables used in content must be defined, and files referenced
+// by variable defaults and by @filename in content must exist.
+func LintDSLFile(dsl *DSLFile) []LintIssue {
+	var issues []LintIssue
+
+	if dsl.Version != 1 {
+		issues = append(issues, LintIssue{Severity: "error", Message: fmt.Sprintf("unsupported DSL version: %d (supported: 1)", dsl.Version)})
+	}
+	if len(dsl.Templates) == 0 {
+		issues = append(issues, LintIssue{Severity: "error", Message: "at least one template is required"})
+	}
+
+	templateIDs := make(map[string]bool)
+	for i := range dsl.Templates {
+		templateDef := &dsl.Templates[i]
+		if templateDef.ID != "" && templateIDs[templateDef.ID] {
+			issues = append(issues, LintIssue{Severity: "error", Template: templateDef.ID, Message: "duplicate template ID"})
+		}
+		templateIDs[templateDef.ID] = true
+
+		issues = append(issues, lintTemplate(templateDef, i)...)
+	}
+
+	return issues
+}
+
+// lintTemplate checks a single template
+func lintTemplate(templateDef *TemplateDefinition, index int) []LintIssue {
+	name := templateDef.ID
+	if name == "" {
+		name = fmt.Sprintf("#%d", index)
+	}
+	var issues []LintIssue
+	addError := func(format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: "error", Template: name, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: "warning", Template: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if templateDef.ID == "" {
+		addError("missing required ID field")
+	}
+	if templateDef.Label == "" {
+		addError("missing required label field")
+	}
+	if len(templateDef.Sections) == 0 {
+		addError("must have at least one section")
+	}
+
+	varNames := make([]string, 0, len(templateDef.Variables))
+	for varName := range templateDef.Variables {
+		varNames = append(varNames, varName)
+	}
+	sort.Strings(varNames)
+
+	for _, varName := range varNames {
+		config := templateDef.Variables[varName]
+		if err := validateVariable(templateDef.ID, varName, config); err != nil {
+			addError("%s", stripTemplatePrefix(err.Error(), templateDef.ID))
+			continue
+		}
+		if err := lintFileReference(config, config.Default); err != nil {
+			addError("variable '%s' default: %s", varName, err)
+		}
+	}
+
+	used := map[string]bool{}
+	sectionIDs := map[string]bool{}
+	for i, section := range templateDef.Sections {
+		if section.ID != "" && sectionIDs[section.ID] {
+			addError("duplicate section ID: %s", section.ID)
+		}
+		sectionIDs[section.ID] = true
+
+		if err := validateSection(&section, templateDef.ID, i); err != nil {
+			addError("%s", stripTemplatePrefix(err.Error(), templateDef.ID))
+		}
+
+		for _, variant := range section.Variants {
+			location := fmt.Sprintf("section '%s' variant '%s'", section.ID, variant.ID)
+
+			// Bullets are inserted into the content before substitution,
+			// so they are templates too
+			contents := []string{strings.ReplaceAll(variant.Content, "{{.}}", "")}
+			contents = append(contents, variant.Bullets...)
+
+			for _, content := range contents {
+				for _, filename := range fileReferences(content) {
+					if _, err := os.Stat(filename); err != nil {
+						addError("%s: broken file reference @%s", location, filename)
+					}
+				}
+
+				refs, err := templateReferences(content)
+				if err != nil {
+					addError("%s: %v", location, err)
+					continue
+				}
+				for _, ref := range refs {
+					used[ref] = true
+					if _, ok := templateDef.Variables[ref]; !ok {
+						addError("%s uses unknown variable '%s'", location, ref)
+					}
+				}
+			}
+		}
+	}
+
+	for _, varName := range varNames {
+		if !used[varName] {
+			addWarning("variable '%s' is never used", varName)
+		}
+	}
+
+	return issues
+}
+
+// LintValues checks variable values given at render time, like those of a
+// selection or variables file, against the templates defining them: their
+// types, and that @filename references and file globs resolve. Only the
+// template with templateID is checked, or all templates if it is empty.
+// Empty values fall back to the defaults, which LintDSLFile checks.
+func LintValues(dsl *DSLFile, templateID string, values map[string]string) []LintIssue {
+	var issues []LintIssue
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	found := false
+	defined := map[string]bool{}
+	for i := range dsl.Templates {
+		templateDef := &dsl.Templates[i]
+		if templateID != "" && templateDef.ID != templateID {
+			continue
+		}
+		found = true
+
+		for _, name := range names {
+			config, ok := templateDef.Variables[name]
+			if !ok {
+				continue
+			}
+			defined[name] = true
+			value := values[name]
+			if value == "" {
+				continue
+			}
+			err := config.Validate(value)
+			if err == nil {
+				err = lintFileReference(config, value)
+			}
+			if err != nil {
+				issues = append(issues, LintIssue{Severity: "error", Template: templateDef.ID, Message: fmt.Sprintf("variable '%s' value: %s", name, err)})
+			}
+		}
+	}
+
+	if templateID != "" && !found {
+		return append(issues, LintIssue{Severity: "error", Message: fmt.Sprintf("template '%s' not found", templateID)})
+	}
+	for _, name := range names {
+		if !defined[name] {
+			issues = append(issues, LintIssue{Severity: "warning", Template: templateID, Message: fmt.Sprintf("variable '%s' is not defined", name)})
+		}
+	}
+
+	return issues
+}
+
+// lintFileReference checks that the files a value of the variable refers to
+// exist
+func lintFileReference(config VariableConfig, value string) error {
+	switch {
+	case strings.HasPrefix(value, "@") && config.acceptsFileReference():
+		filename := strings.TrimPrefix(value, "@")
+		if _, err := os.Stat(filename); err != nil {
+			return fmt.Errorf("broken file reference @%s", filename)
+		}
+	case config.variableType() == VariableTypeFile && value != "":
+		matches, err := doublestar.FilepathGlob(value)
+		if err != nil {
+			return fmt.Errorf("invalid file glob %s", value)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files match %s", value)
+		}
+	}
+	return nil
+}
+
+var (
+	templateActionRegex = regexp.MustCompile(`(?s){{.*?}}`)
+	// fileReferenceRegex matches an @ starting a word followed by something
+	// that looks like a path, so that e-mail addresses and @mentions don't
+	fileReferenceRegex = regexp.MustCompile(`(?:^|\s)@([\w~./-]*[./][\w~./-]*\w)`)
+)
+
+// fileReferences returns the @filename references in the text of a
+// template, outside of its actions
+func fileReferences(content string) []string {
+	var filenames []string
+	text := templateActionRegex.ReplaceAllString(content, " ")
+	for _, match := range fileReferenceRegex.FindAllStringSubmatch(text, -1) {
+		filenames = append(filenames, match[1])
+	}
+	return filenames
+}
+
+// stripTemplatePrefix removes the "template 'id' " prefix of validation
+// errors, lint issues already name the template
+func stripTemplatePrefix(message, templateID string) string {
+	return strings.TrimPrefix(message, fmt.Sprintf("template '%s' ", templateID))
+}
+
+// templateReferences parses a Go template and returns the top-level fields it
+// reads, like language in {{ .language }}, sorted
+func templateReferences(content string) ([]string, error) {
+	tmpl, err := template.New("lint").Funcs(sprig.TxtFuncMap()).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]bool{}
+	if tmpl.Tree != nil {
+		collectReferences(tmpl.Tree.Root, true, refs)
+	}
+
+	result := make([]string, 0, len(refs))
+	for ref := range refs {
+		result = append(result, ref)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// collectReferences walks a template parse tree. Inside range and with
+// blocks dot is no longer the variables map, so only $.name counts there.
+func collectReferences(node parse.Node, dotIsRoot bool, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectReferences(child, dotIsRoot, refs)
+		}
+	case *parse.ActionNode:
+		collectReferences(n.Pipe, dotIsRoot, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectReferences(cmd, dotIsRoot, refs)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectReferences(arg, dotIsRoot, refs)
+		}
+	case *parse.ChainNode:
+		collectReferences(n.Node, dotIsRoot, refs)
+	case *parse.FieldNode:
+		if dotIsRoot && len(n.Ident) > 0 {
+			refs[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			refs[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		collectReferences(n.Pipe, dotIsRoot, refs)
+		collectReferences(n.List, dotIsRoot, refs)
+		collectReferences(n.ElseList, dotIsRoot, refs)
+	case *parse.RangeNode:
+		collectReferences(n.Pipe, dotIsRoot, refs)
+		collectReferences(n.List, false, refs)
+		collectReferences(n.ElseList, dotIsRoot, refs)
+	case *parse.WithNode:
+		collectReferences(n.Pipe, dotIsRoot, refs)
+		collectReferences(n.List, false, refs)
+		collectReferences(n.ElseList, dotIsRoot, refs)
+	case *parse.TemplateNode:
+		collectReferences(n.Pipe, dotIsRoot, refs)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lintDSL is a DSL file with a problem of every kind lint reports
+const lintDSL = `
+version: 1
+templates:
+  - id: review
+    label: Review
+    variables:
+      code:
+        type: multiline
+        default: "@main.go"
+      spec:
+        type: multiline
+        default: "@missing.md"
+      sources:
+        type: file
+        default: "docs/*.md"
+      tests:
+        type: file
+        default: "test/*.go"
+      level:
+        type: enum
+        options: [low, high]
+        default: medium
+      unused:
+        type: bool
+    sections:
+      - id: intro
+        variants:
+          - id: basic
+            type: text
+            content: "Review this {{ .langauge }} code following @docs/style.md and @docs/missing.md."
+          - id: detailed
+            type: text
+            content: "{{ range .sources }}{{ .name }}{{ end }} {{ .code }} {{ .spec }} {{ .tests }} {{ .level }}"
+      - id: intro
+        variants:
+          - id: bullets
+            type: bullets
+            content: "Check:\n{{.}}"
+            bullets:
+              - "{{ if .strict }}everything{{ end }}"
+              - "Ask someone@example.com or @reviewers about @tools/lint.sh"
+              - "{{ .code"
+  - id: review
+    label: ""
+    sections: []
+`
+
+func TestLintDSLFile(t *testing.T) {
+	chdirTemp(t, map[string]string{"main.go": "package main\n", "docs/style.md": "# Style\n"})
+
+	var dsl DSLFile
+	if err := yaml.Unmarshal([]byte(lintDSL), &dsl); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, issue := range LintDSLFile(&dsl) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`error: template 'review': variable 'level' has invalid default: "medium" is not one of: low, high`,
+		"error: template 'review': variable 'spec' default: broken file reference @missing.md",
+		"error: template 'review': variable 'tests' default: no files match test/*.go",
+		"error: template 'review': section 'intro' variant 'basic': broken file reference @docs/missing.md",
+		"error: template 'review': section 'intro' variant 'basic' uses unknown variable 'langauge'",
+		"error: template 'review': duplicate section ID: intro",
+		"error: template 'review': section 'intro' variant 'bullets' uses unknown variable 'strict'",
+		"error: template 'review': section 'intro' variant 'bullets': broken file reference @tools/lint.sh",
+		`error: template 'review': section 'intro' variant 'bullets': template: lint:1: unclosed action`,
+		"warning: template 'review': variable 'unused' is never used",
+		"error: template 'review': duplicate template ID",
+		"error: template 'review': missing required label field",
+		"error: template 'review': must have at least one section",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLintDSLFileClean(t *testing.T) {
+	// The bundled templates lint clean
+	dsl, err := ReadDSLFile("templates.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := LintDSLFile(dsl); len(issues) != 0 {
+		t.Errorf("templates.yml: %v", issues)
+	}
+
+	if issues := LintDSLFile(&DSLFile{Version: 2}); len(issues) != 2 {
+		t.Errorf("issues for an empty version 2 file: %v", issues)
+	}
+}
+
+func TestLintValues(t *testing.T) {
+	chdirTemp(t, map[string]string{"main.go": "package main\n", "docs/a.md": "# A\n"})
+	dsl := &DSLFile{
+		Version: 1,
+		Templates: []TemplateDefinition{
+			{ID: "review", Variables: map[string]VariableConfig{
+				"code":    {Type: VariableTypeMultiline},
+				"sources": {Type: VariableTypeFile},
+				"max":     {Type: VariableTypeNumber, Max: float(10)},
+			}},
+			{ID: "summary", Variables: map[string]VariableConfig{
+				"code": {Type: VariableTypeEnum, Options: []string{"go"}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+		want     []string
+	}{
+		{
+			name:   "valid",
+			values: map[string]string{"sources": "docs/*.md", "max": "3"},
+		},
+		{
+			// Values are checked against every template defining them
+			name:   "missing file",
+			values: map[string]string{"code": "@missing.go"},
+			want: []string{
+				"error: template 'review': variable 'code' value: broken file reference @missing.go",
+				`error: template 'summary': variable 'code' value: "@missing.go" is not one of: go`,
+			},
+		},
+		{
+			name:     "missing file, one template",
+			template: "review",
+			values:   map[string]string{"code": "@missing.go", "sources": "src/**/*.go"},
+			want: []string{
+				"error: template 'review': variable 'code' value: broken file reference @missing.go",
+				"error: template 'review': variable 'sources' value: no files match src/**/*.go",
+			},
+		},
+		{
+			name:     "bad type",
+			template: "review",
+			values:   map[string]string{"max": "eleven"},
+			want:     []string{`error: template 'review': variable 'max' value: "eleven" is not a number`},
+		},
+		{
+			// Empty values fall back to the default
+			name:     "empty and undefined",
+			template: "review",
+			values:   map[string]string{"code": "", "language": "go"},
+			want:     []string{"warning: template 'review': variable 'language' is not defined"},
+		},
+		{
+			name:     "unknown template",
+			template: "translate",
+			values:   map[string]string{"code": "@main.go"},
+			want:     []string{"error: template 'translate' not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range LintValues(dsl, tt.template, tt.values) {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateReferences(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"no variables", []string{}},
+		{"{{ .language }} and {{ .code | trim }}", []string{"code", "language"}},
+		{"{{ if .strict }}{{ .a }}{{ else }}{{ .b }}{{ end }}", []string{"a", "b", "strict"}},
+		{"{{ upper (default .fallback .name) }}", []string{"fallback", "name"}},
+		// Inside range and with, dot is the element, only $ refers to the variables
+		{"{{ range .items }}{{ .name }} {{ $.prefix }}{{ else }}{{ .empty }}{{ end }}", []string{"empty", "items", "prefix"}},
+		{"{{ with .user }}{{ .email }}{{ end }}", []string{"user"}},
+		{"{{ $x := .a }}{{ $x.field }}", []string{"a"}},
+		// Only the top-level field counts
+		{"{{ .config.timeout }}", []string{"config"}},
+	}
+	for _, tt := range tests {
+		got, err := templateReferences(tt.content)
+		if err != nil {
+			t.Errorf("templateReferences(%q) failed: %v", tt.content, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("templateReferences(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+
+	for _, content := range []string{"{{ .a", "{{ end }}", "{{ nosuchfunc .a }}"} {
+		if _, err := templateReferences(content); err == nil {
+			t.Errorf("templateReferences(%q): expected an error", content)
+		}
+	}
+}
+
+func TestFileReferences(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"Follow @docs/style.md.", []string{"docs/style.md"}},
+		{"@main.go, @./run.sh and @~/notes.txt", []string{"main.go", "./run.sh", "~/notes.txt"}},
+		// E-mail addresses, mentions and references inside actions are not files
+		{"mail me@example.com or @team", nil},
+		{`{{ .code | default "@main.go" }}`, nil},
+	}
+	for _, tt := range tests {
+		if got := fileReferences(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fileReferences(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
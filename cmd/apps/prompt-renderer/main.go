@@ -25,8 +25,9 @@ func main() {
 into clipboard-ready prompts through an interactive configuration interface.`,
 		RunE: runApp,
 	}
-	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
-	rootCmd.Flags().StringVar(&dslFilePath, "dsl", "", "Path to DSL file (defaults to auto-discovery)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&dslFilePath, "dsl", "", "Path to DSL file (defaults to auto-discovery)")
+	rootCmd.AddCommand(newRenderCommand(), newLintCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -40,24 +41,11 @@ func runApp(cmd *cobra.Command, args []string) error {
 
 	log.Info().Msg("Starting prompt renderer application")
 
-	// Load DSL file
-	var dslFile *DSLFile
-	var err error
-
-	if dslFilePath != "" {
-		log.Info().Str("path", dslFilePath).Msg("Loading DSL file from specified path")
-		dslFile, err = ParseDSLFile(dslFilePath)
-	} else {
-		log.Info().Msg("Auto-discovering DSL file")
-		dslFile, err = LoadDefaultDSLFile()
-	}
-
+	dslFile, err := loadDSLFile()
 	if err != nil {
-		return errors.Wrap(err, "❌ Failed to load DSL file. Please check your YAML syntax and ensure the file exists.")
+		return err
 	}
 
-	log.Info().Int("templates", len(dslFile.Templates)).Msg("DSL file loaded successfully")
-
 	// Initialize components
 	renderer := NewPromptRenderer(dslFile)
 	clipboard := NewClipboardManager()
@@ -83,6 +71,27 @@ func runApp(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// loadDSLFile loads the DSL file given with --dsl, or the auto-discovered one
+func loadDSLFile() (*DSLFile, error) {
+	var dslFile *DSLFile
+	var err error
+
+	if dslFilePath != "" {
+		log.Info().Str("path", dslFilePath).Msg("Loading DSL file from specified path")
+		dslFile, err = ParseDSLFile(dslFilePath)
+	} else {
+		log.Info().Msg("Auto-discovering DSL file")
+		dslFile, err = LoadDefaultDSLFile()
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "❌ Failed to load DSL file. Please check your YAML syntax and ensure the file exists.")
+	}
+
+	log.Info().Int("templates", len(dslFile.Templates)).Msg("DSL file loaded successfully")
+	return dslFile, nil
+}
+
 func setupLogging() {
 	level, err := zerolog.ParseLevel(logLevel)
 	if err != nil {
@@ -11,6 +11,20 @@ import (
 
 // ParseDSLFile loads and validates a DSL file from the given path
 func ParseDSLFile(path string) (*DSLFile, error) {
+	dslFile, err := ReadDSLFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateDSLFile(dslFile); err != nil {
+		return nil, errors.Wrapf(err, "validation failed for file: %s", path)
+	}
+
+	return dslFile, nil
+}
+
+// ReadDSLFile loads a DSL file without validating it
+func ReadDSLFile(path string) (*DSLFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read DSL file: %s", path)
@@ -21,10 +35,6 @@ func ParseDSLFile(path string) (*DSLFile, error) {
 		return nil, errors.Wrapf(err, "❌ Failed to parse YAML in file: %s. Please check YAML syntax (indentation, colons, quotes)", path)
 	}
 
-	if err := validateDSLFile(&dslFile); err != nil {
-		return nil, errors.Wrapf(err, "validation failed for file: %s", path)
-	}
-
 	return &dslFile, nil
 }
 
@@ -73,8 +83,8 @@ func validateTemplate(template *TemplateDefinition, index int) error {
 
 	// Validate variables
 	for varName, varConfig := range template.Variables {
-		if varConfig.Type != "" && varConfig.Type != "text" {
-			return fmt.Errorf("template '%s' variable '%s' has unsupported type: %s", template.ID, varName, varConfig.Type)
+		if err := validateVariable(template.ID, varName, varConfig); err != nil {
+			return err
 		}
 	}
 
@@ -147,9 +157,19 @@ func validateVariant(variant *VariantDefinition, templateID, sectionID string, i
 
 // LoadDefaultDSLFile attempts to load a DSL file from common locations
 func LoadDefaultDSLFile() (*DSLFile, error) {
+	path, err := FindDefaultDSLFile()
+	if err != nil {
+		return nil, err
+	}
+	return ParseDSLFile(path)
+}
+
+// FindDefaultDSLFile returns the path of the DSL file in the current directory
+// or the XDG data directory
+func FindDefaultDSLFile() (string, error) {
 	// Try current directory first
 	if _, err := os.Stat("templates.yml"); err == nil {
-		return ParseDSLFile("templates.yml")
+		return "templates.yml", nil
 	}
 
 	// Try XDG data directory
@@ -164,9 +184,9 @@ func LoadDefaultDSLFile() (*DSLFile, error) {
 	if xdgDataHome != "" {
 		dslPath := filepath.Join(xdgDataHome, "prompt-builder", "templates.yml")
 		if _, err := os.Stat(dslPath); err == nil {
-			return ParseDSLFile(dslPath)
+			return dslPath, nil
 		}
 	}
 
-	return nil, errors.New("no DSL file found in current directory or XDG data directory")
+	return "", errors.New("no DSL file found in current directory or XDG data directory")
 }
@@ -98,6 +98,11 @@ func (p *PersistenceManager) loadSelectionFromFile(path string) (*SelectionState
 		return nil, nil // File doesn't exist, return nil without error
 	}
 
+	return ReadSelectionFile(path)
+}
+
+// ReadSelectionFile loads a selection state, as saved by the TUI, from a file
+func ReadSelectionFile(path string) (*SelectionState, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read selection file: %s", path)
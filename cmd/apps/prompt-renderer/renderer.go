@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 	"text/template"
@@ -140,33 +139,18 @@ func (r *PromptRenderer) getBulletPrefix() string {
 
 // substituteVariables performs variable substitution using Go templates with sprig functions
 func (r *PromptRenderer) substituteVariables(content string, templateDef *TemplateDefinition, selection *SelectionState) (string, error) {
-	// Prepare variables map with defaults for missing variables
-	variables := make(map[string]string)
+	// Prepare variables map, typed according to the variable definitions
+	variables := make(map[string]interface{})
 
-	// Set default values for all defined variables
-	for varName, varConfig := range templateDef.Variables {
-		defaultValue := fmt.Sprintf("DEFAULT_%s", strings.ToUpper(varName))
-		if varConfig.Hint != "" {
-			defaultValue = fmt.Sprintf("DEFAULT_%s", strings.ToUpper(varName))
+	// Values of variables the template doesn't define are passed as text
+	for varName, value := range selection.Variables {
+		if _, defined := templateDef.Variables[varName]; !defined {
+			variables[varName] = r.resolveValue(VariableConfig{}, varName, value)
 		}
-		variables[varName] = defaultValue
 	}
 
-	// Override with actual values from selection
-	for varName, value := range selection.Variables {
-		// Handle file references
-		if strings.HasPrefix(value, "@") {
-			filename := strings.TrimPrefix(value, "@")
-			fileContent, err := r.readFileContent(filename)
-			if err != nil {
-				// Use error message as value to show the problem
-				variables[varName] = fmt.Sprintf("ERROR_READING_FILE: %s", err.Error())
-			} else {
-				variables[varName] = fileContent
-			}
-		} else {
-			variables[varName] = value
-		}
+	for varName, varConfig := range templateDef.Variables {
+		variables[varName] = r.resolveValue(varConfig, varName, effectiveValue(varConfig, selection, varName))
 	}
 
 	// Create template with sprig functions
@@ -184,13 +168,25 @@ func (r *PromptRenderer) substituteVariables(content string, templateDef *Templa
 	return buf.String(), nil
 }
 
-// readFileContent reads content from a file, with error handling
-func (r *PromptRenderer) readFileContent(filename string) (string, error) {
-	data, err := os.ReadFile(filename)
+// resolveValue converts a variable value for the template. Problems are
+// rendered into the prompt so they show up in the preview, headless
+// rendering rejects them beforehand with ValidateSelection.
+func (r *PromptRenderer) resolveValue(config VariableConfig, varName, value string) interface{} {
+	// Text variables without a value show a placeholder
+	if value == "" && config.variableType() != VariableTypeBool && config.variableType() != VariableTypeNumber {
+		return fmt.Sprintf("DEFAULT_%s", strings.ToUpper(varName))
+	}
+
+	if err := config.Validate(value); err != nil {
+		return fmt.Sprintf("INVALID_VALUE: %s", err.Error())
+	}
+
+	resolved, err := config.Resolve(value)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to read file: %s", filename)
+		// Use error message as value to show the problem
+		return fmt.Sprintf("ERROR_READING_FILE: %s", err.Error())
 	}
-	return string(data), nil
+	return resolved
 }
 
 // cleanupPrompt removes excessive whitespace and normalizes line endings
@@ -66,7 +66,7 @@ func (f *FormHandler) RebuildFormItems(template *TemplateDefinition, selection *
 			Key:   name,
 			Label: name,
 			Value: value,
-			Hint:  varConfig.Hint,
+			Hint:  varConfig.DisplayHint(),
 		})
 	}
 
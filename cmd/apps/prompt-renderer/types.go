@@ -27,7 +27,19 @@ type TemplateDefinition struct {
 // VariableConfig defines a template variable
 type VariableConfig struct {
 	Hint string `yaml:"hint"`
-	Type string `yaml:"type"` // Currently only "text" supported
+	Type string `yaml:"type"` // "text" (default), "multiline", "enum", "bool", "number" or "file"
+	// Default is used when no value is given
+	Default  string `yaml:"default,omitempty"`
+	Required bool   `yaml:"required,omitempty"`
+	// Options are the allowed values of enum variables
+	Options []string `yaml:"options,omitempty"`
+	// Min and Max bound number variables
+	Min *float64 `yaml:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty"`
+	// Pattern, MaxLength and MaxLines constrain text and multiline variables
+	Pattern   string `yaml:"pattern,omitempty"`
+	MaxLength int    `yaml:"max_length,omitempty"`
+	MaxLines  int    `yaml:"max_lines,omitempty"`
 }
 
 // SectionDefinition represents a section with variants
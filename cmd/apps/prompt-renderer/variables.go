@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+)
+
+// Variable types supported in VariableConfig.Type
+const (
+	VariableTypeText      = "text"
+	VariableTypeMultiline = "multiline"
+	VariableTypeEnum      = "enum"
+	VariableTypeBool      = "bool"
+	VariableTypeNumber    = "number"
+	VariableTypeFile      = "file"
+)
+
+var variableTypes = []string{
+	VariableTypeText,
+	VariableTypeMultiline,
+	VariableTypeEnum,
+	VariableTypeBool,
+	VariableTypeNumber,
+	VariableTypeFile,
+}
+
+// variableType returns the type of a variable, text if none is set
+func (v VariableConfig) variableType() string {
+	if v.Type == "" {
+		return VariableTypeText
+	}
+	return v.Type
+}
+
+// DisplayHint returns the hint with the accepted values of typed variables
+func (v VariableConfig) DisplayHint() string {
+	var constraint string
+	switch v.variableType() {
+	case VariableTypeEnum:
+		constraint = "one of: " + strings.Join(v.Options, ", ")
+	case VariableTypeBool:
+		constraint = "true/false"
+	case VariableTypeNumber:
+		constraint = "number"
+		if v.Min != nil && v.Max != nil {
+			constraint = fmt.Sprintf("number %g-%g", *v.Min, *v.Max)
+		} else if v.Min != nil {
+			constraint = fmt.Sprintf("number >= %g", *v.Min)
+		} else if v.Max != nil {
+			constraint = fmt.Sprintf("number <= %g", *v.Max)
+		}
+	case VariableTypeFile:
+		constraint = "file glob"
+	default:
+		return v.Hint
+	}
+
+	if v.Hint == "" {
+		return constraint
+	}
+	return fmt.Sprintf("%s (%s)", v.Hint, constraint)
+}
+
+// validateVariable checks a variable definition
+func validateVariable(templateID, name string, v VariableConfig) error {
+	prefix := fmt.Sprintf("template '%s' variable '%s'", templateID, name)
+
+	valid := false
+	for _, t := range variableTypes {
+		if v.variableType() == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%s has unsupported type: %s (must be one of %s)", prefix, v.Type, strings.Join(variableTypes, ", "))
+	}
+
+	if v.variableType() == VariableTypeEnum && len(v.Options) == 0 {
+		return fmt.Errorf("%s of type 'enum' requires options", prefix)
+	}
+	if v.variableType() != VariableTypeEnum && len(v.Options) > 0 {
+		return fmt.Errorf("%s has options but is not of type 'enum'", prefix)
+	}
+	if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		return fmt.Errorf("%s has min %g greater than max %g", prefix, *v.Min, *v.Max)
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return errors.Wrapf(err, "%s has invalid pattern", prefix)
+		}
+	}
+	if v.Default != "" && !strings.HasPrefix(v.Default, "@") {
+		if err := v.Validate(v.Default); err != nil {
+			return errors.Wrapf(err, "%s has invalid default", prefix)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a value against the variable's type and constraints. Empty
+// values are only invalid for required variables. Values starting with @ are
+// file references and are checked by Resolve.
+func (v VariableConfig) Validate(value string) error {
+	if value == "" {
+		if v.Required {
+			return errors.New("value is required")
+		}
+		return nil
+	}
+	if strings.HasPrefix(value, "@") && v.acceptsFileReference() {
+		return nil
+	}
+
+	switch v.variableType() {
+	case VariableTypeEnum:
+		for _, option := range v.Options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of: %s", value, strings.Join(v.Options, ", "))
+
+	case VariableTypeBool:
+		if _, err := parseBool(value); err != nil {
+			return err
+		}
+
+	case VariableTypeNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		if v.Min != nil && n < *v.Min {
+			return fmt.Errorf("%g is less than the minimum %g", n, *v.Min)
+		}
+		if v.Max != nil && n > *v.Max {
+			return fmt.Errorf("%g is greater than the maximum %g", n, *v.Max)
+		}
+
+	case VariableTypeText, VariableTypeMultiline:
+		if v.variableType() == VariableTypeText && strings.Contains(value, "\n") {
+			return errors.New("text variables must be a single line, use type 'multiline'")
+		}
+		if v.MaxLines > 0 {
+			if lines := strings.Count(strings.TrimRight(value, "\n"), "\n") + 1; lines > v.MaxLines {
+				return fmt.Errorf("value has %d lines, at most %d are allowed", lines, v.MaxLines)
+			}
+		}
+		if v.MaxLength > 0 && len([]rune(value)) > v.MaxLength {
+			return fmt.Errorf("value is longer than %d characters", v.MaxLength)
+		}
+		if v.Pattern != "" {
+			matched, err := regexp.MatchString(v.Pattern, value)
+			if err != nil {
+				return errors.Wrap(err, "invalid pattern")
+			}
+			if !matched {
+				return fmt.Errorf("value doesn't match pattern %s", v.Pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// acceptsFileReference reports whether @filename values are read from files
+func (v VariableConfig) acceptsFileReference() bool {
+	t := v.variableType()
+	return t == VariableTypeText || t == VariableTypeMultiline
+}
+
+// Resolve converts a validated value to what templates see: bools and
+// numbers become typed values, @filename references and file globs are
+// replaced by the file contents.
+func (v VariableConfig) Resolve(value string) (interface{}, error) {
+	switch v.variableType() {
+	case VariableTypeBool:
+		if value == "" {
+			return false, nil
+		}
+		return parseBool(value)
+
+	case VariableTypeNumber:
+		if value == "" {
+			return 0.0, nil
+		}
+		return strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+	case VariableTypeFile:
+		if value == "" {
+			return "", nil
+		}
+		return readFileGlob(value)
+
+	default:
+		if strings.HasPrefix(value, "@") {
+			filename := strings.TrimPrefix(value, "@")
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read file: %s", filename)
+			}
+			return string(data), nil
+		}
+		return value, nil
+	}
+}
+
+// parseBool accepts the usual spellings of booleans
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "on", "1":
+		return true, nil
+	case "false", "no", "n", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a boolean", value)
+}
+
+// readFileGlob concatenates the files matching a glob, ** matching any number
+// of directories. Each file is preceded by its path.
+func readFileGlob(pattern string) (string, error) {
+	matches, err := doublestar.FilepathGlob(pattern)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid file glob: %s", pattern)
+	}
+	sort.Strings(matches)
+
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read file: %s", match)
+		}
+		files = append(files, fmt.Sprintf("File: %s\n%s", match, strings.TrimRight(string(data), "\n")))
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files match %s", pattern)
+	}
+	return strings.Join(files, "\n\n"), nil
+}
+
+// effectiveValue returns the selected value of a variable, or its default
+func effectiveValue(v VariableConfig, selection *SelectionState, name string) string {
+	if value := selection.Variables[name]; value != "" {
+		return value
+	}
+	return v.Default
+}
+
+// ValidateSelection checks every variable value of a selection against the
+// template, including that referenced files exist. It returns one error per
+// invalid variable, sorted by variable name.
+func ValidateSelection(templateDef *TemplateDefinition, selection *SelectionState) []error {
+	var errs []error
+
+	names := make([]string, 0, len(templateDef.Variables))
+	for name := range templateDef.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := templateDef.Variables[name]
+		value := effectiveValue(config, selection, name)
+		if err := config.Validate(value); err != nil {
+			errs = append(errs, errors.Wrapf(err, "variable '%s'", name))
+			continue
+		}
+		if _, err := config.Resolve(value); err != nil {
+			errs = append(errs, errors.Wrapf(err, "variable '%s'", name))
+		}
+	}
+
+	unknown := []string{}
+	for name := range selection.Variables {
+		if _, ok := templateDef.Variables[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Errorf("variable '%s' is not defined by template '%s'", name, templateDef.ID))
+	}
+
+	for sectionID, sectionSelection := range selection.Sections {
+		section := findSection(templateDef, sectionID)
+		if section == nil {
+			errs = append(errs, fmt.Errorf("section '%s' is not defined by template '%s'", sectionID, templateDef.ID))
+			continue
+		}
+		if sectionSelection.Variant != "" && findVariant(section, sectionSelection.Variant) == nil {
+			errs = append(errs, fmt.Errorf("section '%s' has no variant '%s'", sectionID, sectionSelection.Variant))
+		}
+	}
+
+	return errs
+}
+
+func findSection(templateDef *TemplateDefinition, id string) *SectionDefinition {
+	for i := range templateDef.Sections {
+		if templateDef.Sections[i].ID == id {
+			return &templateDef.Sections[i]
+		}
+	}
+	return nil
+}
+
+func findVariant(section *SectionDefinition, id string) *VariantDefinition {
+	for i := range section.Variants {
+		if section.Variants[i].ID == id {
+			return &section.Variants[i]
+		}
+	}
+	return nil
+}
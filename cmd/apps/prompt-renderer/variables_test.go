@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  VariableConfig
+		value   string
+		wantErr string
+	}{
+		{name: "empty text", config: VariableConfig{}, value: ""},
+		{name: "required", config: VariableConfig{Required: true}, value: "", wantErr: "value is required"},
+		{name: "text", config: VariableConfig{}, value: "go"},
+		{name: "text with newline", config: VariableConfig{}, value: "a\nb", wantErr: "must be a single line"},
+		{name: "multiline", config: VariableConfig{Type: VariableTypeMultiline}, value: "a\nb"},
+		{name: "max lines", config: VariableConfig{Type: VariableTypeMultiline, MaxLines: 2}, value: "a\nb\nc\n", wantErr: "value has 3 lines, at most 2"},
+		{name: "max lines, trailing newline", config: VariableConfig{Type: VariableTypeMultiline, MaxLines: 2}, value: "a\nb\n"},
+		{name: "max length in runes", config: VariableConfig{MaxLength: 3}, value: "äöü"},
+		{name: "too long", config: VariableConfig{MaxLength: 3}, value: "abcd", wantErr: "longer than 3 characters"},
+		{name: "pattern", config: VariableConfig{Pattern: `^[a-z]+$`}, value: "go"},
+		{name: "pattern mismatch", config: VariableConfig{Pattern: `^[a-z]+$`}, value: "Go", wantErr: "doesn't match pattern"},
+		// File references are checked by Resolve
+		{name: "file reference", config: VariableConfig{Pattern: `^[a-z]+$`, MaxLength: 2}, value: "@missing.go"},
+		{name: "enum", config: VariableConfig{Type: VariableTypeEnum, Options: []string{"short", "long"}}, value: "long"},
+		{name: "bad enum", config: VariableConfig{Type: VariableTypeEnum, Options: []string{"short", "long"}}, value: "medium", wantErr: `"medium" is not one of: short, long`},
+		{name: "enum file reference", config: VariableConfig{Type: VariableTypeEnum, Options: []string{"short"}}, value: "@short", wantErr: "is not one of"},
+		{name: "bool", config: VariableConfig{Type: VariableTypeBool}, value: "Yes"},
+		{name: "bad bool", config: VariableConfig{Type: VariableTypeBool}, value: "maybe", wantErr: `"maybe" is not a boolean`},
+		{name: "number", config: VariableConfig{Type: VariableTypeNumber}, value: " 1.5 "},
+		{name: "bad number", config: VariableConfig{Type: VariableTypeNumber}, value: "ten", wantErr: `"ten" is not a number`},
+		{name: "number below min", config: VariableConfig{Type: VariableTypeNumber, Min: float(1)}, value: "0", wantErr: "less than the minimum 1"},
+		{name: "number above max", config: VariableConfig{Type: VariableTypeNumber, Max: float(20)}, value: "21", wantErr: "greater than the maximum 20"},
+		{name: "number at bounds", config: VariableConfig{Type: VariableTypeNumber, Min: float(1), Max: float(20)}, value: "20"},
+		{name: "file glob", config: VariableConfig{Type: VariableTypeFile}, value: "docs/**/*.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate(%q) failed: %v", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate(%q) = %v, want an error containing %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// chdirTemp changes to a temporary directory with the given files for the
+// duration of a test
+func chdirTemp(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"main.go":          "package main\n",
+		"docs/a.md":        "# A\n\n",
+		"docs/sub/b.md":    "# B",
+		"docs/notes.txt":   "not markdown",
+		"docs/empty.md/x":  "a directory named like a match",
+		"docs/sub/c.md.go": "not markdown either",
+	})
+
+	tests := []struct {
+		name    string
+		config  VariableConfig
+		value   string
+		want    interface{}
+		wantErr string
+	}{
+		{name: "text", config: VariableConfig{}, value: "go", want: "go"},
+		{name: "file reference", config: VariableConfig{Type: VariableTypeMultiline}, value: "@main.go", want: "package main\n"},
+		{name: "missing file reference", config: VariableConfig{}, value: "@missing.go", wantErr: "failed to read file: missing.go"},
+		{name: "bool", config: VariableConfig{Type: VariableTypeBool}, value: "on", want: true},
+		{name: "empty bool", config: VariableConfig{Type: VariableTypeBool}, value: "", want: false},
+		{name: "number", config: VariableConfig{Type: VariableTypeNumber}, value: "2.5", want: 2.5},
+		{name: "empty number", config: VariableConfig{Type: VariableTypeNumber}, value: "", want: 0.0},
+		{name: "empty file glob", config: VariableConfig{Type: VariableTypeFile}, value: "", want: ""},
+		{
+			// Sorted, directories skipped, trailing newlines trimmed
+			name:   "file glob",
+			config: VariableConfig{Type: VariableTypeFile},
+			value:  "docs/**/*.md",
+			want:   "File: docs/a.md\n# A\n\nFile: docs/sub/b.md\n# B",
+		},
+		{name: "file glob without matches", config: VariableConfig{Type: VariableTypeFile}, value: "src/*.go", wantErr: "no files match src/*.go"},
+		{name: "invalid file glob", config: VariableConfig{Type: VariableTypeFile}, value: "docs/[", wantErr: "invalid file glob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Resolve(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve(%q) = %v, %v, want an error containing %q", tt.value, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) failed: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSelection(t *testing.T) {
+	chdirTemp(t, map[string]string{"main.go": "package main\n"})
+	templateDef := &TemplateDefinition{
+		ID: "review",
+		Variables: map[string]VariableConfig{
+			"code":   {Type: VariableTypeMultiline, Required: true},
+			"level":  {Type: VariableTypeEnum, Options: []string{"low", "high"}, Default: "low"},
+			"issues": {Type: VariableTypeNumber, Max: float(10)},
+		},
+		Sections: []SectionDefinition{{ID: "intro", Variants: []VariantDefinition{{ID: "basic", Type: "text"}}}},
+	}
+
+	valid := &SelectionState{Variables: map[string]string{"code": "@main.go", "issues": "3"}}
+	if errs := ValidateSelection(templateDef, valid); len(errs) != 0 {
+		t.Errorf("valid selection: %v", errs)
+	}
+
+	invalid := &SelectionState{
+		Variables: map[string]string{"code": "@missing.go", "level": "medium", "issues": "11", "extra": "x"},
+		Sections:  map[string]SectionSelection{"intro": {Variant: "long"}, "outro": {}},
+	}
+	var got []string
+	for _, err := range ValidateSelection(templateDef, invalid) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"variable 'code': failed to read file: missing.go",
+		"variable 'issues': 11 is greater than the maximum 10",
+		`variable 'level': "medium" is not one of: low, high`,
+		"variable 'extra' is not defined by template 'review'",
+	}
+	if len(got) != len(want)+2 {
+		t.Fatalf("errors = %q", got)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(got[i], w) {
+			t.Errorf("error %d = %q, want %q", i, got[i], w)
+		}
+	}
+	// Sections are checked in map order
+	sections := strings.Join(got[len(want):], "\n")
+	if !strings.Contains(sections, "section 'intro' has no variant 'long'") || !strings.Contains(sections, "section 'outro' is not defined") {
+		t.Errorf("section errors = %q", sections)
+	}
+}
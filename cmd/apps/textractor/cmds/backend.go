@@ -0,0 +1,70 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/local"
+	"github.com/spf13/cobra"
+)
+
+// DefaultLocalFixtures are the Textract responses the local stand-in
+// returns, relative to the textractor directory
+const DefaultLocalFixtures = "test-docs/json"
+
+// BackendSettings selects between the deployed resources and the local
+// stand-in
+type BackendSettings struct {
+	TfDir         string
+	Config        string
+	Local         string
+	LocalFixtures string
+}
+
+// BackendSettingsFromCommand reads the backend flags of the root command
+func BackendSettingsFromCommand(cmd *cobra.Command) (BackendSettings, error) {
+	var s BackendSettings
+	for name, value := range map[string]*string{
+		"tf-dir":         &s.TfDir,
+		"config":         &s.Config,
+		"local":          &s.Local,
+		"local-fixtures": &s.LocalFixtures,
+	} {
+		v, err := cmd.Flags().GetString(name)
+		if err != nil {
+			return s, fmt.Errorf("failed to get %s flag: %w", name, err)
+		}
+		*value = v
+	}
+	return s, nil
+}
+
+// OpenBackend opens the local stack if --local is set, and the resources
+// from the config file or Terraform state otherwise. The backend must be
+// closed, for a local stack that runs the pipeline.
+func OpenBackend(s BackendSettings) (*pkg.Backend, error) {
+	if s.Local != "" {
+		stack, err := local.Open(local.Options{
+			Dir:      s.Local,
+			Fixtures: s.LocalFixtures,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open local stack: %w", err)
+		}
+		return stack.Backend(), nil
+	}
+
+	resources, err := pkg.NewStateLoader().LoadState(s.TfDir, s.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load terraform state: %w", err)
+	}
+	return pkg.NewAWSBackend(resources), nil
+}
+
+func openBackendFromCommand(cmd *cobra.Command) (*pkg.Backend, error) {
+	s, err := BackendSettingsFromCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBackend(s)
+}
@@ -13,7 +13,7 @@ func newCloudTrailCommand() *cobra.Command {
 		Use:   "cloudtrail",
 		Short: "Debug CloudTrail logs",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -41,7 +41,7 @@ func newCloudTrailCommand() *cobra.Command {
 		Short: "Look up specific CloudTrail events",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
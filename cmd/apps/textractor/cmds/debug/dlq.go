@@ -28,6 +28,13 @@ func addDLQCommand(debugCmd *cobra.Command) {
 
 			fmt.Printf("🔍 Debugging %s DLQ\n", args[0])
 
+			if dir := localDir(cmd); dir != "" {
+				if err := printLocalDeadLetters(dir, args[0]); err != nil {
+					log.Fatalf("Failed to inspect local stand-in: %v", err)
+				}
+				return
+			}
+
 			// Get queue attributes
 			err = runAWSCommand("sqs", "get-queue-attributes",
 				"--queue-url", queueURL,
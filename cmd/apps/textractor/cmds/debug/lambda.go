@@ -30,6 +30,13 @@ func newLambdaCommand() *cobra.Command {
 			fmt.Printf("Document Processor: %s\n", resources.DocumentProcessorName)
 			fmt.Printf("Completion Processor: %s\n", resources.CompletionProcessorName)
 
+			if dir := localDir(cmd); dir != "" {
+				if err := printLocalProcessors(dir); err != nil {
+					log.Fatalf("Failed to inspect local stand-in: %v", err)
+				}
+				return
+			}
+
 			// Initialize AWS SDK
 			cfg, err := config.LoadDefaultConfig(context.Background())
 			if err != nil {
@@ -63,7 +70,7 @@ func newLambdaCommand() *cobra.Command {
 		Long:  "View Lambda function logs. Processor can be 'document' or 'completion'",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -0,0 +1,204 @@
+package debug
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/local"
+	"github.com/spf13/cobra"
+)
+
+func newLocalCommand() *cobra.Command {
+	var notificationCount int
+
+	cmd := &cobra.Command{
+		Use:   "local",
+		Short: "Show the jobs, objects and notifications of the local stand-in",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("local")
+			if dir == "" {
+				return fmt.Errorf("--local is required")
+			}
+			if _, err := os.Stat(dir); err != nil {
+				return fmt.Errorf("no local stand-in in %s: %w", dir, err)
+			}
+
+			stack, err := local.Open(local.Options{Dir: dir})
+			if err != nil {
+				return err
+			}
+			defer stack.Close()
+			resources := stack.Resources()
+
+			fmt.Printf("🔍 Local stand-in in %s\n", dir)
+
+			jobs, err := stack.Jobs().ListJobs(pkg.ListJobsOptions{})
+			if err != nil {
+				return err
+			}
+			byStatus := map[string]int{}
+			for _, job := range jobs {
+				byStatus[job.Status]++
+			}
+			statuses := make([]string, 0, len(byStatus))
+			for status := range byStatus {
+				statuses = append(statuses, status)
+			}
+			sort.Strings(statuses)
+			fmt.Printf("\nJobs (%s): %d\n", resources.JobsTable, len(jobs))
+			for _, status := range statuses {
+				fmt.Printf("  %-12s %d\n", status, byStatus[status])
+			}
+
+			fmt.Printf("\nBuckets:\n")
+			for _, bucket := range []string{resources.DocumentS3Bucket, resources.OutputS3Bucket} {
+				keys, err := stack.Buckets().ListObjects(bucket, "")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("  %-22s %d objects\n", bucket, len(keys))
+			}
+
+			notifications, err := local.ReadNotifications(dir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\nNotifications: %d\n", len(notifications))
+			printNotifications(lastNotifications(notifications, notificationCount))
+
+			if data, err := os.ReadFile(filepath.Join(dir, "dead-letters.jsonl")); err == nil && len(data) > 0 {
+				fmt.Printf("\nDead letters:\n%s", data)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&notificationCount, "notifications", "n", 10, "Number of recent notifications to show")
+	return cmd
+}
+
+// lastNotifications returns the last n notifications, n is clamped to the
+// number of notifications
+func lastNotifications(notifications []local.Notification, n int) []local.Notification {
+	n = min(max(n, 0), len(notifications))
+	return notifications[len(notifications)-n:]
+}
+
+func printNotifications(notifications []local.Notification) {
+	for _, n := range notifications {
+		fmt.Printf("  %s %s %s", n.Timestamp, n.JobID, n.Status)
+		if n.Error != "" {
+			fmt.Printf(" (%s)", n.Error)
+		}
+		fmt.Println()
+	}
+}
+
+// The local queues only live as long as a command and are drained before it
+// exits, so the debug commands show what outlives them: the dead letters and
+// the notifications logged in the local directory.
+
+// localQueues maps the queues of the deployed stack to the local ones and
+// the processors consuming them
+var localQueues = []struct {
+	name      string
+	processor string
+}{
+	{"input", "document-processor"},
+	{"completion", "completion-processor"},
+	{"notifications", ""},
+}
+
+// printLocalQueues is the local version of 'debug queue'
+func printLocalQueues(dir string) error {
+	deadLetters, err := local.ReadDeadLetters(dir)
+	if err != nil {
+		return err
+	}
+	notifications, err := local.ReadNotifications(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nLocal queues in %s are drained when a command exits\n", dir)
+	for _, q := range localQueues {
+		count := 0
+		for _, d := range deadLetters {
+			if d.Queue == q.name {
+				count++
+			}
+		}
+		fmt.Printf("  %-14s %d dead letters", q.name, count)
+		if q.name == "notifications" {
+			fmt.Printf(", %d notifications sent", len(notifications))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printLocalDeadLetters is the local version of 'debug dlq'
+func printLocalDeadLetters(dir string, queue string) error {
+	if queue != "input" && queue != "completion" {
+		return fmt.Errorf("unknown queue %s, expected input or completion", queue)
+	}
+	deadLetters, err := local.ReadDeadLetters(dir)
+	if err != nil {
+		return err
+	}
+
+	var matching []local.DeadLetter
+	for _, d := range deadLetters {
+		if d.Queue == queue {
+			matching = append(matching, d)
+		}
+	}
+	fmt.Printf("\nMessages in the DLQ: %d\n", len(matching))
+	for _, d := range matching {
+		fmt.Printf("\n%s after %d receives: %s\n%s\n", d.Timestamp, d.Receives, d.Error, d.Body)
+	}
+	return nil
+}
+
+// printLocalTopics is the local version of 'debug sns'
+func printLocalTopics(dir string, resources *pkg.TextractorResources) error {
+	notifications, err := local.ReadNotifications(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nSubscriptions:\n")
+	fmt.Printf("  %s -> %s\n", resources.SNSTopic, resources.CompletionQueue)
+	fmt.Printf("  %s -> %s\n", resources.NotificationTopic, resources.NotificationsQueue)
+
+	fmt.Printf("\nNotifications published: %d\n", len(notifications))
+	printNotifications(lastNotifications(notifications, 10))
+	return nil
+}
+
+// printLocalProcessors is the local version of 'debug lambda'
+func printLocalProcessors(dir string) error {
+	deadLetters, err := local.ReadDeadLetters(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nThe processors run in-process when the local stack drains its queues\n")
+	for _, q := range localQueues {
+		if q.processor == "" {
+			continue
+		}
+		failed := 0
+		for _, d := range deadLetters {
+			if d.Queue == q.name {
+				failed++
+			}
+		}
+		fmt.Printf("  %-22s consumes %s, %d messages failed\n", q.processor, q.name, failed)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/local"
+)
+
+func TestLastNotifications(t *testing.T) {
+	notifications := []local.Notification{{JobID: "a"}, {JobID: "b"}, {JobID: "c"}}
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 2, want: "bc"},
+		{n: 3, want: "abc"},
+		{n: 10, want: "abc"},
+		{n: 0, want: ""},
+		{n: -1, want: ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		for _, n := range lastNotifications(notifications, tt.n) {
+			got += n.JobID
+		}
+		if got != tt.want {
+			t.Errorf("lastNotifications(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -13,7 +13,7 @@ func newMetricsCommand() *cobra.Command {
 		Use:   "metrics",
 		Short: "Debug CloudWatch metrics",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -40,7 +40,7 @@ func newMetricsCommand() *cobra.Command {
 		Long:  "View CloudWatch log groups. Processor can be 'document', 'completion', or 'cloudtrail'",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
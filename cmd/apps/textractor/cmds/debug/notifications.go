@@ -41,7 +41,7 @@ func newNotificationsCommand() *cobra.Command {
 }
 
 func runMessagesCommand(cmd *cobra.Command, args []string) {
-	resources, err := LoadResources(cmd)
+	resources, err := LoadAWSResources(cmd)
 	if err != nil {
 		log.Fatalf("Failed to load resources: %v", err)
 	}
@@ -98,7 +98,7 @@ func runMessagesCommand(cmd *cobra.Command, args []string) {
 }
 
 func runMetricsCommand(cmd *cobra.Command, args []string) {
-	resources, err := LoadResources(cmd)
+	resources, err := LoadAWSResources(cmd)
 	if err != nil {
 		log.Fatalf("Failed to load resources: %v", err)
 	}
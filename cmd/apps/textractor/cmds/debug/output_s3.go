@@ -13,7 +13,7 @@ func newOutputS3Command() *cobra.Command {
 		Use:   "output-s3",
 		Short: "Debug output S3 bucket configuration",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -42,7 +42,7 @@ func newOutputS3Command() *cobra.Command {
 		Long:  "List files in output S3 bucket. Optionally specify a prefix to filter results",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -22,6 +22,13 @@ func newQueueCommand() *cobra.Command {
 			fmt.Printf("Completion Queue: %s\n", resources.CompletionQueue)
 			fmt.Printf("Notifications Queue: %s\n", resources.NotificationsQueue)
 
+			if dir := localDir(cmd); dir != "" {
+				if err := printLocalQueues(dir); err != nil {
+					log.Fatalf("Failed to inspect local stand-in: %v", err)
+				}
+				return
+			}
+
 			// Get queue attributes
 			err = runAWSCommand("sqs", "get-queue-attributes",
 				"--queue-url", resources.InputQueue,
@@ -24,7 +24,9 @@ func NewDebugCommand() *cobra.Command {
 		newNotificationsCommand(),
 		newDumpCommand(),
 		newCSVCommand(),
+		newLocalCommand(),
 	)
+	addDLQCommand(debugCmd)
 
 	return debugCmd
 }
@@ -14,7 +14,7 @@ func newS3Command() *cobra.Command {
 		Use:   "s3",
 		Short: "Debug S3 bucket configuration",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -57,7 +57,7 @@ func newS3Command() *cobra.Command {
 		Long:  "List files in document S3 bucket. Optionally specify a prefix to filter results",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
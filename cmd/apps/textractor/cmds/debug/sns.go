@@ -21,6 +21,13 @@ func newSNSCommand() *cobra.Command {
 			fmt.Printf("Main Topic: %s\n", resources.SNSTopic)
 			fmt.Printf("Notification Topic: %s\n", resources.NotificationTopic)
 
+			if dir := localDir(cmd); dir != "" {
+				if err := printLocalTopics(dir, resources); err != nil {
+					log.Fatalf("Failed to inspect local stand-in: %v", err)
+				}
+				return
+			}
+
 			// Get topic attributes
 			err = runAWSCommand("sns", "get-topic-attributes",
 				"--topic-arn", resources.SNSTopic)
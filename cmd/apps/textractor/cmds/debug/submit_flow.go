@@ -13,7 +13,7 @@ func addSubmitFlowCommand(debugCmd *cobra.Command) {
 		Use:   "submit-flow",
 		Short: "Debug submit command flow",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -16,7 +16,7 @@ func newTestCommand() *cobra.Command {
 		Use:   "test",
 		Short: "Run end-to-end tests",
 		Run: func(cmd *cobra.Command, args []string) {
-			resources, err := LoadResources(cmd)
+			resources, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
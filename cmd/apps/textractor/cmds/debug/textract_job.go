@@ -14,7 +14,7 @@ func addTextractJobCommand(debugCmd *cobra.Command) {
 		Long:  "Shows the current status and details of a Textract document analysis job",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			_, err := LoadResources(cmd)
+			_, err := LoadAWSResources(cmd)
 			if err != nil {
 				log.Fatalf("Failed to load resources: %v", err)
 			}
@@ -1,7 +1,9 @@
 package debug
 
 import (
+	"fmt"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/local"
 	"log"
 	"os/exec"
 
@@ -16,8 +18,27 @@ func runAWSCommand(args ...string) error {
 	return cmd.Run()
 }
 
-// LoadResources loads the Textractor resources using the state loader
+// localDir returns the directory of the local stand-in if --local is set
+func localDir(cmd *cobra.Command) string {
+	dir, _ := cmd.Flags().GetString("local")
+	return dir
+}
+
+// LoadResources loads the Textractor resources using the state loader, or
+// the resources of the local stand-in if --local is set
 func LoadResources(cmd *cobra.Command) (*pkg.TextractorResources, error) {
+	if dir := localDir(cmd); dir != "" {
+		return local.Resources(dir), nil
+	}
 	stateLoader := pkg.NewStateLoader()
 	return stateLoader.LoadStateFromCommand(cmd)
 }
+
+// LoadAWSResources is LoadResources for commands that only work against the
+// deployed resources, such as the ones reading CloudWatch or CloudTrail
+func LoadAWSResources(cmd *cobra.Command) (*pkg.TextractorResources, error) {
+	if localDir(cmd) != "" {
+		return nil, fmt.Errorf("%s inspects the deployed AWS resources and has no local stand-in, use 'debug local'", cmd.CommandPath())
+	}
+	return LoadResources(cmd)
+}
@@ -0,0 +1,217 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/extract"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+	"github.com/rs/zerolog/log"
+)
+
+type ExtractCommand struct {
+	*cmds.CommandDescription
+}
+
+type ExtractSettings struct {
+	Files      []string `glazed.parameter:"files"`
+	Extraction string   `glazed.parameter:"extraction"`
+	Report     bool     `glazed.parameter:"report"`
+}
+
+func NewExtractCommand() (*ExtractCommand, error) {
+	glazedParameterLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, fmt.Errorf("could not create Glazed parameter layer: %w", err)
+	}
+
+	return &ExtractCommand{
+		CommandDescription: cmds.NewCommandDescription(
+			"extract",
+			cmds.WithShort("Extract template fields from saved Textract results"),
+			cmds.WithLong(`Apply a YAML extraction template to many Textract results saved with
+"fetch --output". Each document becomes a row with the typed value and the
+confidence of every field, plus the missing required and invalid fields in
+columns starting with an underscore (_file, _valid, _missing, _invalid and
+_low_confidence).
+
+The template is passed with --extraction, since --template is glazed's
+output template. Directories are searched for .json files. With --report,
+one row per field sums up how often it was found, missing, invalid or below
+its confidence threshold.
+
+Example:
+  textractor extract -x templates/invoice.yaml results/ --output csv`),
+			cmds.WithFlags(
+				parameters.NewParameterDefinition(
+					"extraction",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Extraction template (YAML)"),
+					parameters.WithShortFlag("x"),
+					parameters.WithRequired(true),
+				),
+				parameters.NewParameterDefinition(
+					"report",
+					parameters.ParameterTypeBool,
+					parameters.WithHelp("Output a per-field report instead of one row per document"),
+					parameters.WithDefault(false),
+				),
+			),
+			cmds.WithArguments(
+				parameters.NewParameterDefinition(
+					"files",
+					parameters.ParameterTypeStringList,
+					parameters.WithHelp("Textract result files or directories"),
+					parameters.WithRequired(true),
+				),
+			),
+			cmds.WithLayersList(glazedParameterLayer),
+		),
+	}, nil
+}
+
+func (c *ExtractCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	s := &ExtractSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	tmpl, err := extract.LoadTemplate(s.Extraction)
+	if err != nil {
+		return err
+	}
+
+	files, err := resultFiles(s.Files)
+	if err != nil {
+		return err
+	}
+
+	report := extract.NewReport(tmpl)
+	for _, file := range files {
+		docs, err := parser.LoadFromJSON(file)
+		if err != nil {
+			// One broken file shouldn't stop a batch
+			log.Error().Err(err).Str("file", file).Msg("Failed to load results")
+			continue
+		}
+
+		for i, doc := range docs {
+			name := file
+			if len(docs) > 1 {
+				name = fmt.Sprintf("%s#%d", file, i+1)
+			}
+			result := extract.Extract(doc, tmpl)
+			report.Add(name, result)
+
+			if s.Report {
+				continue
+			}
+			if err := gp.AddRow(ctx, extractionRow(name, tmpl, result)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !s.Report {
+		return nil
+	}
+	for _, field := range report.Fields() {
+		row := types.NewRow(
+			types.MRP("field", field.Field),
+			types.MRP("required", field.Required),
+			types.MRP("documents", report.Documents),
+			types.MRP("found", field.Found),
+			types.MRP("missing", field.Missing),
+			types.MRP("invalid", field.Invalid),
+			types.MRP("low_confidence", field.LowConfidence),
+			types.MRP("avg_confidence", roundConfidence(field.AverageConfidence)),
+			types.MRP("missing_in", strings.Join(field.MissingIn, ", ")),
+			types.MRP("invalid_in", strings.Join(field.InvalidIn, ", ")),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractionRow has a value and a confidence column per field. The summary
+// columns start with an underscore, which field names can't, so that a field
+// named e.g. missing doesn't overwrite them.
+func extractionRow(name string, tmpl *extract.Template, result *extract.Result) types.Row {
+	row := types.NewRow(types.MRP("_file", name))
+
+	var invalid, lowConfidence []string
+	for _, v := range result.Values {
+		value := v.Value
+		if t, ok := value.(time.Time); ok {
+			value = t.Format("2006-01-02")
+		}
+		row.Set(v.Field, value)
+		if !v.Missing {
+			row.Set(v.Field+"_confidence", roundConfidence(v.Confidence))
+		} else {
+			row.Set(v.Field+"_confidence", nil)
+		}
+
+		if v.Error != "" {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", v.Field, v.Error))
+		}
+		if v.LowConfidence {
+			lowConfidence = append(lowConfidence, v.Field)
+		}
+	}
+
+	row.Set("_valid", result.Valid(tmpl))
+	row.Set("_missing", strings.Join(result.MissingRequired(tmpl), ", "))
+	row.Set("_invalid", strings.Join(invalid, "; "))
+	row.Set("_low_confidence", strings.Join(lowConfidence, ", "))
+	return row
+}
+
+func roundConfidence(c float64) float64 {
+	return float64(int(c*10+0.5)) / 10
+}
+
+// resultFiles expands directories to the .json files they contain
+func resultFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".json") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
@@ -13,9 +13,6 @@ import (
 	"github.com/go-go-golems/glazed/pkg/settings"
 	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 type ListCommand struct {
@@ -25,9 +22,11 @@ type ListCommand struct {
 type ListSettings struct {
 	Since time.Time `glazed.parameter:"since"`
 
-	Status string `glazed.parameter:"status"`
-	TfDir  string `glazed.parameter:"tf-dir"`
-	Config string `glazed.parameter:"config"`
+	Status        string `glazed.parameter:"status"`
+	TfDir         string `glazed.parameter:"tf-dir"`
+	Config        string `glazed.parameter:"config"`
+	Local         string `glazed.parameter:"local"`
+	LocalFixtures string `glazed.parameter:"local-fixtures"`
 }
 
 func NewListCommand() (*ListCommand, error) {
@@ -61,6 +60,17 @@ func NewListCommand() (*ListCommand, error) {
 					parameters.ParameterTypeString,
 					parameters.WithHelp("Config file"),
 				),
+				parameters.NewParameterDefinition(
+					"local",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Use the local stand-in stored in this directory instead of AWS"),
+				),
+				parameters.NewParameterDefinition(
+					"local-fixtures",
+					parameters.ParameterTypeString,
+					parameters.WithHelp("Directory with the Textract responses of the local stand-in"),
+					parameters.WithDefault(DefaultLocalFixtures),
+				),
 			),
 			cmds.WithLayersList(glazedParameterLayer),
 		),
@@ -77,17 +87,16 @@ func (c *ListCommand) RunIntoGlazeProcessor(
 		return err
 	}
 
-	stateLoader := pkg.NewStateLoader()
-	resources, err := stateLoader.LoadState(s.TfDir, s.Config)
+	backend, err := OpenBackend(BackendSettings{
+		TfDir:         s.TfDir,
+		Config:        s.Config,
+		Local:         s.Local,
+		LocalFixtures: s.LocalFixtures,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to load terraform state: %w", err)
+		return err
 	}
-
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(resources.Region),
-	}))
-
-	jobClient := pkg.NewJobClient(sess, resources.JobsTable)
+	defer backend.Close()
 
 	var opts pkg.ListJobsOptions
 	if !s.Since.IsZero() {
@@ -95,7 +104,7 @@ func (c *ListCommand) RunIntoGlazeProcessor(
 	}
 	opts.Status = s.Status
 
-	jobs, err := jobClient.ListJobs(opts)
+	jobs, err := backend.Jobs.ListJobs(opts)
 	if err != nil {
 		return fmt.Errorf("failed to list jobs: %w", err)
 	}
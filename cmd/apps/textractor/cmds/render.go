@@ -0,0 +1,214 @@
+package cmds
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/render"
+	"github.com/spf13/cobra"
+)
+
+func NewRenderCommand() *cobra.Command {
+	var (
+		outputFormat  string
+		outputFile    string
+		documentIndex int
+		reflow        bool
+		noPageMarkers bool
+		images        string
+		embedImages   bool
+		lowConfidence float64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render [result.json]",
+		Short: "Render a saved Textract result as markdown, CSV, form JSON or an HTML overlay",
+		Long: `Render a Textract result saved with "fetch --output" without AWS access.
+
+Formats:
+  markdown  text in reading order with headings and tables
+  csv       tables as CSV, one file per table in the --output directory
+  forms     form fields as JSON
+  html      bounding boxes over the page images, for checking results`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			docs, err := parser.LoadFromJSON(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load results: %w", err)
+			}
+			if documentIndex > 0 {
+				if documentIndex > len(docs) {
+					return fmt.Errorf("document %d not found, file has %d", documentIndex, len(docs))
+				}
+				docs = docs[documentIndex-1 : documentIndex]
+			}
+
+			if outputFormat == "csv" {
+				return renderTablesCSV(docs, outputFile)
+			}
+
+			var out io.Writer = os.Stdout
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			switch outputFormat {
+			case "markdown", "md":
+				opts := render.DefaultMarkdownOptions()
+				opts.ReflowParagraphs = reflow
+				opts.PageMarkers = !noPageMarkers
+				for i, doc := range docs {
+					if i > 0 {
+						fmt.Fprint(out, "\n---\n\n")
+					}
+					if err := render.WriteMarkdown(out, doc, opts); err != nil {
+						return err
+					}
+				}
+
+			case "forms":
+				if err := render.WriteFormsJSON(out, docs...); err != nil {
+					return err
+				}
+
+			case "html":
+				if len(docs) != 1 {
+					return fmt.Errorf("file has %d documents, pick one with --document", len(docs))
+				}
+				opts := render.DefaultHTMLOptions()
+				opts.Title = filepath.Base(args[0])
+				opts.LowConfidence = lowConfidence
+				if images != "" {
+					opts.Images, err = pageImages(images, docs[0].PageCount(), outputFile, embedImages)
+					if err != nil {
+						return err
+					}
+				}
+				if err := render.WriteHTML(out, docs[0], opts); err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unsupported output format: %s", outputFormat)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "markdown", "Output format (markdown/csv/forms/html)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path, or directory for csv")
+	cmd.Flags().IntVar(&documentIndex, "document", 0, "Render only this document of a multi-document file (1-based)")
+	cmd.Flags().BoolVar(&reflow, "reflow", false, "Join the lines of a paragraph instead of keeping line breaks")
+	cmd.Flags().BoolVar(&noPageMarkers, "no-page-markers", false, "Don't mark page starts in markdown")
+	cmd.Flags().StringVar(&images, "images", "", "Page images for html, a path with %d for the page number (e.g. scan-%d.png)")
+	cmd.Flags().BoolVar(&embedImages, "embed-images", false, "Embed page images in the HTML file")
+	cmd.Flags().Float64Var(&lowConfidence, "low-confidence", 80, "Highlight boxes below this confidence in html")
+	return cmd
+}
+
+// renderTablesCSV writes each table to its own file in dir, or all tables to
+// stdout separated by blank lines if dir is empty
+func renderTablesCSV(docs []parser.Document, dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	first := true
+	for d, doc := range docs {
+		for _, page := range doc.Pages() {
+			for t, table := range page.Tables() {
+				if dir == "" {
+					if !first {
+						fmt.Println()
+					}
+					first = false
+					if err := render.WriteTableCSV(os.Stdout, table); err != nil {
+						return err
+					}
+					continue
+				}
+
+				name := fmt.Sprintf("page-%d-table-%d.csv", page.Number(), t+1)
+				if len(docs) > 1 {
+					name = fmt.Sprintf("document-%d-%s", d+1, name)
+				}
+				f, err := os.Create(filepath.Join(dir, name))
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", name, err)
+				}
+				err = render.WriteTableCSV(f, table)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write %s: %w", name, err)
+				}
+				fmt.Fprintf(os.Stderr, "Wrote %s\n", filepath.Join(dir, name))
+			}
+		}
+	}
+
+	return nil
+}
+
+// pageImages resolves the image of each page from a pattern. Images are
+// referenced relative to the output file, or embedded as data URLs.
+func pageImages(pattern string, pages int, outputFile string, embed bool) (map[int]template.URL, error) {
+	images := make(map[int]template.URL)
+	for page := 1; page <= pages; page++ {
+		path := pattern
+		if strings.Contains(pattern, "%") {
+			path = fmt.Sprintf(pattern, page)
+		} else if pages > 1 {
+			return nil, fmt.Errorf("--images needs a %%d placeholder for a document with %d pages", pages)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("image for page %d: %w", page, err)
+		}
+
+		if embed {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read image %s: %w", path, err)
+			}
+			mimeType := mime.TypeByExtension(filepath.Ext(path))
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			images[page] = template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+			continue
+		}
+
+		if outputFile != "" {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			outDir, err := filepath.Abs(filepath.Dir(outputFile))
+			if err != nil {
+				return nil, err
+			}
+			if rel, err := filepath.Rel(outDir, abs); err == nil {
+				path = rel
+			}
+		}
+		images[page] = template.URL(filepath.ToSlash(path))
+	}
+	return images, nil
+}
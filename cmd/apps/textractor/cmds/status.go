@@ -3,11 +3,6 @@ package cmds
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
 	"github.com/spf13/cobra"
 )
 
@@ -21,42 +16,15 @@ func NewStatusCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			jobID := args[0]
 
-			// Load resources
-			stateLoader := pkg.NewStateLoader()
-			resources, err := stateLoader.LoadStateFromCommand(cmd)
+			backend, err := openBackendFromCommand(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to load terraform state: %w", err)
+				return err
 			}
+			defer backend.Close()
 
-			// Initialize AWS session
-			sess := session.Must(session.NewSession(&aws.Config{
-				Region: aws.String(resources.Region),
-			}))
-
-			// Create DynamoDB client
-			dbClient := dynamodb.New(sess)
-
-			// Get job details
-			result, err := dbClient.GetItem(&dynamodb.GetItemInput{
-				TableName: aws.String(resources.JobsTable),
-				Key: map[string]*dynamodb.AttributeValue{
-					"JobID": {
-						S: aws.String(jobID),
-					},
-				},
-			})
-			if err != nil {
-				return fmt.Errorf("failed to get job status: %w", err)
-			}
-
-			if result.Item == nil {
-				return fmt.Errorf("job %s not found", jobID)
-			}
-
-			var job pkg.TextractJob
-			err = dynamodbattribute.UnmarshalMap(result.Item, &job)
+			job, err := backend.Jobs.GetJob(jobID)
 			if err != nil {
-				return fmt.Errorf("failed to unmarshal job data: %w", err)
+				return err
 			}
 
 			// Display job information based on format
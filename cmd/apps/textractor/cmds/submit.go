@@ -7,20 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 const (
-	StateUploading = "UPLOADING"
-	StateSubmitted = "SUBMITTED"
-	StateError     = "ERROR"
+	StateUploading = pkg.JobStatusUploading
+	StateSubmitted = pkg.JobStatusSubmitted
+	StateError     = pkg.JobStatusError
 
 	supportedExtensions = ".pdf,.png,.jpg,.jpeg"
 )
@@ -32,39 +26,34 @@ func NewSubmitCommand() *cobra.Command {
 		Use:   "submit [file/directory]",
 		Short: "Submit a PDF file or directory for processing",
 		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			path := args[0]
 
-			// Load resources
-			stateLoader := pkg.NewStateLoader()
-			resources, err := stateLoader.LoadStateFromCommand(cmd)
-			if err != nil {
-				return fmt.Errorf("failed to load terraform state: %w", err)
-			}
-
-			// Initialize AWS session
-			sess := session.Must(session.NewSession(&aws.Config{
-				Region: aws.String(resources.Region),
-			}))
-
-			// Create service clients
-			s3Client := s3.New(sess)
-			dbClient := dynamodb.New(sess)
-
 			// Handle directory vs file
 			fileInfo, err := os.Stat(path)
 			if err != nil {
 				return fmt.Errorf("failed to stat path %s: %w", path, err)
 			}
+			if fileInfo.IsDir() && !recursive {
+				return fmt.Errorf("path is a directory, use --recursive to process directories")
+			}
 
-			if fileInfo.IsDir() {
-				if !recursive {
-					return fmt.Errorf("path is a directory, use --recursive to process directories")
+			backend, err := openBackendFromCommand(cmd)
+			if err != nil {
+				return err
+			}
+			// In local mode closing runs the pipeline on what was submitted
+			defer func() {
+				if closeErr := backend.Close(); err == nil {
+					err = closeErr
 				}
-				return submitDirectory(path, resources, s3Client, dbClient)
+			}()
+
+			if fileInfo.IsDir() {
+				return submitDirectory(path, backend)
 			}
 
-			return submitFile(path, resources, s3Client, dbClient)
+			return submitFile(path, backend)
 		},
 	}
 
@@ -72,14 +61,14 @@ func NewSubmitCommand() *cobra.Command {
 	return cmd
 }
 
-func submitDirectory(dirPath string, resources *pkg.TextractorResources, s3Client *s3.S3, dbClient *dynamodb.DynamoDB) error {
+func submitDirectory(dirPath string, backend *pkg.Backend) error {
 	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if !info.IsDir() && isValidFileType(strings.ToLower(filepath.Ext(path))) {
-			if err := submitFile(path, resources, s3Client, dbClient); err != nil {
+			if err := submitFile(path, backend); err != nil {
 				log.Printf("Error processing %s: %v", path, err)
 				return nil // Continue with next file
 			}
@@ -88,65 +77,16 @@ func submitDirectory(dirPath string, resources *pkg.TextractorResources, s3Clien
 	})
 }
 
-func submitFile(filePath string, resources *pkg.TextractorResources, s3Client *s3.S3, dbClient *dynamodb.DynamoDB) error {
+func submitFile(filePath string, backend *pkg.Backend) error {
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(filePath))
 	if !isValidFileType(ext) {
 		return fmt.Errorf("unsupported file type %s. Supported types: %s", ext, supportedExtensions)
 	}
 
-	// Generate unique job ID and S3 key
-	jobID := uuid.New().String()
-	s3Key := fmt.Sprintf("input/%s/%s", jobID, filepath.Base(filePath))
-
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(resources.Region),
-	}))
-
-	jobClient := pkg.NewJobClient(sess, resources.JobsTable)
-
-	// Create initial job record
-	err := jobClient.CreateJob(pkg.TextractJob{
-		JobID:       jobID,
-		DocumentKey: s3Key,
-		Status:      StateUploading,
-		SubmittedAt: time.Now(),
-		TextractID:  "PENDING",
-		ResultKey:   "PENDING",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create initial job record: %w", err)
-	}
-
-	// Open file
-	file, err := os.Open(filePath)
-	if err != nil {
-		if err := jobClient.UpdateJobStatus(jobID, StateError, fmt.Sprintf("Failed to open file: %v", err)); err != nil {
-			log.Printf("Failed to update job status: %v", err)
-		}
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	// Upload to S3
-	_, err = s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(resources.DocumentS3Bucket),
-		Key:         aws.String(s3Key),
-		Body:        file,
-		ContentType: aws.String("application/pdf"),
-	})
+	jobID, err := pkg.SubmitFile(backend, filePath)
 	if err != nil {
-		if err := jobClient.UpdateJobStatus(jobID, StateError, fmt.Sprintf("Failed to upload to S3: %v", err)); err != nil {
-			log.Printf("Failed to update job status: %v", err)
-		}
-		return fmt.Errorf("failed to upload to S3: %w", err)
-	}
-
-	// Update job status
-	if err := jobClient.UpdateJobStatus(jobID, StateSubmitted, ""); err != nil {
-		log.Printf("Warning: Failed to update job status to SUBMITTED: %v", err)
+		return err
 	}
 
 	fmt.Printf("Successfully submitted job %s for %s\n", jobID, filepath.Base(filePath))
@@ -46,6 +46,8 @@ func main() {
 	// Add persistent flags to root command
 	rootCmd.PersistentFlags().String("tf-dir", "terraform", "Directory containing Terraform state")
 	rootCmd.PersistentFlags().String("config", "", "JSON config file containing resource configuration")
+	rootCmd.PersistentFlags().String("local", "", "Run against a local stand-in stored in this directory instead of AWS")
+	rootCmd.PersistentFlags().String("local-fixtures", cmds.DefaultLocalFixtures, "Directory with the Textract responses of the local stand-in")
 	rootCmd.PersistentFlags().String("log-level", "info", "Set the logging level (debug, info, warn, error, fatal)")
 
 	// Initialize list command with glazed support
@@ -59,12 +61,23 @@ func main() {
 	}
 	rootCmd.AddCommand(cobraListCmd)
 
+	extractCmd, err := cmds.NewExtractCommand()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create extract command")
+	}
+	cobraExtractCmd, err := cli.BuildCobraCommandFromGlazeCommand(extractCmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to build cobra extract command")
+	}
+	rootCmd.AddCommand(cobraExtractCmd)
+
 	// Add other commands
 	rootCmd.AddCommand(debug.NewDebugCommand())
 	rootCmd.AddCommand(newSaveConfigCommand())
 	rootCmd.AddCommand(cmds.NewSubmitCommand())
 	rootCmd.AddCommand(cmds.NewStatusCommand())
 	rootCmd.AddCommand(cmds.NewFetchCommand())
+	rootCmd.AddCommand(cmds.NewRenderCommand())
 
 	addDebugVarCommands(rootCmd, "terraform")
 
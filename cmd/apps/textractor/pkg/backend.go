@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/textract"
+)
+
+// JobStore is the jobs table. JobClient implements it on DynamoDB, local
+// mode on SQLite.
+type JobStore interface {
+	CreateJob(job TextractJob) error
+	UpdateJobStatus(jobID string, status string, errorMsg string) error
+	GetJob(jobID string) (*TextractJob, error)
+	ListJobs(opts ListJobsOptions) ([]TextractJob, error)
+}
+
+// ObjectStore is the part of S3 the commands use
+type ObjectStore interface {
+	PutObject(bucket, key string, body io.ReadSeeker, contentType string) error
+	GetObject(bucket, key string) ([]byte, error)
+	// ListObjects returns the keys under prefix in lexical order
+	ListObjects(bucket, prefix string) ([]string, error)
+}
+
+// DocumentAnalysis is the combined output of a Textract document analysis
+type DocumentAnalysis struct {
+	DocumentMetadata *textract.DocumentMetadata `json:"DocumentMetadata"`
+	Blocks           []*textract.Block          `json:"Blocks"`
+}
+
+// AnalysisClient fetches results straight from Textract
+type AnalysisClient interface {
+	GetDocumentAnalysis(textractID string) (*DocumentAnalysis, error)
+}
+
+// Backend bundles the services the commands talk to, either the deployed
+// AWS resources or the local stand-in
+type Backend struct {
+	Resources *TextractorResources
+	Jobs      JobStore
+	Objects   ObjectStore
+	Analysis  AnalysisClient
+
+	closer func() error
+}
+
+// NewBackend creates a backend from its parts. close is called by Close and
+// may be nil.
+func NewBackend(resources *TextractorResources, jobs JobStore, objects ObjectStore, analysis AnalysisClient, close func() error) *Backend {
+	return &Backend{
+		Resources: resources,
+		Jobs:      jobs,
+		Objects:   objects,
+		Analysis:  analysis,
+		closer:    close,
+	}
+}
+
+// NewAWSBackend creates a backend for the deployed resources
+func NewAWSBackend(resources *TextractorResources) *Backend {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String(resources.Region),
+	}))
+
+	return NewBackend(
+		resources,
+		NewJobClient(sess, resources.JobsTable),
+		&s3Objects{client: s3.New(sess)},
+		&textractAnalysis{client: textract.New(sess)},
+		nil,
+	)
+}
+
+// Close releases the backend. For the local stand-in this waits until the
+// pipeline has processed everything that was submitted.
+func (b *Backend) Close() error {
+	if b.closer == nil {
+		return nil
+	}
+	return b.closer()
+}
+
+type s3Objects struct {
+	client *s3.S3
+}
+
+func (o *s3Objects) PutObject(bucket, key string, body io.ReadSeeker, contentType string) error {
+	_, err := o.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (o *s3Objects) GetObject(bucket, key string) ([]byte, error) {
+	output, err := o.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+func (o *s3Objects) ListObjects(bucket, prefix string) ([]string, error) {
+	var keys []string
+	err := o.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+type textractAnalysis struct {
+	client *textract.Textract
+}
+
+func (t *textractAnalysis) GetDocumentAnalysis(textractID string) (*DocumentAnalysis, error) {
+	analysis := &DocumentAnalysis{}
+	var nextToken *string
+
+	for {
+		result, err := t.client.GetDocumentAnalysis(&textract.GetDocumentAnalysisInput{
+			JobId:     aws.String(textractID),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document analysis: %w", err)
+		}
+
+		// Capture first DocumentMetadata we see
+		if analysis.DocumentMetadata == nil && result.DocumentMetadata != nil {
+			analysis.DocumentMetadata = result.DocumentMetadata
+		}
+		analysis.Blocks = append(analysis.Blocks, result.Blocks...)
+
+		nextToken = result.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+
+	return analysis, nil
+}
+
+// ReadResults reads the Textract output files of a completed job from the
+// output bucket and merges them into one result. Hidden files like the
+// .s3_access_check Textract writes are skipped.
+func ReadResults(b *Backend, job *TextractJob) (*DocumentAnalysis, error) {
+	keys, err := b.Objects.ListObjects(b.Resources.OutputS3Bucket, job.ResultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results: %w", err)
+	}
+
+	analysis := &DocumentAnalysis{}
+	found := false
+	for _, key := range keys {
+		relativeKey := strings.TrimPrefix(key, job.ResultKey+"/")
+		if strings.HasPrefix(relativeKey, ".") {
+			continue
+		}
+
+		data, err := b.Objects.GetObject(b.Resources.OutputS3Bucket, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object %s: %w", key, err)
+		}
+
+		var part DocumentAnalysis
+		if err := json.NewDecoder(bytes.NewReader(data)).Decode(&part); err != nil {
+			return nil, fmt.Errorf("failed to parse results in %s: %w", key, err)
+		}
+		if analysis.DocumentMetadata == nil && part.DocumentMetadata != nil {
+			analysis.DocumentMetadata = part.DocumentMetadata
+		}
+		analysis.Blocks = append(analysis.Blocks, part.Blocks...)
+		found = true
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no results found for job %s", job.JobID)
+	}
+	return analysis, nil
+}
@@ -0,0 +1,104 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Buckets stores objects as files under root/<bucket>/<key>. It implements
+// pkg.ObjectStore.
+type Buckets struct {
+	root string
+	// onPut is called after an object is written, like an S3 event
+	// notification
+	onPut func(bucket, key string, size int64)
+}
+
+func NewBuckets(root string) *Buckets {
+	return &Buckets{root: root}
+}
+
+func (b *Buckets) path(bucket, key string) (string, error) {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	p := filepath.Join(b.root, bucket, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Join(b.root, bucket)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return p, nil
+}
+
+func (b *Buckets) PutObject(bucket, key string, body io.ReadSeeker, contentType string) error {
+	p, err := b.path(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return fmt.Errorf("failed to create object %s: %w", key, err)
+	}
+	size, err := io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+
+	if b.onPut != nil {
+		b.onPut(bucket, key, size)
+	}
+	return nil
+}
+
+func (b *Buckets) GetObject(bucket, key string) ([]byte, error) {
+	p, err := b.path(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("NoSuchKey: %s/%s", bucket, key)
+	}
+	return data, err
+}
+
+func (b *Buckets) ListObjects(bucket, prefix string) ([]string, error) {
+	dir := filepath.Join(b.root, bucket)
+	var keys []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", bucket, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
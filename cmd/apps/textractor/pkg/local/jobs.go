@@ -0,0 +1,174 @@
+package local
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const jobsSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	job_id TEXT PRIMARY KEY,
+	document_key TEXT NOT NULL,
+	status TEXT NOT NULL,
+	submitted_at TEXT NOT NULL,
+	completed_at TEXT,
+	textract_id TEXT NOT NULL DEFAULT '',
+	result_key TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_submitted_at ON jobs(submitted_at);
+`
+
+// timeFormat is fixed width so that times compare as strings
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// JobStore is the jobs table in SQLite. It implements pkg.JobStore.
+type JobStore struct {
+	db *sql.DB
+}
+
+// OpenJobStore opens or creates the jobs database at path
+func OpenJobStore(path string) (*JobStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jobs database: %w", err)
+	}
+	if _, err := db.Exec(jobsSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+	return &JobStore{db: db}, nil
+}
+
+func (s *JobStore) Close() error {
+	return s.db.Close()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// CreateJob writes a job record, replacing an existing one like PutItem
+func (s *JobStore) CreateJob(job pkg.TextractJob) error {
+	var completedAt interface{}
+	if job.CompletedAt != nil {
+		completedAt = formatTime(*job.CompletedAt)
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO jobs
+		(job_id, document_key, status, submitted_at, completed_at, textract_id, result_key, error, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.JobID, job.DocumentKey, job.Status, formatTime(job.SubmittedAt), completedAt,
+		job.TextractID, job.ResultKey, job.Error, formatTime(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to create job record: %w", err)
+	}
+	return nil
+}
+
+func (s *JobStore) UpdateJobStatus(jobID string, status string, errorMsg string) error {
+	return s.UpdateJob(jobID, status, func(job *pkg.TextractJob) {
+		job.Error = errorMsg
+	})
+}
+
+// UpdateJob sets the status of a job and lets update change the other
+// fields, like the processors' updateJobStatus with additional data
+func (s *JobStore) UpdateJob(jobID string, status string, update func(job *pkg.TextractJob)) error {
+	job, err := s.GetJob(jobID)
+	if err != nil {
+		return err
+	}
+
+	job.Status = status
+	if update != nil {
+		update(job)
+	}
+
+	var completedAt interface{}
+	if job.CompletedAt != nil {
+		completedAt = formatTime(*job.CompletedAt)
+	}
+
+	_, err = s.db.Exec(`UPDATE jobs SET status = ?, completed_at = ?, textract_id = ?, result_key = ?, error = ?, updated_at = ?
+		WHERE job_id = ?`,
+		job.Status, completedAt, job.TextractID, job.ResultKey, job.Error, formatTime(time.Now()), jobID)
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+func (s *JobStore) GetJob(jobID string) (*pkg.TextractJob, error) {
+	rows, err := s.query("WHERE job_id = ?", jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job details: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+	return &rows[0], nil
+}
+
+func (s *JobStore) ListJobs(opts pkg.ListJobsOptions) ([]pkg.TextractJob, error) {
+	var where []string
+	var args []interface{}
+	if opts.Since != nil {
+		where = append(where, "submitted_at >= ?")
+		args = append(args, formatTime(*opts.Since))
+	}
+	if opts.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, opts.Status)
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+	jobs, err := s.query(clause+" ORDER BY submitted_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func (s *JobStore) query(clause string, args ...interface{}) ([]pkg.TextractJob, error) {
+	rows, err := s.db.Query(`SELECT job_id, document_key, status, submitted_at, completed_at, textract_id, result_key, error
+		FROM jobs `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []pkg.TextractJob
+	for rows.Next() {
+		var job pkg.TextractJob
+		var submittedAt string
+		var completedAt sql.NullString
+		if err := rows.Scan(&job.JobID, &job.DocumentKey, &job.Status, &submittedAt, &completedAt,
+			&job.TextractID, &job.ResultKey, &job.Error); err != nil {
+			return nil, err
+		}
+
+		job.SubmittedAt, err = time.Parse(timeFormat, submittedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid submitted_at for job %s: %w", job.JobID, err)
+		}
+		if completedAt.Valid {
+			t, err := time.Parse(timeFormat, completedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("invalid completed_at for job %s: %w", job.JobID, err)
+			}
+			job.CompletedAt = &t
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,324 @@
+// Package local runs the Textractor pipeline in-process, for tests and for
+// trying the CLI without an AWS account. S3 buckets are directories, the
+// jobs table is SQLite, SQS queues and SNS topics are in memory and
+// Textract answers with fixture JSON.
+//
+// Everything that has to outlive a command, the jobs, the objects and the
+// notifications, is stored in the local directory. Queued messages are
+// processed when the stack is drained, which the CLI does before exiting,
+// so a submit runs the whole pipeline and the next status or fetch sees
+// the result.
+package local
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	documentBucket = "textractor-documents"
+	outputBucket   = "textractor-output"
+
+	notificationsFile = "notifications.jsonl"
+	deadLettersFile   = "dead-letters.jsonl"
+)
+
+// Options configures a local stack
+type Options struct {
+	// Dir holds the buckets, the jobs database and the notification log
+	Dir string
+	// Fixtures is the directory with the Textract responses to return
+	Fixtures string
+	// BlocksPerFile splits the Textract output into several files, 1000 by
+	// default like Textract
+	BlocksPerFile int
+}
+
+// Stack is the local stand-in for the deployed resources
+type Stack struct {
+	opts      Options
+	resources *pkg.TextractorResources
+
+	jobs     *JobStore
+	buckets  *Buckets
+	textract *Textract
+
+	input         *Queue
+	analysis      *Queue
+	completion    *Queue
+	notifications *Queue
+
+	mu sync.Mutex
+}
+
+// Resources returns the resource names of a local stack in dir. They only
+// identify the local stand-ins and can't be used with AWS.
+func Resources(dir string) *pkg.TextractorResources {
+	queueURL := func(name string) string { return "local://queue/" + name }
+	return &pkg.TextractorResources{
+		DocumentS3Bucket:        documentBucket,
+		DocumentS3BucketARN:     "arn:local:s3:::" + documentBucket,
+		OutputS3Bucket:          outputBucket,
+		OutputS3BucketARN:       "arn:local:s3:::" + outputBucket,
+		InputQueue:              queueURL("input"),
+		CompletionQueue:         queueURL("completion"),
+		NotificationsQueue:      queueURL("notifications"),
+		InputDLQURL:             queueURL("input-dlq"),
+		CompletionDLQURL:        queueURL("completion-dlq"),
+		SNSTopic:                "arn:local:sns:textract-completion",
+		DocumentProcessorName:   "document-processor",
+		DocumentProcessorARN:    "arn:local:lambda:document-processor",
+		CompletionProcessorName: "completion-processor",
+		CompletionProcessorARN:  "arn:local:lambda:completion-processor",
+		Region:                  "local",
+		JobsTable:               filepath.Join(dir, "jobs.db"),
+		NotificationTopic:       "arn:local:sns:notifications",
+	}
+}
+
+// Open creates the local directory if needed and starts a stack on it
+func Open(opts Options) (*Stack, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("local directory is required")
+	}
+	if opts.BlocksPerFile <= 0 {
+		opts.BlocksPerFile = defaultBlocksPerFile
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local directory: %w", err)
+	}
+
+	resources := Resources(opts.Dir)
+	jobs, err := OpenJobStore(resources.JobsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stack{
+		opts:      opts,
+		resources: resources,
+		jobs:      jobs,
+		buckets:   NewBuckets(filepath.Join(opts.Dir, "buckets")),
+	}
+	s.input = newQueue("input", s.processDocument)
+	s.completion = newQueue("completion", s.processCompletion)
+	s.notifications = newQueue("notifications", nil)
+
+	s.textract = &Textract{
+		fixtures:      opts.Fixtures,
+		blocksPerFile: opts.BlocksPerFile,
+		buckets:       s.buckets,
+		outputBucket:  resources.OutputS3Bucket,
+		publish: func(message string) {
+			s.completion.Send(newSNSEnvelope(resources.SNSTopic, message))
+		},
+	}
+	s.analysis = newQueue("textract", s.textract.run)
+	s.textract.jobs = s.analysis
+
+	// The document bucket notifies the input queue of new uploads
+	s.buckets.onPut = func(bucket, key string, size int64) {
+		if bucket == resources.DocumentS3Bucket && strings.HasPrefix(key, "input/") {
+			s.input.Send(newS3Event(bucket, key, size))
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Stack) Resources() *pkg.TextractorResources {
+	return s.resources
+}
+
+func (s *Stack) Jobs() *JobStore {
+	return s.jobs
+}
+
+func (s *Stack) Buckets() *Buckets {
+	return s.buckets
+}
+
+func (s *Stack) Textract() *Textract {
+	return s.textract
+}
+
+// Queues returns the input, textract, completion and notifications queues
+func (s *Stack) Queues() []*Queue {
+	return []*Queue{s.input, s.analysis, s.completion, s.notifications}
+}
+
+// Notifications receives the messages waiting on the notifications queue
+func (s *Stack) Notifications() ([]Notification, error) {
+	var notifications []Notification
+	for _, m := range s.notifications.Receive() {
+		var envelope snsEnvelope
+		if err := json.Unmarshal([]byte(m.Body), &envelope); err != nil {
+			return nil, err
+		}
+		var n Notification
+		if err := json.Unmarshal([]byte(envelope.Message), &n); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// Drain delivers queued messages until all queues with a consumer are
+// empty. Messages that keep failing end up in the dead letters and in
+// dead-letters.jsonl.
+func (s *Stack) Drain() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		delivered := false
+		for _, q := range s.Queues() {
+			deadLetter, ok := q.deliver()
+			if !ok {
+				continue
+			}
+			delivered = true
+			if deadLetter != nil {
+				log.Warn().Str("queue", q.Name).Str("error", deadLetter.Error).Msg("Message moved to dead letter queue")
+				err := appendJSONLine(filepath.Join(s.opts.Dir, deadLettersFile), DeadLetter{
+					Queue:     q.Name,
+					Body:      deadLetter.Body,
+					Receives:  deadLetter.Receives,
+					Error:     deadLetter.Error,
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if !delivered {
+			return nil
+		}
+	}
+}
+
+// Close drains the stack and closes the jobs database
+func (s *Stack) Close() error {
+	err := s.Drain()
+	if closeErr := s.jobs.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Backend returns the stack as a backend for the commands. Closing the
+// backend closes the stack.
+func (s *Stack) Backend() *pkg.Backend {
+	return pkg.NewBackend(s.resources, s.jobs, s.buckets, s.textract, s.Close)
+}
+
+// notify publishes to the notification topic. Besides the notifications
+// queue the message is appended to notifications.jsonl, since the queue is
+// gone when the command exits.
+func (s *Stack) notify(n Notification) {
+	if n.Timestamp == "" {
+		n.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal notification")
+		return
+	}
+	log.Debug().Str("job", n.JobID).Str("status", n.Status).Msg("Sending notification")
+	s.notifications.Send(newSNSEnvelope(s.resources.NotificationTopic, string(data)))
+
+	if err := appendJSONLine(filepath.Join(s.opts.Dir, notificationsFile), n); err != nil {
+		log.Error().Err(err).Msg("Failed to log notification")
+	}
+}
+
+// DeadLetter is a message that kept failing, as logged in dead-letters.jsonl
+type DeadLetter struct {
+	Queue     string `json:"queue"`
+	Body      string `json:"body"`
+	Receives  int    `json:"receives"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ReadNotifications returns the notifications logged in dir, oldest first
+func ReadNotifications(dir string) ([]Notification, error) {
+	var notifications []Notification
+	err := readJSONLines(filepath.Join(dir, notificationsFile), func(data []byte) error {
+		var n Notification
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid notification in %s: %w", notificationsFile, err)
+		}
+		notifications = append(notifications, n)
+		return nil
+	})
+	return notifications, err
+}
+
+// ReadDeadLetters returns the dead letters logged in dir, oldest first. The
+// queues themselves only live as long as a command.
+func ReadDeadLetters(dir string) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	err := readJSONLines(filepath.Join(dir, deadLettersFile), func(data []byte) error {
+		var d DeadLetter
+		if err := json.Unmarshal(data, &d); err != nil {
+			return fmt.Errorf("invalid dead letter in %s: %w", deadLettersFile, err)
+		}
+		deadLetters = append(deadLetters, d)
+		return nil
+	})
+	return deadLetters, err
+}
+
+// readJSONLines calls fn for every non-empty line of path. A missing file
+// has no lines.
+func readJSONLines(path string, fn func(data []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Dead letters carry whole message bodies
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func appendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
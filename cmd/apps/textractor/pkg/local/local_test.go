@@ -0,0 +1,269 @@
+package local
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+const fixtures = "../../test-docs/json"
+
+func openStack(t *testing.T, dir string, opts Options) *Stack {
+	t.Helper()
+	opts.Dir = dir
+	if opts.Fixtures == "" {
+		opts.Fixtures = fixtures
+	}
+	stack, err := Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open stack: %v", err)
+	}
+	return stack
+}
+
+func TestSubmitNotifyFetch(t *testing.T) {
+	dir := t.TempDir()
+	stack := openStack(t, dir, Options{BlocksPerFile: 50})
+	backend := stack.Backend()
+
+	jobID, err := pkg.SubmitFile(backend, "../../test-docs/pdf/invoice.pdf")
+	if err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+
+	// Nothing is processed before the stack is drained
+	job, err := backend.Jobs.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.Status != pkg.JobStatusSubmitted {
+		t.Errorf("Expected SUBMITTED before draining, got %s", job.Status)
+	}
+	if job.DocumentKey != "input/"+jobID+"/invoice.pdf" {
+		t.Errorf("Unexpected document key %s", job.DocumentKey)
+	}
+
+	if err := stack.Drain(); err != nil {
+		t.Fatalf("Failed to drain: %v", err)
+	}
+
+	job, err = backend.Jobs.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.Status != pkg.JobStatusCompleted {
+		t.Fatalf("Expected COMPLETED, got %s (%s)", job.Status, job.Error)
+	}
+	if job.TextractID == "PENDING" || job.ResultKey != "textract_output/"+job.TextractID {
+		t.Errorf("Unexpected Textract ID %s and result key %s", job.TextractID, job.ResultKey)
+	}
+	if job.CompletedAt == nil {
+		t.Errorf("Expected CompletedAt")
+	}
+
+	notifications, err := stack.Notifications()
+	if err != nil {
+		t.Fatalf("Failed to receive notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].JobID != jobID || notifications[0].Status != pkg.JobStatusCompleted || notifications[0].ResultKey != job.ResultKey {
+		t.Errorf("Unexpected notifications: %+v", notifications)
+	}
+
+	// 132 blocks in files of 50, plus the access check
+	keys, err := backend.Objects.ListObjects(backend.Resources.OutputS3Bucket, job.ResultKey)
+	if err != nil {
+		t.Fatalf("Failed to list output: %v", err)
+	}
+	if len(keys) != 4 || !strings.HasSuffix(keys[0], "/.s3_access_check") {
+		t.Errorf("Unexpected output files: %v", keys)
+	}
+
+	analysis, err := pkg.ReadResults(backend, job)
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	if len(analysis.Blocks) != 132 {
+		t.Errorf("Expected 132 blocks, got %d", len(analysis.Blocks))
+	}
+	direct, err := backend.Analysis.GetDocumentAnalysis(job.TextractID)
+	if err != nil {
+		t.Fatalf("Failed to get document analysis: %v", err)
+	}
+	if len(direct.Blocks) != len(analysis.Blocks) {
+		t.Errorf("Expected the same blocks from Textract and S3")
+	}
+
+	// The fetched results parse like the fixture
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		t.Fatalf("Failed to marshal results: %v", err)
+	}
+	docs, err := parser.LoadFromJSONReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse results: %v", err)
+	}
+	if len(docs) != 1 || len(docs[0].Pages()[0].Tables()) != 1 {
+		t.Errorf("Expected a document with a table")
+	}
+
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	// A later command sees the job and the logged notification
+	stack = openStack(t, dir, Options{})
+	defer stack.Close()
+	jobs, err := stack.Jobs().ListJobs(pkg.ListJobsOptions{Status: pkg.JobStatusCompleted})
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != jobID || !jobs[0].SubmittedAt.Equal(job.SubmittedAt) {
+		t.Errorf("Unexpected jobs after reopening: %+v", jobs)
+	}
+	logged, err := ReadNotifications(dir)
+	if err != nil {
+		t.Fatalf("Failed to read notifications: %v", err)
+	}
+	if len(logged) != 1 || logged[0].JobID != jobID {
+		t.Errorf("Unexpected logged notifications: %+v", logged)
+	}
+}
+
+func TestMissingFixtureFailsJob(t *testing.T) {
+	dir := t.TempDir()
+	document := filepath.Join(dir, "receipt.pdf")
+	if err := os.WriteFile(document, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a default.json, unlike the shipped fixtures
+	stack := openStack(t, dir, Options{Fixtures: t.TempDir()})
+	defer stack.Close()
+	backend := stack.Backend()
+
+	jobID, err := pkg.SubmitFile(backend, document)
+	if err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+	if err := stack.Drain(); err != nil {
+		t.Fatalf("Failed to drain: %v", err)
+	}
+
+	job, err := backend.Jobs.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.Status != pkg.JobStatusFailed || !strings.Contains(job.Error, "no fixture for receipt.pdf") {
+		t.Errorf("Expected FAILED with a fixture error, got %s (%s)", job.Status, job.Error)
+	}
+	if job.TextractID == "PENDING" {
+		t.Errorf("Expected the Textract ID to be set")
+	}
+
+	notifications, err := stack.Notifications()
+	if err != nil {
+		t.Fatalf("Failed to receive notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Status != pkg.JobStatusFailed || notifications[0].Error != job.Error {
+		t.Errorf("Unexpected notifications: %+v", notifications)
+	}
+}
+
+func TestDefaultFixture(t *testing.T) {
+	// The shipped fixtures answer documents without a fixture of their own,
+	// so the default --local-fixtures work for any document
+	dir := t.TempDir()
+	document := filepath.Join(dir, "scan 1.png")
+	if err := os.WriteFile(document, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stack := openStack(t, filepath.Join(dir, "local"), Options{})
+	defer stack.Close()
+	backend := stack.Backend()
+
+	jobID, err := pkg.SubmitFile(backend, document)
+	if err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+	if err := stack.Drain(); err != nil {
+		t.Fatalf("Failed to drain: %v", err)
+	}
+
+	job, err := backend.Jobs.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.Status != pkg.JobStatusCompleted {
+		t.Errorf("Expected COMPLETED, got %s (%s)", job.Status, job.Error)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	stack := openStack(t, dir, Options{})
+	defer stack.Close()
+
+	stack.completion.Send("not json")
+	if err := stack.Drain(); err != nil {
+		t.Fatalf("Failed to drain: %v", err)
+	}
+
+	stats := stack.completion.Stats()
+	if stats.Visible != 0 || stats.DeadLetters != 1 {
+		t.Errorf("Expected the message in the dead letters, got %+v", stats)
+	}
+	deadLetters := stack.completion.DeadLetters()
+	if len(deadLetters) != 1 || deadLetters[0].Receives != defaultMaxReceives {
+		t.Errorf("Unexpected dead letters: %+v", deadLetters)
+	}
+
+	logged, err := ReadDeadLetters(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dead letters: %v", err)
+	}
+	if len(logged) != 1 || logged[0].Queue != "completion" || logged[0].Body != "not json" || logged[0].Error == "" {
+		t.Errorf("Unexpected logged dead letters: %+v", logged)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	store, err := OpenJobStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	for i, status := range []string{pkg.JobStatusCompleted, pkg.JobStatusFailed, pkg.JobStatusCompleted} {
+		err := store.CreateJob(pkg.TextractJob{
+			JobID:       string(rune('a' + i)),
+			DocumentKey: "input/doc.pdf",
+			Status:      status,
+			SubmittedAt: now.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+	}
+
+	since := now.Add(30 * time.Minute)
+	jobs, err := store.ListJobs(pkg.ListJobsOptions{Since: &since, Status: pkg.JobStatusCompleted})
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != "c" {
+		t.Errorf("Expected job c, got %+v", jobs)
+	}
+
+	if err := store.UpdateJobStatus("missing", pkg.JobStatusError, ""); err == nil {
+		t.Errorf("Expected an error updating a missing job")
+	}
+}
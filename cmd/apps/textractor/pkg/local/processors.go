@@ -0,0 +1,178 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg"
+	"github.com/rs/zerolog/log"
+)
+
+// The processors mirror the Lambda functions in
+// terraform/modules/textractor/lambda
+
+// s3Event is the S3 event notification delivered to the input queue
+type s3Event struct {
+	Records []s3Record `json:"Records"`
+}
+
+type s3Record struct {
+	EventName string `json:"eventName"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key  string `json:"key"`
+			Size int64  `json:"size"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+func newS3Event(bucket, key string, size int64) string {
+	var record s3Record
+	record.EventName = "ObjectCreated:Put"
+	record.S3.Bucket.Name = bucket
+	// S3 form-encodes keys in event notifications
+	record.S3.Object.Key = strings.ReplaceAll(url.QueryEscape(key), "%2F", "/")
+	record.S3.Object.Size = size
+
+	data, _ := json.Marshal(s3Event{Records: []s3Record{record}})
+	return string(data)
+}
+
+// snsEnvelope is how SNS wraps a message delivered to SQS
+type snsEnvelope struct {
+	Type     string `json:"Type"`
+	TopicArn string `json:"TopicArn"`
+	Message  string `json:"Message"`
+}
+
+func newSNSEnvelope(topic, message string) string {
+	data, _ := json.Marshal(snsEnvelope{Type: "Notification", TopicArn: topic, Message: message})
+	return string(data)
+}
+
+// Notification is a job status change published to the notification topic
+type Notification struct {
+	JobID       string `json:"jobId"`
+	Status      string `json:"status"`
+	ResultKey   string `json:"ResultKey,omitempty"`
+	CompletedAt string `json:"CompletedAt,omitempty"`
+	Error       string `json:"Error,omitempty"`
+	Timestamp   string `json:"timestamp"`
+}
+
+var jobIDFromKey = regexp.MustCompile(`(?:input|results)/([^/]+)`)
+
+// processDocument starts the analysis of an uploaded document
+func (s *Stack) processDocument(body string) error {
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return fmt.Errorf("invalid S3 event: %w", err)
+	}
+
+	for _, record := range event.Records {
+		bucket := record.S3.Bucket.Name
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			key = record.S3.Object.Key
+		}
+		log.Debug().Str("bucket", bucket).Str("key", key).Msg("[document-processor] Processing new file")
+
+		matches := jobIDFromKey.FindStringSubmatch(key)
+		if matches == nil {
+			log.Error().Str("key", key).Msg("[document-processor] Could not extract jobId from key")
+			continue
+		}
+		jobID := matches[1]
+
+		err = s.jobs.UpdateJob(jobID, pkg.JobStatusProcessing, nil)
+		if err == nil {
+			var textractID string
+			textractID, err = s.textract.StartDocumentAnalysis(bucket, key, jobID)
+			if err == nil {
+				err = s.jobs.UpdateJob(jobID, pkg.JobStatusProcessing, func(job *pkg.TextractJob) {
+					job.TextractID = textractID
+				})
+			}
+		}
+		if err != nil {
+			log.Error().Err(err).Str("job", jobID).Msg("[document-processor] Error processing record")
+			s.failJob(jobID, err.Error(), nil)
+		}
+	}
+	return nil
+}
+
+// processCompletion records the outcome of an analysis
+func (s *Stack) processCompletion(body string) error {
+	var envelope snsEnvelope
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		return fmt.Errorf("invalid SNS message: %w", err)
+	}
+	var message completionMessage
+	if err := json.Unmarshal([]byte(envelope.Message), &message); err != nil {
+		return fmt.Errorf("invalid Textract notification: %w", err)
+	}
+
+	jobID := message.JobTag
+	log.Debug().Str("job", jobID).Str("textract_id", message.JobID).Msg("[completion-processor] Processing completion")
+
+	completedAt := time.Now()
+	if message.Status != "SUCCEEDED" {
+		errorMsg := message.StatusMessage
+		if errorMsg == "" {
+			errorMsg = "Textract processing failed"
+		}
+		s.failJob(jobID, errorMsg, &completedAt)
+		return nil
+	}
+
+	resultKey := ResultKey(message.JobID)
+	err := s.jobs.UpdateJob(jobID, pkg.JobStatusCompleted, func(job *pkg.TextractJob) {
+		job.ResultKey = resultKey
+		job.CompletedAt = &completedAt
+	})
+	if err != nil {
+		log.Error().Err(err).Str("job", jobID).Msg("[completion-processor] Error processing completion")
+		s.failJob(jobID, err.Error(), &completedAt)
+		return nil
+	}
+
+	s.notify(Notification{
+		JobID:       jobID,
+		Status:      pkg.JobStatusCompleted,
+		ResultKey:   resultKey,
+		CompletedAt: completedAt.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// failJob marks a job FAILED and sends a notification. Errors are only
+// logged, the message counts as processed.
+func (s *Stack) failJob(jobID string, errorMsg string, completedAt *time.Time) {
+	err := s.jobs.UpdateJob(jobID, pkg.JobStatusFailed, func(job *pkg.TextractJob) {
+		job.Error = errorMsg
+		if completedAt != nil {
+			job.CompletedAt = completedAt
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Str("job", jobID).Msg("Failed to update job status")
+	}
+
+	notification := Notification{
+		JobID:  jobID,
+		Status: pkg.JobStatusFailed,
+		Error:  errorMsg,
+	}
+	if completedAt != nil {
+		notification.CompletedAt = completedAt.UTC().Format(time.RFC3339)
+	}
+	s.notify(notification)
+}
@@ -0,0 +1,133 @@
+package local
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultMaxReceives is how often a message is delivered before it moves
+// to the dead letter queue, matching the redrive policy in terraform
+const defaultMaxReceives = 3
+
+// Message is a message in a queue
+type Message struct {
+	Body     string
+	Receives int
+	// Error is the last handler error, set on dead letters
+	Error string
+}
+
+// Queue is an in-memory stand-in for an SQS queue. Messages are delivered
+// to the handler when the stack is drained. Queues without a handler keep
+// their messages, like the notifications queue that clients poll.
+type Queue struct {
+	Name        string
+	URL         string
+	maxReceives int
+	handler     func(body string) error
+
+	mu          sync.Mutex
+	messages    []*Message
+	deadLetters []*Message
+	sent        int
+	deleted     int
+}
+
+func newQueue(name string, handler func(body string) error) *Queue {
+	return &Queue{
+		Name:        name,
+		URL:         "local://queue/" + name,
+		maxReceives: defaultMaxReceives,
+		handler:     handler,
+	}
+}
+
+// Send adds a message to the end of the queue
+func (q *Queue) Send(body string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, &Message{Body: body})
+	q.sent++
+}
+
+// Receive removes and returns all messages of a queue without a handler
+func (q *Queue) Receive() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var messages []Message
+	for _, m := range q.messages {
+		m.Receives++
+		messages = append(messages, *m)
+	}
+	q.deleted += len(q.messages)
+	q.messages = nil
+	return messages
+}
+
+// QueueStats is a snapshot of a queue's counters
+type QueueStats struct {
+	Name        string
+	Visible     int
+	Sent        int
+	Deleted     int
+	DeadLetters int
+}
+
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Name:        q.Name,
+		Visible:     len(q.messages),
+		Sent:        q.sent,
+		Deleted:     q.deleted,
+		DeadLetters: len(q.deadLetters),
+	}
+}
+
+// DeadLetters returns the messages that failed maxReceives times
+func (q *Queue) DeadLetters() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var messages []Message
+	for _, m := range q.deadLetters {
+		messages = append(messages, *m)
+	}
+	return messages
+}
+
+// deliver hands the first message to the handler. A failed message goes to
+// the back of the queue, or to the dead letters after maxReceives. It
+// returns the dead lettered message, if any, and whether there was a
+// message to deliver.
+func (q *Queue) deliver() (*Message, bool) {
+	if q.handler == nil {
+		return nil, false
+	}
+
+	q.mu.Lock()
+	if len(q.messages) == 0 {
+		q.mu.Unlock()
+		return nil, false
+	}
+	m := q.messages[0]
+	q.messages = q.messages[1:]
+	m.Receives++
+	q.mu.Unlock()
+
+	err := q.handler(m.Body)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case err == nil:
+		q.deleted++
+	case m.Receives >= q.maxReceives:
+		m.Error = fmt.Sprintf("%v", err)
+		q.deadLetters = append(q.deadLetters, m)
+		return m, true
+	default:
+		q.messages = append(q.messages, m)
+	}
+	return nil, true
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// InputKey is where a document is uploaded. The document processor takes
+// the job ID from the key.
+func InputKey(jobID, fileName string) string {
+	return fmt.Sprintf("input/%s/%s", jobID, fileName)
+}
+
+// SubmitFile creates a job for a document and uploads it to the document
+// bucket, which starts processing. It returns the job ID.
+func SubmitFile(b *Backend, filePath string) (string, error) {
+	jobID := uuid.New().String()
+	key := InputKey(jobID, filepath.Base(filePath))
+
+	// Create initial job record
+	err := b.Jobs.CreateJob(TextractJob{
+		JobID:       jobID,
+		DocumentKey: key,
+		Status:      JobStatusUploading,
+		SubmittedAt: time.Now(),
+		TextractID:  "PENDING",
+		ResultKey:   "PENDING",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create initial job record: %w", err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if err := b.Jobs.UpdateJobStatus(jobID, JobStatusError, fmt.Sprintf("Failed to open file: %v", err)); err != nil {
+			log.Error().Err(err).Msg("Failed to update job status")
+		}
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	err = b.Objects.PutObject(b.Resources.DocumentS3Bucket, key, file, "application/pdf")
+	if err != nil {
+		if err := b.Jobs.UpdateJobStatus(jobID, JobStatusError, fmt.Sprintf("Failed to upload to S3: %v", err)); err != nil {
+			log.Error().Err(err).Msg("Failed to update job status")
+		}
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+
+	if err := b.Jobs.UpdateJobStatus(jobID, JobStatusSubmitted, ""); err != nil {
+		log.Warn().Err(err).Msg("Failed to update job status to SUBMITTED")
+	}
+
+	return jobID, nil
+}
@@ -0,0 +1,409 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// Source is the locator that found a value
+type Source string
+
+const (
+	SourceForm    Source = "form"
+	SourceTable   Source = "table"
+	SourceAnchor  Source = "anchor"
+	SourcePattern Source = "pattern"
+)
+
+// Value is the outcome of extracting one field
+type Value struct {
+	Field string
+	// Value is the converted value, nil if the field is missing or invalid
+	Value interface{}
+	// Text is the text the value was converted from
+	Text       string
+	Confidence float64
+	Source     Source
+	Page       int
+
+	Missing bool
+	// Error says why a value that was found is invalid
+	Error         string
+	LowConfidence bool
+}
+
+// Result holds the values of all template fields for one document
+type Result struct {
+	Template string
+	Values   []Value
+}
+
+// Get returns the value of a field, or nil if the template has no such field
+func (r *Result) Get(field string) *Value {
+	for i := range r.Values {
+		if r.Values[i].Field == field {
+			return &r.Values[i]
+		}
+	}
+	return nil
+}
+
+// MissingRequired returns the required fields that were not found
+func (r *Result) MissingRequired(t *Template) []string {
+	var missing []string
+	for _, f := range t.Fields {
+		if v := r.Get(f.Name); f.Required && v != nil && v.Missing {
+			missing = append(missing, f.Name)
+		}
+	}
+	return missing
+}
+
+// Invalid returns the fields whose value could not be converted or did not
+// match the pattern
+func (r *Result) Invalid() []Value {
+	var invalid []Value
+	for _, v := range r.Values {
+		if v.Error != "" {
+			invalid = append(invalid, v)
+		}
+	}
+	return invalid
+}
+
+// Valid reports whether all required fields were found and no value is
+// invalid
+func (r *Result) Valid(t *Template) bool {
+	return len(r.MissingRequired(t)) == 0 && len(r.Invalid()) == 0
+}
+
+// candidate is text a locator found for a field
+type candidate struct {
+	text       string
+	value      interface{} // set when the locator already converted it
+	err        error
+	confidence float64
+	source     Source
+	page       int
+}
+
+// Extract applies a template to a document
+func Extract(doc parser.Document, t *Template) *Result {
+	result := &Result{Template: t.Name}
+	for i := range t.Fields {
+		result.Values = append(result.Values, extractField(doc, &t.Fields[i]))
+	}
+	return result
+}
+
+func extractField(doc parser.Document, f *Field) Value {
+	var candidates []candidate
+	if len(f.Keys) > 0 {
+		candidates = append(candidates, formCandidates(doc, f)...)
+	}
+	if f.Table != nil {
+		candidates = append(candidates, tableCandidates(doc, f)...)
+	}
+	if f.Anchor != nil {
+		candidates = append(candidates, anchorCandidates(doc, f)...)
+	}
+	if f.pattern != nil && len(f.Keys) == 0 && f.Table == nil && f.Anchor == nil {
+		candidates = append(candidates, patternCandidates(doc, f)...)
+	}
+
+	// The first candidate that is valid wins. If none is, the first one is
+	// reported with its error.
+	var first *Value
+	for _, c := range candidates {
+		v := Value{
+			Field:      f.Name,
+			Text:       c.text,
+			Confidence: c.confidence,
+			Source:     c.source,
+			Page:       c.page,
+		}
+		v.LowConfidence = f.MinConfidence > 0 && c.confidence < f.MinConfidence
+
+		text := c.text
+		if c.err != nil {
+			v.Error = c.err.Error()
+		} else if f.pattern != nil && c.source != SourcePattern && c.value == nil {
+			match := f.pattern.FindStringSubmatch(text)
+			if match == nil {
+				v.Error = fmt.Sprintf("%q does not match %s", text, f.Pattern)
+			} else if len(match) > 1 {
+				text = match[1]
+			} else {
+				text = match[0]
+			}
+		}
+
+		if v.Error == "" {
+			value := c.value
+			if value == nil {
+				var err error
+				value, err = convert(f, text)
+				if err != nil {
+					v.Error = err.Error()
+				}
+			}
+			if v.Error == "" {
+				v.Value = value
+				return v
+			}
+		}
+
+		if first == nil {
+			first = &v
+		}
+	}
+
+	if first != nil {
+		return *first
+	}
+	return Value{Field: f.Name, Missing: true}
+}
+
+// formCandidates returns the values of form fields whose key is one of the
+// field's keys
+func formCandidates(doc parser.Document, f *Field) []candidate {
+	keys := map[string]bool{}
+	for _, key := range f.Keys {
+		keys[normalizeKey(key)] = true
+	}
+
+	var candidates []candidate
+	for _, page := range doc.Pages() {
+		for _, form := range page.Forms() {
+			for _, kv := range form.Fields() {
+				if !keys[normalizeKey(kv.KeyText())] {
+					continue
+				}
+				c := candidate{
+					text:       strings.TrimSpace(kv.ValueText()),
+					confidence: kv.Confidence(),
+					source:     SourceForm,
+					page:       page.Number(),
+				}
+				if f.Type == TypeBoolean {
+					if status, ok := selectionStatus(kv); ok {
+						c.text = status
+					}
+				}
+				if c.text == "" && f.Type != TypeBoolean {
+					continue
+				}
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// selectionStatus returns the status of the checkbox that is the value of a
+// form field
+func selectionStatus(kv parser.KeyValue) (string, bool) {
+	if kv.Value() == nil {
+		return "", false
+	}
+	for _, child := range kv.Value().Children() {
+		if child.BlockType() == parser.BlockTypeSelectionElement {
+			return child.SelectionStatus(), true
+		}
+	}
+	return "", false
+}
+
+// tableCandidates looks the field up in every table with a matching column
+// header
+func tableCandidates(doc parser.Document, f *Field) []candidate {
+	columns := map[string]bool{}
+	for _, column := range f.Table.Columns {
+		columns[normalizeKey(column)] = true
+	}
+	row := normalizeKey(f.Table.Row)
+
+	aggregate := f.Table.Aggregate
+	if aggregate == "" {
+		aggregate = "join"
+		if f.numeric() {
+			aggregate = "sum"
+		}
+		if row != "" {
+			aggregate = "first"
+		}
+	}
+
+	var candidates []candidate
+	for _, page := range doc.Pages() {
+		for _, table := range page.Tables() {
+			rows := table.Rows()
+			if len(rows) < 2 {
+				continue
+			}
+
+			column := -1
+			for i, cell := range rows[0].Cells() {
+				if cell != nil && columns[normalizeKey(cell.Text())] {
+					column = i
+					break
+				}
+			}
+			if column < 0 {
+				continue
+			}
+
+			var texts []string
+			confidence := math.MaxFloat64
+			for _, r := range rows[1:] {
+				cells := r.Cells()
+				if column >= len(cells) || cells[column] == nil {
+					continue
+				}
+				if row != "" && (cells[0] == nil || normalizeKey(cells[0].Text()) != row) {
+					continue
+				}
+				text := strings.Join(strings.Fields(cells[column].Text()), " ")
+				if text == "" {
+					continue
+				}
+				texts = append(texts, text)
+				confidence = min(confidence, cells[column].Confidence())
+			}
+			if len(texts) == 0 {
+				continue
+			}
+
+			c := candidate{confidence: confidence, source: SourceTable, page: page.Number()}
+			switch aggregate {
+			case "first":
+				c.text = texts[0]
+			case "last":
+				c.text = texts[len(texts)-1]
+			case "join":
+				c.text = strings.Join(texts, "; ")
+			case "sum":
+				c.text = strings.Join(texts, " + ")
+				c.value, c.err = sum(f, texts)
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// anchorCandidates finds lines starting with the anchor text and takes the
+// rest of the line, or the nearest line to the right or below
+func anchorCandidates(doc parser.Document, f *Field) []candidate {
+	maxDistance := f.Anchor.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultMaxDistance
+	}
+	anchor := strings.TrimSpace(f.Anchor.Text)
+
+	var candidates []candidate
+	for _, page := range doc.Pages() {
+		lines := page.Lines()
+		for _, line := range lines {
+			text := strings.TrimSpace(line.Text())
+			if len(text) < len(anchor) || !strings.EqualFold(text[:len(anchor)], anchor) {
+				continue
+			}
+			rest := text[len(anchor):]
+			if rest != "" && isWordChar(rest[0]) && isWordChar(anchor[len(anchor)-1]) {
+				// "Total" must not match "Totals"
+				continue
+			}
+
+			if rest = strings.TrimSpace(strings.TrimLeft(rest, " :")); rest != "" {
+				candidates = append(candidates, candidate{
+					text:       rest,
+					confidence: line.Confidence(),
+					source:     SourceAnchor,
+					page:       page.Number(),
+				})
+				continue
+			}
+
+			if next, ok := nearestLine(line, lines, f.Anchor.Direction, maxDistance); ok {
+				candidates = append(candidates, candidate{
+					text:       strings.TrimSpace(next.Text()),
+					confidence: min(line.Confidence(), next.Confidence()),
+					source:     SourceAnchor,
+					page:       page.Number(),
+				})
+			}
+		}
+	}
+	return candidates
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// nearestLine returns the closest line on the same row to the right of the
+// anchor, or overlapping it horizontally below it
+func nearestLine(anchor parser.Line, lines []parser.Line, direction string, maxDistance float64) (parser.Line, bool) {
+	a := anchor.BoundingBox()
+	var best parser.Line
+	bestDistance := maxDistance
+
+	for _, line := range lines {
+		if line == anchor {
+			continue
+		}
+		b := line.BoundingBox()
+
+		var distance float64
+		if direction == "below" {
+			if b.Top < a.Top+a.Height/2 || !overlaps(a.Left, a.Width, b.Left, b.Width) {
+				continue
+			}
+			distance = b.Top - (a.Top + a.Height)
+		} else {
+			if b.Left < a.Left+a.Width/2 || !overlaps(a.Top, a.Height, b.Top, b.Height) {
+				continue
+			}
+			distance = b.Left - (a.Left + a.Width)
+		}
+		distance = max(distance, 0)
+
+		if distance <= bestDistance {
+			best = line
+			bestDistance = distance
+		}
+	}
+	return best, best != nil
+}
+
+func overlaps(start1, length1, start2, length2 float64) bool {
+	return start1 < start2+length2 && start2 < start1+length1
+}
+
+// patternCandidates searches all lines for the field's pattern
+func patternCandidates(doc parser.Document, f *Field) []candidate {
+	var candidates []candidate
+	for _, page := range doc.Pages() {
+		for _, line := range page.Lines() {
+			match := f.pattern.FindStringSubmatch(line.Text())
+			if match == nil {
+				continue
+			}
+			text := match[0]
+			if len(match) > 1 {
+				text = match[1]
+			}
+			candidates = append(candidates, candidate{
+				text:       text,
+				confidence: line.Confidence(),
+				source:     SourcePattern,
+				page:       page.Number(),
+			})
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,230 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+func loadDocument(t *testing.T, name string) parser.Document {
+	t.Helper()
+	docs, err := parser.LoadFromJSON("../../../test-docs/json/" + name + ".json")
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", name, err)
+	}
+	return docs[0]
+}
+
+func parseTemplate(t *testing.T, yaml string) *Template {
+	t.Helper()
+	tmpl, err := ParseTemplate([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	return tmpl
+}
+
+func TestExtractInvoice(t *testing.T) {
+	tmpl, err := LoadTemplate("../../../templates/invoice.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+	result := Extract(loadDocument(t, "invoice"), tmpl)
+
+	expected := map[string]interface{}{
+		"invoice_number": "INV-2024-001",
+		"invoice_date":   time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+		"due_date":       time.Date(2024, 4, 20, 0, 0, 0, 0, time.UTC),
+		"vendor":         "Acme Corporation 123 Business St Business City, 12345",
+		"customer":       "Client Company Ltd 456 Client Avenue Client City, 67890",
+		"line_items":     int64(35),
+		"line_total":     6375.0,
+		"subtotal":       6375.0,
+		"tax":            637.5,
+		"total":          7012.5,
+	}
+	for name, value := range expected {
+		v := result.Get(name)
+		if v == nil || v.Value != value {
+			t.Errorf("Expected %s = %v, got %+v", name, value, v)
+		}
+	}
+
+	if v := result.Get("line_total"); v.Source != SourceTable || v.Text != "$1500.00 + $4000.00 + $875.00" {
+		t.Errorf("Unexpected line_total: %+v", v)
+	}
+	if v := result.Get("tax"); !v.LowConfidence {
+		t.Errorf("Expected tax below 90%% confidence: %+v", v)
+	}
+	if !result.Valid(tmpl) {
+		t.Errorf("Expected a valid result, missing %v, invalid %v", result.MissingRequired(tmpl), result.Invalid())
+	}
+}
+
+func TestExtractLocators(t *testing.T) {
+	tmpl := parseTemplate(t, `
+name: locators
+fields:
+  - name: total
+    type: money
+    anchor: {text: Total}
+  - name: client_city
+    anchor: {text: "Client Company Ltd", direction: below}
+  - name: date
+    type: date
+    pattern: '^(\d{4}-\d{2}-\d{2})$'
+  - name: rate
+    type: money
+    table: {columns: [Rate], row: Project Management}
+  - name: items
+    table: {columns: [Item]}
+  - name: po_number
+    required: true
+    keys: ["PO Number"]
+  - name: invoice_year
+    type: integer
+    keys: ["Invoice #"]
+    pattern: 'INV-(\d{4})'
+  - name: customer_id
+    keys: ["Invoice #"]
+    pattern: '^CUST-\d+$'
+`)
+	result := Extract(loadDocument(t, "invoice"), tmpl)
+
+	// The anchor doesn't match "Subtotal: ..."
+	if v := result.Get("total"); v.Value != 7012.5 || v.Source != SourceAnchor {
+		t.Errorf("Unexpected total: %+v", v)
+	}
+	if v := result.Get("client_city"); v.Value != "456 Client Avenue" {
+		t.Errorf("Unexpected client_city: %+v", v)
+	}
+	if v := result.Get("date"); v.Value != time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC) || v.Source != SourcePattern {
+		t.Errorf("Unexpected date: %+v", v)
+	}
+	if v := result.Get("rate"); v.Value != 175.0 {
+		t.Errorf("Unexpected rate: %+v", v)
+	}
+	if v := result.Get("items"); v.Value != "Consulting Services; Software Development; Project Management" {
+		t.Errorf("Unexpected items: %+v", v)
+	}
+	if v := result.Get("invoice_year"); v.Value != int64(2024) {
+		t.Errorf("Unexpected invoice_year: %+v", v)
+	}
+
+	if v := result.Get("po_number"); !v.Missing {
+		t.Errorf("Expected po_number to be missing: %+v", v)
+	}
+	if missing := result.MissingRequired(tmpl); len(missing) != 1 || missing[0] != "po_number" {
+		t.Errorf("Unexpected missing fields: %v", missing)
+	}
+	v := result.Get("customer_id")
+	if v.Missing || v.Value != nil || !strings.Contains(v.Error, "does not match") {
+		t.Errorf("Expected customer_id to be invalid: %+v", v)
+	}
+	if result.Valid(tmpl) {
+		t.Errorf("Expected an invalid result")
+	}
+}
+
+func TestExtractCheckboxes(t *testing.T) {
+	tmpl := parseTemplate(t, `
+name: application
+fields:
+  - name: mr
+    type: boolean
+    keys: ["Mr."]
+  - name: phd
+    type: boolean
+    keys: ["PhD"]
+`)
+	result := Extract(loadDocument(t, "forms"), tmpl)
+	for _, name := range []string{"mr", "phd"} {
+		if v := result.Get(name); v.Value != false || v.Text != "NOT_SELECTED" {
+			t.Errorf("Expected %s to be unchecked: %+v", name, v)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	tmpl := parseTemplate(t, `
+name: report
+fields:
+  - name: invoice_number
+    required: true
+    keys: ["Invoice #"]
+  - name: total
+    type: money
+    keys: ["Total"]
+    min_confidence: 99
+`)
+	report := NewReport(tmpl)
+	report.Add("invoice.json", Extract(loadDocument(t, "invoice"), tmpl))
+	report.Add("forms.json", Extract(loadDocument(t, "forms"), tmpl))
+
+	if report.Documents != 2 || report.ValidDocuments != 1 {
+		t.Errorf("Expected 1 of 2 documents valid, got %d of %d", report.ValidDocuments, report.Documents)
+	}
+	fields := report.Fields()
+	if fields[0].Field != "invoice_number" || fields[0].Found != 1 || fields[0].Missing != 1 || fields[0].MissingIn[0] != "forms.json" {
+		t.Errorf("Unexpected invoice_number report: %+v", fields[0])
+	}
+	if fields[1].LowConfidence != 1 || fields[1].AverageConfidence < 90 {
+		t.Errorf("Unexpected total report: %+v", fields[1])
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`name: empty`:         "no fields",
+		`fields: [{name: a}]`: "needs keys",
+		`fields: [{name: a, keys: [x]}, {name: a, keys: [y]}]`:       "duplicate field a",
+		`fields: [{name: a, type: decimal, keys: [x]}]`:              "unknown type decimal",
+		`fields: [{name: a, pattern: "("}]`:                          "invalid pattern",
+		`fields: [{name: a, table: {columns: [x], aggregate: sum}}]`: "sum needs a number",
+		`fields: [{name: a, anchor: {text: x, direction: up}}]`:      "unknown anchor direction",
+		`fields: [{name: _valid, keys: [x]}]`:                        "can't start with an underscore",
+	} {
+		_, err := ParseTemplate([]byte(yaml))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %s, got %v", expected, yaml, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		typ      FieldType
+		text     string
+		expected interface{}
+	}{
+		{TypeMoney, "$1,234.567", 1234.57},
+		{TypeMoney, "($50.00)", -50.0},
+		{TypeNumber, "approx. 3.5 kg", 3.5},
+		{TypeInteger, "Qty: 12", int64(12)},
+		{TypeDate, "Jan 5, 2024", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{TypeBoolean, "SELECTED", true},
+		{TypeString, "  text ", "text"},
+	}
+	for _, test := range tests {
+		v, err := convert(&Field{Type: test.typ}, test.text)
+		if err != nil || v != test.expected {
+			t.Errorf("convert(%s, %q) = %v, %v, expected %v", test.typ, test.text, v, err, test.expected)
+		}
+	}
+
+	for _, test := range []struct {
+		typ  FieldType
+		text string
+	}{
+		{TypeMoney, "n/a"},
+		{TypeInteger, "1.5"},
+		{TypeDate, "someday"},
+		{TypeBoolean, "maybe"},
+	} {
+		if _, err := convert(&Field{Type: test.typ}, test.text); err == nil {
+			t.Errorf("Expected an error converting %q to %s", test.text, test.typ)
+		}
+	}
+}
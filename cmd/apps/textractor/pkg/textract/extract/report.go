@@ -0,0 +1,79 @@
+package extract
+
+import "sort"
+
+// FieldReport sums up how a field fared over many documents
+type FieldReport struct {
+	Field         string
+	Required      bool
+	Found         int
+	Missing       int
+	Invalid       int
+	LowConfidence int
+	// AverageConfidence is over the documents the field was found in
+	AverageConfidence float64
+	// MissingIn and InvalidIn name the documents
+	MissingIn []string
+	InvalidIn []string
+
+	confidenceSum float64
+}
+
+// Report collects the results of a template over many documents
+type Report struct {
+	template  *Template
+	Documents int
+	// ValidDocuments have all required fields and no invalid values
+	ValidDocuments int
+	fields         map[string]*FieldReport
+}
+
+func NewReport(t *Template) *Report {
+	r := &Report{template: t, fields: map[string]*FieldReport{}}
+	for _, f := range t.Fields {
+		r.fields[f.Name] = &FieldReport{Field: f.Name, Required: f.Required}
+	}
+	return r
+}
+
+// Add records the result of one document
+func (r *Report) Add(document string, result *Result) {
+	r.Documents++
+	if result.Valid(r.template) {
+		r.ValidDocuments++
+	}
+
+	for _, v := range result.Values {
+		fr, ok := r.fields[v.Field]
+		if !ok {
+			continue
+		}
+		switch {
+		case v.Missing:
+			fr.Missing++
+			fr.MissingIn = append(fr.MissingIn, document)
+		case v.Error != "":
+			fr.Invalid++
+			fr.InvalidIn = append(fr.InvalidIn, document)
+		default:
+			fr.Found++
+			fr.confidenceSum += v.Confidence
+			fr.AverageConfidence = fr.confidenceSum / float64(fr.Found)
+			if v.LowConfidence {
+				fr.LowConfidence++
+			}
+		}
+	}
+}
+
+// Fields returns the field reports in template order
+func (r *Report) Fields() []FieldReport {
+	var reports []FieldReport
+	for _, f := range r.template.Fields {
+		fr := *r.fields[f.Name]
+		sort.Strings(fr.MissingIn)
+		sort.Strings(fr.InvalidIn)
+		reports = append(reports, fr)
+	}
+	return reports
+}
@@ -0,0 +1,185 @@
+// Package extract pulls named, typed fields out of Textract documents using
+// YAML templates, so that many documents of the same kind can be processed
+// with one description of where their fields are.
+package extract
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldType is the type a field value is converted to
+type FieldType string
+
+const (
+	TypeString  FieldType = "string"
+	TypeNumber  FieldType = "number"
+	TypeInteger FieldType = "integer"
+	TypeMoney   FieldType = "money"
+	TypeDate    FieldType = "date"
+	TypeBoolean FieldType = "boolean"
+)
+
+// Template describes the fields to extract from one kind of document
+type Template struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description,omitempty"`
+	Fields      []Field `yaml:"fields"`
+}
+
+// Field describes one field and where to find it. The locators are tried
+// in the order keys, table, anchor, pattern and the first one that finds a
+// value wins.
+type Field struct {
+	Name     string    `yaml:"name"`
+	Type     FieldType `yaml:"type,omitempty"`
+	Required bool      `yaml:"required,omitempty"`
+
+	// Keys are the form keys the field may appear under. They match
+	// ignoring case, surrounding punctuation and repeated spaces.
+	Keys []string `yaml:"keys,omitempty"`
+	// Table looks the value up in a table column
+	Table *TableLookup `yaml:"table,omitempty"`
+	// Anchor takes the text next to a line starting with the anchor text
+	Anchor *Anchor `yaml:"anchor,omitempty"`
+	// Pattern is a regular expression. With other locators the value must
+	// match it, on its own the document lines are searched for it. If it
+	// has a capture group, the first group is the value.
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// Formats are the Go time layouts tried for dates
+	Formats []string `yaml:"formats,omitempty"`
+	// MinConfidence flags values Textract is less sure about (0-100)
+	MinConfidence float64 `yaml:"min_confidence,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// TableLookup finds a value in the column with one of the given headers
+type TableLookup struct {
+	Columns []string `yaml:"columns"`
+	// Row selects the row whose first cell matches, like a "Total" row.
+	// Without it all rows below the header are used.
+	Row string `yaml:"row,omitempty"`
+	// Aggregate combines several rows: sum, first, last or join. It
+	// defaults to sum for numbers and join otherwise.
+	Aggregate string `yaml:"aggregate,omitempty"`
+}
+
+// Anchor finds a value by its position relative to a label
+type Anchor struct {
+	Text string `yaml:"text"`
+	// Direction is right (the default) or below
+	Direction string `yaml:"direction,omitempty"`
+	// MaxDistance is how far from the anchor the value may be, as a
+	// fraction of the page. Defaults to 0.3.
+	MaxDistance float64 `yaml:"max_distance,omitempty"`
+}
+
+const defaultMaxDistance = 0.3
+
+// LoadTemplate reads and validates a template file
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	t, err := ParseTemplate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseTemplate parses and validates a YAML template
+func ParseTemplate(data []byte) (*Template, error) {
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *Template) validate() error {
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("template has no fields")
+	}
+
+	seen := map[string]bool{}
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.Name == "" {
+			return fmt.Errorf("field %d has no name", i+1)
+		}
+		// Reserved for the summary columns of the extract command
+		if strings.HasPrefix(f.Name, "_") {
+			return fmt.Errorf("field %s: names can't start with an underscore", f.Name)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+		seen[f.Name] = true
+
+		if f.Type == "" {
+			f.Type = TypeString
+		}
+		switch f.Type {
+		case TypeString, TypeNumber, TypeInteger, TypeMoney, TypeDate, TypeBoolean:
+		default:
+			return fmt.Errorf("field %s: unknown type %s", f.Name, f.Type)
+		}
+
+		if len(f.Keys) == 0 && f.Table == nil && f.Anchor == nil && f.Pattern == "" {
+			return fmt.Errorf("field %s: needs keys, table, anchor or pattern", f.Name)
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return fmt.Errorf("field %s: invalid pattern: %w", f.Name, err)
+			}
+			f.pattern = re
+		}
+		if f.Table != nil {
+			if len(f.Table.Columns) == 0 {
+				return fmt.Errorf("field %s: table lookup needs columns", f.Name)
+			}
+			switch f.Table.Aggregate {
+			case "", "sum", "first", "last", "join":
+			default:
+				return fmt.Errorf("field %s: unknown aggregate %s", f.Name, f.Table.Aggregate)
+			}
+			if f.Table.Aggregate == "sum" && !f.numeric() {
+				return fmt.Errorf("field %s: sum needs a number or money field", f.Name)
+			}
+		}
+		if f.Anchor != nil {
+			if f.Anchor.Text == "" {
+				return fmt.Errorf("field %s: anchor needs text", f.Name)
+			}
+			switch f.Anchor.Direction {
+			case "", "right", "below":
+			default:
+				return fmt.Errorf("field %s: unknown anchor direction %s", f.Name, f.Anchor.Direction)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Field) numeric() bool {
+	return f.Type == TypeNumber || f.Type == TypeInteger || f.Type == TypeMoney
+}
+
+// normalizeKey makes form keys and labels comparable: lower case, without
+// surrounding punctuation and with single spaces
+func normalizeKey(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.Trim(s, " :.-*")
+}
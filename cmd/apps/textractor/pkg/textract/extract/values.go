@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDateFormats are tried for date fields without formats
+var DefaultDateFormats = []string{
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"01/02/2006",
+	"1/2/2006",
+	"02.01.2006",
+}
+
+var numberPattern = regexp.MustCompile(`-?\d[\d,]*(\.\d+)?`)
+
+// convert turns the text of a value into the field's type
+func convert(f *Field, text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+
+	switch f.Type {
+	case TypeNumber, TypeMoney, TypeInteger:
+		match := numberPattern.FindString(text)
+		if match == "" {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		// Accounting style negative amounts are in parentheses
+		negative := strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")")
+		match = strings.ReplaceAll(match, ",", "")
+
+		if f.Type == TypeInteger {
+			n, err := strconv.ParseInt(match, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", text)
+			}
+			if negative {
+				n = -n
+			}
+			return n, nil
+		}
+
+		n, err := strconv.ParseFloat(match, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		if negative {
+			n = -n
+		}
+		if f.Type == TypeMoney {
+			n = math.Round(n*100) / 100
+		}
+		return n, nil
+
+	case TypeDate:
+		formats := f.Formats
+		if len(formats) == 0 {
+			formats = DefaultDateFormats
+		}
+		for _, format := range formats {
+			if t, err := time.Parse(format, text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date", text)
+
+	case TypeBoolean:
+		switch strings.ToLower(text) {
+		case "selected", "yes", "y", "true", "x", "checked":
+			return true, nil
+		case "not_selected", "no", "n", "false", "", "unchecked":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", text)
+
+	default:
+		return text, nil
+	}
+}
+
+// sum adds up the numeric values of several table cells
+func sum(f *Field, texts []string) (interface{}, error) {
+	var total float64
+	for _, text := range texts {
+		v, err := convert(f, text)
+		if err != nil {
+			return nil, err
+		}
+		switch n := v.(type) {
+		case int64:
+			total += float64(n)
+		case float64:
+			total += n
+		}
+	}
+
+	switch f.Type {
+	case TypeInteger:
+		return int64(total), nil
+	case TypeMoney:
+		return math.Round(total*100) / 100, nil
+	}
+	return total, nil
+}
@@ -122,14 +122,15 @@ func (b *blockImpl) Text() string {
 		return strings.Join(rows, "\n")
 
 	case BlockTypeKeyValueSet:
-		// For key-value sets, combine key and value texts
+		// A key or a value, made of words and selection elements. The words
+		// of the other half of the pair are not children.
 		var parts []string
 		for _, child := range b.children {
 			if text := child.Text(); text != "" {
 				parts = append(parts, text)
 			}
 		}
-		return strings.Join(parts, ": ")
+		return strings.Join(parts, " ")
 
 	case BlockTypePage:
 		// For pages, combine all line texts
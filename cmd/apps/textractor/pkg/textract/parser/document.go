@@ -10,12 +10,11 @@ import (
 
 // documentImpl implements the Document interface
 type documentImpl struct {
-	raw           *TextractResponse
-	pages         []Page
-	metadata      DocumentMetadata
-	blockIndex    map[string]Block
-	pageIndex     map[int]Page
-	keyValuePairs []KeyValue
+	raw        *TextractResponse
+	pages      []Page
+	metadata   DocumentMetadata
+	blockIndex map[string]Block
+	pageIndex  map[int]Page
 }
 
 // NewDocument creates a new Document from a TextractResponse
@@ -111,7 +110,7 @@ func (d *documentImpl) processBlocks(options *DocumentOptions) error {
 			return fmt.Errorf("creating block: %w", err)
 		}
 		if block.BlockType() == BlockTypeTable {
-			log.Info().Str("block.blockID", block.ID()).
+			log.Debug().Str("block.blockID", block.ID()).
 				Str("block.type", string(block.BlockType())).
 				Msg("created table block")
 		}
@@ -130,7 +129,7 @@ func (d *documentImpl) processBlocks(options *DocumentOptions) error {
 				switch relType {
 				case "CHILD":
 					// Add children to current block
-					log.Info().Str("block.blockID", block.ID()).
+					log.Debug().Str("block.blockID", block.ID()).
 						Str("block.type", string(block.BlockType())).
 						Int("block.page", block.Page()).
 						Interface("rel.Ids", rel.Ids).
@@ -138,7 +137,7 @@ func (d *documentImpl) processBlocks(options *DocumentOptions) error {
 					for _, childID := range rel.Ids {
 						if childBlock, ok := d.blockIndex[*childID]; ok {
 							if block.BlockType() == BlockTypeTable {
-								log.Info().Str("child.blockID", childBlock.ID()).Str("parent.blockID", block.ID()).Msg("adding child")
+								log.Debug().Str("child.blockID", childBlock.ID()).Str("parent.blockID", block.ID()).Msg("adding child")
 							}
 
 							block_.children = append(block_.children, childBlock)
@@ -146,22 +145,11 @@ func (d *documentImpl) processBlocks(options *DocumentOptions) error {
 							childImpl := childBlock.(*blockImpl)
 							childImpl.parents = append(childImpl.parents, block)
 						} else {
-							// Print all known block index values and their types
-							log.Info().Msg("Known block index values:")
-							blockIDs := make([]string, 0, len(d.blockIndex))
-							for blockID := range d.blockIndex {
-								blockIDs = append(blockIDs, blockID)
-							}
-							sort.Strings(blockIDs)
-
-							for _, blockID := range blockIDs {
-								block := d.blockIndex[blockID]
-								log.Info().
-									Str("blockID", blockID).
-									Str("blockType", string(block.BlockType())).
-									Msg("Block in index")
-							}
-							log.Fatal().Str("child.blockID", *childID).Msg("child block not found")
+							// Partial results, e.g. a single page of a paginated
+							// response, reference blocks that aren't included
+							log.Warn().Str("block.blockID", block.ID()).
+								Str("child.blockID", *childID).
+								Msg("child block not found, skipping")
 						}
 					}
 				case "MERGED_CELL":
@@ -170,10 +158,13 @@ func (d *documentImpl) processBlocks(options *DocumentOptions) error {
 					continue
 
 				case "VALUE":
-					// Handle key-value relationships
-					// These are processed later in processBlockRelationships
-					// I think these must be added as parents...
-					continue
+					// Link the value to its key as a parent, which is where
+					// buildForms looks for it
+					for _, valueID := range rel.Ids {
+						if valueBlock, ok := d.blockIndex[*valueID]; ok {
+							block_.parents = append(block_.parents, valueBlock)
+						}
+					}
 				}
 			}
 		}
@@ -258,20 +249,7 @@ func (d *documentImpl) processBlockRelationships(block Block) error {
 		}
 
 	case BlockTypeKeyValueSet:
-		// Process key-value relationships
-		if slices.Contains(block.EntityTypes(), EntityTypeKey) {
-			// Find corresponding value block through parent relationships
-			for _, parent := range block.Parents() {
-				if parent.BlockType() == BlockTypeKeyValueSet &&
-					slices.Contains(parent.EntityTypes(), EntityTypeValue) {
-					kv, err := newKeyValue(block, parent, nil) // Form will be set later
-					if err != nil {
-						return fmt.Errorf("creating key-value pair: %w", err)
-					}
-					d.keyValuePairs = append(d.keyValuePairs, kv)
-				}
-			}
-		}
+		// Key-value pairs are built per page in buildForms
 
 		// Process children of the key-value set
 		for _, child := range children {
@@ -348,34 +326,6 @@ func (d *documentImpl) buildPages() error {
 		}
 	}
 
-	// Associate key-value pairs with forms
-	for _, kv := range d.keyValuePairs {
-		// Find the page containing this key-value pair
-		pageNum := kv.Key().Page()
-		page, err := d.GetPageByIndex(pageNum)
-		if err != nil {
-			return fmt.Errorf("finding page for key-value pair: %w", err)
-		}
-
-		// Find or create a form for this page
-		var form Form
-		forms := page.Forms()
-		if len(forms) == 0 {
-			form = newForm(page)
-			forms = append(forms, form)
-		} else {
-			form = forms[0] // Use first form on page
-		}
-
-		// Update the key-value pair's form reference
-		kvImpl := kv.(*keyValueImpl)
-		kvImpl.form = form
-
-		// Add to form
-		formImpl := form.(*formImpl)
-		formImpl.addField(kv)
-	}
-
 	return nil
 }
 
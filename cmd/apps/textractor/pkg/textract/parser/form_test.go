@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// formJSON is a page with one key-value pair, "Due Date" -> "April 20". The
+// key points at its value through a VALUE relationship and both halves only
+// have their own words as children.
+const formJSON = `{
+	"DocumentMetadata": {"Pages": 1},
+	"Blocks": [
+		{
+			"Id": "page1", "BlockType": "PAGE", "Page": 1, "Confidence": 100,
+			"Relationships": [{"Type": "CHILD", "Ids": ["line1", "key1", "value1"]}]
+		},
+		{
+			"Id": "line1", "BlockType": "LINE", "Page": 1, "Confidence": 99, "Text": "Due Date April 20",
+			"Relationships": [{"Type": "CHILD", "Ids": ["w1", "w2", "w3", "w4"]}]
+		},
+		{"Id": "w1", "BlockType": "WORD", "Page": 1, "Confidence": 99, "Text": "Due"},
+		{"Id": "w2", "BlockType": "WORD", "Page": 1, "Confidence": 99, "Text": "Date"},
+		{"Id": "w3", "BlockType": "WORD", "Page": 1, "Confidence": 99, "Text": "April"},
+		{"Id": "w4", "BlockType": "WORD", "Page": 1, "Confidence": 99, "Text": "20"},
+		{
+			"Id": "key1", "BlockType": "KEY_VALUE_SET", "EntityTypes": ["KEY"], "Page": 1, "Confidence": 95,
+			"Relationships": [
+				{"Type": "VALUE", "Ids": ["value1"]},
+				{"Type": "CHILD", "Ids": ["w1", "w2"]}
+			]
+		},
+		{
+			"Id": "value1", "BlockType": "KEY_VALUE_SET", "EntityTypes": ["VALUE"], "Page": 1, "Confidence": 90,
+			"Relationships": [{"Type": "CHILD", "Ids": ["w3", "w4"]}]
+		}
+	]
+}`
+
+func loadSingleDocument(t *testing.T, jsonData string) Document {
+	t.Helper()
+	docs, err := LoadFromJSONReader(strings.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Failed to load JSON: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 document, got %d", len(docs))
+	}
+	return docs[0]
+}
+
+func TestFormKeyValuePairs(t *testing.T) {
+	doc := loadSingleDocument(t, formJSON)
+
+	// Without following the VALUE relationship the key has no value and the
+	// page has no form at all
+	forms := doc.Pages()[0].Forms()
+	if len(forms) != 1 {
+		t.Fatalf("Expected 1 form, got %d", len(forms))
+	}
+	fields := forms[0].Fields()
+	if len(fields) != 1 {
+		t.Fatalf("Expected 1 field, got %d", len(fields))
+	}
+
+	// The children of a key or value are its words, joining them with ": "
+	// gave "Due: Date" and "April: 20"
+	field := fields[0]
+	if field.KeyText() != "Due Date" {
+		t.Errorf("Expected key %q, got %q", "Due Date", field.KeyText())
+	}
+	if field.ValueText() != "April 20" {
+		t.Errorf("Expected value %q, got %q", "April 20", field.ValueText())
+	}
+	if field.Confidence() != 90 {
+		t.Errorf("Expected the lower confidence of key and value, got %v", field.Confidence())
+	}
+	if forms[0].GetFieldByKey("Due Date") == nil {
+		t.Errorf("Expected to find the field by its key")
+	}
+}
+
+func TestMissingChildBlock(t *testing.T) {
+	// A single page of a paginated response references blocks of the next
+	// page. This used to log.Fatal and exit the whole process, the same goes
+	// for the words missing from the fixtures in json_test.go.
+	jsonData := `{
+		"DocumentMetadata": {"Pages": 1},
+		"Blocks": [
+			{
+				"Id": "page1", "BlockType": "PAGE", "Page": 1, "Confidence": 100,
+				"Relationships": [{"Type": "CHILD", "Ids": ["line1", "line-on-next-page"]}]
+			},
+			{"Id": "line1", "BlockType": "LINE", "Page": 1, "Confidence": 99, "Text": "Kept"}
+		]
+	}`
+
+	doc := loadSingleDocument(t, jsonData)
+	lines := doc.Pages()[0].Lines()
+	if len(lines) != 1 || lines[0].Text() != "Kept" {
+		t.Errorf("Expected the line that is present, got %d lines", len(lines))
+	}
+}
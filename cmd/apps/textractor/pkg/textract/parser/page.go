@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"slices"
+	"sort"
 )
 
 // pageImpl implements the Page interface
@@ -177,6 +178,15 @@ func (p *pageImpl) buildForms() error {
 		return nil // No forms on this page
 	}
 
+	// The index is a map, put fields in reading order
+	sort.Slice(keyBlocks, func(i, j int) bool {
+		bi, bj := keyBlocks[i].BoundingBox(), keyBlocks[j].BoundingBox()
+		if abs(bi.Top-bj.Top) < 0.005 {
+			return bi.Left < bj.Left
+		}
+		return bi.Top < bj.Top
+	})
+
 	// Create a single form for the page
 	form := newForm(p)
 	p.forms = append(p.forms, form)
@@ -196,6 +206,17 @@ func (p *pageImpl) buildForms() error {
 				// Add to form
 				formImpl := form.(*formImpl)
 				formImpl.addField(kv)
+
+				// Checkboxes are children of the value
+				for _, child := range parent.Children() {
+					if child.BlockType() == BlockTypeSelectionElement {
+						element, err := newSelectionElement(child, form)
+						if err != nil {
+							return fmt.Errorf("creating selection element: %w", err)
+						}
+						formImpl.addSelectionElement(element)
+					}
+				}
 				break
 			}
 		}
@@ -109,7 +109,7 @@ func (t *tableImpl) processTableStructure() error {
 
 	// Create cells
 	for _, child := range t.block.Children() {
-		log.Info().Str("child.blockID", child.ID()).Msg("processing child")
+		log.Debug().Str("child.blockID", child.ID()).Msg("processing child")
 		if child.BlockType() == BlockTypeCell {
 			cell, err := newCell(child, t)
 			if err != nil {
@@ -118,12 +118,12 @@ func (t *tableImpl) processTableStructure() error {
 
 			rowIdx := getRowIndex(child)
 			colIdx := getColumnIndex(child)
-			log.Info().Int("rowIdx", rowIdx).Int("colIdx", colIdx).Msg("setting cell")
+			log.Debug().Int("rowIdx", rowIdx).Int("colIdx", colIdx).Msg("setting cell")
 			t.cells[rowIdx][colIdx] = cell
 		}
 	}
 
-	log.Info().Int("cells", len(t.cells)).Msg("cells")
+	log.Debug().Int("cells", len(t.cells)).Msg("cells")
 
 	// Process merged cells
 	if err := t.processMergedCells(); err != nil {
@@ -167,7 +167,7 @@ func (t *tableImpl) createRows() error {
 			}
 		}
 
-		log.Info().Int("rowIdx", i).Interface("rowCells", rowCells).Msg("row cells")
+		log.Debug().Int("rowIdx", i).Interface("rowCells", rowCells).Msg("row cells")
 
 		// check that no cell is nil
 		for j, cell := range rowCells {
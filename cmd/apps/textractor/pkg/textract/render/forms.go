@@ -0,0 +1,75 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// Field is a form field flattened for export
+type Field struct {
+	// Document is set when a result file holds several documents
+	Document int    `json:"document,omitempty"`
+	Page     int    `json:"page"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	// Selected is set for checkboxes and radio buttons
+	Selected   *bool   `json:"selected,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// FormFields returns the form fields of a document in reading order. The
+// trailing colon Textract keeps on keys is removed.
+func FormFields(doc parser.Document) []Field {
+	fields := []Field{}
+	for _, page := range doc.Pages() {
+		for _, form := range page.Forms() {
+			for _, kv := range form.Fields() {
+				field := Field{
+					Page:       page.Number(),
+					Key:        strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(kv.KeyText()), ":")),
+					Value:      strings.TrimSpace(kv.ValueText()),
+					Confidence: kv.Confidence(),
+					Selected:   selectionStatus(kv),
+				}
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// selectionStatus returns whether the checkbox that is the value of a field
+// is ticked, or nil if the value isn't a checkbox
+func selectionStatus(kv parser.KeyValue) *bool {
+	for _, child := range kv.Value().Children() {
+		if child.BlockType() == parser.BlockTypeSelectionElement {
+			selected := child.SelectionStatus() == string(parser.SelectionStatusSelected)
+			return &selected
+		}
+	}
+	return nil
+}
+
+// WriteFormsJSON writes the form fields of all documents as one JSON array
+func WriteFormsJSON(w io.Writer, docs ...parser.Document) error {
+	fields := []Field{}
+	for i, doc := range docs {
+		for _, field := range FormFields(doc) {
+			if len(docs) > 1 {
+				field.Document = i + 1
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(fields); err != nil {
+		return fmt.Errorf("encoding form fields: %w", err)
+	}
+	return nil
+}
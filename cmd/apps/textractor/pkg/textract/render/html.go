@@ -0,0 +1,154 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// HTMLOptions controls the bounding box overlay
+type HTMLOptions struct {
+	Title string
+	// Images maps page numbers to image sources, used as the img src as is.
+	// Pages without an image are drawn as blank letter-sized pages.
+	Images map[int]template.URL
+	// PageWidth is the width of a page in CSS pixels
+	PageWidth int
+	// LowConfidence marks boxes below this confidence (0-100)
+	LowConfidence float64
+}
+
+// DefaultHTMLOptions returns the options used by the render command
+func DefaultHTMLOptions() HTMLOptions {
+	return HTMLOptions{
+		Title:         "Textract results",
+		PageWidth:     900,
+		LowConfidence: 80,
+	}
+}
+
+type htmlBox struct {
+	Kind                     string
+	Left, Top, Width, Height float64 // percent of the page
+	Label                    string
+	Low                      bool
+}
+
+type htmlPage struct {
+	Number int
+	Image  template.URL
+	Boxes  []htmlBox
+}
+
+// WriteHTML writes a self-contained page that draws the bounding boxes of
+// lines, tables, cells, form keys, values and checkboxes over the page
+// images. Hovering a box shows its text and confidence.
+func WriteHTML(w io.Writer, doc parser.Document, opts HTMLOptions) error {
+	if opts.PageWidth <= 0 {
+		opts.PageWidth = DefaultHTMLOptions().PageWidth
+	}
+
+	var pages []htmlPage
+	for _, page := range doc.Pages() {
+		pages = append(pages, htmlPage{
+			Number: page.Number(),
+			Image:  opts.Images[page.Number()],
+			Boxes:  pageBoxes(page, opts.LowConfidence),
+		})
+	}
+
+	err := htmlTemplate.Execute(w, struct {
+		Title     string
+		PageWidth int
+		Pages     []htmlPage
+	}{opts.Title, opts.PageWidth, pages})
+	if err != nil {
+		return fmt.Errorf("rendering HTML: %w", err)
+	}
+	return nil
+}
+
+func pageBoxes(page parser.Page, lowConfidence float64) []htmlBox {
+	var boxes []htmlBox
+	add := func(kind string, bb parser.BoundingBox, text string, confidence float64) {
+		boxes = append(boxes, htmlBox{
+			Kind:   kind,
+			Left:   bb.Left * 100,
+			Top:    bb.Top * 100,
+			Width:  bb.Width * 100,
+			Height: bb.Height * 100,
+			Label:  fmt.Sprintf("%s (%.1f%%): %s", kind, confidence, text),
+			Low:    confidence < lowConfidence,
+		})
+	}
+
+	for _, table := range page.Tables() {
+		add("table", table.BoundingBox(), fmt.Sprintf("%dx%d", table.RowCount(), table.ColumnCount()), 100)
+		for _, r := range table.Cells() {
+			for _, cell := range r {
+				if cell != nil {
+					add("cell", cell.BoundingBox(), cell.Text(), cell.Confidence())
+				}
+			}
+		}
+	}
+	for _, line := range page.Lines() {
+		add("line", line.BoundingBox(), line.Text(), line.Confidence())
+	}
+	for _, form := range page.Forms() {
+		for _, kv := range form.Fields() {
+			add("key", kv.Key().BoundingBox(), kv.KeyText(), kv.Key().Confidence())
+			add("value", kv.Value().BoundingBox(), kv.ValueText(), kv.Value().Confidence())
+		}
+		for _, element := range form.SelectionElements() {
+			add("selection", element.BoundingBox(), string(element.SelectionStatus()), element.Confidence())
+		}
+	}
+
+	return boxes
+}
+
+// boxKinds are the classes of boxes, each can be toggled in the page
+var boxKinds = []string{"line", "table", "cell", "key", "value", "selection"}
+
+var htmlTemplate = template.Must(template.New("overlay").Funcs(template.FuncMap{
+	"kinds": func() []string { return boxKinds },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #eee; margin: 0; padding: 1em; }
+.controls { position: sticky; top: 0; background: #eee; padding: .5em 0; z-index: 10; }
+.controls label { margin-right: 1em; }
+.page { position: relative; width: {{.PageWidth}}px; margin: 1em auto; background: white; box-shadow: 0 0 4px #999; }
+.page img { display: block; width: 100%; }
+.page .blank { padding-bottom: 129.4%; }
+.page h2 { position: absolute; top: -1.6em; left: 0; margin: 0; font-size: 1em; color: #555; }
+.box { position: absolute; box-sizing: border-box; border: 1px solid; }
+.box:hover { background: rgba(255, 255, 0, .3); z-index: 5; }
+.line { border-color: #1f77b4; }
+.table { border: 2px solid #2ca02c; }
+.cell { border-color: #98df8a; }
+.key { border-color: #d62728; }
+.value { border-color: #ff7f0e; }
+.selection { border: 2px solid #9467bd; }
+.box.low { background: rgba(255, 0, 0, .15); }
+{{range $kind := kinds}}body.hide-{{$kind}} .{{$kind}} { display: none; }
+{{end}}</style>
+</head>
+<body>
+<div class="controls">
+{{range $kind := kinds}}<label><input type="checkbox" checked onchange="document.body.classList.toggle('hide-{{$kind}}', !this.checked)"> {{$kind}}</label>
+{{end}}</div>
+{{range .Pages}}<div class="page" id="page-{{.Number}}">
+<h2>Page {{.Number}}</h2>
+{{if .Image}}<img src="{{.Image}}" alt="page {{.Number}}">{{else}}<div class="blank"></div>{{end}}
+{{range .Boxes}}<div class="box {{.Kind}}{{if .Low}} low{{end}}" style="left: {{printf "%.3f" .Left}}%; top: {{printf "%.3f" .Top}}%; width: {{printf "%.3f" .Width}}%; height: {{printf "%.3f" .Height}}%" title="{{.Label}}"></div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
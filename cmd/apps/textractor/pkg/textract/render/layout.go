@@ -0,0 +1,138 @@
+package render
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// row is a group of lines that sit next to each other on the page
+type row struct {
+	lines  []parser.Line
+	top    float64
+	bottom float64
+}
+
+func (r *row) text() string {
+	texts := make([]string, len(r.lines))
+	for i, line := range r.lines {
+		texts[i] = line.Text()
+	}
+	return strings.Join(texts, " ")
+}
+
+func (r *row) height() float64 {
+	return r.bottom - r.top
+}
+
+// item is a row of text or a table, in reading order
+type item struct {
+	row   *row
+	table parser.Table
+}
+
+func (i item) top() float64 {
+	if i.table != nil {
+		return i.table.BoundingBox().Top
+	}
+	return i.row.top
+}
+
+// pageItems returns the rows and tables of a page in reading order. Lines
+// inside tables are dropped, since the table renders them.
+func pageItems(page parser.Page) []item {
+	tables := page.Tables()
+
+	var lines []parser.Line
+	for _, line := range page.Lines() {
+		if !insideAny(line.BoundingBox(), tables) {
+			lines = append(lines, line)
+		}
+	}
+
+	var items []item
+	for _, r := range groupRows(lines) {
+		items = append(items, item{row: r})
+	}
+	for _, table := range tables {
+		items = append(items, item{table: table})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].top() < items[j].top()
+	})
+
+	return items
+}
+
+// groupRows puts lines whose vertical centers are within half a line height
+// of each other on the same row, left to right
+func groupRows(lines []parser.Line) []*row {
+	sorted := append([]parser.Line(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return center(sorted[i].BoundingBox()) < center(sorted[j].BoundingBox())
+	})
+
+	var rows []*row
+	var current *row
+	for _, line := range sorted {
+		bb := line.BoundingBox()
+		if current != nil {
+			rowCenter := (current.top + current.bottom) / 2
+			if abs(center(bb)-rowCenter) < max(bb.Height, current.height())/2 {
+				current.lines = append(current.lines, line)
+				current.top = min(current.top, bb.Top)
+				current.bottom = max(current.bottom, bb.Top+bb.Height)
+				continue
+			}
+		}
+		current = &row{lines: []parser.Line{line}, top: bb.Top, bottom: bb.Top + bb.Height}
+		rows = append(rows, current)
+	}
+
+	for _, r := range rows {
+		sort.SliceStable(r.lines, func(i, j int) bool {
+			return r.lines[i].BoundingBox().Left < r.lines[j].BoundingBox().Left
+		})
+	}
+	return rows
+}
+
+// medianLineHeight is the height of a typical line of body text
+func medianLineHeight(lines []parser.Line) float64 {
+	if len(lines) == 0 {
+		return 0
+	}
+	heights := make([]float64, len(lines))
+	for i, line := range lines {
+		heights[i] = line.BoundingBox().Height
+	}
+	sort.Float64s(heights)
+	return heights[len(heights)/2]
+}
+
+func center(bb parser.BoundingBox) float64 {
+	return bb.Top + bb.Height/2
+}
+
+// inside reports whether the center of a lies within b
+func inside(a, b parser.BoundingBox) bool {
+	x, y := a.Left+a.Width/2, center(a)
+	return x >= b.Left && x <= b.Left+b.Width && y >= b.Top && y <= b.Top+b.Height
+}
+
+func insideAny(bb parser.BoundingBox, tables []parser.Table) bool {
+	for _, table := range tables {
+		if inside(bb, table.BoundingBox()) {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,215 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// MarkdownOptions controls how documents are turned into markdown
+type MarkdownOptions struct {
+	// HeadingRatio is how much taller than the page's median line a short
+	// line has to be to become a second level heading
+	HeadingRatio float64
+	// TitleRatio is the same for first level headings
+	TitleRatio float64
+	// ReflowParagraphs joins the lines of a paragraph with spaces instead of
+	// keeping the line breaks of the page
+	ReflowParagraphs bool
+	// PageMarkers puts an HTML comment with the page number before each page
+	PageMarkers bool
+	// BoldKeys emphasizes form keys at the start of a line and puts [ ] or
+	// [x] in front of checkbox labels
+	BoldKeys bool
+}
+
+// DefaultMarkdownOptions returns the options used by the render command
+func DefaultMarkdownOptions() MarkdownOptions {
+	return MarkdownOptions{
+		HeadingRatio: 1.2,
+		TitleRatio:   1.6,
+		PageMarkers:  true,
+		BoldKeys:     true,
+	}
+}
+
+// Headings are short; longer lines in a large font are body text
+const maxHeadingWords = 10
+
+// WriteMarkdown renders the text of a document in reading order, with
+// headings guessed from line heights and tables as markdown tables
+func WriteMarkdown(w io.Writer, doc parser.Document, opts MarkdownOptions) error {
+	bw := bufio.NewWriter(w)
+
+	for i, page := range doc.Pages() {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		if opts.PageMarkers {
+			fmt.Fprintf(bw, "<!-- page %d -->\n\n", page.Number())
+		}
+		if err := writePageMarkdown(bw, page, opts); err != nil {
+			return fmt.Errorf("rendering page %d: %w", page.Number(), err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writePageMarkdown(w io.Writer, page parser.Page, opts MarkdownOptions) error {
+	lineHeight := medianLineHeight(page.Lines())
+	keys := formKeys(page)
+
+	// Blocks are separated by blank lines, rows within a paragraph by line
+	// breaks
+	var paragraph []string
+	var last *row
+	first := true
+	emit := func(block string) {
+		if !first {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, block)
+		first = false
+	}
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		sep := "  \n"
+		if opts.ReflowParagraphs {
+			sep = " "
+		}
+		emit(strings.Join(paragraph, sep))
+		paragraph = nil
+	}
+
+	for _, it := range pageItems(page) {
+		if it.table != nil {
+			flush()
+			var sb strings.Builder
+			if err := WriteMarkdownTable(&sb, it.table); err != nil {
+				return err
+			}
+			emit(strings.TrimSuffix(sb.String(), "\n"))
+			last = nil
+			continue
+		}
+
+		r := it.row
+		if level := headingLevel(r, lineHeight, keys, opts); level > 0 {
+			flush()
+			emit(strings.Repeat("#", level) + " " + escapeMarkdown(r.text()))
+			last = nil
+			continue
+		}
+
+		// A gap of most of a line starts a new paragraph
+		if last != nil && r.top-last.bottom > 0.8*lineHeight {
+			flush()
+		}
+		paragraph = append(paragraph, rowMarkdown(r, keys, opts))
+		last = r
+	}
+	flush()
+
+	return nil
+}
+
+// headingLevel returns 1 or 2 for rows that look like headings, 0 otherwise
+func headingLevel(r *row, lineHeight float64, keys []formKey, opts MarkdownOptions) int {
+	if lineHeight <= 0 || len(r.lines) != 1 {
+		return 0
+	}
+	line := r.lines[0]
+	if len(strings.Fields(line.Text())) > maxHeadingWords {
+		return 0
+	}
+	// Labels in forms are often set larger but aren't headings
+	for _, key := range keys {
+		if inside(key.box, line.BoundingBox()) {
+			return 0
+		}
+	}
+
+	ratio := line.BoundingBox().Height / lineHeight
+	switch {
+	case opts.TitleRatio > 0 && ratio >= opts.TitleRatio:
+		return 1
+	case opts.HeadingRatio > 0 && ratio >= opts.HeadingRatio:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func rowMarkdown(r *row, keys []formKey, opts MarkdownOptions) string {
+	texts := make([]string, len(r.lines))
+	for i, line := range r.lines {
+		texts[i] = lineMarkdown(line, keys, opts)
+	}
+	return strings.Join(texts, " ")
+}
+
+func lineMarkdown(line parser.Line, keys []formKey, opts MarkdownOptions) string {
+	text := line.Text()
+	if opts.BoldKeys {
+		for _, key := range keys {
+			if key.text != "" && strings.HasPrefix(text, key.text) && inside(key.box, line.BoundingBox()) {
+				rest := strings.TrimPrefix(text, key.text)
+				if key.selected != nil {
+					box := "[ ] "
+					if *key.selected {
+						box = "[x] "
+					}
+					return box + escapeMarkdown(text)
+				}
+				return "**" + escapeMarkdown(key.text) + "**" + escapeMarkdown(rest)
+			}
+		}
+	}
+	return escapeMarkdown(text)
+}
+
+type formKey struct {
+	text     string
+	box      parser.BoundingBox
+	selected *bool // for checkbox labels
+}
+
+func formKeys(page parser.Page) []formKey {
+	var keys []formKey
+	for _, form := range page.Forms() {
+		for _, field := range form.Fields() {
+			key := formKey{text: field.KeyText(), box: field.Key().BoundingBox()}
+			key.selected = selectionStatus(field)
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"|", `\|`,
+)
+
+// escapeMarkdown keeps OCR text from being read as markdown syntax
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+	// Characters that only matter at the start of a line
+	if strings.HasPrefix(s, "#") || strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "+ ") {
+		s = `\` + s
+	}
+	return s
+}
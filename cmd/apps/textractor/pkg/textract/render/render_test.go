@@ -0,0 +1,255 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+func loadDocument(t *testing.T, name string) parser.Document {
+	t.Helper()
+	docs, err := parser.LoadFromJSON("../../../test-docs/json/" + name + ".json")
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", name, err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 document, got %d", len(docs))
+	}
+	return docs[0]
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	doc := loadDocument(t, "invoice")
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, doc, DefaultMarkdownOptions()); err != nil {
+		t.Fatalf("Failed to render markdown: %v", err)
+	}
+	md := buf.String()
+
+	for _, expected := range []string{
+		"<!-- page 1 -->\n",
+		"# Sample Invoice\n",
+		"## INVOICE\n",
+		"**Invoice #:** INV-2024-001  \n**Date:** March 20, 2024",
+		"Acme Corporation  \n123 Business St",
+		"| Item                 | Quantity | Rate    | Amount   |\n| -------------------- |",
+		"| Project Management   | 5        | $175.00 | $875.00  |\n",
+		"**Total:** $7012.50",
+	} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", expected, md)
+		}
+	}
+
+	// The table cells are only rendered in the table
+	if strings.Count(md, "Consulting Services") != 1 {
+		t.Errorf("Expected table text once, got:\n%s", md)
+	}
+	// Reading order: the table comes between the addresses and the totals
+	if strings.Index(md, "67890") > strings.Index(md, "| Item") || strings.Index(md, "| Item") > strings.Index(md, "Subtotal") {
+		t.Errorf("Unexpected order:\n%s", md)
+	}
+
+	opts := DefaultMarkdownOptions()
+	opts.ReflowParagraphs = true
+	opts.PageMarkers = false
+	buf.Reset()
+	if err := WriteMarkdown(&buf, doc, opts); err != nil {
+		t.Fatalf("Failed to render markdown: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Acme Corporation 123 Business St Business City, 12345\n") {
+		t.Errorf("Expected reflowed paragraph, got:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "<!--") {
+		t.Errorf("Expected no page markers")
+	}
+}
+
+func TestWriteMarkdownCheckboxes(t *testing.T) {
+	doc := loadDocument(t, "forms")
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, doc, DefaultMarkdownOptions()); err != nil {
+		t.Fatalf("Failed to render markdown: %v", err)
+	}
+	if !strings.Contains(buf.String(), "[ ] Mr. [ ] Mrs. [ ] Ms. [ ] Dr.\n") {
+		t.Errorf("Expected checkboxes, got:\n%s", buf.String())
+	}
+}
+
+func TestFormFields(t *testing.T) {
+	fields := FormFields(loadDocument(t, "invoice"))
+
+	values := map[string]string{}
+	for _, field := range fields {
+		values[field.Key] = field.Value
+		if field.Page != 1 {
+			t.Errorf("Expected page 1, got %d", field.Page)
+		}
+	}
+	for key, expected := range map[string]string{
+		"Invoice #": "INV-2024-001",
+		"Due Date":  "April 20, 2024",
+		"Tax (10%)": "$637.50",
+		"From":      "Acme Corporation 123 Business St Business City, 12345",
+	} {
+		if values[key] != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, key, values[key])
+		}
+	}
+
+	// Fields are in reading order
+	if fields[0].Key != "Invoice #" {
+		t.Errorf("Expected first field to be Invoice #, got %q", fields[0].Key)
+	}
+
+	fields = FormFields(loadDocument(t, "forms"))
+	checkboxes := 0
+	for _, field := range fields {
+		if field.Selected != nil {
+			checkboxes++
+			if *field.Selected {
+				t.Errorf("Expected %q to be unchecked", field.Key)
+			}
+		}
+	}
+	if checkboxes != 10 {
+		t.Errorf("Expected 10 checkboxes, got %d", checkboxes)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFormsJSON(&buf, loadDocument(t, "forms")); err != nil {
+		t.Fatalf("Failed to write forms: %v", err)
+	}
+	var decoded []Field
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode forms JSON: %v", err)
+	}
+	if len(decoded) != len(fields) {
+		t.Errorf("Expected %d fields, got %d", len(fields), len(decoded))
+	}
+}
+
+func TestWriteTableCSV(t *testing.T) {
+	tables := loadDocument(t, "invoice").Pages()[0].Tables()
+	if len(tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(tables))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTableCSV(&buf, tables[0]); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	expected := "Item,Quantity,Rate,Amount\n" +
+		"Consulting Services,10,$150.00,$1500.00\n" +
+		"Software Development,20,$200.00,$4000.00\n" +
+		"Project Management,5,$175.00,$875.00\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// Fakes for tables that don't match their declared column count. Methods
+// that aren't overridden panic.
+type fakeTable struct {
+	parser.Table
+	columns int
+	rows    [][]string
+}
+
+type fakeRow struct {
+	parser.TableRow
+	cells []parser.Cell
+}
+
+type fakeCell struct {
+	parser.Cell
+	text string
+}
+
+func (t fakeTable) ColumnCount() int { return t.columns }
+
+func (t fakeTable) Rows() []parser.TableRow {
+	var rows []parser.TableRow
+	for _, r := range t.rows {
+		row := fakeRow{}
+		for _, text := range r {
+			row.cells = append(row.cells, fakeCell{text: text})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r fakeRow) Cells() []parser.Cell { return r.cells }
+
+func (c fakeCell) Text() string { return c.text }
+
+func TestWriteMarkdownTableWiderRows(t *testing.T) {
+	table := fakeTable{
+		columns: 2,
+		rows: [][]string{
+			{"Name", "Qty"},
+			{"Bolts", "10", "merged"},
+			{"Nuts"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdownTable(&buf, table); err != nil {
+		t.Fatalf("Failed to write table: %v", err)
+	}
+	expected := "| Name  | Qty |        |\n" +
+		"| ----- | --- | ------ |\n" +
+		"| Bolts | 10  | merged |\n" +
+		"| Nuts  |     |        |\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	doc := loadDocument(t, "forms")
+
+	opts := DefaultHTMLOptions()
+	opts.Images = map[int]template.URL{1: "scans/forms-1.png"}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, doc, opts); err != nil {
+		t.Fatalf("Failed to render HTML: %v", err)
+	}
+	html := buf.String()
+
+	if !strings.Contains(html, `<img src="scans/forms-1.png"`) {
+		t.Errorf("Expected page image")
+	}
+	if n := strings.Count(html, `class="box line`); n != len(doc.Pages()[0].Lines()) {
+		t.Errorf("Expected a box per line, got %d", n)
+	}
+	if n := strings.Count(html, `class="box selection`); n != 10 {
+		t.Errorf("Expected 10 checkbox boxes, got %d", n)
+	}
+	// Line text is escaped into the tooltip
+	if !strings.Contains(html, `title="line (`) {
+		t.Errorf("Expected tooltips")
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"plain text":      "plain text",
+		"# not a heading": `\# not a heading`,
+		"a_b*c":           `a\_b\*c`,
+		"[x] | y":         `\[x\] \| y`,
+		"Invoice #: 1":    "Invoice #: 1",
+	}
+	for input, expected := range tests {
+		if got := escapeMarkdown(input); got != expected {
+			t.Errorf("escapeMarkdown(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
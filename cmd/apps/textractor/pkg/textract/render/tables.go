@@ -0,0 +1,97 @@
+package render
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-go-golems/go-go-labs/cmd/apps/textractor/pkg/textract/parser"
+)
+
+// WriteMarkdownTable writes a table as a markdown table. The first row
+// becomes the header, since markdown tables need one.
+func WriteMarkdownTable(w io.Writer, table parser.Table) error {
+	rows := tableText(table)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Merged or misreported cells can give a row more cells than the table
+	// has columns, size the table from the widest row
+	columns := table.ColumnCount()
+	for _, r := range rows {
+		columns = max(columns, len(r))
+	}
+	for j, r := range rows {
+		for i, cell := range r {
+			r[i] = escapeMarkdown(cell)
+		}
+		// Pad short rows so every row has the same number of cells
+		rows[j] = append(r, make([]string, columns-len(r))...)
+	}
+
+	widths := make([]int, columns)
+	for _, r := range rows {
+		for i, cell := range r {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell), 3)
+		}
+	}
+
+	writeRow := func(cells []string) error {
+		var sb strings.Builder
+		sb.WriteString("|")
+		for i, cell := range cells {
+			sb.WriteString(" ")
+			sb.WriteString(cell)
+			sb.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			sb.WriteString(" |")
+		}
+		_, err := fmt.Fprintln(w, sb.String())
+		return err
+	}
+
+	if err := writeRow(rows[0]); err != nil {
+		return err
+	}
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	if err := writeRow(separator); err != nil {
+		return err
+	}
+	for _, r := range rows[1:] {
+		if err := writeRow(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableText returns the text of every cell, row by row
+func tableText(table parser.Table) [][]string {
+	var rows [][]string
+	for _, r := range table.Rows() {
+		cells := make([]string, len(r.Cells()))
+		for i, cell := range r.Cells() {
+			if cell != nil {
+				// Cells can hold several lines
+				cells[i] = strings.Join(strings.Fields(cell.Text()), " ")
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// WriteTableCSV writes all rows of a table, including the header row
+func WriteTableCSV(w io.Writer, table parser.Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(tableText(table)); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
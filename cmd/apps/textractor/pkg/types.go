@@ -13,3 +13,14 @@ type TextractJob struct {
 	ResultKey   string     `json:"result_key" dynamodbav:"ResultKey"`
 	Error       string     `json:"error,omitempty" dynamodbav:"Error,omitempty"`
 }
+
+// Job statuses. The CLI sets UPLOADING, SUBMITTED and ERROR, the Lambda
+// processors set the rest.
+const (
+	JobStatusUploading  = "UPLOADING"
+	JobStatusSubmitted  = "SUBMITTED"
+	JobStatusProcessing = "PROCESSING"
+	JobStatusCompleted  = "COMPLETED"
+	JobStatusFailed     = "FAILED"
+	JobStatusError      = "ERROR"
+)
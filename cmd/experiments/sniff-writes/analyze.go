@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/analysis"
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/database"
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/formatter"
+)
+
+// Analyze-specific flags
+var analyzeFlags struct {
+	startTime string
+	endTime   string
+	filename  string
+	format    string
+	top       int
+	rmwWindow time.Duration
+	hotWindow time.Duration
+	depth     int
+	showDiffs bool
+	showFinal bool
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze a recorded database offline",
+	Long: `Analyze file activity recorded with --sqlite. No eBPF or root privileges are
+needed, so recordings can be studied on any machine.
+
+Examples:
+  # Processes writing far more bytes than the files they produce
+  sniff-writes analyze amplification --sqlite /tmp/file_events.db
+
+  # Files that get rewritten most often
+  sniff-writes analyze rewrites --sqlite /tmp/file_events.db --top 20
+
+  # Read-modify-write cycles completing within 5 seconds
+  sniff-writes analyze rmw --sqlite /tmp/file_events.db --window 5s
+
+  # Busiest directories per 10 minute window, grouped 3 levels deep
+  sniff-writes analyze hot-dirs --sqlite /tmp/file_events.db --window 10m --depth 3
+
+  # Content history of a file with diffs between versions
+  sniff-writes analyze history --sqlite /tmp/file_events.db --file notes/todo.md --diffs`,
+}
+
+var analyzeAmplificationCmd = &cobra.Command{
+	Use:   "amplification",
+	Short: "Show per-process write amplification",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recording, err := loadRecording()
+		if err != nil {
+			return err
+		}
+		stats := recording.WriteAmplification()
+		if analyzeFlags.top > 0 && len(stats) > analyzeFlags.top {
+			stats = stats[:analyzeFlags.top]
+		}
+
+		return writeAnalysis(stats, func(w io.Writer) {
+			fmt.Fprintln(w, "PROCESS\tPIDS\tWRITES\tWRITTEN\tREADS\tREAD\tFILES\tUNIQUE\tAMPLIFICATION")
+			for _, s := range stats {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.2fx\n",
+					s.Process, s.PIDs, s.Writes, s.BytesWritten, s.Reads, s.BytesRead,
+					s.Files, s.UniqueBytes, s.Amplification)
+			}
+		})
+	},
+}
+
+var analyzeRewritesCmd = &cobra.Command{
+	Use:   "rewrites",
+	Short: "Show the files rewritten most often",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recording, err := loadRecording()
+		if err != nil {
+			return err
+		}
+		stats := recording.MostRewrittenFiles()
+		if analyzeFlags.top > 0 && len(stats) > analyzeFlags.top {
+			stats = stats[:analyzeFlags.top]
+		}
+
+		return writeAnalysis(stats, func(w io.Writer) {
+			fmt.Fprintln(w, "FILENAME\tREWRITES\tSESSIONS\tWRITES\tBYTES\tPROCESSES\tLAST WRITE")
+			for _, s := range stats {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+					s.Filename, s.Rewrites, s.WriteSessions, s.Writes, s.BytesWritten,
+					strings.Join(s.Processes, ","), s.LastWrite)
+			}
+		})
+	},
+}
+
+var analyzeRMWCmd = &cobra.Command{
+	Use:   "rmw",
+	Short: "Show read-modify-write cycles",
+	Long:  "Show read-modify-write cycles: a read followed by a write to the same file by the same process within --window.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recording, err := loadRecording()
+		if err != nil {
+			return err
+		}
+		summaries := analysis.SummarizeCycles(recording.ReadModifyWriteCycles(analyzeFlags.rmwWindow))
+		if analyzeFlags.top > 0 && len(summaries) > analyzeFlags.top {
+			summaries = summaries[:analyzeFlags.top]
+		}
+
+		return writeAnalysis(summaries, func(w io.Writer) {
+			fmt.Fprintln(w, "FILENAME\tPROCESS\tCYCLES\tREAD\tWRITTEN")
+			for _, s := range summaries {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", s.Filename, s.Process, s.Cycles, s.BytesRead, s.BytesWritten)
+			}
+		})
+	},
+}
+
+var analyzeHotDirsCmd = &cobra.Command{
+	Use:   "hot-dirs",
+	Short: "Show the busiest directories per time window",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recording, err := loadRecording()
+		if err != nil {
+			return err
+		}
+		activity := recording.HotDirectories(analyzeFlags.hotWindow, analyzeFlags.depth, analyzeFlags.top)
+
+		return writeAnalysis(activity, func(w io.Writer) {
+			fmt.Fprintln(w, "WINDOW\tDIRECTORY\tEVENTS\tWRITES\tREADS\tBYTES\tPROCESSES")
+			for _, a := range activity {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n",
+					a.WindowStart.Format(time.RFC3339), a.Directory, a.Events, a.Writes, a.Reads, a.Bytes, a.Processes)
+			}
+		})
+	},
+}
+
+var analyzeHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Reconstruct the content history of a file",
+	Long: `Reconstruct the successive versions of a file from captured write chunks.
+Requires a recording made with --capture-content. Versions marked partial are
+missing bytes that were not captured.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if analyzeFlags.filename == "" {
+			return fmt.Errorf("--file is required")
+		}
+
+		recording, err := loadRecording()
+		if err != nil {
+			return err
+		}
+		versions := recording.FileHistory(analyzeFlags.filename)
+		if len(versions) == 0 {
+			return fmt.Errorf("no writes recorded for %s", analyzeFlags.filename)
+		}
+		if !analyzeFlags.showDiffs {
+			for i := range versions {
+				versions[i].Diff = ""
+			}
+		}
+
+		if analyzeFlags.format == "json" {
+			return writeAnalysis(versions, nil)
+		}
+
+		diffFormatter := formatter.NewColoredDiffFormatter()
+		for _, v := range versions {
+			partial := ""
+			if v.Partial {
+				partial = " (partial)"
+			}
+			fmt.Printf("v%d %s pid=%d process=%s writes=%d size=%d sha256=%s%s\n",
+				v.Version, v.Timestamp, v.Pid, v.Process, v.Writes, v.Size, v.Hash[:12], partial)
+			if v.Diff != "" {
+				fmt.Print(diffFormatter.FormatDiff(v.Diff))
+			}
+		}
+		if analyzeFlags.showFinal {
+			fmt.Printf("\n--- content of v%d ---\n", versions[len(versions)-1].Version)
+			fmt.Println(string(versions[len(versions)-1].Content))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+	analyzeCmd.AddCommand(analyzeAmplificationCmd)
+	analyzeCmd.AddCommand(analyzeRewritesCmd)
+	analyzeCmd.AddCommand(analyzeRMWCmd)
+	analyzeCmd.AddCommand(analyzeHotDirsCmd)
+	analyzeCmd.AddCommand(analyzeHistoryCmd)
+
+	analyzeCmd.PersistentFlags().StringVar(&config.SqliteDB, "sqlite", "", "SQLite database file path (required)")
+	analyzeCmd.PersistentFlags().StringVar(&config.ProcessFilter, "process", "", "Filter by process name (substring match)")
+	analyzeCmd.PersistentFlags().StringVar(&analyzeFlags.startTime, "start-time", "", "Start time filter (RFC3339 format)")
+	analyzeCmd.PersistentFlags().StringVar(&analyzeFlags.endTime, "end-time", "", "End time filter (RFC3339 format)")
+	analyzeCmd.PersistentFlags().StringVar(&analyzeFlags.format, "format", "table", "Output format: table, json")
+	analyzeCmd.PersistentFlags().IntVar(&analyzeFlags.top, "top", 0, "Only show the top N entries (0 = all, per window for hot-dirs)")
+	analyzeCmd.MarkPersistentFlagRequired("sqlite")
+
+	analyzeRMWCmd.Flags().DurationVar(&analyzeFlags.rmwWindow, "window", 10*time.Second, "Maximum delay between read and write (0 = unlimited)")
+	analyzeHotDirsCmd.Flags().DurationVar(&analyzeFlags.hotWindow, "window", time.Minute, "Time window size")
+	analyzeHotDirsCmd.Flags().IntVar(&analyzeFlags.depth, "depth", 0, "Group directories by their first N path components (0 = full directory)")
+	analyzeHistoryCmd.Flags().StringVar(&analyzeFlags.filename, "file", "", "File to reconstruct, as recorded in the database")
+	analyzeHistoryCmd.Flags().BoolVar(&analyzeFlags.showDiffs, "diffs", false, "Show diffs between consecutive versions")
+	analyzeHistoryCmd.Flags().BoolVar(&analyzeFlags.showFinal, "show-content", false, "Print the content of the latest version")
+}
+
+// loadRecording opens the database and loads the events matching the analyze flags
+func loadRecording() (*analysis.Recording, error) {
+	if err := initSQLite(); err != nil {
+		return nil, fmt.Errorf("failed to initialize SQLite: %w", err)
+	}
+	defer closeSQLite()
+
+	filter := database.QueryFilter{
+		ProcessFilter: config.ProcessFilter,
+	}
+	if analyzeFlags.filename != "" {
+		filter.FilenamePattern = analyzeFlags.filename
+	}
+
+	if analyzeFlags.startTime != "" {
+		startTime, err := time.Parse(time.RFC3339, analyzeFlags.startTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start-time format (use RFC3339): %w", err)
+		}
+		filter.StartTime = &startTime
+	}
+
+	if analyzeFlags.endTime != "" {
+		endTime, err := time.Parse(time.RFC3339, analyzeFlags.endTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end-time format (use RFC3339): %w", err)
+		}
+		filter.EndTime = &endTime
+	}
+
+	recording, err := analysis.Load(sqliteDB, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	return recording, nil
+}
+
+// writeAnalysis prints results as JSON or as an aligned table
+func writeAnalysis(results interface{}, table func(w io.Writer)) error {
+	if analyzeFlags.format == "json" || table == nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
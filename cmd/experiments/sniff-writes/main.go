@@ -17,6 +17,7 @@ import (
 	"github.com/rs/zerolog"
 	zlog "github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/api"
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/cache"
@@ -27,6 +28,7 @@ import (
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/formatter"
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/processor"
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/sink"
 	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/web"
 )
 
@@ -43,6 +45,7 @@ var sqliteDB *database.SQLiteDB
 var webHub *web.WebHub
 var pathCache *cache.PathCache
 var fileCache *filecache.FileCache
+var eventSinks *sink.MultiSink
 
 // Query-specific flags
 var queryFlags struct {
@@ -60,6 +63,13 @@ var serverFlags struct {
 	port int
 }
 
+// Collector-specific flags
+var collectorFlags struct {
+	port  int
+	quiet bool
+	keep  int
+}
+
 func initSQLite() error {
 	var err error
 	sqliteDB, err = database.NewSQLiteDB(config.SqliteDB)
@@ -72,6 +82,24 @@ func closeSQLite() {
 	}
 }
 
+// openSinks opens the configured sinks. Live tracing drops events when an
+// HTTP sink falls behind, replays wait for it instead.
+func openSinks(ctx context.Context, replay bool) error {
+	batch := sink.DefaultBatchConfig()
+	batch.Block = replay
+	var err error
+	eventSinks, err = sink.OpenAll(ctx, config.Sinks, batch)
+	return err
+}
+
+func closeSinks() {
+	if eventSinks != nil {
+		if err := eventSinks.Close(context.Background()); err != nil {
+			zlog.Error().Err(err).Msg("Failed to close sinks")
+		}
+	}
+}
+
 func handleIndex(w http.ResponseWriter, r *http.Request) {
 	index().Render(r.Context(), w)
 }
@@ -146,7 +174,13 @@ Examples:
   sudo sniff-writes monitor --web --web-port 8080
 
   # Web UI with filtering and database logging
-  sudo sniff-writes monitor --web --glob "*.log" --sqlite /tmp/web_events.db`,
+  sudo sniff-writes monitor --web --glob "*.log" --sqlite /tmp/web_events.db
+
+  # Stream events to NDJSON and an OpenTelemetry collector
+  sudo sniff-writes monitor --sink ndjson:/tmp/events.ndjson --sink otlp:http://localhost:4318
+
+  # Replay stored events to a webhook in batches
+  sniff-writes query --sqlite /tmp/file_events.db --limit 0 --sink webhook:https://example.com/hook`,
 }
 
 var monitorCmd = &cobra.Command{
@@ -170,11 +204,20 @@ var serverCmd = &cobra.Command{
 	RunE:  runServer,
 }
 
+var collectorCmd = &cobra.Command{
+	Use:   "collector",
+	Short: "Run a local OpenTelemetry collector stand-in",
+	Long: `Run a minimal OTLP/HTTP logs receiver that prints the records it receives.
+Use it to try the otlp sink without a real OpenTelemetry collector.`,
+	RunE: runCollector,
+}
+
 func init() {
 	// Add subcommands
 	rootCmd.AddCommand(monitorCmd)
 	rootCmd.AddCommand(queryCmd)
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(collectorCmd)
 
 	// Add global logging flags
 	rootCmd.PersistentFlags().Bool("debug-logging", false, "Enable debug logging")
@@ -222,6 +265,7 @@ func init() {
 	monitorCmd.Flags().StringVar(&config.SqliteDB, "sqlite", "", "Log events to SQLite database (specify database file path)")
 	monitorCmd.Flags().BoolVar(&config.WebUI, "web", false, "Enable real-time web UI")
 	monitorCmd.Flags().IntVar(&config.WebPort, "web-port", 8080, "Web UI port (default: 8080)")
+	monitorCmd.Flags().StringArrayVar(&config.Sinks, "sink", []string{}, "Stream events to a sink: ndjson:<file|->, columnar:<file>, otlp:<url>, webhook:<url> (repeatable)")
 
 	// Add flags to query command
 	queryCmd.Flags().StringVar(&config.SqliteDB, "sqlite", "", "SQLite database file path (required)")
@@ -236,12 +280,18 @@ func init() {
 	queryCmd.Flags().StringVar(&queryFlags.filename, "filename", "", "Filter by filename pattern")
 	queryCmd.Flags().Uint32Var(&queryFlags.pid, "pid", 0, "Filter by process ID")
 	queryCmd.Flags().StringVar(&queryFlags.exportFmt, "export", "", "Export format: json, csv, markdown")
+	queryCmd.Flags().StringArrayVar(&config.Sinks, "sink", []string{}, "Stream results to a sink: ndjson:<file|->, columnar:<file>, otlp:<url>, webhook:<url> (repeatable)")
 	queryCmd.MarkFlagRequired("sqlite")
 
 	// Add flags to server command
 	serverCmd.Flags().StringVar(&config.SqliteDB, "sqlite", "", "SQLite database file path (required)")
 	serverCmd.Flags().IntVar(&serverFlags.port, "port", 8080, "API server port (default: 8080)")
 	serverCmd.MarkFlagRequired("sqlite")
+
+	// Add flags to collector command
+	collectorCmd.Flags().IntVar(&collectorFlags.port, "port", 4318, "OTLP/HTTP port (default: 4318)")
+	collectorCmd.Flags().BoolVar(&collectorFlags.quiet, "quiet", false, "Only count received records")
+	collectorCmd.Flags().IntVar(&collectorFlags.keep, "keep", sink.DefaultCollectorRecords, "Number of most recent records kept in memory")
 }
 
 func runMonitor(cmd *cobra.Command, args []string) error {
@@ -280,6 +330,12 @@ func runMonitor(cmd *cobra.Command, args []string) error {
 	}
 	defer closeSQLite()
 
+	// Open streaming sinks if specified
+	if err := openSinks(ctx, false); err != nil {
+		return fmt.Errorf("failed to open sinks: %w", err)
+	}
+	defer closeSinks()
+
 	// Start web server if enabled
 	if config.WebUI {
 		startWebServer()
@@ -447,6 +503,13 @@ func outputEvent(event *models.Event, resolvedPath string, writer *os.File) {
 		zlog.Error().Err(err).Msg("Failed to log event to SQLite")
 	}
 
+	// Stream to sinks if configured
+	if eventSinks != nil {
+		if err := eventSinks.Write(context.Background(), eventOutput); err != nil {
+			zlog.Error().Err(err).Msg("Failed to write event to sinks")
+		}
+	}
+
 	// Broadcast to WebSocket clients if web UI is enabled
 	if config.WebUI && webHub != nil {
 		webHub.Broadcast(eventOutput)
@@ -524,6 +587,26 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to query events: %w", err)
 	}
 
+	// Stream to sinks instead of printing when requested
+	if len(config.Sinks) > 0 {
+		ctx := cmd.Context()
+		if err := openSinks(ctx, true); err != nil {
+			return fmt.Errorf("failed to open sinks: %w", err)
+		}
+		// Events come newest first, sinks get them in the order they happened
+		for i := len(events) - 1; i >= 0; i-- {
+			if err := eventSinks.Write(ctx, events[i]); err != nil {
+				closeSinks()
+				return fmt.Errorf("failed to write event to sinks: %w", err)
+			}
+		}
+		if err := eventSinks.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close sinks: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Streamed %d events to %d sinks\n", len(events), eventSinks.Len())
+		return nil
+	}
+
 	// Handle export format
 	if queryFlags.exportFmt != "" {
 		var exportFormat export.ExportFormat
@@ -602,3 +685,48 @@ func runServer(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Starting API server on port %d\n", serverFlags.port)
 	return server.Start()
 }
+
+func runCollector(cmd *cobra.Command, args []string) error {
+	var onRecord func(record sink.OTLPLogRecord)
+	if !collectorFlags.quiet {
+		onRecord = printLogRecord
+	}
+	collector := sink.NewCollector(onRecord, collectorFlags.keep)
+
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", collectorFlags.port),
+		Handler: collector,
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		<-ctx.Done()
+		return server.Close()
+	})
+	eg.Go(func() error {
+		defer cancel()
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+
+	fmt.Printf("Collector listening on :%d/v1/logs\n", collectorFlags.port)
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	fmt.Printf("Received %d log records\n", collector.Received())
+	return nil
+}
+
+func printLogRecord(record sink.OTLPLogRecord) {
+	body := ""
+	if record.Body.StringValue != nil {
+		body = *record.Body.StringValue
+	}
+	process, _ := record.Attribute("process.executable.name")
+	pid, _ := record.Attribute("process.pid")
+	fmt.Printf("[%s] %s (pid %s): %s\n", record.TimeUnixNano, process, pid, body)
+}
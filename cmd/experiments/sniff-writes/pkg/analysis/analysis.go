@@ -0,0 +1,516 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/database"
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// ChunkSize is the amount of content the eBPF program captures per chunk event
+const ChunkSize = 4096
+
+// Recording is a chronologically ordered set of events loaded from a
+// sniff-writes database. All analyses work on the recording alone, so they
+// can run on any machine without eBPF.
+type Recording struct {
+	Events []models.EventOutput
+}
+
+// Load reads all events matching the filter from the database, oldest first.
+// Limit and Offset of the filter are ignored. Events recorded without an
+// offset are placed with resolveUnknownOffsets.
+func Load(db *database.SQLiteDB, filter database.QueryFilter) (*Recording, error) {
+	filter.Chronological = true
+	filter.Limit = 0
+	filter.Offset = 0
+
+	events, err := db.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+	resolveUnknownOffsets(events)
+	return &Recording{Events: events}, nil
+}
+
+// resolveUnknownOffsets gives writes without a recorded offset append
+// semantics: each one lands at the end of the file as known from the writes
+// before it. Reading them as offset 0 would turn every legacy write into a
+// rewrite of the start of the file.
+func resolveUnknownOffsets(events []models.EventOutput) {
+	ends := map[string]uint64{}
+	for i := range events {
+		e := &events[i]
+		if e.Operation != "write" || !isPrimaryChunk(*e) {
+			continue
+		}
+		if e.OffsetUnknown {
+			e.FileOffset = ends[e.Filename]
+		}
+		size := e.WriteSize
+		if size == 0 {
+			size = uint64(len(e.Content))
+		}
+		if end := e.FileOffset + size; end > ends[e.Filename] {
+			ends[e.Filename] = end
+		}
+	}
+}
+
+// ParseTimestamp parses the timestamp formats found in recorded databases
+func ParseTimestamp(ts string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05-07:00",
+		"2006-01-02 15:04:05",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, ts); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp: %s", ts)
+}
+
+// isPrimaryChunk reports whether the event carries the size of its operation.
+// Chunked writes repeat the total size on every chunk, so only the first chunk
+// is counted when summing bytes.
+func isPrimaryChunk(e models.EventOutput) bool {
+	return e.ChunkSeq == 0
+}
+
+// ProcessStats summarizes the write activity of a single process name
+type ProcessStats struct {
+	Process       string  `json:"process"`
+	PIDs          int     `json:"pids"`
+	Writes        int     `json:"writes"`
+	BytesWritten  uint64  `json:"bytes_written"`
+	Reads         int     `json:"reads"`
+	BytesRead     uint64  `json:"bytes_read"`
+	Files         int     `json:"files"`
+	UniqueBytes   uint64  `json:"unique_bytes"`
+	Amplification float64 `json:"amplification"`
+}
+
+// WriteAmplification computes, per process, how many bytes were written
+// compared to the size of the file regions they ended up covering. A process
+// that rewrites the same 1KB file ten times has an amplification of 10.
+func (r *Recording) WriteAmplification() []ProcessStats {
+	type fileKey struct {
+		process  string
+		filename string
+	}
+
+	stats := map[string]*ProcessStats{}
+	pids := map[string]map[uint32]bool{}
+	extents := map[fileKey]uint64{}
+
+	for _, e := range r.Events {
+		if e.Operation != "write" && e.Operation != "read" {
+			continue
+		}
+
+		s, ok := stats[e.Process]
+		if !ok {
+			s = &ProcessStats{Process: e.Process}
+			stats[e.Process] = s
+			pids[e.Process] = map[uint32]bool{}
+		}
+		pids[e.Process][e.Pid] = true
+
+		if !isPrimaryChunk(e) {
+			continue
+		}
+
+		if e.Operation == "read" {
+			s.Reads++
+			s.BytesRead += e.WriteSize
+			continue
+		}
+
+		s.Writes++
+		s.BytesWritten += e.WriteSize
+
+		key := fileKey{process: e.Process, filename: e.Filename}
+		end := e.FileOffset + e.WriteSize
+		if extent, seen := extents[key]; !seen || end > extent {
+			extents[key] = end
+		}
+	}
+
+	for key, extent := range extents {
+		s := stats[key.process]
+		s.Files++
+		s.UniqueBytes += extent
+	}
+
+	result := make([]ProcessStats, 0, len(stats))
+	for name, s := range stats {
+		s.PIDs = len(pids[name])
+		if s.UniqueBytes > 0 {
+			s.Amplification = float64(s.BytesWritten) / float64(s.UniqueBytes)
+		}
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Amplification != result[j].Amplification {
+			return result[i].Amplification > result[j].Amplification
+		}
+		return result[i].BytesWritten > result[j].BytesWritten
+	})
+
+	return result
+}
+
+// FileRewriteStats summarizes how often a file was written from scratch
+type FileRewriteStats struct {
+	Filename      string   `json:"filename"`
+	WriteSessions int      `json:"write_sessions"`
+	Rewrites      int      `json:"rewrites"`
+	Writes        int      `json:"writes"`
+	BytesWritten  uint64   `json:"bytes_written"`
+	Processes     []string `json:"processes"`
+	LastWrite     string   `json:"last_write"`
+}
+
+// sessionTracker splits the writes of a process to a file into sessions. A
+// session ends when the process closes the file, or when it seeks back to the
+// start of the file after having written past it.
+type sessionTracker struct {
+	open    bool
+	extent  uint64
+	written bool
+}
+
+// observe records an event and reports whether it starts a new write session
+func (t *sessionTracker) observe(e models.EventOutput) bool {
+	switch e.Operation {
+	case "close":
+		t.open = false
+		return false
+	case "write":
+		if !isPrimaryChunk(e) {
+			return false
+		}
+		newSession := !t.open || (e.FileOffset == 0 && t.written && t.extent > 0)
+		if newSession {
+			t.open = true
+			t.extent = 0
+		}
+		t.written = true
+		if end := e.FileOffset + e.WriteSize; end > t.extent {
+			t.extent = end
+		}
+		return newSession
+	}
+	return false
+}
+
+// MostRewrittenFiles ranks files by the number of times they were rewritten
+func (r *Recording) MostRewrittenFiles() []FileRewriteStats {
+	type sessionKey struct {
+		pid      uint32
+		filename string
+	}
+
+	stats := map[string]*FileRewriteStats{}
+	processes := map[string]map[string]bool{}
+	trackers := map[sessionKey]*sessionTracker{}
+
+	for _, e := range r.Events {
+		if e.Operation != "write" && e.Operation != "close" {
+			continue
+		}
+
+		key := sessionKey{pid: e.Pid, filename: e.Filename}
+		tracker, ok := trackers[key]
+		if !ok {
+			tracker = &sessionTracker{}
+			trackers[key] = tracker
+		}
+		newSession := tracker.observe(e)
+
+		if e.Operation != "write" || !isPrimaryChunk(e) {
+			continue
+		}
+
+		s, ok := stats[e.Filename]
+		if !ok {
+			s = &FileRewriteStats{Filename: e.Filename}
+			stats[e.Filename] = s
+			processes[e.Filename] = map[string]bool{}
+		}
+		if newSession {
+			s.WriteSessions++
+		}
+		s.Writes++
+		s.BytesWritten += e.WriteSize
+		s.LastWrite = e.Timestamp
+		processes[e.Filename][e.Process] = true
+	}
+
+	result := make([]FileRewriteStats, 0, len(stats))
+	for name, s := range stats {
+		if s.WriteSessions > 1 {
+			s.Rewrites = s.WriteSessions - 1
+		}
+		s.Processes = sortedKeys(processes[name])
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rewrites != result[j].Rewrites {
+			return result[i].Rewrites > result[j].Rewrites
+		}
+		if result[i].Writes != result[j].Writes {
+			return result[i].Writes > result[j].Writes
+		}
+		return result[i].Filename < result[j].Filename
+	})
+
+	return result
+}
+
+// RMWCycle is a read of a file followed by a write to the same file by the
+// same process
+type RMWCycle struct {
+	Filename     string `json:"filename"`
+	Process      string `json:"process"`
+	Pid          uint32 `json:"pid"`
+	ReadAt       string `json:"read_at"`
+	WriteAt      string `json:"write_at"`
+	BytesRead    uint64 `json:"bytes_read"`
+	BytesWritten uint64 `json:"bytes_written"`
+}
+
+// ReadModifyWriteCycles finds read-modify-write cycles. A cycle starts with a
+// read and completes with the next write by the same process to the same file,
+// provided it happens within window. A zero window accepts any delay.
+func (r *Recording) ReadModifyWriteCycles(window time.Duration) []RMWCycle {
+	type key struct {
+		pid      uint32
+		filename string
+	}
+	type pendingRead struct {
+		at    string
+		t     time.Time
+		bytes uint64
+	}
+
+	pending := map[key]*pendingRead{}
+	var cycles []RMWCycle
+
+	for _, e := range r.Events {
+		if !isPrimaryChunk(e) {
+			continue
+		}
+		k := key{pid: e.Pid, filename: e.Filename}
+
+		switch e.Operation {
+		case "read":
+			t, _ := ParseTimestamp(e.Timestamp)
+			if p, ok := pending[k]; ok {
+				// Consecutive reads belong to the same cycle
+				p.bytes += e.WriteSize
+				continue
+			}
+			pending[k] = &pendingRead{at: e.Timestamp, t: t, bytes: e.WriteSize}
+
+		case "write":
+			p, ok := pending[k]
+			if !ok {
+				continue
+			}
+			delete(pending, k)
+
+			if window > 0 {
+				t, err := ParseTimestamp(e.Timestamp)
+				if err == nil && !p.t.IsZero() && t.Sub(p.t) > window {
+					continue
+				}
+			}
+
+			cycles = append(cycles, RMWCycle{
+				Filename:     e.Filename,
+				Process:      e.Process,
+				Pid:          e.Pid,
+				ReadAt:       p.at,
+				WriteAt:      e.Timestamp,
+				BytesRead:    p.bytes,
+				BytesWritten: e.WriteSize,
+			})
+
+		case "close":
+			delete(pending, k)
+		}
+	}
+
+	return cycles
+}
+
+// RMWSummary aggregates read-modify-write cycles per file and process
+type RMWSummary struct {
+	Filename     string `json:"filename"`
+	Process      string `json:"process"`
+	Cycles       int    `json:"cycles"`
+	BytesRead    uint64 `json:"bytes_read"`
+	BytesWritten uint64 `json:"bytes_written"`
+}
+
+// SummarizeCycles groups cycles by file and process, most frequent first
+func SummarizeCycles(cycles []RMWCycle) []RMWSummary {
+	type key struct {
+		filename string
+		process  string
+	}
+
+	summaries := map[key]*RMWSummary{}
+	for _, c := range cycles {
+		k := key{filename: c.Filename, process: c.Process}
+		s, ok := summaries[k]
+		if !ok {
+			s = &RMWSummary{Filename: c.Filename, Process: c.Process}
+			summaries[k] = s
+		}
+		s.Cycles++
+		s.BytesRead += c.BytesRead
+		s.BytesWritten += c.BytesWritten
+	}
+
+	result := make([]RMWSummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cycles != result[j].Cycles {
+			return result[i].Cycles > result[j].Cycles
+		}
+		if result[i].Filename != result[j].Filename {
+			return result[i].Filename < result[j].Filename
+		}
+		return result[i].Process < result[j].Process
+	})
+	return result
+}
+
+// DirectoryActivity is the activity of one directory within one time window
+type DirectoryActivity struct {
+	WindowStart time.Time `json:"window_start"`
+	Directory   string    `json:"directory"`
+	Events      int       `json:"events"`
+	Writes      int       `json:"writes"`
+	Reads       int       `json:"reads"`
+	Bytes       uint64    `json:"bytes"`
+	Processes   int       `json:"processes"`
+}
+
+// HotDirectories buckets events into windows of the given size and returns the
+// top directories of each window by event count. Directories are truncated to
+// depth path components when depth is positive. Events with unparseable
+// timestamps are skipped.
+func (r *Recording) HotDirectories(window time.Duration, depth int, top int) []DirectoryActivity {
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	type key struct {
+		window    time.Time
+		directory string
+	}
+
+	buckets := map[key]*DirectoryActivity{}
+	processes := map[key]map[string]bool{}
+
+	for _, e := range r.Events {
+		if e.Filename == "" || !isPrimaryChunk(e) {
+			continue
+		}
+		t, err := ParseTimestamp(e.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		k := key{window: t.Truncate(window), directory: truncateDir(filepath.Dir(e.Filename), depth)}
+		a, ok := buckets[k]
+		if !ok {
+			a = &DirectoryActivity{WindowStart: k.window, Directory: k.directory}
+			buckets[k] = a
+			processes[k] = map[string]bool{}
+		}
+
+		a.Events++
+		switch e.Operation {
+		case "write":
+			a.Writes++
+			a.Bytes += e.WriteSize
+		case "read":
+			a.Reads++
+			a.Bytes += e.WriteSize
+		}
+		processes[k][e.Process] = true
+	}
+
+	byWindow := map[time.Time][]DirectoryActivity{}
+	for k, a := range buckets {
+		a.Processes = len(processes[k])
+		byWindow[k.window] = append(byWindow[k.window], *a)
+	}
+
+	windows := make([]time.Time, 0, len(byWindow))
+	for w := range byWindow {
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Before(windows[j]) })
+
+	var result []DirectoryActivity
+	for _, w := range windows {
+		dirs := byWindow[w]
+		sort.Slice(dirs, func(i, j int) bool {
+			if dirs[i].Events != dirs[j].Events {
+				return dirs[i].Events > dirs[j].Events
+			}
+			return dirs[i].Directory < dirs[j].Directory
+		})
+		if top > 0 && len(dirs) > top {
+			dirs = dirs[:top]
+		}
+		result = append(result, dirs...)
+	}
+
+	return result
+}
+
+// truncateDir keeps at most depth leading components of dir
+func truncateDir(dir string, depth int) string {
+	if depth <= 0 {
+		return dir
+	}
+
+	absolute := strings.HasPrefix(dir, "/")
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+
+	truncated := strings.Join(parts, "/")
+	if absolute {
+		return "/" + truncated
+	}
+	return truncated
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,332 @@
+package analysis
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/database"
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+func ev(ts string, pid uint32, process, op, filename string, offset uint64, content string) models.EventOutput {
+	e := models.EventOutput{
+		Timestamp:   ts,
+		Pid:         pid,
+		Process:     process,
+		Operation:   op,
+		Filename:    filename,
+		FileOffset:  offset,
+		TotalChunks: 1,
+	}
+	if op == "write" || op == "read" {
+		e.WriteSize = uint64(len(content))
+		e.Content = content
+	}
+	return e
+}
+
+// TestWriteAmplification tests bytes written versus bytes covered per process
+func TestWriteAmplification(t *testing.T) {
+	r := &Recording{Events: []models.EventOutput{
+		ev("2024-01-01T10:00:00Z", 1, "editor", "write", "/w/a.txt", 0, "hello"),
+		ev("2024-01-01T10:00:01Z", 1, "editor", "write", "/w/a.txt", 0, "hello"),
+		ev("2024-01-01T10:00:02Z", 1, "editor", "write", "/w/a.txt", 0, "hello"),
+		ev("2024-01-01T10:00:03Z", 2, "logger", "write", "/w/log", 0, "ab"),
+		ev("2024-01-01T10:00:04Z", 2, "logger", "write", "/w/log", 2, "cd"),
+	}}
+
+	stats := r.WriteAmplification()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 processes, got %d", len(stats))
+	}
+
+	editor := stats[0]
+	if editor.Process != "editor" {
+		t.Fatalf("expected editor first, got %s", editor.Process)
+	}
+	if editor.BytesWritten != 15 || editor.UniqueBytes != 5 {
+		t.Errorf("expected 15 bytes written over 5 unique, got %d over %d", editor.BytesWritten, editor.UniqueBytes)
+	}
+	if math.Abs(editor.Amplification-3) > 0.001 {
+		t.Errorf("expected amplification 3, got %f", editor.Amplification)
+	}
+
+	logger := stats[1]
+	if logger.UniqueBytes != 4 || math.Abs(logger.Amplification-1) > 0.001 {
+		t.Errorf("expected append-only logger to have amplification 1 over 4 bytes, got %f over %d",
+			logger.Amplification, logger.UniqueBytes)
+	}
+}
+
+// TestWriteAmplificationCountsChunksOnce tests that chunked writes are counted once
+func TestWriteAmplificationCountsChunksOnce(t *testing.T) {
+	first := ev("2024-01-01T10:00:00Z", 1, "cp", "write", "/w/big", 0, strings.Repeat("a", ChunkSize))
+	first.WriteSize = 2 * ChunkSize
+	first.TotalChunks = 2
+	second := ev("2024-01-01T10:00:00Z", 1, "cp", "write", "/w/big", ChunkSize, strings.Repeat("b", ChunkSize))
+	second.WriteSize = 2 * ChunkSize
+	second.ChunkSeq = 1
+	second.TotalChunks = 2
+
+	stats := (&Recording{Events: []models.EventOutput{first, second}}).WriteAmplification()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 process, got %d", len(stats))
+	}
+	if stats[0].Writes != 1 || stats[0].BytesWritten != 2*ChunkSize {
+		t.Errorf("expected 1 write of %d bytes, got %d writes of %d bytes", 2*ChunkSize, stats[0].Writes, stats[0].BytesWritten)
+	}
+}
+
+// TestMostRewrittenFiles tests write session detection
+func TestMostRewrittenFiles(t *testing.T) {
+	r := &Recording{Events: []models.EventOutput{
+		ev("2024-01-01T10:00:00Z", 1, "editor", "write", "/w/a.txt", 0, "v1"),
+		ev("2024-01-01T10:00:01Z", 1, "editor", "close", "/w/a.txt", 0, ""),
+		ev("2024-01-01T10:00:02Z", 1, "editor", "write", "/w/a.txt", 0, "v2"),
+		ev("2024-01-01T10:00:03Z", 1, "editor", "close", "/w/a.txt", 0, ""),
+		// Seeking back to the start without closing also counts as a rewrite
+		ev("2024-01-01T10:00:04Z", 3, "db", "write", "/w/a.txt", 0, "v3"),
+		ev("2024-01-01T10:00:05Z", 3, "db", "write", "/w/a.txt", 0, "v4"),
+		ev("2024-01-01T10:00:06Z", 2, "logger", "write", "/w/log", 0, "a"),
+		ev("2024-01-01T10:00:07Z", 2, "logger", "write", "/w/log", 1, "b"),
+	}}
+
+	stats := r.MostRewrittenFiles()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(stats))
+	}
+
+	if stats[0].Filename != "/w/a.txt" || stats[0].WriteSessions != 4 || stats[0].Rewrites != 3 {
+		t.Errorf("expected /w/a.txt with 4 sessions and 3 rewrites, got %+v", stats[0])
+	}
+	if strings.Join(stats[0].Processes, ",") != "db,editor" {
+		t.Errorf("expected processes db,editor, got %v", stats[0].Processes)
+	}
+	if stats[1].Filename != "/w/log" || stats[1].Rewrites != 0 || stats[1].Writes != 2 {
+		t.Errorf("expected /w/log with 2 writes and no rewrites, got %+v", stats[1])
+	}
+}
+
+// TestReadModifyWriteCycles tests cycle detection and the time window
+func TestReadModifyWriteCycles(t *testing.T) {
+	r := &Recording{Events: []models.EventOutput{
+		ev("2024-01-01T10:00:00Z", 1, "sed", "read", "/w/config", 0, "x=1"),
+		ev("2024-01-01T10:00:01Z", 1, "sed", "write", "/w/config", 0, "x=2"),
+		// Write long after the read is outside the window
+		ev("2024-01-01T10:01:00Z", 1, "sed", "read", "/w/config", 0, "x=2"),
+		ev("2024-01-01T10:05:00Z", 1, "sed", "write", "/w/config", 0, "x=3"),
+		// Close between read and write breaks the cycle
+		ev("2024-01-01T10:06:00Z", 2, "cat", "read", "/w/config", 0, "x=3"),
+		ev("2024-01-01T10:06:00Z", 2, "cat", "close", "/w/config", 0, ""),
+		ev("2024-01-01T10:06:01Z", 2, "cat", "write", "/w/config", 0, "x=4"),
+	}}
+
+	cycles := r.ReadModifyWriteCycles(10 * time.Second)
+	if len(cycles) != 1 {
+		t.Fatalf("expected 1 cycle within window, got %d", len(cycles))
+	}
+	if cycles[0].ReadAt != "2024-01-01T10:00:00Z" || cycles[0].WriteAt != "2024-01-01T10:00:01Z" {
+		t.Errorf("unexpected cycle %+v", cycles[0])
+	}
+
+	unbounded := r.ReadModifyWriteCycles(0)
+	if len(unbounded) != 2 {
+		t.Fatalf("expected 2 cycles without window, got %d", len(unbounded))
+	}
+
+	summaries := SummarizeCycles(unbounded)
+	if len(summaries) != 1 || summaries[0].Process != "sed" || summaries[0].Cycles != 2 {
+		t.Errorf("expected a single summary of 2 sed cycles, got %+v", summaries)
+	}
+}
+
+// TestHotDirectories tests windowing, depth grouping and top N
+func TestHotDirectories(t *testing.T) {
+	r := &Recording{Events: []models.EventOutput{
+		ev("2024-01-01T10:00:05Z", 1, "go", "write", "/src/app/pkg/a.go", 0, "a"),
+		ev("2024-01-01T10:00:10Z", 1, "go", "write", "/src/app/pkg/b.go", 0, "b"),
+		ev("2024-01-01T10:00:20Z", 2, "vim", "write", "/src/app/main.go", 0, "c"),
+		ev("2024-01-01T10:01:30Z", 2, "vim", "write", "/src/app/main.go", 0, "d"),
+		ev("not a timestamp", 2, "vim", "write", "/src/app/main.go", 0, "e"),
+	}}
+
+	activity := r.HotDirectories(time.Minute, 0, 0)
+	if len(activity) != 3 {
+		t.Fatalf("expected 3 directory buckets, got %d", len(activity))
+	}
+	if activity[0].Directory != "/src/app/pkg" || activity[0].Events != 2 {
+		t.Errorf("expected /src/app/pkg with 2 events first, got %+v", activity[0])
+	}
+	if activity[1].Directory != "/src/app" || activity[2].Directory != "/src/app" {
+		t.Errorf("expected /src/app in both windows, got %+v", activity[1:])
+	}
+	if !activity[2].WindowStart.After(activity[0].WindowStart) {
+		t.Errorf("expected windows in chronological order")
+	}
+
+	grouped := r.HotDirectories(time.Minute, 2, 1)
+	if len(grouped) != 2 {
+		t.Fatalf("expected 1 directory per window, got %d", len(grouped))
+	}
+	if grouped[0].Directory != "/src/app" || grouped[0].Events != 3 || grouped[0].Processes != 2 {
+		t.Errorf("expected /src/app with 3 events from 2 processes, got %+v", grouped[0])
+	}
+}
+
+// TestFileHistory tests version reconstruction and diffs
+func TestFileHistory(t *testing.T) {
+	r := &Recording{Events: []models.EventOutput{
+		ev("2024-01-01T10:00:00Z", 1, "editor", "write", "/w/notes.md", 0, "line one\n"),
+		ev("2024-01-01T10:00:00Z", 1, "editor", "write", "/w/notes.md", 9, "line two\n"),
+		ev("2024-01-01T10:00:01Z", 1, "editor", "close", "/w/notes.md", 0, ""),
+		// Rewritten from scratch, shorter than before
+		ev("2024-01-01T10:00:02Z", 2, "editor", "write", "/w/notes.md", 0, "line one\n"),
+		ev("2024-01-01T10:00:03Z", 2, "editor", "close", "/w/notes.md", 0, ""),
+		// Same content again does not produce a version
+		ev("2024-01-01T10:00:04Z", 3, "editor", "write", "/w/notes.md", 0, "line one\n"),
+		ev("2024-01-01T10:00:05Z", 3, "editor", "close", "/w/notes.md", 0, ""),
+		// Appended without close before the recording ends
+		ev("2024-01-01T10:00:06Z", 4, "echo", "write", "/w/notes.md", 9, "line three\n"),
+		ev("2024-01-01T10:00:06Z", 4, "echo", "write", "/w/other.md", 0, "unrelated"),
+	}}
+
+	versions := r.FileHistory("/w/notes.md")
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %d", len(versions))
+	}
+
+	if string(versions[0].Content) != "line one\nline two\n" || versions[0].Writes != 2 || versions[0].Diff != "" {
+		t.Errorf("unexpected first version %+v", versions[0])
+	}
+
+	if string(versions[1].Content) != "line one\n" {
+		t.Errorf("expected rewritten content, got %q", versions[1].Content)
+	}
+	if !strings.Contains(versions[1].Diff, "--- /w/notes.md (v1)") || !strings.Contains(versions[1].Diff, "-line two") {
+		t.Errorf("expected diff removing line two, got:\n%s", versions[1].Diff)
+	}
+
+	if string(versions[2].Content) != "line one\nline three\n" || versions[2].Process != "echo" {
+		t.Errorf("unexpected appended version %+v", versions[2])
+	}
+	if !strings.Contains(versions[2].Diff, "+line three") {
+		t.Errorf("expected diff adding line three, got:\n%s", versions[2].Diff)
+	}
+	if versions[2].Partial {
+		t.Errorf("expected fully captured version")
+	}
+}
+
+// TestLoadLegacyRows tests rows recorded before offsets were captured, which
+// are appended instead of being read as writes at offset 0
+func TestLoadLegacyRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`
+	CREATE TABLE file_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		pid INTEGER NOT NULL,
+		process TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		filename TEXT,
+		fd INTEGER,
+		write_size INTEGER,
+		content TEXT,
+		truncated BOOLEAN DEFAULT FALSE
+	);
+	INSERT INTO file_events (timestamp, pid, process, operation, filename, write_size, content) VALUES
+		('2024-01-01T10:00:00Z', 1, 'logger', 'write', '/w/app.log', 6, 'start' || char(10)),
+		('2024-01-01T10:00:01Z', 1, 'logger', 'write', '/w/app.log', 5, 'tick' || char(10)),
+		('2024-01-01T10:00:02Z', 1, 'logger', 'close', '/w/app.log', NULL, NULL),
+		('2024-01-01T10:00:03Z', 2, 'logger', 'write', '/w/app.log', 5, 'stop' || char(10)),
+		('2024-01-01T10:00:03Z', 2, 'logger', 'close', '/w/app.log', NULL, NULL);
+	`)
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("create legacy rows: %v", err)
+	}
+
+	db, err := database.NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("migrate legacy database: %v", err)
+	}
+	defer db.Close()
+	// Written after the migration, with its offset
+	if err := db.LogEvent(ev("2024-01-01T10:00:04Z", 3, "editor", "write", "/w/app.log", 0, "reset\n")); err != nil {
+		t.Fatalf("log event: %v", err)
+	}
+
+	r, err := Load(db, database.QueryFilter{})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var offsets []uint64
+	var unknown []bool
+	for _, e := range r.Events {
+		if e.Operation == "write" {
+			offsets = append(offsets, e.FileOffset)
+			unknown = append(unknown, e.OffsetUnknown)
+		}
+	}
+	if want := []uint64{0, 6, 11, 0}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets = %v, want %v", offsets, want)
+	}
+	if want := []bool{true, true, true, false}; !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown offsets = %v, want %v", unknown, want)
+	}
+
+	versions := r.FileHistory("/w/app.log")
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %d", len(versions))
+	}
+	// The second legacy session appends instead of rewriting the file
+	if string(versions[1].Content) != "start\ntick\nstop\n" {
+		t.Errorf("legacy content = %q", versions[1].Content)
+	}
+	if string(versions[2].Content) != "reset\n" {
+		t.Errorf("rewritten content = %q", versions[2].Content)
+	}
+}
+
+// TestFileHistoryMarksMissingContentAsPartial tests writes larger than the captured content
+func TestFileHistoryMarksMissingContentAsPartial(t *testing.T) {
+	truncated := ev("2024-01-01T10:00:00Z", 1, "app", "write", "/w/data", 0, "abc")
+	truncated.WriteSize = 10
+	truncated.Truncated = true
+
+	versions := (&Recording{Events: []models.EventOutput{truncated}}).FileHistory("/w/data")
+	if len(versions) != 1 {
+		t.Fatalf("expected 1 version, got %d", len(versions))
+	}
+	if !versions[0].Partial || versions[0].Size != 10 {
+		t.Errorf("expected partial version of 10 bytes, got %+v", versions[0])
+	}
+}
+
+func TestTruncateDir(t *testing.T) {
+	tests := []struct {
+		dir      string
+		depth    int
+		expected string
+	}{
+		{"/a/b/c/d", 2, "/a/b"},
+		{"a/b/c", 2, "a/b"},
+		{"/a", 3, "/a"},
+		{"/a/b/c", 0, "/a/b/c"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateDir(tt.dir, tt.depth); got != tt.expected {
+			t.Errorf("truncateDir(%q, %d) = %q, expected %q", tt.dir, tt.depth, got, tt.expected)
+		}
+	}
+}
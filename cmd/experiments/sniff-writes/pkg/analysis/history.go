@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// FileVersion is the reconstructed content of a file after a write session
+type FileVersion struct {
+	Version   int    `json:"version"`
+	Timestamp string `json:"timestamp"`
+	Pid       uint32 `json:"pid"`
+	Process   string `json:"process"`
+	Writes    int    `json:"writes"`
+	Size      int    `json:"size"`
+	Hash      string `json:"hash"`
+	// Partial is set when some of the written bytes were not captured, either
+	// because content capture was off or because writes exceeded the capture
+	// size. Missing bytes are left as zero bytes.
+	Partial bool   `json:"partial"`
+	Content []byte `json:"-"`
+	// Diff is the unified diff against the previous version
+	Diff string `json:"diff,omitempty"`
+}
+
+// contentBuffer rebuilds file content from write chunks at their offsets
+type contentBuffer struct {
+	data    []byte
+	partial bool
+}
+
+func (b *contentBuffer) apply(e models.EventOutput) {
+	content := []byte(e.Content)
+	end := int(e.FileOffset) + len(content)
+	if end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	copy(b.data[e.FileOffset:], content)
+
+	// Work out how many bytes this chunk should have carried
+	expected := e.WriteSize
+	if e.TotalChunks > 1 {
+		chunkStart := uint64(e.ChunkSeq) * ChunkSize
+		if e.WriteSize > chunkStart {
+			expected = e.WriteSize - chunkStart
+		}
+		if expected > ChunkSize {
+			expected = ChunkSize
+		}
+		if e.ChunkSeq == e.TotalChunks-1 && e.WriteSize > uint64(e.TotalChunks)*ChunkSize {
+			// The kernel side caps the number of chunks, the tail was dropped
+			b.partial = true
+		}
+	}
+	if uint64(len(content)) < expected {
+		b.partial = true
+		// Keep the file length consistent with what was written
+		if writtenEnd := int(e.FileOffset + expected); writtenEnd > len(b.data) {
+			b.data = append(b.data, make([]byte, writtenEnd-len(b.data))...)
+		}
+	}
+}
+
+// FileHistory reconstructs the successive versions of a file from the write
+// chunks captured in the recording. A version is emitted every time a process
+// that wrote to the file closes it, and once more at the end of the recording
+// for sessions that were never closed. Versions with unchanged content are
+// skipped.
+//
+// When a new write session starts at offset 0, the file is assumed to have
+// been truncated and rewritten, which is what editors and most tools do.
+func (r *Recording) FileHistory(filename string) []FileVersion {
+	type session struct {
+		pid     uint32
+		process string
+		writes  int
+		last    string
+	}
+
+	var versions []FileVersion
+	buffer := &contentBuffer{}
+	sessions := map[uint32]*session{}
+	var lastHash string
+
+	snapshot := func(s *session) {
+		hash := fmt.Sprintf("%x", sha256.Sum256(buffer.data))
+		if hash == lastHash {
+			return
+		}
+
+		version := FileVersion{
+			Version:   len(versions) + 1,
+			Timestamp: s.last,
+			Pid:       s.pid,
+			Process:   s.process,
+			Writes:    s.writes,
+			Size:      len(buffer.data),
+			Hash:      hash,
+			Partial:   buffer.partial,
+			Content:   bytes.Clone(buffer.data),
+		}
+		if len(versions) > 0 {
+			previous := versions[len(versions)-1]
+			version.Diff = UnifiedDiff(
+				fmt.Sprintf("%s (v%d)", filename, previous.Version),
+				fmt.Sprintf("%s (v%d)", filename, version.Version),
+				previous.Content, version.Content)
+		}
+
+		versions = append(versions, version)
+		lastHash = hash
+	}
+
+	for _, e := range r.Events {
+		if e.Filename != filename {
+			continue
+		}
+
+		switch e.Operation {
+		case "write":
+			s, ok := sessions[e.Pid]
+			if !ok {
+				s = &session{pid: e.Pid, process: e.Process}
+				sessions[e.Pid] = s
+				if e.FileOffset == 0 && isPrimaryChunk(e) {
+					buffer = &contentBuffer{}
+				}
+			}
+			if isPrimaryChunk(e) {
+				s.writes++
+			}
+			s.last = e.Timestamp
+			buffer.apply(e)
+
+		case "close":
+			if s, ok := sessions[e.Pid]; ok {
+				snapshot(s)
+				delete(sessions, e.Pid)
+			}
+		}
+	}
+
+	// Flush sessions that were still open when the recording stopped, in the
+	// order they last wrote
+	for len(sessions) > 0 {
+		var next *session
+		for _, s := range sessions {
+			if next == nil || s.last < next.last || (s.last == next.last && s.pid < next.pid) {
+				next = s
+			}
+		}
+		snapshot(next)
+		delete(sessions, next.pid)
+	}
+
+	return versions
+}
+
+// UnifiedDiff renders a line based diff between two contents
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte) string {
+	dmp := diffmatchpatch.New()
+	a, b, c := dmp.DiffLinesToChars(string(oldContent), string(newContent))
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), c)
+
+	if len(diffs) == 0 || (len(diffs) == 1 && diffs[0].Type == diffmatchpatch.DiffEqual) {
+		return ""
+	}
+
+	oldLines := strings.Count(string(oldContent), "\n")
+	newLines := strings.Count(string(newContent), "\n")
+
+	var diff strings.Builder
+	diff.WriteString(fmt.Sprintf("--- %s\n", oldName))
+	diff.WriteString(fmt.Sprintf("+++ %s\n", newName))
+	diff.WriteString(fmt.Sprintf("@@ -1,%d +1,%d @@\n", oldLines, newLines))
+
+	for _, d := range diffs {
+		lines := strings.Split(d.Text, "\n")
+		for i, line := range lines {
+			if i == len(lines)-1 && line == "" {
+				continue
+			}
+			switch d.Type {
+			case diffmatchpatch.DiffDelete:
+				diff.WriteString("-" + line + "\n")
+			case diffmatchpatch.DiffInsert:
+				diff.WriteString("+" + line + "\n")
+			case diffmatchpatch.DiffEqual:
+				diff.WriteString(" " + line + "\n")
+			}
+		}
+	}
+
+	return diff.String()
+}
@@ -36,7 +36,10 @@ func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
 		fd INTEGER,
 		write_size INTEGER,
 		content TEXT,
-		truncated BOOLEAN DEFAULT FALSE
+		truncated BOOLEAN DEFAULT FALSE,
+		file_offset INTEGER,
+		chunk_seq INTEGER DEFAULT 0,
+		total_chunks INTEGER DEFAULT 1
 	);
 	
 	CREATE INDEX IF NOT EXISTS idx_timestamp ON file_events(timestamp);
@@ -49,9 +52,54 @@ func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
+	if err := migrateOffsetColumns(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate table: %w", err)
+	}
+
 	return &SQLiteDB{db: db}, nil
 }
 
+// migrateOffsetColumns adds the offset and chunk columns to databases recorded
+// before they were part of the schema. Older rows keep NULL offsets.
+func migrateOffsetColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(file_events)")
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull int
+		var defaultValue sql.NullString
+		var pk int
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{"file_offset", "INTEGER"},
+		{"chunk_seq", "INTEGER DEFAULT 0"},
+		{"total_chunks", "INTEGER DEFAULT 1"},
+	}
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE file_events ADD COLUMN %s %s", c.name, c.def)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *SQLiteDB) Close() {
 	if s.db != nil {
 		s.db.Close()
@@ -64,8 +112,8 @@ func (s *SQLiteDB) LogEvent(event models.EventOutput) error {
 	}
 
 	insertSQL := `
-	INSERT INTO file_events (timestamp, pid, process, operation, filename, fd, write_size, content, truncated)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO file_events (timestamp, pid, process, operation, filename, fd, write_size, content, truncated, file_offset, chunk_seq, total_chunks)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	var fd *int32
@@ -92,7 +140,10 @@ func (s *SQLiteDB) LogEvent(event models.EventOutput) error {
 		fd,
 		writeSize,
 		content,
-		event.Truncated)
+		event.Truncated,
+		event.FileOffset,
+		event.ChunkSeq,
+		event.TotalChunks)
 
 	return err
 }
@@ -107,6 +158,7 @@ type QueryFilter struct {
 	PID             *uint32
 	Limit           int
 	Offset          int
+	Chronological   bool // Return events oldest first, in insertion order
 }
 
 // QueryEvents retrieves events from the database based on filters
@@ -152,11 +204,17 @@ func (s *SQLiteDB) QueryEvents(filter QueryFilter) ([]models.EventOutput, error)
 		args = append(args, *filter.PID)
 	}
 
-	query := "SELECT timestamp, pid, process, operation, filename, fd, write_size, content, truncated FROM file_events"
+	query := "SELECT timestamp, pid, process, operation, filename, fd, write_size, content, truncated, file_offset, chunk_seq, total_chunks FROM file_events"
 	if len(whereConditions) > 0 {
 		query += " WHERE " + strings.Join(whereConditions, " AND ")
 	}
-	query += " ORDER BY datetime(timestamp) DESC"
+	if filter.Chronological {
+		// Timestamps only have second precision, so the row id keeps the
+		// original ordering of events that happened within the same second.
+		query += " ORDER BY id ASC"
+	} else {
+		query += " ORDER BY datetime(timestamp) DESC"
+	}
 
 	if filter.Limit > 0 {
 		query += " LIMIT ?"
@@ -180,6 +238,9 @@ func (s *SQLiteDB) QueryEvents(filter QueryFilter) ([]models.EventOutput, error)
 		var fd sql.NullInt32
 		var writeSize sql.NullInt64
 		var content sql.NullString
+		var fileOffset sql.NullInt64
+		var chunkSeq sql.NullInt64
+		var totalChunks sql.NullInt64
 
 		err := rows.Scan(
 			&event.Timestamp,
@@ -191,6 +252,9 @@ func (s *SQLiteDB) QueryEvents(filter QueryFilter) ([]models.EventOutput, error)
 			&writeSize,
 			&content,
 			&event.Truncated,
+			&fileOffset,
+			&chunkSeq,
+			&totalChunks,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -205,6 +269,18 @@ func (s *SQLiteDB) QueryEvents(filter QueryFilter) ([]models.EventOutput, error)
 		if content.Valid {
 			event.Content = content.String
 		}
+		if fileOffset.Valid {
+			event.FileOffset = uint64(fileOffset.Int64)
+		} else {
+			// Rows recorded before the migration have no offset
+			event.OffsetUnknown = true
+		}
+		if chunkSeq.Valid {
+			event.ChunkSeq = uint32(chunkSeq.Int64)
+		}
+		if totalChunks.Valid {
+			event.TotalChunks = uint32(totalChunks.Int64)
+		}
 
 		events = append(events, event)
 	}
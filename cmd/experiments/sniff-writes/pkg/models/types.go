@@ -41,6 +41,7 @@ type Config struct {
 	SqliteDB           string   // Path to SQLite database for logging
 	WebUI              bool     // Enable web UI
 	WebPort            int      // Web UI port
+	Sinks              []string // Streaming sink specs, e.g. "ndjson:/tmp/events.ndjson"
 }
 
 type EventOutput struct {
@@ -59,4 +60,6 @@ type EventOutput struct {
 	TotalChunks uint32 `json:"total_chunks,omitempty"`
 	Truncated   bool   `json:"truncated,omitempty"`
 	Diff        string `json:"diff,omitempty"`
+	// OffsetUnknown is set for events recorded before offsets were captured
+	OffsetUnknown bool `json:"offset_unknown,omitempty"`
 }
@@ -0,0 +1,429 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// DefaultRowGroupSize is the number of events buffered per row group
+const DefaultRowGroupSize = 4096
+
+// columnarMagic marks the start and end of a columnar file
+var columnarMagic = []byte("SWC1")
+
+// Column describes one column of the columnar file
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // "string", "uint" or "bool"
+}
+
+// ColumnarSchema lists the columns written for every event, in file order
+var ColumnarSchema = []Column{
+	{Name: "timestamp", Type: "string"},
+	{Name: "pid", Type: "uint"},
+	{Name: "process", Type: "string"},
+	{Name: "operation", Type: "string"},
+	{Name: "filename", Type: "string"},
+	{Name: "fd", Type: "uint"},
+	{Name: "write_size", Type: "uint"},
+	{Name: "file_offset", Type: "uint"},
+	{Name: "chunk_seq", Type: "uint"},
+	{Name: "total_chunks", Type: "uint"},
+	{Name: "content", Type: "string"},
+	{Name: "truncated", Type: "bool"},
+}
+
+// ColumnChunk locates the data of one column within a row group
+type ColumnChunk struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// RowGroup is a batch of rows stored column by column
+type RowGroup struct {
+	Rows    int           `json:"rows"`
+	MinTime string        `json:"min_time"`
+	MaxTime string        `json:"max_time"`
+	Columns []ColumnChunk `json:"columns"`
+}
+
+// ColumnarFooter is stored as JSON at the end of the file
+type ColumnarFooter struct {
+	Schema    []Column   `json:"schema"`
+	RowGroups []RowGroup `json:"row_groups"`
+}
+
+// ColumnarSink writes events in a Parquet-style layout: rows are buffered into
+// row groups, each row group is stored one column after the other, and a
+// footer records the schema and where every column chunk lives. Readers can
+// load single columns without decoding the rest of the file.
+//
+// File layout:
+//
+//	"SWC1" | row group column chunks... | footer JSON | footer length (uint32 LE) | "SWC1"
+//
+// Strings are stored as uvarint length followed by bytes, unsigned integers as
+// uvarints and booleans as single bytes.
+type ColumnarSink struct {
+	mu           sync.Mutex
+	writer       io.WriteCloser
+	offset       int64
+	rowGroupSize int
+	buffer       []models.EventOutput
+	footer       ColumnarFooter
+	started      bool
+}
+
+// NewColumnarSink creates a columnar sink flushing a row group every
+// rowGroupSize events
+func NewColumnarSink(writer io.WriteCloser, rowGroupSize int) *ColumnarSink {
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	return &ColumnarSink{
+		writer:       writer,
+		rowGroupSize: rowGroupSize,
+		footer:       ColumnarFooter{Schema: ColumnarSchema},
+	}
+}
+
+func (s *ColumnarSink) Write(ctx context.Context, event models.EventOutput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer = append(s.buffer, event)
+	if len(s.buffer) >= s.rowGroupSize {
+		return s.writeRowGroup()
+	}
+	return nil
+}
+
+// Flush writes buffered events as a row group. Small row groups make reads
+// less efficient, so flushing is best left to Close unless data must hit disk.
+func (s *ColumnarSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeRowGroup()
+}
+
+func (s *ColumnarSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeRowGroup(); err != nil {
+		s.writer.Close()
+		return err
+	}
+	if err := s.writeFooter(); err != nil {
+		s.writer.Close()
+		return err
+	}
+	return s.writer.Close()
+}
+
+func (s *ColumnarSink) write(p []byte) error {
+	n, err := s.writer.Write(p)
+	s.offset += int64(n)
+	return err
+}
+
+func (s *ColumnarSink) writeHeader() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return s.write(columnarMagic)
+}
+
+func (s *ColumnarSink) writeRowGroup() error {
+	if len(s.buffer) == 0 {
+		return nil
+	}
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+
+	group := RowGroup{
+		Rows:    len(s.buffer),
+		MinTime: s.buffer[0].Timestamp,
+		MaxTime: s.buffer[0].Timestamp,
+	}
+	for _, e := range s.buffer {
+		if e.Timestamp < group.MinTime {
+			group.MinTime = e.Timestamp
+		}
+		if e.Timestamp > group.MaxTime {
+			group.MaxTime = e.Timestamp
+		}
+	}
+
+	for i := range ColumnarSchema {
+		chunk := encodeColumn(i, s.buffer)
+		group.Columns = append(group.Columns, ColumnChunk{Offset: s.offset, Length: int64(len(chunk))})
+		if err := s.write(chunk); err != nil {
+			return fmt.Errorf("failed to write column %s: %w", ColumnarSchema[i].Name, err)
+		}
+	}
+
+	s.footer.RowGroups = append(s.footer.RowGroups, group)
+	s.buffer = s.buffer[:0]
+	return nil
+}
+
+func (s *ColumnarSink) writeFooter() error {
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+
+	footer, err := json.Marshal(s.footer)
+	if err != nil {
+		return err
+	}
+	if err := s.write(footer); err != nil {
+		return err
+	}
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if err := s.write(length[:]); err != nil {
+		return err
+	}
+	return s.write(columnarMagic)
+}
+
+// columnValue extracts the value of column i from an event
+func columnValue(i int, e models.EventOutput) interface{} {
+	switch ColumnarSchema[i].Name {
+	case "timestamp":
+		return e.Timestamp
+	case "pid":
+		return uint64(e.Pid)
+	case "process":
+		return e.Process
+	case "operation":
+		return e.Operation
+	case "filename":
+		return e.Filename
+	case "fd":
+		return uint64(uint32(e.Fd))
+	case "write_size":
+		return e.WriteSize
+	case "file_offset":
+		return e.FileOffset
+	case "chunk_seq":
+		return uint64(e.ChunkSeq)
+	case "total_chunks":
+		return uint64(e.TotalChunks)
+	case "content":
+		return e.Content
+	case "truncated":
+		return e.Truncated
+	}
+	return nil
+}
+
+func encodeColumn(i int, events []models.EventOutput) []byte {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	for _, e := range events {
+		switch v := columnValue(i, e).(type) {
+		case string:
+			n := binary.PutUvarint(scratch[:], uint64(len(v)))
+			buf.Write(scratch[:n])
+			buf.WriteString(v)
+		case uint64:
+			n := binary.PutUvarint(scratch[:], v)
+			buf.Write(scratch[:n])
+		case bool:
+			if v {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// ColumnarReader reads files written by ColumnarSink
+type ColumnarReader struct {
+	reader io.ReaderAt
+	Footer ColumnarFooter
+}
+
+// OpenColumnar reads the footer of a columnar file of the given size
+func OpenColumnar(reader io.ReaderAt, size int64) (*ColumnarReader, error) {
+	trailerLen := int64(4 + len(columnarMagic))
+	if size < int64(len(columnarMagic))+trailerLen {
+		return nil, fmt.Errorf("file too small to be a columnar file")
+	}
+
+	trailer := make([]byte, trailerLen)
+	if _, err := reader.ReadAt(trailer, size-trailerLen); err != nil {
+		return nil, fmt.Errorf("failed to read trailer: %w", err)
+	}
+	if !bytes.Equal(trailer[4:], columnarMagic) {
+		return nil, fmt.Errorf("not a columnar file")
+	}
+
+	footerLen := int64(binary.LittleEndian.Uint32(trailer[:4]))
+	footerStart := size - trailerLen - footerLen
+	if footerStart < int64(len(columnarMagic)) {
+		return nil, fmt.Errorf("invalid footer length %d", footerLen)
+	}
+
+	footer := make([]byte, footerLen)
+	if _, err := reader.ReadAt(footer, footerStart); err != nil {
+		return nil, fmt.Errorf("failed to read footer: %w", err)
+	}
+
+	r := &ColumnarReader{reader: reader}
+	if err := json.Unmarshal(footer, &r.Footer); err != nil {
+		return nil, fmt.Errorf("failed to decode footer: %w", err)
+	}
+	if err := r.Footer.validate(footerStart); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// validate checks that every column chunk lies between the header and the
+// footer starting at dataEnd, and that it is long enough for its rows, since
+// every encoded value takes at least one byte. Readers size their buffers
+// from these numbers, so a corrupt footer must not get through.
+func (f *ColumnarFooter) validate(dataEnd int64) error {
+	dataStart := int64(len(columnarMagic))
+	for g, rg := range f.RowGroups {
+		if rg.Rows < 0 {
+			return fmt.Errorf("row group %d: invalid row count %d", g, rg.Rows)
+		}
+		for i, chunk := range rg.Columns {
+			if chunk.Offset < dataStart || chunk.Length < 0 || chunk.Length > dataEnd-chunk.Offset {
+				return fmt.Errorf("row group %d: column %d at %d+%d is outside the data section", g, i, chunk.Offset, chunk.Length)
+			}
+			if int64(rg.Rows) > chunk.Length {
+				return fmt.Errorf("row group %d: column %d is too short for %d rows", g, i, rg.Rows)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadColumn decodes a single column of a row group. Values are strings,
+// uint64s or bools depending on the column type.
+func (r *ColumnarReader) ReadColumn(group int, name string) ([]interface{}, error) {
+	if group < 0 || group >= len(r.Footer.RowGroups) {
+		return nil, fmt.Errorf("row group %d out of range", group)
+	}
+	rg := r.Footer.RowGroups[group]
+
+	index := -1
+	for i, c := range r.Footer.Schema {
+		if c.Name == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 || index >= len(rg.Columns) {
+		return nil, fmt.Errorf("unknown column %s", name)
+	}
+
+	chunk := rg.Columns[index]
+	data := make([]byte, chunk.Length)
+	if _, err := r.reader.ReadAt(data, chunk.Offset); err != nil {
+		return nil, fmt.Errorf("failed to read column %s: %w", name, err)
+	}
+
+	buf := bytes.NewReader(data)
+	values := make([]interface{}, 0, rg.Rows)
+	for i := 0; i < rg.Rows; i++ {
+		switch r.Footer.Schema[index].Type {
+		case "string":
+			n, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return nil, fmt.Errorf("corrupt column %s: %w", name, err)
+			}
+			if n > uint64(buf.Len()) {
+				return nil, fmt.Errorf("corrupt column %s: string of %d bytes exceeds the column", name, n)
+			}
+			s := make([]byte, n)
+			if _, err := io.ReadFull(buf, s); err != nil {
+				return nil, fmt.Errorf("corrupt column %s: %w", name, err)
+			}
+			values = append(values, string(s))
+		case "uint":
+			v, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return nil, fmt.Errorf("corrupt column %s: %w", name, err)
+			}
+			values = append(values, v)
+		case "bool":
+			b, err := buf.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("corrupt column %s: %w", name, err)
+			}
+			values = append(values, b == 1)
+		default:
+			return nil, fmt.Errorf("unsupported column type %s", r.Footer.Schema[index].Type)
+		}
+	}
+	return values, nil
+}
+
+// ReadEvents decodes all rows of all row groups back into events
+func (r *ColumnarReader) ReadEvents() ([]models.EventOutput, error) {
+	var events []models.EventOutput
+
+	for g, rg := range r.Footer.RowGroups {
+		rows := make([]models.EventOutput, rg.Rows)
+		for _, c := range r.Footer.Schema {
+			values, err := r.ReadColumn(g, c.Name)
+			if err != nil {
+				return nil, err
+			}
+			for i, v := range values {
+				setColumnValue(&rows[i], c.Name, v)
+			}
+		}
+		events = append(events, rows...)
+	}
+
+	return events, nil
+}
+
+func setColumnValue(e *models.EventOutput, name string, v interface{}) {
+	switch name {
+	case "timestamp":
+		e.Timestamp = v.(string)
+	case "pid":
+		e.Pid = uint32(v.(uint64))
+	case "process":
+		e.Process = v.(string)
+	case "operation":
+		e.Operation = v.(string)
+	case "filename":
+		e.Filename = v.(string)
+	case "fd":
+		e.Fd = int32(uint32(v.(uint64)))
+	case "write_size":
+		e.WriteSize = v.(uint64)
+	case "file_offset":
+		e.FileOffset = v.(uint64)
+	case "chunk_seq":
+		e.ChunkSeq = uint32(v.(uint64))
+	case "total_chunks":
+		e.TotalChunks = uint32(v.(uint64))
+	case "content":
+		e.Content = v.(string)
+	case "truncated":
+		e.Truncated = v.(bool)
+	}
+}
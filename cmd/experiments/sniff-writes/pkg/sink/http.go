@@ -0,0 +1,340 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// BatchConfig controls batching and retries of HTTP based sinks
+type BatchConfig struct {
+	MaxBatch       int           // Events per request
+	FlushInterval  time.Duration // Flush partial batches this often (0 = only when full)
+	MaxRetries     int           // Retries after the first attempt
+	InitialBackoff time.Duration // Delay before the first retry, doubled each time
+	MaxBackoff     time.Duration // Upper bound for the retry delay
+	QueueSize      int           // Events waiting for delivery before Write drops them
+	Block          bool          // Wait for queue space instead of dropping, for replays
+	Client         *http.Client
+}
+
+const defaultQueueSize = 10000
+
+// DefaultBatchConfig returns the settings used by Open
+func DefaultBatchConfig() BatchConfig {
+	return BatchConfig{
+		MaxBatch:       100,
+		FlushInterval:  2 * time.Second,
+		MaxRetries:     5,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		QueueSize:      defaultQueueSize,
+		Client:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// encodeFunc turns a batch into a request body and its content type
+type encodeFunc func(events []models.EventOutput) ([]byte, string, error)
+
+// flushRequest asks the delivery goroutine to send everything queued
+type flushRequest struct {
+	ctx   context.Context
+	close bool
+	reply chan error
+}
+
+// batchingSink POSTs events in batches from a background goroutine, retrying
+// transient failures with exponential backoff. Write only queues the event,
+// so a slow endpoint never stalls the caller: when the queue is full the
+// event is dropped and counted, unless the sink is configured to block.
+// Batches are sent in order.
+type batchingSink struct {
+	name   string
+	url    string
+	config BatchConfig
+	encode encodeFunc
+
+	queue   chan models.EventOutput
+	flushes chan flushRequest
+	dropped atomic.Uint64
+
+	closeOnce sync.Once
+	closeErr  error
+	group     errgroup.Group
+	done      chan struct{}
+}
+
+func newBatchingSink(name, url string, config BatchConfig, encode encodeFunc) *batchingSink {
+	if config.MaxBatch <= 0 {
+		config.MaxBatch = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	s := &batchingSink{
+		name:    name,
+		url:     url,
+		config:  config,
+		encode:  encode,
+		queue:   make(chan models.EventOutput, config.QueueSize),
+		flushes: make(chan flushRequest),
+		done:    make(chan struct{}),
+	}
+	s.group.Go(func() error {
+		s.run()
+		return nil
+	})
+	return s
+}
+
+// run owns the current batch and delivers it when it is full, on every
+// flush interval and when asked to flush
+func (s *batchingSink) run() {
+	defer close(s.done)
+
+	var tick <-chan time.Time
+	if s.config.FlushInterval > 0 {
+		ticker := time.NewTicker(s.config.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var batch []models.EventOutput
+	for {
+		select {
+		case event := <-s.queue:
+			batch = append(batch, event)
+			if len(batch) >= s.config.MaxBatch {
+				s.logError(s.deliver(context.Background(), batch))
+				batch = nil
+			}
+		case <-tick:
+			if len(batch) > 0 {
+				s.logError(s.deliver(context.Background(), batch))
+				batch = nil
+			}
+		case req := <-s.flushes:
+			req.reply <- s.drain(req.ctx, batch)
+			batch = nil
+			if req.close {
+				return
+			}
+		}
+	}
+}
+
+func (s *batchingSink) logError(err error) {
+	if err != nil {
+		log.Error().Err(err).Str("sink", s.name).Msg("Failed to deliver events")
+	}
+}
+
+// fill moves queued events into the batch until it is full or the queue is
+// empty
+func (s *batchingSink) fill(batch []models.EventOutput) []models.EventOutput {
+	for len(batch) < s.config.MaxBatch {
+		select {
+		case event := <-s.queue:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain delivers the pending batch and everything queued behind it
+func (s *batchingSink) drain(ctx context.Context, batch []models.EventOutput) error {
+	var errs []error
+	for batch = s.fill(batch); len(batch) > 0; batch = s.fill(nil) {
+		if err := s.deliver(ctx, batch); err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *batchingSink) deliver(ctx context.Context, batch []models.EventOutput) error {
+	if err := s.send(ctx, batch); err != nil {
+		return fmt.Errorf("%s: dropped %d events: %w", s.name, len(batch), err)
+	}
+	return nil
+}
+
+func (s *batchingSink) Write(ctx context.Context, event models.EventOutput) error {
+	select {
+	case <-s.done:
+		return fmt.Errorf("%s: sink is closed", s.name)
+	default:
+	}
+
+	if s.config.Block {
+		select {
+		case s.queue <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return fmt.Errorf("%s: sink is closed", s.name)
+		}
+	}
+
+	select {
+	case s.queue <- event:
+	default:
+		if n := s.dropped.Add(1); n == 1 || n%1000 == 0 {
+			log.Warn().Str("sink", s.name).Uint64("dropped", n).Msg("Delivery queue full, dropping events")
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of events dropped because the queue was full
+func (s *batchingSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *batchingSink) Flush(ctx context.Context) error {
+	return s.request(ctx, false)
+}
+
+func (s *batchingSink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.request(ctx, true)
+		if ctx.Err() == nil {
+			// The delivery goroutine returns once it has drained the queue
+			_ = s.group.Wait()
+		}
+		if n := s.Dropped(); n > 0 {
+			s.closeErr = errors.Join(s.closeErr, fmt.Errorf("%s: dropped %d events because the delivery queue was full", s.name, n))
+		}
+	})
+	return s.closeErr
+}
+
+func (s *batchingSink) request(ctx context.Context, close bool) error {
+	req := flushRequest{ctx: ctx, close: close, reply: make(chan error, 1)}
+	select {
+	case s.flushes <- req:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send POSTs one batch, retrying network errors, 429 and 5xx responses
+func (s *batchingSink) send(ctx context.Context, batch []models.EventOutput) error {
+	body, contentType, err := s.encode(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	backoff := s.config.InitialBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Debug().Err(lastErr).Str("sink", s.name).Int("attempt", attempt).Dur("backoff", backoff).Msg("Retrying batch")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if s.config.MaxBackoff > 0 && backoff > s.config.MaxBackoff {
+				backoff = s.config.MaxBackoff
+			}
+		}
+
+		retry, err := s.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			return err
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", s.config.MaxRetries+1, lastErr)
+}
+
+// post performs a single request and reports whether a failure is retryable
+func (s *batchingSink) post(ctx context.Context, body []byte, contentType string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, err
+}
+
+// WebhookPayload is the body POSTed by the webhook sink
+type WebhookPayload struct {
+	Source string               `json:"source"`
+	SentAt string               `json:"sent_at"`
+	Events []models.EventOutput `json:"events"`
+}
+
+// NewWebhookSink creates a sink that POSTs batches of events as JSON to url
+func NewWebhookSink(url string, config BatchConfig) Sink {
+	return newBatchingSink("webhook", url, config, func(events []models.EventOutput) ([]byte, string, error) {
+		body, err := json.Marshal(WebhookPayload{
+			Source: "sniff-writes",
+			SentAt: time.Now().Format(time.RFC3339),
+			Events: events,
+		})
+		return body, "application/json", err
+	})
+}
+
+// NewOTLPSink creates a sink exporting events as OpenTelemetry log records to
+// an OTLP/HTTP endpoint, such as a collector listening on port 4318. The
+// /v1/logs path is appended when the endpoint has no path.
+func NewOTLPSink(endpoint string, config BatchConfig) Sink {
+	return newBatchingSink("otlp", otlpLogsURL(endpoint), config, func(events []models.EventOutput) ([]byte, string, error) {
+		body, err := json.Marshal(NewOTLPLogsRequest(events))
+		return body, "application/json", err
+	})
+}
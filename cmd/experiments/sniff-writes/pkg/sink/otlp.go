@@ -0,0 +1,243 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// The types below are the subset of the OTLP/HTTP JSON encoding of logs used
+// by sniff-writes. Integers are encoded as strings, as the protobuf JSON
+// mapping requires for 64 bit values.
+
+type OTLPLogsRequest struct {
+	ResourceLogs []OTLPResourceLogs `json:"resourceLogs"`
+}
+
+type OTLPResourceLogs struct {
+	Resource  OTLPResource    `json:"resource"`
+	ScopeLogs []OTLPScopeLogs `json:"scopeLogs"`
+}
+
+type OTLPResource struct {
+	Attributes []OTLPKeyValue `json:"attributes"`
+}
+
+type OTLPScopeLogs struct {
+	Scope      OTLPScope       `json:"scope"`
+	LogRecords []OTLPLogRecord `json:"logRecords"`
+}
+
+type OTLPScope struct {
+	Name string `json:"name"`
+}
+
+type OTLPLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 OTLPAnyValue   `json:"body"`
+	Attributes           []OTLPKeyValue `json:"attributes"`
+}
+
+type OTLPKeyValue struct {
+	Key   string       `json:"key"`
+	Value OTLPAnyValue `json:"value"`
+}
+
+type OTLPAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// Attribute returns the value of the attribute with the given key as a string
+func (r OTLPLogRecord) Attribute(key string) (string, bool) {
+	for _, kv := range r.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch {
+		case kv.Value.StringValue != nil:
+			return *kv.Value.StringValue, true
+		case kv.Value.IntValue != nil:
+			return *kv.Value.IntValue, true
+		case kv.Value.BoolValue != nil:
+			return strconv.FormatBool(*kv.Value.BoolValue), true
+		}
+	}
+	return "", false
+}
+
+const otlpSeverityInfo = 9
+
+func stringAttr(key, value string) OTLPKeyValue {
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{StringValue: &value}}
+}
+
+func intAttr(key string, value uint64) OTLPKeyValue {
+	s := strconv.FormatUint(value, 10)
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{IntValue: &s}}
+}
+
+func boolAttr(key string, value bool) OTLPKeyValue {
+	return OTLPKeyValue{Key: key, Value: OTLPAnyValue{BoolValue: &value}}
+}
+
+// NewOTLPLogsRequest converts events into an OTLP logs request, one log record
+// per event, following the OpenTelemetry semantic conventions where they exist
+func NewOTLPLogsRequest(events []models.EventOutput) OTLPLogsRequest {
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	records := make([]OTLPLogRecord, 0, len(events))
+	for _, e := range events {
+		ts := observed
+		if t, err := time.Parse(time.RFC3339, e.Timestamp); err == nil {
+			ts = strconv.FormatInt(t.UnixNano(), 10)
+		}
+
+		body := fmt.Sprintf("%s %s", e.Operation, e.Filename)
+		attributes := []OTLPKeyValue{
+			intAttr("process.pid", uint64(e.Pid)),
+			stringAttr("process.executable.name", e.Process),
+			stringAttr("file.path", e.Filename),
+			stringAttr("sniff_writes.operation", e.Operation),
+		}
+		if e.Fd != 0 {
+			attributes = append(attributes, intAttr("sniff_writes.fd", uint64(e.Fd)))
+		}
+		if e.WriteSize > 0 {
+			attributes = append(attributes,
+				intAttr("sniff_writes.size", e.WriteSize),
+				intAttr("sniff_writes.offset", e.FileOffset))
+		}
+		if e.Content != "" {
+			attributes = append(attributes,
+				stringAttr("sniff_writes.content", e.Content),
+				boolAttr("sniff_writes.truncated", e.Truncated))
+		}
+
+		records = append(records, OTLPLogRecord{
+			TimeUnixNano:         ts,
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       otlpSeverityInfo,
+			SeverityText:         "INFO",
+			Body:                 OTLPAnyValue{StringValue: &body},
+			Attributes:           attributes,
+		})
+	}
+
+	return OTLPLogsRequest{
+		ResourceLogs: []OTLPResourceLogs{{
+			Resource: OTLPResource{Attributes: []OTLPKeyValue{stringAttr("service.name", "sniff-writes")}},
+			ScopeLogs: []OTLPScopeLogs{{
+				Scope:      OTLPScope{Name: "sniff-writes"},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+func otlpLogsURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Path != "" && u.Path != "/") {
+		return endpoint
+	}
+	u.Path = "/v1/logs"
+	return u.String()
+}
+
+// DefaultCollectorRecords is the number of records a collector keeps
+const DefaultCollectorRecords = 10000
+
+// Collector is a minimal stand-in for an OpenTelemetry collector. It accepts
+// OTLP/HTTP JSON log requests on /v1/logs and keeps the most recent records,
+// so the OTLP sink can be tested and demoed without running a real collector.
+type Collector struct {
+	mu         sync.Mutex
+	records    []OTLPLogRecord
+	next       int // Slot of the oldest record once records is full
+	maxRecords int
+	received   int
+	onRecord   func(record OTLPLogRecord)
+}
+
+// NewCollector creates a collector calling onRecord for every received record.
+// onRecord may be nil. Only the last maxRecords records are kept, older ones
+// are overwritten; 0 uses DefaultCollectorRecords.
+func NewCollector(onRecord func(record OTLPLogRecord), maxRecords int) *Collector {
+	if maxRecords <= 0 {
+		maxRecords = DefaultCollectorRecords
+	}
+	return &Collector{onRecord: onRecord, maxRecords: maxRecords}
+}
+
+// ServeHTTP implements http.Handler
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/logs" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req OTLPLogsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid OTLP logs request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var received []OTLPLogRecord
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			received = append(received, sl.LogRecords...)
+		}
+	}
+
+	c.mu.Lock()
+	for _, record := range received {
+		if len(c.records) < c.maxRecords {
+			c.records = append(c.records, record)
+		} else {
+			c.records[c.next] = record
+			c.next = (c.next + 1) % c.maxRecords
+		}
+	}
+	c.received += len(received)
+	c.mu.Unlock()
+
+	if c.onRecord != nil {
+		for _, record := range received {
+			c.onRecord(record)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"partialSuccess":{}}`))
+}
+
+// Records returns a copy of the kept records, oldest first
+func (c *Collector) Records() []OTLPLogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	records := make([]OTLPLogRecord, 0, len(c.records))
+	records = append(records, c.records[c.next:]...)
+	return append(records, c.records[:c.next]...)
+}
+
+// Received returns the number of records received, including those no
+// longer kept
+func (c *Collector) Received() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.received
+}
@@ -0,0 +1,167 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+// Sink receives events one at a time as they are produced. Unlike
+// export.Exporter, which formats a finished slice, a sink can be fed from the
+// live monitor as well as from database queries.
+type Sink interface {
+	// Write hands one event to the sink. Sinks may buffer events.
+	Write(ctx context.Context, event models.EventOutput) error
+	// Flush delivers buffered events
+	Flush(ctx context.Context) error
+	// Close flushes remaining events and releases resources
+	Close(ctx context.Context) error
+}
+
+// Open creates a sink from a spec of the form "type:target":
+//
+//	ndjson:-                          newline-delimited JSON to stdout
+//	ndjson:/tmp/events.ndjson         newline-delimited JSON to a file
+//	columnar:/tmp/events.swc          columnar file with row groups
+//	otlp:http://localhost:4318        OpenTelemetry logs over OTLP/HTTP JSON
+//	webhook:https://example.com/hook  batched JSON POSTs
+func Open(spec string) (Sink, error) {
+	return OpenWithConfig(spec, DefaultBatchConfig())
+}
+
+// OpenWithConfig is Open with the batching used by the HTTP sinks
+func OpenWithConfig(spec string, batch BatchConfig) (Sink, error) {
+	kind, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid sink spec %q, expected type:target", spec)
+	}
+
+	switch kind {
+	case "ndjson":
+		if target == "-" {
+			return NewNDJSONSink(nopCloser{os.Stdout}), nil
+		}
+		file, err := os.Create(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ndjson file: %w", err)
+		}
+		return NewNDJSONSink(file), nil
+	case "columnar":
+		file, err := os.Create(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create columnar file: %w", err)
+		}
+		return NewColumnarSink(file, DefaultRowGroupSize), nil
+	case "otlp":
+		return NewOTLPSink(target, batch), nil
+	case "webhook":
+		return NewWebhookSink(target, batch), nil
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s. Use: ndjson, columnar, otlp, webhook", kind)
+	}
+}
+
+// OpenAll opens every spec and combines the sinks. Sinks opened before a
+// failing spec are closed again.
+func OpenAll(ctx context.Context, specs []string, batch BatchConfig) (*MultiSink, error) {
+	multi := &MultiSink{}
+	for _, spec := range specs {
+		s, err := OpenWithConfig(spec, batch)
+		if err != nil {
+			_ = multi.Close(ctx)
+			return nil, err
+		}
+		multi.sinks = append(multi.sinks, s)
+	}
+	return multi, nil
+}
+
+// MultiSink fans events out to several sinks. Errors from individual sinks
+// are collected so one failing sink does not starve the others.
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink combines sinks into one
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Len returns the number of sinks
+func (m *MultiSink) Len() int {
+	return len(m.sinks)
+}
+
+func (m *MultiSink) Write(ctx context.Context, event models.EventOutput) error {
+	return m.each(func(s Sink) error { return s.Write(ctx, event) })
+}
+
+func (m *MultiSink) Flush(ctx context.Context) error {
+	return m.each(func(s Sink) error { return s.Flush(ctx) })
+}
+
+func (m *MultiSink) Close(ctx context.Context) error {
+	return m.each(func(s Sink) error { return s.Close(ctx) })
+}
+
+func (m *MultiSink) each(fn func(s Sink) error) error {
+	var errs []string
+	for _, s := range m.sinks {
+		if err := fn(s); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sink errors: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// NDJSONSink writes one JSON object per line
+type NDJSONSink struct {
+	mu      sync.Mutex
+	writer  io.WriteCloser
+	encoder *json.Encoder
+}
+
+// NewNDJSONSink creates a sink writing newline-delimited JSON to writer
+func NewNDJSONSink(writer io.WriteCloser) *NDJSONSink {
+	return &NDJSONSink{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (s *NDJSONSink) Write(ctx context.Context, event models.EventOutput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(event)
+}
+
+func (s *NDJSONSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if syncer, ok := s.writer.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+func (s *NDJSONSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Close()
+}
+
+// nopCloser keeps stdout open when a sink is closed
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,431 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-labs/cmd/experiments/sniff-writes/pkg/models"
+)
+
+func testEvents(n int) []models.EventOutput {
+	events := make([]models.EventOutput, n)
+	for i := range events {
+		events[i] = models.EventOutput{
+			Timestamp:   time.Date(2024, 1, 1, 10, 0, i, 0, time.UTC).Format(time.RFC3339),
+			Pid:         uint32(100 + i),
+			Process:     "writer",
+			Operation:   "write",
+			Filename:    "/tmp/file.txt",
+			Fd:          3,
+			WriteSize:   uint64(i + 1),
+			FileOffset:  uint64(i * 10),
+			TotalChunks: 1,
+			Content:     strings.Repeat("x", i+1),
+			Truncated:   i%2 == 0,
+		}
+	}
+	return events
+}
+
+// fastBatchConfig retries quickly and only flushes when asked to
+func fastBatchConfig(maxBatch int) BatchConfig {
+	return BatchConfig{
+		MaxBatch:       maxBatch,
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Client:         &http.Client{Timeout: time.Second},
+	}
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+func TestNDJSONSink(t *testing.T) {
+	ctx := context.Background()
+	buf := &bufferCloser{}
+	s := NewNDJSONSink(buf)
+
+	for _, e := range testEvents(3) {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	var decoded models.EventOutput
+	if err := json.Unmarshal([]byte(lines[2]), &decoded); err != nil {
+		t.Fatalf("invalid json line: %v", err)
+	}
+	if decoded.Pid != 102 {
+		t.Errorf("expected pid 102, got %d", decoded.Pid)
+	}
+}
+
+func TestColumnarRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.swc")
+
+	s, err := Open("columnar:" + path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	// Use a small row group size to get several row groups
+	s.(*ColumnarSink).rowGroupSize = 4
+
+	events := testEvents(10)
+	for _, e := range events {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenColumnar(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open columnar file: %v", err)
+	}
+
+	if len(r.Footer.RowGroups) != 3 {
+		t.Fatalf("expected 3 row groups, got %d", len(r.Footer.RowGroups))
+	}
+	if r.Footer.RowGroups[0].MinTime != events[0].Timestamp || r.Footer.RowGroups[0].MaxTime != events[3].Timestamp {
+		t.Errorf("unexpected time range %s - %s", r.Footer.RowGroups[0].MinTime, r.Footer.RowGroups[0].MaxTime)
+	}
+
+	sizes, err := r.ReadColumn(2, "write_size")
+	if err != nil {
+		t.Fatalf("read column failed: %v", err)
+	}
+	if len(sizes) != 2 || sizes[1].(uint64) != 10 {
+		t.Errorf("unexpected write_size column %v", sizes)
+	}
+
+	decoded, err := r.ReadEvents()
+	if err != nil {
+		t.Fatalf("read events failed: %v", err)
+	}
+	if len(decoded) != len(events) {
+		t.Fatalf("expected %d events, got %d", len(events), len(decoded))
+	}
+	for i := range events {
+		if decoded[i] != events[i] {
+			t.Errorf("event %d mismatch:\nexpected %+v\ngot      %+v", i, events[i], decoded[i])
+		}
+	}
+}
+
+func TestColumnarRejectsOtherFiles(t *testing.T) {
+	data := []byte("this is definitely not a columnar file")
+	if _, err := OpenColumnar(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("expected error for non columnar data")
+	}
+}
+
+// columnarFile builds a columnar file from raw column data and a footer
+func columnarFile(t *testing.T, data []byte, footer ColumnarFooter) []byte {
+	t.Helper()
+	encoded, err := json.Marshal(footer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := append([]byte{}, columnarMagic...)
+	file = append(file, data...)
+	file = append(file, encoded...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(encoded)))
+	return append(file, columnarMagic...)
+}
+
+func TestColumnarRejectsCorruptFooters(t *testing.T) {
+	// Five bytes of column data right after the header
+	data := []byte{1, 2, 3, 4, 5}
+	schema := []Column{{Name: "pid", Type: "uint"}}
+	tests := []struct {
+		name  string
+		group RowGroup
+	}{
+		{"length past the footer", RowGroup{Rows: 1, Columns: []ColumnChunk{{Offset: 4, Length: 1 << 40}}}},
+		{"offset in the header", RowGroup{Rows: 1, Columns: []ColumnChunk{{Offset: 0, Length: 5}}}},
+		{"negative length", RowGroup{Rows: 0, Columns: []ColumnChunk{{Offset: 4, Length: -1}}}},
+		{"more rows than bytes", RowGroup{Rows: 1 << 30, Columns: []ColumnChunk{{Offset: 4, Length: 5}}}},
+		{"negative rows", RowGroup{Rows: -1, Columns: []ColumnChunk{{Offset: 4, Length: 5}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := columnarFile(t, data, ColumnarFooter{Schema: schema, RowGroups: []RowGroup{tt.group}})
+			if _, err := OpenColumnar(bytes.NewReader(file), int64(len(file))); err == nil {
+				t.Error("expected an error for a corrupt footer")
+			}
+		})
+	}
+
+	file := columnarFile(t, data, ColumnarFooter{Schema: schema, RowGroups: []RowGroup{
+		{Rows: 5, Columns: []ColumnChunk{{Offset: 4, Length: 5}}},
+	}})
+	r, err := OpenColumnar(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("failed to open valid file: %v", err)
+	}
+	values, err := r.ReadColumn(0, "pid")
+	if err != nil || len(values) != 5 || values[4].(uint64) != 5 {
+		t.Errorf("unexpected pid column %v (%v)", values, err)
+	}
+}
+
+func TestColumnarRejectsOversizedStrings(t *testing.T) {
+	// A string claiming a gigabyte in a column of three bytes
+	data := binary.AppendUvarint(nil, 1<<30)
+	file := columnarFile(t, data, ColumnarFooter{
+		Schema:    []Column{{Name: "process", Type: "string"}},
+		RowGroups: []RowGroup{{Rows: 1, Columns: []ColumnChunk{{Offset: 4, Length: int64(len(data))}}}},
+	})
+	r, err := OpenColumnar(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	if _, err := r.ReadColumn(0, "process"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("expected an oversized string error, got %v", err)
+	}
+}
+
+func TestWebhookSinkBatchesAndRetries(t *testing.T) {
+	var attempts int32
+	var mu sync.Mutex
+	var batches [][]models.EventOutput
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt of every batch
+		if atomic.AddInt32(&attempts, 1)%2 == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		batches = append(batches, payload.Events)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s := NewWebhookSink(server.URL, fastBatchConfig(2))
+	for _, e := range testEvents(5) {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != 2 || len(batches[2]) != 1 {
+		t.Errorf("unexpected batch sizes %d, %d", len(batches[0]), len(batches[2]))
+	}
+	if batches[2][0].Pid != 104 {
+		t.Errorf("expected batches in order, last pid %d", batches[2][0].Pid)
+	}
+	if attempts != 6 {
+		t.Errorf("expected 6 attempts, got %d", attempts)
+	}
+}
+
+func TestWebhookSinkGivesUpOnClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s := NewWebhookSink(server.URL, fastBatchConfig(10))
+	if err := s.Write(ctx, testEvents(1)[0]); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := s.Flush(ctx); err == nil {
+		t.Error("expected flush to fail")
+	}
+	if attempts != 1 {
+		t.Errorf("expected no retries for 400, got %d attempts", attempts)
+	}
+}
+
+func TestWebhookSinkDropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&received, int32(len(payload.Events)))
+	}))
+	defer server.Close()
+
+	config := fastBatchConfig(1)
+	config.QueueSize = 2
+	config.Client = &http.Client{Timeout: 5 * time.Second}
+
+	ctx := context.Background()
+	s := NewWebhookSink(server.URL, config).(*batchingSink)
+	start := time.Now()
+	for _, e := range testEvents(50) {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writes blocked on a stalled endpoint for %v", elapsed)
+	}
+	dropped := s.Dropped()
+	if dropped == 0 {
+		t.Fatal("expected events to be dropped")
+	}
+
+	close(release)
+	err := s.Close(ctx)
+	if err == nil || !strings.Contains(err.Error(), "queue was full") {
+		t.Errorf("expected close to report dropped events, got %v", err)
+	}
+	if got := uint64(atomic.LoadInt32(&received)) + dropped; got != 50 {
+		t.Errorf("expected received + dropped = 50, got %d", got)
+	}
+}
+
+func TestWebhookSinkBlocksForReplays(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&received, int32(len(payload.Events)))
+	}))
+	defer server.Close()
+
+	config := fastBatchConfig(2)
+	config.QueueSize = 1
+	config.Block = true
+
+	ctx := context.Background()
+	s := NewWebhookSink(server.URL, config)
+	for _, e := range testEvents(9) {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if received != 9 {
+		t.Errorf("expected all 9 events delivered, got %d", received)
+	}
+}
+
+func TestOTLPSinkToCollector(t *testing.T) {
+	collector := NewCollector(nil, 0)
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	ctx := context.Background()
+	s, err := Open("otlp:" + server.URL)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	for _, e := range testEvents(3) {
+		if err := s.Write(ctx, e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	records := collector.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if body := *records[0].Body.StringValue; body != "write /tmp/file.txt" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if pid, _ := records[1].Attribute("process.pid"); pid != "101" {
+		t.Errorf("expected pid attribute 101, got %q", pid)
+	}
+	if records[0].TimeUnixNano != "1704103200000000000" {
+		t.Errorf("unexpected timestamp %s", records[0].TimeUnixNano)
+	}
+}
+
+func TestCollectorKeepsMostRecentRecords(t *testing.T) {
+	collector := NewCollector(nil, 4)
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	for _, events := range [][]models.EventOutput{testEvents(3), testEvents(6)[3:]} {
+		body, err := json.Marshal(NewOTLPLogsRequest(events))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(server.URL+"/v1/logs", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("post failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if collector.Received() != 6 {
+		t.Errorf("expected 6 received records, got %d", collector.Received())
+	}
+	var pids []string
+	for _, record := range collector.Records() {
+		pid, _ := record.Attribute("process.pid")
+		pids = append(pids, pid)
+	}
+	if got := strings.Join(pids, ","); got != "102,103,104,105" {
+		t.Errorf("expected the last 4 records oldest first, got %s", got)
+	}
+}
+
+func TestOpenRejectsUnknownSinks(t *testing.T) {
+	for _, spec := range []string{"kafka:localhost", "ndjson", "ndjson:"} {
+		if _, err := Open(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+)
+
+// DebugExecutionCommand walks an execution node by node
+type DebugExecutionCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for DebugExecutionCommand
+type DebugExecutionSettings struct {
+	ExecutionID string `glazed.parameter:"execution-id"`
+	MaxItems    int    `glazed.parameter:"max-items"`
+	FailedOnly  bool   `glazed.parameter:"failed-only"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *DebugExecutionCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &DebugExecutionSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	// Get API settings
+	apiSettings, err := n8n.GetN8NAPISettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	client := n8n.NewN8NClient(apiSettings.BaseURL, apiSettings.APIKey)
+
+	execution, err := client.GetExecutionData(s.ExecutionID)
+	if err != nil {
+		return err
+	}
+
+	analysis, err := n8n.AnalyzeExecution(execution, n8n.AnalysisOptions{MaxItems: s.MaxItems})
+	if err != nil {
+		return err
+	}
+
+	var totalMs int64
+	for _, run := range analysis.Runs {
+		totalMs += run.ExecutionTimeMs
+	}
+
+	// Output one row per node run
+	for _, run := range analysis.Runs {
+		if s.FailedOnly && run.Error == nil {
+			continue
+		}
+
+		row := types.NewRow(
+			types.MRP("node", run.Node),
+			types.MRP("type", run.Type),
+			types.MRP("run", run.RunIndex),
+			types.MRP("status", run.Status),
+			types.MRP("start_time", run.StartTime),
+			types.MRP("execution_ms", run.ExecutionTimeMs),
+			types.MRP("time_pct", percentage(run.ExecutionTimeMs, totalMs)),
+			types.MRP("input_from", strings.Join(run.InputNodes, ", ")),
+			types.MRP("input_items", run.InputItems),
+			types.MRP("output_items", formatOutputItems(run.OutputItems)),
+		)
+		if run.Error != nil {
+			row.Set("error", run.Error.Message)
+			row.Set("error_description", run.Error.Description)
+			row.Set("http_code", run.Error.HTTPCode)
+			row.Set("parameters", run.Parameters)
+		}
+		if s.MaxItems > 0 {
+			row.Set("input", run.Input)
+			row.Set("output", run.Output)
+		}
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	// The execution failed before or outside of any node
+	if analysis.FailedNode == nil && analysis.Error != nil {
+		row := types.NewRow(
+			types.MRP("node", "(execution)"),
+			types.MRP("status", analysis.Status),
+			types.MRP("error", analysis.Error.Message),
+			types.MRP("error_description", analysis.Error.Description),
+		)
+		return gp.AddRow(ctx, row)
+	}
+
+	return nil
+}
+
+// formatOutputItems shows the item count per output, e.g. 2/0 for an If
+// node that sent two items to its true output
+func formatOutputItems(counts []int) string {
+	parts := make([]string, len(counts))
+	for i, n := range counts {
+		parts[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(parts, "/")
+}
+
+func percentage(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part*1000/total) / 10
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &DebugExecutionCommand{}
+
+// NewDebugExecutionCommand creates a new DebugExecutionCommand
+func NewDebugExecutionCommand() (*DebugExecutionCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the n8n API layer
+	apiLayer, err := n8n.NewN8NAPILayer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"debug-execution",
+		cmds.WithShort("Walk an execution node by node"),
+		cmds.WithLong(`Show every node run of an execution in the order it ran: status, timing,
+where its input came from and how many items went in and out.
+
+The failing node is shown with its error and the parameters it ran with.
+Use --max-items to include the first input and output items of each run.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"execution-id",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("ID of the execution to debug"),
+				parameters.WithRequired(true),
+			),
+			parameters.NewParameterDefinition(
+				"max-items",
+				parameters.ParameterTypeInteger,
+				parameters.WithHelp("Number of input and output items to show per node run (0 for counts only)"),
+				parameters.WithDefault(0),
+			),
+			parameters.NewParameterDefinition(
+				"failed-only",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Only show the failing node"),
+				parameters.WithDefault(false),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer, apiLayer),
+	)
+
+	// Return the command instance
+	return &DebugExecutionCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+)
+
+// DiffCommand shows the differences between workflow files and the server
+type DiffCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for DiffCommand
+type DiffSettings struct {
+	Dir             string `glazed.parameter:"dir"`
+	IgnorePositions bool   `glazed.parameter:"ignore-positions"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *DiffCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &DiffSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	// Get API settings
+	apiSettings, err := n8n.GetN8NAPISettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	client := n8n.NewN8NClient(apiSettings.BaseURL, apiSettings.APIKey)
+
+	files, err := n8n.LoadWorkflowDir(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	remote, err := client.ListAllWorkflows()
+	if err != nil {
+		return err
+	}
+
+	actions, err := n8n.PlanSync(files, remote, n8n.DiffOptions{IgnorePositions: s.IgnorePositions})
+	if err != nil {
+		return err
+	}
+
+	// Output one row per change
+	for _, action := range actions {
+		file := ""
+		if action.File != nil {
+			file = action.File.Path
+		}
+		for _, change := range action.Changes {
+			row := types.NewRow(
+				types.MRP("id", action.ID()),
+				types.MRP("workflow", action.Name()),
+				types.MRP("file", file),
+				types.MRP("kind", change.Kind),
+				types.MRP("op", change.Op),
+				types.MRP("target", change.Target),
+				types.MRP("detail", change.Detail),
+			)
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &DiffCommand{}
+
+// NewDiffCommand creates a new DiffCommand
+func NewDiffCommand() (*DiffCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the n8n API layer
+	apiLayer, err := n8n.NewN8NAPILayer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"diff",
+		cmds.WithShort("Show differences between workflow files and the server"),
+		cmds.WithLong(`Compare the workflow files in a directory with the workflows on the server.
+
+Differences are reported per node, connection and setting rather than per
+line. "added" and "removed" are seen from the files: an added node exists in
+the file but not on the server, and push would add it. Workflows that only
+exist on the server are reported as removed.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"dir",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Directory with workflow files"),
+				parameters.WithDefault("workflows"),
+			),
+			parameters.NewParameterDefinition(
+				"ignore-positions",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Don't report node position changes"),
+				parameters.WithDefault(false),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer, apiLayer),
+	)
+
+	// Return the command instance
+	return &DiffCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
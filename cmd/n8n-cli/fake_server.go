@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n/fakeserver"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newFakeServerCommand creates the fake-server command, which serves an
+// in-memory imitation of the n8n API for trying out pull, push and diff
+func newFakeServerCommand() *cobra.Command {
+	var addr, apiKey, seedDir, seedExecutionsDir string
+
+	cmd := &cobra.Command{
+		Use:   "fake-server",
+		Short: "Run an in-memory fake n8n API for testing",
+		Long: `Run an in-memory imitation of the n8n REST API (workflows, executions and
+node types).
+
+Use it to try out pull, diff, push and validate without an n8n instance:
+
+  n8n-cli fake-server --addr :5679 --api-key test --seed examples/
+  n8n-cli pull --base-url http://localhost:5679 --api-key test --dir workflows
+
+Executions can be loaded from JSON files (as returned by the API with
+includeData) to try out debug-execution and triage-failures.
+
+Workflows are lost when the server stops.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := fakeserver.New(apiKey)
+			if seedDir != "" {
+				if err := server.LoadWorkflows(seedDir); err != nil {
+					return err
+				}
+			}
+			if seedExecutionsDir != "" {
+				if err := server.LoadExecutions(seedExecutionsDir); err != nil {
+					return err
+				}
+			}
+
+			log.Info().Str("addr", addr).Msg("Starting fake n8n server")
+			return http.ListenAndServe(addr, server.Handler())
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":5679", "Address to listen on")
+	cmd.Flags().StringVar(&apiKey, "api-key", "test", "API key clients must send")
+	cmd.Flags().StringVar(&seedDir, "seed", "", "Directory of workflow JSON files to load on startup")
+	cmd.Flags().StringVar(&seedExecutionsDir, "seed-executions", "", "Directory of execution JSON files to load on startup")
+
+	return cmd
+}
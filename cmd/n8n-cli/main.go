@@ -8,6 +8,7 @@ import (
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/logging"
 	"github.com/go-go-golems/glazed/pkg/help"
+	help_cmd "github.com/go-go-golems/glazed/pkg/help/cmd"
 	"github.com/go-go-golems/go-go-mcp/pkg/embeddable"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -51,7 +52,7 @@ Settings → n8n API → "Create an API key".
 
 	// Initialize help system
 	helpSystem := help.NewHelpSystem()
-	helpSystem.SetupCobraRootCommand(rootCmd)
+	help_cmd.SetupCobraRootCommand(helpSystem, rootCmd)
 
 	// Create all commands
 	commands, err := createCommands()
@@ -90,6 +91,8 @@ Settings → n8n API → "Create an API key".
 		rootCmd.AddCommand(cobraCmd)
 	}
 
+	rootCmd.AddCommand(newFakeServerCommand())
+
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -156,5 +159,47 @@ func createCommands() ([]cmds.Command, error) {
 	}
 	commands = append(commands, getExecutionCmd)
 
+	// Debug execution command
+	debugExecutionCmd, err := NewDebugExecutionCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating debug-execution command: %w", err)
+	}
+	commands = append(commands, debugExecutionCmd)
+
+	// Triage failures command
+	triageFailuresCmd, err := NewTriageFailuresCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating triage-failures command: %w", err)
+	}
+	commands = append(commands, triageFailuresCmd)
+
+	// Pull workflows to a directory command
+	pullCmd, err := NewPullCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating pull command: %w", err)
+	}
+	commands = append(commands, pullCmd)
+
+	// Diff workflow files against the server command
+	diffCmd, err := NewDiffCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating diff command: %w", err)
+	}
+	commands = append(commands, diffCmd)
+
+	// Push workflow files to the server command
+	pushCmd, err := NewPushCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating push command: %w", err)
+	}
+	commands = append(commands, pushCmd)
+
+	// Validate workflow files offline command
+	validateCmd, err := NewValidateCommand()
+	if err != nil {
+		return nil, fmt.Errorf("error creating validate command: %w", err)
+	}
+	commands = append(commands, validateCmd)
+
 	return commands, nil
 }
@@ -604,6 +604,62 @@ This should be the execution ID from an execution record, typically obtained fro
 				embeddable.PropertyRequired(),
 			),
 		),
+
+		// Debug execution
+		embeddable.WithEnhancedTool("debug_execution", debugExecutionHandler,
+			embeddable.WithEnhancedDescription(`Walk a single execution node by node and pinpoint where it failed.
+
+Unlike get_execution, which returns the raw execution record, this tool returns a structured analysis:
+- **runs**: every node run in the order it ran, with status, start time, execution time in milliseconds, the nodes it received input from, the number of input items and the number of output items per output (e.g. true/false of an If node)
+- **failedNode**: the run that failed, with the error message, description, HTTP code and the parameters the node ran with
+- **error**: the execution level error, when the execution failed outside of a node
+- the first input and output items of each run, controlled by max_items
+
+Use this tool to:
+- Find out which node broke an automation and why
+- Check what data a node actually received before it failed
+- Find slow nodes from the per-node timing
+- Compare the failing node's parameters with the data it got
+
+Typical flow: triage_failures to find the most common failures, then debug_execution on one of the listed execution IDs, then get_workflow to fix the node.`),
+			embeddable.WithReadOnlyHint(true),
+			embeddable.WithIdempotentHint(true),
+			embeddable.WithStringProperty("id",
+				embeddable.PropertyDescription(`ID of the execution to analyze, as returned by list_executions or triage_failures.`),
+				embeddable.PropertyRequired(),
+			),
+			embeddable.WithIntProperty("max_items",
+				embeddable.PropertyDescription(`Number of input and output items to include per node run. Only the JSON payload of items is included, binary data is left out. Use 0 to only get item counts, which keeps the response small for large executions.`),
+				embeddable.DefaultNumber(3),
+				embeddable.Minimum(0),
+				embeddable.Maximum(50),
+			),
+		),
+
+		// Triage failures
+		embeddable.WithEnhancedTool("triage_failures", triageFailuresHandler,
+			embeddable.WithEnhancedDescription(`Group recent failed executions by workflow, failing node and error message.
+
+Fetches the most recent failed executions and returns one group per distinct failure, most frequent first. Each group has the workflow, the failing node and its type, the error message, the number of occurrences, when it was first and last seen in the fetched executions, and the IDs of the executions.
+
+IDs, timestamps and long numbers in error messages are masked, so the same error with different record IDs ends up in one group.
+
+Use this tool to:
+- Get an overview of what is currently broken
+- Decide which failure to fix first
+- Find an execution ID to hand to debug_execution`),
+			embeddable.WithReadOnlyHint(true),
+			embeddable.WithIdempotentHint(true),
+			embeddable.WithStringProperty("workflow_id",
+				embeddable.PropertyDescription(`Only look at failed executions of this workflow. Leave empty for all workflows.`),
+			),
+			embeddable.WithIntProperty("limit",
+				embeddable.PropertyDescription(`Number of recent failed executions to fetch and group. Every execution is fetched with its run data, so large limits take longer.`),
+				embeddable.DefaultNumber(20),
+				embeddable.Minimum(1),
+				embeddable.Maximum(100),
+			),
+		),
 	}
 }
 
@@ -973,3 +1029,59 @@ func getExecutionHandler(ctx context.Context, args embeddable.Arguments) (*proto
 		protocol.WithText(string(data)),
 	), nil
 }
+
+func debugExecutionHandler(ctx context.Context, args embeddable.Arguments) (*protocol.ToolResult, error) {
+	client, err := createN8NClientFromContext(ctx)
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(err.Error())), nil
+	}
+
+	id, err := args.RequireString("id")
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(err.Error())), nil
+	}
+	maxItems := args.GetInt("max_items", 3)
+
+	execution, err := client.GetExecutionData(id)
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(fmt.Sprintf("Failed to get execution: %v", err))), nil
+	}
+
+	analysis, err := n8n.AnalyzeExecution(execution, n8n.AnalysisOptions{MaxItems: maxItems})
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(fmt.Sprintf("Failed to analyze execution: %v", err))), nil
+	}
+
+	data, err := json.MarshalIndent(analysis, "", "  ")
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(fmt.Sprintf("Failed to marshal analysis: %v", err))), nil
+	}
+
+	return protocol.NewToolResult(
+		protocol.WithText(string(data)),
+	), nil
+}
+
+func triageFailuresHandler(ctx context.Context, args embeddable.Arguments) (*protocol.ToolResult, error) {
+	client, err := createN8NClientFromContext(ctx)
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(err.Error())), nil
+	}
+
+	workflowID := args.GetString("workflow_id", "")
+	limit := args.GetInt("limit", 20)
+
+	analyses, err := client.RecentFailures(workflowID, limit)
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(fmt.Sprintf("Failed to fetch failed executions: %v", err))), nil
+	}
+
+	data, err := json.MarshalIndent(n8n.AggregateFailures(analyses), "", "  ")
+	if err != nil {
+		return protocol.NewErrorToolResult(protocol.NewTextContent(fmt.Sprintf("Failed to marshal failures: %v", err))), nil
+	}
+
+	return protocol.NewToolResult(
+		protocol.WithText(string(data)),
+	), nil
+}
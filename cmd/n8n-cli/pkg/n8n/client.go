@@ -442,13 +442,17 @@ func (c *N8NClient) ListExecutions(params map[string]string) ([]map[string]inter
 
 // GetExecution gets details of a specific execution by ID
 func (c *N8NClient) GetExecution(id string) (map[string]interface{}, error) {
-	resp, err := c.DoRequest("GET", fmt.Sprintf("executions/%s", id), nil)
+	return c.getExecution(fmt.Sprintf("executions/%s", id))
+}
+
+func (c *N8NClient) getExecution(endpoint string) (map[string]interface{}, error) {
+	resp, err := c.DoRequest("GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if err := handleErrorResponse(resp, fmt.Sprintf("executions/%s", id), http.StatusOK); err != nil {
+	if err := handleErrorResponse(resp, endpoint, http.StatusOK); err != nil {
 		return nil, err
 	}
 
@@ -465,6 +469,12 @@ func (c *N8NClient) GetExecution(id string) (map[string]interface{}, error) {
 	return execution, nil
 }
 
+// GetExecutionData gets an execution including its run data (the input and
+// output items of every node), which GetExecution leaves out
+func (c *N8NClient) GetExecutionData(id string) (map[string]interface{}, error) {
+	return c.getExecution(fmt.Sprintf("executions/%s?includeData=true", id))
+}
+
 // GetNodes gets available node types in n8n
 func (c *N8NClient) GetNodes() ([]map[string]interface{}, error) {
 	resp, err := c.DoRequest("GET", "nodes", nil)
@@ -497,3 +507,20 @@ func (c *N8NClient) GetNodes() ([]map[string]interface{}, error) {
 
 	return result.Data, nil
 }
+
+// ListAllWorkflows retrieves all workflows, following the pagination cursor
+func (c *N8NClient) ListAllWorkflows() ([]map[string]interface{}, error) {
+	var workflows []map[string]interface{}
+	cursor := ""
+	for {
+		page, nextCursor, err := c.ListWorkflows(false, 100, cursor)
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, page...)
+		if nextCursor == "" {
+			return workflows, nil
+		}
+		cursor = nextCursor
+	}
+}
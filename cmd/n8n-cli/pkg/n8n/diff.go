@@ -0,0 +1,234 @@
+package n8n
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change operations
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// WorkflowChange is a semantic difference between a local and a remote
+// workflow. Added and removed are seen from the local side: "added" means
+// the local file has something the server doesn't.
+type WorkflowChange struct {
+	Kind   string // workflow, name, node, connection or setting
+	Op     string // added, removed or changed
+	Target string // node name, connection or setting key
+	Detail string
+}
+
+// DiffOptions controls which differences are reported
+type DiffOptions struct {
+	// IgnorePositions skips node position changes, which only affect the
+	// layout in the editor
+	IgnorePositions bool
+}
+
+// DiffWorkflows compares two workflows node by node and connection by
+// connection. Both are normalized first, so server managed fields and node
+// order don't show up as changes.
+func DiffWorkflows(local, remote map[string]interface{}, opts DiffOptions) []WorkflowChange {
+	local = NormalizeWorkflow(toJSONValue(local))
+	remote = NormalizeWorkflow(toJSONValue(remote))
+
+	var changes []WorkflowChange
+
+	if local["name"] != remote["name"] {
+		changes = append(changes, WorkflowChange{
+			Kind:   "name",
+			Op:     ChangeChanged,
+			Target: fmt.Sprint(local["name"]),
+			Detail: fmt.Sprintf("%v -> %v", remote["name"], local["name"]),
+		})
+	}
+
+	changes = append(changes, diffNodes(local, remote, opts)...)
+	changes = append(changes, diffConnections(local, remote)...)
+	changes = append(changes, diffSettings(local, remote)...)
+
+	return changes
+}
+
+func diffNodes(local, remote map[string]interface{}, opts DiffOptions) []WorkflowChange {
+	localNodes := nodesByName(local)
+	remoteNodes := nodesByName(remote)
+
+	var changes []WorkflowChange
+	for _, name := range sortedKeys(localNodes, remoteNodes) {
+		localNode, inLocal := localNodes[name]
+		remoteNode, inRemote := remoteNodes[name]
+		switch {
+		case !inRemote:
+			changes = append(changes, WorkflowChange{Kind: "node", Op: ChangeAdded, Target: name, Detail: fmt.Sprint(localNode["type"])})
+		case !inLocal:
+			changes = append(changes, WorkflowChange{Kind: "node", Op: ChangeRemoved, Target: name, Detail: fmt.Sprint(remoteNode["type"])})
+		default:
+			for _, field := range changedFields(localNode, remoteNode, "") {
+				if opts.IgnorePositions && field == "position" {
+					continue
+				}
+				changes = append(changes, WorkflowChange{Kind: "node", Op: ChangeChanged, Target: name, Detail: field})
+			}
+		}
+	}
+	return changes
+}
+
+// changedFields returns the paths of the fields that differ, descending into
+// parameters and credentials so that a changed parameter is reported as
+// parameters.url instead of parameters
+func changedFields(local, remote map[string]interface{}, prefix string) []string {
+	var fields []string
+	for _, key := range sortedKeys(local, remote) {
+		localValue, inLocal := local[key]
+		remoteValue, inRemote := remote[key]
+		if inLocal && inRemote && reflect.DeepEqual(localValue, remoteValue) {
+			continue
+		}
+
+		path := prefix + key
+		localMap, localIsMap := localValue.(map[string]interface{})
+		remoteMap, remoteIsMap := remoteValue.(map[string]interface{})
+		if prefix == "" && (key == "parameters" || key == "credentials") && localIsMap && remoteIsMap {
+			fields = append(fields, changedFields(localMap, remoteMap, path+".")...)
+			continue
+		}
+		fields = append(fields, path)
+	}
+	return fields
+}
+
+func diffConnections(local, remote map[string]interface{}) []WorkflowChange {
+	localEdges := map[string]bool{}
+	for _, edge := range workflowEdges(local) {
+		localEdges[edge.String()] = true
+	}
+	remoteEdges := map[string]bool{}
+	for _, edge := range workflowEdges(remote) {
+		remoteEdges[edge.String()] = true
+	}
+
+	var changes []WorkflowChange
+	for _, edge := range sortedKeys(localEdges, remoteEdges) {
+		switch {
+		case !remoteEdges[edge]:
+			changes = append(changes, WorkflowChange{Kind: "connection", Op: ChangeAdded, Target: edge})
+		case !localEdges[edge]:
+			changes = append(changes, WorkflowChange{Kind: "connection", Op: ChangeRemoved, Target: edge})
+		}
+	}
+	return changes
+}
+
+func diffSettings(local, remote map[string]interface{}) []WorkflowChange {
+	localSettings, _ := local["settings"].(map[string]interface{})
+	remoteSettings, _ := remote["settings"].(map[string]interface{})
+
+	var changes []WorkflowChange
+	for _, key := range sortedKeys(localSettings, remoteSettings) {
+		localValue, inLocal := localSettings[key]
+		remoteValue, inRemote := remoteSettings[key]
+		switch {
+		case !inRemote:
+			changes = append(changes, WorkflowChange{Kind: "setting", Op: ChangeAdded, Target: key, Detail: fmt.Sprint(localValue)})
+		case !inLocal:
+			changes = append(changes, WorkflowChange{Kind: "setting", Op: ChangeRemoved, Target: key, Detail: fmt.Sprint(remoteValue)})
+		case !reflect.DeepEqual(localValue, remoteValue):
+			changes = append(changes, WorkflowChange{Kind: "setting", Op: ChangeChanged, Target: key, Detail: fmt.Sprintf("%v -> %v", remoteValue, localValue)})
+		}
+	}
+	return changes
+}
+
+// Edge is a single connection between two nodes
+type Edge struct {
+	Source      string
+	OutputType  string
+	OutputIndex int
+	Target      string
+	InputType   string
+	InputIndex  int
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%s[%s:%d] -> %s[%s:%d]", e.Source, e.OutputType, e.OutputIndex, e.Target, e.InputType, e.InputIndex)
+}
+
+// workflowEdges flattens the connections map of a workflow:
+// {"Source": {"main": [[{"node": "Target", "type": "main", "index": 0}]]}}
+func workflowEdges(workflow map[string]interface{}) []Edge {
+	connections, _ := workflow["connections"].(map[string]interface{})
+
+	var edges []Edge
+	for source, rawOutputs := range connections {
+		outputs, _ := rawOutputs.(map[string]interface{})
+		for outputType, rawIndexes := range outputs {
+			indexes, _ := rawIndexes.([]interface{})
+			for outputIndex, rawTargets := range indexes {
+				targets, _ := rawTargets.([]interface{})
+				for _, rawTarget := range targets {
+					target, ok := rawTarget.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					edge := Edge{Source: source, OutputType: outputType, OutputIndex: outputIndex}
+					edge.Target, _ = target["node"].(string)
+					edge.InputType, _ = target["type"].(string)
+					if index, ok := target["index"].(float64); ok {
+						edge.InputIndex = int(index)
+					}
+					edges = append(edges, edge)
+				}
+			}
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool { return edges[i].String() < edges[j].String() })
+	return edges
+}
+
+func nodesByName(workflow map[string]interface{}) map[string]map[string]interface{} {
+	nodes := map[string]map[string]interface{}{}
+	for _, node := range workflowNodes(workflow) {
+		name, _ := node["name"].(string)
+		nodes[name] = node
+	}
+	return nodes
+}
+
+// sortedKeys returns the union of the keys of two maps, sorted
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toJSONValue converts a workflow to the types encoding/json produces, so
+// that values built in code compare equal to decoded ones
+func toJSONValue(workflow map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(workflow)
+	if err != nil {
+		return workflow
+	}
+	var converted map[string]interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return workflow
+	}
+	return converted
+}
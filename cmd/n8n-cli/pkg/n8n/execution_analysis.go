@@ -0,0 +1,458 @@
+package n8n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExecutionAnalysis is an execution walked node by node
+type ExecutionAnalysis struct {
+	ID               string     `json:"id"`
+	WorkflowID       string     `json:"workflowId"`
+	WorkflowName     string     `json:"workflowName,omitempty"`
+	Status           string     `json:"status"`
+	Mode             string     `json:"mode,omitempty"`
+	StartedAt        *time.Time `json:"startedAt,omitempty"`
+	StoppedAt        *time.Time `json:"stoppedAt,omitempty"`
+	DurationMs       int64      `json:"durationMs"`
+	LastNodeExecuted string     `json:"lastNodeExecuted,omitempty"`
+	// Runs are ordered by start time. A node inside a loop runs more than once.
+	Runs []*NodeRun `json:"runs"`
+	// FailedNode is the run that failed, nil for successful executions
+	FailedNode *NodeRun `json:"failedNode,omitempty"`
+	// Error is the execution level error. It is set when the execution
+	// failed outside of a node, e.g. because the workflow couldn't start.
+	Error *NodeError `json:"error,omitempty"`
+}
+
+// NodeRun is one run of a node
+type NodeRun struct {
+	Node            string                   `json:"node"`
+	Type            string                   `json:"type,omitempty"`
+	RunIndex        int                      `json:"runIndex"`
+	Status          string                   `json:"status"`
+	StartTime       *time.Time               `json:"startTime,omitempty"`
+	ExecutionTimeMs int64                    `json:"executionTimeMs"`
+	InputNodes      []string                 `json:"inputNodes,omitempty"`
+	InputItems      int                      `json:"inputItems"`
+	OutputItems     []int                    `json:"outputItems"` // Per output, e.g. true and false of an If node
+	Input           []interface{}            `json:"input,omitempty"`
+	Output          [][]interface{}          `json:"output,omitempty"`
+	Parameters      map[string]interface{}   `json:"parameters,omitempty"`
+	Error           *NodeError               `json:"error,omitempty"`
+	inputRefs       []map[string]interface{} // source entries, resolved to Input after all runs are read
+}
+
+// TotalOutputItems returns the number of items over all outputs
+func (r *NodeRun) TotalOutputItems() int {
+	total := 0
+	for _, n := range r.OutputItems {
+		total += n
+	}
+	return total
+}
+
+// NodeError is an error reported by n8n for a node or an execution
+type NodeError struct {
+	Name        string `json:"name,omitempty"`
+	Message     string `json:"message"`
+	Description string `json:"description,omitempty"`
+	HTTPCode    string `json:"httpCode,omitempty"`
+	Node        string `json:"node,omitempty"`
+	Stack       string `json:"stack,omitempty"`
+}
+
+// AnalysisOptions controls how much item data is kept
+type AnalysisOptions struct {
+	// MaxItems is the number of input and output items kept per run, 0
+	// keeps only the counts
+	MaxItems int
+}
+
+// AnalyzeExecution walks the run data of an execution fetched with
+// includeData. Input items of a run are the output items of the runs it
+// received data from.
+func AnalyzeExecution(execution map[string]interface{}, opts AnalysisOptions) (*ExecutionAnalysis, error) {
+	analysis := &ExecutionAnalysis{
+		ID:         stringValue(execution["id"]),
+		WorkflowID: stringValue(execution["workflowId"]),
+		Status:     executionStatus(execution),
+		Mode:       stringValue(execution["mode"]),
+		StartedAt:  parseTime(execution["startedAt"]),
+		StoppedAt:  parseTime(execution["stoppedAt"]),
+	}
+	if analysis.StartedAt != nil && analysis.StoppedAt != nil {
+		analysis.DurationMs = analysis.StoppedAt.Sub(*analysis.StartedAt).Milliseconds()
+	}
+
+	// Node types and parameters come from the workflow as it was when the
+	// execution ran
+	nodes := map[string]map[string]interface{}{}
+	if workflowData, ok := execution["workflowData"].(map[string]interface{}); ok {
+		analysis.WorkflowName = stringValue(workflowData["name"])
+		for _, node := range workflowNodes(workflowData) {
+			nodes[stringValue(node["name"])] = node
+		}
+	}
+
+	data, _ := execution["data"].(map[string]interface{})
+	if data == nil {
+		return nil, fmt.Errorf("execution %s has no run data, fetch it with includeData=true", analysis.ID)
+	}
+	resultData, _ := data["resultData"].(map[string]interface{})
+	analysis.LastNodeExecuted = stringValue(resultData["lastNodeExecuted"])
+	if rawError, ok := resultData["error"].(map[string]interface{}); ok {
+		analysis.Error = parseNodeError(rawError)
+	}
+
+	runData, _ := resultData["runData"].(map[string]interface{})
+	for nodeName, rawRuns := range runData {
+		runs, _ := rawRuns.([]interface{})
+		for runIndex, rawRun := range runs {
+			run, ok := rawRun.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			nodeRun := parseNodeRun(nodeName, runIndex, run)
+			if node, ok := nodes[nodeName]; ok {
+				nodeRun.Type = stringValue(node["type"])
+				if nodeRun.Error != nil {
+					nodeRun.Parameters, _ = node["parameters"].(map[string]interface{})
+				}
+			}
+			analysis.Runs = append(analysis.Runs, nodeRun)
+		}
+	}
+
+	for _, run := range analysis.Runs {
+		resolveInput(run, runData)
+	}
+	for _, run := range analysis.Runs {
+		run.Input = truncateItems(run.Input, opts.MaxItems)
+		for i := range run.Output {
+			run.Output[i] = truncateItems(run.Output[i], opts.MaxItems)
+		}
+	}
+
+	sort.SliceStable(analysis.Runs, func(i, j int) bool {
+		a, b := analysis.Runs[i], analysis.Runs[j]
+		if a.StartTime == nil || b.StartTime == nil {
+			return a.StartTime != nil
+		}
+		if !a.StartTime.Equal(*b.StartTime) {
+			return a.StartTime.Before(*b.StartTime)
+		}
+		return a.Node < b.Node
+	})
+
+	analysis.FailedNode = findFailedRun(analysis)
+	if failed := analysis.FailedNode; failed != nil && failed.Parameters == nil {
+		// Older versions only name the failed node in the execution error
+		failed.Parameters, _ = nodes[failed.Node]["parameters"].(map[string]interface{})
+	}
+	return analysis, nil
+}
+
+// parseNodeRun reads a runData entry:
+//
+//	{"startTime": 1700000000000, "executionTime": 12, "executionStatus": "success",
+//	 "source": [{"previousNode": "A"}], "data": {"main": [[{"json": {...}}]]}, "error": {...}}
+func parseNodeRun(nodeName string, runIndex int, run map[string]interface{}) *NodeRun {
+	nodeRun := &NodeRun{
+		Node:     nodeName,
+		RunIndex: runIndex,
+		Status:   stringValue(run["executionStatus"]),
+	}
+	if ms, ok := run["startTime"].(float64); ok {
+		t := time.UnixMilli(int64(ms)).UTC()
+		nodeRun.StartTime = &t
+	}
+	if ms, ok := run["executionTime"].(float64); ok {
+		nodeRun.ExecutionTimeMs = int64(ms)
+	}
+	if rawError, ok := run["error"].(map[string]interface{}); ok {
+		nodeRun.Error = parseNodeError(rawError)
+		nodeRun.Status = "error"
+	}
+	if nodeRun.Status == "" {
+		nodeRun.Status = "success"
+	}
+
+	if sources, ok := run["source"].([]interface{}); ok {
+		for _, rawSource := range sources {
+			if source, ok := rawSource.(map[string]interface{}); ok {
+				nodeRun.inputRefs = append(nodeRun.inputRefs, source)
+				nodeRun.InputNodes = append(nodeRun.InputNodes, stringValue(source["previousNode"]))
+			}
+		}
+	}
+
+	nodeRun.Output = mainOutputs(run)
+	nodeRun.OutputItems = make([]int, len(nodeRun.Output))
+	for i, items := range nodeRun.Output {
+		nodeRun.OutputItems[i] = len(items)
+	}
+	return nodeRun
+}
+
+// resolveInput collects the items a run received from the runs named in its
+// source entries
+func resolveInput(run *NodeRun, runData map[string]interface{}) {
+	for _, source := range run.inputRefs {
+		previousNode := stringValue(source["previousNode"])
+		outputIndex := intValue(source["previousNodeOutput"])
+		previousRun := intValue(source["previousNodeRun"])
+
+		// Items stay in the raw run data, runs only reference them
+		rawRuns, _ := runData[previousNode].([]interface{})
+		if previousRun >= len(rawRuns) {
+			continue
+		}
+		rawRun, _ := rawRuns[previousRun].(map[string]interface{})
+		outputs := mainOutputs(rawRun)
+		if outputIndex < len(outputs) {
+			run.Input = append(run.Input, outputs[outputIndex]...)
+			run.InputItems += len(outputs[outputIndex])
+		}
+	}
+	run.inputRefs = nil
+}
+
+// findFailedRun returns the run that failed. n8n marks it with an error;
+// older versions only name the node in the execution error.
+func findFailedRun(analysis *ExecutionAnalysis) *NodeRun {
+	for _, run := range analysis.Runs {
+		if run.Error != nil {
+			return run
+		}
+	}
+	if analysis.Error == nil {
+		return nil
+	}
+	name := analysis.Error.Node
+	if name == "" && analysis.Status == "error" {
+		name = analysis.LastNodeExecuted
+	}
+	for i := len(analysis.Runs) - 1; i >= 0; i-- {
+		if analysis.Runs[i].Node == name {
+			analysis.Runs[i].Error = analysis.Error
+			analysis.Runs[i].Status = "error"
+			return analysis.Runs[i]
+		}
+	}
+	return nil
+}
+
+func parseNodeError(raw map[string]interface{}) *NodeError {
+	nodeError := &NodeError{
+		Name:        stringValue(raw["name"]),
+		Message:     stringValue(raw["message"]),
+		Description: stringValue(raw["description"]),
+		HTTPCode:    stringValue(raw["httpCode"]),
+		Stack:       stringValue(raw["stack"]),
+	}
+	if node, ok := raw["node"].(map[string]interface{}); ok {
+		nodeError.Node = stringValue(node["name"])
+	}
+	return nodeError
+}
+
+// mainOutputs returns the items per main output of a run
+func mainOutputs(run map[string]interface{}) [][]interface{} {
+	data, _ := run["data"].(map[string]interface{})
+	rawOutputs, _ := data["main"].([]interface{})
+	outputs := make([][]interface{}, len(rawOutputs))
+	for i, rawItems := range rawOutputs {
+		items, _ := rawItems.([]interface{})
+		outputs[i] = items
+	}
+	return outputs
+}
+
+func truncateItems(items []interface{}, max int) []interface{} {
+	if max == 0 || len(items) == 0 {
+		return nil
+	}
+	if len(items) > max {
+		items = items[:max]
+	}
+	// Keep the JSON payload, binary data only bloats the output
+	truncated := make([]interface{}, len(items))
+	for i, rawItem := range items {
+		if item, ok := rawItem.(map[string]interface{}); ok {
+			truncated[i] = item["json"]
+		} else {
+			truncated[i] = rawItem
+		}
+	}
+	return truncated
+}
+
+func executionStatus(execution map[string]interface{}) string {
+	if status := stringValue(execution["status"]); status != "" {
+		return status
+	}
+	// Older versions only have finished
+	if finished, _ := execution["finished"].(bool); finished {
+		return "success"
+	}
+	return "error"
+}
+
+// FailureGroup is a set of failed executions with the same failing node and
+// error message
+type FailureGroup struct {
+	WorkflowID   string    `json:"workflowId"`
+	WorkflowName string    `json:"workflowName,omitempty"`
+	Node         string    `json:"node"`
+	NodeType     string    `json:"nodeType,omitempty"`
+	Message      string    `json:"message"`
+	Count        int       `json:"count"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+	ExecutionIDs []string  `json:"executionIds"`
+}
+
+// AggregateFailures groups failed executions by workflow, failing node and
+// error message. Volatile parts of messages (IDs, timestamps, long numbers)
+// are masked so the same error groups together. Groups are sorted by count.
+func AggregateFailures(analyses []*ExecutionAnalysis) []*FailureGroup {
+	groups := map[string]*FailureGroup{}
+	var ordered []*FailureGroup
+
+	for _, analysis := range analyses {
+		if analysis.Status == "success" {
+			continue
+		}
+		node, nodeType, message := "", "", ""
+		switch {
+		case analysis.FailedNode != nil:
+			node = analysis.FailedNode.Node
+			nodeType = analysis.FailedNode.Type
+			message = analysis.FailedNode.Error.Message
+		case analysis.Error != nil:
+			message = analysis.Error.Message
+		default:
+			continue
+		}
+		message = NormalizeErrorMessage(message)
+
+		key := analysis.WorkflowID + "\x00" + node + "\x00" + message
+		group, ok := groups[key]
+		if !ok {
+			group = &FailureGroup{
+				WorkflowID:   analysis.WorkflowID,
+				WorkflowName: analysis.WorkflowName,
+				Node:         node,
+				NodeType:     nodeType,
+				Message:      message,
+			}
+			groups[key] = group
+			ordered = append(ordered, group)
+		}
+		group.Count++
+		group.ExecutionIDs = append(group.ExecutionIDs, analysis.ID)
+		if analysis.StartedAt != nil {
+			if group.FirstSeen.IsZero() || analysis.StartedAt.Before(group.FirstSeen) {
+				group.FirstSeen = *analysis.StartedAt
+			}
+			if analysis.StartedAt.After(group.LastSeen) {
+				group.LastSeen = *analysis.StartedAt
+			}
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Count != ordered[j].Count {
+			return ordered[i].Count > ordered[j].Count
+		}
+		return ordered[i].LastSeen.After(ordered[j].LastSeen)
+	})
+	return ordered
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	longNumber       = regexp.MustCompile(`\b\d{5,}\b`)
+	whitespace       = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeErrorMessage masks the parts of an error message that differ
+// between occurrences of the same error
+func NormalizeErrorMessage(message string) string {
+	message = uuidPattern.ReplaceAllString(message, "<uuid>")
+	message = timestampPattern.ReplaceAllString(message, "<time>")
+	message = longNumber.ReplaceAllString(message, "<n>")
+	message = strings.TrimSpace(whitespace.ReplaceAllString(message, " "))
+	if len(message) > 300 {
+		message = message[:300] + "..."
+	}
+	return message
+}
+
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func intValue(v interface{}) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+func parseTime(v interface{}) *time.Time {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// RecentFailures fetches and analyzes the most recent failed executions,
+// optionally of a single workflow
+func (c *N8NClient) RecentFailures(workflowID string, limit int) ([]*ExecutionAnalysis, error) {
+	params := map[string]string{
+		"status": "error",
+		"limit":  fmt.Sprintf("%d", limit),
+	}
+	if workflowID != "" {
+		params["workflowId"] = workflowID
+	}
+	executions, err := c.ListExecutions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var analyses []*ExecutionAnalysis
+	for _, execution := range executions {
+		id := stringValue(execution["id"])
+		full, err := c.GetExecutionData(id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching execution %s: %w", id, err)
+		}
+		analysis, err := AnalyzeExecution(full, AnalysisOptions{})
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, nil
+}
@@ -0,0 +1,290 @@
+package n8n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadExecution reads a recorded execution from testdata/executions
+func loadExecution(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "executions", name))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	var execution map[string]interface{}
+	if err := json.Unmarshal(raw, &execution); err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return execution
+}
+
+func analyzeFixture(t *testing.T, name string) *ExecutionAnalysis {
+	t.Helper()
+	analysis, err := AnalyzeExecution(loadExecution(t, name), AnalysisOptions{MaxItems: 10})
+	if err != nil {
+		t.Fatalf("analyzing %s: %v", name, err)
+	}
+	return analysis
+}
+
+func TestAnalyzeExecution(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		mutate  func(execution map[string]interface{})
+		wantErr string
+
+		status     string
+		runs       []string // node/runIndex/status in start order
+		inputs     []int
+		outputs    [][]int
+		failedNode string
+		failedType string
+		message    string
+		httpCode   string
+		params     bool
+		execError  string
+	}{
+		{
+			name:       "failed node",
+			fixture:    "failed-node.json",
+			status:     "error",
+			runs:       []string{"Schedule/0/success", "Fetch Orders/0/error"},
+			inputs:     []int{0, 1},
+			outputs:    [][]int{{1}, {}},
+			failedNode: "Fetch Orders",
+			failedType: "n8n-nodes-base.httpRequest",
+			message:    "Request failed with status code 503",
+			httpCode:   "503",
+			params:     true,
+			execError:  "Request failed with status code 503",
+		},
+		{
+			name:    "pinned data",
+			fixture: "pinned-data.json",
+			status:  "success",
+			// The pinned node has no executionStatus, its items come from the pin
+			runs:    []string{"Webhook/0/success", "Split/0/success", "Enrich/0/success", "Enrich/1/success"},
+			inputs:  []int{0, 3, 2, 1},
+			outputs: [][]int{{3}, {2, 1}, {2}, {1}},
+		},
+		{
+			name:      "missing run data",
+			fixture:   "missing-run-data.json",
+			status:    "error",
+			execError: "Credentials for 'Slack account' are not set",
+		},
+		{
+			name:    "not fetched with data",
+			fixture: "missing-run-data.json",
+			mutate: func(execution map[string]interface{}) {
+				delete(execution, "data")
+			},
+			wantErr: "execution 103 has no run data",
+		},
+		{
+			name:       "nested error",
+			fixture:    "nested-error.json",
+			status:     "error",
+			runs:       []string{"Cron/0/success", "Build Report/0/error"},
+			inputs:     []int{0, 1},
+			outputs:    [][]int{{1}, {}},
+			failedNode: "Build Report",
+			failedType: "n8n-nodes-base.code",
+			message:    "Cannot read properties of undefined (reading 'toFixed')",
+			params:     true,
+			execError:  "Cannot read properties of undefined (reading 'toFixed')",
+		},
+		{
+			name:    "nested error without node",
+			fixture: "nested-error.json",
+			mutate: func(execution map[string]interface{}) {
+				resultData := execution["data"].(map[string]interface{})["resultData"].(map[string]interface{})
+				delete(resultData["error"].(map[string]interface{}), "node")
+			},
+			status:     "error",
+			runs:       []string{"Cron/0/success", "Build Report/0/error"},
+			inputs:     []int{0, 1},
+			outputs:    [][]int{{1}, {}},
+			failedNode: "Build Report",
+			failedType: "n8n-nodes-base.code",
+			message:    "Cannot read properties of undefined (reading 'toFixed')",
+			params:     true,
+			execError:  "Cannot read properties of undefined (reading 'toFixed')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execution := loadExecution(t, tt.fixture)
+			if tt.mutate != nil {
+				tt.mutate(execution)
+			}
+			analysis, err := AnalyzeExecution(execution, AnalysisOptions{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if analysis.Status != tt.status {
+				t.Errorf("status = %q, want %q", analysis.Status, tt.status)
+			}
+
+			var runs []string
+			var inputs []int
+			var outputs [][]int
+			for _, run := range analysis.Runs {
+				runs = append(runs, fmt.Sprintf("%s/%d/%s", run.Node, run.RunIndex, run.Status))
+				inputs = append(inputs, run.InputItems)
+				outputs = append(outputs, run.OutputItems)
+			}
+			if !reflect.DeepEqual(runs, tt.runs) {
+				t.Errorf("runs = %v, want %v", runs, tt.runs)
+			}
+			if !reflect.DeepEqual(inputs, tt.inputs) {
+				t.Errorf("input items = %v, want %v", inputs, tt.inputs)
+			}
+			if !reflect.DeepEqual(outputs, tt.outputs) {
+				t.Errorf("output items = %v, want %v", outputs, tt.outputs)
+			}
+
+			if tt.failedNode == "" {
+				if analysis.FailedNode != nil {
+					t.Errorf("expected no failed node, got %s", analysis.FailedNode.Node)
+				}
+			} else {
+				failed := analysis.FailedNode
+				if failed == nil {
+					t.Fatalf("expected failed node %s, got none", tt.failedNode)
+				}
+				if failed.Node != tt.failedNode || failed.Type != tt.failedType {
+					t.Errorf("failed node = %s (%s), want %s (%s)", failed.Node, failed.Type, tt.failedNode, tt.failedType)
+				}
+				if failed.Error == nil || !strings.HasPrefix(failed.Error.Message, tt.message) {
+					t.Errorf("failed node error = %+v, want message %q", failed.Error, tt.message)
+				} else if failed.Error.HTTPCode != tt.httpCode {
+					t.Errorf("http code = %q, want %q", failed.Error.HTTPCode, tt.httpCode)
+				}
+				if (failed.Parameters != nil) != tt.params {
+					t.Errorf("failed node parameters = %v, want present: %v", failed.Parameters, tt.params)
+				}
+			}
+
+			if tt.execError == "" {
+				if analysis.Error != nil {
+					t.Errorf("expected no execution error, got %q", analysis.Error.Message)
+				}
+			} else if analysis.Error == nil || !strings.HasPrefix(analysis.Error.Message, tt.execError) {
+				t.Errorf("execution error = %+v, want %q", analysis.Error, tt.execError)
+			}
+		})
+	}
+}
+
+func TestAnalyzeExecutionKeepsItems(t *testing.T) {
+	analysis, err := AnalyzeExecution(loadExecution(t, "pinned-data.json"), AnalysisOptions{MaxItems: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if analysis.DurationMs != 250 || analysis.WorkflowName != "Enrich Leads" {
+		t.Errorf("analysis = %s %dms, want Enrich Leads 250ms", analysis.WorkflowName, analysis.DurationMs)
+	}
+
+	// The second Enrich run received the false output of Split
+	enrich := analysis.Runs[3]
+	if enrich.Node != "Enrich" || enrich.RunIndex != 1 {
+		t.Fatalf("unexpected run order, got %s/%d", enrich.Node, enrich.RunIndex)
+	}
+	want := []interface{}{map[string]interface{}{"email": "b@example.org"}}
+	if !reflect.DeepEqual(enrich.Input, want) {
+		t.Errorf("input = %v, want %v", enrich.Input, want)
+	}
+
+	// Items are cut to MaxItems and only the JSON payload is kept
+	webhook := analysis.Runs[0]
+	if len(webhook.Output[0]) != 1 || webhook.OutputItems[0] != 3 {
+		t.Errorf("webhook output = %v (%v items), want 1 of 3 items", webhook.Output, webhook.OutputItems)
+	}
+}
+
+func TestAggregateFailures(t *testing.T) {
+	first := analyzeFixture(t, "nested-error.json")
+
+	// The same error a day later, in another report
+	second := loadExecution(t, "nested-error.json")
+	second["id"] = "105"
+	second["startedAt"] = "2024-05-04T09:30:00.000Z"
+	resultData := second["data"].(map[string]interface{})["resultData"].(map[string]interface{})
+	resultData["error"].(map[string]interface{})["message"] = "Cannot read properties of undefined (reading 'toFixed') [line 1, item 0]\n  in report 0b7c2a91-55e4-4f0e-8d3a-6c1e2f3a4b5c at 2024-05-04T09:30:01Z"
+	secondAnalysis, err := AnalyzeExecution(second, AnalysisOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	analyses := []*ExecutionAnalysis{
+		analyzeFixture(t, "failed-node.json"),
+		first,
+		analyzeFixture(t, "pinned-data.json"),
+		analyzeFixture(t, "missing-run-data.json"),
+		secondAnalysis,
+	}
+	groups := AggregateFailures(analyses)
+
+	type group struct {
+		workflow, node, message string
+		count                   int
+		ids                     []string
+	}
+	var got []group
+	for _, g := range groups {
+		got = append(got, group{g.WorkflowID, g.Node, g.Message, g.Count, g.ExecutionIDs})
+	}
+	want := []group{
+		{"wf4", "Build Report", "Cannot read properties of undefined (reading 'toFixed') [line 1, item 0] in report <uuid> at <time>", 2, []string{"104", "105"}},
+		// Same count, sorted by the most recent one first
+		{"wf3", "", "Credentials for 'Slack account' are not set", 1, []string{"103"}},
+		{"wf1", "Fetch Orders", "Request failed with status code 503", 1, []string{"101"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("groups:\n got %+v\nwant %+v", got, want)
+	}
+
+	report := groups[0]
+	if report.NodeType != "n8n-nodes-base.code" || report.WorkflowName != "Nightly Report" {
+		t.Errorf("group node type %q, workflow %q", report.NodeType, report.WorkflowName)
+	}
+	if !report.FirstSeen.Equal(time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)) || !report.LastSeen.Equal(time.Date(2024, 5, 4, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("group seen %s to %s", report.FirstSeen, report.LastSeen)
+	}
+}
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Request failed with status code 503", "Request failed with status code 503"},
+		{"Record 8f14e45f-ceea-467f-a0e6-1d2b3c4d5e6f not found", "Record <uuid> not found"},
+		{"Timed out at 2024-05-01 10:00:00.123+02:00", "Timed out at <time>"},
+		{"Order 1234567 rejected, retry 3", "Order <n> rejected, retry 3"},
+		{"line one\n\n   line two\t", "line one line two"},
+		{strings.Repeat("x", 310), strings.Repeat("x", 300) + "..."},
+	}
+	for _, tt := range tests {
+		if got := NormalizeErrorMessage(tt.message); got != tt.want {
+			t.Errorf("NormalizeErrorMessage(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
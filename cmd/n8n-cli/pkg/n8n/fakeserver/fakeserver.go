@@ -0,0 +1,410 @@
+// Package fakeserver is an in-memory stand-in for the n8n public REST API,
+// used to try out n8n-cli (pull, push, diff, ...) without an n8n instance.
+// It implements the workflow, execution and node endpoints with the same
+// request validation as n8n, e.g. unknown workflow fields are rejected.
+package fakeserver
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultNodeTypes are the node descriptions served by the nodes endpoint
+// unless others are configured
+var DefaultNodeTypes = []map[string]interface{}{
+	{"name": "n8n-nodes-base.manualTrigger", "displayName": "Manual Trigger"},
+	{"name": "n8n-nodes-base.scheduleTrigger", "displayName": "Schedule Trigger"},
+	{"name": "n8n-nodes-base.webhook", "displayName": "Webhook"},
+	{"name": "n8n-nodes-base.respondToWebhook", "displayName": "Respond to Webhook"},
+	{"name": "n8n-nodes-base.httpRequest", "displayName": "HTTP Request"},
+	{"name": "n8n-nodes-base.set", "displayName": "Edit Fields (Set)"},
+	{"name": "n8n-nodes-base.if", "displayName": "If"},
+	{"name": "n8n-nodes-base.code", "displayName": "Code"},
+	{"name": "n8n-nodes-base.stickyNote", "displayName": "Sticky Note"},
+	{"name": "n8n-nodes-base.slack", "displayName": "Slack", "credentials": []interface{}{
+		map[string]interface{}{"name": "slackApi", "required": true},
+	}},
+	{"name": "n8n-nodes-base.postgres", "displayName": "Postgres", "credentials": []interface{}{
+		map[string]interface{}{"name": "postgres", "required": true},
+	}},
+}
+
+// Fields accepted in workflow create and update requests
+var writableWorkflowFields = map[string]bool{
+	"name":        true,
+	"nodes":       true,
+	"connections": true,
+	"settings":    true,
+	"staticData":  true,
+}
+
+// Server is a fake n8n API
+type Server struct {
+	APIKey    string
+	NodeTypes []map[string]interface{}
+
+	mu         sync.Mutex
+	workflows  map[string]map[string]interface{}
+	executions map[string]map[string]interface{}
+}
+
+// New creates an empty fake server
+func New(apiKey string) *Server {
+	return &Server{
+		APIKey:     apiKey,
+		NodeTypes:  DefaultNodeTypes,
+		workflows:  map[string]map[string]interface{}{},
+		executions: map[string]map[string]interface{}{},
+	}
+}
+
+// LoadWorkflows adds the workflows stored as JSON files in a directory, as
+// written by get-workflow --save-to-file. Workflows keep their ID if they
+// have one.
+func (s *Server) LoadWorkflows(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var workflow map[string]interface{}
+		if err := json.Unmarshal(data, &workflow); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		id, _ := workflow["id"].(string)
+		s.store(id, workflow)
+	}
+	return nil
+}
+
+// LoadExecutions adds the executions stored as JSON files in a directory,
+// one execution per file in the format returned by get-execution with
+// includeData. The workflow of an execution is added too if it is missing,
+// taken from the execution's workflowData.
+func (s *Server) LoadExecutions(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var execution map[string]interface{}
+		if err := json.Unmarshal(data, &execution); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		var id string
+		switch v := execution["id"].(type) {
+		case string:
+			id = v
+		case float64:
+			id = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			id = strconv.Itoa(len(s.executions) + 1)
+		}
+		execution["id"] = id
+
+		workflowID, _ := execution["workflowId"].(string)
+		s.mu.Lock()
+		s.executions[id] = execution
+		_, hasWorkflow := s.workflows[workflowID]
+		s.mu.Unlock()
+
+		if workflowData, ok := execution["workflowData"].(map[string]interface{}); ok && workflowID != "" && !hasWorkflow {
+			workflow := map[string]interface{}{}
+			for k, v := range workflowData {
+				workflow[k] = v
+			}
+			s.store(workflowID, workflow)
+		}
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving the API under /api/v1
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/workflows", s.listWorkflows)
+	mux.HandleFunc("POST /api/v1/workflows", s.createWorkflow)
+	mux.HandleFunc("GET /api/v1/workflows/{id}", s.getWorkflow)
+	mux.HandleFunc("PUT /api/v1/workflows/{id}", s.updateWorkflow)
+	mux.HandleFunc("DELETE /api/v1/workflows/{id}", s.deleteWorkflow)
+	mux.HandleFunc("GET /api/v1/executions", s.listExecutions)
+	mux.HandleFunc("GET /api/v1/executions/{id}", s.getExecution)
+	mux.HandleFunc("GET /api/v1/nodes", s.listNodes)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Debug().Str("method", r.Method).Str("path", r.URL.Path).Msg("fake n8n request")
+		if r.Header.Get("X-N8N-API-KEY") != s.APIKey {
+			writeMessage(w, http.StatusUnauthorized, "'X-N8N-API-KEY' header required")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listWorkflows(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 250 {
+		limit = v
+	}
+	offset := 0
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			offset, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	}
+	onlyActive := r.URL.Query().Get("active") == "true"
+
+	s.mu.Lock()
+	var workflows []map[string]interface{}
+	for _, workflow := range s.workflows {
+		if onlyActive && workflow["active"] != true {
+			continue
+		}
+		workflows = append(workflows, workflow)
+	}
+	s.mu.Unlock()
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i]["id"].(string) < workflows[j]["id"].(string)
+	})
+
+	result := map[string]interface{}{"data": []interface{}{}, "nextCursor": nil}
+	if offset < len(workflows) {
+		end := offset + limit
+		if end < len(workflows) {
+			result["nextCursor"] = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+		} else {
+			end = len(workflows)
+		}
+		result["data"] = workflows[offset:end]
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) createWorkflow(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := decodeWorkflow(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.store("", workflow))
+}
+
+func (s *Server) getWorkflow(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	workflow, ok := s.workflows[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, workflow)
+}
+
+func (s *Server) updateWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, exists := s.workflows[id]
+	s.mu.Unlock()
+	if !exists {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	workflow, ok := decodeWorkflow(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.store(id, workflow))
+}
+
+func (s *Server) deleteWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	workflow, ok := s.workflows[id]
+	delete(s.workflows, id)
+	s.mu.Unlock()
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, workflow)
+}
+
+func (s *Server) listExecutions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 100
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v > 0 && v <= 250 {
+		limit = v
+	}
+	includeData := query.Get("includeData") == "true"
+
+	s.mu.Lock()
+	var executions []map[string]interface{}
+	for _, execution := range s.executions {
+		if workflowID := query.Get("workflowId"); workflowID != "" && execution["workflowId"] != workflowID {
+			continue
+		}
+		if status := query.Get("status"); status != "" && execution["status"] != status {
+			continue
+		}
+		executions = append(executions, executionView(execution, includeData))
+	}
+	s.mu.Unlock()
+
+	// Most recent first
+	sort.Slice(executions, func(i, j int) bool {
+		a, _ := executions[i]["startedAt"].(string)
+		b, _ := executions[j]["startedAt"].(string)
+		return a > b
+	})
+	if len(executions) > limit {
+		executions = executions[:limit]
+	}
+	if executions == nil {
+		executions = []map[string]interface{}{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": executions, "nextCursor": nil})
+}
+
+func (s *Server) getExecution(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	execution, ok := s.executions[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, executionView(execution, r.URL.Query().Get("includeData") == "true"))
+}
+
+// executionView leaves out the run data unless it was asked for, like n8n
+func executionView(execution map[string]interface{}, includeData bool) map[string]interface{} {
+	if includeData {
+		return execution
+	}
+	view := map[string]interface{}{}
+	for k, v := range execution {
+		if k != "data" && k != "workflowData" {
+			view[k] = v
+		}
+	}
+	return view
+}
+
+func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": s.NodeTypes})
+}
+
+// store saves a workflow, filling in the fields n8n manages. An empty id
+// creates a new workflow.
+func (s *Server) store(id string, workflow map[string]interface{}) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	previous, exists := s.workflows[id]
+	if id == "" {
+		id = randomHex(8)
+	}
+
+	stored := map[string]interface{}{}
+	for k, v := range workflow {
+		stored[k] = v
+	}
+	stored["id"] = id
+	stored["updatedAt"] = now
+	stored["versionId"] = randomHex(16)
+	if exists {
+		stored["createdAt"] = previous["createdAt"]
+		stored["active"] = previous["active"]
+		stored["tags"] = previous["tags"]
+	} else {
+		if _, ok := stored["createdAt"]; !ok {
+			stored["createdAt"] = now
+		}
+		if _, ok := stored["active"]; !ok {
+			stored["active"] = false
+		}
+		if _, ok := stored["tags"]; !ok {
+			stored["tags"] = []interface{}{}
+		}
+	}
+
+	// Nodes get an ID like in the editor
+	if nodes, ok := stored["nodes"].([]interface{}); ok {
+		for _, rawNode := range nodes {
+			if node, ok := rawNode.(map[string]interface{}); ok {
+				if _, ok := node["id"]; !ok {
+					node["id"] = randomHex(16)
+				}
+			}
+		}
+	}
+
+	s.workflows[id] = stored
+	return stored
+}
+
+// decodeWorkflow reads a workflow request body and validates it like the
+// n8n OpenAPI schema does
+func decodeWorkflow(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var workflow map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&workflow); err != nil {
+		writeMessage(w, http.StatusBadRequest, "request body must be a JSON object")
+		return nil, false
+	}
+	for field := range workflow {
+		if !writableWorkflowFields[field] {
+			writeMessage(w, http.StatusBadRequest, "request/body must NOT have additional properties")
+			return nil, false
+		}
+	}
+	for _, field := range []string{"name", "nodes", "connections", "settings"} {
+		if _, ok := workflow[field]; !ok {
+			writeMessage(w, http.StatusBadRequest, fmt.Sprintf("request/body must have required property '%s'", field))
+			return nil, false
+		}
+	}
+	return workflow, true
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package n8n
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sync actions
+const (
+	SyncCreate     = "create"
+	SyncUpdate     = "update"
+	SyncUnchanged  = "unchanged"
+	SyncRemoteOnly = "remote-only"
+)
+
+// SyncAction pairs a workflow file with its server counterpart and says
+// what push would do with it
+type SyncAction struct {
+	Action  string
+	File    *WorkflowFile          // nil for remote-only workflows
+	Remote  map[string]interface{} // nil for workflows that don't exist on the server yet
+	Changes []WorkflowChange
+}
+
+// ID returns the server ID of the workflow, empty if it will be created
+func (a *SyncAction) ID() string {
+	if a.Remote != nil {
+		return WorkflowID(a.Remote)
+	}
+	return ""
+}
+
+// Name returns the workflow name, preferring the local one
+func (a *SyncAction) Name() string {
+	if a.File != nil {
+		return a.File.Name()
+	}
+	name, _ := a.Remote["name"].(string)
+	return name
+}
+
+// PlanSync matches workflow files to server workflows by ID and diffs each
+// pair. Files without an ID, or with an ID the server doesn't know, are
+// created. Server workflows without a file are reported as remote-only.
+func PlanSync(files []*WorkflowFile, remote []map[string]interface{}, opts DiffOptions) ([]*SyncAction, error) {
+	remoteByID := map[string]map[string]interface{}{}
+	for _, workflow := range remote {
+		remoteByID[WorkflowID(workflow)] = workflow
+	}
+
+	var actions []*SyncAction
+	claimed := map[string]string{}
+	for _, file := range files {
+		id := file.ID()
+		if id != "" {
+			if other, ok := claimed[id]; ok {
+				return nil, fmt.Errorf("%s and %s both contain workflow %s", other, file.Path, id)
+			}
+			claimed[id] = file.Path
+		}
+
+		remoteWorkflow, ok := remoteByID[id]
+		if id == "" || !ok {
+			actions = append(actions, &SyncAction{
+				Action:  SyncCreate,
+				File:    file,
+				Changes: []WorkflowChange{{Kind: "workflow", Op: ChangeAdded, Target: file.Name()}},
+			})
+			continue
+		}
+
+		action := &SyncAction{Action: SyncUnchanged, File: file, Remote: remoteWorkflow}
+		action.Changes = DiffWorkflows(file.Workflow, remoteWorkflow, opts)
+		if len(action.Changes) > 0 {
+			action.Action = SyncUpdate
+		}
+		actions = append(actions, action)
+	}
+
+	var remoteOnly []*SyncAction
+	for id, workflow := range remoteByID {
+		if _, ok := claimed[id]; ok {
+			continue
+		}
+		name, _ := workflow["name"].(string)
+		remoteOnly = append(remoteOnly, &SyncAction{
+			Action:  SyncRemoteOnly,
+			Remote:  workflow,
+			Changes: []WorkflowChange{{Kind: "workflow", Op: ChangeRemoved, Target: name}},
+		})
+	}
+	sort.Slice(remoteOnly, func(i, j int) bool { return remoteOnly[i].ID() < remoteOnly[j].ID() })
+
+	return append(actions, remoteOnly...), nil
+}
@@ -0,0 +1,82 @@
+package n8n
+
+import (
+	"strings"
+	"testing"
+)
+
+func syncWorkflow(id, name, value string) map[string]interface{} {
+	workflow := map[string]interface{}{
+		"name": name,
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "Set", "type": "n8n-nodes-base.set", "parameters": map[string]interface{}{"value": value}},
+		},
+		"connections": map[string]interface{}{},
+		"settings":    map[string]interface{}{},
+	}
+	if id != "" {
+		workflow["id"] = id
+	}
+	return workflow
+}
+
+func TestPlanSync(t *testing.T) {
+	remote := []map[string]interface{}{
+		syncWorkflow("1", "Same", "a"),
+		syncWorkflow("2", "Edited", "a"),
+		syncWorkflow("3", "Server Only", "a"),
+	}
+	// The server adds fields that aren't kept in files
+	remote[0]["updatedAt"] = "2024-01-01T00:00:00Z"
+	remote[0]["active"] = true
+
+	files := []*WorkflowFile{
+		{Path: "same.yaml", Workflow: syncWorkflow("1", "Same", "a")},
+		{Path: "edited.yaml", Workflow: syncWorkflow("2", "Edited", "b")},
+		{Path: "new.yaml", Workflow: syncWorkflow("", "New", "a")},
+		{Path: "foreign.yaml", Workflow: syncWorkflow("99", "Foreign", "a")},
+	}
+
+	actions, err := PlanSync(files, remote, DiffOptions{})
+	if err != nil {
+		t.Fatalf("PlanSync: %v", err)
+	}
+
+	want := []struct {
+		name    string
+		action  string
+		id      string
+		changes int
+	}{
+		{"Same", SyncUnchanged, "1", 0},
+		{"Edited", SyncUpdate, "2", 1},
+		{"New", SyncCreate, "", 1},
+		// An ID the server doesn't know is created and gets a new ID
+		{"Foreign", SyncCreate, "", 1},
+		{"Server Only", SyncRemoteOnly, "3", 1},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("expected %d actions, got %d", len(want), len(actions))
+	}
+	for i, w := range want {
+		a := actions[i]
+		if a.Name() != w.name || a.Action != w.action || a.ID() != w.id || len(a.Changes) != w.changes {
+			t.Errorf("action %d: got %s %s id=%q changes=%d, want %s %s id=%q changes=%d",
+				i, a.Name(), a.Action, a.ID(), len(a.Changes), w.name, w.action, w.id, w.changes)
+		}
+	}
+	if c := actions[1].Changes[0]; c.Kind != "node" || c.Detail != "parameters.value" {
+		t.Errorf("unexpected change for the edited workflow: %+v", c)
+	}
+}
+
+func TestPlanSyncRejectsDuplicateIDs(t *testing.T) {
+	files := []*WorkflowFile{
+		{Path: "a.yaml", Workflow: syncWorkflow("1", "A", "a")},
+		{Path: "b.yaml", Workflow: syncWorkflow("1", "B", "a")},
+	}
+	_, err := PlanSync(files, nil, DiffOptions{})
+	if err == nil || !strings.Contains(err.Error(), "a.yaml and b.yaml both contain workflow 1") {
+		t.Errorf("expected a duplicate ID error, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package n8n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a problem found in a workflow
+type ValidationIssue struct {
+	Severity string
+	Node     string
+	Check    string // structure, connection, node-type or credential
+	Message  string
+}
+
+// ValidateWorkflow checks a workflow without talking to a server. Node types
+// and credentials are checked against the catalog written by pull; with a
+// nil catalog only the structure and connections are checked.
+func ValidateWorkflow(workflow map[string]interface{}, catalog *Catalog) []ValidationIssue {
+	workflow = toJSONValue(workflow)
+
+	var issues []ValidationIssue
+	add := func(severity, node, check, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: severity, Node: node, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if name, _ := workflow["name"].(string); strings.TrimSpace(name) == "" {
+		add(SeverityError, "", "structure", "workflow has no name")
+	}
+
+	var nodeTypes map[string]CatalogNodeType
+	var credentialsByType map[string][]CatalogCredential
+	if catalog != nil {
+		nodeTypes = map[string]CatalogNodeType{}
+		for _, nodeType := range catalog.NodeTypes {
+			nodeTypes[nodeType.Name] = nodeType
+		}
+		credentialsByType = map[string][]CatalogCredential{}
+		for _, credential := range catalog.Credentials {
+			credentialsByType[credential.Type] = append(credentialsByType[credential.Type], credential)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, node := range workflowNodes(workflow) {
+		name, _ := node["name"].(string)
+		if name == "" {
+			add(SeverityError, fmt.Sprintf("#%d", i), "structure", "node has no name")
+			continue
+		}
+		if names[name] {
+			add(SeverityError, name, "structure", "duplicate node name")
+		}
+		names[name] = true
+
+		nodeType, _ := node["type"].(string)
+		if nodeType == "" {
+			add(SeverityError, name, "structure", "node has no type")
+			continue
+		}
+
+		refs := nodeCredentials(node)
+		for _, ref := range refs {
+			if ref.ID == "" && ref.Name == "" {
+				add(SeverityError, name, "credential", "%s credential has neither id nor name", ref.Type)
+			}
+		}
+
+		if catalog == nil {
+			continue
+		}
+
+		// Skip the type checks when the catalog has no node types, e.g.
+		// because the server doesn't expose them
+		if len(nodeTypes) > 0 {
+			known, ok := nodeTypes[nodeType]
+			if !ok {
+				add(SeverityError, name, "node-type", "unknown node type %s", nodeType)
+			}
+			for _, required := range known.Credentials {
+				if !hasCredentialType(refs, required) {
+					add(SeverityError, name, "credential", "missing required %s credential", required)
+				}
+			}
+		}
+
+		for _, ref := range refs {
+			if ref.ID == "" && ref.Name == "" {
+				continue
+			}
+			if !credentialExists(credentialsByType[ref.Type], ref) {
+				add(SeverityError, name, "credential", "%s credential %s not found in catalog", ref.Type, describeCredential(ref))
+			}
+		}
+	}
+
+	for _, edge := range workflowEdges(workflow) {
+		if !names[edge.Source] {
+			add(SeverityError, edge.Source, "connection", "connection %s starts at a node that doesn't exist", edge)
+		}
+		if edge.Target == "" {
+			add(SeverityError, edge.Source, "connection", "connection %s has no target node", edge)
+		} else if !names[edge.Target] {
+			add(SeverityError, edge.Source, "connection", "connection %s points to a node that doesn't exist", edge)
+		}
+	}
+
+	if catalog == nil {
+		add(SeverityWarning, "", "node-type", "no %s, node types and credentials were not checked", CatalogFileName)
+	} else if len(nodeTypes) == 0 {
+		add(SeverityWarning, "", "node-type", "%s lists no node types, node types were not checked", CatalogFileName)
+	}
+
+	return issues
+}
+
+func hasCredentialType(refs []credentialRef, credentialType string) bool {
+	for _, ref := range refs {
+		if ref.Type == credentialType {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialExists matches by ID when both sides have one, by name otherwise
+func credentialExists(known []CatalogCredential, ref credentialRef) bool {
+	for _, credential := range known {
+		if ref.ID != "" && credential.ID != "" {
+			if ref.ID == credential.ID {
+				return true
+			}
+			continue
+		}
+		if ref.Name == credential.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func describeCredential(ref credentialRef) string {
+	switch {
+	case ref.ID != "" && ref.Name != "":
+		return fmt.Sprintf("%q (id %s)", ref.Name, ref.ID)
+	case ref.ID != "":
+		return "id " + ref.ID
+	default:
+		return fmt.Sprintf("%q", ref.Name)
+	}
+}
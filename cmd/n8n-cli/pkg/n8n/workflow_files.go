@@ -0,0 +1,378 @@
+package n8n
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CatalogFileName is the name of the catalog file written next to the
+// workflow files by pull. It is not a workflow and is skipped when loading.
+const CatalogFileName = "n8n-catalog.yaml"
+
+// WorkflowFile is a workflow stored in a directory
+type WorkflowFile struct {
+	Path     string
+	Workflow map[string]interface{}
+}
+
+// ID returns the server ID stored in the file, empty for workflows that
+// haven't been pushed yet
+func (f *WorkflowFile) ID() string {
+	return WorkflowID(f.Workflow)
+}
+
+// Name returns the workflow name
+func (f *WorkflowFile) Name() string {
+	name, _ := f.Workflow["name"].(string)
+	return name
+}
+
+// WorkflowID returns the ID of a workflow as a string. n8n uses string IDs,
+// older versions used numbers.
+func WorkflowID(workflow map[string]interface{}) string {
+	switch id := workflow["id"].(type) {
+	case string:
+		return id
+	case float64:
+		return fmt.Sprintf("%.0f", id)
+	case int:
+		return fmt.Sprintf("%d", id)
+	default:
+		return ""
+	}
+}
+
+// NormalizeWorkflow reduces a workflow to the fields that are kept under
+// version control: id, name, nodes, connections and settings. Server managed
+// fields (timestamps, versionId, active, tags, staticData, ...) are dropped
+// and nodes are sorted by name, so that pulling an unchanged workflow
+// produces an identical file.
+func NormalizeWorkflow(workflow map[string]interface{}) map[string]interface{} {
+	normalized := map[string]interface{}{}
+
+	if id := WorkflowID(workflow); id != "" {
+		normalized["id"] = id
+	}
+	if name, ok := workflow["name"]; ok {
+		normalized["name"] = name
+	}
+
+	var nodes []interface{}
+	if rawNodes, ok := workflow["nodes"].([]interface{}); ok {
+		for _, rawNode := range rawNodes {
+			node, ok := rawNode.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// The node ID is assigned by the server and carries no meaning,
+			// connections reference nodes by name
+			cleaned := map[string]interface{}{}
+			for k, v := range node {
+				if k == "id" {
+					continue
+				}
+				cleaned[k] = v
+			}
+			nodes = append(nodes, cleaned)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodeName(nodes[i]) < nodeName(nodes[j])
+	})
+	if nodes == nil {
+		nodes = []interface{}{}
+	}
+	normalized["nodes"] = nodes
+
+	connections, ok := workflow["connections"].(map[string]interface{})
+	if !ok {
+		connections = map[string]interface{}{}
+	}
+	normalized["connections"] = connections
+
+	settings, ok := workflow["settings"].(map[string]interface{})
+	if !ok {
+		settings = map[string]interface{}{}
+	}
+	normalized["settings"] = settings
+
+	return normalized
+}
+
+// WorkflowFileName returns the file name used for a workflow, a slug of its
+// name followed by the extension for the format
+func WorkflowFileName(workflow map[string]interface{}, format string) string {
+	name, _ := workflow["name"].(string)
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "workflow-" + WorkflowID(workflow)
+	}
+	return slug + "." + format
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// WriteWorkflowFile writes a workflow as YAML or JSON, depending on the file
+// extension. The file is only written when its content changes, the
+// returned bool reports whether it did.
+func WriteWorkflowFile(path string, workflow map[string]interface{}) (bool, error) {
+	data, err := encodeByExtension(path, workflow)
+	if err != nil {
+		return false, err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, data, 0644)
+}
+
+// ReadWorkflowFile reads a workflow from a YAML or JSON file. Values are
+// converted to the types encoding/json produces, so workflows read from
+// files compare equal to workflows received from the API.
+func ReadWorkflowFile(path string) (*WorkflowFile, error) {
+	var workflow map[string]interface{}
+	if err := decodeByExtension(path, &workflow); err != nil {
+		return nil, err
+	}
+	if workflow == nil {
+		return nil, fmt.Errorf("%s: empty workflow file", path)
+	}
+	return &WorkflowFile{Path: path, Workflow: workflow}, nil
+}
+
+// LoadWorkflowDir reads all workflow files (.yaml, .yml, .json) in a directory
+func LoadWorkflowDir(dir string) ([]*WorkflowFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*WorkflowFile
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == CatalogFileName {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		file, err := ReadWorkflowFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// Catalog lists the node types and credentials known to exist on the
+// server. pull writes it so that validate can check workflows offline.
+type Catalog struct {
+	NodeTypes   []CatalogNodeType   `yaml:"nodeTypes" json:"nodeTypes"`
+	Credentials []CatalogCredential `yaml:"credentials" json:"credentials"`
+}
+
+// CatalogNodeType is a node type and the credential types it requires
+type CatalogNodeType struct {
+	Name        string   `yaml:"name" json:"name"`
+	Credentials []string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+// CatalogCredential is a credential referenced by a node
+type CatalogCredential struct {
+	ID   string `yaml:"id,omitempty" json:"id,omitempty"`
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+}
+
+// LoadCatalog reads the catalog of a workflow directory. It returns nil
+// without an error when the directory has no catalog.
+func LoadCatalog(dir string) (*Catalog, error) {
+	path := filepath.Join(dir, CatalogFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	catalog := &Catalog{}
+	if err := decodeByExtension(path, catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// WriteCatalog writes the catalog of a workflow directory
+func WriteCatalog(dir string, catalog *Catalog) error {
+	sort.Slice(catalog.NodeTypes, func(i, j int) bool {
+		return catalog.NodeTypes[i].Name < catalog.NodeTypes[j].Name
+	})
+	sort.Slice(catalog.Credentials, func(i, j int) bool {
+		a, b := catalog.Credentials[i], catalog.Credentials[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	data, err := marshalYAML(catalog)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, CatalogFileName), data, 0644)
+}
+
+// NodeTypesFromDescriptions builds catalog node types from the node
+// descriptions returned by the nodes endpoint
+func NodeTypesFromDescriptions(descriptions []map[string]interface{}) []CatalogNodeType {
+	var nodeTypes []CatalogNodeType
+	for _, description := range descriptions {
+		name, _ := description["name"].(string)
+		if name == "" {
+			continue
+		}
+		nodeType := CatalogNodeType{Name: name}
+		credentials, _ := description["credentials"].([]interface{})
+		for _, rawCredential := range credentials {
+			credential, ok := rawCredential.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if required, _ := credential["required"].(bool); !required {
+				continue
+			}
+			if credentialType, _ := credential["name"].(string); credentialType != "" {
+				nodeType.Credentials = append(nodeType.Credentials, credentialType)
+			}
+		}
+		nodeTypes = append(nodeTypes, nodeType)
+	}
+	return nodeTypes
+}
+
+// CredentialsFromWorkflows collects the credentials referenced by the nodes
+// of the workflows
+func CredentialsFromWorkflows(workflows []map[string]interface{}) []CatalogCredential {
+	seen := map[CatalogCredential]bool{}
+	var credentials []CatalogCredential
+	for _, workflow := range workflows {
+		for _, node := range workflowNodes(workflow) {
+			for _, ref := range nodeCredentials(node) {
+				credential := CatalogCredential{ID: ref.ID, Name: ref.Name, Type: ref.Type}
+				if seen[credential] {
+					continue
+				}
+				seen[credential] = true
+				credentials = append(credentials, credential)
+			}
+		}
+	}
+	return credentials
+}
+
+// credentialRef is a credential as referenced from a node:
+// "credentials": {"slackApi": {"id": "12", "name": "Slack account"}}
+type credentialRef struct {
+	Type string
+	ID   string
+	Name string
+}
+
+func nodeCredentials(node map[string]interface{}) []credentialRef {
+	credentials, _ := node["credentials"].(map[string]interface{})
+	var refs []credentialRef
+	for credentialType, raw := range credentials {
+		ref := credentialRef{Type: credentialType}
+		switch v := raw.(type) {
+		case map[string]interface{}:
+			ref.ID = WorkflowID(v)
+			ref.Name, _ = v["name"].(string)
+		case string:
+			// Old workflows store only the credential name
+			ref.Name = v
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Type < refs[j].Type })
+	return refs
+}
+
+func workflowNodes(workflow map[string]interface{}) []map[string]interface{} {
+	rawNodes, _ := workflow["nodes"].([]interface{})
+	var nodes []map[string]interface{}
+	for _, rawNode := range rawNodes {
+		if node, ok := rawNode.(map[string]interface{}); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func nodeName(rawNode interface{}) string {
+	node, _ := rawNode.(map[string]interface{})
+	name, _ := node["name"].(string)
+	return name
+}
+
+func encodeByExtension(path string, v interface{}) ([]byte, error) {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return marshalYAML(v)
+	case ".json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported file extension: %s", path)
+	}
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeByExtension(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		// Round-trip through JSON so numbers and maps have the same types
+		// as in API responses
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		data, err = json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".json":
+	default:
+		return fmt.Errorf("unsupported file extension: %s", path)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
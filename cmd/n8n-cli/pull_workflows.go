@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+	"github.com/rs/zerolog/log"
+)
+
+// PullCommand exports all workflows to files in a directory
+type PullCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for PullCommand
+type PullSettings struct {
+	Dir    string `glazed.parameter:"dir"`
+	Format string `glazed.parameter:"format"`
+	Prune  bool   `glazed.parameter:"prune"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *PullCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &PullSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	// Get API settings
+	apiSettings, err := n8n.GetN8NAPISettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	client := n8n.NewN8NClient(apiSettings.BaseURL, apiSettings.APIKey)
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+
+	// Existing files keep their path, even if the workflow was renamed
+	files, err := n8n.LoadWorkflowDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	pathByID := map[string]string{}
+	usedPaths := map[string]bool{}
+	for _, file := range files {
+		if id := file.ID(); id != "" {
+			pathByID[id] = file.Path
+		}
+		usedPaths[file.Path] = true
+	}
+
+	workflows, err := client.ListAllWorkflows()
+	if err != nil {
+		return err
+	}
+
+	pulled := map[string]bool{}
+	for _, workflow := range workflows {
+		normalized := n8n.NormalizeWorkflow(workflow)
+		id := n8n.WorkflowID(normalized)
+		pulled[id] = true
+
+		path, exists := pathByID[id]
+		if !exists {
+			path = filepath.Join(s.Dir, n8n.WorkflowFileName(normalized, s.Format))
+			if usedPaths[path] {
+				// Another workflow has the same name
+				path = filepath.Join(s.Dir, fmt.Sprintf("%s-%s.%s",
+					trimExt(n8n.WorkflowFileName(normalized, s.Format)), id, s.Format))
+			}
+			usedPaths[path] = true
+		}
+
+		changed, err := n8n.WriteWorkflowFile(path, normalized)
+		if err != nil {
+			return err
+		}
+
+		status := "unchanged"
+		switch {
+		case !exists:
+			status = "created"
+		case changed:
+			status = "updated"
+		}
+		if err := addPullRow(ctx, gp, id, normalized["name"], path, status); err != nil {
+			return err
+		}
+	}
+
+	// Remove files of workflows deleted on the server. Files without an ID
+	// haven't been pushed yet and are kept.
+	if s.Prune {
+		for _, file := range files {
+			if id := file.ID(); id != "" && !pulled[id] {
+				if err := os.Remove(file.Path); err != nil {
+					return err
+				}
+				if err := addPullRow(ctx, gp, id, file.Name(), file.Path, "pruned"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return updateCatalog(client, s.Dir, workflows)
+}
+
+// updateCatalog refreshes the node types and credentials that validate
+// checks against. Credentials added to the catalog by hand are kept.
+func updateCatalog(client *n8n.N8NClient, dir string, workflows []map[string]interface{}) error {
+	catalog, err := n8n.LoadCatalog(dir)
+	if err != nil {
+		return err
+	}
+	if catalog == nil {
+		catalog = &n8n.Catalog{}
+	}
+
+	nodes, err := client.GetNodes()
+	if err != nil {
+		log.Warn().Err(err).Msg("Could not fetch node types, keeping the node types in the catalog")
+	} else {
+		catalog.NodeTypes = n8n.NodeTypesFromDescriptions(nodes)
+	}
+
+	known := map[n8n.CatalogCredential]bool{}
+	for _, credential := range catalog.Credentials {
+		known[credential] = true
+	}
+	for _, credential := range n8n.CredentialsFromWorkflows(workflows) {
+		if !known[credential] {
+			catalog.Credentials = append(catalog.Credentials, credential)
+		}
+	}
+
+	return n8n.WriteCatalog(dir, catalog)
+}
+
+func addPullRow(ctx context.Context, gp middlewares.Processor, id string, name interface{}, path, status string) error {
+	row := types.NewRow(
+		types.MRP("id", id),
+		types.MRP("name", name),
+		types.MRP("file", path),
+		types.MRP("status", status),
+	)
+	return gp.AddRow(ctx, row)
+}
+
+func trimExt(path string) string {
+	return path[:len(path)-len(filepath.Ext(path))]
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &PullCommand{}
+
+// NewPullCommand creates a new PullCommand
+func NewPullCommand() (*PullCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the n8n API layer
+	apiLayer, err := n8n.NewN8NAPILayer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"pull",
+		cmds.WithShort("Export all workflows to a directory"),
+		cmds.WithLong(`Export every workflow to a normalized YAML or JSON file in a directory.
+
+Only the fields that describe the workflow are kept (id, name, nodes,
+connections, settings) and nodes are sorted by name, so pulling an unchanged
+workflow doesn't change its file. Existing files are updated in place.
+
+pull also writes n8n-catalog.yaml with the node types and credentials known
+to the server, which validate uses to check workflows offline.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"dir",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Directory to write workflow files to"),
+				parameters.WithDefault("workflows"),
+			),
+			parameters.NewParameterDefinition(
+				"format",
+				parameters.ParameterTypeChoice,
+				parameters.WithHelp("File format for new workflow files"),
+				parameters.WithChoices("yaml", "json"),
+				parameters.WithDefault("yaml"),
+			),
+			parameters.NewParameterDefinition(
+				"prune",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Delete files of workflows that no longer exist on the server"),
+				parameters.WithDefault(false),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer, apiLayer),
+	)
+
+	// Return the command instance
+	return &PullCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
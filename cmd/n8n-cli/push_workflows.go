@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+	"github.com/rs/zerolog/log"
+)
+
+// PushCommand applies workflow files to the server
+type PushCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for PushCommand
+type PushSettings struct {
+	Dir            string `glazed.parameter:"dir"`
+	DryRun         bool   `glazed.parameter:"dry-run"`
+	DeleteMissing  bool   `glazed.parameter:"delete-missing"`
+	SkipValidation bool   `glazed.parameter:"skip-validation"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *PushCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &PushSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	// Get API settings
+	apiSettings, err := n8n.GetN8NAPISettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	client := n8n.NewN8NClient(apiSettings.BaseURL, apiSettings.APIKey)
+
+	files, err := n8n.LoadWorkflowDir(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	// Don't push workflows that n8n would reject or that can't run
+	if !s.SkipValidation {
+		catalog, err := n8n.LoadCatalog(s.Dir)
+		if err != nil {
+			return err
+		}
+		errorCount := 0
+		for _, file := range files {
+			for _, issue := range n8n.ValidateWorkflow(file.Workflow, catalog) {
+				if issue.Severity == n8n.SeverityError {
+					log.Error().Str("file", file.Path).Str("node", issue.Node).Msg(issue.Message)
+					errorCount++
+				}
+			}
+		}
+		if errorCount > 0 {
+			return fmt.Errorf("%d validation errors, run validate for details or use --skip-validation", errorCount)
+		}
+	}
+
+	remote, err := client.ListAllWorkflows()
+	if err != nil {
+		return err
+	}
+
+	actions, err := n8n.PlanSync(files, remote, n8n.DiffOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, action := range actions {
+		id := action.ID()
+		file := ""
+		if action.File != nil {
+			file = action.File.Path
+		}
+
+		status := action.Action
+		switch action.Action {
+		case n8n.SyncCreate:
+			if !s.DryRun {
+				result, err := client.CreateWorkflow(n8n.NormalizeWorkflow(action.File.Workflow))
+				if err != nil {
+					return fmt.Errorf("creating %s: %w", file, err)
+				}
+				// Store the assigned ID so the next push updates the workflow
+				id = n8n.WorkflowID(result)
+				if _, err := n8n.WriteWorkflowFile(file, n8n.NormalizeWorkflow(result)); err != nil {
+					return err
+				}
+			}
+
+		case n8n.SyncUpdate:
+			if !s.DryRun {
+				if _, err := client.UpdateWorkflow(id, n8n.NormalizeWorkflow(action.File.Workflow)); err != nil {
+					return fmt.Errorf("updating %s: %w", file, err)
+				}
+			}
+
+		case n8n.SyncRemoteOnly:
+			if !s.DeleteMissing {
+				status = "skipped"
+				break
+			}
+			status = "delete"
+			if !s.DryRun {
+				if err := client.DeleteWorkflow(id); err != nil {
+					return fmt.Errorf("deleting workflow %s: %w", id, err)
+				}
+			}
+		}
+
+		row := types.NewRow(
+			types.MRP("id", id),
+			types.MRP("name", action.Name()),
+			types.MRP("file", file),
+			types.MRP("action", status),
+			types.MRP("changes", len(action.Changes)),
+			types.MRP("dry_run", s.DryRun),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &PushCommand{}
+
+// NewPushCommand creates a new PushCommand
+func NewPushCommand() (*PushCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the n8n API layer
+	apiLayer, err := n8n.NewN8NAPILayer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"push",
+		cmds.WithShort("Apply workflow files to the server"),
+		cmds.WithLong(`Create or update the workflows on the server from the files in a directory.
+
+Files are matched to workflows by their id. Workflows whose file differs
+from the server are updated, files without an id (or with an id the server
+doesn't know) are created and the new id is written back to the file.
+Workflows that only exist on the server are left alone unless
+--delete-missing is given.
+
+The files are validated first, see validate. Use --dry-run to see what would
+change without changing anything.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"dir",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Directory with workflow files"),
+				parameters.WithDefault("workflows"),
+			),
+			parameters.NewParameterDefinition(
+				"dry-run",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Show what would be pushed without changing the server"),
+				parameters.WithDefault(false),
+			),
+			parameters.NewParameterDefinition(
+				"delete-missing",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Delete workflows on the server that have no file"),
+				parameters.WithDefault(false),
+			),
+			parameters.NewParameterDefinition(
+				"skip-validation",
+				parameters.ParameterTypeBool,
+				parameters.WithHelp("Push even if validation finds errors"),
+				parameters.WithDefault(false),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer, apiLayer),
+	)
+
+	// Return the command instance
+	return &PushCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
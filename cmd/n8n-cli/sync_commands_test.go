@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	cmdmiddlewares "github.com/go-go-golems/glazed/pkg/cmds/middlewares"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n/fakeserver"
+)
+
+const testAPIKey = "test-key"
+
+// runGlazeCommand runs a command with the given flags against a server and
+// returns its rows
+func runGlazeCommand(t *testing.T, cmd cmds.GlazeCommand, baseURL string, flags map[string]interface{}) ([]map[string]interface{}, error) {
+	t.Helper()
+	parsedLayers := layers.NewParsedLayers()
+	err := cmdmiddlewares.ExecuteMiddlewares(cmd.Description().Layers, parsedLayers,
+		cmdmiddlewares.UpdateFromMap(map[string]map[string]interface{}{
+			layers.DefaultSlug: flags,
+			"n8n-api":          {"base-url": baseURL, "api-key": testAPIKey},
+		}),
+		cmdmiddlewares.SetFromDefaults(),
+	)
+	if err != nil {
+		t.Fatalf("parsing flags: %v", err)
+	}
+
+	gp := &rowCollector{}
+	err = cmd.RunIntoGlazeProcessor(context.Background(), parsedLayers, gp)
+	return gp.rows, err
+}
+
+// rowCollector keeps the rows a command outputs
+type rowCollector struct {
+	rows []map[string]interface{}
+}
+
+var _ middlewares.Processor = &rowCollector{}
+
+func (c *rowCollector) AddRow(ctx context.Context, row types.Row) error {
+	values := map[string]interface{}{}
+	for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+		values[pair.Key] = pair.Value
+	}
+	c.rows = append(c.rows, values)
+	return nil
+}
+
+func (c *rowCollector) Close(ctx context.Context) error {
+	return nil
+}
+
+// newFakeN8N starts an empty fake n8n server and returns a client for it
+func newFakeN8N(t *testing.T) *n8n.N8NClient {
+	t.Helper()
+	ts := httptest.NewServer(fakeserver.New(testAPIKey).Handler())
+	t.Cleanup(ts.Close)
+	return n8n.NewN8NClient(ts.URL, testAPIKey)
+}
+
+func testWorkflow(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name,
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "Start", "type": "n8n-nodes-base.manualTrigger", "position": []interface{}{0, 0}, "parameters": map[string]interface{}{}},
+			map[string]interface{}{"name": "Set", "type": "n8n-nodes-base.set", "position": []interface{}{200, 0}, "parameters": map[string]interface{}{"value": "a"}},
+		},
+		"connections": map[string]interface{}{
+			"Start": map[string]interface{}{"main": []interface{}{[]interface{}{
+				map[string]interface{}{"node": "Set", "type": "main", "index": 0},
+			}}},
+		},
+		"settings": map[string]interface{}{},
+	}
+}
+
+func mustCommand[T cmds.GlazeCommand](t *testing.T, newCommand func() (T, error)) T {
+	t.Helper()
+	cmd, err := newCommand()
+	if err != nil {
+		t.Fatalf("creating command: %v", err)
+	}
+	return cmd
+}
+
+// actionsByName maps workflow names to the push action of their row
+func actionsByName(rows []map[string]interface{}) map[string]string {
+	actions := map[string]string{}
+	for _, row := range rows {
+		actions[row["name"].(string)] = row["action"].(string)
+	}
+	return actions
+}
+
+func TestPullDiffPushRoundTrip(t *testing.T) {
+	client := newFakeN8N(t)
+	baseURL := client.BaseURL
+	existing, err := client.CreateWorkflow(testWorkflow("Existing Flow"))
+	if err != nil {
+		t.Fatalf("seeding workflow: %v", err)
+	}
+	existingID := n8n.WorkflowID(existing)
+	dir := t.TempDir()
+
+	pull := mustCommand(t, NewPullCommand)
+	diff := mustCommand(t, NewDiffCommand)
+	push := mustCommand(t, NewPushCommand)
+	validate := mustCommand(t, NewValidateCommand)
+
+	rows, err := runGlazeCommand(t, pull, baseURL, map[string]interface{}{"dir": dir, "format": "yaml"})
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["status"] != "created" {
+		t.Fatalf("expected one created file, got %v", rows)
+	}
+	path := filepath.Join(dir, "existing-flow.yaml")
+	if _, err := os.Stat(filepath.Join(dir, n8n.CatalogFileName)); err != nil {
+		t.Errorf("expected pull to write the catalog: %v", err)
+	}
+
+	if _, err := runGlazeCommand(t, validate, baseURL, map[string]interface{}{"dir": dir}); err != nil {
+		t.Errorf("validate after pull: %v", err)
+	}
+	if rows, err := runGlazeCommand(t, diff, baseURL, map[string]interface{}{"dir": dir}); err != nil || len(rows) != 0 {
+		t.Errorf("expected no differences after pull, got %v, %v", rows, err)
+	}
+
+	// Edit the pulled workflow, add a new one and one with an ID the server
+	// doesn't know, e.g. copied from another instance
+	file, err := n8n.ReadWorkflowFile(path)
+	if err != nil {
+		t.Fatalf("reading pulled file: %v", err)
+	}
+	file.Workflow["nodes"].([]interface{})[0].(map[string]interface{})["parameters"].(map[string]interface{})["value"] = "b"
+	if _, err := n8n.WriteWorkflowFile(path, file.Workflow); err != nil {
+		t.Fatal(err)
+	}
+	newPath := filepath.Join(dir, "new-flow.json")
+	if _, err := n8n.WriteWorkflowFile(newPath, testWorkflow("New Flow")); err != nil {
+		t.Fatal(err)
+	}
+	foreign := testWorkflow("Foreign Flow")
+	foreign["id"] = "from-another-instance"
+	foreignPath := filepath.Join(dir, "foreign-flow.yaml")
+	if _, err := n8n.WriteWorkflowFile(foreignPath, foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err = runGlazeCommand(t, diff, baseURL, map[string]interface{}{"dir": dir})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	changes := map[string]bool{}
+	for _, row := range rows {
+		changes[row["workflow"].(string)+" "+row["op"].(string)+" "+row["target"].(string)+" "+row["detail"].(string)] = true
+	}
+	for _, want := range []string{
+		"Existing Flow changed Set parameters.value",
+		"New Flow added New Flow ",
+		"Foreign Flow added Foreign Flow ",
+	} {
+		if !changes[want] {
+			t.Errorf("diff is missing %q, got %v", want, changes)
+		}
+	}
+
+	want := map[string]string{"Existing Flow": n8n.SyncUpdate, "New Flow": n8n.SyncCreate, "Foreign Flow": n8n.SyncCreate}
+	for _, dryRun := range []bool{true, false} {
+		rows, err = runGlazeCommand(t, push, baseURL, map[string]interface{}{"dir": dir, "dry-run": dryRun})
+		if err != nil {
+			t.Fatalf("push (dry run %v): %v", dryRun, err)
+		}
+		got := actionsByName(rows)
+		if len(got) != len(want) {
+			t.Errorf("push (dry run %v) actions %v, want %v", dryRun, got, want)
+		}
+		for name, action := range want {
+			if got[name] != action {
+				t.Errorf("push (dry run %v) %s: got %s, want %s", dryRun, name, got[name], action)
+			}
+		}
+		if remote, _ := client.ListAllWorkflows(); dryRun && len(remote) != 1 {
+			t.Errorf("dry run changed the server, %d workflows", len(remote))
+		}
+	}
+
+	// Created workflows get the server's ID written back to their file
+	for _, p := range []string{newPath, foreignPath} {
+		file, err := n8n.ReadWorkflowFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := file.ID()
+		if id == "" || id == "from-another-instance" {
+			t.Errorf("%s: expected the server ID, got %q", filepath.Base(p), id)
+			continue
+		}
+		remote, err := client.GetWorkflow(id)
+		if err != nil || remote["name"] != file.Name() {
+			t.Errorf("%s: workflow %s not on the server: %v", filepath.Base(p), id, err)
+		}
+	}
+	updated, err := client.GetWorkflow(existingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n8n.DiffWorkflows(file.Workflow, updated, n8n.DiffOptions{}) != nil {
+		t.Error("expected the edit to be pushed")
+	}
+
+	// Pushing again changes nothing
+	rows, err = runGlazeCommand(t, push, baseURL, map[string]interface{}{"dir": dir})
+	if err != nil {
+		t.Fatalf("second push: %v", err)
+	}
+	for name, action := range actionsByName(rows) {
+		if action != n8n.SyncUnchanged {
+			t.Errorf("second push %s: got %s, want unchanged", name, action)
+		}
+	}
+}
+
+func TestPushRemoteOnlyWorkflows(t *testing.T) {
+	client := newFakeN8N(t)
+	if _, err := client.CreateWorkflow(testWorkflow("Server Only")); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	push := mustCommand(t, NewPushCommand)
+
+	rows, err := runGlazeCommand(t, push, client.BaseURL, map[string]interface{}{"dir": dir})
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if got := actionsByName(rows)["Server Only"]; got != "skipped" {
+		t.Errorf("expected remote-only workflow to be skipped, got %q", got)
+	}
+
+	rows, err = runGlazeCommand(t, push, client.BaseURL, map[string]interface{}{"dir": dir, "delete-missing": true})
+	if err != nil {
+		t.Fatalf("push --delete-missing: %v", err)
+	}
+	if got := actionsByName(rows)["Server Only"]; got != "delete" {
+		t.Errorf("expected remote-only workflow to be deleted, got %q", got)
+	}
+	if remote, _ := client.ListAllWorkflows(); len(remote) != 0 {
+		t.Errorf("expected no workflows left, got %d", len(remote))
+	}
+}
+
+func TestSyncRejectsFilesWithTheSameID(t *testing.T) {
+	client := newFakeN8N(t)
+	created, err := client.CreateWorkflow(testWorkflow("Flow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	workflow := testWorkflow("Flow")
+	workflow["id"] = n8n.WorkflowID(created)
+	for _, name := range []string{"flow.yaml", "flow-copy.yaml"} {
+		if _, err := n8n.WriteWorkflowFile(filepath.Join(dir, name), workflow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, cmd := range []cmds.GlazeCommand{mustCommand(t, NewDiffCommand), mustCommand(t, NewPushCommand)} {
+		_, err := runGlazeCommand(t, cmd, client.BaseURL, map[string]interface{}{"dir": dir})
+		if err == nil || !strings.Contains(err.Error(), "both contain workflow "+n8n.WorkflowID(created)) {
+			t.Errorf("%s: expected a conflict error, got %v", cmd.Description().Name, err)
+		}
+	}
+}
+
+func TestValidateAndPushRejectBrokenWorkflows(t *testing.T) {
+	client := newFakeN8N(t)
+	dir := t.TempDir()
+	broken := testWorkflow("Broken")
+	broken["connections"].(map[string]interface{})["Set"] = map[string]interface{}{"main": []interface{}{[]interface{}{
+		map[string]interface{}{"node": "Missing", "type": "main", "index": 0},
+	}}}
+	if _, err := n8n.WriteWorkflowFile(filepath.Join(dir, "broken.yaml"), broken); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := runGlazeCommand(t, mustCommand(t, NewValidateCommand), client.BaseURL, map[string]interface{}{"dir": dir})
+	if err == nil {
+		t.Fatal("expected validate to fail")
+	}
+	found := false
+	for _, row := range rows {
+		if row["check"] == "connection" && row["severity"] == n8n.SeverityError {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a connection error, got %v", rows)
+	}
+
+	push := mustCommand(t, NewPushCommand)
+	if _, err := runGlazeCommand(t, push, client.BaseURL, map[string]interface{}{"dir": dir}); err == nil {
+		t.Error("expected push to refuse invalid workflows")
+	}
+	if remote, _ := client.ListAllWorkflows(); len(remote) != 0 {
+		t.Errorf("expected nothing pushed, got %d workflows", len(remote))
+	}
+	if _, err := runGlazeCommand(t, push, client.BaseURL, map[string]interface{}{"dir": dir, "skip-validation": true}); err != nil {
+		t.Errorf("push --skip-validation: %v", err)
+	}
+}
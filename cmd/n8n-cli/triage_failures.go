@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+)
+
+// TriageFailuresCommand groups recent failed executions by node and error
+type TriageFailuresCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for TriageFailuresCommand
+type TriageFailuresSettings struct {
+	WorkflowID string `glazed.parameter:"workflow-id"`
+	Limit      int    `glazed.parameter:"limit"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *TriageFailuresCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &TriageFailuresSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	// Get API settings
+	apiSettings, err := n8n.GetN8NAPISettingsFromParsedLayers(parsedLayers)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	client := n8n.NewN8NClient(apiSettings.BaseURL, apiSettings.APIKey)
+
+	analyses, err := client.RecentFailures(s.WorkflowID, s.Limit)
+	if err != nil {
+		return err
+	}
+
+	// Output one row per failure group, most frequent first
+	for _, group := range n8n.AggregateFailures(analyses) {
+		row := types.NewRow(
+			types.MRP("workflow_id", group.WorkflowID),
+			types.MRP("workflow", group.WorkflowName),
+			types.MRP("node", group.Node),
+			types.MRP("node_type", group.NodeType),
+			types.MRP("error", group.Message),
+			types.MRP("count", group.Count),
+			types.MRP("first_seen", group.FirstSeen),
+			types.MRP("last_seen", group.LastSeen),
+			types.MRP("executions", strings.Join(group.ExecutionIDs, ", ")),
+		)
+		if err := gp.AddRow(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &TriageFailuresCommand{}
+
+// NewTriageFailuresCommand creates a new TriageFailuresCommand
+func NewTriageFailuresCommand() (*TriageFailuresCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the n8n API layer
+	apiLayer, err := n8n.NewN8NAPILayer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"triage-failures",
+		cmds.WithShort("Group recent failed executions by node and error"),
+		cmds.WithLong(`Fetch the most recent failed executions and group them by workflow, failing
+node and error message, most frequent first.
+
+IDs, timestamps and long numbers in error messages are masked so that
+occurrences of the same error end up in one group. Use debug-execution with
+one of the listed execution IDs to look at a failure in detail.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"workflow-id",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Only look at executions of this workflow"),
+				parameters.WithDefault(""),
+			),
+			parameters.NewParameterDefinition(
+				"limit",
+				parameters.ParameterTypeInteger,
+				parameters.WithHelp("Number of recent failed executions to look at"),
+				parameters.WithDefault(50),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer, apiLayer),
+	)
+
+	// Return the command instance
+	return &TriageFailuresCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
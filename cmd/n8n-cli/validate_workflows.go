@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/layers"
+	"github.com/go-go-golems/glazed/pkg/cmds/parameters"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/settings"
+	"github.com/go-go-golems/glazed/pkg/types"
+	"github.com/go-go-golems/go-go-labs/cmd/n8n-cli/pkg/n8n"
+)
+
+// ValidateCommand checks workflow files offline
+type ValidateCommand struct {
+	*cmds.CommandDescription
+}
+
+// Settings for ValidateCommand
+type ValidateSettings struct {
+	Dir     string `glazed.parameter:"dir"`
+	Catalog string `glazed.parameter:"catalog"`
+}
+
+// RunIntoGlazeProcessor implements the GlazeCommand interface
+func (c *ValidateCommand) RunIntoGlazeProcessor(
+	ctx context.Context,
+	parsedLayers *layers.ParsedLayers,
+	gp middlewares.Processor,
+) error {
+	// Parse settings from layers
+	s := &ValidateSettings{}
+	if err := parsedLayers.InitializeStruct(layers.DefaultSlug, s); err != nil {
+		return err
+	}
+
+	files, err := n8n.LoadWorkflowDir(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	catalogDir := s.Dir
+	if s.Catalog != "" {
+		catalogDir = s.Catalog
+	}
+	catalog, err := n8n.LoadCatalog(catalogDir)
+	if err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, file := range files {
+		for _, issue := range n8n.ValidateWorkflow(file.Workflow, catalog) {
+			if issue.Severity == n8n.SeverityError {
+				errorCount++
+			}
+			row := types.NewRow(
+				types.MRP("file", file.Path),
+				types.MRP("workflow", file.Name()),
+				types.MRP("severity", issue.Severity),
+				types.MRP("check", issue.Check),
+				types.MRP("node", issue.Node),
+				types.MRP("message", issue.Message),
+			)
+			if err := gp.AddRow(ctx, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	if errorCount > 0 {
+		// Returning an error exits before the output is rendered, so flush
+		// the issues first. The error sets the exit code for CI.
+		if err := gp.Close(ctx); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d validation errors in %s", errorCount, s.Dir)
+	}
+	fmt.Fprintf(os.Stderr, "%d workflows valid\n", len(files))
+	return nil
+}
+
+// Ensure the interface is implemented
+var _ cmds.GlazeCommand = &ValidateCommand{}
+
+// NewValidateCommand creates a new ValidateCommand
+func NewValidateCommand() (*ValidateCommand, error) {
+	// Create the standard Glazed output layer
+	glazedLayer, err := settings.NewGlazedParameterLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the command description
+	cmdDesc := cmds.NewCommandDescription(
+		"validate",
+		cmds.WithShort("Check workflow files offline"),
+		cmds.WithLong(`Check the workflow files in a directory without contacting the server.
+
+Checks:
+- every node has a unique name and a type
+- connections start and end at existing nodes
+- node types exist (needs the catalog)
+- nodes have the credentials their type requires and the referenced
+  credentials exist (needs the catalog)
+
+The catalog (n8n-catalog.yaml) is written by pull. Credentials that exist on
+the server but aren't used by any workflow yet can be added to it by hand.
+Exits with an error if any check fails.`),
+
+		// Define flags (parameters)
+		cmds.WithFlags(
+			parameters.NewParameterDefinition(
+				"dir",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Directory with workflow files"),
+				parameters.WithDefault("workflows"),
+			),
+			parameters.NewParameterDefinition(
+				"catalog",
+				parameters.ParameterTypeString,
+				parameters.WithHelp("Directory containing n8n-catalog.yaml (defaults to --dir)"),
+				parameters.WithDefault(""),
+			),
+		),
+
+		// Add parameter layers
+		cmds.WithLayersList(glazedLayer),
+	)
+
+	// Return the command instance
+	return &ValidateCommand{
+		CommandDescription: cmdDesc,
+	}, nil
+}
@@ -50,6 +50,7 @@ require (
 	github.com/go-go-golems/go-go-mcp v0.0.15
 	github.com/go-go-golems/pinocchio v0.4.44
 	github.com/go-go-golems/uhoh v0.0.8
+	github.com/go-jose/go-jose/v3 v3.0.4
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/google/uuid v1.6.0
@@ -173,7 +174,6 @@ require (
 	github.com/felixge/fgprof v0.9.3 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/go-go-golems/sqleton v0.3.4 // indirect
-	github.com/go-jose/go-jose/v3 v3.0.4 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-ole/go-ole v1.2.6 // indirect